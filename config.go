@@ -7,21 +7,48 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/spf13/viper"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 
+	"github.com/falcosecurity/falcosidekick/outputs"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
+// Flags of the sample payload command, see printSamplePayload
+var (
+	samplePayload = kingpin.Flag("sample-payload", "print the headers and the body the output would post for the event, then exit").PlaceHolder("OUTPUT").String()
+	sampleEvent   = kingpin.Flag("sample-event", "file of the event for --sample-payload (default: the test event)").PlaceHolder("FILE").ExistingFile()
+)
+
 func getConfig() *types.Configuration {
 	c := &types.Configuration{
-		Customfields: make(map[string]string),
-		Webhook:      types.WebhookOutputConfig{CustomHeaders: make(map[string]string)},
-		CloudEvents:  types.CloudEventsOutputConfig{Extensions: make(map[string]string)},
+		Customfields:    make(map[string]string),
+		Templatedfields: make(map[string]string),
+		Webhook:         types.WebhookOutputConfig{CustomHeaders: make(map[string]string), StatusCodePolicies: make(map[string]string), FieldsMapping: make(map[string]string), KeysMapping: make(map[string]string)},
+		CloudEvents:     types.CloudEventsOutputConfig{Extensions: make(map[string]string)},
+		Metrics:         types.MetricsConfig{Labels: make(map[string]string)},
+		Priorities:      types.PrioritiesConfig{Aliases: make(map[string]string)},
+		Dispatch:        types.DispatchConfig{Dependencies: make(map[string]string), Queues: make(map[string]string)},
+		QuietHours:      types.QuietHoursConfig{Windows: make(map[string]string)},
+		Cooldown:        types.CooldownConfig{Windows: make(map[string]string)},
+		Timeout:         types.TimeoutConfig{Outputs: make(map[string]string)},
+		Sampling:        types.SamplingConfig{Outputs: make(map[string]string)},
+		CEL:             types.CELConfig{Fields: make(map[string]string)},
+		Tenants:         types.TenantsOutputConfig{Destinations: make(map[string]string), Tokens: make(map[string]string)},
+		WebSocket:       types.WebSocketOutputConfig{CustomHeaders: make(map[string]string)},
 	}
+	c.Elasticsearch.StatusCodePolicies = make(map[string]string)
+	c.Elasticsearch.FieldsMapping = make(map[string]string)
+	c.Elasticsearch.KeysMapping = make(map[string]string)
+	c.Elasticsearch.CustomHeaders = make(map[string]string)
+	c.Loki.CustomHeaders = make(map[string]string)
+	c.Opsgenie.CloseRules = make(map[string]string)
+	c.Opsgenie.Priorities = make(map[string]string)
 
 	configFile := kingpin.Flag("config-file", "config file").Short('c').ExistingFile()
 	kingpin.Parse()
@@ -31,13 +58,107 @@ func getConfig() *types.Configuration {
 	v.SetDefault("ListenPort", 2801)
 	v.SetDefault("Debug", false)
 	v.SetDefault("MutualTlsFilesPath", "/etc/certs")
+	v.SetDefault("FieldsPrecedence", "custom")
+	v.SetDefault("FieldsConflict", "overwrite")
+	v.SetDefault("IngestLatency.Enabled", false)
+	v.SetDefault("IngestLatency.Metric", false)
+	v.SetDefault("Node.Enabled", false)
+	v.SetDefault("Node.Field", "hostname")
+	v.SetDefault("Node.Sources", []string{"hostname", "host.name", "k8s.node.name", "container.host"})
+	v.SetDefault("Node.Default", "")
+	v.SetDefault("Replay.File", "")
+	v.SetDefault("Replay.Parallelism", 1)
+	v.SetDefault("Replay.Rate", 0)
+	v.SetDefault("Replay.Offset", 0)
+	v.SetDefault("OPA.URL", "")
+	v.SetDefault("OPA.CacheTTL", 5)
+	v.SetDefault("OPA.FailOpen", true)
+	v.SetDefault("OPA.CheckCert", true)
+	v.SetDefault("Drain.Token", "")
+	v.SetDefault("Drain.ShutdownTimeout", 20)
+	v.SetDefault("Test.Token", "")
+	v.SetDefault("Readiness.Outputs", []string{})
+	v.SetDefault("Readiness.CacheTTL", 5)
+	v.SetDefault("Readiness.Timeout", 2000)
+	v.SetDefault("DeadLetter.File", "")
+	v.SetDefault("DeadLetter.MaxSize", 104857600)
+	v.SetDefault("DeadLetter.MaxFiles", 5)
+	v.SetDefault("Log.Format", "text")
+	v.SetDefault("Log.Level", "debug")
+	v.SetDefault("Log.ErrorSampling.Window", 0)
+	v.SetDefault("Log.ErrorSampling.Rate", 0)
+	v.SetDefault("Consul.Address", "http://127.0.0.1:8500")
+	v.SetDefault("Consul.Token", "")
+	v.SetDefault("Consul.RefreshInterval", 30)
+	v.SetDefault("Metrics.CardinalityLimit", 100)
+	v.SetDefault("Priorities.Unknown", "")
+	v.SetDefault("Reordering.Window", 0)
+	v.SetDefault("Correlation.Enabled", false)
+	v.SetDefault("Correlation.Window", 2000)
+	v.SetDefault("Correlation.SyscallKeys", []string{"k8s.ns.name", "k8s.pod.name"})
+	v.SetDefault("Correlation.AuditKeys", []string{"ka.target.namespace", "ka.target.name"})
+	v.SetDefault("Escalation.Keys", []string{"k8s.ns.name", "k8s.pod.name"})
+	v.SetDefault("Escalation.Threshold", 0)
+	v.SetDefault("Escalation.Window", 300)
+	v.SetDefault("Escalation.Levels", 1)
+	v.SetDefault("CEL.Filter", "")
+	v.SetDefault("CEL.Priority", "")
+	v.SetDefault("Enrichment.URL", "")
+	v.SetDefault("Enrichment.Field", "k8s.pod.name")
+	v.SetDefault("Enrichment.CacheTTL", 300)
+	v.SetDefault("Enrichment.Rate", 0)
+	v.SetDefault("Enrichment.Timeout", 2000)
+	v.SetDefault("Enrichment.CheckCert", true)
+	v.SetDefault("Runbooks.File", "")
+	v.SetDefault("Sampling.ExemptPriority", "critical")
+	v.SetDefault("Sampling.AddRate", false)
+	v.SetDefault("Dispatch.Deadline", 0)
+	v.SetDefault("DryRun.Outputs", []string{})
+	v.SetDefault("Retry.MaxAttempts", 1)
+	v.SetDefault("Retry.InitialDelay", 500)
+	v.SetDefault("Retry.MaxDelay", 10000)
+	v.SetDefault("Timeout.Default", "")
+	v.SetDefault("Cooldown.Key", "{{.Rule}}")
+	v.SetDefault("Flattening.Outputs", []string{})
+	v.SetDefault("Flattening.Separator", "_")
+	v.SetDefault("Flattening.Coerce", "")
+	v.SetDefault("QuietHours.Timezone", "UTC")
+	v.SetDefault("QuietHours.ExemptPriority", "critical")
+	v.SetDefault("Schedule.Timezone", "UTC")
+	v.SetDefault("Routing.Default", []string{})
+	v.SetDefault("Deduplication.Window", 0)
+	v.SetDefault("Deduplication.MaxAge", 0)
+	v.SetDefault("Deduplication.Fields", []string{})
+	v.SetDefault("Summary.Interval", 0)
+	v.SetDefault("Summary.Outputs", []string{})
+	v.SetDefault("Summary.Priority", "informational")
+	v.SetDefault("PayloadSize.Threshold", 0)
+	v.SetDefault("KafkaInput.HostPort", "")
+	v.SetDefault("KafkaInput.Topic", "")
+	v.SetDefault("KafkaInput.GroupID", "falcosidekick")
+	v.SetDefault("KafkaInput.TLS", false)
+	v.SetDefault("KafkaInput.CheckCert", true)
+	v.SetDefault("KafkaInput.SASLMechanism", "")
+	v.SetDefault("KafkaInput.Username", "")
+	v.SetDefault("KafkaInput.Password", "")
 	v.SetDefault("Slack.WebhookURL", "")
+	v.SetDefault("Slack.Token", "")
+	v.SetDefault("Slack.Channel", "")
+	v.SetDefault("Slack.ThreadWindow", 0)
+	v.SetDefault("Slack.ThreadField", "")
 	v.SetDefault("Slack.Footer", "https://github.com/falcosecurity/falcosidekick")
 	v.SetDefault("Slack.Username", "Falcosidekick")
 	v.SetDefault("Slack.Icon", "https://raw.githubusercontent.com/falcosecurity/falcosidekick/master/imgs/falcosidekick_color.png")
 	v.SetDefault("Slack.OutputFormat", "all")
 	v.SetDefault("Slack.MessageFormat", "")
+	v.SetDefault("Slack.MessageFormatField", "")
 	v.SetDefault("Slack.MinimumPriority", "")
+	v.SetDefault("Slack.RedactFields", []string{})
+	v.SetDefault("Slack.MaskFields", []string{})
+	v.SetDefault("Slack.RedactPatterns", []string{})
+	v.SetDefault("Slack.RetryAfterPolicy", "")
+	v.SetDefault("Slack.MaxRate", "")
+	v.SetDefault("Slack.RateLimitMode", "block")
 	v.SetDefault("Slack.MutualTLS", false)
 	v.SetDefault("Slack.CheckCert", true)
 	v.SetDefault("Rocketchat.WebhookURL", "")
@@ -46,7 +167,11 @@ func getConfig() *types.Configuration {
 	v.SetDefault("Rocketchat.Icon", "https://raw.githubusercontent.com/falcosecurity/falcosidekick/master/imgs/falcosidekick_color.png")
 	v.SetDefault("Rocketchat.OutputFormat", "all")
 	v.SetDefault("Rocketchat.MessageFormat", "")
+	v.SetDefault("Rocketchat.MessageFormatField", "")
 	v.SetDefault("Rocketchat.MinimumPriority", "")
+	v.SetDefault("Rocketchat.RedactFields", []string{})
+	v.SetDefault("Rocketchat.MaskFields", []string{})
+	v.SetDefault("Rocketchat.RedactPatterns", []string{})
 	v.SetDefault("Rocketchat.MutualTLS", false)
 	v.SetDefault("Rocketchat.CheckCert", true)
 	v.SetDefault("Mattermost.WebhookURL", "")
@@ -55,46 +180,129 @@ func getConfig() *types.Configuration {
 	v.SetDefault("Mattermost.Icon", "https://raw.githubusercontent.com/falcosecurity/falcosidekick/master/imgs/falcosidekick_color.png")
 	v.SetDefault("Mattermost.OutputFormat", "all")
 	v.SetDefault("Mattermost.MessageFormat", "")
+	v.SetDefault("Mattermost.MessageFormatField", "")
 	v.SetDefault("Mattermost.MinimumPriority", "")
+	v.SetDefault("Mattermost.RedactFields", []string{})
+	v.SetDefault("Mattermost.MaskFields", []string{})
+	v.SetDefault("Mattermost.RedactPatterns", []string{})
 	v.SetDefault("Mattermost.MutualTLS", false)
 	v.SetDefault("Mattermost.CheckCert", true)
 	v.SetDefault("Teams.WebhookURL", "")
 	v.SetDefault("Teams.ActivityImage", "https://raw.githubusercontent.com/falcosecurity/falcosidekick/master/imgs/falcosidekick_color.png")
 	v.SetDefault("Teams.OutputFormat", "all")
+	v.SetDefault("Teams.Mode", "legacy")
+	v.SetDefault("Teams.UseAdaptiveCard", false)
+	v.SetDefault("Teams.MessageFormat", "")
+	v.SetDefault("Teams.MessageFormatField", "")
 	v.SetDefault("Teams.MinimumPriority", "")
+	v.SetDefault("Teams.RedactFields", []string{})
+	v.SetDefault("Teams.MaskFields", []string{})
+	v.SetDefault("Teams.RedactPatterns", []string{})
+	v.SetDefault("Teams.RetryAfterPolicy", "")
+	v.SetDefault("Teams.MaxRate", "")
+	v.SetDefault("Teams.RateLimitMode", "block")
 	v.SetDefault("Teams.MutualTLS", false)
 	v.SetDefault("Teams.CheckCert", true)
 	v.SetDefault("Datadog.APIKey", "")
 	v.SetDefault("Datadog.Host", "https://api.datadoghq.com")
 	v.SetDefault("Datadog.MinimumPriority", "")
+	v.SetDefault("Datadog.RedactFields", []string{})
+	v.SetDefault("Datadog.MaskFields", []string{})
+	v.SetDefault("Datadog.RedactPatterns", []string{})
 	v.SetDefault("Datadog.MutualTLS", false)
 	v.SetDefault("Datadog.CheckCert", true)
+	v.SetDefault("DatadogLogs.APIKey", "")
+	v.SetDefault("DatadogLogs.Site", "datadoghq.com")
+	v.SetDefault("DatadogLogs.Service", "falco")
+	v.SetDefault("DatadogLogs.Tags", []string{})
+	v.SetDefault("DatadogLogs.Fields", []string{})
+	v.SetDefault("DatadogLogs.BatchSize", 100)
+	v.SetDefault("DatadogLogs.FlushInterval", 5)
+	v.SetDefault("DatadogLogs.MinimumPriority", "")
+	v.SetDefault("DatadogLogs.MutualTLS", false)
+	v.SetDefault("DatadogLogs.CheckCert", true)
 	v.SetDefault("Discord.WebhookURL", "")
 	v.SetDefault("Discord.MinimumPriority", "")
+	v.SetDefault("Discord.RedactFields", []string{})
+	v.SetDefault("Discord.MaskFields", []string{})
+	v.SetDefault("Discord.RedactPatterns", []string{})
 	v.SetDefault("Discord.Icon", "https://raw.githubusercontent.com/falcosecurity/falcosidekick/master/imgs/falcosidekick_color.png")
+	v.SetDefault("Discord.RetryAfterPolicy", "")
+	v.SetDefault("Discord.MaxRate", "")
+	v.SetDefault("Discord.RateLimitMode", "block")
 	v.SetDefault("Discord.MutualTLS", false)
 	v.SetDefault("Discord.CheckCert", true)
 	v.SetDefault("Alertmanager.HostPort", "")
 	v.SetDefault("Alertmanager.MinimumPriority", "")
+	v.SetDefault("Alertmanager.ServerName", "")
+	v.SetDefault("Alertmanager.DisableSessionTickets", false)
+	v.SetDefault("Alertmanager.SessionCacheSize", 0)
+	v.SetDefault("Alertmanager.SchemaVersion", "")
+	v.SetDefault("Alertmanager.SchemaVersionInPayload", false)
 	v.SetDefault("Alertmanager.MutualTls", false)
 	v.SetDefault("Alertmanager.CheckCert", true)
 	v.SetDefault("Elasticsearch.HostPort", "")
 	v.SetDefault("Elasticsearch.Index", "falco")
 	v.SetDefault("Elasticsearch.Type", "event")
 	v.SetDefault("Elasticsearch.MinimumPriority", "")
+	v.SetDefault("Elasticsearch.ServerName", "")
+	v.SetDefault("Elasticsearch.DisableSessionTickets", false)
+	v.SetDefault("Elasticsearch.SessionCacheSize", 0)
 	v.SetDefault("Elasticsearch.Suffix", "daily")
+	v.SetDefault("Elasticsearch.RequiredFields", []string{})
+	v.SetDefault("Elasticsearch.RequiredFieldsAction", "drop")
+	v.SetDefault("Elasticsearch.Pipeline", []string{})
+	v.SetDefault("Elasticsearch.RedactFields", []string{})
+	v.SetDefault("Elasticsearch.MaskFields", []string{})
+	v.SetDefault("Elasticsearch.RedactPatterns", []string{})
+	v.SetDefault("Elasticsearch.SchemaVersion", "")
+	v.SetDefault("Elasticsearch.SchemaVersionInPayload", false)
+	v.SetDefault("Elasticsearch.PriorityCase", "asis")
+	v.SetDefault("Elasticsearch.StatusCodeRetries", 3)
+	v.SetDefault("Elasticsearch.MaxRetryDuration", 0)
+	v.SetDefault("Elasticsearch.CircuitBreakerThreshold", 0)
+	v.SetDefault("Elasticsearch.CircuitBreakerCooldown", 30)
+	v.SetDefault("Elasticsearch.BackpressureDelay", 0)
+	v.SetDefault("Elasticsearch.BackpressureMaxDelay", 60000)
+	v.SetDefault("Elasticsearch.BatchSize", 0)
+	v.SetDefault("Elasticsearch.FlushInterval", 5)
+	v.SetDefault("Elasticsearch.CompactFields", false)
 	v.SetDefault("Elasticsearch.MutualTls", false)
+	v.SetDefault("Elasticsearch.MutualTLSCACert", "")
+	v.SetDefault("Elasticsearch.MutualTLSClientCert", "")
+	v.SetDefault("Elasticsearch.MutualTLSClientKey", "")
+	v.SetDefault("Elasticsearch.EnableCompression", false)
+	v.SetDefault("Elasticsearch.CompressionThreshold", 1024)
 	v.SetDefault("Elasticsearch.CheckCert", true)
 	v.SetDefault("Influxdb.HostPort", "")
 	v.SetDefault("Influxdb.Database", "falco")
 	v.SetDefault("Influxdb.User", "")
 	v.SetDefault("Influxdb.Password", "")
+	v.SetDefault("Influxdb.Token", "")
+	v.SetDefault("Influxdb.Organization", "")
+	v.SetDefault("Influxdb.Bucket", "")
+	v.SetDefault("Influxdb.Tags", []string{"priority", "source", "hostname"})
 	v.SetDefault("Influxdb.MinimumPriority", "")
+	v.SetDefault("Influxdb.ServerName", "")
+	v.SetDefault("Influxdb.DisableSessionTickets", false)
+	v.SetDefault("Influxdb.SessionCacheSize", 0)
 	v.SetDefault("Influxdb.MutualTls", false)
 	v.SetDefault("Influxdb.CheckCert", true)
 	v.SetDefault("Loki.HostPort", "")
 	v.SetDefault("Loki.MinimumPriority", "")
+	v.SetDefault("Loki.ExtraLabels", []string{})
+	v.SetDefault("Loki.MaxLabelValues", 100)
+	v.SetDefault("Loki.ServerName", "")
+	v.SetDefault("Loki.DisableSessionTickets", false)
+	v.SetDefault("Loki.SessionCacheSize", 0)
+	v.SetDefault("Loki.HedgeDelay", 0)
+	v.SetDefault("Loki.CircuitBreakerThreshold", 0)
+	v.SetDefault("Loki.CircuitBreakerCooldown", 30)
+	v.SetDefault("Loki.ConsulService", "")
+	v.SetDefault("Loki.ConsulTags", []string{})
 	v.SetDefault("Loki.MutualTLS", false)
+	v.SetDefault("Loki.EnableCompression", false)
+	v.SetDefault("Loki.CompressionThreshold", 1024)
 	v.SetDefault("Loki.CheckCert", true)
 	v.SetDefault("AWS.AccessKeyID", "")
 	v.SetDefault("AWS.SecretAccessKey", "")
@@ -104,6 +312,10 @@ func getConfig() *types.Configuration {
 	v.SetDefault("AWS.Lambda.Logtype", "Tail")
 	v.SetDefault("AWS.Lambda.MinimumPriority", "")
 	v.SetDefault("AWS.SQS.URL", "")
+	v.SetDefault("AWS.SQS.MessageGroupID", "")
+	v.SetDefault("AWS.SQS.Deduplication", "")
+	v.SetDefault("AWS.SQS.DeduplicationID", "")
+	v.SetDefault("AWS.SQS.BatchSize", 1)
 	v.SetDefault("AWS.SQS.MinimumPriority", "")
 	v.SetDefault("AWS.SNS.TopicArn", "")
 	v.SetDefault("AWS.SNS.MinimumPriority", "")
@@ -111,14 +323,30 @@ func getConfig() *types.Configuration {
 	v.SetDefault("AWS.CloudWatchLogs.LogGroup", "")
 	v.SetDefault("AWS.CloudWatchLogs.LogStream", "")
 	v.SetDefault("AWS.CloudWatchLogs.MinimumPriority", "")
+	v.SetDefault("AWS.Kinesis.StreamName", "")
+	v.SetDefault("AWS.Kinesis.PartitionKey", "{{ .Hostname }}")
+	v.SetDefault("AWS.Kinesis.BatchSize", outputs.KinesisMaxBatchSize)
+	v.SetDefault("AWS.Kinesis.FlushInterval", 5)
+	v.SetDefault("AWS.Kinesis.MaxAttempts", 3)
+	v.SetDefault("AWS.Kinesis.MinimumPriority", "")
 	v.SetDefault("AWS.S3.Bucket", "")
 	v.SetDefault("AWS.S3.Prefix", "falco")
+	v.SetDefault("AWS.S3.RawEventKey", "")
+	v.SetDefault("AWS.S3.Passthrough", false)
+	v.SetDefault("AWS.S3.EncryptionKey", "")
+	v.SetDefault("AWS.S3.EncryptionKeyID", "default")
+	v.SetDefault("AWS.S3.KMSKeyID", "")
 	v.SetDefault("AWS.S3.MinimumPriority", "")
 	v.SetDefault("SMTP.HostPort", "")
+	v.SetDefault("SMTP.TLS", "")
+	v.SetDefault("SMTP.CheckCert", true)
+	v.SetDefault("SMTP.AuthMechanism", "plain")
 	v.SetDefault("SMTP.User", "")
 	v.SetDefault("SMTP.Password", "")
 	v.SetDefault("SMTP.From", "")
 	v.SetDefault("SMTP.To", "")
+	v.SetDefault("SMTP.Cc", "")
+	v.SetDefault("SMTP.Bcc", "")
 	v.SetDefault("SMTP.OutputFormat", "html")
 	v.SetDefault("SMTP.MinimumPriority", "")
 	v.SetDefault("STAN.HostPort", "")
@@ -131,9 +359,57 @@ func getConfig() *types.Configuration {
 	v.SetDefault("NATS.ClientID", "")
 	v.SetDefault("NATS.MutualTls", false)
 	v.SetDefault("NATS.CheckCert", true)
+	v.SetDefault("JetStream.HostPort", "")
+	v.SetDefault("JetStream.Subject", "falco.events")
+	v.SetDefault("JetStream.Stream", "")
+	v.SetDefault("JetStream.CredsFile", "")
+	v.SetDefault("JetStream.MaxReconnects", 60)
+	v.SetDefault("JetStream.AckTimeout", 5000)
+	v.SetDefault("JetStream.MinimumPriority", "")
+	v.SetDefault("JetStream.MutualTls", false)
+	v.SetDefault("JetStream.CheckCert", true)
+	v.SetDefault("MQTT.Broker", "")
+	v.SetDefault("MQTT.Topic", "falco/events")
+	v.SetDefault("MQTT.QOS", 0)
+	v.SetDefault("MQTT.Retained", false)
+	v.SetDefault("MQTT.ClientID", "")
+	v.SetDefault("MQTT.User", "")
+	v.SetDefault("MQTT.Password", "")
+	v.SetDefault("MQTT.AckTimeout", 5000)
+	v.SetDefault("MQTT.MinimumPriority", "")
+	v.SetDefault("MQTT.MutualTls", false)
+	v.SetDefault("MQTT.CheckCert", true)
+	v.SetDefault("Redis.Address", "")
+	v.SetDefault("Redis.Password", "")
+	v.SetDefault("Redis.Database", 0)
+	v.SetDefault("Redis.Mode", outputs.RedisList)
+	v.SetDefault("Redis.Key", "falco")
+	v.SetDefault("Redis.MaxLength", 0)
+	v.SetDefault("Redis.MinimumPriority", "")
+	v.SetDefault("Redis.TLS", false)
+	v.SetDefault("Redis.MutualTLS", false)
+	v.SetDefault("Redis.CheckCert", true)
+	v.SetDefault("Syslog.Address", "")
+	v.SetDefault("Syslog.Transport", outputs.SyslogUDP)
+	v.SetDefault("Syslog.Facility", "local0")
+	v.SetDefault("Syslog.AppName", "falcosidekick")
+	v.SetDefault("Syslog.MinimumPriority", "")
+	v.SetDefault("Syslog.CheckCert", true)
+	v.SetDefault("Syslog.MutualTLS", false)
+	v.SetDefault("GELF.Address", "")
+	v.SetDefault("GELF.Transport", outputs.GELFUDP)
+	v.SetDefault("GELF.ChunkSize", 1420)
+	v.SetDefault("GELF.MinimumPriority", "")
 	v.SetDefault("Opsgenie.Region", "us")
 	v.SetDefault("Opsgenie.APIKey", "")
 	v.SetDefault("Opsgenie.MinimumPriority", "")
+	v.SetDefault("Opsgenie.Alias", "")
+	v.SetDefault("Opsgenie.Tags", []string{})
+	v.SetDefault("Opsgenie.RedactFields", []string{})
+	v.SetDefault("Opsgenie.MaskFields", []string{})
+	v.SetDefault("Opsgenie.RedactPatterns", []string{})
+	v.SetDefault("Opsgenie.MaxRate", "")
+	v.SetDefault("Opsgenie.RateLimitMode", "block")
 	v.SetDefault("Opsgenie.MutualTLS", false)
 	v.SetDefault("Opsgenie.CheckCert", true)
 	v.SetDefault("Statsd.Forwarder", "")
@@ -143,20 +419,90 @@ func getConfig() *types.Configuration {
 	v.SetDefault("Dogstatsd.Tags", []string{})
 	v.SetDefault("Webhook.Address", "")
 	v.SetDefault("Webhook.MinimumPriority", "")
+	v.SetDefault("Webhook.ServerName", "")
+	v.SetDefault("Webhook.DisableSessionTickets", false)
+	v.SetDefault("Webhook.SessionCacheSize", 0)
+	v.SetDefault("Webhook.RequiredFields", []string{})
+	v.SetDefault("Webhook.RequiredFieldsAction", "drop")
+	v.SetDefault("Webhook.Pipeline", []string{})
+	v.SetDefault("Webhook.RedactFields", []string{})
+	v.SetDefault("Webhook.MaskFields", []string{})
+	v.SetDefault("Webhook.RedactPatterns", []string{})
+	v.SetDefault("Webhook.RawEventKey", "")
+	v.SetDefault("Webhook.Passthrough", false)
+	v.SetDefault("Webhook.MessageFormat", "")
+	v.SetDefault("Webhook.RetryAfterPolicy", "")
+	v.SetDefault("Webhook.MaxRate", "")
+	v.SetDefault("Webhook.RateLimitMode", "block")
+	v.SetDefault("Webhook.SchemaVersion", "")
+	v.SetDefault("Webhook.SchemaVersionInPayload", false)
+	v.SetDefault("Webhook.PriorityCase", "asis")
+	v.SetDefault("Webhook.StatusCodeRetries", 3)
+	v.SetDefault("Webhook.MaxRetryDuration", 0)
+	v.SetDefault("Webhook.CircuitBreakerThreshold", 0)
+	v.SetDefault("Webhook.CircuitBreakerCooldown", 30)
+	v.SetDefault("Webhook.HedgeDelay", 0)
+	v.SetDefault("Webhook.ConsulService", "")
+	v.SetDefault("Webhook.ConsulTags", []string{})
+	v.SetDefault("Webhook.Authorization", "")
+	v.SetDefault("Webhook.ProxyAuthorization", "")
+	v.SetDefault("Webhook.AuthMethods", []string{})
+	v.SetDefault("Webhook.OAuth2TokenURL", "")
+	v.SetDefault("Webhook.OAuth2ClientID", "")
+	v.SetDefault("Webhook.OAuth2ClientSecret", "")
+	v.SetDefault("Webhook.OAuth2Scopes", []string{})
 	v.SetDefault("Webhook.MutualTls", false)
+	v.SetDefault("Webhook.MutualTLSCACert", "")
+	v.SetDefault("Webhook.MutualTLSClientCert", "")
+	v.SetDefault("Webhook.MutualTLSClientKey", "")
+	v.SetDefault("Webhook.EnableCompression", false)
+	v.SetDefault("Webhook.CompressionThreshold", 1024)
 	v.SetDefault("Webhook.CheckCert", true)
+	v.SetDefault("Tenants.Field", "k8s.ns.name")
+	v.SetDefault("Tenants.DefaultURL", "")
+	v.SetDefault("Tenants.DefaultToken", "")
+	v.SetDefault("Tenants.MinimumPriority", "")
+	v.SetDefault("Tenants.MutualTLS", false)
+	v.SetDefault("Tenants.CheckCert", true)
+	v.SetDefault("WebSocket.Address", "")
+	v.SetDefault("WebSocket.BufferSize", 1000)
+	v.SetDefault("WebSocket.PingInterval", 30)
+	v.SetDefault("WebSocket.MinimumPriority", "")
+	v.SetDefault("WebSocket.MutualTLS", false)
+	v.SetDefault("WebSocket.CheckCert", true)
+	v.SetDefault("Fifo.Path", "")
+	v.SetDefault("Fifo.MinimumPriority", "")
 	v.SetDefault("CloudEvents.Address", "")
+	v.SetDefault("CloudEvents.Mode", "binary")
+	v.SetDefault("CloudEvents.Type", "falco.rule.output.v1")
+	v.SetDefault("CloudEvents.Source", "falco.org")
 	v.SetDefault("CloudEvents.MinimumPriority", "")
 	v.SetDefault("CloudEvents.MutualTls", false)
 	v.SetDefault("CloudEvents.CheckCert", true)
 	v.SetDefault("Azure.eventHub.Namespace", "")
 	v.SetDefault("Azure.eventHub.Name", "")
 	v.SetDefault("Azure.eventHub.MinimumPriority", "")
+	v.SetDefault("Azure.Blob.AccountName", "")
+	v.SetDefault("Azure.Blob.Container", "")
+	v.SetDefault("Azure.Blob.Endpoint", "")
+	v.SetDefault("Azure.Blob.ConnectionString", "")
+	v.SetDefault("Azure.Blob.SASToken", "")
+	v.SetDefault("Azure.Blob.UseManagedIdentity", false)
+	v.SetDefault("Azure.Blob.KeyFormat", `falcosidekick/{{ .Time.Format "2006-01-02" }}/{{ .Batch }}.ndjson`)
+	v.SetDefault("Azure.Blob.BatchSize", 1048576)
+	v.SetDefault("Azure.Blob.FlushInterval", 60)
+	v.SetDefault("Azure.Blob.AppendMode", false)
+	v.SetDefault("Azure.Blob.CompactFields", false)
+	v.SetDefault("Azure.Blob.RawEventKey", "")
+	v.SetDefault("Azure.Blob.MinimumPriority", "")
 	v.SetDefault("GCP.Credentials", "")
 	v.SetDefault("GCP.PubSub.ProjectID", "")
 	v.SetDefault("GCP.PubSub.Topic", "")
+	v.SetDefault("GCP.PubSub.OrderingKey", "")
+	v.SetDefault("GCP.PubSub.Attributes", []string{})
 	v.SetDefault("GCP.PubSub.MinimumPriority", "")
 	v.SetDefault("GCP.Storage.Prefix", "")
+	v.SetDefault("GCP.Storage.RawEventKey", "")
 	v.SetDefault("GCP.Storage.Bucket", "")
 	v.SetDefault("GCP.Storage.MinimumPriority", "")
 	v.SetDefault("GCP.CloudFunctions.Name", "")
@@ -167,14 +513,34 @@ func getConfig() *types.Configuration {
 	v.SetDefault("Googlechat.WebhookURL", "")
 	v.SetDefault("Googlechat.OutputFormat", "all")
 	v.SetDefault("Googlechat.MessageFormat", "")
+	v.SetDefault("Googlechat.MessageFormatField", "")
+	v.SetDefault("Googlechat.UseCardsV2", false)
+	v.SetDefault("Googlechat.ThreadKey", "")
 	v.SetDefault("Googlechat.MinimumPriority", "")
+	v.SetDefault("Googlechat.RedactFields", []string{})
+	v.SetDefault("Googlechat.MaskFields", []string{})
+	v.SetDefault("Googlechat.RedactPatterns", []string{})
 	v.SetDefault("Googlechat.MutualTls", false)
 	v.SetDefault("Googlechat.CheckCert", true)
 	v.SetDefault("Kafka.HostPort", "")
 	v.SetDefault("Kafka.Topic", "")
+	v.SetDefault("Kafka.TLS", false)
+	v.SetDefault("Kafka.CheckCert", true)
+	v.SetDefault("Kafka.SASLMechanism", "")
+	v.SetDefault("Kafka.Username", "")
+	v.SetDefault("Kafka.Password", "")
+	v.SetDefault("Kafka.MessageKey", "")
+	v.SetDefault("Kafka.RequiredAcks", "all")
+	v.SetDefault("Kafka.Compression", "none")
+	v.SetDefault("Kafka.Passthrough", false)
 	v.SetDefault("Kafka.MinimumPriority", "")
 	v.SetDefault("Pagerduty.RoutingKey", "")
 	v.SetDefault("Pagerduty.MinimumPriority", "")
+	v.SetDefault("Pagerduty.RedactFields", []string{})
+	v.SetDefault("Pagerduty.MaskFields", []string{})
+	v.SetDefault("Pagerduty.RedactPatterns", []string{})
+	v.SetDefault("Pagerduty.MaxRate", "")
+	v.SetDefault("Pagerduty.RateLimitMode", "block")
 	v.SetDefault("Googlechat.MutualTls", false)
 	v.SetDefault("Pagerduty.CheckCert", true)
 	v.SetDefault("Kubeless.Namespace", "")
@@ -208,6 +574,7 @@ func getConfig() *types.Configuration {
 	v.SetDefault("Wavefront.MetricName", "falco.alert")
 	v.SetDefault("Wavefront.EndpointMetricPort", 2878)
 	v.SetDefault("Wavefront.MinimumPriority", "")
+	v.SetDefault("Wavefront.PriorityCase", "asis")
 	v.SetDefault("Wavefront.FlushIntervalSecods", 1)
 	v.SetDefault("Wavefront.BatchSize", 10000)
 
@@ -227,7 +594,30 @@ func getConfig() *types.Configuration {
 	}
 
 	v.GetStringMapString("customfields")
+	v.GetStringMapString("templatedfields")
+	v.GetStringMapString("Metrics.Labels")
+	v.GetStringMapString("Priorities.Aliases")
+	v.GetStringMapString("Dispatch.Dependencies")
+	v.GetStringMapString("Dispatch.Queues")
+	v.GetStringMapString("QuietHours.Windows")
+	v.GetStringMapString("Cooldown.Windows")
+	v.GetStringMapString("Timeout.Outputs")
+	v.GetStringMapString("Sampling.Outputs")
+	v.GetStringMapString("CEL.Fields")
 	v.GetStringMapString("Webhook.CustomHeaders")
+	v.GetStringMapString("Elasticsearch.CustomHeaders")
+	v.GetStringMapString("Loki.CustomHeaders")
+	v.GetStringMapString("Opsgenie.CloseRules")
+	v.GetStringMapString("Opsgenie.Priorities")
+	v.GetStringMapString("Webhook.StatusCodePolicies")
+	v.GetStringMapString("Elasticsearch.StatusCodePolicies")
+	v.GetStringMapString("Webhook.FieldsMapping")
+	v.GetStringMapString("Elasticsearch.FieldsMapping")
+	v.GetStringMapString("Webhook.KeysMapping")
+	v.GetStringMapString("Elasticsearch.KeysMapping")
+	v.GetStringMapString("Tenants.Destinations")
+	v.GetStringMapString("Tenants.Tokens")
+	v.GetStringMapString("WebSocket.CustomHeaders")
 	v.GetStringMapString("CloudEvents.Extensions")
 	if err := v.Unmarshal(c); err != nil {
 		log.Printf("[ERROR] : Error unmarshalling config : %s", err)
@@ -243,12 +633,278 @@ func getConfig() *types.Configuration {
 		}
 	}
 
-	if value, present := os.LookupEnv("WEBHOOK_CUSTOMHEADERS"); present {
-		customfields := strings.Split(value, ",")
-		for _, label := range customfields {
+	if value, present := os.LookupEnv("TEMPLATEDFIELDS"); present {
+		templatedfields := strings.Split(value, ",")
+		for _, label := range templatedfields {
+			tagkeys := strings.SplitN(label, ":", 2)
+			if len(tagkeys) == 2 {
+				c.Templatedfields[tagkeys[0]] = tagkeys[1]
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("SAMPLING_RULES"); present {
+		c.Sampling.Rules = nil
+		for _, label := range strings.Split(value, ",") {
+			i := strings.LastIndex(label, ":")
+			if i < 1 {
+				continue
+			}
+			rate, err := strconv.ParseFloat(label[i+1:], 64)
+			if err != nil {
+				log.Printf("[ERROR] : Bad sampling rate for rule '%v'\n", label[:i])
+				continue
+			}
+			c.Sampling.Rules = append(c.Sampling.Rules, types.SamplingRuleConfig{Rule: label[:i], Rate: rate})
+		}
+	}
+
+	if value, present := os.LookupEnv("SAMPLING_OUTPUTS"); present {
+		for _, label := range strings.Split(value, ",") {
+			tagkeys := strings.SplitN(label, ":", 2)
+			if len(tagkeys) == 2 {
+				c.Sampling.Outputs[tagkeys[0]] = tagkeys[1]
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("SUPPRESSION_RULES"); present {
+		c.Suppression.Rules = nil
+		for _, label := range strings.Split(value, ",") {
+			tagkeys := strings.SplitN(label, ":", 2)
+			if len(tagkeys) != 2 {
+				continue
+			}
+			condition := strings.SplitN(tagkeys[1], "=", 2)
+			if len(condition) != 2 {
+				log.Printf("[ERROR] : Bad suppression condition for reason '%v', must be 'field=value|value'\n", tagkeys[0])
+				continue
+			}
+			c.Suppression.Rules = append(c.Suppression.Rules, types.SuppressionRuleConfig{Reason: tagkeys[0], Field: condition[0], Values: strings.Split(condition[1], "|")})
+		}
+	}
+
+	if value, present := os.LookupEnv("METRICS_LABELS"); present {
+		for _, label := range strings.Split(value, ",") {
+			tagkeys := strings.Split(label, ":")
+			if len(tagkeys) == 2 {
+				c.Metrics.Labels[tagkeys[0]] = tagkeys[1]
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("PRIORITIES_ALIASES"); present {
+		for _, label := range strings.Split(value, ",") {
 			tagkeys := strings.Split(label, ":")
 			if len(tagkeys) == 2 {
-				c.Webhook.CustomHeaders[tagkeys[0]] = tagkeys[1]
+				c.Priorities.Aliases[tagkeys[0]] = tagkeys[1]
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("DISPATCH_DEPENDENCIES"); present {
+		for _, label := range strings.Split(value, ",") {
+			tagkeys := strings.Split(label, ":")
+			if len(tagkeys) == 2 {
+				if d := c.Dispatch.Dependencies[tagkeys[0]]; d != "" {
+					tagkeys[1] = d + "," + tagkeys[1]
+				}
+				c.Dispatch.Dependencies[tagkeys[0]] = tagkeys[1]
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("DISPATCH_QUEUES"); present {
+		for _, label := range strings.Split(value, ",") {
+			tagkeys := strings.SplitN(label, ":", 2)
+			if len(tagkeys) == 2 {
+				c.Dispatch.Queues[tagkeys[0]] = tagkeys[1]
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("LOKI_EXTRALABELS"); present {
+		c.Loki.ExtraLabels = strings.Split(value, ",")
+	}
+
+	if value, present := os.LookupEnv("WEBHOOK_AUTHMETHODS"); present {
+		c.Webhook.AuthMethods = strings.Split(value, ",")
+	}
+
+	if value, present := os.LookupEnv("WEBHOOK_OAUTH2SCOPES"); present {
+		c.Webhook.OAuth2Scopes = strings.Split(value, ",")
+	}
+
+	if value, present := os.LookupEnv("READINESS_OUTPUTS"); present {
+		c.Readiness.Outputs = strings.Split(value, ",")
+	}
+
+	if value, present := os.LookupEnv("DRYRUN_OUTPUTS"); present {
+		c.DryRun.Outputs = strings.Split(value, ",")
+	}
+
+	if value, present := os.LookupEnv("SCHEDULE_ROUTES"); present {
+		c.Schedule.Routes = nil
+		for _, label := range strings.Split(value, ",") {
+			tagkeys := strings.SplitN(label, "=", 2)
+			if len(tagkeys) == 2 {
+				c.Schedule.Routes = append(c.Schedule.Routes, types.ScheduleRouteConfig{Window: tagkeys[0], Outputs: strings.Split(tagkeys[1], "|")})
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("ROUTING_DEFAULT"); present {
+		c.Routing.Default = strings.Split(value, ",")
+	}
+
+	if value, present := os.LookupEnv("OPSGENIE_CLOSERULES"); present {
+		for _, label := range strings.Split(value, ",") {
+			tagkeys := strings.SplitN(label, ":", 2)
+			if len(tagkeys) == 2 {
+				c.Opsgenie.CloseRules[tagkeys[0]] = tagkeys[1]
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("OPSGENIE_PRIORITIES"); present {
+		for _, label := range strings.Split(value, ",") {
+			tagkeys := strings.Split(label, ":")
+			if len(tagkeys) == 2 {
+				c.Opsgenie.Priorities[tagkeys[0]] = tagkeys[1]
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("OPSGENIE_TAGS"); present {
+		c.Opsgenie.Tags = strings.Split(value, ",")
+	}
+
+	if value, present := os.LookupEnv("DATADOGLOGS_TAGS"); present {
+		c.DatadogLogs.Tags = strings.Split(value, ",")
+	}
+
+	if value, present := os.LookupEnv("DATADOGLOGS_FIELDS"); present {
+		c.DatadogLogs.Fields = strings.Split(value, ",")
+	}
+
+	if value, present := os.LookupEnv("DEDUPLICATION_FIELDS"); present {
+		c.Deduplication.Fields = strings.Split(value, ",")
+	}
+
+	if value, present := os.LookupEnv("SUMMARY_OUTPUTS"); present {
+		c.Summary.Outputs = strings.Split(value, ",")
+	}
+
+	if value, present := os.LookupEnv("CEL_FIELDS"); present {
+		// the expressions can have commas, the fields are separated by semicolons
+		for _, label := range strings.Split(value, ";") {
+			tagkeys := strings.SplitN(label, ":", 2)
+			if len(tagkeys) == 2 {
+				c.CEL.Fields[strings.TrimSpace(tagkeys[0])] = tagkeys[1]
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("COOLDOWN_WINDOWS"); present {
+		for _, label := range strings.Split(value, ",") {
+			tagkeys := strings.SplitN(label, ":", 2)
+			if len(tagkeys) == 2 {
+				c.Cooldown.Windows[tagkeys[0]] = tagkeys[1]
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("TIMEOUT_OUTPUTS"); present {
+		for _, label := range strings.Split(value, ",") {
+			tagkeys := strings.SplitN(label, ":", 2)
+			if len(tagkeys) == 2 {
+				c.Timeout.Outputs[tagkeys[0]] = tagkeys[1]
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("FLATTENING_OUTPUTS"); present {
+		c.Flattening.Outputs = strings.Split(value, ",")
+	}
+
+	if value, present := os.LookupEnv("QUIETHOURS_WINDOWS"); present {
+		for _, label := range strings.Split(value, ",") {
+			tagkeys := strings.SplitN(label, ":", 2)
+			if len(tagkeys) == 2 {
+				c.QuietHours.Windows[tagkeys[0]] = tagkeys[1]
+			}
+		}
+	}
+
+	for env, headers := range map[string]map[string]string{"WEBHOOK_CUSTOMHEADERS": c.Webhook.CustomHeaders, "ELASTICSEARCH_CUSTOMHEADERS": c.Elasticsearch.CustomHeaders, "LOKI_CUSTOMHEADERS": c.Loki.CustomHeaders} {
+		if value, present := os.LookupEnv(env); present {
+			// the values can have colons, the headers are separated by commas
+			for _, label := range strings.Split(value, ",") {
+				tagkeys := strings.SplitN(label, ":", 2)
+				if len(tagkeys) == 2 && strings.TrimSpace(tagkeys[0]) != "" {
+					headers[strings.TrimSpace(tagkeys[0])] = strings.TrimSpace(tagkeys[1])
+				}
+			}
+		}
+	}
+
+	for env, policies := range map[string]map[string]string{"WEBHOOK_STATUSCODEPOLICIES": c.Webhook.StatusCodePolicies, "ELASTICSEARCH_STATUSCODEPOLICIES": c.Elasticsearch.StatusCodePolicies} {
+		if value, present := os.LookupEnv(env); present {
+			for _, label := range strings.Split(value, ",") {
+				tagkeys := strings.SplitN(label, ":", 2)
+				if len(tagkeys) == 2 {
+					policies[tagkeys[0]] = tagkeys[1]
+				}
+			}
+		}
+	}
+
+	for env, patterns := range map[string]*[]string{
+		"SLACK_REDACTPATTERNS":         &c.Slack.RedactPatterns,
+		"ROCKETCHAT_REDACTPATTERNS":    &c.Rocketchat.RedactPatterns,
+		"MATTERMOST_REDACTPATTERNS":    &c.Mattermost.RedactPatterns,
+		"TEAMS_REDACTPATTERNS":         &c.Teams.RedactPatterns,
+		"DATADOG_REDACTPATTERNS":       &c.Datadog.RedactPatterns,
+		"DISCORD_REDACTPATTERNS":       &c.Discord.RedactPatterns,
+		"ELASTICSEARCH_REDACTPATTERNS": &c.Elasticsearch.RedactPatterns,
+		"OPSGENIE_REDACTPATTERNS":      &c.Opsgenie.RedactPatterns,
+		"WEBHOOK_REDACTPATTERNS":       &c.Webhook.RedactPatterns,
+		"GOOGLECHAT_REDACTPATTERNS":    &c.Googlechat.RedactPatterns,
+		"PAGERDUTY_REDACTPATTERNS":     &c.Pagerduty.RedactPatterns,
+	} {
+		// the regexps can have commas, they're separated by semicolons
+		if value, present := os.LookupEnv(env); present {
+			*patterns = strings.Split(value, ";")
+		}
+	}
+
+	for env, mapping := range map[string]map[string]string{"WEBHOOK_FIELDSMAPPING": c.Webhook.FieldsMapping, "ELASTICSEARCH_FIELDSMAPPING": c.Elasticsearch.FieldsMapping, "WEBHOOK_KEYSMAPPING": c.Webhook.KeysMapping, "ELASTICSEARCH_KEYSMAPPING": c.Elasticsearch.KeysMapping} {
+		if value, present := os.LookupEnv(env); present {
+			for _, label := range strings.Split(value, ",") {
+				tagkeys := strings.SplitN(label, ":", 2)
+				if len(tagkeys) == 2 {
+					mapping[tagkeys[0]] = tagkeys[1]
+				}
+			}
+		}
+	}
+
+	for env, tenants := range map[string]map[string]string{"TENANTS_DESTINATIONS": c.Tenants.Destinations, "TENANTS_TOKENS": c.Tenants.Tokens} {
+		if value, present := os.LookupEnv(env); present {
+			for _, label := range strings.Split(value, ",") {
+				tagkeys := strings.SplitN(label, ":", 2)
+				if len(tagkeys) == 2 {
+					tenants[strings.ToLower(tagkeys[0])] = tagkeys[1]
+				}
+			}
+		}
+	}
+
+	if value, present := os.LookupEnv("WEBSOCKET_CUSTOMHEADERS"); present {
+		for _, label := range strings.Split(value, ",") {
+			tagkeys := strings.SplitN(label, ":", 2)
+			if len(tagkeys) == 2 {
+				c.WebSocket.CustomHeaders[tagkeys[0]] = tagkeys[1]
 			}
 		}
 	}
@@ -271,26 +927,166 @@ func getConfig() *types.Configuration {
 		log.Fatalf("[ERROR] : Failed to parse ListenAddress")
 	}
 
+	if f := strings.ToLower(c.Log.Format); f != "text" && f != "json" {
+		log.Fatalf("[ERROR] : Bad Log.Format, must be 'text' or 'json'\n")
+	}
+
+	if l := strings.ToLower(c.Log.Level); l != "debug" && l != "info" && l != "warn" && l != "error" {
+		log.Fatalf("[ERROR] : Bad Log.Level, must be 'debug', 'info', 'warn' or 'error'\n")
+	}
+
+	if c.FieldsPrecedence != "custom" && c.FieldsPrecedence != "output" {
+		log.Fatalf("[ERROR] : Bad FieldsPrecedence, must be 'custom' or 'output'\n")
+	}
+
+	if c.FieldsConflict != "overwrite" && c.FieldsConflict != "prefix" {
+		log.Fatalf("[ERROR] : Bad FieldsConflict, must be 'overwrite' or 'prefix'\n")
+	}
+
+	if d, err := time.ParseDuration(c.Timeout.Default); c.Timeout.Default != "" && (err != nil || d < 0) {
+		log.Fatalf("[ERROR] : Bad Timeout.Default, must be a duration (ex: 10s)\n")
+	}
+	for i, j := range c.Timeout.Outputs {
+		if d, err := time.ParseDuration(j); err != nil || d < 0 {
+			log.Fatalf("[ERROR] : Bad timeout '%v' of output '%v', must be a duration (ex: 3s)\n", j, i)
+		}
+	}
+
+	if c.Node.Enabled && c.Node.Field == "" {
+		log.Fatalf("[ERROR] : Node.Field can't be empty\n")
+	}
+
+	if c.Influxdb.HostPort != "" && c.Influxdb.Token != "" && (c.Influxdb.Organization == "" || c.Influxdb.Bucket == "") {
+		log.Fatalf("[ERROR] : Influxdb.Organization and Influxdb.Bucket can't be empty with Influxdb.Token\n")
+	}
+
+	if c.JetStream.HostPort != "" && (c.JetStream.Subject == "" || c.JetStream.AckTimeout <= 0) {
+		log.Fatalf("[ERROR] : JetStream.Subject can't be empty and JetStream.AckTimeout must be positive\n")
+	}
+
+	if c.DatadogLogs.APIKey != "" && (c.DatadogLogs.BatchSize < 1 || c.DatadogLogs.BatchSize > outputs.DatadogLogsMaxBatchSize) {
+		log.Fatalf("[ERROR] : DatadogLogs.BatchSize must be between 1 and %v\n", outputs.DatadogLogsMaxBatchSize)
+	}
+
+	if c.Redis.Address != "" && ((c.Redis.Mode != outputs.RedisList && c.Redis.Mode != outputs.RedisPublish) || c.Redis.Key == "" || c.Redis.MaxLength < 0) {
+		log.Fatalf("[ERROR] : Redis.Mode must be %v or %v, Redis.Key can't be empty and Redis.MaxLength can't be negative\n", outputs.RedisList, outputs.RedisPublish)
+	}
+
+	if _, ok := outputs.SyslogFacilities[c.Syslog.Facility]; c.Syslog.Address != "" && (!ok || (c.Syslog.Transport != outputs.SyslogUDP && c.Syslog.Transport != outputs.SyslogTCP && c.Syslog.Transport != outputs.SyslogTLS)) {
+		log.Fatalf("[ERROR] : Syslog.Transport must be %v, %v or %v and Syslog.Facility a syslog facility (ex: local0)\n", outputs.SyslogUDP, outputs.SyslogTCP, outputs.SyslogTLS)
+	}
+
+	if c.GELF.Address != "" && ((c.GELF.Transport != outputs.GELFUDP && c.GELF.Transport != outputs.GELFTCP) || c.GELF.ChunkSize < 512 || c.GELF.ChunkSize > 65507) {
+		log.Fatalf("[ERROR] : GELF.Transport must be %v or %v and GELF.ChunkSize between 512 and 65507\n", outputs.GELFUDP, outputs.GELFTCP)
+	}
+
+	// the keys of the maps of the config file are lowercased
+	closeRules := make(map[string]string, len(c.Opsgenie.CloseRules))
+	for i, j := range c.Opsgenie.CloseRules {
+		closeRules[strings.ToLower(i)] = j
+	}
+	c.Opsgenie.CloseRules = closeRules
+	if len(c.Opsgenie.CloseRules) != 0 && c.Opsgenie.Alias == "" {
+		log.Fatalf("[ERROR] : Opsgenie.CloseRules require an Opsgenie.Alias\n")
+	}
+	priorities := make(map[string]string, len(c.Opsgenie.Priorities))
+	for i, j := range c.Opsgenie.Priorities {
+		p := types.Priority(i)
+		if p == types.Default || (j != "P1" && j != "P2" && j != "P3" && j != "P4" && j != "P5") {
+			log.Fatalf("[ERROR] : Bad Opsgenie priority '%v' for '%v', Falco priorities must be mapped to P1, P2, P3, P4 or P5\n", j, i)
+		}
+		priorities[strings.ToLower(p.String())] = j
+	}
+	c.Opsgenie.Priorities = priorities
+
+	if c.MQTT.Broker != "" && (c.MQTT.Topic == "" || c.MQTT.QOS < 0 || c.MQTT.QOS > 2 || c.MQTT.AckTimeout <= 0) {
+		log.Fatalf("[ERROR] : MQTT.Topic can't be empty, MQTT.QOS must be 0, 1 or 2 and MQTT.AckTimeout must be positive\n")
+	}
+
+	if c.CloudEvents.Mode != outputs.CloudEventsBinaryMode && c.CloudEvents.Mode != outputs.CloudEventsStructuredMode {
+		log.Fatalf("[ERROR] : CloudEvents.Mode must be '%v' or '%v'\n", outputs.CloudEventsBinaryMode, outputs.CloudEventsStructuredMode)
+	}
+
+	if outputs.IsFIFOQueue(c.AWS.SQS.URL) && (c.AWS.SQS.MessageGroupID == "" || (c.AWS.SQS.Deduplication != outputs.SQSContentBasedDeduplication && c.AWS.SQS.Deduplication != outputs.SQSIDDeduplication)) {
+		log.Fatalf("[ERROR] : AWS.SQS.URL is a FIFO queue, AWS.SQS.MessageGroupID can't be empty and AWS.SQS.Deduplication must be 'contentbased' or 'id'\n")
+	}
+
+	if c.Slack.Token != "" && c.Slack.Channel == "" {
+		log.Fatalf("[ERROR] : Slack.Channel can't be empty with a Slack.Token\n")
+	}
+
+	if c.Slack.ThreadWindow > 0 && c.Slack.Token == "" {
+		log.Fatalf("[ERROR] : Slack.ThreadWindow requires a Slack.Token, the replies need the ts of the messages\n")
+	}
+
+	if c.Webhook.Passthrough && (len(c.Webhook.RedactFields) != 0 || len(c.Webhook.MaskFields) != 0 || len(c.Webhook.RedactPatterns) != 0) {
+		log.Fatalf("[ERROR] : Webhook.Passthrough can't be enabled with Webhook.RedactFields, Webhook.MaskFields or Webhook.RedactPatterns, the events would be posted unredacted\n")
+	}
+
+	if c.Webhook.RawEventKey != "" && (len(c.Webhook.RedactFields) != 0 || len(c.Webhook.MaskFields) != 0 || len(c.Webhook.RedactPatterns) != 0) {
+		log.Fatalf("[ERROR] : Webhook.RawEventKey can't be set with Webhook.RedactFields, Webhook.MaskFields or Webhook.RedactPatterns, the events as received would be posted unredacted\n")
+	}
+
+	if c.AWS.SQS.BatchSize < 1 || c.AWS.SQS.BatchSize > 10 {
+		log.Fatalf("[ERROR] : AWS.SQS.BatchSize must be between 1 and 10\n")
+	}
+
+	if c.AWS.Kinesis.StreamName != "" && (c.AWS.Kinesis.BatchSize < 1 || c.AWS.Kinesis.BatchSize > outputs.KinesisMaxBatchSize || c.AWS.Kinesis.MaxAttempts < 1) {
+		log.Fatalf("[ERROR] : AWS.Kinesis.BatchSize must be between 1 and %v and AWS.Kinesis.MaxAttempts at least 1\n", outputs.KinesisMaxBatchSize)
+	}
+
+	c.Slack.RetryAfterPolicy = checkRetryAfterPolicy("Slack", c.Slack.RetryAfterPolicy)
+	c.Teams.RetryAfterPolicy = checkRetryAfterPolicy("Teams", c.Teams.RetryAfterPolicy)
+	c.Discord.RetryAfterPolicy = checkRetryAfterPolicy("Discord", c.Discord.RetryAfterPolicy)
+	c.Webhook.RetryAfterPolicy = checkRetryAfterPolicy("Webhook", c.Webhook.RetryAfterPolicy)
+	c.Slack.RateLimitMode = checkRateLimitMode("Slack", c.Slack.RateLimitMode)
+	c.Teams.RateLimitMode = checkRateLimitMode("Teams", c.Teams.RateLimitMode)
+	c.Discord.RateLimitMode = checkRateLimitMode("Discord", c.Discord.RateLimitMode)
+	c.Opsgenie.RateLimitMode = checkRateLimitMode("Opsgenie", c.Opsgenie.RateLimitMode)
+	c.Pagerduty.RateLimitMode = checkRateLimitMode("Pagerduty", c.Pagerduty.RateLimitMode)
+	c.Webhook.RateLimitMode = checkRateLimitMode("Webhook", c.Webhook.RateLimitMode)
+	checkRateLimitOutputs(v)
+	c.Webhook.PriorityCase = checkPriorityCase("Webhook", c.Webhook.PriorityCase)
+	c.Webhook.Pipeline = checkPipeline("Webhook", c.Webhook.Pipeline)
+	c.Elasticsearch.Pipeline = checkPipeline("Elasticsearch", c.Elasticsearch.Pipeline)
+	c.Elasticsearch.PriorityCase = checkPriorityCase("Elasticsearch", c.Elasticsearch.PriorityCase)
+	c.Wavefront.PriorityCase = checkPriorityCase("Wavefront", c.Wavefront.PriorityCase)
+
+	c.Priorities.Unknown = checkPriority(c.Priorities.Unknown)
+	c.Sampling.ExemptPriority = checkPriority(c.Sampling.ExemptPriority)
+	c.QuietHours.ExemptPriority = checkPriority(c.QuietHours.ExemptPriority)
+	c.Summary.Priority = checkPriority(c.Summary.Priority)
 	c.Slack.MinimumPriority = checkPriority(c.Slack.MinimumPriority)
 	c.Rocketchat.MinimumPriority = checkPriority(c.Rocketchat.MinimumPriority)
 	c.Mattermost.MinimumPriority = checkPriority(c.Mattermost.MinimumPriority)
 	c.Teams.MinimumPriority = checkPriority(c.Teams.MinimumPriority)
 	c.Datadog.MinimumPriority = checkPriority(c.Datadog.MinimumPriority)
+	c.DatadogLogs.MinimumPriority = checkPriority(c.DatadogLogs.MinimumPriority)
 	c.Alertmanager.MinimumPriority = checkPriority(c.Alertmanager.MinimumPriority)
 	c.Elasticsearch.MinimumPriority = checkPriority(c.Elasticsearch.MinimumPriority)
 	c.Influxdb.MinimumPriority = checkPriority(c.Influxdb.MinimumPriority)
 	c.Loki.MinimumPriority = checkPriority(c.Loki.MinimumPriority)
 	c.Nats.MinimumPriority = checkPriority(c.Nats.MinimumPriority)
+	c.JetStream.MinimumPriority = checkPriority(c.JetStream.MinimumPriority)
+	c.MQTT.MinimumPriority = checkPriority(c.MQTT.MinimumPriority)
+	c.Redis.MinimumPriority = checkPriority(c.Redis.MinimumPriority)
+	c.Syslog.MinimumPriority = checkPriority(c.Syslog.MinimumPriority)
+	c.GELF.MinimumPriority = checkPriority(c.GELF.MinimumPriority)
 	c.Stan.MinimumPriority = checkPriority(c.Stan.MinimumPriority)
 	c.AWS.Lambda.MinimumPriority = checkPriority(c.AWS.Lambda.MinimumPriority)
 	c.AWS.SQS.MinimumPriority = checkPriority(c.AWS.SQS.MinimumPriority)
+	c.AWS.Kinesis.MinimumPriority = checkPriority(c.AWS.Kinesis.MinimumPriority)
 	c.AWS.SNS.MinimumPriority = checkPriority(c.AWS.SNS.MinimumPriority)
 	c.AWS.S3.MinimumPriority = checkPriority(c.AWS.S3.MinimumPriority)
 	c.AWS.CloudWatchLogs.MinimumPriority = checkPriority(c.AWS.CloudWatchLogs.MinimumPriority)
 	c.Opsgenie.MinimumPriority = checkPriority(c.Opsgenie.MinimumPriority)
 	c.Webhook.MinimumPriority = checkPriority(c.Webhook.MinimumPriority)
+	c.Tenants.MinimumPriority = checkPriority(c.Tenants.MinimumPriority)
+	c.WebSocket.MinimumPriority = checkPriority(c.WebSocket.MinimumPriority)
+	c.Fifo.MinimumPriority = checkPriority(c.Fifo.MinimumPriority)
 	c.CloudEvents.MinimumPriority = checkPriority(c.CloudEvents.MinimumPriority)
 	c.Azure.EventHub.MinimumPriority = checkPriority(c.Azure.EventHub.MinimumPriority)
+	c.Azure.Blob.MinimumPriority = checkPriority(c.Azure.Blob.MinimumPriority)
 	c.GCP.PubSub.MinimumPriority = checkPriority(c.GCP.PubSub.MinimumPriority)
 	c.GCP.Storage.MinimumPriority = checkPriority(c.GCP.Storage.MinimumPriority)
 	c.GCP.CloudFunctions.MinimumPriority = checkPriority(c.GCP.CloudFunctions.MinimumPriority)
@@ -303,10 +1099,38 @@ func getConfig() *types.Configuration {
 	c.Rabbitmq.MinimumPriority = checkPriority(c.Rabbitmq.MinimumPriority)
 	c.Wavefront.MinimumPriority = checkPriority(c.Wavefront.MinimumPriority)
 
+	// RedactFields is the former name of the MaskFields of the outputs without redact step
+	c.Slack.MaskFields = append(c.Slack.MaskFields, c.Slack.RedactFields...)
+	c.Rocketchat.MaskFields = append(c.Rocketchat.MaskFields, c.Rocketchat.RedactFields...)
+	c.Mattermost.MaskFields = append(c.Mattermost.MaskFields, c.Mattermost.RedactFields...)
+	c.Teams.MaskFields = append(c.Teams.MaskFields, c.Teams.RedactFields...)
+	c.Datadog.MaskFields = append(c.Datadog.MaskFields, c.Datadog.RedactFields...)
+	c.Discord.MaskFields = append(c.Discord.MaskFields, c.Discord.RedactFields...)
+	c.Opsgenie.MaskFields = append(c.Opsgenie.MaskFields, c.Opsgenie.RedactFields...)
+	c.Googlechat.MaskFields = append(c.Googlechat.MaskFields, c.Googlechat.RedactFields...)
+	c.Pagerduty.MaskFields = append(c.Pagerduty.MaskFields, c.Pagerduty.RedactFields...)
+
 	c.Slack.MessageFormatTemplate = getMessageFormatTemplate("Slack", c.Slack.MessageFormat)
+	c.Slack.MessageFormatTemplates = getMessageFormatTemplates("Slack", c.Slack.MessageFormats)
 	c.Rocketchat.MessageFormatTemplate = getMessageFormatTemplate("Rocketchat", c.Rocketchat.MessageFormat)
+	c.Rocketchat.MessageFormatTemplates = getMessageFormatTemplates("Rocketchat", c.Rocketchat.MessageFormats)
 	c.Mattermost.MessageFormatTemplate = getMessageFormatTemplate("Mattermost", c.Mattermost.MessageFormat)
+	c.Mattermost.MessageFormatTemplates = getMessageFormatTemplates("Mattermost", c.Mattermost.MessageFormats)
 	c.Googlechat.MessageFormatTemplate = getMessageFormatTemplate("Googlechat", c.Googlechat.MessageFormat)
+	c.Googlechat.MessageFormatTemplates = getMessageFormatTemplates("Googlechat", c.Googlechat.MessageFormats)
+	c.Googlechat.ThreadKeyTemplate = getMessageFormatTemplate("Googlechat.ThreadKey", c.Googlechat.ThreadKey)
+	c.Opsgenie.AliasTemplate = getMessageFormatTemplate("Opsgenie.Alias", c.Opsgenie.Alias)
+	c.MQTT.TopicTemplate = getMessageFormatTemplate("MQTT.Topic", c.MQTT.Topic)
+	c.Redis.KeyTemplate = getMessageFormatTemplate("Redis.Key", c.Redis.Key)
+	c.AWS.Kinesis.PartitionKeyTemplate = getMessageFormatTemplate("AWS.Kinesis.PartitionKey", c.AWS.Kinesis.PartitionKey)
+	c.GCP.PubSub.OrderingKeyTemplate = getMessageFormatTemplate("GCP.PubSub.OrderingKey", c.GCP.PubSub.OrderingKey)
+	// the custom headers are parsed by the outputs as the secret files can change them, they stop falcosidekick here too
+	getMessageFormatTemplates("Webhook.CustomHeaders", c.Webhook.CustomHeaders)
+	getMessageFormatTemplates("Elasticsearch.CustomHeaders", c.Elasticsearch.CustomHeaders)
+	getMessageFormatTemplates("Loki.CustomHeaders", c.Loki.CustomHeaders)
+	c.Teams.MessageFormatTemplate = getMessageFormatTemplate("Teams", c.Teams.MessageFormat)
+	c.Teams.MessageFormatTemplates = getMessageFormatTemplates("Teams", c.Teams.MessageFormats)
+	c.Webhook.MessageFormatTemplate = getMessageFormatTemplate("Webhook", c.Webhook.MessageFormat)
 	return c
 }
 
@@ -319,10 +1143,75 @@ func checkPriority(prio string) string {
 	return ""
 }
 
+func checkRetryAfterPolicy(output, policy string) string {
+	switch p := strings.ToLower(policy); p {
+	case "", "queue", "drop":
+		return p
+	default:
+		log.Printf("[ERROR] : %v - Bad RetryAfterPolicy '%v', must be 'queue' or 'drop', pause on Retry-After is disabled\n", output, policy)
+		return ""
+	}
+}
+
+func checkRateLimitMode(output, mode string) string {
+	switch m := strings.ToLower(mode); m {
+	case "block", "drop":
+		return m
+	case "":
+		return "block"
+	default:
+		log.Printf("[ERROR] : %v - Bad RateLimitMode '%v', must be 'block' or 'drop', events over the MaxRate are delayed\n", output, mode)
+		return "block"
+	}
+}
+
+// rateLimitOutputs are the settings of the outputs with a rate limit, lowercased
+var rateLimitOutputs = map[string]bool{"slack": true, "teams": true, "discord": true, "opsgenie": true, "pagerduty": true, "webhook": true}
+
+// checkRateLimitOutputs refuses the MaxRate and the RateLimitMode of the other outputs, they'd be ignored
+func checkRateLimitOutputs(v *viper.Viper) {
+	for _, i := range v.AllKeys() {
+		j := strings.LastIndex(i, ".")
+		if j < 0 || rateLimitOutputs[i[:j]] {
+			continue
+		}
+		if v.IsSet(i[:j]+".maxrate") || v.IsSet(i[:j]+".ratelimitmode") {
+			log.Fatalf("[ERROR] : %v.MaxRate and %v.RateLimitMode aren't supported, only the Slack, Teams, Discord, Opsgenie, Pagerduty and Webhook outputs have a rate limit\n", i[:j], i[:j])
+		}
+	}
+}
+
+func checkPipeline(output string, steps []string) []string {
+	seen := make(map[string]bool, len(steps))
+	pipeline := make([]string, 0, len(steps))
+	for _, i := range steps {
+		s := strings.ToLower(strings.TrimSpace(i))
+		if s != "transform" && s != "redact" && s != "validate" || seen[s] {
+			log.Printf("[ERROR] : %v - Bad Pipeline '%v', steps must be 'transform', 'redact' or 'validate', once each, default order is used\n", output, strings.Join(steps, ","))
+			return nil
+		}
+		seen[s] = true
+		pipeline = append(pipeline, s)
+	}
+	return pipeline
+}
+
+func checkPriorityCase(output, priorityCase string) string {
+	switch p := strings.ToLower(priorityCase); p {
+	case "asis", "lower", "upper":
+		return p
+	case "":
+		return "asis"
+	default:
+		log.Printf("[ERROR] : %v - Bad PriorityCase '%v', must be 'asis', 'lower' or 'upper', priorities are sent as is\n", output, priorityCase)
+		return "asis"
+	}
+}
+
 func getMessageFormatTemplate(output, temp string) *template.Template {
 	if temp != "" {
 		var err error
-		t, err := template.New(output).Parse(temp)
+		t, err := template.New(output).Funcs(outputs.MessageFormatFuncs).Parse(temp)
 		if err != nil {
 			log.Fatalf("[ERROR] : Error compiling %v message template : %v\n", output, err)
 		}
@@ -331,3 +1220,11 @@ func getMessageFormatTemplate(output, temp string) *template.Template {
 
 	return nil
 }
+
+func getMessageFormatTemplates(output string, temps map[string]string) map[string]*template.Template {
+	templates := make(map[string]*template.Template, len(temps))
+	for i, j := range temps {
+		templates[strings.ToLower(i)] = getMessageFormatTemplate(output+"."+i, j)
+	}
+	return templates
+}
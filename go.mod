@@ -7,17 +7,24 @@ require (
 	cloud.google.com/go/pubsub v1.9.1
 	cloud.google.com/go/storage v1.14.0
 	github.com/Azure/azure-event-hubs-go/v3 v3.3.4
+	github.com/Azure/go-autorest/autorest/adal v0.9.5
 	github.com/DataDog/datadog-go v4.2.0+incompatible
 	github.com/PagerDuty/go-pagerduty v1.3.0
+	github.com/alicebob/miniredis/v2 v2.14.3
 	github.com/aws/aws-sdk-go v1.37.22
 	github.com/cloudevents/sdk-go/v2 v2.3.1
+	github.com/eclipse/paho.mqtt.golang v1.3.5
 	github.com/emersion/go-sasl v0.0.0-20200509203442-7bfe0ed36a21
 	github.com/emersion/go-smtp v0.14.0
+	github.com/go-redis/redis/v8 v8.11.4
+	github.com/google/cel-go v0.7.3
 	github.com/google/uuid v1.2.0
 	github.com/googleapis/gax-go v1.0.3
+	github.com/gorilla/websocket v1.4.2
 	github.com/imdario/mergo v0.3.7 // indirect
+	github.com/nats-io/nats-server/v2 v2.2.6
 	github.com/nats-io/nats-streaming-server v0.19.0 // indirect
-	github.com/nats-io/nats.go v1.10.0
+	github.com/nats-io/nats.go v1.11.0
 	github.com/nats-io/stan.go v0.8.3
 	github.com/prometheus/client_golang v1.9.0
 	github.com/segmentio/kafka-go v0.4.10
@@ -25,9 +32,14 @@ require (
 	github.com/streadway/amqp v1.0.0
 	github.com/stretchr/testify v1.7.0
 	github.com/wavefronthq/wavefront-sdk-go v0.9.8
+	github.com/xdg/scram v0.0.0-20180814205039-7eeb5667e42c
 	golang.org/x/oauth2 v0.0.0-20210220000619-9bb904979d93
+	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
+	golang.org/x/time v0.0.0-20201208040808-7e3f01d25324
 	google.golang.org/api v0.40.0
 	google.golang.org/genproto v0.0.0-20210226172003-ab064af71705
+	google.golang.org/grpc v1.35.0
+	google.golang.org/protobuf v1.26.0
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
 	k8s.io/client-go v0.20.4
 )
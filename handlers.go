@@ -10,16 +10,28 @@ import (
 	"strings"
 	"time"
 
+	"github.com/falcosecurity/falcosidekick/outputs"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
-const testRule string = "Test rule"
+const (
+	testRule         string = "Test rule"
+	testPriorityRule string = "Test priority rule" // the test event with a priority is routed like the events of Falco
+)
 
 // mainHandler is Falco Sidekick main handler (default).
 func mainHandler(w http.ResponseWriter, r *http.Request) {
 	stats.Requests.Add("total", 1)
 	nullClient.CountMetric("total", 1, []string{})
 
+	if drainer.Refuse(w) {
+		stats.Requests.Add("rejected", 1)
+		promStats.Inputs.With(map[string]string{"source": "requests", "status": "rejected"}).Inc()
+		nullClient.CountMetric("inputs.requests.rejected", 1, []string{"error:draining"})
+
+		return
+	}
+
 	if r.Body == nil {
 		http.Error(w, "Please send a valid request body", http.StatusBadRequest)
 		stats.Requests.Add("rejected", 1)
@@ -38,7 +50,20 @@ func mainHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	falcopayload, err := newFalcoPayload(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = outputs.CheckJSON(body)
+	}
+	if err != nil {
+		malformedInputs.Reject(w, "requests", body, err)
+		stats.Requests.Add("rejected", 1)
+		promStats.Inputs.With(map[string]string{"source": "requests", "status": "rejected"}).Inc()
+		nullClient.CountMetric("inputs.requests.rejected", 1, []string{"error:malformedjson"})
+
+		return
+	}
+
+	falcopayload, err := newFalcoPayload(bytes.NewReader(body))
 	if err != nil || len(falcopayload.Output) == 0 {
 		http.Error(w, "Please send a valid request body", http.StatusBadRequest)
 		stats.Requests.Add("rejected", 1)
@@ -51,9 +76,59 @@ func mainHandler(w http.ResponseWriter, r *http.Request) {
 	nullClient.CountMetric("inputs.requests.accepted", 1, []string{})
 	stats.Requests.Add("accepted", 1)
 	promStats.Inputs.With(map[string]string{"source": "requests", "status": "accepted"}).Inc()
+	if reorderer != nil {
+		reorderer.Add(falcopayload)
+		return
+	}
+	if correlator != nil {
+		correlator.Add(falcopayload)
+		return
+	}
 	forwardEvent(falcopayload)
 }
 
+// kafkaInputHandler handles the events consumed from Kafka like mainHandler, it returns once they're sent to the outputs
+// with an error if some of them failed, so the message isn't committed. The invalid events aren't retried, and the
+// events kept by the reordering or the correlation are sent later so they can't be retried.
+func kafkaInputHandler(message []byte) error {
+	stats.KafkaInput.Add("total", 1)
+
+	if err := outputs.CheckJSON(message); err != nil {
+		log.Printf("[ERROR] : Kafka input - Malformed event : %v\n", err)
+		malformedInputs.Add("kafka", message, err)
+		stats.KafkaInput.Add("rejected", 1)
+		promStats.Inputs.With(map[string]string{"source": "kafka", "status": "rejected"}).Inc()
+		nullClient.CountMetric("inputs.kafka.rejected", 1, []string{"error:malformedjson"})
+
+		return nil
+	}
+
+	falcopayload, err := newFalcoPayload(bytes.NewReader(message))
+	if err != nil || len(falcopayload.Output) == 0 {
+		log.Printf("[ERROR] : Kafka input - Invalid event : %s\n", message)
+		stats.KafkaInput.Add("rejected", 1)
+		promStats.Inputs.With(map[string]string{"source": "kafka", "status": "rejected"}).Inc()
+		nullClient.CountMetric("inputs.kafka.rejected", 1, []string{"error:invalidjson"})
+
+		return nil
+	}
+
+	nullClient.CountMetric("inputs.kafka.accepted", 1, []string{})
+	stats.KafkaInput.Add("accepted", 1)
+	promStats.Inputs.With(map[string]string{"source": "kafka", "status": "accepted"}).Inc()
+	if reorderer != nil {
+		reorderer.Add(falcopayload)
+		return nil
+	}
+	if correlator != nil {
+		correlator.Add(falcopayload)
+		return nil
+	}
+	x := forwardEvent(falcopayload)
+	x.Wait()
+	return x.Err()
+}
+
 // pingHandler is a simple handler to test if daemon is UP.
 func pingHandler(w http.ResponseWriter, r *http.Request) {
 	// #nosec G104 nothing to be done if the following fails
@@ -67,33 +142,68 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status": "ok"}`))
 }
 
-// testHandler sends a test event to all enabled outputs.
-func testHandler(w http.ResponseWriter, r *http.Request) {
-	r.Body = ioutil.NopCloser(bytes.NewReader([]byte(`{"output":"This is a test from falcosidekick","priority":"Debug","rule":"Test rule", "time":"` + time.Now().UTC().Format(time.RFC3339) + `","outputfields": {"proc.name":"falcosidekick","user.name":"falcosidekick"}}`)))
-	mainHandler(w, r)
+// sendTestEvent sends the test event through the pipeline, at the priority if it isn't Default, the Dispatch returned
+// is nil if the event isn't sent
+func sendTestEvent(priority types.PriorityType) *outputs.Dispatch {
+	falcopayload, err := newFalcoPayload(strings.NewReader(newTestEvent(priority)))
+	if err != nil {
+		log.Printf("[ERROR] : Test event - %v\n", err)
+		return nil
+	}
+	return forwardEvent(falcopayload)
+}
+
+// newTestEvent returns the test event, at the current time, with the Debug priority and the rule bypassing the
+// filters of the outputs if priority is Default
+func newTestEvent(priority types.PriorityType) string {
+	rule := testPriorityRule
+	if priority == types.Default {
+		priority, rule = types.Debug, testRule
+	}
+	return `{"output":"This is a test from falcosidekick","priority":"` + priority.String() + `","rule":"` + rule + `", "time":"` + time.Now().UTC().Format(time.RFC3339) + `","outputfields": {"proc.name":"falcosidekick","user.name":"falcosidekick"}}`
 }
 
 func newFalcoPayload(payload io.Reader) (types.FalcoPayload, error) {
 	var falcopayload types.FalcoPayload
 
-	d := json.NewDecoder(payload)
+	raw, err := ioutil.ReadAll(payload)
+	if err != nil {
+		return types.FalcoPayload{}, err
+	}
+	d := json.NewDecoder(bytes.NewReader(raw))
 	d.UseNumber()
 
-	err := d.Decode(&falcopayload)
+	err = d.Decode(&falcopayload)
 	if err != nil {
 		return types.FalcoPayload{}, err
 	}
+	falcopayload.RawPayload = raw
 
-	// falcopayload.OutputFields = make(map[string]interface{})
-	if len(config.Customfields) > 0 {
-		if falcopayload.OutputFields == nil {
-			falcopayload.OutputFields = make(map[string]interface{})
-		}
-		for key, value := range config.Customfields {
-			falcopayload.OutputFields[key] = value
+	if config.IngestLatency.Enabled {
+		latency := outputs.AddIngestLatency(&falcopayload, time.Now())
+		if latency < 0 {
+			log.Printf("[INFO]  : Event time is %v in the future, check the clocks of Falco and Falcosidekick\n", -latency)
+		} else if promStats.IngestLatency != nil {
+			promStats.IngestLatency.Observe(latency.Seconds())
 		}
 	}
 
+	if err := fieldsMerger.Merge(&falcopayload); err != nil {
+		log.Printf("[ERROR] : Templated fields - %v\n", err)
+	}
+
+	if enricher != nil {
+		enricher.Enrich(&falcopayload)
+	}
+
+	if config.Node.Enabled {
+		outputs.AddNodeField(&falcopayload, config.Node.Field, config.Node.Sources, config.Node.Default)
+	}
+
+	if runbooks != nil {
+		runbooks.Attach(&falcopayload)
+	}
+
 	var kn, kp string
 	for i, j := range falcopayload.OutputFields {
 		if i == "k8s.ns.name" {
@@ -106,7 +216,9 @@ func newFalcoPayload(payload io.Reader) (types.FalcoPayload, error) {
 
 	nullClient.CountMetric("falco.accepted", 1, []string{"priority:" + falcopayload.Priority.String()})
 	stats.Falco.Add(strings.ToLower(falcopayload.Priority.String()), 1)
-	promStats.Falco.With(map[string]string{"rule": falcopayload.Rule, "priority": falcopayload.Priority.String(), "k8s_ns_name": kn, "k8s_pod_name": kp}).Inc()
+	labels := metricLabels.Values(falcopayload)
+	labels["rule"], labels["priority"], labels["k8s_ns_name"], labels["k8s_pod_name"] = falcopayload.Rule, falcopayload.Priority.String(), kn, kp
+	promStats.Falco.With(labels).Inc()
 
 	if config.Debug == true {
 		body, _ := json.Marshal(falcopayload)
@@ -116,140 +228,251 @@ func newFalcoPayload(payload io.Reader) (types.FalcoPayload, error) {
 	return falcopayload, nil
 }
 
-func forwardEvent(falcopayload types.FalcoPayload) {
-	if config.Slack.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Slack.MinimumPriority) || falcopayload.Rule == testRule) {
-		go slackClient.SlackPost(falcopayload)
+// forwardEvent sends the event to the outputs selected for it, the Dispatch returned is nil if the event isn't sent
+func forwardEvent(falcopayload types.FalcoPayload) *outputs.Dispatch {
+	if escalator != nil {
+		escalator.Escalate(&falcopayload)
 	}
 
-	if config.Rocketchat.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Rocketchat.MinimumPriority) || falcopayload.Rule == testRule) {
-		go rocketchatClient.RocketchatPost(falcopayload)
+	if celExpressions != nil && !celExpressions.Apply(&falcopayload) {
+		if config.Debug {
+			log.Printf("[DEBUG] : CEL - event for rule '%v' is dropped by the filter\n", falcopayload.Rule)
+		}
+		return nil
+	}
+
+	if summarizer != nil {
+		summarizer.Add(falcopayload)
+	}
+
+	if falcopayload.Rule != testRule && deduplicator.Suppress(falcopayload) {
+		return nil
+	}
+
+	return routeEvent(falcopayload)
+}
+
+// routeEvent sends the event to the outputs selected by the sampling, the policy, the routes and the schedule
+func routeEvent(falcopayload types.FalcoPayload) *outputs.Dispatch {
+	if sampler != nil && !sampler.Keep(falcopayload) {
+		return nil
+	}
+
+	var targets outputs.OutputSelection
+	if policyClient != nil {
+		var allowed bool
+		targets, allowed = policyClient.Apply(&falcopayload)
+		if !allowed {
+			if config.Debug {
+				log.Printf("[DEBUG] : OPA - event for rule '%v' is dropped by the policy\n", falcopayload.Rule)
+			}
+			return nil
+		}
+	}
+
+	// the outputs of the routes are restricted to the ones of the schedule
+	if targets == nil && falcopayload.Rule != testRule {
+		if router != nil {
+			targets = router.Route(falcopayload)
+		}
+		if schedule != nil {
+			targets = targets.Intersect(schedule.Route(falcopayload))
+		}
+	}
+
+	return dispatchEvent(falcopayload, targets)
+}
+
+// dispatchEvent sends the event to the targets, the Dispatch returned is finished once all the outputs have been called
+func dispatchEvent(falcopayload types.FalcoPayload, targets outputs.OutputSelection) *outputs.Dispatch {
+	dispatch := dispatcher.NewDispatch(falcopayload)
+
+	if (config.Slack.WebhookURL != "" || config.Slack.Token != "") && targets.Has("Slack") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Slack", config.Slack.MinimumPriority)) {
+		dispatch.Add("Slack", slackClient.Redaction.Output(slackClient.SlackPost))
+	}
+
+	if config.Rocketchat.WebhookURL != "" && targets.Has("Rocketchat") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Rocketchat", config.Rocketchat.MinimumPriority)) {
+		dispatch.Add("Rocketchat", rocketchatClient.Redaction.Output(rocketchatClient.RocketchatPost))
 	}
 
-	if config.Mattermost.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Mattermost.MinimumPriority) || falcopayload.Rule == testRule) {
-		go mattermostClient.MattermostPost(falcopayload)
+	if config.Mattermost.WebhookURL != "" && targets.Has("Mattermost") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Mattermost", config.Mattermost.MinimumPriority)) {
+		dispatch.Add("Mattermost", mattermostClient.Redaction.Output(mattermostClient.MattermostPost))
 	}
 
-	if config.Teams.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Teams.MinimumPriority) || falcopayload.Rule == testRule) {
-		go teamsClient.TeamsPost(falcopayload)
+	if config.Teams.WebhookURL != "" && targets.Has("Teams") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Teams", config.Teams.MinimumPriority)) {
+		dispatch.Add("Teams", teamsClient.Redaction.Output(teamsClient.TeamsPost))
 	}
 
-	if config.Datadog.APIKey != "" && (falcopayload.Priority >= types.Priority(config.Datadog.MinimumPriority) || falcopayload.Rule == testRule) {
-		go datadogClient.DatadogPost(falcopayload)
+	if config.Datadog.APIKey != "" && targets.Has("Datadog") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Datadog", config.Datadog.MinimumPriority)) {
+		dispatch.Add("Datadog", datadogClient.Redaction.Output(datadogClient.DatadogPost))
 	}
 
-	if config.Discord.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Discord.MinimumPriority) || falcopayload.Rule == testRule) {
-		go discordClient.DiscordPost(falcopayload)
+	if config.DatadogLogs.APIKey != "" && targets.Has("DatadogLogs") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("DatadogLogs", config.DatadogLogs.MinimumPriority)) {
+		dispatch.Add("DatadogLogs", outputs.OutputFunc(datadogLogsClient.DatadogLogsPost))
 	}
 
-	if config.Alertmanager.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Alertmanager.MinimumPriority) || falcopayload.Rule == testRule) {
-		go alertmanagerClient.AlertmanagerPost(falcopayload)
+	if config.Discord.WebhookURL != "" && targets.Has("Discord") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Discord", config.Discord.MinimumPriority)) {
+		dispatch.Add("Discord", discordClient.Redaction.Output(discordClient.DiscordPost))
 	}
 
-	if config.Elasticsearch.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Elasticsearch.MinimumPriority) || falcopayload.Rule == testRule) {
-		go elasticsearchClient.ElasticsearchPost(falcopayload)
+	if config.Alertmanager.HostPort != "" && targets.Has("AlertManager") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("AlertManager", config.Alertmanager.MinimumPriority)) {
+		dispatch.Add("AlertManager", outputs.OutputFunc(alertmanagerClient.AlertmanagerPost))
 	}
 
-	if config.Influxdb.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Influxdb.MinimumPriority) || falcopayload.Rule == testRule) {
-		go influxdbClient.InfluxdbPost(falcopayload)
+	if config.Elasticsearch.HostPort != "" && targets.Has("Elasticsearch") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Elasticsearch", config.Elasticsearch.MinimumPriority)) {
+		dispatch.Add("Elasticsearch", outputs.OutputFunc(elasticsearchClient.ElasticsearchPost))
 	}
 
-	if config.Loki.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Loki.MinimumPriority) || falcopayload.Rule == testRule) {
-		go lokiClient.LokiPost(falcopayload)
+	if config.Influxdb.HostPort != "" && targets.Has("Influxdb") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Influxdb", config.Influxdb.MinimumPriority)) {
+		dispatch.Add("Influxdb", outputs.OutputFunc(influxdbClient.InfluxdbPost))
 	}
 
-	if config.Nats.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Nats.MinimumPriority) || falcopayload.Rule == testRule) {
-		go natsClient.NatsPublish(falcopayload)
+	if config.Loki.HostPort != "" && targets.Has("Loki") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Loki", config.Loki.MinimumPriority)) {
+		dispatch.Add("Loki", outputs.OutputFunc(lokiClient.LokiPost))
 	}
 
-	if config.Stan.HostPort != "" && config.Stan.ClusterID != "" && config.Stan.ClientID != "" && (falcopayload.Priority >= types.Priority(config.Stan.MinimumPriority) || falcopayload.Rule == testRule) {
-		go stanClient.StanPublish(falcopayload)
+	if config.Nats.HostPort != "" && targets.Has("NATS") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("NATS", config.Nats.MinimumPriority)) {
+		dispatch.Add("NATS", outputs.OutputFunc(natsClient.NatsPublish))
 	}
 
-	if config.AWS.Lambda.FunctionName != "" && (falcopayload.Priority >= types.Priority(config.AWS.Lambda.MinimumPriority) || falcopayload.Rule == testRule) {
-		go awsClient.InvokeLambda(falcopayload)
+	if config.Stan.HostPort != "" && config.Stan.ClusterID != "" && config.Stan.ClientID != "" && targets.Has("STAN") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("STAN", config.Stan.MinimumPriority)) {
+		dispatch.Add("STAN", outputs.OutputFunc(stanClient.StanPublish))
 	}
 
-	if config.AWS.SQS.URL != "" && (falcopayload.Priority >= types.Priority(config.AWS.SQS.MinimumPriority) || falcopayload.Rule == testRule) {
-		go awsClient.SendMessage(falcopayload)
+	if config.JetStream.HostPort != "" && config.JetStream.Stream != "" && targets.Has("JetStream") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("JetStream", config.JetStream.MinimumPriority)) {
+		dispatch.Add("JetStream", outputs.OutputFunc(jetstreamClient.JetStreamPublish))
 	}
 
-	if config.AWS.SNS.TopicArn != "" && (falcopayload.Priority >= types.Priority(config.AWS.SNS.MinimumPriority) || falcopayload.Rule == testRule) {
-		go awsClient.PublishTopic(falcopayload)
+	if config.MQTT.Broker != "" && targets.Has("MQTT") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("MQTT", config.MQTT.MinimumPriority)) {
+		dispatch.Add("MQTT", outputs.OutputFunc(mqttClient.MQTTPublish))
 	}
 
-	if config.AWS.CloudWatchLogs.LogGroup != "" && (falcopayload.Priority >= types.Priority(config.AWS.CloudWatchLogs.MinimumPriority) || falcopayload.Rule == testRule) {
-		go awsClient.SendCloudWatchLog(falcopayload)
+	if config.Redis.Address != "" && targets.Has("Redis") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Redis", config.Redis.MinimumPriority)) {
+		dispatch.Add("Redis", outputs.OutputFunc(redisClient.RedisPost))
 	}
 
-	if config.AWS.S3.Bucket != "" && (falcopayload.Priority >= types.Priority(config.AWS.S3.MinimumPriority) || falcopayload.Rule == testRule) {
-		go awsClient.UploadS3(falcopayload)
+	if config.Syslog.Address != "" && targets.Has("Syslog") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Syslog", config.Syslog.MinimumPriority)) {
+		dispatch.Add("Syslog", outputs.OutputFunc(syslogClient.SyslogPost))
 	}
 
-	if config.SMTP.HostPort != "" && (falcopayload.Priority >= types.Priority(config.SMTP.MinimumPriority) || falcopayload.Rule == testRule) {
-		go smtpClient.SendMail(falcopayload)
+	if config.GELF.Address != "" && targets.Has("GELF") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("GELF", config.GELF.MinimumPriority)) {
+		dispatch.Add("GELF", outputs.OutputFunc(gelfClient.GELFPost))
 	}
 
-	if config.Opsgenie.APIKey != "" && (falcopayload.Priority >= types.Priority(config.Opsgenie.MinimumPriority) || falcopayload.Rule == testRule) {
-		go opsgenieClient.OpsgeniePost(falcopayload)
+	if config.AWS.Lambda.FunctionName != "" && targets.Has("AWSLambda") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("AWSLambda", config.AWS.Lambda.MinimumPriority)) {
+		dispatch.Add("AWSLambda", outputs.OutputFunc(awsClient.InvokeLambda))
 	}
 
-	if config.Webhook.Address != "" && (falcopayload.Priority >= types.Priority(config.Webhook.MinimumPriority) || falcopayload.Rule == testRule) {
-		go webhookClient.WebhookPost(falcopayload)
+	if config.AWS.SQS.URL != "" && targets.Has("AWSSQS") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("AWSSQS", config.AWS.SQS.MinimumPriority)) {
+		dispatch.Add("AWSSQS", outputs.OutputFunc(awsClient.SendMessage))
 	}
 
-	if config.CloudEvents.Address != "" && (falcopayload.Priority >= types.Priority(config.CloudEvents.MinimumPriority) || falcopayload.Rule == testRule) {
-		go cloudeventsClient.CloudEventsSend(falcopayload)
+	if config.AWS.Kinesis.StreamName != "" && targets.Has("AWSKinesis") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("AWSKinesis", config.AWS.Kinesis.MinimumPriority)) {
+		dispatch.Add("AWSKinesis", outputs.OutputFunc(awsClient.PutRecord))
 	}
 
-	if config.Azure.EventHub.Name != "" && (falcopayload.Priority >= types.Priority(config.Azure.EventHub.MinimumPriority) || falcopayload.Rule == testRule) {
-		go azureClient.EventHubPost(falcopayload)
+	if config.AWS.SNS.TopicArn != "" && targets.Has("AWSSNS") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("AWSSNS", config.AWS.SNS.MinimumPriority)) {
+		dispatch.Add("AWSSNS", outputs.OutputFunc(awsClient.PublishTopic))
 	}
 
-	if config.GCP.PubSub.ProjectID != "" && config.GCP.PubSub.Topic != "" && (falcopayload.Priority >= types.Priority(config.GCP.PubSub.MinimumPriority) || falcopayload.Rule == testRule) {
-		go gcpClient.GCPPublishTopic(falcopayload)
+	if config.AWS.CloudWatchLogs.LogGroup != "" && targets.Has("AWSCloudWatchLogs") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("AWSCloudWatchLogs", config.AWS.CloudWatchLogs.MinimumPriority)) {
+		dispatch.Add("AWSCloudWatchLogs", outputs.OutputFunc(awsClient.SendCloudWatchLog))
 	}
 
-	if config.GCP.CloudFunctions.Name != "" && (falcopayload.Priority >= types.Priority(config.GCP.CloudFunctions.MinimumPriority) || falcopayload.Rule == testRule) {
-		go gcpClient.GCPCallCloudFunction(falcopayload)
+	if config.AWS.S3.Bucket != "" && targets.Has("AWSS3") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("AWSS3", config.AWS.S3.MinimumPriority)) {
+		dispatch.Add("AWSS3", outputs.OutputFunc(awsClient.UploadS3))
 	}
 
-	if config.GCP.CloudRun.Endpoint != "" && (falcopayload.Priority >= types.Priority(config.GCP.CloudRun.MinimumPriority) || falcopayload.Rule == testRule) {
-		go gcpCloudRunClient.CloudRunFunctionPost(falcopayload)
+	if config.SMTP.HostPort != "" && targets.Has("SMTP") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("SMTP", config.SMTP.MinimumPriority)) {
+		dispatch.Add("SMTP", outputs.OutputFunc(smtpClient.SendMail))
 	}
 
-	if config.GCP.Storage.Bucket != "" && (falcopayload.Priority >= types.Priority(config.GCP.Storage.MinimumPriority) || falcopayload.Rule == testRule) {
-		go gcpClient.UploadGCS(falcopayload)
+	if config.Opsgenie.APIKey != "" && targets.Has("Opsgenie") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Opsgenie", config.Opsgenie.MinimumPriority)) {
+		dispatch.Add("Opsgenie", opsgenieClient.Redaction.Output(opsgenieClient.OpsgeniePost))
 	}
 
-	if config.Googlechat.WebhookURL != "" && (falcopayload.Priority >= types.Priority(config.Googlechat.MinimumPriority) || falcopayload.Rule == testRule) {
-		go googleChatClient.GooglechatPost(falcopayload)
+	if config.Webhook.Address != "" && targets.Has("Webhook") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Webhook", config.Webhook.MinimumPriority)) {
+		dispatch.Add("Webhook", outputs.OutputFunc(webhookClient.WebhookPost))
 	}
 
-	if config.Kafka.HostPort != "" && (falcopayload.Priority >= types.Priority(config.Kafka.MinimumPriority) || falcopayload.Rule == testRule) {
-		go kafkaClient.KafkaProduce(falcopayload)
+	if (len(config.Tenants.Destinations) != 0 || config.Tenants.DefaultURL != "") && targets.Has("Tenants") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Tenants", config.Tenants.MinimumPriority)) {
+		dispatch.Add("Tenants", outputs.OutputFunc(tenantRouter.TenantsPost))
 	}
 
-	if config.Pagerduty.RoutingKey != "" && (falcopayload.Priority >= types.Priority(config.Pagerduty.MinimumPriority) || falcopayload.Rule == testRule) {
-		go pagerdutyClient.PagerdutyPost(falcopayload)
+	if config.WebSocket.Address != "" && targets.Has("WebSocket") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("WebSocket", config.WebSocket.MinimumPriority)) {
+		dispatch.Add("WebSocket", outputs.OutputFunc(webSocketClient.WebSocketPost))
 	}
 
-	if config.Kubeless.Namespace != "" && config.Kubeless.Function != "" && (falcopayload.Priority >= types.Priority(config.Kubeless.MinimumPriority) || falcopayload.Rule == testRule) {
-		go kubelessClient.KubelessCall(falcopayload)
+	if config.Fifo.Path != "" && targets.Has("Fifo") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Fifo", config.Fifo.MinimumPriority)) {
+		dispatch.Add("Fifo", outputs.OutputFunc(fifoClient.FifoPost))
 	}
 
-	if config.Openfaas.FunctionName != "" && (falcopayload.Priority >= types.Priority(config.Openfaas.MinimumPriority) || falcopayload.Rule == testRule) {
-		go openfaasClient.OpenfaasCall(falcopayload)
+	if config.CloudEvents.Address != "" && targets.Has("CloudEvents") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("CloudEvents", config.CloudEvents.MinimumPriority)) {
+		dispatch.Add("CloudEvents", outputs.OutputFunc(cloudeventsClient.CloudEventsSend))
 	}
 
-	if config.Rabbitmq.URL != "" && config.Rabbitmq.Queue != "" && (falcopayload.Priority >= types.Priority(config.Openfaas.MinimumPriority) || falcopayload.Rule == testRule) {
-		go rabbitmqClient.Publish(falcopayload)
+	if config.Azure.EventHub.Name != "" && targets.Has("EventHub") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("EventHub", config.Azure.EventHub.MinimumPriority)) {
+		dispatch.Add("EventHub", outputs.OutputFunc(azureClient.EventHubPost))
 	}
 
-	if config.Wavefront.EndpointHost != "" && config.Wavefront.EndpointType != "" && (falcopayload.Priority >= types.Priority(config.Wavefront.MinimumPriority) || falcopayload.Rule == testRule) {
-		go wavefrontClient.WavefrontPost(falcopayload)
+	if config.Azure.Blob.Container != "" && targets.Has("AzureBlob") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("AzureBlob", config.Azure.Blob.MinimumPriority)) {
+		dispatch.Add("AzureBlob", outputs.OutputFunc(azureBlobClient.AzureBlobPost))
 	}
 
-	if config.WebUI.URL != "" {
-		go webUIClient.WebUIPost(falcopayload)
+	if config.GCP.PubSub.ProjectID != "" && config.GCP.PubSub.Topic != "" && targets.Has("GCPPubSub") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("GCPPubSub", config.GCP.PubSub.MinimumPriority)) {
+		dispatch.Add("GCPPubSub", outputs.OutputFunc(gcpClient.GCPPublishTopic))
 	}
+
+	if config.GCP.CloudFunctions.Name != "" && targets.Has("GCPCloudFunctions") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("GCPCloudFunctions", config.GCP.CloudFunctions.MinimumPriority)) {
+		dispatch.Add("GCPCloudFunctions", outputs.OutputFunc(gcpClient.GCPCallCloudFunction))
+	}
+
+	if config.GCP.CloudRun.Endpoint != "" && targets.Has("GCPCloudRun") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("GCPCloudRun", config.GCP.CloudRun.MinimumPriority)) {
+		dispatch.Add("GCPCloudRun", outputs.OutputFunc(gcpCloudRunClient.CloudRunFunctionPost))
+	}
+
+	if config.GCP.Storage.Bucket != "" && targets.Has("GCPStorage") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("GCPStorage", config.GCP.Storage.MinimumPriority)) {
+		dispatch.Add("GCPStorage", outputs.OutputFunc(gcpClient.UploadGCS))
+	}
+
+	if config.Googlechat.WebhookURL != "" && targets.Has("Google Chat") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Google Chat", config.Googlechat.MinimumPriority)) {
+		dispatch.Add("Google Chat", googleChatClient.Redaction.Output(googleChatClient.GooglechatPost))
+	}
+
+	if config.Kafka.HostPort != "" && targets.Has("Kafka") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Kafka", config.Kafka.MinimumPriority)) {
+		dispatch.Add("Kafka", outputs.OutputFunc(kafkaClient.KafkaProduce))
+	}
+
+	if config.Pagerduty.RoutingKey != "" && targets.Has("Pagerduty") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Pagerduty", config.Pagerduty.MinimumPriority)) {
+		dispatch.Add("Pagerduty", pagerdutyClient.Redaction.Output(pagerdutyClient.PagerdutyPost))
+	}
+
+	if config.Kubeless.Namespace != "" && config.Kubeless.Function != "" && targets.Has("Kubeless") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Kubeless", config.Kubeless.MinimumPriority)) {
+		dispatch.Add("Kubeless", outputs.OutputFunc(kubelessClient.KubelessCall))
+	}
+
+	if config.Openfaas.FunctionName != "" && targets.Has("OpenFaaS") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("OpenFaaS", config.Openfaas.MinimumPriority)) {
+		dispatch.Add("OpenFaaS", outputs.OutputFunc(openfaasClient.OpenfaasCall))
+	}
+
+	if config.Rabbitmq.URL != "" && config.Rabbitmq.Queue != "" && targets.Has("RabbitMQ") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("RabbitMQ", config.Rabbitmq.MinimumPriority)) {
+		dispatch.Add("RabbitMQ", outputs.OutputFunc(rabbitmqClient.Publish))
+	}
+
+	if config.Wavefront.EndpointHost != "" && config.Wavefront.EndpointType != "" && targets.Has("Wavefront") && (falcopayload.Rule == testRule || dispatch.HasMinimumPriority("Wavefront", config.Wavefront.MinimumPriority)) {
+		dispatch.Add("Wavefront", outputs.OutputFunc(wavefrontClient.WavefrontPost))
+	}
+
+	if config.WebUI.URL != "" && targets.Has("WebUI") {
+		dispatch.Add("WebUI", outputs.OutputFunc(webUIClient.WebUIPost))
+	}
+
+	for _, i := range registeredOutputs {
+		if targets.Has(i.Name) {
+			dispatch.Add(i.Name, i.Output)
+		}
+	}
+
+	dispatch.Run()
+	return dispatch
 }
@@ -1,19 +1,39 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/DataDog/datadog-go/statsd"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/time/rate"
 
 	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/routing"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
+// Version and Commit are set at build time with -ldflags "-X main.Version=... -X main.Commit=..."
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
 // Globale variables
 var (
 	nullClient          *outputs.Client
@@ -22,6 +42,7 @@ var (
 	mattermostClient    *outputs.Client
 	teamsClient         *outputs.Client
 	datadogClient       *outputs.Client
+	datadogLogsClient   *outputs.Client
 	discordClient       *outputs.Client
 	alertmanagerClient  *outputs.Client
 	elasticsearchClient *outputs.Client
@@ -29,12 +50,21 @@ var (
 	lokiClient          *outputs.Client
 	natsClient          *outputs.Client
 	stanClient          *outputs.Client
+	jetstreamClient     *outputs.Client
+	mqttClient          *outputs.Client
+	redisClient         *outputs.Client
+	syslogClient        *outputs.Client
+	gelfClient          *outputs.Client
 	awsClient           *outputs.Client
 	smtpClient          *outputs.Client
 	opsgenieClient      *outputs.Client
 	webhookClient       *outputs.Client
+	tenantRouter        *outputs.TenantRouter
+	webSocketClient     *outputs.Client
+	fifoClient          *outputs.Client
 	cloudeventsClient   *outputs.Client
 	azureClient         *outputs.Client
+	azureBlobClient     *outputs.Client
 	gcpClient           *outputs.Client
 	googleChatClient    *outputs.Client
 	kafkaClient         *outputs.Client
@@ -45,7 +75,30 @@ var (
 	webUIClient         *outputs.Client
 	rabbitmqClient      *outputs.Client
 	wavefrontClient     *outputs.Client
+	policyClient        *outputs.PolicyClient
+	fieldsMerger        *outputs.FieldsMerger
+	drainer             = new(outputs.Drainer)
+	readiness           *outputs.Readiness
+	malformedInputs     *outputs.MalformedInputs
+	correlator          *outputs.Correlator
+	reorderer           *outputs.Reorderer
+	escalator           *outputs.Escalator
+	celExpressions      *outputs.CELExpressions
+	enricher            *outputs.Enricher
+	runbooks            *outputs.Runbooks
+	secretFiles         *outputs.SecretFiles
+	sampler             *outputs.Sampler
+	deduplicator        *outputs.Deduplicator
+	dispatcher          *outputs.Dispatcher
+	registeredOutputs   []outputs.RegisteredOutput
+	kafkaConsumer       *outputs.KafkaConsumer
+	quietHours          *outputs.QuietHours
+	schedule            *outputs.Schedule
+	router              *routing.Router
+	summarizer          *outputs.Summarizer
+	metricLabels        *outputs.MetricLabels
 
+	startTime                     = time.Now()
 	statsdClient, dogstatsdClient *statsd.Client
 	config                        *types.Configuration
 	stats                         *types.Statistics
@@ -54,9 +107,35 @@ var (
 
 func init() {
 	config = getConfig()
+	outputs.SetupLogs(config)
+	// the sample payload is printed before the creation of the clients, nothing is connected to
+	if *samplePayload != "" {
+		if err := printSamplePayload(*samplePayload, *sampleEvent); err != nil {
+			log.Fatalf("[ERROR] : Sample payload - %v\n", err)
+		}
+		os.Exit(0)
+	}
 	stats = getInitStats()
+
+	var err error
+	secretFiles, err = outputs.NewSecretFiles(config)
+	if err != nil {
+		log.Fatalf("[ERROR] : Secret files - %v\n", err)
+	}
+	outputs.SetSecretFiles(secretFiles)
+	metricLabels, err = outputs.NewMetricLabels(config)
+	if err != nil {
+		log.Fatalf("[ERROR] : Metrics - %v\n", err)
+	}
 	promStats = getInitPromStats()
 
+	deadLetter, err := outputs.NewDeadLetterFile(config)
+	if err != nil {
+		log.Fatalf("[ERROR] : Dead letter - %v\n", err)
+	}
+	malformedInputs = &outputs.MalformedInputs{PromStats: promStats, DeadLetter: deadLetter}
+	outputs.SetDeadLetterFile(deadLetter)
+
 	nullClient = &outputs.Client{
 		OutputType:      "null",
 		Config:          config,
@@ -66,6 +145,68 @@ func init() {
 		DogstatsdClient: dogstatsdClient,
 	}
 
+	if err := types.SetPriorityAliases(config.Priorities.Aliases, config.Priorities.Unknown); err != nil {
+		log.Fatalf("[ERROR] : Priorities - %v\n", err)
+	}
+
+	fieldsMerger, err = outputs.NewFieldsMerger(config)
+	if err != nil {
+		log.Fatalf("[ERROR] : Templated fields - %v\n", err)
+	}
+
+	if config.Escalation.Threshold > 0 {
+		escalator = outputs.NewEscalator(config)
+	}
+
+	if config.CEL.Filter != "" || config.CEL.Priority != "" || len(config.CEL.Fields) != 0 {
+		celExpressions, err = outputs.NewCELExpressions(config)
+		if err != nil {
+			log.Fatalf("[ERROR] : CEL - %v\n", err)
+		}
+	}
+
+	if config.Correlation.Enabled {
+		correlator = outputs.NewCorrelator(config, func(falcopayload types.FalcoPayload) { forwardEvent(falcopayload) })
+	}
+
+	if config.Reordering.Window > 0 {
+		// each event is sent to the outputs before the next one, so they receive them in order
+		reorderer = outputs.NewReorderer(config, func(falcopayload types.FalcoPayload) {
+			if correlator != nil {
+				correlator.Add(falcopayload)
+				return
+			}
+			forwardEvent(falcopayload).Wait()
+		})
+	}
+
+	if config.Deduplication.Window > 0 {
+		deduplicator = outputs.NewDeduplicator(config, promStats, func(summary types.FalcoPayload) { routeEvent(summary) })
+	}
+
+	if config.Enrichment.URL != "" {
+		enricher, err = outputs.NewEnricher(config, promStats)
+		if err != nil {
+			config.Enrichment.URL = ""
+		}
+	}
+
+	if config.Runbooks.File != "" {
+		var err error
+		runbooks, err = outputs.NewRunbooks(config.Runbooks.File)
+		if err != nil {
+			log.Fatalf("[ERROR] : Runbooks - %v\n", err)
+		}
+	}
+
+	if config.OPA.URL != "" {
+		var err error
+		policyClient, err = outputs.NewPolicyClient(config, promStats)
+		if err != nil {
+			config.OPA.URL = ""
+		}
+	}
+
 	if config.Statsd.Forwarder != "" {
 		var err error
 		statsdClient, err = outputs.NewStatsdClient("StatsD", config, stats)
@@ -88,12 +229,24 @@ func init() {
 		}
 	}
 
-	if config.Slack.WebhookURL != "" {
+	if config.Slack.WebhookURL != "" || config.Slack.Token != "" {
 		var err error
-		slackClient, err = outputs.NewClient("Slack", config.Slack.WebhookURL, config.Slack.MutualTLS, config.Slack.CheckCert, config, stats, promStats, statsdClient, dogstatsdClient)
+		endpointURL := config.Slack.WebhookURL
+		if config.Slack.Token != "" {
+			endpointURL = outputs.SlackPostMessageURL
+		}
+		slackClient, err = outputs.NewClient("Slack", endpointURL, config.Slack.MutualTLS, config.Slack.CheckCert, config, stats, promStats, statsdClient, dogstatsdClient)
 		if err != nil {
 			config.Slack.WebhookURL = ""
+			config.Slack.Token = ""
 		} else {
+			if config.Slack.ThreadWindow > 0 {
+				slackClient.EnableSlackThreads()
+			}
+			slackClient.RetryAfterPolicy = config.Slack.RetryAfterPolicy
+			slackClient.RateLimiter = newRateLimiter("Slack", config.Slack.MaxRate)
+			slackClient.RateLimitMode = config.Slack.RateLimitMode
+			slackClient.Redaction = newRedaction("Slack", config.Slack.MaskFields, config.Slack.RedactPatterns)
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Slack")
 		}
 	}
@@ -104,6 +257,7 @@ func init() {
 		if err != nil {
 			config.Rocketchat.WebhookURL = ""
 		} else {
+			rocketchatClient.Redaction = newRedaction("Rocketchat", config.Rocketchat.MaskFields, config.Rocketchat.RedactPatterns)
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Rocketchat")
 		}
 	}
@@ -114,6 +268,7 @@ func init() {
 		if err != nil {
 			config.Mattermost.WebhookURL = ""
 		} else {
+			mattermostClient.Redaction = newRedaction("Mattermost", config.Mattermost.MaskFields, config.Mattermost.RedactPatterns)
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Mattermost")
 		}
 	}
@@ -124,6 +279,10 @@ func init() {
 		if err != nil {
 			config.Teams.WebhookURL = ""
 		} else {
+			teamsClient.RetryAfterPolicy = config.Teams.RetryAfterPolicy
+			teamsClient.RateLimiter = newRateLimiter("Teams", config.Teams.MaxRate)
+			teamsClient.RateLimitMode = config.Teams.RateLimitMode
+			teamsClient.Redaction = newRedaction("Teams", config.Teams.MaskFields, config.Teams.RedactPatterns)
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Teams")
 		}
 	}
@@ -134,16 +293,34 @@ func init() {
 		if err != nil {
 			config.Datadog.APIKey = ""
 		} else {
+			datadogClient.Redaction = newRedaction("Datadog", config.Datadog.MaskFields, config.Datadog.RedactPatterns)
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Datadog")
 		}
 	}
 
+	if config.DatadogLogs.APIKey != "" {
+		var err error
+		datadogLogsClient, err = outputs.NewClient("DatadogLogs", outputs.DatadogLogsURL(config.DatadogLogs.Site), config.DatadogLogs.MutualTLS, config.DatadogLogs.CheckCert, config, stats, promStats, statsdClient, dogstatsdClient)
+		if err != nil {
+			config.DatadogLogs.APIKey = ""
+		} else {
+			if config.DatadogLogs.BatchSize > 1 {
+				datadogLogsClient.EnableDatadogLogsBatches()
+			}
+			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "DatadogLogs")
+		}
+	}
+
 	if config.Discord.WebhookURL != "" {
 		var err error
 		discordClient, err = outputs.NewClient("Discord", config.Discord.WebhookURL, config.Discord.MutualTLS, config.Discord.CheckCert, config, stats, promStats, statsdClient, dogstatsdClient)
 		if err != nil {
 			config.Discord.WebhookURL = ""
 		} else {
+			discordClient.RetryAfterPolicy = config.Discord.RetryAfterPolicy
+			discordClient.RateLimiter = newRateLimiter("Discord", config.Discord.MaxRate)
+			discordClient.RateLimitMode = config.Discord.RateLimitMode
+			discordClient.Redaction = newRedaction("Discord", config.Discord.MaskFields, config.Discord.RedactPatterns)
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Discord")
 		}
 	}
@@ -154,6 +331,11 @@ func init() {
 		if err != nil {
 			config.Alertmanager.HostPort = ""
 		} else {
+			alertmanagerClient.ServerName = config.Alertmanager.ServerName
+			alertmanagerClient.DisableSessionTickets = config.Alertmanager.DisableSessionTickets
+			alertmanagerClient.TLSSessionCache = newTLSSessionCache(config.Alertmanager.SessionCacheSize)
+			alertmanagerClient.SchemaVersion = config.Alertmanager.SchemaVersion
+			alertmanagerClient.SchemaVersionInPayload = config.Alertmanager.SchemaVersionInPayload
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "AlertManager")
 		}
 	}
@@ -164,6 +346,32 @@ func init() {
 		if err != nil {
 			config.Elasticsearch.HostPort = ""
 		} else {
+			elasticsearchClient.ServerName = config.Elasticsearch.ServerName
+			elasticsearchClient.MutualTLSCACert = config.Elasticsearch.MutualTLSCACert
+			elasticsearchClient.MutualTLSClientCert = config.Elasticsearch.MutualTLSClientCert
+			elasticsearchClient.MutualTLSClientKey = config.Elasticsearch.MutualTLSClientKey
+			elasticsearchClient.DisableSessionTickets = config.Elasticsearch.DisableSessionTickets
+			elasticsearchClient.TLSSessionCache = newTLSSessionCache(config.Elasticsearch.SessionCacheSize)
+			elasticsearchClient.EnableCompression = config.Elasticsearch.EnableCompression
+			elasticsearchClient.CompressionThreshold = config.Elasticsearch.CompressionThreshold
+			elasticsearchClient.SchemaVersion = config.Elasticsearch.SchemaVersion
+			elasticsearchClient.SchemaVersionInPayload = config.Elasticsearch.SchemaVersionInPayload
+			elasticsearchClient.PriorityCase = config.Elasticsearch.PriorityCase
+			elasticsearchClient.StatusCodeRetries = config.Elasticsearch.StatusCodeRetries
+			elasticsearchClient.MaxRetryDuration = time.Duration(config.Elasticsearch.MaxRetryDuration) * time.Second
+			elasticsearchClient.BackpressureDelay = time.Duration(config.Elasticsearch.BackpressureDelay) * time.Millisecond
+			elasticsearchClient.BackpressureMaxDelay = time.Duration(config.Elasticsearch.BackpressureMaxDelay) * time.Millisecond
+			elasticsearchClient.CircuitBreaker = outputs.NewCircuitBreaker("Elasticsearch", config.Elasticsearch.CircuitBreakerThreshold, time.Duration(config.Elasticsearch.CircuitBreakerCooldown)*time.Second, promStats)
+			elasticsearchClient.StatusCodePolicies, err = outputs.ParseStatusCodePolicies(config.Elasticsearch.StatusCodePolicies)
+			if err != nil {
+				log.Fatalf("[ERROR] : Elasticsearch - %v\n", err)
+			}
+			elasticsearchClient.Redaction = newRedaction("Elasticsearch", config.Elasticsearch.MaskFields, config.Elasticsearch.RedactPatterns)
+			if config.Elasticsearch.BatchSize > 0 {
+				if err := elasticsearchClient.EnableElasticsearchBulk(); err != nil {
+					log.Fatalf("[ERROR] : Elasticsearch - %v\n", err)
+				}
+			}
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Elasticsearch")
 		}
 	}
@@ -174,6 +382,19 @@ func init() {
 		if err != nil {
 			config.Loki.HostPort = ""
 		} else {
+			lokiClient.ServerName = config.Loki.ServerName
+			lokiClient.DisableSessionTickets = config.Loki.DisableSessionTickets
+			lokiClient.TLSSessionCache = newTLSSessionCache(config.Loki.SessionCacheSize)
+			lokiClient.EnableCompression = config.Loki.EnableCompression
+			lokiClient.CompressionThreshold = config.Loki.CompressionThreshold
+			lokiClient.CircuitBreaker = outputs.NewCircuitBreaker("Loki", config.Loki.CircuitBreakerThreshold, time.Duration(config.Loki.CircuitBreakerCooldown)*time.Second, promStats)
+			lokiClient.HedgeDelay = time.Duration(config.Loki.HedgeDelay) * time.Millisecond
+			if len(config.Loki.ExtraLabels) != 0 {
+				lokiClient.EnableLokiExtraLabels()
+			}
+			if config.Loki.ConsulService != "" {
+				lokiClient.Resolver = newServiceResolver(config.Loki.ConsulService, config.Loki.ConsulTags)
+			}
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Loki")
 		}
 	}
@@ -188,6 +409,56 @@ func init() {
 		}
 	}
 
+	if config.JetStream.HostPort != "" && config.JetStream.Stream != "" {
+		var err error
+		jetstreamClient, err = outputs.NewJetStreamClient(config, stats, promStats, statsdClient, dogstatsdClient)
+		if err != nil {
+			config.JetStream.HostPort = ""
+		} else {
+			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "JetStream")
+		}
+	}
+
+	if config.MQTT.Broker != "" {
+		var err error
+		mqttClient, err = outputs.NewMQTTClient(config, stats, promStats, statsdClient, dogstatsdClient)
+		if err != nil {
+			config.MQTT.Broker = ""
+		} else {
+			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "MQTT")
+		}
+	}
+
+	if config.Redis.Address != "" {
+		var err error
+		redisClient, err = outputs.NewRedisClient(config, stats, promStats, statsdClient, dogstatsdClient)
+		if err != nil {
+			config.Redis.Address = ""
+		} else {
+			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Redis")
+		}
+	}
+
+	if config.Syslog.Address != "" {
+		var err error
+		syslogClient, err = outputs.NewSyslogClient(config, stats, promStats, statsdClient, dogstatsdClient)
+		if err != nil {
+			config.Syslog.Address = ""
+		} else {
+			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Syslog")
+		}
+	}
+
+	if config.GELF.Address != "" {
+		var err error
+		gelfClient, err = outputs.NewGELFClient(config, stats, promStats, statsdClient, dogstatsdClient)
+		if err != nil {
+			config.GELF.Address = ""
+		} else {
+			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "GELF")
+		}
+	}
+
 	if config.Stan.HostPort != "" && config.Stan.ClusterID != "" && config.Stan.ClientID != "" {
 		var err error
 		stanClient, err = outputs.NewClient("STAN", config.Stan.HostPort, config.Stan.MutualTLS, config.Stan.CheckCert, config, stats, promStats, statsdClient, dogstatsdClient)
@@ -205,17 +476,24 @@ func init() {
 		if config.Influxdb.User != "" && config.Influxdb.Password != "" {
 			credentials = "&u=" + config.Influxdb.User + "&p=" + config.Influxdb.Password
 		}
+		endpoint := config.Influxdb.HostPort + "/write?db=" + config.Influxdb.Database + credentials
+		if config.Influxdb.Token != "" {
+			endpoint = config.Influxdb.HostPort + "/api/v2/write?org=" + url.QueryEscape(config.Influxdb.Organization) + "&bucket=" + url.QueryEscape(config.Influxdb.Bucket) + "&precision=ns"
+		}
 
 		var err error
-		influxdbClient, err = outputs.NewClient("Influxdb", config.Influxdb.HostPort+"/write?db="+config.Influxdb.Database+credentials, config.Influxdb.MutualTLS, config.Influxdb.CheckCert, config, stats, promStats, statsdClient, dogstatsdClient)
+		influxdbClient, err = outputs.NewClient("Influxdb", endpoint, config.Influxdb.MutualTLS, config.Influxdb.CheckCert, config, stats, promStats, statsdClient, dogstatsdClient)
 		if err != nil {
 			config.Influxdb.HostPort = ""
 		} else {
+			influxdbClient.ServerName = config.Influxdb.ServerName
+			influxdbClient.DisableSessionTickets = config.Influxdb.DisableSessionTickets
+			influxdbClient.TLSSessionCache = newTLSSessionCache(config.Influxdb.SessionCacheSize)
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Influxdb")
 		}
 	}
 
-	if config.AWS.Lambda.FunctionName != "" || config.AWS.SQS.URL != "" ||
+	if config.AWS.Lambda.FunctionName != "" || config.AWS.SQS.URL != "" || config.AWS.Kinesis.StreamName != "" ||
 		config.AWS.SNS.TopicArn != "" || config.AWS.CloudWatchLogs.LogGroup != "" || config.AWS.S3.Bucket != "" {
 		var err error
 		awsClient, err = outputs.NewAWSClient(config, stats, promStats, statsdClient, dogstatsdClient)
@@ -225,6 +503,7 @@ func init() {
 			config.AWS.Region = ""
 			config.AWS.Lambda.FunctionName = ""
 			config.AWS.SQS.URL = ""
+			config.AWS.Kinesis.StreamName = ""
 			config.AWS.S3.Bucket = ""
 			config.AWS.SNS.TopicArn = ""
 			config.AWS.CloudWatchLogs.LogGroup = ""
@@ -236,6 +515,9 @@ func init() {
 			if config.AWS.SQS.URL != "" {
 				outputs.EnabledOutputs = append(outputs.EnabledOutputs, "AWSSQS")
 			}
+			if config.AWS.Kinesis.StreamName != "" {
+				outputs.EnabledOutputs = append(outputs.EnabledOutputs, "AWSKinesis")
+			}
 			if config.AWS.SNS.TopicArn != "" {
 				outputs.EnabledOutputs = append(outputs.EnabledOutputs, "AWSSNS")
 			}
@@ -268,6 +550,9 @@ func init() {
 		if err != nil {
 			config.Opsgenie.APIKey = ""
 		} else {
+			opsgenieClient.RateLimiter = newRateLimiter("Opsgenie", config.Opsgenie.MaxRate)
+			opsgenieClient.RateLimitMode = config.Opsgenie.RateLimitMode
+			opsgenieClient.Redaction = newRedaction("Opsgenie", config.Opsgenie.MaskFields, config.Opsgenie.RedactPatterns)
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Opsgenie")
 		}
 	}
@@ -278,10 +563,68 @@ func init() {
 		if err != nil {
 			config.Webhook.Address = ""
 		} else {
+			webhookClient.ServerName = config.Webhook.ServerName
+			webhookClient.MutualTLSCACert = config.Webhook.MutualTLSCACert
+			webhookClient.MutualTLSClientCert = config.Webhook.MutualTLSClientCert
+			webhookClient.MutualTLSClientKey = config.Webhook.MutualTLSClientKey
+			webhookClient.DisableSessionTickets = config.Webhook.DisableSessionTickets
+			webhookClient.TLSSessionCache = newTLSSessionCache(config.Webhook.SessionCacheSize)
+			webhookClient.EnableCompression = config.Webhook.EnableCompression
+			webhookClient.CompressionThreshold = config.Webhook.CompressionThreshold
+			webhookClient.SchemaVersion = config.Webhook.SchemaVersion
+			webhookClient.SchemaVersionInPayload = config.Webhook.SchemaVersionInPayload
+			webhookClient.PriorityCase = config.Webhook.PriorityCase
+			webhookClient.StatusCodeRetries = config.Webhook.StatusCodeRetries
+			webhookClient.MaxRetryDuration = time.Duration(config.Webhook.MaxRetryDuration) * time.Second
+			webhookClient.CircuitBreaker = outputs.NewCircuitBreaker("Webhook", config.Webhook.CircuitBreakerThreshold, time.Duration(config.Webhook.CircuitBreakerCooldown)*time.Second, promStats)
+			webhookClient.HedgeDelay = time.Duration(config.Webhook.HedgeDelay) * time.Millisecond
+			if config.Webhook.OAuth2TokenURL != "" {
+				webhookClient.TokenSource = outputs.NewOAuth2TokenSource(config.Webhook.OAuth2TokenURL, config.Webhook.OAuth2ClientID, config.Webhook.OAuth2ClientSecret, config.Webhook.OAuth2Scopes)
+			}
+			webhookClient.AuthMethods, err = outputs.ParseAuthMethods(config.Webhook.AuthMethods)
+			if err != nil {
+				log.Fatalf("[ERROR] : Webhook - %v\n", err)
+			}
+			if config.Webhook.ConsulService != "" {
+				webhookClient.Resolver = newServiceResolver(config.Webhook.ConsulService, config.Webhook.ConsulTags)
+			}
+			webhookClient.StatusCodePolicies, err = outputs.ParseStatusCodePolicies(config.Webhook.StatusCodePolicies)
+			if err != nil {
+				log.Fatalf("[ERROR] : Webhook - %v\n", err)
+			}
+			webhookClient.RetryAfterPolicy = config.Webhook.RetryAfterPolicy
+			webhookClient.RateLimiter = newRateLimiter("Webhook", config.Webhook.MaxRate)
+			webhookClient.RateLimitMode = config.Webhook.RateLimitMode
+			webhookClient.Redaction = newRedaction("Webhook", config.Webhook.MaskFields, config.Webhook.RedactPatterns)
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Webhook")
 		}
 	}
 
+	if len(config.Tenants.Destinations) != 0 || config.Tenants.DefaultURL != "" {
+		tenantRouter = outputs.NewTenantRouter(config, stats, promStats, statsdClient, dogstatsdClient)
+		outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Tenants")
+	}
+
+	if config.WebSocket.Address != "" {
+		var err error
+		webSocketClient, err = outputs.NewWebSocketClient(config, stats, promStats, statsdClient, dogstatsdClient)
+		if err != nil {
+			config.WebSocket.Address = ""
+		} else {
+			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "WebSocket")
+		}
+	}
+
+	if config.Fifo.Path != "" {
+		var err error
+		fifoClient, err = outputs.NewFifoClient(config, stats, promStats, statsdClient, dogstatsdClient)
+		if err != nil {
+			config.Fifo.Path = ""
+		} else {
+			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Fifo")
+		}
+	}
+
 	if config.CloudEvents.Address != "" {
 		var err error
 		cloudeventsClient, err = outputs.NewClient("CloudEvents", config.CloudEvents.Address, config.CloudEvents.MutualTLS, config.CloudEvents.CheckCert, config, stats, promStats, statsdClient, dogstatsdClient)
@@ -305,6 +648,16 @@ func init() {
 		}
 	}
 
+	if config.Azure.Blob.Container != "" {
+		var err error
+		azureBlobClient, err = outputs.NewAzureBlobClient(config, stats, promStats, statsdClient, dogstatsdClient)
+		if err != nil {
+			config.Azure.Blob.Container = ""
+		} else {
+			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "AzureBlob")
+		}
+	}
+
 	if (config.GCP.PubSub.ProjectID != "" && config.GCP.PubSub.Topic != "") || config.GCP.Storage.Bucket != "" || config.GCP.CloudFunctions.Name != "" {
 		var err error
 		gcpClient, err = outputs.NewGCPClient(config, stats, promStats, statsdClient, dogstatsdClient)
@@ -341,10 +694,11 @@ func init() {
 
 	if config.Googlechat.WebhookURL != "" {
 		var err error
-		googleChatClient, err = outputs.NewClient("Googlechat", config.Googlechat.WebhookURL, config.Googlechat.MutualTLS, config.Googlechat.CheckCert, config, stats, promStats, statsdClient, dogstatsdClient)
+		googleChatClient, err = outputs.NewClient("Googlechat", outputs.GooglechatWebhookURL(config.Googlechat), config.Googlechat.MutualTLS, config.Googlechat.CheckCert, config, stats, promStats, statsdClient, dogstatsdClient)
 		if err != nil {
 			config.Googlechat.WebhookURL = ""
 		} else {
+			googleChatClient.Redaction = newRedaction("Google Chat", config.Googlechat.MaskFields, config.Googlechat.RedactPatterns)
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Google Chat")
 		}
 	}
@@ -369,6 +723,9 @@ func init() {
 		if err != nil {
 			config.Pagerduty.RoutingKey = ""
 		} else {
+			pagerdutyClient.RateLimiter = newRateLimiter(outputName, config.Pagerduty.MaxRate)
+			pagerdutyClient.RateLimitMode = config.Pagerduty.RateLimitMode
+			pagerdutyClient.Redaction = newRedaction(outputName, config.Pagerduty.MaskFields, config.Pagerduty.RedactPatterns)
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, outputName)
 		}
 	}
@@ -422,26 +779,307 @@ func init() {
 			log.Printf("[ERROR] : Wavefront - %v\n", err)
 			config.Wavefront.EndpointHost = ""
 		} else {
+			wavefrontClient.PriorityCase = config.Wavefront.PriorityCase
 			outputs.EnabledOutputs = append(outputs.EnabledOutputs, "Wavefront")
 		}
 	}
 
+	if config.KafkaInput.HostPort != "" && config.KafkaInput.Topic != "" {
+		kafkaConsumer, err = outputs.NewKafkaConsumer(config, kafkaInputHandler)
+		if err != nil {
+			config.KafkaInput.HostPort = ""
+		} else {
+			log.Printf("[INFO]  : Kafka input - Consuming topic %v with group %v\n", config.KafkaInput.Topic, config.KafkaInput.GroupID)
+		}
+	}
+
+	registeredOutputs, err = outputs.NewRegisteredOutputs(config, stats, promStats)
+	if err != nil {
+		log.Fatalf("[ERROR] : Registered outputs - %v\n", err)
+	}
+	for _, i := range registeredOutputs {
+		outputs.EnabledOutputs = append(outputs.EnabledOutputs, i.Name)
+	}
+
+	dispatcher, err = outputs.NewDispatcher(config.Dispatch.Dependencies, outputs.EnabledOutputs, drainer, promStats)
+	if err != nil {
+		log.Fatalf("[ERROR] : Dispatch - %v\n", err)
+	}
+	dispatcher.Deadline = time.Duration(config.Dispatch.Deadline) * time.Millisecond
+	if len(config.Dispatch.Queues) != 0 {
+		dispatcher.Queues, err = outputs.NewOutputQueues(config.Dispatch.Queues, outputs.EnabledOutputs, drainer, promStats)
+		if err != nil {
+			log.Fatalf("[ERROR] : Dispatch - %v\n", err)
+		}
+	}
+	for _, i := range []*outputs.Client{awsClient, azureBlobClient, datadogLogsClient, elasticsearchClient, gcpClient, kafkaClient, jetstreamClient, mqttClient, redisClient, syslogClient, gelfClient} {
+		if i != nil {
+			dispatcher.Flushers = append(dispatcher.Flushers, i)
+		}
+	}
+	for _, i := range registeredOutputs {
+		if f, ok := i.Output.(outputs.Flusher); ok {
+			dispatcher.Flushers = append(dispatcher.Flushers, f)
+		}
+	}
+
+	checkers := make(map[string]outputs.HealthChecker)
+	for i, j := range map[string]*outputs.Client{"AlertManager": alertmanagerClient, "Elasticsearch": elasticsearchClient, "Influxdb": influxdbClient, "JetStream": jetstreamClient, "Kafka": kafkaClient, "Loki": lokiClient, "MQTT": mqttClient, "NATS": natsClient, "Redis": redisClient, "Syslog": syslogClient, "GELF": gelfClient, "Webhook": webhookClient} {
+		if j != nil {
+			checkers[i] = j
+		}
+	}
+	for _, i := range registeredOutputs {
+		if c, ok := i.Output.(outputs.HealthChecker); ok {
+			checkers[i.Name] = c
+		}
+	}
+	readiness, err = outputs.NewReadiness(config.Readiness.Outputs, checkers, time.Duration(config.Readiness.CacheTTL)*time.Second, time.Duration(config.Readiness.Timeout)*time.Millisecond)
+	if err != nil {
+		log.Fatalf("[ERROR] : Readiness - %v\n", err)
+	}
+
+	if len(config.QuietHours.Windows) != 0 {
+		quietHours, err = outputs.NewQuietHours(config, outputs.EnabledOutputs, func(output string, digest types.FalcoPayload) {
+			dispatchEvent(digest, outputs.NewOutputSelection(output))
+		})
+		if err != nil {
+			log.Fatalf("[ERROR] : Quiet hours - %v\n", err)
+		}
+		dispatcher.QuietHours = quietHours
+	}
+
+	if len(config.Routing.Routes) != 0 {
+		router, err = routing.NewRouter(config, outputs.EnabledOutputs)
+		if err != nil {
+			log.Fatalf("[ERROR] : Routing - %v\n", err)
+		}
+	}
+
+	if len(config.Schedule.Routes) != 0 {
+		schedule, err = outputs.NewSchedule(config, outputs.EnabledOutputs)
+		if err != nil {
+			log.Fatalf("[ERROR] : Schedule - %v\n", err)
+		}
+	}
+
+	if config.Summary.Interval > 0 {
+		summarizer, err = outputs.NewSummarizer(config, outputs.EnabledOutputs, func(names []string, summary types.FalcoPayload) {
+			dispatchEvent(summary, outputs.NewOutputSelection(names...))
+		})
+		if err != nil {
+			log.Fatalf("[ERROR] : Summary - %v\n", err)
+		}
+	}
+
+	if len(config.Suppression.Rules) != 0 {
+		dispatcher.Suppressor, err = outputs.NewSuppressor(config, outputs.EnabledOutputs, promStats)
+		if err != nil {
+			log.Fatalf("[ERROR] : Suppression - %v\n", err)
+		}
+	}
+
+	if len(config.Sampling.Rules) != 0 || len(config.Sampling.Outputs) != 0 {
+		sampler, err = outputs.NewSampler(config, outputs.EnabledOutputs, promStats)
+		if err != nil {
+			log.Fatalf("[ERROR] : Sampling - %v\n", err)
+		}
+		dispatcher.Sampler = sampler
+	}
+
+	if len(config.Cooldown.Windows) != 0 {
+		dispatcher.Cooldown, err = outputs.NewCooldown(config, outputs.EnabledOutputs, promStats)
+		if err != nil {
+			log.Fatalf("[ERROR] : Cooldown - %v\n", err)
+		}
+	}
+
+	if len(config.Flattening.Outputs) != 0 {
+		dispatcher.Flattener, err = outputs.NewFlattening(config, outputs.EnabledOutputs)
+		if err != nil {
+			log.Fatalf("[ERROR] : Flattening - %v\n", err)
+		}
+	}
+
 	log.Printf("[INFO]  : Enabled Outputs : %s\n", outputs.EnabledOutputs)
+	if len(config.DryRun.Outputs) != 0 {
+		log.Printf("[INFO]  : Dry Run Outputs : %s\n", config.DryRun.Outputs)
+	}
 }
 
 func main() {
 	http.HandleFunc("/", mainHandler)
 	http.HandleFunc("/ping", pingHandler)
 	http.HandleFunc("/healthz", healthHandler)
-	http.HandleFunc("/test", testHandler)
+	http.HandleFunc("/readyz", readiness.Handler())
+	http.HandleFunc("/test", outputs.TestEventHandler(config.Test.Token, drainer, sendTestEvent))
 	http.Handle("/metrics", promhttp.Handler())
+	http.HandleFunc("/info", outputs.InventoryHandler(config, Version, Commit, startTime))
+	if config.Drain.Token != "" {
+		http.HandleFunc("/drain", drainer.Handler(config.Drain.Token))
+	}
 
 	log.Printf("[INFO]  : Falco Sidekick is up and listening on %s:%d", config.ListenAddress, config.ListenPort)
 	if config.Debug {
 		log.Printf("[INFO]  : Debug mode : %v", config.Debug)
+		log.Printf("[DEBUG] : Configuration : %v", outputs.RedactedConfig(config))
+	}
+
+	if config.Replay.File != "" {
+		go replayFile()
 	}
 
+	if kafkaConsumer != nil {
+		go kafkaConsumer.Run(context.Background())
+	}
+
+	if quietHours != nil {
+		go quietHours.Run(time.Minute)
+	}
+
+	if summarizer != nil {
+		go summarizer.Run()
+	}
+
+	if runbooks != nil || secretFiles.Files() != 0 {
+		go reloadOnSIGHUP()
+	}
+
+	go flushOnShutdown()
+
 	if err := http.ListenAndServe(fmt.Sprintf("%s:%d", config.ListenAddress, config.ListenPort), nil); err != nil {
 		log.Fatalf("[ERROR] : %v", err.Error())
 	}
 }
+
+// printSamplePayload prints the headers and the body an output would post for the event of a file (or the test event),
+// without creating its client
+func printSamplePayload(output, file string) error {
+	var event io.Reader = strings.NewReader(newTestEvent(types.Default))
+	if file != "" {
+		f, err := os.Open(filepath.Clean(file))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		event = f
+	}
+	raw, err := ioutil.ReadAll(event)
+	if err != nil {
+		return err
+	}
+	var falcopayload types.FalcoPayload
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	if err := d.Decode(&falcopayload); err != nil {
+		return err
+	}
+	falcopayload.RawPayload = raw
+
+	sample, err := outputs.SamplePayload(output, falcopayload, config)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(sample)
+	return err
+}
+
+// newServiceResolver returns a ServiceResolver of the Consul agent configured, refreshed in the background
+func newServiceResolver(service string, tags []string) *outputs.ServiceResolver {
+	r := outputs.NewServiceResolver(config.Consul.Address, config.Consul.Token, service, tags)
+	if config.Consul.RefreshInterval > 0 {
+		go r.Run(time.Duration(config.Consul.RefreshInterval) * time.Second)
+	}
+	return r
+}
+
+// newTLSSessionCache returns a cache of TLS sessions of the size configured, nil if it's 0
+func newTLSSessionCache(size int) tls.ClientSessionCache {
+	if size <= 0 {
+		return nil
+	}
+	return tls.NewLRUClientSessionCache(size)
+}
+
+// newRateLimiter returns the rate limiter of an output for the MaxRate configured, nil if it's empty or bad
+func newRateLimiter(output, maxRate string) *rate.Limiter {
+	limiter, err := outputs.NewRateLimiter(maxRate)
+	if err != nil {
+		log.Printf("[ERROR] : %v - %v, rate limit is disabled\n", output, err)
+		return nil
+	}
+	return limiter
+}
+
+// newRedaction returns the redaction of an output for the fields and the patterns configured, nil if there are none,
+// a bad one is fatal as the secrets would be sent
+func newRedaction(output string, fields, patterns []string) *outputs.Redaction {
+	redaction, err := outputs.NewRedaction(fields, patterns)
+	if err != nil {
+		log.Fatalf("[ERROR] : %v - %v\n", output, err)
+	}
+	return redaction
+}
+
+// flushOnShutdown waits for the events being sent and sends the ones still buffered by the outputs before exiting on
+// SIGINT or SIGTERM, for Drain.ShutdownTimeout at most
+func flushOnShutdown() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+	log.Printf("[INFO]  : Falco Sidekick is shutting down")
+	if reorderer != nil {
+		reorderer.Flush()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Drain.ShutdownTimeout)*time.Second)
+	if err := dispatcher.Shutdown(ctx); err != nil {
+		log.Printf("[ERROR] : Shutdown - %v, events may have been lost\n", err)
+	}
+	cancel()
+	os.Exit(0)
+}
+
+// reloadOnSIGHUP reloads the runbooks file and the settings read from files on SIGHUP
+func reloadOnSIGHUP() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	for range sig {
+		if secretFiles.Files() != 0 {
+			if err := secretFiles.Load(); err != nil {
+				log.Printf("[ERROR] : Secret files - %v, previous values kept\n", err)
+			} else {
+				log.Printf("[INFO]  : Secret files - %v settings reloaded\n", secretFiles.Files())
+			}
+		}
+		if runbooks == nil {
+			continue
+		}
+		if err := runbooks.Load(); err != nil {
+			log.Printf("[ERROR] : Runbooks - %v, previous runbooks kept\n", err)
+			continue
+		}
+		log.Printf("[INFO]  : Runbooks - %v reloaded\n", config.Runbooks.File)
+	}
+}
+
+// replayFile sends the events of the replay file to the enabled outputs, waiting for each event to be sent to all of
+// them, the process exits with an error if some events couldn't be sent
+func replayFile() {
+	log.Printf("[INFO]  : Replay - Start replaying %v from offset %v", config.Replay.File, config.Replay.Offset)
+	opts := outputs.ReplayOptions{
+		Parallelism: config.Replay.Parallelism,
+		Rate:        config.Replay.Rate,
+		Offset:      config.Replay.Offset,
+	}
+	replayStats, err := outputs.ReplayFile(config.Replay.File, opts, func(falcopayload types.FalcoPayload) error {
+		x := forwardEvent(falcopayload)
+		x.Wait()
+		return x.Err()
+	})
+	if err != nil {
+		log.Fatalf("[ERROR] : Replay - %v, resume offset %v", err, replayStats.Offset)
+	}
+	if replayStats.Failed > 0 {
+		log.Fatalf("[ERROR] : Replay - %v/%v events failed, resume offset %v", replayStats.Failed, replayStats.Total, replayStats.Offset)
+	}
+}
@@ -1,8 +1,8 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
-	"log"
 	"strconv"
 	"strings"
 
@@ -87,19 +87,28 @@ func newAlertmanagerPayload(falcopayload types.FalcoPayload) []alertmanagerPaylo
 }
 
 // AlertmanagerPost posts event to AlertManager
-func (c *Client) AlertmanagerPost(falcopayload types.FalcoPayload) {
+func (c *Client) AlertmanagerPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Alertmanager.Add(Total, 1)
 
-	err := c.Post(newAlertmanagerPayload(falcopayload))
+	payload := newAlertmanagerPayload(falcopayload)
+	if c.SchemaVersionInPayload && c.SchemaVersion != "" {
+		for _, i := range payload {
+			i.Labels["schema_version"] = c.SchemaVersion
+		}
+	}
+
+	err := c.post(ctx, payload, falcopayload)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:alertmanager", "status:error"})
 		c.Stats.Alertmanager.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "alertmanager", "status": Error}).Inc()
-		log.Printf("[ERROR] : AlertManager - %v\n", err)
-		return
+		logEventError("AlertManager", falcopayload, err)
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:alertmanager", "status:ok"})
 	c.Stats.Alertmanager.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "alertmanager", "status": OK}).Inc()
+
+	return nil
 }
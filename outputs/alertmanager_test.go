@@ -1,7 +1,11 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -27,3 +31,28 @@ func TestNewAlertmanagerPayloadO(t *testing.T) {
 
 	require.Equal(t, o1, o2)
 }
+
+func TestAlertmanagerPostSchemaVersion(t *testing.T) {
+	var header string
+	var payload []alertmanagerPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(SchemaVersionHeader)
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer ts.Close()
+
+	client, err := NewClient("AlertManager", ts.URL+AlertmanagerURI, false, false, &types.Configuration{}, &types.Statistics{Alertmanager: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.SchemaVersion = "2"
+	client.SchemaVersionInPayload = true
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	client.AlertmanagerPost(context.Background(), f)
+
+	require.Equal(t, "2", header)
+	require.NotEmpty(t, payload)
+	for _, i := range payload {
+		require.Equal(t, "2", i.Labels["schema_version"])
+	}
+}
@@ -0,0 +1,68 @@
+package outputs
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// AuthMethod is a header authenticating the requests of an output
+type AuthMethod struct {
+	Header string
+	Value  string
+}
+
+// ParseAuthMethods returns the authentication methods configured ("Header: value"), in the order to try them
+func ParseAuthMethods(methods []string) ([]AuthMethod, error) {
+	var m []AuthMethod
+	for _, i := range methods {
+		h := strings.SplitN(i, ":", 2)
+		if len(h) != 2 || strings.TrimSpace(h[0]) == "" {
+			return nil, fmt.Errorf("Bad authentication method, must be 'Header: value'")
+		}
+		m = append(m, AuthMethod{Header: http.CanonicalHeaderKey(strings.TrimSpace(h[0])), Value: strings.TrimSpace(h[1])})
+	}
+	return m, nil
+}
+
+// setAuthMethod sets the header of the i-th authentication method, the headers of the other ones are removed
+func (c *Client) setAuthMethod(req *http.Request, i int) {
+	for _, j := range c.AuthMethods {
+		req.Header.Del(j.Header)
+	}
+	req.Header.Set(c.AuthMethods[i].Header, c.AuthMethods[i].Value)
+}
+
+// sendAuthenticated sends the request with the current authentication method, on a 401 or a 403 it's sent once again with
+// the next one, which becomes the current one if it's accepted
+func (c *Client) sendAuthenticated(client *http.Client, req *http.Request) (*http.Response, error) {
+	if len(c.AuthMethods) == 0 {
+		return c.send(client, req)
+	}
+	i := int(atomic.LoadInt32(&c.authMethod))
+	c.setAuthMethod(req, i)
+	resp, err := c.send(client, req)
+	if err != nil || len(c.AuthMethods) < 2 || !isAuthFailure(resp.StatusCode) || req.GetBody == nil {
+		return resp, err
+	}
+
+	resp.Body.Close()
+	next := (i + 1) % len(c.AuthMethods)
+	r := req.Clone(req.Context())
+	if r.Body, err = req.GetBody(); err != nil {
+		return nil, err
+	}
+	c.setAuthMethod(r, next)
+	log.Printf("[WARN]  : %v - Authentication refused (%v), retry with the %v header\n", c.OutputType, resp.StatusCode, c.AuthMethods[next].Header)
+	resp, err = c.send(client, r)
+	if err == nil && !isAuthFailure(resp.StatusCode) && atomic.CompareAndSwapInt32(&c.authMethod, int32(i), int32(next)) {
+		log.Printf("[INFO]  : %v - Authentication with the %v header from now on\n", c.OutputType, c.AuthMethods[next].Header)
+	}
+	return resp, err
+}
+
+func isAuthFailure(statusCode int) bool {
+	return statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden
+}
@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -16,6 +17,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kms"
 	"github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/sns"
@@ -52,6 +55,13 @@ func NewAWSClient(config *types.Configuration, stats *types.Statistics, promStat
 		return nil, errors.New("Error while getting AWS Token")
 	}
 
+	if config.AWS.S3.Bucket != "" && config.AWS.S3.EncryptionKey != "" {
+		if _, err := decodeEncryptionKey(config.AWS.S3.EncryptionKey); err != nil {
+			log.Printf("[ERROR] : AWS S3 - Invalid encryption key : %v\n", err.Error())
+			return nil, ErrClientCreation
+		}
+	}
+
 	var endpointURL *url.URL
 	endpointURL, err = url.Parse(config.AWS.SQS.URL)
 	if err != nil {
@@ -59,28 +69,44 @@ func NewAWSClient(config *types.Configuration, stats *types.Statistics, promStat
 		return nil, ErrClientCreation
 	}
 
-	return &Client{
-		OutputType:      "AWS",
-		EndpointURL:     endpointURL,
-		Config:          config,
-		AWSSession:      sess,
-		Stats:           stats,
-		PromStats:       promStats,
-		StatsdClient:    statsdClient,
-		DogstatsdClient: dogstatsdClient,
-	}, nil
+	var sqsSender *sqsSender
+	if config.AWS.SQS.URL != "" {
+		sqsSender, err = newSQSSender(config, sqs.New(sess))
+		if err != nil {
+			log.Printf("[ERROR] : AWS SQS - %v\n", err.Error())
+			return nil, ErrClientCreation
+		}
+	}
+
+	c := &Client{
+		OutputType:        "AWS",
+		EndpointURL:       endpointURL,
+		Config:            config,
+		AWSSession:        sess,
+		awsSQS:            sqsSender,
+		RetryInitialDelay: time.Duration(config.Retry.InitialDelay) * time.Millisecond,
+		RetryMaxDelay:     time.Duration(config.Retry.MaxDelay) * time.Millisecond,
+		Stats:             stats,
+		PromStats:         promStats,
+		StatsdClient:      statsdClient,
+		DogstatsdClient:   dogstatsdClient,
+	}
+	if config.AWS.Kinesis.StreamName != "" {
+		c.awsKinesis = c.newKinesisBuffer(kinesis.New(sess))
+	}
+	return c, nil
 }
 
 // InvokeLambda invokes a lambda function
-func (c *Client) InvokeLambda(falcopayload types.FalcoPayload) {
+func (c *Client) InvokeLambda(ctx context.Context, falcopayload types.FalcoPayload) error {
 	svc := lambda.New(c.AWSSession)
 
 	f, _ := json.Marshal(falcopayload)
 
 	input := &lambda.InvokeInput{
-		FunctionName:   aws.String(c.Config.AWS.Lambda.FunctionName),
-		InvocationType: aws.String(c.Config.AWS.Lambda.InvocationType),
-		LogType:        aws.String(c.Config.AWS.Lambda.LogType),
+		FunctionName:   aws.String(c.config().AWS.Lambda.FunctionName),
+		InvocationType: aws.String(c.config().AWS.Lambda.InvocationType),
+		LogType:        aws.String(c.config().AWS.Lambda.LogType),
 		Payload:        f,
 	}
 
@@ -92,10 +118,10 @@ func (c *Client) InvokeLambda(falcopayload types.FalcoPayload) {
 		c.Stats.AWSLambda.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "awslambda", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v Lambda - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
-	if c.Config.Debug == true {
+	if c.config().Debug == true {
 		r, _ := base64.StdEncoding.DecodeString(*resp.LogResult)
 		log.Printf("[DEBUG] : %v Lambda result : %v\n", c.OutputType, string(r))
 	}
@@ -104,53 +130,65 @@ func (c *Client) InvokeLambda(falcopayload types.FalcoPayload) {
 	go c.CountMetric("outputs", 1, []string{"output:awslambda", "status:ok"})
 	c.Stats.AWSLambda.Add("ok", 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "awslambda", "status": "ok"}).Inc()
-}
 
-// SendMessage sends a message to SQS Queue
-func (c *Client) SendMessage(falcopayload types.FalcoPayload) {
-	svc := sqs.New(c.AWSSession)
+	return nil
+}
 
+// SendMessage sends a message to SQS Queue, with the message group and deduplication IDs for a FIFO queue
+func (c *Client) SendMessage(ctx context.Context, falcopayload types.FalcoPayload) error {
 	f, _ := json.Marshal(falcopayload)
 
-	input := &sqs.SendMessageInput{
-		MessageBody: aws.String(string(f)),
-		QueueUrl:    aws.String(c.Config.AWS.SQS.URL),
-	}
-
 	c.Stats.AWSSQS.Add("total", 1)
 
-	resp, err := svc.SendMessage(input)
+	messageID, err := c.awsSQS.send(falcopayload, string(f))
 	if err != nil {
 		go c.CountMetric("outputs", 1, []string{"output:awssqs", "status:error"})
 		c.Stats.AWSSQS.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "awssqs", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v SQS - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
-	if c.Config.Debug == true {
-		log.Printf("[DEBUG] : %v SQS - MD5OfMessageBody : %v\n", c.OutputType, *resp.MD5OfMessageBody)
+	if messageID != "" {
+		log.Printf("[INFO]  : %v SQS - Send Message OK (%v)\n", c.OutputType, messageID)
+	} else {
+		log.Printf("[INFO]  : %v SQS - Send Message OK\n", c.OutputType)
 	}
-
-	log.Printf("[INFO]  : %v SQS - Send Message OK (%v)\n", c.OutputType, *resp.MessageId)
 	go c.CountMetric("outputs", 1, []string{"output:awssqs", "status:ok"})
 	c.Stats.AWSSQS.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "awssqs", "status": "ok"}).Inc()
+
+	return nil
 }
 
 // UploadS3 upload payload to S3
-func (c *Client) UploadS3(falcopayload types.FalcoPayload) {
-	f, _ := json.Marshal(falcopayload)
+func (c *Client) UploadS3(ctx context.Context, falcopayload types.FalcoPayload) error {
+	f := falcopayload.RawPayload
+	if !c.config().AWS.S3.Passthrough || f == nil {
+		f, _ = json.Marshal(withRawEvent(falcopayload, falcopayload, c.config().AWS.S3.RawEventKey))
+	}
 
 	prefix := ""
 	t := time.Now()
-	if c.Config.AWS.S3.Prefix != "" {
-		prefix = c.Config.AWS.S3.Prefix
+	if c.config().AWS.S3.Prefix != "" {
+		prefix = c.config().AWS.S3.Prefix
 	}
 
 	key := fmt.Sprintf("%s/%s/%s.json", prefix, t.Format("2006-01-02"), t.Format(time.RFC3339Nano))
+	if c.config().AWS.S3.EncryptionKey != "" || c.config().AWS.S3.KMSKeyID != "" {
+		var err error
+		f, err = c.encryptS3Payload(f)
+		if err != nil {
+			go c.CountMetric("outputs", 1, []string{"output:awss3", "status:error"})
+			c.PromStats.Outputs.With(map[string]string{"destination": "awss3", "status": Error}).Inc()
+			log.Printf("[ERROR] : %v S3 - Encryption failed : %v\n", c.OutputType, err.Error())
+			return err
+		}
+		key += ".enc"
+	}
+
 	resp, err := s3.New(c.AWSSession).PutObject(&s3.PutObjectInput{
-		Bucket: aws.String(c.Config.AWS.S3.Bucket),
+		Bucket: aws.String(c.config().AWS.S3.Bucket),
 		Key:    aws.String(key),
 		Body:   bytes.NewReader(f),
 	})
@@ -158,7 +196,7 @@ func (c *Client) UploadS3(falcopayload types.FalcoPayload) {
 		go c.CountMetric("outputs", 1, []string{"output:awss3", "status:error"})
 		c.PromStats.Outputs.With(map[string]string{"destination": "awss3", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v S3 - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
 	if resp.SSECustomerAlgorithm != nil {
@@ -169,19 +207,41 @@ func (c *Client) UploadS3(falcopayload types.FalcoPayload) {
 
 	go c.CountMetric("outputs", 1, []string{"output:awss3", "status:ok"})
 	c.PromStats.Outputs.With(map[string]string{"destination": "awss3", "status": "ok"}).Inc()
+
+	return nil
+}
+
+// encryptS3Payload encrypts the payload with the configured key, or with a data key generated by KMS
+func (c *Client) encryptS3Payload(payload []byte) ([]byte, error) {
+	if c.config().AWS.S3.KMSKeyID != "" {
+		dataKey, err := kms.New(c.AWSSession).GenerateDataKey(&kms.GenerateDataKeyInput{
+			KeyId:   aws.String(c.config().AWS.S3.KMSKeyID),
+			KeySpec: aws.String(kms.DataKeySpecAes256),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return encryptPayload(payload, aws.StringValue(dataKey.KeyId), dataKey.Plaintext, dataKey.CiphertextBlob)
+	}
+
+	key, err := decodeEncryptionKey(c.config().AWS.S3.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return encryptPayload(payload, c.config().AWS.S3.EncryptionKeyID, key, nil)
 }
 
 // PublishTopic sends a message to a SNS Topic
-func (c *Client) PublishTopic(falcopayload types.FalcoPayload) {
+func (c *Client) PublishTopic(ctx context.Context, falcopayload types.FalcoPayload) error {
 	svc := sns.New(c.AWSSession)
 
 	var msg *sns.PublishInput
 
-	if c.Config.AWS.SNS.RawJSON == true {
+	if c.config().AWS.SNS.RawJSON == true {
 		f, _ := json.Marshal(falcopayload)
 		msg = &sns.PublishInput{
 			Message:  aws.String(string(f)),
-			TopicArn: aws.String(c.Config.AWS.SNS.TopicArn),
+			TopicArn: aws.String(c.config().AWS.SNS.TopicArn),
 		}
 	} else {
 		msg = &sns.PublishInput{
@@ -196,7 +256,7 @@ func (c *Client) PublishTopic(falcopayload types.FalcoPayload) {
 					StringValue: aws.String(falcopayload.Rule),
 				},
 			},
-			TopicArn: aws.String(c.Config.AWS.SNS.TopicArn),
+			TopicArn: aws.String(c.config().AWS.SNS.TopicArn),
 		}
 
 		for i, j := range falcopayload.OutputFields {
@@ -212,7 +272,7 @@ func (c *Client) PublishTopic(falcopayload types.FalcoPayload) {
 		}
 	}
 
-	if c.Config.Debug == true {
+	if c.config().Debug == true {
 		p, _ := json.Marshal(msg)
 		log.Printf("[DEBUG] : %v SNS - Message : %v\n", c.OutputType, string(p))
 	}
@@ -224,28 +284,30 @@ func (c *Client) PublishTopic(falcopayload types.FalcoPayload) {
 		c.Stats.AWSSNS.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "awssns", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
 	log.Printf("[INFO]  : %v SNS - Send to topic OK (%v)\n", c.OutputType, *resp.MessageId)
 	go c.CountMetric("outputs", 1, []string{"output:awssns", "status:ok"})
 	c.Stats.AWSSNS.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "awssns", "status": OK}).Inc()
+
+	return nil
 }
 
 // SendCloudWatchLog sends a message to CloudWatch Log
-func (c *Client) SendCloudWatchLog(falcopayload types.FalcoPayload) {
+func (c *Client) SendCloudWatchLog(ctx context.Context, falcopayload types.FalcoPayload) error {
 	svc := cloudwatchlogs.New(c.AWSSession)
 
 	f, _ := json.Marshal(falcopayload)
 
 	c.Stats.AWSCloudWatchLogs.Add(Total, 1)
 
-	if c.Config.AWS.CloudWatchLogs.LogStream == "" {
+	if c.config().AWS.CloudWatchLogs.LogStream == "" {
 		streamName := "falcosidekick-logstream"
 		log.Printf("[INFO]  : %v CloudWatchLogs - Log Stream not configured creating one called %s\n", c.OutputType, streamName)
 		inputLogStream := &cloudwatchlogs.CreateLogStreamInput{
-			LogGroupName:  aws.String(c.Config.AWS.CloudWatchLogs.LogGroup),
+			LogGroupName:  aws.String(c.config().AWS.CloudWatchLogs.LogGroup),
 			LogStreamName: aws.String(streamName),
 		}
 
@@ -258,11 +320,11 @@ func (c *Client) SendCloudWatchLog(falcopayload types.FalcoPayload) {
 				c.Stats.AWSCloudWatchLogs.Add(Error, 1)
 				c.PromStats.Outputs.With(map[string]string{"destination": "awscloudwatchlogs", "status": Error}).Inc()
 				log.Printf("[ERROR] : %v CloudWatchLogs - %v\n", c.OutputType, err.Error())
-				return
+				return err
 			}
 		}
 
-		c.Config.AWS.CloudWatchLogs.LogStream = streamName
+		c.config().AWS.CloudWatchLogs.LogStream = streamName
 	}
 
 	logevent := &cloudwatchlogs.InputLogEvent{
@@ -272,8 +334,8 @@ func (c *Client) SendCloudWatchLog(falcopayload types.FalcoPayload) {
 
 	input := &cloudwatchlogs.PutLogEventsInput{
 		LogEvents:     []*cloudwatchlogs.InputLogEvent{logevent},
-		LogGroupName:  aws.String(c.Config.AWS.CloudWatchLogs.LogGroup),
-		LogStreamName: aws.String(c.Config.AWS.CloudWatchLogs.LogStream),
+		LogGroupName:  aws.String(c.config().AWS.CloudWatchLogs.LogGroup),
+		LogStreamName: aws.String(c.config().AWS.CloudWatchLogs.LogStream),
 	}
 
 	var err error
@@ -283,13 +345,15 @@ func (c *Client) SendCloudWatchLog(falcopayload types.FalcoPayload) {
 		c.Stats.AWSCloudWatchLogs.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "awscloudwatchlogs", "status": Error}).Inc()
 		log.Printf("[ERROR] : %v CloudWatchLogs - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
 	log.Printf("[INFO]  : %v CloudWatchLogs - Send Log OK (%v)\n", c.OutputType, resp.String())
 	go c.CountMetric("outputs", 1, []string{"output:awscloudwatchlogs", "status:ok"})
 	c.Stats.AWSCloudWatchLogs.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "awscloudwatchlogs", "status": OK}).Inc()
+
+	return nil
 }
 
 // PutLogEvents will attempt to execute and handle invalid tokens.
@@ -0,0 +1,167 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Limits of the PutRecords requests
+const (
+	// KinesisMaxBatchSize is the maximum number of records of a request
+	KinesisMaxBatchSize int = 500
+	// KinesisMaxRequestSize is the maximum size in bytes of the records of a request, partition keys included
+	KinesisMaxRequestSize int = 5 * 1024 * 1024
+	// KinesisMaxRecordSize is the maximum size in bytes of a record, partition key included
+	KinesisMaxRecordSize int = 1024 * 1024
+
+	kinesisMaxPartitionKeyLength = 256
+)
+
+// kinesisBuffer buffers the events of the AWS Kinesis output, they're put by batches of BatchSize records, or less
+// to stay under KinesisMaxRequestSize
+type kinesisBuffer struct {
+	*batcher
+	client kinesisiface.KinesisAPI
+}
+
+// newKinesisBuffer returns the buffer of the stream configured, the batches are put every FlushInterval
+func (c *Client) newKinesisBuffer(client kinesisiface.KinesisAPI) *kinesisBuffer {
+	b := &kinesisBuffer{client: client}
+	b.batcher = newBatcher(c.config().AWS.Kinesis.BatchSize, KinesisMaxRequestSize, time.Duration(c.config().AWS.Kinesis.FlushInterval)*time.Second, c.putKinesisRecords, c.exponentialBackoff)
+	return b
+}
+
+// kinesisPartitionKey returns the partition key of an event, the value of the template of its fields, the rule of the
+// event if it's empty
+func kinesisPartitionKey(partitionKey *template.Template, falcopayload types.FalcoPayload) (string, error) {
+	key, err := executeFieldTemplate(partitionKey, falcopayload, nil)
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		key = falcopayload.Rule
+	}
+	if len(key) > kinesisMaxPartitionKeyLength {
+		key = key[:kinesisMaxPartitionKeyLength]
+	}
+	return key, nil
+}
+
+// PutRecord buffers the event to put it to the Kinesis stream with the next batch, once BatchSize events are buffered
+// or every FlushInterval, its partition key is the one of PartitionKey. It returns once its batch has been put.
+func (c *Client) PutRecord(ctx context.Context, falcopayload types.FalcoPayload) error {
+	result, err := c.bufferKinesisRecord(falcopayload)
+	if err != nil {
+		return err
+	}
+	return waitBatch(ctx, result)
+}
+
+func (c *Client) bufferKinesisRecord(falcopayload types.FalcoPayload) (<-chan error, error) {
+	c.Stats.AWSKinesis.Add(Total, 1)
+
+	data, err := json.Marshal(falcopayload)
+	var key string
+	if err == nil {
+		key, err = kinesisPartitionKey(c.config().AWS.Kinesis.PartitionKeyTemplate, falcopayload)
+	}
+	if err == nil {
+		size := len(data) + len(key)
+		if size > KinesisMaxRecordSize {
+			err = fmt.Errorf("Record of %v bytes is over the maximum of %v bytes", size, KinesisMaxRecordSize)
+		} else {
+			c.checkPayloadSize(size, falcopayload.Rule)
+			return c.awsKinesis.add(falcopayload, &kinesis.PutRecordsRequestEntry{Data: data, PartitionKey: aws.String(key)}, size), nil
+		}
+	}
+	c.setKinesisMetrics(Error, 1)
+	logEventError("AWS Kinesis", falcopayload, err)
+	return nil, err
+}
+
+// AWSKinesisFlush puts all the events buffered, it's called at shutdown
+func (c *Client) AWSKinesisFlush() {
+	if c.awsKinesis != nil {
+		c.awsKinesis.Flush()
+	}
+}
+
+// putKinesisRecords puts a batch to the stream, the records failing are put again, up to MaxAttempts times, after a
+// backoff if the throughput of the stream is exceeded. The events of the records still failing are dead-lettered.
+func (c *Client) putKinesisRecords(records []*batchItem) []*batchItem {
+	for attempt := 0; ; attempt++ {
+		input := &kinesis.PutRecordsInput{StreamName: aws.String(c.config().AWS.Kinesis.StreamName)}
+		for _, i := range records {
+			input.Records = append(input.Records, i.value.(*kinesis.PutRecordsRequestEntry))
+		}
+
+		var failed []*batchItem
+		var lastErr error
+		throttled := false
+		resp, err := c.awsKinesis.client.PutRecords(input)
+		if err != nil {
+			if e, ok := err.(awserr.Error); !ok || e.Code() != kinesis.ErrCodeProvisionedThroughputExceededException {
+				c.failKinesisRecords(records, err)
+				return nil
+			}
+			failed, lastErr, throttled = records, err, true
+		} else if len(resp.Records) != len(records) {
+			c.failKinesisRecords(records, fmt.Errorf("Response of %v records for %v records put", len(resp.Records), len(records)))
+			return nil
+		} else {
+			for n, i := range resp.Records {
+				if i.ErrorCode == nil {
+					records[n].done(nil)
+					continue
+				}
+				failed = append(failed, records[n])
+				lastErr = fmt.Errorf("%v : %v", aws.StringValue(i.ErrorCode), aws.StringValue(i.ErrorMessage))
+				throttled = throttled || aws.StringValue(i.ErrorCode) == kinesis.ErrCodeProvisionedThroughputExceededException
+			}
+		}
+
+		if sent := len(records) - len(failed); sent > 0 {
+			c.setKinesisMetrics(OK, sent)
+			log.Printf("[INFO]  : AWS Kinesis - Put %v records OK\n", sent)
+		}
+		if len(failed) == 0 {
+			return nil
+		}
+		if attempt+1 >= c.config().AWS.Kinesis.MaxAttempts {
+			c.failKinesisRecords(failed, lastErr)
+			return nil
+		}
+		log.Printf("[WARN]  : AWS Kinesis - %v records failed, put them again : %v\n", len(failed), lastErr)
+		c.countRetry()
+		if throttled {
+			time.Sleep(c.exponentialBackoff(attempt))
+		}
+		records = failed
+	}
+}
+
+func (c *Client) failKinesisRecords(records []*batchItem, err error) {
+	c.setKinesisMetrics(Error, len(records))
+	log.Printf("[ERROR] : AWS Kinesis - %v records failed : %v\n", len(records), err)
+	for _, i := range records {
+		c.deadLetter(i.falcopayload, err)
+		i.done(err)
+	}
+}
+
+func (c *Client) setKinesisMetrics(status string, n int) {
+	go c.CountMetric(Outputs, int64(n), []string{"output:awskinesis", "status:" + status})
+	c.Stats.AWSKinesis.Add(status, int64(n))
+	c.PromStats.Outputs.With(map[string]string{"destination": "awskinesis", "status": status}).Add(float64(n))
+}
@@ -0,0 +1,133 @@
+package outputs
+
+import (
+	"encoding/json"
+	"expvar"
+	"strconv"
+	"sync"
+	"testing"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+type mockKinesis struct {
+	kinesisiface.KinesisAPI
+	mu     sync.Mutex
+	inputs []*kinesis.PutRecordsInput
+	fail   func(attempt int, entry *kinesis.PutRecordsRequestEntry) string // error code of a record, "" if it's put
+}
+
+func (m *mockKinesis) PutRecords(input *kinesis.PutRecordsInput) (*kinesis.PutRecordsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attempt := len(m.inputs)
+	m.inputs = append(m.inputs, input)
+	resp := new(kinesis.PutRecordsOutput)
+	for _, i := range input.Records {
+		r := &kinesis.PutRecordsResultEntry{SequenceNumber: aws.String("1")}
+		if m.fail != nil {
+			if code := m.fail(attempt, i); code != "" {
+				r = &kinesis.PutRecordsResultEntry{ErrorCode: aws.String(code), ErrorMessage: aws.String("Rate exceeded for shard")}
+				resp.FailedRecordCount = aws.Int64(aws.Int64Value(resp.FailedRecordCount) + 1)
+			}
+		}
+		resp.Records = append(resp.Records, r)
+	}
+	return resp, nil
+}
+
+func newTestKinesisClient(m *mockKinesis) *Client {
+	config := &types.Configuration{}
+	config.AWS.Kinesis.StreamName = "falco"
+	config.AWS.Kinesis.PartitionKeyTemplate = template.Must(parseFieldTemplate("", "{{ .Hostname }}"))
+	config.AWS.Kinesis.BatchSize = KinesisMaxBatchSize
+	config.AWS.Kinesis.MaxAttempts = 3
+	c := &Client{OutputType: "AWS", Config: config, Stats: &types.Statistics{AWSKinesis: new(expvar.Map)}, PromStats: newTestPromStats()}
+	c.awsKinesis = c.newKinesisBuffer(m)
+	return c
+}
+
+func TestPutRecordBatches(t *testing.T) {
+	m := new(mockKinesis)
+	c := newTestKinesisClient(m)
+
+	var results []<-chan error
+	for i := 0; i < 600; i++ {
+		result, err := c.bufferKinesisRecord(types.FalcoPayload{Rule: "Test rule", Hostname: "node-" + strconv.Itoa(i%3), Output: strconv.Itoa(i)})
+		require.Nil(t, err)
+		results = append(results, result)
+	}
+	// the full batch is put, the other events wait for the next flush
+	for _, i := range results[:500] {
+		require.Nil(t, <-i)
+	}
+	require.Len(t, m.inputs, 1)
+	c.AWSKinesisFlush()
+	for _, i := range results[500:] {
+		require.Nil(t, <-i)
+	}
+	require.Len(t, m.inputs, 2)
+	require.Len(t, m.inputs[0].Records, 500)
+	require.Len(t, m.inputs[1].Records, 100)
+
+	for n, input := range m.inputs {
+		require.Equal(t, "falco", aws.StringValue(input.StreamName))
+		for i, j := range input.Records {
+			var f types.FalcoPayload
+			require.Nil(t, json.Unmarshal(j.Data, &f))
+			require.Equal(t, strconv.Itoa(n*500+i), f.Output)
+			require.Equal(t, f.Hostname, aws.StringValue(j.PartitionKey))
+			require.Equal(t, "node-"+strconv.Itoa((n*500+i)%3), aws.StringValue(j.PartitionKey))
+		}
+	}
+	require.Equal(t, "600", c.Stats.AWSKinesis.Get(OK).String())
+
+	// without the field, the partition key is the rule
+	key, err := kinesisPartitionKey(c.Config.AWS.Kinesis.PartitionKeyTemplate, types.FalcoPayload{Rule: "Test rule"})
+	require.Nil(t, err)
+	require.Equal(t, "Test rule", key)
+}
+
+func TestPutRecordRetries(t *testing.T) {
+	m := &mockKinesis{fail: func(attempt int, entry *kinesis.PutRecordsRequestEntry) string {
+		switch {
+		case aws.StringValue(entry.PartitionKey) == "throttled" && attempt < 2:
+			return kinesis.ErrCodeProvisionedThroughputExceededException
+		case aws.StringValue(entry.PartitionKey) == "rejected":
+			return "InternalFailure"
+		}
+		return ""
+	}}
+	c := newTestKinesisClient(m)
+
+	var results []<-chan error
+	for _, i := range []string{"node-1", "throttled", "rejected", "node-2"} {
+		result, err := c.bufferKinesisRecord(types.FalcoPayload{Rule: "Test rule", Hostname: i})
+		require.Nil(t, err)
+		results = append(results, result)
+	}
+	c.AWSKinesisFlush()
+	require.Nil(t, <-results[1])
+	require.EqualError(t, <-results[2], "InternalFailure : Rate exceeded for shard")
+
+	// only the records failing are put again, up to MaxAttempts times
+	require.Len(t, m.inputs, 3)
+	require.Len(t, m.inputs[0].Records, 4)
+	keys := func(input *kinesis.PutRecordsInput) []string {
+		var k []string
+		for _, i := range input.Records {
+			k = append(k, aws.StringValue(i.PartitionKey))
+		}
+		return k
+	}
+	require.Equal(t, []string{"throttled", "rejected"}, keys(m.inputs[1]))
+	require.Equal(t, []string{"throttled", "rejected"}, keys(m.inputs[2]))
+	require.Equal(t, "3", c.Stats.AWSKinesis.Get(OK).String())
+	require.Equal(t, "1", c.Stats.AWSKinesis.Get(Error).String())
+}
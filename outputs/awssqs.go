@@ -0,0 +1,184 @@
+package outputs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Deduplication strategies of the messages sent to a FIFO queue
+const (
+	SQSContentBasedDeduplication string = "contentbased" // the queue deduplicates by the SHA-256 of the body
+	SQSIDDeduplication           string = "id"           // the deduplication ID of the message is set
+)
+
+// sqsBatchSenders is the number of SendMessageBatch calls in progress at most for a standard queue, a FIFO queue has a
+// single one so the messages are sent in order
+const sqsBatchSenders = 4
+
+// SQSMaxBatchPayloadSize is the maximum size in bytes of the bodies of the messages of a batch
+const SQSMaxBatchPayloadSize = 256 * 1024
+
+// sqsSender sends the messages to the queue, with their message group and deduplication IDs if it's a FIFO queue.
+// With a BatchSize above 1, the messages waiting to be sent are sent together with SendMessageBatch.
+type sqsSender struct {
+	client          sqsiface.SQSAPI
+	url             string
+	fifo            bool
+	groupID         *template.Template
+	deduplicationID *template.Template // nil with the content-based deduplication, empty for the SHA-256 of the body
+	batchSize       int
+	queue           chan *sqsEntry // nil without batching
+}
+
+// sqsEntry is a message waiting to be sent in a batch, the result of the sending is sent to result
+type sqsEntry struct {
+	body            string
+	groupID         string
+	deduplicationID string
+	result          chan error
+}
+
+// IsFIFOQueue returns true if the URL is the one of a FIFO queue
+func IsFIFOQueue(url string) bool {
+	return strings.HasSuffix(url, ".fifo")
+}
+
+// newSQSSender returns the sender of the queue configured, the templates of the IDs are parsed for a FIFO queue
+func newSQSSender(config *types.Configuration, client sqsiface.SQSAPI) (*sqsSender, error) {
+	s := &sqsSender{client: client, url: config.AWS.SQS.URL, fifo: IsFIFOQueue(config.AWS.SQS.URL), batchSize: config.AWS.SQS.BatchSize}
+	if s.fifo {
+		var err error
+		if s.groupID, err = parseFieldTemplate("messagegroupid", config.AWS.SQS.MessageGroupID); err != nil {
+			return nil, fmt.Errorf("Bad MessageGroupID : %v", err)
+		}
+		if config.AWS.SQS.Deduplication == SQSIDDeduplication {
+			if s.deduplicationID, err = parseFieldTemplate("deduplicationid", config.AWS.SQS.DeduplicationID); err != nil {
+				return nil, fmt.Errorf("Bad DeduplicationID : %v", err)
+			}
+		}
+	}
+	if s.batchSize > 1 {
+		senders := sqsBatchSenders
+		if s.fifo {
+			senders = 1
+		}
+		s.queue = make(chan *sqsEntry, s.batchSize*senders)
+		for i := 0; i < senders; i++ {
+			go s.sendBatches()
+		}
+	}
+	return s, nil
+}
+
+// send sends the message of an event, in a batch if it's enabled
+func (s *sqsSender) send(falcopayload types.FalcoPayload, body string) (string, error) {
+	e := &sqsEntry{body: body}
+	if s.fifo {
+		var err error
+		if e.groupID, err = executeFieldTemplate(s.groupID, falcopayload, nil); err != nil {
+			return "", err
+		}
+		if e.groupID == "" {
+			return "", fmt.Errorf("Empty message group ID for the rule '%v'", falcopayload.Rule)
+		}
+		if s.deduplicationID != nil {
+			if e.deduplicationID, err = executeFieldTemplate(s.deduplicationID, falcopayload, nil); err != nil {
+				return "", err
+			}
+			if e.deduplicationID == "" {
+				h := sha256.Sum256([]byte(body))
+				e.deduplicationID = hex.EncodeToString(h[:])
+			}
+		}
+	}
+
+	if s.queue != nil {
+		e.result = make(chan error, 1)
+		s.queue <- e
+		return "", <-e.result
+	}
+
+	resp, err := s.client.SendMessage(&sqs.SendMessageInput{
+		MessageBody:            aws.String(e.body),
+		QueueUrl:               aws.String(s.url),
+		MessageGroupId:         optionalString(e.groupID),
+		MessageDeduplicationId: optionalString(e.deduplicationID),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(resp.MessageId), nil
+}
+
+// sendBatches sends the entries queued, with the ones waiting to be sent up to BatchSize and SQSMaxBatchPayloadSize,
+// an entry which doesn't fit in the batch is the first one of the next batch
+func (s *sqsSender) sendBatches() {
+	var next *sqsEntry
+	for {
+		e := next
+		if e == nil {
+			var ok bool
+			if e, ok = <-s.queue; !ok {
+				return
+			}
+		}
+		next = nil
+		batch, size := []*sqsEntry{e}, len(e.body)
+	fill:
+		for len(batch) < s.batchSize {
+			select {
+			case e := <-s.queue:
+				if size+len(e.body) > SQSMaxBatchPayloadSize {
+					next = e
+					break fill
+				}
+				batch = append(batch, e)
+				size += len(e.body)
+			default:
+				break fill
+			}
+		}
+
+		input := &sqs.SendMessageBatchInput{QueueUrl: aws.String(s.url)}
+		for i, j := range batch {
+			input.Entries = append(input.Entries, &sqs.SendMessageBatchRequestEntry{
+				Id:                     aws.String(strconv.Itoa(i)),
+				MessageBody:            aws.String(j.body),
+				MessageGroupId:         optionalString(j.groupID),
+				MessageDeduplicationId: optionalString(j.deduplicationID),
+			})
+		}
+		resp, err := s.client.SendMessageBatch(input)
+		if err != nil {
+			for _, i := range batch {
+				i.result <- err
+			}
+			continue
+		}
+		failed := make(map[string]error, len(resp.Failed))
+		for _, i := range resp.Failed {
+			failed[aws.StringValue(i.Id)] = fmt.Errorf("%v : %v", aws.StringValue(i.Code), aws.StringValue(i.Message))
+		}
+		for i, j := range batch {
+			j.result <- failed[strconv.Itoa(i)]
+		}
+	}
+}
+
+// optionalString returns nil for an empty string, the optional parameters of the AWS API can't be empty
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
@@ -0,0 +1,232 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+type mockSQS struct {
+	sqsiface.SQSAPI
+	mu      sync.Mutex
+	inputs  []*sqs.SendMessageInput
+	batches []*sqs.SendMessageBatchInput
+	release chan struct{} // if not nil, the batches are sent once it's closed
+}
+
+func (m *mockSQS) SendMessage(input *sqs.SendMessageInput) (*sqs.SendMessageOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inputs = append(m.inputs, input)
+	return &sqs.SendMessageOutput{MessageId: aws.String("id")}, nil
+}
+
+func (m *mockSQS) SendMessageBatch(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+	m.mu.Lock()
+	m.batches = append(m.batches, input)
+	m.mu.Unlock()
+	if m.release != nil {
+		<-m.release
+	}
+	resp := new(sqs.SendMessageBatchOutput)
+	for _, i := range input.Entries {
+		if strings.Contains(aws.StringValue(i.MessageBody), `"rejected"`) {
+			resp.Failed = append(resp.Failed, &sqs.BatchResultErrorEntry{Id: i.Id, Code: aws.String("InvalidParameterValue"), Message: aws.String("rejected")})
+		}
+	}
+	return resp, nil
+}
+
+func newTestSQSClient(t *testing.T, config *types.Configuration, m *mockSQS) *Client {
+	s, err := newSQSSender(config, m)
+	require.Nil(t, err)
+	return &Client{OutputType: "AWS", Config: config, awsSQS: s, Stats: &types.Statistics{AWSSQS: new(expvar.Map)}, PromStats: newTestPromStats()}
+}
+
+func TestSendMessageFIFO(t *testing.T) {
+	falcopayload := types.FalcoPayload{Rule: "Test rule", Priority: types.Warning, OutputFields: map[string]interface{}{"k8s.ns.name": "falco"}}
+
+	config := &types.Configuration{}
+	config.AWS.SQS.URL = "https://sqs.eu-west-1.amazonaws.com/123456789012/falco.fifo"
+	config.AWS.SQS.MessageGroupID = `{{or (index .OutputFields "k8s.ns.name") "host"}}`
+	config.AWS.SQS.Deduplication = SQSIDDeduplication
+	config.AWS.SQS.DeduplicationID = "{{.Rule}}"
+	m := new(mockSQS)
+	c := newTestSQSClient(t, config, m)
+	require.Nil(t, c.SendMessage(context.Background(), falcopayload))
+	require.Nil(t, c.SendMessage(context.Background(), types.FalcoPayload{Rule: "Other rule"}))
+	require.Len(t, m.inputs, 2)
+	require.Equal(t, config.AWS.SQS.URL, aws.StringValue(m.inputs[0].QueueUrl))
+	require.Equal(t, "falco", aws.StringValue(m.inputs[0].MessageGroupId))
+	require.Equal(t, "Test rule", aws.StringValue(m.inputs[0].MessageDeduplicationId))
+	require.Equal(t, "host", aws.StringValue(m.inputs[1].MessageGroupId))
+	require.Equal(t, "Other rule", aws.StringValue(m.inputs[1].MessageDeduplicationId))
+
+	// without a template, the deduplication ID is the SHA-256 of the body
+	config.AWS.SQS.DeduplicationID = ""
+	m = new(mockSQS)
+	c = newTestSQSClient(t, config, m)
+	require.Nil(t, c.SendMessage(context.Background(), falcopayload))
+	require.Len(t, aws.StringValue(m.inputs[0].MessageDeduplicationId), 64)
+
+	// the queue deduplicates the messages itself
+	config.AWS.SQS.Deduplication = SQSContentBasedDeduplication
+	m = new(mockSQS)
+	c = newTestSQSClient(t, config, m)
+	require.Nil(t, c.SendMessage(context.Background(), falcopayload))
+	require.Equal(t, "falco", aws.StringValue(m.inputs[0].MessageGroupId))
+	require.Nil(t, m.inputs[0].MessageDeduplicationId)
+
+	// an empty message group ID is an error
+	config.AWS.SQS.MessageGroupID = `{{index .OutputFields "k8s.pod.name"}}`
+	c = newTestSQSClient(t, config, m)
+	require.NotNil(t, c.SendMessage(context.Background(), types.FalcoPayload{Rule: "Test rule"}))
+	require.Equal(t, "1", c.Stats.AWSSQS.Get(Error).String())
+
+	// a standard queue has neither
+	config.AWS.SQS.URL = "https://sqs.eu-west-1.amazonaws.com/123456789012/falco"
+	m = new(mockSQS)
+	c = newTestSQSClient(t, config, m)
+	require.Nil(t, c.SendMessage(context.Background(), falcopayload))
+	require.Nil(t, m.inputs[0].MessageGroupId)
+	require.Nil(t, m.inputs[0].MessageDeduplicationId)
+}
+
+func TestSendMessageBatch(t *testing.T) {
+	config := &types.Configuration{}
+	config.AWS.SQS.URL = "https://sqs.eu-west-1.amazonaws.com/123456789012/falco"
+	config.AWS.SQS.BatchSize = 10
+	m := &mockSQS{release: make(chan struct{})}
+	c := newTestSQSClient(t, config, m)
+
+	// the senders are all busy, the messages sent meanwhile are batched
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 40; i++ {
+		rule := "Test rule"
+		if i == 39 {
+			rule = "rejected"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- c.SendMessage(context.Background(), types.FalcoPayload{Rule: rule})
+		}()
+	}
+	require.Eventually(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		n := len(c.awsSQS.queue)
+		for _, i := range m.batches {
+			n += len(i.Entries)
+		}
+		return len(m.batches) == sqsBatchSenders && n == 40
+	}, time.Second, 10*time.Millisecond)
+	close(m.release)
+	wg.Wait()
+	close(errs)
+
+	var failed int
+	for err := range errs {
+		if err != nil {
+			require.Equal(t, "InvalidParameterValue : rejected", err.Error())
+			failed++
+		}
+	}
+	require.Equal(t, 1, failed)
+
+	var sent int
+	for _, i := range m.batches {
+		require.True(t, len(i.Entries) <= 10)
+		for _, j := range i.Entries {
+			require.Nil(t, j.MessageGroupId)
+			require.Nil(t, j.MessageDeduplicationId)
+		}
+		sent += len(i.Entries)
+	}
+	require.Equal(t, 40, sent)
+	require.True(t, len(m.batches) < 20)
+	require.Equal(t, "39", c.Stats.AWSSQS.Get(OK).String())
+}
+
+func TestSendMessageBatchFIFO(t *testing.T) {
+	config := &types.Configuration{}
+	config.AWS.SQS.URL = "https://sqs.eu-west-1.amazonaws.com/123456789012/falco.fifo"
+	config.AWS.SQS.MessageGroupID = "falco"
+	config.AWS.SQS.Deduplication = SQSContentBasedDeduplication
+	config.AWS.SQS.BatchSize = 10
+	m := &mockSQS{release: make(chan struct{})}
+	c := newTestSQSClient(t, config, m)
+
+	// a single batch is in progress, the messages sent meanwhile are sent in order in the next batches
+	errs := make(chan error, 11)
+	go func() {
+		errs <- c.SendMessage(context.Background(), types.FalcoPayload{Rule: "0"})
+	}()
+	require.Eventually(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return len(m.batches) == 1
+	}, time.Second, 10*time.Millisecond)
+	for i := 1; i < 11; i++ {
+		rule := strconv.Itoa(i)
+		go func() {
+			errs <- c.SendMessage(context.Background(), types.FalcoPayload{Rule: rule})
+		}()
+		require.Eventually(t, func() bool { return len(c.awsSQS.queue) == i }, time.Second, time.Millisecond)
+	}
+	m.mu.Lock()
+	require.Len(t, m.batches, 1)
+	m.mu.Unlock()
+	close(m.release)
+	for i := 0; i < 11; i++ {
+		require.Nil(t, <-errs)
+	}
+
+	var rules []string
+	for _, i := range m.batches {
+		for _, j := range i.Entries {
+			var falcopayload types.FalcoPayload
+			require.Nil(t, json.Unmarshal([]byte(aws.StringValue(j.MessageBody)), &falcopayload))
+			rules = append(rules, falcopayload.Rule)
+		}
+	}
+	require.Equal(t, []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}, rules)
+	require.Len(t, m.batches, 2)
+
+	// the batches stay under the maximum payload size
+	m = &mockSQS{release: make(chan struct{})}
+	c = newTestSQSClient(t, config, m)
+	output := strings.Repeat("a", SQSMaxBatchPayloadSize/3)
+	results := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			results <- c.SendMessage(context.Background(), types.FalcoPayload{Output: output})
+		}()
+	}
+	require.Eventually(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return len(m.batches) == 1 && len(c.awsSQS.queue) == 3
+	}, time.Second, time.Millisecond)
+	close(m.release)
+	for i := 0; i < 4; i++ {
+		require.Nil(t, <-results)
+	}
+	require.Len(t, m.batches, 3)
+	require.Len(t, m.batches[0].Entries, 1)
+	require.Len(t, m.batches[1].Entries, 2)
+	require.Len(t, m.batches[2].Entries, 1)
+}
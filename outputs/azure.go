@@ -25,15 +25,15 @@ func NewEventHubClient(config *types.Configuration, stats *types.Statistics, pro
 }
 
 // EventHubPost posts event to Azure Event Hub
-func (c *Client) EventHubPost(falcopayload types.FalcoPayload) {
+func (c *Client) EventHubPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.AzureEventHub.Add(Total, 1)
 
 	log.Printf("[INFO] : %v EventHub - Try sending event", c.OutputType)
-	hub, err := eventhub.NewHubWithNamespaceNameAndEnvironment(c.Config.Azure.EventHub.Namespace, c.Config.Azure.EventHub.Name)
+	hub, err := eventhub.NewHubWithNamespaceNameAndEnvironment(c.config().Azure.EventHub.Namespace, c.config().Azure.EventHub.Name)
 	if err != nil {
 		c.setEventHubErrorMetrics()
 		log.Printf("[ERROR] : %v EventHub - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
 	log.Printf("[INFO]  : %v EventHub - Hub client created\n", c.OutputType)
@@ -42,7 +42,7 @@ func (c *Client) EventHubPost(falcopayload types.FalcoPayload) {
 	if err != nil {
 		c.setEventHubErrorMetrics()
 		log.Printf("[ERROR] : Cannot marshal payload: %v", err.Error())
-		return
+		return err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
@@ -52,7 +52,7 @@ func (c *Client) EventHubPost(falcopayload types.FalcoPayload) {
 	if err != nil {
 		c.setEventHubErrorMetrics()
 		log.Printf("[ERROR] : %v EventHub - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
 	// Setting the success status
@@ -60,6 +60,8 @@ func (c *Client) EventHubPost(falcopayload types.FalcoPayload) {
 	c.Stats.AzureEventHub.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "azureeventhub", "status": OK}).Inc()
 	log.Printf("[INFO]  : %v EventHub - Publish OK", c.OutputType)
+
+	return nil
 }
 
 // setEventHubErrorMetrics set the error stats
@@ -0,0 +1,415 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/DataDog/datadog-go/statsd"
+	"github.com/google/uuid"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// AzureBlobAPIVersion is the version of the Blob service REST API used
+const AzureBlobAPIVersion string = "2020-04-08"
+
+// AzureBlobMaxAppendBlock is the max size of a block appended to an append blob
+const AzureBlobMaxAppendBlock int = 4 * 1024 * 1024
+
+const azureStorageResource string = "https://storage.azure.com/"
+
+// azureBlobKeyData is what the key format of the blobs is executed with, Batch changes at each flush
+type azureBlobKeyData struct {
+	types.FalcoPayload
+	Batch string
+}
+
+// azureBlobWriter buffers the events as NDJSON, by blob, until they're uploaded
+type azureBlobWriter struct {
+	endpoint    *url.URL
+	accountName string
+	accountKey  []byte
+	sasToken    url.Values
+	msiToken    *adal.ServicePrincipalToken
+	keyFormat   *template.Template
+	batchSize   int
+	appendMode  bool
+	compact     bool
+	httpClient  *http.Client
+
+	mu      sync.Mutex
+	batch   string
+	size    int
+	buffers map[string]*bytes.Buffer
+
+	uploadMu sync.Mutex
+	created  map[string]bool
+}
+
+// NewAzureBlobClient returns a new output.Client for accessing the Azure Blob Storage.
+func NewAzureBlobClient(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics, statsdClient, dogstatsdClient *statsd.Client) (*Client, error) {
+	w, err := newAzureBlobWriter(config)
+	if err != nil {
+		log.Printf("[ERROR] : AzureBlob - %v\n", err.Error())
+		return nil, ErrClientCreation
+	}
+
+	c := &Client{
+		OutputType:      "AzureBlob",
+		Config:          config,
+		Stats:           stats,
+		PromStats:       promStats,
+		StatsdClient:    statsdClient,
+		DogstatsdClient: dogstatsdClient,
+		azureBlob:       w,
+	}
+
+	if config.Azure.Blob.FlushInterval > 0 {
+		go func() {
+			for range time.Tick(time.Duration(config.Azure.Blob.FlushInterval) * time.Second) {
+				c.AzureBlobFlush()
+			}
+		}()
+	}
+	return c, nil
+}
+
+func newAzureBlobWriter(c *types.Configuration) (*azureBlobWriter, error) {
+	config := c.Azure.Blob
+	w := &azureBlobWriter{
+		accountName: config.AccountName,
+		batchSize:   config.BatchSize,
+		appendMode:  config.AppendMode,
+		compact:     config.CompactFields,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		batch:       uuid.New().String(),
+		buffers:     make(map[string]*bytes.Buffer),
+		created:     make(map[string]bool),
+	}
+	// leaves room for the event reaching the batch size, blocks can't be bigger than AzureBlobMaxAppendBlock
+	if w.appendMode && (w.batchSize <= 0 || w.batchSize > AzureBlobMaxAppendBlock/2) {
+		w.batchSize = AzureBlobMaxAppendBlock / 2
+	}
+
+	endpoint := config.Endpoint
+	sasToken := config.SASToken
+	if config.ConnectionString != "" {
+		s, err := parseAzureConnectionString(config.ConnectionString)
+		if err != nil {
+			return nil, err
+		}
+		if s["accountname"] != "" {
+			w.accountName = s["accountname"]
+		}
+		if s["accountkey"] != "" {
+			key, err := base64.StdEncoding.DecodeString(s["accountkey"])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid account key : %v", err)
+			}
+			w.accountKey = key
+		}
+		if sasToken == "" {
+			sasToken = s["sharedaccesssignature"]
+		}
+		if endpoint == "" && s["blobendpoint"] != "" {
+			endpoint = s["blobendpoint"]
+		}
+		if endpoint == "" && w.accountName != "" {
+			protocol, suffix := "https", "core.windows.net"
+			if s["defaultendpointsprotocol"] != "" {
+				protocol = s["defaultendpointsprotocol"]
+			}
+			if s["endpointsuffix"] != "" {
+				suffix = s["endpointsuffix"]
+			}
+			endpoint = fmt.Sprintf("%v://%v.blob.%v", protocol, w.accountName, suffix)
+		}
+	}
+	if endpoint == "" {
+		if w.accountName == "" {
+			return nil, errors.New("Account name or connection string is required")
+		}
+		endpoint = fmt.Sprintf("https://%v.blob.core.windows.net", w.accountName)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.New("Bad Endpoint")
+	}
+	u.Path = path.Join(u.Path, config.Container)
+	w.endpoint = u
+
+	switch {
+	case w.accountKey != nil:
+	case sasToken != "":
+		v, err := url.ParseQuery(strings.TrimPrefix(sasToken, "?"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid SAS token : %v", err)
+		}
+		w.sasToken = v
+	case config.UseManagedIdentity:
+		msiEndpoint, err := adal.GetMSIVMEndpoint()
+		if err != nil {
+			return nil, err
+		}
+		w.msiToken, err = adal.NewServicePrincipalTokenFromMSI(msiEndpoint, azureStorageResource)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("No credentials, a connection string with an account key, a SAS token or the managed identity is required")
+	}
+
+	w.keyFormat, err = template.New("key").Funcs(template.FuncMap{"uuid": func() string { return uuid.New().String() }}).Parse(config.KeyFormat)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid key format : %v", err)
+	}
+	return w, nil
+}
+
+// parseAzureConnectionString returns the settings of a connection string, with lowercased names
+func parseAzureConnectionString(connectionString string) (map[string]string, error) {
+	s := make(map[string]string)
+	for _, i := range strings.Split(connectionString, ";") {
+		if strings.TrimSpace(i) == "" {
+			continue
+		}
+		kv := strings.SplitN(i, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.New("Invalid connection string")
+		}
+		s[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.TrimSpace(kv[1])
+	}
+	return s, nil
+}
+
+// AzureBlobPost adds an event to the current batch, which is uploaded if it reaches the batch size
+func (c *Client) AzureBlobPost(ctx context.Context, falcopayload types.FalcoPayload) error {
+	c.Stats.AzureBlob.Add(Total, 1)
+	w := c.azureBlob
+
+	line, err := json.Marshal(withRawEvent(falcopayload, falcopayload, c.config().Azure.Blob.RawEventKey))
+	if err != nil {
+		c.setAzureBlobMetrics(Error, 1)
+		logEventError("AzureBlob", falcopayload, err)
+		return err
+	}
+
+	w.mu.Lock()
+	key := new(bytes.Buffer)
+	if err := w.keyFormat.Execute(key, azureBlobKeyData{FalcoPayload: falcopayload, Batch: w.batch}); err != nil {
+		w.mu.Unlock()
+		c.setAzureBlobMetrics(Error, 1)
+		logEventError("AzureBlob", falcopayload, err)
+		return err
+	}
+	b, ok := w.buffers[key.String()]
+	if !ok {
+		b = new(bytes.Buffer)
+		w.buffers[key.String()] = b
+	}
+	b.Write(line)
+	b.WriteByte('\n')
+	w.size += len(line) + 1
+	full := w.batchSize > 0 && w.size >= w.batchSize
+	w.mu.Unlock()
+
+	if full {
+		c.AzureBlobFlush()
+	}
+
+	return nil
+}
+
+// AzureBlobFlush uploads the buffered events, it's called periodically and at shutdown
+func (c *Client) AzureBlobFlush() {
+	w := c.azureBlob
+
+	w.mu.Lock()
+	buffers := w.buffers
+	w.buffers = make(map[string]*bytes.Buffer)
+	w.batch = uuid.New().String()
+	w.size = 0
+	w.mu.Unlock()
+
+	w.uploadMu.Lock()
+	defer w.uploadMu.Unlock()
+	for key, b := range buffers {
+		n := bytes.Count(b.Bytes(), []byte("\n"))
+		body := b.Bytes()
+		if w.compact {
+			body = compactAzureBlobBatch(body)
+		}
+		if err := w.upload(key, body); err != nil {
+			c.setAzureBlobMetrics(Error, n)
+			log.Printf("[ERROR] : AzureBlob - %v\n", err.Error())
+			continue
+		}
+		c.setAzureBlobMetrics(OK, n)
+		log.Printf("[INFO]  : AzureBlob - Upload of %v events to %v OK\n", n, key)
+	}
+}
+
+// compactAzureBlobBatch factors out the output fields identical across all the events of a batch, they're written once
+// in a header line {"shared_output_fields": {...}} and omitted from the events following it
+func compactAzureBlobBatch(body []byte) []byte {
+	events, shared := compactOutputFields(bytes.Split(bytes.TrimSuffix(body, []byte("\n")), []byte("\n")), "output_fields")
+	if shared == nil {
+		return body
+	}
+
+	compacted := new(bytes.Buffer)
+	header, _ := json.Marshal(map[string]interface{}{SharedOutputFields: shared})
+	compacted.Write(header)
+	compacted.WriteByte('\n')
+	for _, i := range events {
+		line, _ := json.Marshal(i)
+		compacted.Write(line)
+		compacted.WriteByte('\n')
+	}
+	return compacted.Bytes()
+}
+
+func (c *Client) setAzureBlobMetrics(status string, n int) {
+	go c.CountMetric(Outputs, int64(n), []string{"output:azureblob", "status:" + status})
+	c.Stats.AzureBlob.Add(status, int64(n))
+	c.PromStats.Outputs.With(map[string]string{"destination": "azureblob", "status": status}).Add(float64(n))
+}
+
+// upload writes the events to a new block blob or appends them to an append blob
+func (w *azureBlobWriter) upload(key string, body []byte) error {
+	if !w.appendMode {
+		return w.do(http.MethodPut, key, nil, map[string]string{"x-ms-blob-type": "BlockBlob"}, body, http.StatusCreated)
+	}
+
+	if !w.created[key] {
+		// the blob may already exist, from a previous run for example
+		if err := w.do(http.MethodPut, key, nil, map[string]string{"x-ms-blob-type": "AppendBlob", "If-None-Match": "*"}, nil, http.StatusCreated, http.StatusConflict); err != nil {
+			return err
+		}
+		w.created[key] = true
+	}
+	return w.do(http.MethodPut, key, url.Values{"comp": {"appendblock"}}, nil, body, http.StatusCreated)
+}
+
+func (w *azureBlobWriter) do(method, key string, query url.Values, headers map[string]string, body []byte, expected ...int) error {
+	u := *w.endpoint
+	u.Path = path.Join(u.Path, key)
+	q := url.Values{}
+	for i, j := range query {
+		q[i] = j
+	}
+	for i, j := range w.sasToken {
+		q[i] = j
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", AzureBlobAPIVersion)
+	req.Header.Set("User-Agent", "Falcosidekick")
+	if len(body) != 0 {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	}
+	for i, j := range headers {
+		req.Header.Set(i, j)
+	}
+
+	switch {
+	case w.accountKey != nil:
+		req.Header.Set("Authorization", "SharedKey "+w.accountName+":"+w.sign(req))
+	case w.msiToken != nil:
+		if err := w.msiToken.EnsureFresh(); err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+w.msiToken.OAuthToken())
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	for _, i := range expected {
+		if resp.StatusCode == i {
+			return nil
+		}
+	}
+	message, _ := ioutil.ReadAll(resp.Body)
+	return fmt.Errorf("Unexpected Response (%v) : %s", resp.StatusCode, bytes.TrimSpace(message))
+}
+
+// sign returns the Shared Key signature of a request
+func (w *azureBlobWriter) sign(req *http.Request) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var msHeaders []string
+	for i := range req.Header {
+		if i = strings.ToLower(i); strings.HasPrefix(i, "x-ms-") {
+			msHeaders = append(msHeaders, i)
+		}
+	}
+	sort.Strings(msHeaders)
+	var canonicalizedHeaders string
+	for _, i := range msHeaders {
+		canonicalizedHeaders += i + ":" + strings.TrimSpace(req.Header.Get(i)) + "\n"
+	}
+
+	canonicalizedResource := "/" + w.accountName + req.URL.EscapedPath()
+	query := req.URL.Query()
+	var params []string
+	for i := range query {
+		params = append(params, i)
+	}
+	sort.Strings(params)
+	for _, i := range params {
+		values := query[i]
+		sort.Strings(values)
+		canonicalizedResource += "\n" + strings.ToLower(i) + ":" + strings.Join(values, ",")
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, x-ms-date is used
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		canonicalizedHeaders + canonicalizedResource,
+	}, "\n")
+
+	h := hmac.New(sha256.New, w.accountKey)
+	h.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,199 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+const azuriteKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+type azuriteRequest struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   string
+}
+
+// newAzuriteStub returns a Blob service answering 201 to every request, and the requests it received
+func newAzuriteStub(t *testing.T) (*httptest.Server, func() []azuriteRequest) {
+	var mu sync.Mutex
+	var requests []azuriteRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.Nil(t, err)
+		mu.Lock()
+		requests = append(requests, azuriteRequest{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery, header: r.Header, body: string(body)})
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	return ts, func() []azuriteRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]azuriteRequest{}, requests...)
+	}
+}
+
+func newTestAzureBlobClient(t *testing.T, config *types.Configuration) *Client {
+	client, err := NewAzureBlobClient(config, &types.Statistics{AzureBlob: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	return client
+}
+
+func TestAzureBlobBlockBlob(t *testing.T) {
+	ts, requests := newAzuriteStub(t)
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Azure.Blob.Container = "falco"
+	config.Azure.Blob.ConnectionString = "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;AccountKey=" + azuriteKey + ";BlobEndpoint=" + ts.URL + "/devstoreaccount1;"
+	config.Azure.Blob.KeyFormat = `{{ .Time.Format "2006-01-02" }}/{{ index .OutputFields "proc.name" }}/{{ .Batch }}.ndjson`
+	config.Azure.Blob.BatchSize = 1048576
+	client := newTestAzureBlobClient(t, config)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	client.AzureBlobPost(context.Background(), f)
+	client.AzureBlobPost(context.Background(), f)
+	require.Empty(t, requests())
+
+	client.AzureBlobFlush()
+	r := requests()
+	require.Len(t, r, 1)
+	require.Equal(t, http.MethodPut, r[0].method)
+	require.True(t, strings.HasPrefix(r[0].path, "/devstoreaccount1/falco/2001-01-01/falcosidekick/"), r[0].path)
+	require.True(t, strings.HasSuffix(r[0].path, ".ndjson"))
+	require.Equal(t, "BlockBlob", r[0].header.Get("x-ms-blob-type"))
+	require.Equal(t, AzureBlobAPIVersion, r[0].header.Get("x-ms-version"))
+	require.Equal(t, "application/x-ndjson", r[0].header.Get("Content-Type"))
+	require.True(t, strings.HasPrefix(r[0].header.Get("Authorization"), "SharedKey devstoreaccount1:"))
+
+	lines := strings.Split(strings.TrimSuffix(r[0].body, "\n"), "\n")
+	require.Len(t, lines, 2)
+	for _, i := range lines {
+		var e types.FalcoPayload
+		require.Nil(t, json.Unmarshal([]byte(i), &e))
+		require.Equal(t, "Test rule", e.Rule)
+	}
+	require.Equal(t, "2", client.Stats.AzureBlob.Get(OK).String())
+
+	// a flush without events doesn't upload anything, the next batch has another name
+	client.AzureBlobFlush()
+	require.Len(t, requests(), 1)
+	client.AzureBlobPost(context.Background(), f)
+	client.AzureBlobFlush()
+	r = requests()
+	require.Len(t, r, 2)
+	require.NotEqual(t, r[0].path, r[1].path)
+}
+
+func TestAzureBlobBatchSize(t *testing.T) {
+	ts, requests := newAzuriteStub(t)
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Azure.Blob.Container = "falco"
+	config.Azure.Blob.AccountName = "devstoreaccount1"
+	config.Azure.Blob.SASToken = "?sv=2020-04-08&sig=c2lnbmF0dXJl"
+	config.Azure.Blob.Endpoint = ts.URL + "/devstoreaccount1"
+	config.Azure.Blob.KeyFormat = "{{ .Batch }}.ndjson"
+	config.Azure.Blob.BatchSize = 1
+	client := newTestAzureBlobClient(t, config)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	client.AzureBlobPost(context.Background(), f)
+	r := requests()
+	require.Len(t, r, 1)
+	require.Contains(t, r[0].query, "sig=c2lnbmF0dXJl")
+	require.Empty(t, r[0].header.Get("Authorization"))
+}
+
+func TestAzureBlobAppendMode(t *testing.T) {
+	ts, requests := newAzuriteStub(t)
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Azure.Blob.Container = "falco"
+	config.Azure.Blob.ConnectionString = "AccountName=devstoreaccount1;AccountKey=" + azuriteKey
+	config.Azure.Blob.Endpoint = ts.URL + "/devstoreaccount1"
+	config.Azure.Blob.KeyFormat = `events-{{ .Time.Format "2006-01-02" }}.ndjson`
+	config.Azure.Blob.AppendMode = true
+	client := newTestAzureBlobClient(t, config)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	client.AzureBlobPost(context.Background(), f)
+	client.AzureBlobFlush()
+	client.AzureBlobPost(context.Background(), f)
+	client.AzureBlobFlush()
+
+	r := requests()
+	require.Len(t, r, 3)
+	for _, i := range r {
+		require.Equal(t, "/devstoreaccount1/falco/events-2001-01-01.ndjson", i.path)
+	}
+	require.Equal(t, "AppendBlob", r[0].header.Get("x-ms-blob-type"))
+	require.Equal(t, "*", r[0].header.Get("If-None-Match"))
+	require.Empty(t, r[0].body)
+	for _, i := range r[1:] {
+		require.Equal(t, "comp=appendblock", i.query)
+		require.Equal(t, 1, strings.Count(i.body, "\n"))
+	}
+}
+
+func TestAzureBlobCompactFields(t *testing.T) {
+	ts, requests := newAzuriteStub(t)
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Azure.Blob.Container = "falco"
+	config.Azure.Blob.ConnectionString = "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;AccountKey=" + azuriteKey + ";BlobEndpoint=" + ts.URL + "/devstoreaccount1;"
+	config.Azure.Blob.KeyFormat = "events.ndjson"
+	config.Azure.Blob.CompactFields = true
+	client := newTestAzureBlobClient(t, config)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	for _, i := range []int{1, 2} {
+		f.OutputFields = map[string]interface{}{"container.image.digest": "sha256:abcd", "proc.tty": i}
+		client.AzureBlobPost(context.Background(), f)
+	}
+	client.AzureBlobFlush()
+
+	r := requests()
+	require.Len(t, r, 1)
+	lines := strings.Split(strings.TrimSuffix(r[0].body, "\n"), "\n")
+	require.Len(t, lines, 3)
+	require.JSONEq(t, `{"shared_output_fields":{"container.image.digest":"sha256:abcd"}}`, lines[0])
+	for i, j := range lines[1:] {
+		var e types.FalcoPayload
+		require.Nil(t, json.Unmarshal([]byte(j), &e))
+		require.Equal(t, "Test rule", e.Rule)
+		require.Equal(t, map[string]interface{}{"proc.tty": float64(i + 1)}, e.OutputFields)
+	}
+}
+
+func TestNewAzureBlobClientCredentials(t *testing.T) {
+	config := &types.Configuration{}
+	config.Azure.Blob.Container = "falco"
+	config.Azure.Blob.AccountName = "account"
+	_, err := NewAzureBlobClient(config, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Equal(t, ErrClientCreation, err)
+
+	config.Azure.Blob.ConnectionString = "AccountName=account;AccountKey=" + azuriteKey + ";EndpointSuffix=core.chinacloudapi.cn"
+	client, err := NewAzureBlobClient(config, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	require.Equal(t, "https://account.blob.core.chinacloudapi.cn/falco", client.azureBlob.endpoint.String())
+}
@@ -0,0 +1,181 @@
+package outputs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// batchItem is an event buffered by a batcher until its batch is sent
+type batchItem struct {
+	falcopayload types.FalcoPayload
+	value        interface{} // what the output sends for the event
+	size         int         // bytes of the item in a batch
+	attempts     int         // sends of the item which failed with a transient error
+	result       chan error
+}
+
+// done sets the result of the send of the item, nil if it succeeded
+func (i *batchItem) done(err error) {
+	i.result <- err
+}
+
+// batcher buffers the events of an output, they're sent by batches of maxItems items and at most maxSize bytes
+// (0 means no maximum). The batches are sent one at a time by the goroutine of the batcher, once a batch is full,
+// every interval and when it's flushed, while draining the items are sent as soon as they're buffered.
+// send sets the result of each item of a batch and returns the ones to send again, they're sent first after the
+// backoff of their attempts.
+type batcher struct {
+	maxItems int
+	maxSize  int
+	send     func([]*batchItem) []*batchItem
+	backoff  func(attempt int) time.Duration
+
+	mu       sync.Mutex
+	items    []*batchItem
+	size     int
+	draining bool
+	full     chan struct{}
+	flushes  chan chan struct{}
+}
+
+// newBatcher returns a batcher sending its batches with send, every interval if it's not 0
+func newBatcher(maxItems, maxSize int, interval time.Duration, send func([]*batchItem) []*batchItem, backoff func(int) time.Duration) *batcher {
+	b := &batcher{
+		maxItems: maxItems,
+		maxSize:  maxSize,
+		send:     send,
+		backoff:  backoff,
+		full:     make(chan struct{}, 1),
+		flushes:  make(chan chan struct{}),
+	}
+	go b.run(interval)
+	return b
+}
+
+func (b *batcher) run(interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		tick = time.NewTicker(interval).C
+	}
+	for {
+		select {
+		case <-b.full:
+			b.flush(false)
+		case <-tick:
+			b.flush(true)
+		case done := <-b.flushes:
+			b.flush(true)
+			close(done)
+		}
+	}
+}
+
+// add buffers an event, the result of its send is received once its batch has been sent
+func (b *batcher) add(falcopayload types.FalcoPayload, value interface{}, size int) <-chan error {
+	i := &batchItem{falcopayload: falcopayload, value: value, size: size, result: make(chan error, 1)}
+	b.mu.Lock()
+	b.items = append(b.items, i)
+	b.size += size
+	full := b.draining || len(b.items) >= b.maxItems || (b.maxSize > 0 && b.size >= b.maxSize)
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+	return i.result
+}
+
+// Flush sends all the events buffered and returns once they're sent
+func (b *batcher) Flush() {
+	done := make(chan struct{})
+	b.flushes <- done
+	<-done
+}
+
+// drain makes the batcher send the events as soon as they're buffered, for the events being sent at shutdown
+func (b *batcher) drain() {
+	b.mu.Lock()
+	b.draining = true
+	b.mu.Unlock()
+	select {
+	case b.full <- struct{}{}:
+	default:
+	}
+}
+
+// flush sends the events buffered by batches, the last batch is only sent if it's full, if all is true or while
+// draining
+func (b *batcher) flush(all bool) {
+	for {
+		b.mu.Lock()
+		n, size := 0, 0
+		for n < len(b.items) && n < b.maxItems && (b.maxSize == 0 || n == 0 || size+b.items[n].size <= b.maxSize) {
+			size += b.items[n].size
+			n++
+		}
+		full := n == b.maxItems || n < len(b.items) || (b.maxSize > 0 && size >= b.maxSize)
+		if n == 0 || (!full && !all && !b.draining) {
+			b.mu.Unlock()
+			return
+		}
+		items := make([]*batchItem, n)
+		copy(items, b.items)
+		b.items = b.items[n:]
+		b.size -= size
+		b.mu.Unlock()
+
+		retry := b.send(items)
+		if len(retry) == 0 {
+			continue
+		}
+		attempts := 0
+		b.mu.Lock()
+		for _, i := range retry {
+			b.size += i.size
+			if i.attempts > attempts {
+				attempts = i.attempts
+			}
+		}
+		b.items = append(retry, b.items...)
+		b.mu.Unlock()
+		time.Sleep(b.backoff(attempts - 1))
+	}
+}
+
+// resize sets the maximum number of items of the next batches, from 1 to max, and returns it
+func (b *batcher) resize(maxItems, max int) int {
+	if maxItems < 1 {
+		maxItems = 1
+	}
+	if maxItems > max {
+		maxItems = max
+	}
+	b.mu.Lock()
+	b.maxItems = maxItems
+	b.mu.Unlock()
+	return maxItems
+}
+
+// batchSize returns the maximum number of items of the next batches
+func (b *batcher) batchSize() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.maxItems
+}
+
+// waitBatch returns the result of the send of an event by a batcher, or ErrDeadlineExceeded if ctx is done before, the
+// event is then still sent with its batch
+func waitBatch(ctx context.Context, result <-chan error) error {
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ErrDeadlineExceeded
+	}
+}
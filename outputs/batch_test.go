@@ -0,0 +1,74 @@
+package outputs
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestBatcher(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]string
+	release := make(chan struct{})
+	var backoffs []int
+	send := func(items []*batchItem) []*batchItem {
+		<-release
+		var batch []string
+		for _, i := range items {
+			batch = append(batch, i.falcopayload.Rule)
+		}
+		mu.Lock()
+		batches = append(batches, batch)
+		mu.Unlock()
+		var retry []*batchItem
+		for _, i := range items {
+			switch {
+			case i.falcopayload.Rule == "throttled" && i.attempts == 0:
+				i.attempts++
+				retry = append(retry, i)
+			case i.falcopayload.Rule == "invalid":
+				i.done(errors.New("invalid"))
+			default:
+				i.done(nil)
+			}
+		}
+		return retry
+	}
+	b := newBatcher(2, 10, 0, send, func(attempt int) time.Duration {
+		backoffs = append(backoffs, attempt)
+		return 0
+	})
+
+	// the full batch is sent by the goroutine of the batcher, the events are buffered without waiting for it
+	var results []<-chan error
+	for _, i := range []string{"a", "throttled", "invalid"} {
+		results = append(results, b.add(types.FalcoPayload{Rule: i}, nil, 1))
+	}
+	close(release)
+	require.Nil(t, <-results[0])
+	// the item to retry is sent first after its backoff, the last batch is sent once flushed
+	b.Flush()
+	require.Nil(t, <-results[1])
+	require.EqualError(t, <-results[2], "invalid")
+	require.Equal(t, [][]string{{"a", "throttled"}, {"throttled", "invalid"}}, batches)
+	require.Equal(t, []int{0}, backoffs)
+
+	// the batches stay under the maximum size
+	batches = nil
+	for _, i := range []string{"a", "b"} {
+		results = append(results, b.add(types.FalcoPayload{Rule: i}, nil, 6))
+	}
+	b.Flush()
+	require.Equal(t, [][]string{{"a"}, {"b"}}, batches)
+
+	// while draining, the events are sent without waiting for the batch to be full
+	batches = nil
+	b.drain()
+	require.Nil(t, <-b.add(types.FalcoPayload{Rule: "a"}, nil, 1))
+	require.Equal(t, [][]string{{"a"}}, batches)
+}
@@ -0,0 +1,186 @@
+package outputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types/ref"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// CELExpressions filters the events, remaps their priority and computes fields with CEL expressions, compiled once.
+// The expressions are evaluated with the variables rule, priority (lowercase), fields (the output fields), source and tags.
+type CELExpressions struct {
+	filter     cel.Program
+	priority   cel.Program
+	fields     map[string]cel.Program
+	fieldNames []string // sorted
+}
+
+// NewCELExpressions compiles the CEL expressions configured, the filter must return a bool and the priority a string
+func NewCELExpressions(config *types.Configuration) (*CELExpressions, error) {
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewVar("rule", decls.String),
+		decls.NewVar("priority", decls.String),
+		decls.NewVar("fields", decls.NewMapType(decls.String, decls.Dyn)),
+		decls.NewVar("source", decls.String),
+		decls.NewVar("tags", decls.NewListType(decls.String)),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	e := &CELExpressions{fields: make(map[string]cel.Program, len(config.CEL.Fields))}
+	if config.CEL.Filter != "" {
+		if e.filter, err = compileCEL(env, "filter", config.CEL.Filter, decls.Bool); err != nil {
+			return nil, err
+		}
+	}
+	if config.CEL.Priority != "" {
+		if e.priority, err = compileCEL(env, "priority", config.CEL.Priority, decls.String); err != nil {
+			return nil, err
+		}
+	}
+	for i, j := range config.CEL.Fields {
+		if e.fields[i], err = compileCEL(env, "field '"+i+"'", j, nil); err != nil {
+			return nil, err
+		}
+		e.fieldNames = append(e.fieldNames, i)
+	}
+	sort.Strings(e.fieldNames)
+	return e, nil
+}
+
+// compileCEL compiles an expression, its type must be resultType (or dyn) if it's not nil
+func compileCEL(env *cel.Env, name, expression string, resultType *exprpb.Type) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("Bad CEL expression of the %v : %v", name, issues.Err())
+	}
+	if resultType != nil && !proto.Equal(ast.ResultType(), resultType) && !proto.Equal(ast.ResultType(), decls.Dyn) {
+		return nil, fmt.Errorf("Bad CEL expression of the %v : must return a %v", name, strings.ToLower(resultType.GetPrimitive().String()))
+	}
+	return env.Program(ast)
+}
+
+// Apply computes the fields of the event, then remaps its priority and returns the result of the filter, the computed
+// fields and the priority are seen by the filter. An expression failing is logged and ignored, the event is kept.
+func (e *CELExpressions) Apply(falcopayload *types.FalcoPayload) bool {
+	vars := celVariables(*falcopayload)
+	for _, i := range e.fieldNames {
+		out, _, err := e.fields[i].Eval(vars)
+		if err != nil {
+			logEventError("CEL", *falcopayload, fmt.Errorf("field '%v' : %v", i, err))
+			continue
+		}
+		v, err := celNative(out)
+		if err != nil {
+			logEventError("CEL", *falcopayload, fmt.Errorf("field '%v' : %v", i, err))
+			continue
+		}
+		if falcopayload.OutputFields == nil {
+			falcopayload.OutputFields = make(map[string]interface{})
+		}
+		falcopayload.OutputFields[i] = v
+		vars["fields"].(map[string]interface{})[i] = v
+	}
+
+	if e.priority != nil {
+		out, _, err := e.priority.Eval(vars)
+		if err != nil {
+			logEventError("CEL", *falcopayload, fmt.Errorf("priority : %v", err))
+		} else if p, ok := out.Value().(string); !ok {
+			logEventError("CEL", *falcopayload, fmt.Errorf("priority : %v isn't a string", out.Value()))
+		} else if p != "" {
+			falcopayload.Priority = types.Priority(p)
+			vars["priority"] = strings.ToLower(falcopayload.Priority.String())
+		}
+	}
+
+	if e.filter == nil {
+		return true
+	}
+	out, _, err := e.filter.Eval(vars)
+	if err != nil {
+		logEventError("CEL", *falcopayload, fmt.Errorf("filter : %v", err))
+		return true
+	}
+	keep, ok := out.Value().(bool)
+	if !ok {
+		logEventError("CEL", *falcopayload, fmt.Errorf("filter : %v isn't a bool", out.Value()))
+		return true
+	}
+	return keep
+}
+
+// celVariables returns the variables of the expressions for an event, the numbers of the fields are converted to int or double
+func celVariables(falcopayload types.FalcoPayload) map[string]interface{} {
+	source := falcopayload.Source
+	if source == "" {
+		source = SyscallSource
+		for i := range falcopayload.OutputFields {
+			if strings.HasPrefix(i, "ka.") {
+				source = K8sAuditSource
+				break
+			}
+		}
+	}
+	tags := falcopayload.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return map[string]interface{}{
+		"rule":     falcopayload.Rule,
+		"priority": strings.ToLower(falcopayload.Priority.String()),
+		"fields":   celValue(falcopayload.OutputFields).(map[string]interface{}),
+		"source":   source,
+		"tags":     tags,
+	}
+}
+
+func celValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for i, j := range t {
+			m[i] = celValue(j)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, j := range t {
+			l[i] = celValue(j)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+// celNative converts the result of an expression to a value of the output fields
+func celNative(out ref.Val) (interface{}, error) {
+	switch v := out.Value().(type) {
+	case string, bool, int64, uint64, float64:
+		return v, nil
+	}
+	v, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, err
+	}
+	return v.(*structpb.Value).AsInterface(), nil
+}
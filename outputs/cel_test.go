@@ -0,0 +1,58 @@
+package outputs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestCELExpressions(t *testing.T) {
+	config := &types.Configuration{CEL: types.CELConfig{
+		Filter:   `!(fields["proc.name"] in ["healthcheck", "readiness"]) && priority != "debug"`,
+		Priority: `"team" in fields && fields.team == "security" ? "critical" : ""`,
+		Fields: map[string]string{
+			"team":       `fields["k8s.ns.name"].startsWith("sec-") ? "security" : "platform"`,
+			"proc.pid10": `fields["proc.pid"] * 10`,
+			"tagged":     `"container" in tags && source == "syscall"`,
+		},
+	}}
+	e, err := NewCELExpressions(config)
+	require.Nil(t, err)
+
+	f := types.FalcoPayload{
+		Rule:         "Terminal shell in container",
+		Priority:     types.Notice,
+		OutputFields: map[string]interface{}{"k8s.ns.name": "sec-tools", "proc.name": "bash", "proc.pid": json.Number("42")},
+		Tags:         []string{"container", "shell"},
+	}
+	require.True(t, e.Apply(&f))
+	require.Equal(t, "security", f.OutputFields["team"])
+	require.Equal(t, int64(420), f.OutputFields["proc.pid10"])
+	require.Equal(t, true, f.OutputFields["tagged"])
+	require.Equal(t, types.PriorityType(types.Critical), f.Priority)
+
+	f = types.FalcoPayload{
+		Rule:         "Terminal shell in container",
+		Priority:     types.Notice,
+		OutputFields: map[string]interface{}{"k8s.ns.name": "default", "proc.name": "healthcheck", "proc.pid": json.Number("7")},
+	}
+	require.False(t, e.Apply(&f))
+	require.Equal(t, "platform", f.OutputFields["team"])
+	require.Equal(t, false, f.OutputFields["tagged"])
+	require.Equal(t, types.PriorityType(types.Notice), f.Priority)
+}
+
+func TestNewCELExpressionsErrors(t *testing.T) {
+	for _, i := range []types.CELConfig{
+		{Filter: `rule ==`},
+		{Filter: `rule`},
+		{Priority: `priority == "debug"`},
+		{Fields: map[string]string{"x": `unknown.value`}},
+	} {
+		_, err := NewCELExpressions(&types.Configuration{CEL: i})
+		require.NotNil(t, err)
+	}
+}
@@ -0,0 +1,126 @@
+package outputs
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// States of a circuit breaker, the values of the falcosidekick_outputs_circuit_breaker gauge
+const (
+	CircuitClosed   int = 0 // the events are sent
+	CircuitOpen     int = 1 // the events aren't sent until the end of the cooldown
+	CircuitHalfOpen int = 2 // a single event is sent to probe the output
+)
+
+// ErrCircuitOpen is returned for the events not sent because the circuit breaker of the output is open
+var ErrCircuitOpen = errors.New("Circuit breaker open")
+
+// CircuitBreaker stops sending the events to an output after Threshold consecutive failures (connection errors, 5xx
+// and 429, the other 4xx aren't failures of the output), for the Cooldown. Then a single event is sent, the breaker
+// is closed if it succeeds and opened again otherwise.
+type CircuitBreaker struct {
+	output    string
+	threshold int
+	cooldown  time.Duration
+	promStats *types.PromStatistics
+	mu        sync.Mutex
+	state     int
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+// NewCircuitBreaker returns the circuit breaker of an output, nil if the threshold is 0
+func NewCircuitBreaker(output string, threshold int, cooldown time.Duration, promStats *types.PromStatistics) *CircuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	b := &CircuitBreaker{output: output, threshold: threshold, cooldown: cooldown, promStats: promStats}
+	b.setState(CircuitClosed)
+	return b
+}
+
+// allow returns ErrCircuitOpen if the event can't be sent, it's always nil for a nil CircuitBreaker
+func (b *CircuitBreaker) allow(now time.Time) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.setState(CircuitHalfOpen)
+		log.Printf("[INFO]  : %v - Circuit breaker half-open, probing the output\n", b.output)
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+	}
+	b.probing = b.state == CircuitHalfOpen
+	return nil
+}
+
+// record updates the breaker with the result of the sending of an event allowed, the events dropped by the rate
+// limiter aren't results
+func (b *CircuitBreaker) record(err error, now time.Time) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err == ErrRateLimited {
+		return
+	}
+	if !isOutputFailure(err) {
+		if b.state != CircuitClosed {
+			log.Printf("[INFO]  : %v - Circuit breaker closed\n", b.output)
+			b.setState(CircuitClosed)
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.failures >= b.threshold) {
+		log.Printf("[WARN]  : %v - Circuit breaker open for %v after %v consecutive failures\n", b.output, b.cooldown, b.failures)
+		b.setState(CircuitOpen)
+		b.openedAt = now
+	}
+}
+
+// State returns CircuitClosed, CircuitOpen or CircuitHalfOpen
+func (b *CircuitBreaker) State() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// setState sets the state and the gauge, b.mu must be held
+func (b *CircuitBreaker) setState(state int) {
+	b.state = state
+	if b.promStats != nil && b.promStats.CircuitBreakers != nil {
+		b.promStats.CircuitBreakers.With(map[string]string{"destination": strings.ToLower(b.output)}).Set(float64(state))
+	}
+}
+
+// isOutputFailure returns true if the error is a failure of the output, the requests refused for their content or
+// their credentials are answered by an output which works
+func isOutputFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, i := range []error{ErrHeaderMissing, ErrClientAuthenticationError, ErrForbidden, ErrNotFound, ErrUnprocessableEntityError} {
+		if errors.Is(err, i) {
+			return false
+		}
+	}
+	return true
+}
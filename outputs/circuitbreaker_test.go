@@ -0,0 +1,87 @@
+package outputs
+
+import (
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var requests int32
+	var status int32 = http.StatusServiceUnavailable
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer ts.Close()
+
+	promStats := newTestPromStats()
+	promStats.CircuitBreakers = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "falcosidekick_outputs_circuit_breaker"}, []string{"destination"})
+	gauge := promStats.CircuitBreakers.With(map[string]string{"destination": "webhook"})
+
+	nc, err := NewClient("Webhook", ts.URL, false, false, &types.Configuration{}, &types.Statistics{Webhook: new(expvar.Map)}, promStats, nil, nil)
+	require.Nil(t, err)
+	nc.CircuitBreaker = NewCircuitBreaker("Webhook", 5, 100*time.Millisecond, promStats)
+	require.Equal(t, float64(CircuitClosed), testutil.ToFloat64(gauge))
+
+	for i := 0; i < 5; i++ {
+		require.NotNil(t, nc.Post(""))
+	}
+	require.Equal(t, int32(5), atomic.LoadInt32(&requests))
+	require.Equal(t, CircuitOpen, nc.CircuitBreaker.State())
+	require.Equal(t, float64(CircuitOpen), testutil.ToFloat64(gauge))
+
+	// the sixth call doesn't send a request
+	require.Equal(t, ErrCircuitOpen, nc.Post(""))
+	require.Equal(t, int32(5), atomic.LoadInt32(&requests))
+
+	// after the cooldown, the probe fails and the breaker is open again
+	time.Sleep(100 * time.Millisecond)
+	require.NotNil(t, nc.Post(""))
+	require.Equal(t, int32(6), atomic.LoadInt32(&requests))
+	require.Equal(t, ErrCircuitOpen, nc.Post(""))
+
+	// the probe succeeds once the output is back
+	atomic.StoreInt32(&status, http.StatusOK)
+	time.Sleep(100 * time.Millisecond)
+	require.Nil(t, nc.Post(""))
+	require.Equal(t, CircuitClosed, nc.CircuitBreaker.State())
+	require.Equal(t, float64(CircuitClosed), testutil.ToFloat64(gauge))
+	require.Nil(t, nc.Post(""))
+	require.Equal(t, int32(8), atomic.LoadInt32(&requests))
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	b := NewCircuitBreaker("Webhook", 2, time.Minute, nil)
+	now := time.Now()
+
+	// the requests refused for their content don't count, the consecutive failures do
+	for _, i := range []error{errors.New("503 Service Unavailable"), ErrHeaderMissing, ErrTooManyRequest} {
+		require.Nil(t, b.allow(now))
+		b.record(i, now)
+	}
+	require.Equal(t, CircuitClosed, b.State())
+	require.Nil(t, b.allow(now))
+	b.record(ErrDeadlineExceeded, now)
+	require.Equal(t, CircuitOpen, b.State())
+
+	// a single event probes the output
+	now = now.Add(time.Minute)
+	require.Nil(t, b.allow(now))
+	require.Equal(t, CircuitHalfOpen, b.State())
+	require.Equal(t, ErrCircuitOpen, b.allow(now))
+	b.record(ErrForbidden, now)
+	require.Equal(t, CircuitClosed, b.State())
+
+	require.Nil(t, NewCircuitBreaker("Webhook", 0, time.Minute, nil))
+}
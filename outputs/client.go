@@ -2,17 +2,22 @@ package outputs
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
 
 	gcpfunctions "cloud.google.com/go/functions/apiv1"
 	"github.com/streadway/amqp"
@@ -23,8 +28,12 @@ import (
 	"github.com/DataDog/datadog-go/statsd"
 	"github.com/aws/aws-sdk-go/aws/session"
 	cloudevents "github.com/cloudevents/sdk-go/v2"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
+	nats "github.com/nats-io/nats.go"
 	"github.com/segmentio/kafka-go"
+	"golang.org/x/time/rate"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/falcosecurity/falcosidekick/types"
@@ -45,12 +54,43 @@ var ErrNotFound = errors.New("Resource not found")
 // ErrUnprocessableEntityError = 422
 var ErrUnprocessableEntityError = errors.New("Bad Request")
 
+// DefaultContentType is the Content-Type of the requests of the HTTP outputs
+const DefaultContentType string = "application/json; charset=utf-8"
+
 // ErrTooManyRequest = 429
 var ErrTooManyRequest = errors.New("Exceeding post rate limit")
 
 // ErrClientCreation is returned if client can't be created
 var ErrClientCreation = errors.New("Client creation Error")
 
+// ErrEventDropped is returned if an output drops an event instead of sending it
+var ErrEventDropped = errors.New("Event dropped")
+
+// ErrDeadlineExceeded is returned if the deadline of an event expires before it's sent, retries included
+var ErrDeadlineExceeded = errors.New("Deadline of the event exceeded")
+
+// Limits of the read of the body of an error response, a server can't make the request hang with it
+const (
+	ErrorBodyMaxSize     = 1024 // bytes
+	ErrorBodyReadTimeout = 2 * time.Second
+)
+
+// ResponseError is the error of a response refused by an output, with the beginning of the body explaining why
+// (ex: a mapping rejected by Elasticsearch), errors.Is matches it with Err
+type ResponseError struct {
+	Err  error // ErrHeaderMissing, ErrUnprocessableEntityError... or the status of the response
+	Body string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%v : %v", e.Err, e.Body)
+}
+
+// Unwrap returns Err
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
 // EnabledOutputs list all enabled outputs
 var EnabledOutputs []string
 
@@ -64,7 +104,40 @@ type Client struct {
 	OutputType              string
 	EndpointURL             *url.URL
 	MutualTLSEnabled        bool
+	MutualTLSCACert         string // file or PEM of the CA bundle of the output, instead of the one of MutualTLSFilesPath
+	MutualTLSClientCert     string // file or PEM of the client certificate of the output, with MutualTLSClientKey
+	MutualTLSClientKey      string
 	CheckCert               bool
+	ServerName              string
+	DisableSessionTickets   bool                   // if true, the TLS sessions aren't resumed, even with a TLSSessionCache
+	TLSSessionCache         tls.ClientSessionCache // if set, the TLS sessions are resumed across the requests
+	BearerToken             string
+	Authorization           string             // if set, value of the Authorization header, instead of the BearerToken
+	ProxyAuthorization      string             // if set, value of the Proxy-Authorization header of the CONNECT requests to an HTTP proxy, and of the requests in clear through it
+	AuthMethods             []AuthMethod       // if set, the requests are authenticated with one of them, the next one is tried on a 401 or a 403
+	TokenSource             *OAuth2TokenSource // if set, the requests are authenticated with its tokens, instead of the Authorization and the BearerToken
+	RetryAfterPolicy        string             // "" (disabled), queue or drop
+	SchemaVersion           string
+	SchemaVersionInPayload  bool
+	PriorityCase            string            // asis (default), lower or upper
+	KeysMapping             map[string]string // top-level key of the JSON payloads: new name, "" drops it
+	StatusCodePolicies      map[int]StatusCodePolicy
+	StatusCodeRetries       int
+	MaxRetryDuration        time.Duration    // 0 (disabled) or duration of the retries, instead of StatusCodeRetries, connection errors included
+	RetryMaxAttempts        int              // attempts of a request failing with a transient error, without policy for its status code
+	RetryInitialDelay       time.Duration    // backoff after the first attempt, doubled at each retry
+	RetryMaxDelay           time.Duration    // 0 (no maximum) or maximum backoff between the attempts
+	HedgeDelay              time.Duration    // 0 (disabled) or delay before a second request if the output hasn't responded
+	Timeout                 time.Duration    // 0 (disabled) or timeout of the requests, dial and TLS handshake included
+	Resolver                *ServiceResolver // if set, the host of EndpointURL is replaced by the instances of a Consul service
+	BackpressureDelay       time.Duration    // 0 (disabled) or pause of the output after a 429, doubled at each consecutive one
+	BackpressureMaxDelay    time.Duration    // 0 (no maximum) or maximum pause after consecutive 429s
+	RateLimiter             *rate.Limiter    // if set, the events over the MaxRate of the output are delayed or dropped, see RateLimitMode, only Slack, Teams, Discord, Opsgenie, Pagerduty and Webhook have a MaxRate
+	RateLimitMode           string           // block (default) or drop
+	DryRun                  bool             // if true, the requests are logged instead of being sent
+	Redaction               *Redaction       // if set, the secrets of the events are redacted before being sent
+	EnableCompression       bool             // if true, the bodies of at least CompressionThreshold bytes are gzipped
+	CompressionThreshold    int              // bytes
 	Config                  *types.Configuration
 	Stats                   *types.Statistics
 	PromStats               *types.PromStatistics
@@ -79,7 +152,28 @@ type Client struct {
 	CloudEventsClient cloudevents.Client
 	KubernetesClient  kubernetes.Interface
 	RabbitmqClient    *amqp.Channel
+	NatsConn          *nats.Conn
+	JetStreamContext  nats.JetStreamContext
+	MQTTClient        mqtt.Client
+	RedisClient       *redis.Client
 	WavefrontSender   *wavefront.Sender
+
+	pausedUntil       int64 // unix nano
+	backpressure      int64 // current pause after consecutive 429s, nano
+	azureBlob         *azureBlobWriter
+	awsSQS            *sqsSender
+	awsKinesis        *kinesisBuffer
+	lokiLabels        *lokiLabelValues
+	CircuitBreaker    *CircuitBreaker // nil disables it
+	slackThreads      *slackThreads
+	kafkaMessageKey   *template.Template
+	webSocket         *webSocketConn
+	fifo              *fifoWriter
+	syslog            *connWriter
+	gelf              *connWriter
+	elasticsearchBulk *batcher
+	datadogLogs       *batcher
+	authMethod        int32 // index of the current AuthMethods
 }
 
 // NewClient returns a new output.Client for accessing the different API.
@@ -98,112 +192,222 @@ func NewClient(outputType string, defaultEndpointURL string, mutualTLSEnabled bo
 		log.Printf("[ERROR] : %v - %v\n", outputType, err.Error())
 		return nil, ErrClientCreation
 	}
-	return &Client{OutputType: outputType, EndpointURL: endpointURL, MutualTLSEnabled: mutualTLSEnabled, Config: config, Stats: stats, PromStats: promStats, StatsdClient: statsdClient, DogstatsdClient: dogstatsdClient}, nil
+	return &Client{OutputType: outputType, EndpointURL: endpointURL, MutualTLSEnabled: mutualTLSEnabled, CheckCert: checkCert, DryRun: isDryRun(config, outputType), KeysMapping: keysMapping(config, outputType), Timeout: httpTimeout(config, outputType), RetryMaxAttempts: config.Retry.MaxAttempts, RetryInitialDelay: time.Duration(config.Retry.InitialDelay) * time.Millisecond, RetryMaxDelay: time.Duration(config.Retry.MaxDelay) * time.Millisecond, Config: config, Stats: stats, PromStats: promStats, StatsdClient: statsdClient, DogstatsdClient: dogstatsdClient}, nil
+}
+
+// httpTimeout returns the timeout of the requests of the output, the one of the output if it's set, otherwise the
+// default one
+func httpTimeout(config *types.Configuration, outputType string) time.Duration {
+	for i, j := range config.Timeout.Outputs {
+		if d, err := time.ParseDuration(j); err == nil && d > 0 && dispatchName(i) == dispatchName(outputType) {
+			return d
+		}
+	}
+	d, _ := time.ParseDuration(config.Timeout.Default)
+	return d
 }
 
 // Post sends event (payload) to Output.
 func (c *Client) Post(payload interface{}) error {
+	return c.post(context.Background(), payload, types.FalcoPayload{})
+}
+
+// post sends the payload built for an event to Output, until the deadline of ctx, unless the circuit breaker of
+// the output is open. The event is appended to the dead-letter file if it can't be sent.
+func (c *Client) post(ctx context.Context, payload interface{}, falcopayload types.FalcoPayload) error {
+	return c.postURL(ctx, c.EndpointURL.String(), payload, falcopayload)
+}
+
+// postURL is post to another endpoint of the output than its EndpointURL
+func (c *Client) postURL(ctx context.Context, endpointURL string, payload interface{}, falcopayload types.FalcoPayload) error {
+	err := c.CircuitBreaker.allow(time.Now())
+	if err == nil {
+		err = c.postEvent(ctx, endpointURL, payload, falcopayload)
+		c.CircuitBreaker.record(err, time.Now())
+	}
+	if err != nil && (falcopayload.Rule != "" || falcopayload.Output != "") {
+		c.deadLetter(falcopayload, err)
+	}
+	return err
+}
+
+func (c *Client) postEvent(ctx context.Context, endpointURL string, payload interface{}, falcopayload types.FalcoPayload) error {
 	// defer + recover to catch panic if output doesn't respond
 	defer func() {
 		if err := recover(); err != nil {
 		}
 	}()
 
+	if c.RetryAfterPolicy != "" || c.BackpressureDelay > 0 {
+		if err := c.waitPause(ctx); err != nil {
+			if ctx.Err() != nil {
+				return c.deadlineExceeded()
+			}
+			return err
+		}
+	}
+	if c.RateLimiter != nil {
+		if err := c.rateLimit(ctx); err != nil {
+			return err
+		}
+	}
+
 	body := new(bytes.Buffer)
-	switch payload.(type) {
+	switch p := payload.(type) {
 	case influxdbPayload:
 		fmt.Fprintf(body, "%v", payload)
+	case *elasticsearchBulkRequest:
+		body.Write(p.body)
+	case rawPayload:
+		body.Write(p)
+	case *slackRequest:
+		if err := json.NewEncoder(body).Encode(p.payload); err != nil {
+			log.Printf("[ERROR] : %v - %s", c.OutputType, err)
+		}
 	default:
-		if err := json.NewEncoder(body).Encode(payload); err != nil {
+		if err := json.NewEncoder(body).Encode(c.mapKeys(payload)); err != nil {
 			log.Printf("[ERROR] : %v - %s", c.OutputType, err)
 		}
 	}
 
-	if c.Config.Debug == true {
+	if c.config().Debug == true {
 		log.Printf("[DEBUG] : %v payload : %v\n", c.OutputType, body)
 	}
 
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-
-	if c.MutualTLSEnabled {
-		// Load client cert
-		cert, err := tls.LoadX509KeyPair(c.Config.MutualTLSFilesPath+MutualTLSClientCertFilename, c.Config.MutualTLSFilesPath+MutualTLSClientKeyFilename)
-		if err != nil {
-			log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
-		}
-
-		// Load CA cert
-		caCert, err := ioutil.ReadFile(c.Config.MutualTLSFilesPath + MutualTLSCacertFilename)
-		if err != nil {
-			log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
-		}
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		customTransport.TLSClientConfig = &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			RootCAs:      caCertPool,
-			MinVersion:   tls.VersionTLS12,
-		}
-	} else {
-		// With MutualTLS enabled, the check cert flag is ignored
-		if c.CheckCert == false {
-			// #nosec G402 This is only set as a result of explicit configuration
-			customTransport.TLSClientConfig = &tls.Config{
-				InsecureSkipVerify: true,
-			}
-		}
-	}
+	c.checkPayloadSize(body.Len(), falcopayload.Rule)
+	body, compressed := c.compress(body)
 
-	client := &http.Client{
-		Transport: customTransport,
+	client, err := c.httpClient()
+	if err != nil {
+		log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
+		return err
 	}
 
-	req, err := http.NewRequest("POST", c.EndpointURL.String(), body)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpointURL, body)
 	if err != nil {
 		log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
 	}
-	contentType := "application/json; charset=utf-8"
+	contentType := DefaultContentType
 	if c.OutputType == "Loki" || c.OutputType == Kubeless {
 		contentType = "application/json"
 	}
+	if _, ok := payload.(*elasticsearchBulkRequest); ok {
+		contentType = "application/x-ndjson"
+	}
 	req.Header.Add("Content-Type", contentType)
+	if compressed {
+		req.Header.Add("Content-Encoding", "gzip")
+	}
 
 	if c.OutputType == "Opsgenie" {
-		req.Header.Add("Authorization", "GenieKey "+c.Config.Opsgenie.APIKey)
+		req.Header.Add("Authorization", "GenieKey "+c.config().Opsgenie.APIKey)
+	}
+
+	if c.OutputType == "DatadogLogs" {
+		req.Header.Add("DD-API-KEY", c.config().DatadogLogs.APIKey)
 	}
 
 	if c.OutputType == Kubeless {
 		req.Header.Add("event-id", uuid.New().String())
 		req.Header.Add("event-type", "falco")
-		req.Header.Add("event-namespace", c.Config.Kubeless.Namespace)
+		req.Header.Add("event-namespace", c.config().Kubeless.Namespace)
 	}
 
-	if c.OutputType == "GCPCloudRun" && c.Config.GCP.CloudRun.JWT != "" {
-		req.Header.Add("Authorization", "Bearer "+c.Config.GCP.CloudRun.JWT)
+	if c.OutputType == "GCPCloudRun" && c.config().GCP.CloudRun.JWT != "" {
+		req.Header.Add("Authorization", "Bearer "+c.config().GCP.CloudRun.JWT)
+	}
+
+	// with a TokenSource, the token is set by each attempt
+	if authorization := c.authorization(); c.TokenSource == nil && authorization != "" {
+		req.Header.Add("Authorization", authorization)
+	} else if bearerToken := c.bearerToken(); c.TokenSource == nil && bearerToken != "" {
+		req.Header.Add("Authorization", "Bearer "+bearerToken)
+	}
+
+	if proxyAuthorization := c.proxyAuthorization(); proxyAuthorization != "" && sendsProxyAuthorization(client, req) {
+		req.Header.Add("Proxy-Authorization", proxyAuthorization)
 	}
 
 	req.Header.Add("User-Agent", "Falcosidekick")
 
-	if len(c.Config.Webhook.CustomHeaders) != 0 && c.OutputType == "Webhook" {
-		for i, j := range c.Config.Webhook.CustomHeaders {
-			req.Header.Add(i, j)
-		}
+	if c.SchemaVersion != "" {
+		req.Header.Add(SchemaVersionHeader, c.SchemaVersion)
 	}
 
-	resp, err := client.Do(req)
+	for i, j := range customHeaders(c.config(), c.OutputType) {
+		req.Header.Add(i, expandCustomHeader(j, falcopayload))
+	}
+
+	start := time.Now()
+	resp, err := c.sendWithToken(client, req)
+	for i := 0; ; i++ {
+		backoff, retry := c.retryBackoff(resp, err, i)
+		if !retry {
+			break
+		}
+		if c.MaxRetryDuration > 0 && time.Since(start)+backoff > c.MaxRetryDuration {
+			if ctx.Err() == nil {
+				c.finalFailure(time.Since(start))
+			}
+			break
+		}
+		reason := fmt.Sprintf("%v", err)
+		if err == nil {
+			resp.Body.Close()
+			reason = fmt.Sprintf("%v", resp.StatusCode)
+		}
+		log.Printf("[WARN]  : %v - Retry in %v (%v)\n", c.OutputType, backoff, reason)
+		c.countRetry()
+		if err = sleepContext(ctx, backoff); err != nil {
+			break
+		}
+		req.Body, _ = req.GetBody()
+		resp, err = c.sendWithToken(client, req)
+	}
+	if err != nil && ctx.Err() != nil {
+		return c.deadlineExceeded()
+	}
 	if err != nil {
 		log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
 		go c.CountMetric("outputs", 1, []string{"output:" + strings.ToLower(c.OutputType), "status:connectionrefused"})
 		return err
 	}
 	defer resp.Body.Close()
+	if c.BackpressureDelay > 0 && resp.StatusCode != http.StatusTooManyRequests {
+		atomic.StoreInt64(&c.backpressure, 0)
+	}
 
 	go c.CountMetric("outputs", 1, []string{"output:" + strings.ToLower(c.OutputType), "status:" + strings.ToLower(http.StatusText(resp.StatusCode))})
 
+	switch c.StatusCodePolicies[resp.StatusCode].Action {
+	case SuccessPolicy:
+		log.Printf("[INFO]  : %v - Post OK (%v)\n", c.OutputType, resp.StatusCode)
+		return nil
+	case FailPolicy, RetryPolicy:
+		err := c.responseError(resp, errors.New(resp.Status))
+		log.Printf("[ERROR] : %v - Unexpected Response  (%v)\n", c.OutputType, resp.StatusCode)
+		return err
+	}
+
 	switch resp.StatusCode {
 	case http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent: //200, 201, 202, 204
-		log.Printf("[INFO]  : %v - Post OK (%v)\n", c.OutputType, resp.StatusCode)
 		body, _ := ioutil.ReadAll(resp.Body)
+		if r, ok := payload.(*elasticsearchBulkRequest); ok {
+			r.response = body
+		}
+		if r, ok := payload.(*slackRequest); ok {
+			if err := r.setResponse(body); err != nil {
+				log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, err, resp.StatusCode)
+				return err
+			}
+		}
+		if c.OutputType == "Teams" {
+			if err := checkTeamsResponse(c.config().Teams.Mode, resp.StatusCode, body); err != nil {
+				log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, err, resp.StatusCode)
+				return err
+			}
+		}
+		log.Printf("[INFO]  : %v - Post OK (%v)\n", c.OutputType, resp.StatusCode)
 		if c.OutputType == Kubeless {
 			log.Printf("[INFO]  : Kubeless - Function Response : %v\n", string(body))
 		} else if c.OutputType == Openfaas {
@@ -212,25 +416,244 @@ func (c *Client) Post(payload interface{}) error {
 		}
 		return nil
 	case http.StatusBadRequest: //400
-		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, ErrHeaderMissing, resp.StatusCode)
-		return ErrHeaderMissing
+		err := c.responseError(resp, ErrHeaderMissing)
+		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, err, resp.StatusCode)
+		return err
 	case http.StatusUnauthorized: //401
-		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, ErrClientAuthenticationError, resp.StatusCode)
-		return ErrClientAuthenticationError
+		err := c.responseError(resp, ErrClientAuthenticationError)
+		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, err, resp.StatusCode)
+		return err
 	case http.StatusForbidden: //403
-		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, ErrForbidden, resp.StatusCode)
-		return ErrForbidden
+		err := c.responseError(resp, ErrForbidden)
+		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, err, resp.StatusCode)
+		return err
 	case http.StatusNotFound: //404
-		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, ErrNotFound, resp.StatusCode)
-		return ErrNotFound
+		err := c.responseError(resp, ErrNotFound)
+		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, err, resp.StatusCode)
+		return err
 	case http.StatusUnprocessableEntity: //422
-		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, ErrUnprocessableEntityError, resp.StatusCode)
-		return ErrUnprocessableEntityError
+		err := c.responseError(resp, ErrUnprocessableEntityError)
+		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, err, resp.StatusCode)
+		return err
 	case http.StatusTooManyRequests: //429
-		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, ErrTooManyRequest, resp.StatusCode)
-		return ErrTooManyRequest
+		err := c.responseError(resp, ErrTooManyRequest)
+		log.Printf("[ERROR] : %v - %v (%v)\n", c.OutputType, err, resp.StatusCode)
+		if c.RetryAfterPolicy != "" {
+			if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				c.pauseFor(d, "Retry-After")
+			}
+		}
+		if c.BackpressureDelay > 0 {
+			c.pauseFor(c.nextBackpressure(), "backpressure")
+		}
+		return err
 	default:
+		err := c.responseError(resp, errors.New(resp.Status))
 		log.Printf("[ERROR] : %v - Unexpected Response  (%v)\n", c.OutputType, resp.StatusCode)
-		return errors.New(resp.Status)
+		return err
+	}
+}
+
+// responseError returns err with the first ErrorBodyMaxSize bytes of the body of the response, read within
+// ErrorBodyReadTimeout, or err if the body is empty or can't be read
+func (c *Client) responseError(resp *http.Response, err error) error {
+	read := make(chan []byte, 1)
+	go func() {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, ErrorBodyMaxSize))
+		read <- b
+	}()
+	timer := time.NewTimer(ErrorBodyReadTimeout)
+	defer timer.Stop()
+	var body []byte
+	select {
+	case body = <-read:
+	case <-timer.C:
+		// unblocks the read
+		resp.Body.Close()
+		return err
+	}
+	if c.config() != nil && c.config().Debug {
+		log.Printf("[DEBUG] : %v response : %s\n", c.OutputType, body)
+	}
+	b := strings.Join(strings.Fields(strings.ToValidUTF8(string(body), "")), " ")
+	if b == "" {
+		return err
+	}
+	return &ResponseError{Err: err, Body: b}
+}
+
+// config returns the configuration of the client, with the contents of the secret files of the last reload
+func (c *Client) config() *types.Configuration {
+	if secretFiles != nil && c.Config == secretFiles.config {
+		return secretFiles.Config()
+	}
+	return c.Config
+}
+
+// bearerToken returns the BearerToken, or the token of the output read from the configuration reloaded
+func (c *Client) bearerToken() string {
+	if c.BearerToken != "" {
+		return c.BearerToken
+	}
+	if c.OutputType == "Slack" {
+		return c.config().Slack.Token
+	}
+	return ""
+}
+
+// authorization returns the Authorization, or the value of the header of the output read from the configuration
+// reloaded
+func (c *Client) authorization() string {
+	if c.Authorization != "" {
+		return c.Authorization
+	}
+	switch c.OutputType {
+	case "Webhook":
+		return c.config().Webhook.Authorization
+	case "Influxdb":
+		if c.config().Influxdb.Token != "" {
+			return "Token " + c.config().Influxdb.Token
+		}
+	}
+	return ""
+}
+
+// proxyAuthorization returns the ProxyAuthorization, or the value of the header of the output read from the
+// configuration reloaded
+func (c *Client) proxyAuthorization() string {
+	if c.ProxyAuthorization != "" {
+		return c.ProxyAuthorization
+	}
+	if c.OutputType == "Webhook" {
+		return c.config().Webhook.ProxyAuthorization
+	}
+	return ""
+}
+
+// httpClient returns the client of the requests to the output, with its TLS and proxy configuration
+func (c *Client) httpClient() (*http.Client, error) {
+	customTransport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if c.MutualTLSEnabled || c.MutualTLSCACert != "" || c.MutualTLSClientCert != "" {
+		tlsConfig, err := c.mutualTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		customTransport.TLSClientConfig = tlsConfig
+	} else if c.CheckCert == false {
+		// #nosec G402 This is only set as a result of explicit configuration
+		customTransport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	}
+
+	if c.ServerName != "" {
+		if customTransport.TLSClientConfig == nil {
+			customTransport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		customTransport.TLSClientConfig.ServerName = c.ServerName
+	}
+
+	if c.DisableSessionTickets || c.TLSSessionCache != nil {
+		if customTransport.TLSClientConfig == nil {
+			customTransport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		customTransport.TLSClientConfig.SessionTicketsDisabled = c.DisableSessionTickets
+		customTransport.TLSClientConfig.ClientSessionCache = c.TLSSessionCache
+	}
+
+	proxyAuthorization := c.proxyAuthorization()
+	if proxyAuthorization != "" {
+		customTransport.ProxyConnectHeader = http.Header{"Proxy-Authorization": {proxyAuthorization}}
+	}
+
+	if c.Timeout > 0 {
+		dialer := &net.Dialer{Timeout: c.Timeout, KeepAlive: 30 * time.Second}
+		customTransport.DialContext = dialer.DialContext
+		if customTransport.TLSHandshakeTimeout > c.Timeout {
+			customTransport.TLSHandshakeTimeout = c.Timeout
+		}
+	}
+
+	client := &http.Client{
+		Transport: customTransport,
+		Timeout:   c.Timeout,
+	}
+	if proxyAuthorization != "" {
+		client.CheckRedirect = c.checkProxyRedirect(client, proxyAuthorization)
+	}
+	return client, nil
+}
+
+// checkProxyRedirect sets the Proxy-Authorization header on the redirects only if they go through an HTTP proxy, like
+// the first request, the client copies it otherwise even to another host
+func (c *Client) checkProxyRedirect(client *http.Client, proxyAuthorization string) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		req.Header.Del("Proxy-Authorization")
+		if sendsProxyAuthorization(client, req) {
+			req.Header.Set("Proxy-Authorization", proxyAuthorization)
+		}
+		return nil
+	}
+}
+
+// sendsProxyAuthorization returns true if the request is sent in clear to an HTTP proxy, which reads its headers, the
+// Proxy-Authorization header is otherwise only sent in the CONNECT requests to the proxy, never to the endpoint
+func sendsProxyAuthorization(client *http.Client, req *http.Request) bool {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok || transport.Proxy == nil || req.URL.Scheme != "http" {
+		return false
+	}
+	proxy, err := transport.Proxy(req)
+	return err == nil && proxy != nil && proxy.Scheme == "http"
+}
+
+// deadlineExceeded counts an event abandoned because its deadline expired
+func (c *Client) deadlineExceeded() error {
+	log.Printf("[ERROR] : %v - %v, event abandoned\n", c.OutputType, ErrDeadlineExceeded)
+	go c.CountMetric(Outputs, 1, []string{"output:" + strings.ToLower(c.OutputType), "status:" + Timeout})
+	if c.PromStats != nil && c.PromStats.Outputs != nil {
+		c.PromStats.Outputs.With(map[string]string{"destination": strings.ToLower(c.OutputType), "status": Timeout}).Inc()
+	}
+	return ErrDeadlineExceeded
+}
+
+// sleepContext waits for d, it returns the error of the context if it's done before
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkPayloadSize observes the size of a payload and warns if it's above the threshold, to find the rules with huge events
+func (c *Client) checkPayloadSize(size int, rule string) {
+	if c.PromStats != nil && c.PromStats.PayloadSize != nil {
+		c.PromStats.PayloadSize.With(map[string]string{"destination": strings.ToLower(c.OutputType)}).Observe(float64(size))
+	}
+	if t := c.config().PayloadSize.Threshold; t > 0 && size > t {
+		log.Printf("[WARN]  : %v - Payload of %v bytes is above the threshold of %v bytes (rule: %v)\n", c.OutputType, size, t, rule)
+	}
+}
+
+// timeRequest sends the request and records its duration by class of status code, in the
+// falcosidekick_output_request_duration_seconds histogram
+func (c *Client) timeRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.do(client, req)
+	if c.PromStats != nil && c.PromStats.RequestDuration != nil {
+		class := "error"
+		if err == nil {
+			class = fmt.Sprintf("%vxx", resp.StatusCode/100)
+		}
+		c.PromStats.RequestDuration.With(map[string]string{"destination": strings.ToLower(c.OutputType), "status_class": class}).Observe(time.Since(start).Seconds())
 	}
+	return resp, err
 }
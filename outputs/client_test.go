@@ -2,13 +2,17 @@ package outputs
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"net"
@@ -16,9 +20,15 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 
 	"github.com/falcosecurity/falcosidekick/types"
@@ -33,7 +43,7 @@ func TestNewClient(t *testing.T) {
 	stats := &types.Statistics{}
 	promStats := &types.PromStatistics{}
 
-	testClientOutput := Client{OutputType: "test", EndpointURL: u, MutualTLSEnabled: false, Config: config, Stats: stats, PromStats: promStats}
+	testClientOutput := Client{OutputType: "test", EndpointURL: u, MutualTLSEnabled: false, CheckCert: true, Config: config, Stats: stats, PromStats: promStats}
 	_, err := NewClient("test", "localhost/%*$¨^!/:;", false, true, config, stats, promStats, nil, nil)
 	require.NotNil(t, err)
 
@@ -85,6 +95,50 @@ func TestPost(t *testing.T) {
 	}
 }
 
+func TestPostErrorBody(t *testing.T) {
+	stalled := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/422":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [output_fields.proc.pid] of type [long]"}}` + "\n"))
+		case "/500":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(strings.Repeat("a", 2*ErrorBodyMaxSize)))
+		case "/stalled":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("partial"))
+			w.(http.Flusher).Flush()
+			<-stalled
+		}
+	}))
+	defer ts.Close()
+	defer close(stalled)
+
+	nc, err := NewClient("Webhook", ts.URL+"/422", false, false, &types.Configuration{}, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	err = nc.Post("")
+	require.True(t, errors.Is(err, ErrUnprocessableEntityError))
+	require.Contains(t, err.Error(), "mapper_parsing_exception")
+	require.Equal(t, `Bad Request : {"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [output_fields.proc.pid] of type [long]"}}`, err.Error())
+	// a rejected event isn't a failure of the output
+	require.False(t, isOutputFailure(err))
+
+	// the body is truncated
+	nc, err = NewClient("Webhook", ts.URL+"/500", false, false, &types.Configuration{}, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	err = nc.Post("")
+	require.Equal(t, "500 Internal Server Error : "+strings.Repeat("a", ErrorBodyMaxSize), err.Error())
+
+	// the body isn't awaited beyond the timeout
+	nc, err = NewClient("Webhook", ts.URL+"/stalled", false, false, &types.Configuration{}, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	start := time.Now()
+	require.Equal(t, ErrHeaderMissing, nc.Post(""))
+	require.WithinDuration(t, start.Add(ErrorBodyReadTimeout), time.Now(), time.Second)
+}
+
 func TestMutualTlsPost(t *testing.T) {
 	config := &types.Configuration{}
 	config.MutualTLSFilesPath = "/tmp/falcosidekicktests"
@@ -124,6 +178,125 @@ func TestMutualTlsPost(t *testing.T) {
 
 }
 
+func TestCheckCertPost(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// the self-signed certificate of the server is rejected unless CheckCert is false
+	nc, err := NewClient("Webhook", server.URL, false, true, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+	require.True(t, nc.CheckCert)
+	require.NotNil(t, nc.Post(""))
+
+	nc, err = NewClient("Webhook", server.URL, false, false, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, nc.Post(""))
+}
+
+func TestMutualTLSPerOutputPost(t *testing.T) {
+	dir := t.TempDir()
+	var servers []*httptest.Server
+	for _, i := range []string{"a", "b"} {
+		config := &types.Configuration{MutualTLSFilesPath: filepath.Join(dir, i)}
+		serverTLSConf, err := certsetup(config)
+		require.Nil(t, err)
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		server.TLS = serverTLSConf
+		server.StartTLS()
+		defer server.Close()
+		servers = append(servers, server)
+	}
+
+	// the global files are the ones of the server a, the client of the server b has its own
+	config := &types.Configuration{MutualTLSFilesPath: filepath.Join(dir, "a")}
+	a, err := NewClient("Elasticsearch", servers[0].URL, true, true, config, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+	b, err := NewClient("Webhook", servers[1].URL, true, true, config, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+	b.MutualTLSCACert = filepath.Join(dir, "b", MutualTLSCacertFilename)
+	b.MutualTLSClientCert = filepath.Join(dir, "b", MutualTLSClientCertFilename)
+	b.MutualTLSClientKey = filepath.Join(dir, "b", MutualTLSClientKeyFilename)
+	require.Nil(t, a.Post(""))
+	require.Nil(t, b.Post(""))
+
+	// each client only trusts the CA of its server
+	a.EndpointURL, b.EndpointURL = b.EndpointURL, a.EndpointURL
+	require.NotNil(t, a.Post(""))
+	require.NotNil(t, b.Post(""))
+
+	// the PEM can be inline, without mutual TLS the CA bundle verifies the server
+	ca, err := ioutil.ReadFile(filepath.Join(dir, "b", MutualTLSCacertFilename))
+	require.Nil(t, err)
+	cert, err := ioutil.ReadFile(filepath.Join(dir, "b", MutualTLSClientCertFilename))
+	require.Nil(t, err)
+	key, err := ioutil.ReadFile(filepath.Join(dir, "b", MutualTLSClientKeyFilename))
+	require.Nil(t, err)
+	c, err := NewClient("Webhook", servers[1].URL, false, true, config, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+	c.MutualTLSCACert, c.MutualTLSClientCert, c.MutualTLSClientKey = string(ca), string(cert), string(key)
+	require.Nil(t, c.Post(""))
+	tlsConfig, err := c.mutualTLSConfig()
+	require.Nil(t, err)
+	require.False(t, tlsConfig.InsecureSkipVerify)
+}
+
+func TestServerNamePost(t *testing.T) {
+	var serverName string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+			return nil, nil
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	nc, err := NewClient("", server.URL, false, false, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+	nc.ServerName = "falco.example.com"
+
+	require.Nil(t, nc.Post(""))
+	require.Equal(t, "falco.example.com", serverName)
+}
+
+func TestTLSSessionResumptionPost(t *testing.T) {
+	var resumed []bool
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resumed = append(resumed, r.TLS.DidResume)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	nc, err := NewClient("", server.URL, false, false, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+
+	// by default, the sessions aren't resumed across the requests
+	require.Nil(t, nc.Post(""))
+	require.Nil(t, nc.Post(""))
+	require.Equal(t, []bool{false, false}, resumed)
+
+	resumed = nil
+	nc.TLSSessionCache = tls.NewLRUClientSessionCache(8)
+	require.Nil(t, nc.Post(""))
+	require.Nil(t, nc.Post(""))
+	require.Equal(t, []bool{false, true}, resumed)
+
+	resumed = nil
+	nc.TLSSessionCache = tls.NewLRUClientSessionCache(8)
+	nc.DisableSessionTickets = true
+	require.Nil(t, nc.Post(""))
+	require.Nil(t, nc.Post(""))
+	require.Equal(t, []bool{false, false}, resumed)
+}
+
 func certsetup(config *types.Configuration) (serverTLSConf *tls.Config, err error) {
 	err = os.Mkdir(config.MutualTLSFilesPath, 0755)
 	if err != nil {
@@ -284,3 +457,450 @@ func certsetup(config *types.Configuration) (serverTLSConf *tls.Config, err erro
 	}
 	return serverTLSConf, nil
 }
+
+func TestPostRetryAfterPause(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	for _, policy := range []string{Drop, Queue} {
+		atomic.StoreInt32(&calls, 0)
+		nc, err := NewClient("Slack", ts.URL, false, false, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+		require.Nil(t, err)
+		nc.RetryAfterPolicy = policy
+
+		require.Equal(t, ErrTooManyRequest, nc.Post(""))
+
+		start := time.Now()
+		err = nc.Post("")
+		if policy == Drop {
+			require.Equal(t, ErrOutputPaused, err)
+			require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+			continue
+		}
+		require.Nil(t, err)
+		require.True(t, time.Since(start) > 900*time.Millisecond)
+		require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	}
+}
+
+func TestPostBackpressure(t *testing.T) {
+	var status int32 = http.StatusTooManyRequests
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer ts.Close()
+
+	nc, err := NewClient("Elasticsearch", ts.URL, false, false, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+	nc.BackpressureDelay = 20 * time.Millisecond
+	nc.BackpressureMaxDelay = 80 * time.Millisecond
+
+	// each 429 doubles the pause of the output, which the next request waits for
+	for _, i := range []time.Duration{20, 40, 80, 80} {
+		start := time.Now()
+		require.Equal(t, ErrTooManyRequest, nc.Post(""))
+		require.Equal(t, i*time.Millisecond, time.Duration(atomic.LoadInt64(&nc.backpressure)))
+		if i != 20 {
+			require.True(t, time.Since(start) >= i/2*time.Millisecond)
+		}
+	}
+
+	// the pause is reset once the output accepts the requests again
+	atomic.StoreInt32(&status, http.StatusOK)
+	require.Nil(t, nc.Post(""))
+	require.Equal(t, int64(0), atomic.LoadInt64(&nc.backpressure))
+}
+
+func TestPostRequestDuration(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	promStats := &types.PromStatistics{
+		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "falcosidekick_output_request_duration_seconds", Buckets: prometheus.DefBuckets}, []string{"destination", "status_class"}),
+	}
+	registry := prometheus.NewRegistry()
+	require.Nil(t, registry.Register(promStats.RequestDuration))
+
+	nc, err := NewClient("Webhook", ts.URL, false, false, &types.Configuration{}, &types.Statistics{}, promStats, nil, nil)
+	require.Nil(t, err)
+	require.Equal(t, ErrNotFound, nc.Post(""))
+
+	families, err := registry.Gather()
+	require.Nil(t, err)
+	require.Len(t, families, 1)
+	require.Len(t, families[0].GetMetric(), 1)
+	m := families[0].GetMetric()[0]
+	labels := map[string]string{}
+	for _, i := range m.GetLabel() {
+		labels[i.GetName()] = i.GetValue()
+	}
+	require.Equal(t, map[string]string{"destination": "webhook", "status_class": "4xx"}, labels)
+
+	// the observation is in the first bucket above 300ms, 0.5s
+	require.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+	for _, i := range m.GetHistogram().GetBucket() {
+		if i.GetUpperBound() < 0.5 {
+			require.Equal(t, uint64(0), i.GetCumulativeCount(), i.GetUpperBound())
+		} else {
+			require.Equal(t, uint64(1), i.GetCumulativeCount(), i.GetUpperBound())
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	d, ok := parseRetryAfter("60", now)
+	require.True(t, ok)
+	require.Equal(t, 60*time.Second, d)
+
+	d, ok = parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now)
+	require.True(t, ok)
+	require.Equal(t, 30*time.Second, d)
+
+	_, ok = parseRetryAfter("soon", now)
+	require.False(t, ok)
+}
+
+func TestPostStatusCodePolicies(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	nc, err := NewClient("Webhook", ts.URL, false, false, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+
+	// 409 isn't retried by default
+	require.NotNil(t, nc.Post(""))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, 0)
+	nc.StatusCodeRetries = 3
+	nc.StatusCodePolicies, err = ParseStatusCodePolicies(map[string]string{"409": "retry:10ms"})
+	require.Nil(t, err)
+	require.Nil(t, nc.Post(""))
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, 0)
+	nc.StatusCodeRetries = 1
+	require.NotNil(t, nc.Post(""))
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, 0)
+	nc.StatusCodePolicies, err = ParseStatusCodePolicies(map[string]string{"409": "success"})
+	require.Nil(t, err)
+	require.Nil(t, nc.Post(""))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestPostDeadline(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	promStats := newTestPromStats()
+	nc, err := NewClient("Webhook", ts.URL, false, false, &types.Configuration{}, &types.Statistics{}, promStats, nil, nil)
+	require.Nil(t, err)
+	nc.StatusCodeRetries = 10
+	nc.StatusCodePolicies, err = ParseStatusCodePolicies(map[string]string{"503": "retry:100ms"})
+	require.Nil(t, err)
+
+	d, err := NewDispatcher(nil, []string{"Webhook"}, new(Drainer), promStats)
+	require.Nil(t, err)
+	d.Deadline = 250 * time.Millisecond
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	x := d.NewDispatch(f)
+	var result error
+	x.Add("Webhook", OutputFunc(func(ctx context.Context, falcopayload types.FalcoPayload) error {
+		result = nc.post(ctx, falcopayload, falcopayload)
+		return result
+	}))
+	start := time.Now()
+	x.Run()
+	x.Wait()
+
+	// the retries stop at the deadline even if attempts remain
+	require.Equal(t, ErrDeadlineExceeded, result)
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+	require.LessOrEqual(t, atomic.LoadInt32(&calls), int32(3))
+	require.Equal(t, float64(1), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "webhook", "status": Timeout})))
+}
+
+func TestNewClientTimeout(t *testing.T) {
+	config := &types.Configuration{Timeout: types.TimeoutConfig{
+		Default: "10s",
+		Outputs: map[string]string{"elasticsearch": "30s", "slack": "3s", "loki": "0"},
+	}}
+
+	es, err := NewClient("Elasticsearch", "http://localhost", false, true, config, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	require.Equal(t, 30*time.Second, es.Timeout)
+	slack, err := NewClient("Slack", "http://localhost", false, true, config, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	require.Equal(t, 3*time.Second, slack.Timeout)
+	client, err := slack.httpClient()
+	require.Nil(t, err)
+	require.Equal(t, 3*time.Second, client.Timeout)
+	require.Equal(t, 3*time.Second, client.Transport.(*http.Transport).TLSHandshakeTimeout)
+
+	// 0 and the outputs without timeout use the default one
+	loki, err := NewClient("Loki", "http://localhost", false, true, config, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	require.Equal(t, 10*time.Second, loki.Timeout)
+	webhook, err := NewClient("Webhook", "http://localhost", false, true, config, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	require.Equal(t, 10*time.Second, webhook.Timeout)
+}
+
+func TestPostTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{Timeout: types.TimeoutConfig{
+		Outputs: map[string]string{"slack": "50ms", "elasticsearch": "5s"},
+	}}
+
+	// the output with the short timeout fails fast, the other one waits for the slow server
+	slack, err := NewClient("Slack", ts.URL, false, true, config, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	start := time.Now()
+	require.NotNil(t, slack.Post(""))
+	require.Less(t, int64(time.Since(start)), int64(300*time.Millisecond))
+
+	es, err := NewClient("Elasticsearch", ts.URL, false, true, config, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, es.Post(""))
+}
+
+func TestPostMaxRetryDuration(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	promStats := newTestPromStats()
+	nc, err := NewClient("Webhook", ts.URL, false, false, &types.Configuration{}, &types.Statistics{}, promStats, nil, nil)
+	require.Nil(t, err)
+	nc.StatusCodeRetries = 1
+	nc.MaxRetryDuration = 300 * time.Millisecond
+	nc.StatusCodePolicies, err = ParseStatusCodePolicies(map[string]string{"503": "retry:50ms"})
+	require.Nil(t, err)
+
+	// the retries go on beyond StatusCodeRetries until the duration is exhausted, then the final failure is counted once
+	start := time.Now()
+	require.NotNil(t, nc.Post(""))
+	require.Less(t, int64(time.Since(start)), int64(300*time.Millisecond))
+	require.Greater(t, atomic.LoadInt32(&calls), int32(2))
+	finalFailures := promStats.Outputs.With(map[string]string{"destination": "webhook", "status": FinalFailure})
+	require.Equal(t, float64(1), testutil.ToFloat64(finalFailures))
+
+	// the connection errors are retried too
+	ts.Close()
+	nc.MaxRetryDuration = 1500 * time.Millisecond
+	start = time.Now()
+	require.NotNil(t, nc.Post(""))
+	require.GreaterOrEqual(t, int64(time.Since(start)), int64(DefaultStatusCodeBackoff))
+	require.Equal(t, float64(2), testutil.ToFloat64(finalFailures))
+}
+
+func TestPostRetry(t *testing.T) {
+	var calls int32
+	status := http.StatusServiceUnavailable
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	promStats := newTestPromStats()
+	promStats.Retries = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "falcosidekick_outputs_retries"}, []string{"destination"})
+	config := &types.Configuration{Retry: types.RetryConfig{MaxAttempts: 3, InitialDelay: 10, MaxDelay: 100}}
+	nc, err := NewClient("Webhook", ts.URL, false, false, config, &types.Statistics{}, promStats, nil, nil)
+	require.Nil(t, err)
+	require.Equal(t, 10*time.Millisecond, nc.RetryInitialDelay)
+
+	require.Nil(t, nc.Post(""))
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	retries := promStats.Retries.With(map[string]string{"destination": "webhook"})
+	require.Equal(t, float64(2), testutil.ToFloat64(retries))
+
+	// the permanent failures aren't retried
+	for _, i := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound} {
+		atomic.StoreInt32(&calls, 0)
+		status = i
+		require.NotNil(t, nc.Post(""))
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	}
+	require.Equal(t, float64(2), testutil.ToFloat64(retries))
+
+	// without enough attempts, the last failure is returned
+	atomic.StoreInt32(&calls, 0)
+	status = http.StatusBadGateway
+	nc.RetryMaxAttempts = 2
+	require.NotNil(t, nc.Post(""))
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestPostRateLimit(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	promStats := newTestPromStats()
+	promStats.RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "falcosidekick_outputs_ratelimited"}, []string{"destination"})
+	nc, err := NewClient("Slack", ts.URL, false, false, &types.Configuration{}, &types.Statistics{}, promStats, nil, nil)
+	require.Nil(t, err)
+	nc.RateLimiter, err = NewRateLimiter("10/s")
+	require.Nil(t, err)
+
+	post := func(n int) time.Duration {
+		var wg sync.WaitGroup
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				nc.Post("")
+			}()
+		}
+		wg.Wait()
+		return time.Since(start)
+	}
+
+	// in drop mode, only the events of the bucket are sent, the others are dropped at once
+	nc.RateLimitMode = Drop
+	elapsed := post(100)
+	require.Less(t, int64(elapsed), int64(500*time.Millisecond))
+	dropped := testutil.ToFloat64(promStats.RateLimited.With(map[string]string{"destination": "slack"}))
+	require.InDelta(t, 90, dropped, 1)
+	require.Equal(t, float64(100), dropped+float64(atomic.LoadInt32(&calls)))
+
+	// in block mode, the events wait for the rate, none is dropped
+	time.Sleep(time.Second)
+	atomic.StoreInt32(&calls, 0)
+	nc.RateLimitMode = Block
+	elapsed = post(20)
+	require.GreaterOrEqual(t, int64(elapsed), int64(900*time.Millisecond))
+	require.Less(t, int64(elapsed), int64(2*time.Second))
+	require.Equal(t, int32(20), atomic.LoadInt32(&calls))
+	require.Equal(t, dropped, testutil.ToFloat64(promStats.RateLimited.With(map[string]string{"destination": "slack"})))
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	limiter, err := NewRateLimiter("")
+	require.Nil(t, err)
+	require.Nil(t, limiter)
+
+	for i, j := range map[string]float64{"10/s": 10, "120/m": 2, "1800/h": 0.5} {
+		limiter, err := NewRateLimiter(i)
+		require.Nil(t, err)
+		require.Equal(t, j, float64(limiter.Limit()))
+		require.GreaterOrEqual(t, limiter.Burst(), 1)
+	}
+
+	for _, i := range []string{"10", "10/d", "-1/s", "x/s"} {
+		_, err := NewRateLimiter(i)
+		require.NotNil(t, err)
+	}
+}
+
+func TestPostCompression(t *testing.T) {
+	var encoding string
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	nc, err := NewClient("Elasticsearch", ts.URL, false, false, &types.Configuration{}, &types.Statistics{}, &types.PromStatistics{}, nil, nil)
+	require.Nil(t, err)
+	nc.EnableCompression = true
+	nc.CompressionThreshold = DefaultCompressionThreshold
+
+	// 2MB, half random to be realistic about the ratio
+	random := make([]byte, 512*1024)
+	_, err = rand.Read(random)
+	require.Nil(t, err)
+	payload := map[string]string{"random": fmt.Sprintf("%x", random), "repeated": strings.Repeat("falcosidekick", 1024*1024/13)}
+	expected := new(bytes.Buffer)
+	require.Nil(t, json.NewEncoder(expected).Encode(payload))
+	require.Greater(t, expected.Len(), 2*1024*1024)
+
+	require.Nil(t, nc.Post(payload))
+	require.Equal(t, "gzip", encoding)
+	require.Less(t, len(body), expected.Len())
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	require.Nil(t, err)
+	decompressed, err := ioutil.ReadAll(r)
+	require.Nil(t, err)
+	require.Equal(t, expected.Bytes(), decompressed)
+
+	// the small bodies aren't compressed
+	require.Nil(t, nc.Post(map[string]string{"rule": "Test rule"}))
+	require.Equal(t, "", encoding)
+	require.Equal(t, "{\"rule\":\"Test rule\"}\n", string(body))
+
+	// nor the bodies of the outputs without compression
+	nc.EnableCompression = false
+	require.Nil(t, nc.Post(payload))
+	require.Equal(t, "", encoding)
+	require.Equal(t, expected.Bytes(), body)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	nc := &Client{RetryInitialDelay: 100 * time.Millisecond, RetryMaxDelay: time.Second}
+	for i, j := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		d := nc.exponentialBackoff(i)
+		require.GreaterOrEqual(t, int64(d), int64(j/2))
+		require.LessOrEqual(t, int64(d), int64(j))
+	}
+}
+
+func TestParseStatusCodePolicies(t *testing.T) {
+	p, err := ParseStatusCodePolicies(map[string]string{"409": "retry", "503": "retry:5s", "202": "fail"})
+	require.Nil(t, err)
+	require.Equal(t, map[int]StatusCodePolicy{
+		409: {Action: RetryPolicy, Backoff: DefaultStatusCodeBackoff},
+		503: {Action: RetryPolicy, Backoff: 5 * time.Second},
+		202: {Action: FailPolicy},
+	}, p)
+
+	for _, i := range []map[string]string{{"conflict": "retry"}, {"409": "retry:soon"}, {"409": "ignore"}} {
+		_, err := ParseStatusCodePolicies(i)
+		require.NotNil(t, err)
+	}
+}
@@ -2,15 +2,25 @@ package outputs
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"log"
+	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
+// Modes of the CloudEvents sent, see https://github.com/cloudevents/spec/blob/v1.0/http-protocol-binding.md
+const (
+	CloudEventsBinaryMode     string = "binary"     // the attributes are in the ce-* headers, the body is the event
+	CloudEventsStructuredMode string = "structured" // the body is the JSON envelope of the attributes and the event
+)
+
 // CloudEventsSend produces a CloudEvent and sends to the CloudEvents consumers.
-func (c *Client) CloudEventsSend(falcopayload types.FalcoPayload) {
+func (c *Client) CloudEventsSend(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.CloudEvents.Add(Total, 1)
 
 	if c.CloudEventsClient == nil {
@@ -18,22 +28,28 @@ func (c *Client) CloudEventsSend(falcopayload types.FalcoPayload) {
 		if err != nil {
 			go c.CountMetric(Outputs, 1, []string{"output:cloudevents", "status:error"})
 			log.Printf("[ERROR] : CloudEvents - NewDefaultClient : %v\n", err)
-			return
+			return err
 		}
 		c.CloudEventsClient = client
 	}
 
-	ctx := cloudevents.ContextWithTarget(context.Background(), c.EndpointURL.String())
+	ctx = cloudevents.ContextWithTarget(ctx, c.EndpointURL.String())
+	if c.config().CloudEvents.Mode == CloudEventsStructuredMode {
+		ctx = cloudevents.WithEncodingStructured(ctx)
+	} else {
+		ctx = cloudevents.WithEncodingBinary(ctx)
+	}
 
 	event := cloudevents.NewEvent()
+	event.SetID(cloudEventID(falcopayload))
 	event.SetTime(falcopayload.Time)
-	event.SetSource("falco.org") // TODO: this should have some info on the falco server that made the event.
-	event.SetType("falco.rule.output.v1")
+	event.SetSource(c.config().CloudEvents.Source)
+	event.SetType(c.config().CloudEvents.Type)
 	event.SetExtension("priority", falcopayload.Priority.String())
 	event.SetExtension("rule", falcopayload.Rule)
 
 	// Set Extensions.
-	for k, v := range c.Config.CloudEvents.Extensions {
+	for k, v := range c.config().CloudEvents.Extensions {
 		event.SetExtension(k, v)
 	}
 
@@ -46,7 +62,7 @@ func (c *Client) CloudEventsSend(falcopayload types.FalcoPayload) {
 		c.Stats.CloudEvents.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "cloudevents", "status": Error}).Inc()
 		log.Printf("[ERROR] : CloudEvents - %v\n", result)
-		return
+		return result
 	}
 
 	// Setting the success status
@@ -54,4 +70,17 @@ func (c *Client) CloudEventsSend(falcopayload types.FalcoPayload) {
 	c.Stats.CloudEvents.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "cloudevents", "status": OK}).Inc()
 	log.Printf("[INFO]  : CloudEvents - Send OK\n")
+
+	return nil
+}
+
+// cloudEventID returns the ID of the CloudEvent of an event, the SHA-256 of its rule, its time and its output fields,
+// the same event sent twice has the same ID for the consumers deduplicating the CloudEvents
+func cloudEventID(falcopayload types.FalcoPayload) string {
+	h := sha256.New()
+	h.Write([]byte(falcopayload.Rule + "\x00" + falcopayload.Time.UTC().Format(time.RFC3339Nano) + "\x00"))
+	// the keys of the fields are sorted by json.Marshal
+	fields, _ := json.Marshal(falcopayload.OutputFields)
+	h.Write(fields)
+	return hex.EncodeToString(h.Sum(nil))
 }
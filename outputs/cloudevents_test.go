@@ -0,0 +1,81 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestCloudEventsSend(t *testing.T) {
+	var headers http.Header
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.CloudEvents.Type = "falco.event"
+	config.CloudEvents.Source = "falco.org/node-1"
+	config.CloudEvents.Extensions = map[string]string{"cluster": "prod"}
+	client, err := NewClient("CloudEvents", ts.URL, false, false, config, &types.Statistics{CloudEvents: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	id := cloudEventID(f)
+	require.Len(t, id, 64)
+
+	// binary mode, the attributes are in the headers
+	config.CloudEvents.Mode = CloudEventsBinaryMode
+	require.Nil(t, client.CloudEventsSend(context.Background(), f))
+	require.Equal(t, "1.0", headers.Get("ce-specversion"))
+	require.Equal(t, "falco.event", headers.Get("ce-type"))
+	require.Equal(t, "falco.org/node-1", headers.Get("ce-source"))
+	require.Equal(t, id, headers.Get("ce-id"))
+	require.Equal(t, "2001-01-01T01:10:00Z", headers.Get("ce-time"))
+	require.Equal(t, "Test rule", headers.Get("ce-rule"))
+	require.Equal(t, "prod", headers.Get("ce-cluster"))
+	require.Equal(t, "application/json", headers.Get("Content-Type"))
+	var data types.FalcoPayload
+	require.Nil(t, json.Unmarshal(body, &data))
+	require.Equal(t, f.Output, data.Output)
+
+	// structured mode, the attributes are in the envelope
+	config.CloudEvents.Mode = CloudEventsStructuredMode
+	require.Nil(t, client.CloudEventsSend(context.Background(), f))
+	require.Empty(t, headers.Get("ce-id"))
+	require.Equal(t, "application/cloudevents+json", headers.Get("Content-Type"))
+	var envelope struct {
+		SpecVersion string             `json:"specversion"`
+		ID          string             `json:"id"`
+		Type        string             `json:"type"`
+		Source      string             `json:"source"`
+		Time        string             `json:"time"`
+		Rule        string             `json:"rule"`
+		Data        types.FalcoPayload `json:"data"`
+	}
+	require.Nil(t, json.Unmarshal(body, &envelope))
+	require.Equal(t, "1.0", envelope.SpecVersion)
+	require.Equal(t, id, envelope.ID)
+	require.Equal(t, "falco.event", envelope.Type)
+	require.Equal(t, "falco.org/node-1", envelope.Source)
+	require.Equal(t, "2001-01-01T01:10:00Z", envelope.Time)
+	require.Equal(t, "Test rule", envelope.Rule)
+	require.Equal(t, f.Output, envelope.Data.Output)
+	require.Equal(t, "2", client.Stats.CloudEvents.Get(OK).String())
+
+	// the ID is the same for the same event only
+	f.OutputFields["proc.name"] = "bash"
+	require.NotEqual(t, id, cloudEventID(f))
+}
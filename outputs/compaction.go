@@ -0,0 +1,54 @@
+package outputs
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// SharedOutputFields is the key of the output fields identical across all the events of a batch compacted, they're
+// written once instead of in each event
+const SharedOutputFields string = "shared_output_fields"
+
+// compactOutputFields factors out the output fields identical across all the events of a batch, encoded as JSON
+// objects whose output fields are under key. It returns the events decoded without them and the shared fields, which
+// are nil if there are none, if there are fewer than 2 events or if an event can't be decoded.
+func compactOutputFields(events [][]byte, key string) ([]map[string]json.RawMessage, map[string]json.RawMessage) {
+	if len(events) < 2 {
+		return nil, nil
+	}
+	decoded := make([]map[string]json.RawMessage, len(events))
+	fields := make([]map[string]json.RawMessage, len(events))
+	for i, j := range events {
+		if err := json.Unmarshal(j, &decoded[i]); err != nil {
+			return nil, nil
+		}
+		if err := json.Unmarshal(decoded[i][key], &fields[i]); err != nil {
+			return nil, nil
+		}
+	}
+
+	shared := make(map[string]json.RawMessage)
+	for k, v := range fields[0] {
+		identical := true
+		for _, i := range fields[1:] {
+			if w, ok := i[k]; !ok || !bytes.Equal(v, w) {
+				identical = false
+				break
+			}
+		}
+		if identical {
+			shared[k] = v
+		}
+	}
+	if len(shared) == 0 {
+		return nil, nil
+	}
+
+	for i, j := range decoded {
+		for k := range shared {
+			delete(fields[i], k)
+		}
+		j[key], _ = json.Marshal(fields[i])
+	}
+	return decoded, shared
+}
@@ -0,0 +1,50 @@
+package outputs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// DefaultCompressionThreshold is the size in bytes under which the bodies aren't compressed, they would get bigger
+const DefaultCompressionThreshold int = 1024
+
+// gzipWriters are reused across the requests of all the outputs, a gzip.Writer allocates large buffers
+var gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(ioutil.Discard) }}
+
+// compress returns the body gzipped if EnableCompression is set and it's at least CompressionThreshold bytes,
+// and true if it has been compressed
+func (c *Client) compress(body *bytes.Buffer) (*bytes.Buffer, bool) {
+	if !c.EnableCompression || body.Len() < c.CompressionThreshold {
+		return body, false
+	}
+	w := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(w)
+	compressed := new(bytes.Buffer)
+	w.Reset(compressed)
+	if _, err := w.Write(body.Bytes()); err != nil {
+		return body, false
+	}
+	if err := w.Close(); err != nil {
+		return body, false
+	}
+	return compressed, true
+}
+
+// decompressedBody returns the body of a request, decompressed if it's gzipped
+func decompressedBody(req *http.Request, body []byte) []byte {
+	if req.Header.Get("Content-Encoding") != "gzip" {
+		return body
+	}
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return body
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return body
+	}
+	return b
+}
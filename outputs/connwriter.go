@@ -0,0 +1,97 @@
+package outputs
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+)
+
+const connWriteTimeout = 10 * time.Second
+
+// connWriter sends the messages over its connection, the TCP connections, with TLS if tlsConfig is set, are
+// reestablished once lost
+type connWriter struct {
+	network   string // udp or tcp
+	address   string
+	tlsConfig *tls.Config
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (w *connWriter) dial(ctx context.Context) (net.Conn, error) {
+	d := &net.Dialer{}
+	if w.tlsConfig != nil {
+		return (&tls.Dialer{NetDialer: d, Config: w.tlsConfig}).DialContext(ctx, w.network, w.address)
+	}
+	return d.DialContext(ctx, w.network, w.address)
+}
+
+// write sends a message, with a new connection if there's none or if the current one is lost, a message failing on
+// a connection reestablished is an error
+func (w *connWriter) write(ctx context.Context, message []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for attempt := 0; ; attempt++ {
+		if w.conn == nil {
+			conn, err := w.dial(ctx)
+			if err != nil {
+				return err
+			}
+			w.conn = conn
+			if w.network != "udp" {
+				go w.watch(conn)
+			}
+		}
+		deadline := time.Now().Add(connWriteTimeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		// #nosec G104 a failure is returned by the write
+		w.conn.SetWriteDeadline(deadline)
+		_, err := w.conn.Write(message)
+		if err == nil {
+			return nil
+		}
+		w.close()
+		if attempt > 0 || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// watch drops the connection once the server closes it, the server never sends data so a read only ends when the
+// connection is lost, before the next write would fail silently
+func (w *connWriter) watch(conn net.Conn) {
+	// #nosec G104 the read ends with the connection
+	io.Copy(ioutil.Discard, conn)
+	w.mu.Lock()
+	if w.conn == conn {
+		w.close()
+	}
+	w.mu.Unlock()
+}
+
+// close closes the current connection, w.mu must be held
+func (w *connWriter) close() {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// healthCheck checks the connectivity of the server with a new connection, always up with UDP
+func (w *connWriter) healthCheck(ctx context.Context) error {
+	if w.network == "udp" {
+		return nil
+	}
+	conn, err := w.dial(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
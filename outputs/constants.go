@@ -19,6 +19,20 @@ const (
 	Rejected string = "rejected"
 	Accepted string = "accepted"
 	Outputs  string = "outputs"
+	Dropped  string = "dropped"
+	Forward  string = "forward"
+	Timeout  string = "timeout"
+	DryRun   string = "dryrun"
+
+	// RequiredFieldsAction writing the events dropped to the dead letter file
+	DeadLetter string = "deadletter"
+
+	FinalFailure string = "finalfailure"
+
+	IngestLatencyField   string = "ingest_latency_ms"
+	IngestClockSkewField string = "ingest_clock_skew_ms"
+
+	SchemaVersionHeader string = "X-Falco-Schema-Version"
 
 	Rule     string = "rule"
 	Priority string = "priority"
@@ -38,4 +52,7 @@ const (
 
 	Kubeless string = "Kubeless"
 	Openfaas string = "OpenFaas"
+
+	TeamsLegacy    string = "legacy"
+	TeamsWorkflows string = "workflows"
 )
@@ -0,0 +1,154 @@
+package outputs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoHealthyInstance is returned if the service of an output has no healthy instance in Consul
+var ErrNoHealthyInstance = errors.New("No healthy instance of the service")
+
+type consulServiceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+	}
+}
+
+// ServiceResolver resolves the instances of a service registered in Consul, only the healthy ones with all the tags are kept.
+// The requests are balanced across the instances, with a failover on the next ones.
+type ServiceResolver struct {
+	Service    string
+	Tags       []string
+	consulURL  string
+	token      string
+	httpClient *http.Client
+	mu         sync.RWMutex
+	instances  []string // host:port
+	next       uint32
+}
+
+// NewServiceResolver returns a ServiceResolver for a service of the Consul agent at address, it's resolved a first time
+func NewServiceResolver(address, token, service string, tags []string) *ServiceResolver {
+	r := &ServiceResolver{
+		Service:    service,
+		Tags:       tags,
+		consulURL:  address,
+		token:      token,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+	if err := r.Refresh(); err != nil {
+		log.Printf("[ERROR] : Consul - %v\n", err)
+	}
+	return r
+}
+
+// Refresh gets the healthy instances of the service, the previous ones are kept if Consul can't be reached
+func (r *ServiceResolver) Refresh() error {
+	query := url.Values{"passing": []string{"true"}}
+	for _, i := range r.Tags {
+		query.Add("tag", i)
+	}
+	req, err := http.NewRequest("GET", fmt.Sprintf("%v/v1/health/service/%v?%v", r.consulURL, url.PathEscape(r.Service), query.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	if r.token != "" {
+		req.Header.Set("X-Consul-Token", r.token)
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected Response (%v) for service '%v'", resp.StatusCode, r.Service)
+	}
+
+	var entries []consulServiceEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return err
+	}
+	instances := make([]string, 0, len(entries))
+	for _, i := range entries {
+		host := i.Service.Address
+		if host == "" {
+			host = i.Node.Address
+		}
+		instances = append(instances, net.JoinHostPort(host, strconv.Itoa(i.Service.Port)))
+	}
+
+	r.mu.Lock()
+	r.instances = instances
+	r.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the instances every interval
+func (r *ServiceResolver) Run(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := r.Refresh(); err != nil {
+			log.Printf("[ERROR] : Consul - %v\n", err)
+		}
+	}
+}
+
+// Instances returns the instances in the order to try them for a request, starting with a different one each time
+func (r *ServiceResolver) Instances() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n := len(r.instances)
+	if n == 0 {
+		return nil
+	}
+	start := int(atomic.AddUint32(&r.next, 1)-1) % n
+	return append(append([]string{}, r.instances[start:]...), r.instances[:start]...)
+}
+
+// send sends the request to the output, or logs it in dry run, for an output resolved with Consul the instances are
+// tried in turn until one responds without a connection error or a 5xx
+func (c *Client) send(client *http.Client, req *http.Request) (*http.Response, error) {
+	if c.DryRun {
+		return c.dryRun(req)
+	}
+	if c.Resolver == nil {
+		return c.timeRequest(client, req)
+	}
+	instances := c.Resolver.Instances()
+	if len(instances) == 0 {
+		return nil, ErrNoHealthyInstance
+	}
+
+	var resp *http.Response
+	var err error
+	for i, j := range instances {
+		r := req.Clone(req.Context())
+		r.URL.Host, r.Host = j, ""
+		if i > 0 {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if r.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+			log.Printf("[WARN]  : %v - Failover to %v\n", c.OutputType, j)
+		}
+		resp, err = c.timeRequest(client, r)
+		if (err == nil && resp.StatusCode < http.StatusInternalServerError) || req.Context().Err() != nil {
+			return resp, err
+		}
+	}
+	return resp, err
+}
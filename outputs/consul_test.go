@@ -0,0 +1,94 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestServiceResolver(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	var failing int32
+	instance := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/falco", r.URL.Path)
+			if name == "a" && atomic.LoadInt32(&failing) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			mu.Lock()
+			received = append(received, name)
+			mu.Unlock()
+		}))
+	}
+	a, b := instance("a"), instance("b")
+	defer a.Close()
+	defer b.Close()
+
+	var instances []*httptest.Server
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/health/service/webhook", r.URL.Path)
+		require.Equal(t, "true", r.URL.Query().Get("passing"))
+		require.Equal(t, []string{"falco"}, r.URL.Query()["tag"])
+		require.Equal(t, "secret", r.Header.Get("X-Consul-Token"))
+		var entries []map[string]interface{}
+		for _, i := range instances {
+			u, _ := url.Parse(i.URL)
+			port, _ := strconv.Atoi(u.Port())
+			entries = append(entries, map[string]interface{}{
+				"Node":    map[string]interface{}{"Address": u.Hostname()},
+				"Service": map[string]interface{}{"Address": "", "Port": port},
+			})
+		}
+		require.Nil(t, json.NewEncoder(w).Encode(entries))
+	}))
+	defer consul.Close()
+
+	instances = []*httptest.Server{a, b}
+	resolver := NewServiceResolver(consul.URL, "secret", "webhook", []string{"falco"})
+	require.Len(t, resolver.Instances(), 2)
+
+	client, err := NewClient("Webhook", "http://webhook/falco", false, false, &types.Configuration{}, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.Resolver = resolver
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	post := func() {
+		require.Nil(t, client.WebhookPost(context.Background(), f))
+	}
+
+	// the requests are balanced across the instances
+	post()
+	post()
+	require.ElementsMatch(t, []string{"a", "b"}, received)
+
+	// a failing instance fails over to the next one
+	received = nil
+	atomic.StoreInt32(&failing, 1)
+	post()
+	post()
+	require.Equal(t, []string{"b", "b"}, received)
+
+	// the instances follow the catalog
+	instances = []*httptest.Server{a}
+	atomic.StoreInt32(&failing, 0)
+	require.Nil(t, resolver.Refresh())
+	require.Equal(t, []string{a.URL[len("http://"):]}, resolver.Instances())
+
+	instances = nil
+	require.Nil(t, resolver.Refresh())
+	require.Equal(t, ErrNoHealthyInstance, client.Post("test"))
+}
@@ -0,0 +1,145 @@
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// CooldownReason is the reason of the events suppressed during the cooldown of an output, in falcosidekick_suppressed
+const CooldownReason string = "cooldown"
+
+// Cooldown suppresses for an output the events identical to one sent successfully to it within its window (ex: the same
+// page to PagerDuty for 10 minutes), the window isn't extended by the events suppressed and a failed event doesn't start
+// one. The identical events have the same key,
+// built with a template on the event (ex: {{.Rule}}), they're counted in falcosidekick_suppressed with the reason cooldown.
+type Cooldown struct {
+	PromStats *types.PromStatistics
+	windows   map[string]time.Duration // names are lowercased without spaces
+	key       *template.Template
+	mu        sync.Mutex
+	sent      map[cooldownKey]time.Time // time of the last event sent
+	purge     int                       // size of sent purging the expired keys
+	now       func() time.Time
+}
+
+type cooldownKey struct {
+	output string
+	key    string
+}
+
+// NewCooldown returns a Cooldown for the windows configured by output, all the outputs must be enabled
+func NewCooldown(config *types.Configuration, enabledOutputs []string, promStats *types.PromStatistics) (*Cooldown, error) {
+	enabled := enabledOutputSet(enabledOutputs)
+	key, err := parseFieldTemplate("key", config.Cooldown.Key)
+	if err != nil {
+		return nil, fmt.Errorf("Bad key : %v", err)
+	}
+	c := &Cooldown{
+		PromStats: promStats,
+		windows:   make(map[string]time.Duration, len(config.Cooldown.Windows)),
+		key:       key,
+		sent:      make(map[cooldownKey]time.Time),
+		purge:     1024,
+		now:       time.Now,
+	}
+	for i, j := range config.Cooldown.Windows {
+		if !enabled[dispatchName(i)] {
+			return nil, fmt.Errorf("Output '%v' with a cooldown isn't enabled", i)
+		}
+		d, err := time.ParseDuration(j)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("Bad window '%v' of output '%v', must be a duration (ex: 10m)", j, i)
+		}
+		c.windows[dispatchName(i)] = d
+	}
+	return c, nil
+}
+
+// Suppress returns true if an identical event has been sent successfully to the output within its window, an event
+// whose key can't be built is never suppressed
+func (c *Cooldown) Suppress(output string, falcopayload types.FalcoPayload) bool {
+	k, window, ok := c.keyOf(output, falcopayload)
+	if !ok {
+		return false
+	}
+	now := c.now()
+	c.mu.Lock()
+	last, ok := c.sent[k]
+	c.mu.Unlock()
+	if !ok || now.Sub(last) >= window {
+		return false
+	}
+	if c.PromStats != nil && c.PromStats.Suppressed != nil {
+		c.PromStats.Suppressed.With(map[string]string{"destination": k.output, "reason": CooldownReason}).Inc()
+	}
+	return true
+}
+
+// Output returns the output starting a new window once an event has been sent to it successfully, an event that
+// failed doesn't start one so it can be sent again, the output is returned as is if it has no cooldown
+func (c *Cooldown) Output(name string, output Output) Output {
+	if c == nil {
+		return output
+	}
+	if _, ok := c.windows[dispatchName(name)]; !ok {
+		return output
+	}
+	return OutputFunc(func(ctx context.Context, falcopayload types.FalcoPayload) error {
+		err := output.Send(ctx, falcopayload)
+		if err == nil {
+			c.sentTo(name, falcopayload)
+		}
+		return err
+	})
+}
+
+// sentTo starts the window of an event sent to the output
+func (c *Cooldown) sentTo(output string, falcopayload types.FalcoPayload) {
+	k, _, ok := c.keyOf(output, falcopayload)
+	if !ok {
+		return
+	}
+	now := c.now()
+	c.mu.Lock()
+	c.sent[k] = now
+	if len(c.sent) >= c.purge {
+		c.purgeExpired(now)
+	}
+	c.mu.Unlock()
+}
+
+// keyOf returns the key of an event and the window of the output, false if the output has no cooldown or the key can't
+// be built
+func (c *Cooldown) keyOf(output string, falcopayload types.FalcoPayload) (cooldownKey, time.Duration, bool) {
+	if c == nil {
+		return cooldownKey{}, 0, false
+	}
+	output = dispatchName(output)
+	window, ok := c.windows[output]
+	if !ok {
+		return cooldownKey{}, 0, false
+	}
+	key, err := executeFieldTemplate(c.key, falcopayload, nil)
+	if err != nil {
+		logEventError("Cooldown", falcopayload, err)
+		return cooldownKey{}, 0, false
+	}
+	return cooldownKey{output: output, key: key}, window, true
+}
+
+// purgeExpired removes the keys whose window is over, c.mu must be held
+func (c *Cooldown) purgeExpired(now time.Time) {
+	for i, j := range c.sent {
+		if now.Sub(j) >= c.windows[i.output] {
+			delete(c.sent, i)
+		}
+	}
+	if c.purge < 2*len(c.sent) {
+		c.purge = 2 * len(c.sent)
+	}
+}
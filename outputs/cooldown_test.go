@@ -0,0 +1,74 @@
+package outputs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestCooldown(t *testing.T) {
+	config := &types.Configuration{Cooldown: types.CooldownConfig{
+		Windows: map[string]string{"PagerDuty": "10m"},
+		Key:     `{{.Rule}} {{index .OutputFields "k8s.pod.name"}}`,
+	}}
+	promStats := newTestPromStats()
+	promStats.Suppressed = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "falcosidekick_suppressed"}, []string{"destination", "reason"})
+	c, err := NewCooldown(config, []string{"PagerDuty", "Slack"}, promStats)
+	require.Nil(t, err)
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	d, err := NewDispatcher(nil, []string{"PagerDuty", "Slack"}, nil, promStats)
+	require.Nil(t, err)
+	d.Cooldown = c
+
+	var pagerdutyErr error
+	dispatched := func(pod string) []string {
+		var called []string
+		f := types.FalcoPayload{Rule: "Terminal shell in container", OutputFields: map[string]interface{}{"k8s.pod.name": pod}}
+		x := d.NewDispatch(f)
+		x.Add("PagerDuty", OutputFunc(func(context.Context, types.FalcoPayload) error {
+			called = append(called, "PagerDuty")
+			return pagerdutyErr
+		}))
+		x.Add("Slack", OutputFunc(func(context.Context, types.FalcoPayload) error { called = append(called, "Slack"); return nil }))
+		for _, i := range x.order {
+			i.output.Send(x.ctx, x.falcopayload)
+		}
+		return called
+	}
+
+	require.Equal(t, []string{"PagerDuty", "Slack"}, dispatched("nginx"))
+	// an identical event within the cooldown is suppressed, only for the output with a cooldown
+	now = now.Add(5 * time.Minute)
+	require.Equal(t, []string{"Slack"}, dispatched("nginx"))
+	// an event with another key isn't
+	require.Equal(t, []string{"PagerDuty", "Slack"}, dispatched("redis"))
+	// the suppressed events don't extend the cooldown, an identical event after it passes
+	now = now.Add(5 * time.Minute)
+	require.Equal(t, []string{"PagerDuty", "Slack"}, dispatched("nginx"))
+	require.Equal(t, float64(1), testutil.ToFloat64(promStats.Suppressed.With(map[string]string{"destination": "pagerduty", "reason": CooldownReason})))
+	require.Equal(t, float64(1), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "pagerduty", "status": Suppressed})))
+
+	// an event which failed doesn't start a window, the identical events are sent until one succeeds
+	pagerdutyErr = errors.New("unavailable")
+	require.Equal(t, []string{"PagerDuty", "Slack"}, dispatched("postgres"))
+	require.Equal(t, []string{"PagerDuty", "Slack"}, dispatched("postgres"))
+	pagerdutyErr = nil
+	require.Equal(t, []string{"PagerDuty", "Slack"}, dispatched("postgres"))
+	require.Equal(t, []string{"Slack"}, dispatched("postgres"))
+
+	config.Cooldown.Windows = map[string]string{"Teams": "10m"}
+	_, err = NewCooldown(config, []string{"PagerDuty", "Slack"}, promStats)
+	require.NotNil(t, err)
+	config.Cooldown.Windows = map[string]string{"PagerDuty": "10"}
+	_, err = NewCooldown(config, []string{"PagerDuty", "Slack"}, promStats)
+	require.NotNil(t, err)
+}
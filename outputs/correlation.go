@@ -0,0 +1,143 @@
+package outputs
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Sources of events for the correlation
+const (
+	SyscallSource  string = "syscall"
+	K8sAuditSource string = "k8s_audit"
+)
+
+// CorrelatedRulesField is the field of a merged event listing the rules of the correlated events
+const CorrelatedRulesField string = "correlated_rules"
+
+type pendingEvent struct {
+	falcopayload types.FalcoPayload
+	source       string
+	timer        *time.Timer
+}
+
+// Correlator holds syscall and k8s_audit events for a short window and merges the ones describing the same action,
+// i.e. with the same values for their keys, into a single event. Events which don't correlate are emitted unchanged
+// at the end of the window.
+type Correlator struct {
+	Window      time.Duration
+	SyscallKeys []string
+	AuditKeys   []string
+	emit        func(types.FalcoPayload)
+	mu          sync.Mutex
+	pending     map[string]*pendingEvent
+}
+
+// NewCorrelator returns a Correlator for the keys and the window configured, events are emitted with emit
+func NewCorrelator(config *types.Configuration, emit func(types.FalcoPayload)) *Correlator {
+	return &Correlator{
+		Window:      time.Duration(config.Correlation.Window) * time.Millisecond,
+		SyscallKeys: config.Correlation.SyscallKeys,
+		AuditKeys:   config.Correlation.AuditKeys,
+		emit:        emit,
+		pending:     make(map[string]*pendingEvent),
+	}
+}
+
+// Add receives an event, it's emitted immediately if it can't be correlated (missing keys)
+func (c *Correlator) Add(falcopayload types.FalcoPayload) {
+	source, key, ok := c.correlationKey(falcopayload)
+	if !ok {
+		c.emit(falcopayload)
+		return
+	}
+
+	c.mu.Lock()
+	p, found := c.pending[key]
+	if found && p.source != source && absDuration(p.falcopayload.Time.Sub(falcopayload.Time)) <= c.Window && p.timer.Stop() {
+		delete(c.pending, key)
+		c.mu.Unlock()
+		if source == SyscallSource {
+			c.emit(mergeCorrelatedEvents(falcopayload, p.falcopayload))
+		} else {
+			c.emit(mergeCorrelatedEvents(p.falcopayload, falcopayload))
+		}
+		return
+	}
+	var previous *pendingEvent
+	if found && p.timer.Stop() {
+		previous = p
+	}
+	e := &pendingEvent{falcopayload: falcopayload, source: source}
+	e.timer = time.AfterFunc(c.Window, func() { c.expire(key, e) })
+	c.pending[key] = e
+	c.mu.Unlock()
+
+	if previous != nil {
+		c.emit(previous.falcopayload)
+	}
+}
+
+func (c *Correlator) expire(key string, e *pendingEvent) {
+	c.mu.Lock()
+	if c.pending[key] == e {
+		delete(c.pending, key)
+	}
+	c.mu.Unlock()
+	c.emit(e.falcopayload)
+}
+
+// correlationKey returns the source of the event and the values of its keys
+func (c *Correlator) correlationKey(falcopayload types.FalcoPayload) (string, string, bool) {
+	source, keys := SyscallSource, c.SyscallKeys
+	for i := range falcopayload.OutputFields {
+		if strings.HasPrefix(i, "ka.") {
+			source, keys = K8sAuditSource, c.AuditKeys
+			break
+		}
+	}
+	if len(keys) == 0 {
+		return "", "", false
+	}
+
+	values := make([]string, 0, len(keys))
+	for _, i := range keys {
+		v, ok := falcopayload.OutputFields[i].(string)
+		if !ok || v == "" {
+			return "", "", false
+		}
+		values = append(values, v)
+	}
+	return source, strings.Join(values, "\x00"), true
+}
+
+// mergeCorrelatedEvents merges a syscall event and a k8s_audit one, fields of the syscall event take precedence
+func mergeCorrelatedEvents(syscall, audit types.FalcoPayload) types.FalcoPayload {
+	merged := syscall
+	merged.Rule = syscall.Rule + " + " + audit.Rule
+	merged.Output = syscall.Output + "\n" + audit.Output
+	if audit.Priority > merged.Priority {
+		merged.Priority = audit.Priority
+	}
+	if audit.Time.Before(merged.Time) {
+		merged.Time = audit.Time
+	}
+	merged.OutputFields = make(map[string]interface{}, len(syscall.OutputFields)+len(audit.OutputFields)+1)
+	for i, j := range audit.OutputFields {
+		merged.OutputFields[i] = j
+	}
+	for i, j := range syscall.OutputFields {
+		merged.OutputFields[i] = j
+	}
+	merged.OutputFields[CorrelatedRulesField] = syscall.Rule + ", " + audit.Rule
+	return merged
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,59 @@
+package outputs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestCorrelator(t *testing.T) {
+	emitted := make(chan types.FalcoPayload, 10)
+	config := &types.Configuration{Correlation: types.CorrelationConfig{
+		Window:      100,
+		SyscallKeys: []string{"k8s.ns.name", "k8s.pod.name"},
+		AuditKeys:   []string{"ka.target.namespace", "ka.target.name"},
+	}}
+	c := NewCorrelator(config, func(falcopayload types.FalcoPayload) { emitted <- falcopayload })
+
+	now := time.Now()
+	syscall := types.FalcoPayload{
+		Rule:         "Terminal shell in container",
+		Output:       "A shell was spawned",
+		Priority:     types.Notice,
+		Time:         now,
+		OutputFields: map[string]interface{}{"k8s.ns.name": "default", "k8s.pod.name": "nginx", "proc.name": "bash"},
+	}
+	audit := types.FalcoPayload{
+		Rule:         "Attach/Exec Pod",
+		Output:       "Attach/Exec to pod",
+		Priority:     types.Warning,
+		Time:         now.Add(-10 * time.Millisecond),
+		OutputFields: map[string]interface{}{"ka.target.namespace": "default", "ka.target.name": "nginx", "ka.user.name": "admin"},
+	}
+
+	c.Add(audit)
+	c.Add(syscall)
+	merged := <-emitted
+	require.Equal(t, "Terminal shell in container + Attach/Exec Pod", merged.Rule)
+	require.Equal(t, types.PriorityType(types.Warning), merged.Priority)
+	require.Equal(t, audit.Time, merged.Time)
+	require.Equal(t, "bash", merged.OutputFields["proc.name"])
+	require.Equal(t, "admin", merged.OutputFields["ka.user.name"])
+	require.Equal(t, "Terminal shell in container, Attach/Exec Pod", merged.OutputFields[CorrelatedRulesField])
+
+	// no match, the event is emitted alone at the end of the window
+	c.Add(syscall)
+	select {
+	case <-emitted:
+		t.Fatal("event emitted before the end of the window")
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.Equal(t, syscall.Rule, (<-emitted).Rule)
+
+	// missing keys, the event is emitted immediately
+	c.Add(types.FalcoPayload{Rule: "Test rule"})
+	require.Equal(t, "Test rule", (<-emitted).Rule)
+}
@@ -1,8 +1,7 @@
 package outputs
 
 import (
-	"log"
-
+	"context"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
@@ -52,19 +51,21 @@ func newDatadogPayload(falcopayload types.FalcoPayload) datadogPayload {
 }
 
 // DatadogPost posts event to Datadog
-func (c *Client) DatadogPost(falcopayload types.FalcoPayload) {
+func (c *Client) DatadogPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Datadog.Add(Total, 1)
 
-	err := c.Post(newDatadogPayload(falcopayload))
+	err := c.post(ctx, newDatadogPayload(falcopayload), falcopayload)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:datadog", "status:error"})
 		c.Stats.Datadog.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "datadog", "status": Error}).Inc()
-		log.Printf("[ERROR] : Datadog - %v\n", err)
-		return
+		logEventError("Datadog", falcopayload, err)
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:datadog", "status:ok"})
 	c.Stats.Datadog.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "datadog", "status": OK}).Inc()
+
+	return nil
 }
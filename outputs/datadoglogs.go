@@ -0,0 +1,163 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Limits of the requests to the Datadog logs intake
+const (
+	// DatadogLogsPath is the path of the Datadog logs intake
+	DatadogLogsPath string = "/api/v2/logs"
+	// DatadogLogsMaxPayloadSize is the maximum size in bytes of the body of a request, uncompressed
+	DatadogLogsMaxPayloadSize int = 5 * 1024 * 1024
+	// DatadogLogsMaxBatchSize is the maximum number of logs of a request
+	DatadogLogsMaxBatchSize int = 1000
+)
+
+// DatadogLogsURL returns the URL of the logs intake of the site (ex: datadoghq.com, datadoghq.eu), a site starting with
+// http:// or https:// is the URL of a custom intake
+func DatadogLogsURL(site string) string {
+	if strings.HasPrefix(site, "http://") || strings.HasPrefix(site, "https://") {
+		return strings.TrimSuffix(site, "/") + DatadogLogsPath
+	}
+	return "https://http-intake.logs." + site + DatadogLogsPath
+}
+
+// datadogLogsStatus returns the status of the log of an event with the priority
+func datadogLogsStatus(priority types.PriorityType) string {
+	switch priority {
+	case types.Emergency:
+		return "emergency"
+	case types.Alert:
+		return "alert"
+	case types.Critical:
+		return "critical"
+	case types.Error:
+		return Error
+	case types.Warning:
+		return Warning
+	case types.Notice:
+		return "notice"
+	case types.Debug:
+		return "debug"
+	default:
+		return Info
+	}
+}
+
+// newDatadogLogsEntry returns the log of an event, the Fields of the output fields are promoted to attributes
+func newDatadogLogsEntry(falcopayload types.FalcoPayload, config *types.Configuration) map[string]interface{} {
+	tags := append([]string{"priority:" + strings.ToLower(falcopayload.Priority.String())}, config.DatadogLogs.Tags...)
+	entry := map[string]interface{}{
+		"ddsource":  "falco",
+		"ddtags":    strings.Join(tags, ","),
+		"service":   config.DatadogLogs.Service,
+		"message":   falcopayload.Output,
+		"status":    datadogLogsStatus(falcopayload.Priority),
+		"timestamp": falcopayload.Time,
+		"rule":      falcopayload.Rule,
+		"priority":  falcopayload.Priority.String(),
+	}
+	if falcopayload.Hostname != "" {
+		entry["hostname"] = falcopayload.Hostname
+	}
+	if falcopayload.Source != "" {
+		entry["source"] = falcopayload.Source
+	}
+	if len(falcopayload.Tags) != 0 {
+		entry["tags"] = falcopayload.Tags
+	}
+	for _, i := range config.DatadogLogs.Fields {
+		if v, ok := falcopayload.OutputFields[i]; ok {
+			entry[i] = v
+		}
+	}
+	return entry
+}
+
+// EnableDatadogLogsBatches makes the Datadog Logs output buffer the events and send them by batches, once BatchSize
+// events are buffered or every FlushInterval, a batch has at most DatadogLogsMaxPayloadSize bytes
+func (c *Client) EnableDatadogLogsBatches() {
+	// the body is the JSON array of the entries, separated by commas
+	c.datadogLogs = newBatcher(c.config().DatadogLogs.BatchSize, DatadogLogsMaxPayloadSize-1, time.Duration(c.config().DatadogLogs.FlushInterval)*time.Second, c.sendDatadogLogs, c.exponentialBackoff)
+}
+
+// DatadogLogsPost sends the event to the Datadog logs intake, or buffers it with BatchSize and returns once its batch
+// has been sent
+func (c *Client) DatadogLogsPost(ctx context.Context, falcopayload types.FalcoPayload) error {
+	entry, err := c.marshalDatadogLogsEntry(falcopayload)
+	if err != nil {
+		return err
+	}
+
+	if c.datadogLogs == nil {
+		err := c.post(ctx, []json.RawMessage{entry}, falcopayload)
+		if err != nil {
+			c.setDatadogLogsMetrics(Error, 1)
+			logEventError("DatadogLogs", falcopayload, err)
+			return err
+		}
+		c.setDatadogLogsMetrics(OK, 1)
+		return nil
+	}
+
+	return waitBatch(ctx, c.bufferDatadogLogs(falcopayload, entry))
+}
+
+func (c *Client) marshalDatadogLogsEntry(falcopayload types.FalcoPayload) (json.RawMessage, error) {
+	c.Stats.DatadogLogs.Add(Total, 1)
+
+	entry, err := json.Marshal(newDatadogLogsEntry(falcopayload, c.config()))
+	if err != nil {
+		c.setDatadogLogsMetrics(Error, 1)
+		logEventError("DatadogLogs", falcopayload, err)
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (c *Client) bufferDatadogLogs(falcopayload types.FalcoPayload, entry json.RawMessage) <-chan error {
+	return c.datadogLogs.add(falcopayload, entry, len(entry)+1)
+}
+
+// DatadogLogsFlush sends all the events buffered, it's called at shutdown
+func (c *Client) DatadogLogsFlush() {
+	if c.datadogLogs != nil {
+		c.datadogLogs.Flush()
+	}
+}
+
+// sendDatadogLogs sends a batch to the logs intake, the events of a batch failing are dead-lettered
+func (c *Client) sendDatadogLogs(items []*batchItem) []*batchItem {
+	entries := make([]json.RawMessage, len(items))
+	for n, i := range items {
+		entries[n] = i.value.(json.RawMessage)
+	}
+	if err := c.Post(entries); err != nil {
+		c.setDatadogLogsMetrics(Error, len(items))
+		log.Printf("[ERROR] : DatadogLogs - Batch of %v events failed : %v\n", len(items), err)
+		for _, i := range items {
+			c.deadLetter(i.falcopayload, err)
+			i.done(err)
+		}
+		return nil
+	}
+	c.setDatadogLogsMetrics(OK, len(items))
+	log.Printf("[INFO]  : DatadogLogs - Batch of %v events OK\n", len(items))
+	for _, i := range items {
+		i.done(nil)
+	}
+	return nil
+}
+
+func (c *Client) setDatadogLogsMetrics(status string, n int) {
+	go c.CountMetric(Outputs, int64(n), []string{"output:datadoglogs", "status:" + status})
+	c.Stats.DatadogLogs.Add(status, int64(n))
+	c.PromStats.Outputs.With(map[string]string{"destination": "datadoglogs", "status": status}).Add(float64(n))
+}
@@ -0,0 +1,107 @@
+package outputs
+
+import (
+	"encoding/json"
+	"expvar"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestDatadogLogsURL(t *testing.T) {
+	require.Equal(t, "https://http-intake.logs.datadoghq.com/api/v2/logs", DatadogLogsURL("datadoghq.com"))
+	require.Equal(t, "https://http-intake.logs.datadoghq.eu/api/v2/logs", DatadogLogsURL("datadoghq.eu"))
+	require.Equal(t, "http://localhost:8080/api/v2/logs", DatadogLogsURL("http://localhost:8080/"))
+}
+
+func TestDatadogLogsPost(t *testing.T) {
+	var mu sync.Mutex
+	var requests [][]map[string]interface{}
+	var sizes []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v2/logs", r.URL.Path)
+		require.Equal(t, "key", r.Header.Get("DD-API-KEY"))
+		body, _ := ioutil.ReadAll(r.Body)
+		var entries []map[string]interface{}
+		require.Nil(t, json.Unmarshal(body, &entries))
+		mu.Lock()
+		requests = append(requests, entries)
+		sizes = append(sizes, len(body))
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.DatadogLogs.APIKey = "key"
+	config.DatadogLogs.Service = "falco"
+	config.DatadogLogs.Tags = []string{"env:prod"}
+	config.DatadogLogs.Fields = []string{"proc.name"}
+	config.DatadogLogs.BatchSize = 3
+	stats := &types.Statistics{DatadogLogs: new(expvar.Map)}
+	client, err := NewClient("DatadogLogs", DatadogLogsURL(ts.URL), false, false, config, stats, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.EnableDatadogLogsBatches()
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.Hostname = "node-1"
+	f.Priority = types.Critical
+	buffer := func(n int) []<-chan error {
+		var results []<-chan error
+		for i := 0; i < n; i++ {
+			entry, err := client.marshalDatadogLogsEntry(f)
+			require.Nil(t, err)
+			results = append(results, client.bufferDatadogLogs(f, entry))
+		}
+		return results
+	}
+	results := buffer(7)
+	// 2 full batches are sent, the last event is sent at the flush
+	for _, i := range results[:6] {
+		require.Nil(t, <-i)
+	}
+	require.Len(t, requests, 2)
+	client.DatadogLogsFlush()
+	require.Nil(t, <-results[6])
+	require.Len(t, requests, 3)
+	require.Len(t, requests[0], 3)
+	require.Len(t, requests[1], 3)
+	require.Len(t, requests[2], 1)
+	require.Equal(t, "7", stats.DatadogLogs.Get(OK).String())
+
+	e := requests[0][0]
+	require.Equal(t, "falco", e["ddsource"])
+	require.Equal(t, "priority:critical,env:prod", e["ddtags"])
+	require.Equal(t, "falco", e["service"])
+	require.Equal(t, "node-1", e["hostname"])
+	require.Equal(t, "critical", e["status"])
+	require.Equal(t, "This is a test from falcosidekick", e["message"])
+	require.Equal(t, "Test rule", e["rule"])
+	require.Equal(t, "falcosidekick", e["proc.name"])
+	require.Nil(t, e["proc.tty"])
+
+	// the batches are split to stay under the maximum size of the requests
+	requests, sizes = nil, nil
+	config.DatadogLogs.BatchSize = 10
+	client.EnableDatadogLogsBatches()
+	f.Output = strings.Repeat("a", 2*1024*1024)
+	results = buffer(3)
+	client.DatadogLogsFlush()
+	for _, i := range results {
+		require.Nil(t, <-i)
+	}
+	require.Len(t, requests, 2)
+	require.Len(t, requests[0], 2)
+	require.Len(t, requests[1], 1)
+	for _, i := range sizes {
+		require.LessOrEqual(t, i, DatadogLogsMaxPayloadSize)
+	}
+}
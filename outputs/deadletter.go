@@ -0,0 +1,120 @@
+package outputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// deadLetter is a line of the dead-letter file, a malformed input (source and body) or an event an output failed to send
+type deadLetter struct {
+	Time   time.Time           `json:"time"`
+	Source string              `json:"source,omitempty"`
+	Output string              `json:"output,omitempty"`
+	Error  string              `json:"error"`
+	Body   string              `json:"body,omitempty"`
+	Event  *types.FalcoPayload `json:"event,omitempty"`
+}
+
+// DeadLetterFile appends the dead letters to a file, one JSON object per line. Once the file reaches MaxSize bytes it's
+// rotated, the rotated files are suffixed by .1 (the newest) to .<MaxFiles>, the older ones are removed.
+type DeadLetterFile struct {
+	path     string
+	maxSize  int64 // 0 disables the rotation
+	maxFiles int
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+}
+
+// deadLetterFile receives the events the outputs failed to send, if it's set
+var deadLetterFile *DeadLetterFile
+
+// SetDeadLetterFile makes the outputs append to d the events they failed to send, nil disables it
+func SetDeadLetterFile(d *DeadLetterFile) {
+	deadLetterFile = d
+}
+
+// NewDeadLetterFile returns the DeadLetterFile configured, the file is created if needed, it returns nil if there's none
+func NewDeadLetterFile(config *types.Configuration) (*DeadLetterFile, error) {
+	if config.DeadLetter.File == "" {
+		return nil, nil
+	}
+	d := &DeadLetterFile{path: filepath.Clean(config.DeadLetter.File), maxSize: config.DeadLetter.MaxSize, maxFiles: config.DeadLetter.MaxFiles}
+	if err := d.open(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *DeadLetterFile) open() error {
+	f, err := os.OpenFile(d.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	d.file, d.size = f, info.Size()
+	return nil
+}
+
+// write appends a dead letter, the file is rotated before if the line would make it exceed MaxSize
+func (d *DeadLetterFile) write(l deadLetter) error {
+	line, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.maxSize > 0 && d.size > 0 && d.size+int64(len(line)) > d.maxSize {
+		if err := d.rotate(); err != nil {
+			return fmt.Errorf("Can't rotate : %v", err)
+		}
+	}
+	n, err := d.file.Write(line)
+	d.size += int64(n)
+	return err
+}
+
+// rotate renames the file to .1, after shifting the rotated files, and opens a new file, d.mu must be held
+func (d *DeadLetterFile) rotate() error {
+	if err := d.file.Close(); err != nil {
+		return err
+	}
+	if d.maxFiles < 1 {
+		if err := os.Remove(d.path); err != nil {
+			return err
+		}
+		return d.open()
+	}
+	for i := d.maxFiles - 1; i > 0; i-- {
+		if err := os.Rename(fmt.Sprintf("%v.%v", d.path, i), fmt.Sprintf("%v.%v", d.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(d.path, d.path+".1"); err != nil {
+		return err
+	}
+	return d.open()
+}
+
+// deadLetter appends the event the output failed to send, with the error, to the dead-letter file if it's set
+func (c *Client) deadLetter(falcopayload types.FalcoPayload, err error) {
+	if deadLetterFile == nil {
+		return
+	}
+	if err := deadLetterFile.write(deadLetter{Time: time.Now().UTC(), Output: c.OutputType, Error: err.Error(), Event: &falcopayload}); err != nil {
+		log.Printf("[ERROR] : %v - Dead letter - %v\n", c.OutputType, err)
+	}
+}
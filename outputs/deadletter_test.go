@@ -0,0 +1,68 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestPostDeadLetter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "deadletter.ndjson")
+	d, err := NewDeadLetterFile(&types.Configuration{DeadLetter: types.DeadLetterConfig{File: file}})
+	require.Nil(t, err)
+	SetDeadLetterFile(d)
+	defer SetDeadLetterFile(nil)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	nc, err := NewClient("Webhook", ts.URL, false, false, &types.Configuration{}, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	require.Equal(t, ErrForbidden, nc.post(context.Background(), f, f))
+
+	content, err := ioutil.ReadFile(file)
+	require.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 1)
+	var l deadLetter
+	require.Nil(t, json.Unmarshal([]byte(lines[0]), &l))
+	require.Equal(t, "Webhook", l.Output)
+	require.Equal(t, ErrForbidden.Error(), l.Error)
+	require.Equal(t, "Test rule", l.Event.Rule)
+	require.Equal(t, "This is a test from falcosidekick", l.Event.Output)
+	require.Equal(t, "falcosidekick", l.Event.OutputFields["proc.name"])
+	require.False(t, l.Time.IsZero())
+}
+
+func TestDeadLetterFileRotation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "deadletter.ndjson")
+	d, err := NewDeadLetterFile(&types.Configuration{DeadLetter: types.DeadLetterConfig{File: file, MaxSize: 150, MaxFiles: 2}})
+	require.Nil(t, err)
+
+	// each line is about 100 bytes, the file is rotated at each line
+	for _, i := range []string{"first", "second", "third", "fourth"} {
+		require.Nil(t, d.write(deadLetter{Source: "requests", Error: "malformed", Body: strings.Repeat(i[:1], 40) + i}))
+	}
+	for i, j := range map[string]string{file: "fourth", file + ".1": "third", file + ".2": "second"} {
+		content, err := ioutil.ReadFile(i)
+		require.Nil(t, err)
+		require.Equal(t, 1, strings.Count(string(content), "\n"))
+		require.Contains(t, string(content), j)
+	}
+	_, err = os.Stat(file + ".3")
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,150 @@
+package outputs
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Fields of the events summarizing the duplicates of an event suppressed during a deduplication window
+const (
+	DeduplicationCountField string = "deduplication.count" // occurrences of the event during the window, the first one included
+	DeduplicationStartField string = "deduplication.start"
+	DeduplicationEndField   string = "deduplication.end"
+)
+
+type duplicatedEvent struct {
+	falcopayload types.FalcoPayload // first occurrence
+	start        time.Time
+	last         time.Time // last occurrence
+	count        int
+}
+
+// Deduplicator sends the first occurrence of an event and suppresses its duplicates, the events with the same
+// fingerprint (rule, hostname and the values of Fields), until the end of the window. The window slides, it ends once
+// no duplicate occurred during Window, and at most MaxAge after the first occurrence. If there were duplicates, an
+// event with the count of the occurrences is then emitted. The duplicates are counted in
+// falcosidekick_outputs_deduplicated by rule.
+type Deduplicator struct {
+	Window    time.Duration
+	MaxAge    time.Duration
+	Fields    []string
+	PromStats *types.PromStatistics
+	emit      func(types.FalcoPayload)
+	mu        sync.Mutex
+	events    map[string]*duplicatedEvent
+	now       func() time.Time
+	afterFunc func(time.Duration, func()) // time.AfterFunc
+}
+
+// NewDeduplicator returns a Deduplicator for the window and the fields configured, the summaries are emitted with emit
+func NewDeduplicator(config *types.Configuration, promStats *types.PromStatistics, emit func(types.FalcoPayload)) *Deduplicator {
+	maxAge := config.Deduplication.MaxAge
+	if maxAge <= 0 {
+		maxAge = 10 * config.Deduplication.Window
+	}
+	return &Deduplicator{
+		Window:    time.Duration(config.Deduplication.Window) * time.Second,
+		MaxAge:    time.Duration(maxAge) * time.Second,
+		Fields:    config.Deduplication.Fields,
+		PromStats: promStats,
+		emit:      emit,
+		events:    make(map[string]*duplicatedEvent),
+		now:       time.Now,
+		afterFunc: func(d time.Duration, f func()) { time.AfterFunc(d, f) },
+	}
+}
+
+// fingerprint returns the key of the duplicates of an event
+func (d *Deduplicator) fingerprint(falcopayload types.FalcoPayload) string {
+	keys := []string{falcopayload.Rule, falcopayload.Hostname}
+	for _, i := range d.Fields {
+		v, ok := falcopayload.OutputFields[i]
+		if !ok || v == nil {
+			keys = append(keys, "")
+			continue
+		}
+		keys = append(keys, fmt.Sprintf("%v", v))
+	}
+	return strings.Join(keys, "\x00")
+}
+
+// Suppress returns true if the event is a duplicate of an event sent during the window, which slides to it, otherwise
+// the event starts a new window
+func (d *Deduplicator) Suppress(falcopayload types.FalcoPayload) bool {
+	if d == nil {
+		return false
+	}
+	key := d.fingerprint(falcopayload)
+	now := d.now()
+	d.mu.Lock()
+	ended, ok := d.events[key]
+	if ok && now.Before(d.end(ended)) {
+		ended.count++
+		ended.last = now
+		d.mu.Unlock()
+		if d.PromStats != nil && d.PromStats.Deduplicated != nil {
+			d.PromStats.Deduplicated.With(map[string]string{"rule": falcopayload.Rule}).Inc()
+		}
+		return true
+	}
+	e := &duplicatedEvent{falcopayload: falcopayload, start: now, last: now, count: 1}
+	d.events[key] = e
+	d.mu.Unlock()
+	// the window ended before its expiration ran
+	if ok && ended.count > 1 {
+		d.emit(d.newSummary(ended))
+	}
+	d.afterFunc(d.Window, func() { d.expire(key, e) })
+	return false
+}
+
+// end returns the end of the window of an event, Window after its last occurrence and at most MaxAge after its first one
+func (d *Deduplicator) end(e *duplicatedEvent) time.Time {
+	end := e.last.Add(d.Window)
+	if max := e.start.Add(d.MaxAge); end.After(max) {
+		return max
+	}
+	return end
+}
+
+// expire ends the window of the event if it didn't slide, the summary is emitted if there were duplicates
+func (d *Deduplicator) expire(key string, e *duplicatedEvent) {
+	d.mu.Lock()
+	if d.events[key] != e {
+		// ended by the next occurrence
+		d.mu.Unlock()
+		return
+	}
+	if wait := d.end(e).Sub(d.now()); wait > 0 {
+		d.mu.Unlock()
+		d.afterFunc(wait, func() { d.expire(key, e) })
+		return
+	}
+	delete(d.events, key)
+	d.mu.Unlock()
+	if e.count > 1 {
+		d.emit(d.newSummary(e))
+	}
+}
+
+// newSummary returns the first occurrence of the event, with the count of the occurrences during the window
+func (d *Deduplicator) newSummary(e *duplicatedEvent) types.FalcoPayload {
+	now := d.now()
+	s := e.falcopayload
+	s.UUID = ""
+	s.RawPayload = nil
+	s.Time = now
+	s.OutputFields = copyOutputFields(e.falcopayload.OutputFields)
+	if s.OutputFields == nil {
+		s.OutputFields = make(map[string]interface{}, 3)
+	}
+	s.OutputFields[DeduplicationCountField] = e.count
+	s.OutputFields[DeduplicationStartField] = e.start.UTC().Format(time.RFC3339)
+	s.OutputFields[DeduplicationEndField] = now.UTC().Format(time.RFC3339)
+	s.Output = fmt.Sprintf("%v (%v occurrences from %v to %v)", s.Output, e.count, e.start.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+	return s
+}
@@ -0,0 +1,96 @@
+package outputs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestDeduplicator(t *testing.T) {
+	config := &types.Configuration{Deduplication: types.DeduplicationConfig{Window: 10, Fields: []string{"container.id"}}}
+	promStats := &types.PromStatistics{Deduplicated: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "deduplicated"}, []string{"rule"})}
+	var emitted []types.FalcoPayload
+	d := NewDeduplicator(config, promStats, func(falcopayload types.FalcoPayload) { emitted = append(emitted, falcopayload) })
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	d.now = func() time.Time { return now }
+	var windows []func()
+	var waits []time.Duration
+	d.afterFunc = func(w time.Duration, f func()) {
+		waits = append(waits, w)
+		windows = append(windows, f)
+	}
+
+	event := types.FalcoPayload{Rule: "Terminal shell in container", Output: "A shell was spawned", Priority: types.Notice, Hostname: "node-1"}
+	var sent int
+	for i := 0; i < 50; i++ {
+		now = start.Add(time.Duration(i) * 200 * time.Millisecond)
+		event.OutputFields = map[string]interface{}{"container.id": "abc", "proc.pid": i}
+		if !d.Suppress(event) {
+			sent++
+		}
+	}
+	require.Equal(t, 1, sent)
+	require.Equal(t, 49.0, testutil.ToFloat64(promStats.Deduplicated.With(map[string]string{"rule": event.Rule})))
+
+	// another container isn't a duplicate
+	other := types.FalcoPayload{Rule: event.Rule, Hostname: "node-1", OutputFields: map[string]interface{}{"container.id": "def"}}
+	require.False(t, d.Suppress(other))
+	require.Len(t, windows, 2)
+
+	// the windows slid to the last occurrences
+	now = start.Add(10 * time.Second)
+	windows[0]()
+	windows[1]()
+	require.Len(t, emitted, 0)
+	require.Equal(t, []time.Duration{10 * time.Second, 10 * time.Second, 9800 * time.Millisecond, 9800 * time.Millisecond}, waits)
+
+	// the summary is emitted at the end of the window, none for an event without duplicates
+	now = start.Add(19800 * time.Millisecond)
+	windows[2]()
+	windows[3]()
+	require.Len(t, emitted, 1)
+	summary := emitted[0]
+	require.Equal(t, event.Rule, summary.Rule)
+	require.Equal(t, types.PriorityType(types.Notice), summary.Priority)
+	require.Equal(t, 50, summary.OutputFields[DeduplicationCountField])
+	require.Equal(t, "2021-06-01T12:00:00Z", summary.OutputFields[DeduplicationStartField])
+	require.Equal(t, "2021-06-01T12:00:19Z", summary.OutputFields[DeduplicationEndField])
+	require.Equal(t, 0, summary.OutputFields["proc.pid"])
+	require.Equal(t, "A shell was spawned (50 occurrences from 2021-06-01T12:00:00Z to 2021-06-01T12:00:19Z)", summary.Output)
+
+	// the next occurrence starts a new window
+	require.False(t, d.Suppress(event))
+}
+
+func TestDeduplicatorMaxAge(t *testing.T) {
+	config := &types.Configuration{Deduplication: types.DeduplicationConfig{Window: 10, MaxAge: 20}}
+	var emitted []types.FalcoPayload
+	d := NewDeduplicator(config, nil, func(falcopayload types.FalcoPayload) { emitted = append(emitted, falcopayload) })
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	d.now = func() time.Time { return now }
+	var windows []func()
+	d.afterFunc = func(w time.Duration, f func()) { windows = append(windows, f) }
+
+	// the occurrences straddling the end of the window started by the first one are duplicates, at most for MaxAge
+	event := types.FalcoPayload{Rule: "Terminal shell in container", Hostname: "node-1"}
+	var suppressed []bool
+	for _, i := range []int{0, 8, 16, 24} {
+		now = start.Add(time.Duration(i) * time.Second)
+		suppressed = append(suppressed, d.Suppress(event))
+	}
+	require.Equal(t, []bool{false, true, true, false}, suppressed)
+
+	// the window is ended by the occurrence after the max age, before its expiration ran
+	require.Len(t, emitted, 1)
+	require.Equal(t, 3, emitted[0].OutputFields[DeduplicationCountField])
+	windows[0]()
+	require.Len(t, emitted, 1)
+	require.Len(t, windows, 2)
+}
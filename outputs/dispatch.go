@@ -0,0 +1,248 @@
+package outputs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Statuses of the outputs not called for an event
+const (
+	Skipped  string = "skipped"  // an output they depend on failed
+	Deferred string = "deferred" // the event is kept for the digest of their quiet hours
+	Filtered string = "filtered" // the priority of the event is below their minimum priority
+)
+
+// ErrDependencyFailed is the result of an output skipped because an output it depends on failed or wasn't called
+var ErrDependencyFailed = errors.New("Dependency failed")
+
+// Dispatcher sends the events to the outputs concurrently, except the outputs depending on other ones,
+// which are called only once all the outputs they depend on succeeded
+type Dispatcher struct {
+	Dependencies map[string][]string // output: outputs it depends on, names are lowercased without spaces
+	QuietHours   *QuietHours
+	Suppressor   *Suppressor
+	Sampler      *Sampler
+	Cooldown     *Cooldown
+	Flattener    *Flattening
+	Deadline     time.Duration // 0 (disabled) or time budget of an event for all the outputs
+	Drainer      *Drainer
+	Queues       map[string]*OutputQueue // output: queue its events are sent from, names are lowercased without spaces
+	Flushers     []Flusher               // flushed at shutdown
+	PromStats    *types.PromStatistics
+}
+
+type dispatchedOutput struct {
+	name   string
+	output Output
+	done   chan struct{}
+	err    error
+}
+
+// Dispatch is the sending of an event to the outputs selected for it
+type Dispatch struct {
+	dispatcher   *Dispatcher
+	falcopayload types.FalcoPayload
+	ctx          context.Context // expires with the Deadline of the Dispatcher
+	cancel       context.CancelFunc
+	outputs      map[string]*dispatchedOutput
+	order        []*dispatchedOutput
+}
+
+// NewDispatcher returns a Dispatcher for the dependencies configured (output: comma separated outputs it depends on),
+// all the outputs must be enabled and the dependencies can't be circular
+func NewDispatcher(dependencies map[string]string, enabledOutputs []string, drainer *Drainer, promStats *types.PromStatistics) (*Dispatcher, error) {
+	enabled := enabledOutputSet(enabledOutputs)
+	d := &Dispatcher{Dependencies: make(map[string][]string), Drainer: drainer, PromStats: promStats}
+	for output, deps := range dependencies {
+		output = dispatchName(output)
+		if !enabled[output] {
+			return nil, fmt.Errorf("Output '%v' with dependencies isn't enabled", output)
+		}
+		for _, i := range strings.Split(deps, ",") {
+			i = dispatchName(i)
+			if i == "" {
+				continue
+			}
+			if !enabled[i] {
+				return nil, fmt.Errorf("Output '%v' depends on '%v' which isn't enabled", output, i)
+			}
+			d.Dependencies[output] = append(d.Dependencies[output], i)
+		}
+	}
+	for i := range d.Dependencies {
+		if err := d.checkCycle(i, map[string]bool{}); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (d *Dispatcher) checkCycle(output string, path map[string]bool) error {
+	if path[output] {
+		return fmt.Errorf("Circular dependency of output '%v'", output)
+	}
+	path[output] = true
+	for _, i := range d.Dependencies[output] {
+		if err := d.checkCycle(i, path); err != nil {
+			return err
+		}
+	}
+	delete(path, output)
+	return nil
+}
+
+// NewDispatch returns a Dispatch for an event, with a Deadline the context the outputs are called with expires with it
+func (d *Dispatcher) NewDispatch(falcopayload types.FalcoPayload) *Dispatch {
+	x := &Dispatch{dispatcher: d, falcopayload: falcopayload, ctx: context.Background(), outputs: make(map[string]*dispatchedOutput)}
+	if d.Deadline > 0 {
+		x.ctx, x.cancel = context.WithTimeout(x.ctx, d.Deadline)
+	}
+	return x
+}
+
+// HasMinimumPriority returns true if the priority of the event is at least the minimum priority of the output, an empty
+// minimum priority or an unknown one (the events with an unknown priority have the lowest one) selects all the events.
+// The events below it are counted as filtered.
+func (x *Dispatch) HasMinimumPriority(name, minimumPriority string) bool {
+	if x.falcopayload.Priority >= types.Priority(minimumPriority) {
+		return true
+	}
+	x.dispatcher.countStatus(name, Filtered)
+	return false
+}
+
+// Add selects an output for the event, the output isn't selected if the event is suppressed or sampled out for it,
+// if it's in its quiet hours or if an identical event has been sent successfully to it during its cooldown, the output fields
+// of the event are flattened for the outputs configured
+func (x *Dispatch) Add(name string, output Output) {
+	if x.dispatcher.Suppressor.Suppress(name, x.falcopayload) {
+		x.dispatcher.countStatus(name, Suppressed)
+		return
+	}
+	if !x.dispatcher.Sampler.KeepFor(name, x.falcopayload) {
+		x.dispatcher.countStatus(name, Sampled)
+		return
+	}
+	if x.dispatcher.QuietHours.Defer(name, x.falcopayload) {
+		x.dispatcher.countStatus(name, Deferred)
+		return
+	}
+	if x.dispatcher.Cooldown.Suppress(name, x.falcopayload) {
+		x.dispatcher.countStatus(name, Suppressed)
+		return
+	}
+	output = x.dispatcher.Sampler.Output(name, output)
+	output = x.dispatcher.Flattener.Output(name, output)
+	output = x.dispatcher.Cooldown.Output(name, output)
+	o := &dispatchedOutput{name: name, output: output, done: make(chan struct{})}
+	x.outputs[dispatchName(name)] = o
+	x.order = append(x.order, o)
+}
+
+// Run calls the outputs selected, an output with dependencies is skipped if one of them isn't selected or fails.
+// The events of the outputs with a queue are queued, once their dependencies succeeded, from their own goroutine for
+// the queues which block when they're full, so a full queue doesn't delay the other outputs.
+func (x *Dispatch) Run() {
+	for _, o := range x.order {
+		o := o
+		deps := x.dispatcher.Dependencies[dispatchName(o.name)]
+		q := x.dispatcher.Queues[dispatchName(o.name)]
+		if q != nil && len(deps) == 0 && q.policy != QueueBlock {
+			q.push(x.ctx, o, x.falcopayload)
+			continue
+		}
+		x.dispatcher.Drainer.Go(func() {
+			for _, i := range deps {
+				dep, ok := x.outputs[i]
+				if ok {
+					<-dep.done
+				}
+				if !ok || dep.err != nil {
+					o.err = ErrDependencyFailed
+					x.dispatcher.countStatus(o.name, Skipped)
+					log.Printf("[WARN]  : %v - Event skipped, output '%v' it depends on didn't succeed\n", o.name, i)
+					close(o.done)
+					return
+				}
+			}
+			if q != nil {
+				q.push(x.ctx, o, x.falcopayload)
+				return
+			}
+			o.err = o.output.Send(x.ctx, x.falcopayload)
+			close(o.done)
+		})
+	}
+	if x.cancel != nil {
+		go func() {
+			x.Wait()
+			x.cancel()
+		}()
+	}
+}
+
+// Wait returns once all the outputs selected have been called, it returns immediately for a nil Dispatch
+func (x *Dispatch) Wait() {
+	if x == nil {
+		return
+	}
+	for _, o := range x.order {
+		<-o.done
+	}
+}
+
+// Results returns the error of each output selected by its name, nil if it succeeded, once Wait returned, nil for a
+// nil Dispatch
+func (x *Dispatch) Results() map[string]error {
+	if x == nil {
+		return nil
+	}
+	results := make(map[string]error, len(x.order))
+	for _, o := range x.order {
+		results[o.name] = o.err
+	}
+	return results
+}
+
+// Err returns an error naming the outputs which failed, sorted by name, once Wait returned, nil if all of them
+// succeeded or for a nil Dispatch
+func (x *Dispatch) Err() error {
+	var failed []string
+	for name, err := range x.Results() {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%v: %v", name, err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.Strings(failed)
+	return fmt.Errorf("Outputs failed - %v", strings.Join(failed, ", "))
+}
+
+func (d *Dispatcher) countStatus(output, status string) {
+	if d.PromStats != nil && d.PromStats.Outputs != nil {
+		d.PromStats.Outputs.With(map[string]string{"destination": dispatchName(output), "status": status}).Inc()
+	}
+}
+
+// dispatchName returns the name of an output lowercased and without spaces, "Google Chat" is "googlechat"
+func dispatchName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", ""))
+}
+
+// enabledOutputSet returns the set of the dispatch names of the enabled outputs
+func enabledOutputSet(enabledOutputs []string) map[string]bool {
+	enabled := make(map[string]bool, len(enabledOutputs))
+	for _, i := range enabledOutputs {
+		enabled[dispatchName(i)] = true
+	}
+	return enabled
+}
@@ -0,0 +1,100 @@
+package outputs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestDispatchDependencies(t *testing.T) {
+	promStats := newTestPromStats()
+	d, err := NewDispatcher(map[string]string{"pagerduty": "AWSS3"}, []string{"AWSS3", "Pagerduty", "Slack"}, new(Drainer), promStats)
+	require.Nil(t, err)
+
+	for _, s3Err := range []error{nil, errors.New("upload failed")} {
+		var mu sync.Mutex
+		var calls []string
+		call := func(name string, err error) OutputFunc {
+			return func(context.Context, types.FalcoPayload) error {
+				mu.Lock()
+				calls = append(calls, name)
+				mu.Unlock()
+				return err
+			}
+		}
+
+		x := d.NewDispatch(types.FalcoPayload{})
+		x.Add("Pagerduty", call("Pagerduty", nil))
+		x.Add("AWSS3", OutputFunc(func(context.Context, types.FalcoPayload) error {
+			time.Sleep(50 * time.Millisecond)
+			return call("AWSS3", s3Err)(context.Background(), types.FalcoPayload{})
+		}))
+		x.Add("Slack", call("Slack", nil))
+		x.Run()
+		require.Eventually(t, func() bool { return atomic.LoadInt64(&d.Drainer.inflight) == 0 }, time.Second, 10*time.Millisecond)
+
+		if s3Err == nil {
+			require.ElementsMatch(t, []string{"Slack", "AWSS3", "Pagerduty"}, calls)
+			require.Equal(t, "AWSS3", calls[1])
+			require.Equal(t, float64(0), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "pagerduty", "status": Skipped})))
+			require.Nil(t, x.Err())
+		} else {
+			require.ElementsMatch(t, []string{"Slack", "AWSS3"}, calls)
+			require.Equal(t, float64(1), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "pagerduty", "status": Skipped})))
+			require.Equal(t, "Outputs failed - AWSS3: upload failed, Pagerduty: Dependency failed", x.Err().Error())
+		}
+	}
+
+	// the output it depends on isn't selected for the event
+	x := d.NewDispatch(types.FalcoPayload{})
+	x.Add("Pagerduty", OutputFunc(func(context.Context, types.FalcoPayload) error { t.Error("Pagerduty shouldn't be called"); return nil }))
+	x.Run()
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "pagerduty", "status": Skipped})) == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestNewDispatcherErrors(t *testing.T) {
+	_, err := NewDispatcher(map[string]string{"pagerduty": "awss3"}, []string{"Pagerduty"}, new(Drainer), nil)
+	require.NotNil(t, err)
+
+	_, err = NewDispatcher(map[string]string{"pagerduty": "awss3", "awss3": "slack", "slack": "pagerduty"}, []string{"AWSS3", "Pagerduty", "Slack"}, new(Drainer), nil)
+	require.NotNil(t, err)
+
+	d, err := NewDispatcher(map[string]string{"googlechat": "awss3, elasticsearch"}, []string{"AWSS3", "Elasticsearch", "Google Chat"}, new(Drainer), nil)
+	require.Nil(t, err)
+	require.Equal(t, []string{"awss3", "elasticsearch"}, d.Dependencies["googlechat"])
+}
+
+func TestDispatchMinimumPriority(t *testing.T) {
+	promStats := newTestPromStats()
+	d, err := NewDispatcher(nil, []string{"Pagerduty"}, new(Drainer), promStats)
+	require.Nil(t, err)
+
+	var sent []string
+	for _, i := range []types.PriorityType{types.Debug, types.Informational, types.Notice, types.Warning, types.Error, types.Critical, types.Alert, types.Emergency, types.Default} {
+		x := d.NewDispatch(types.FalcoPayload{Priority: i})
+		if x.HasMinimumPriority("Pagerduty", "warning") {
+			x.Add("Pagerduty", OutputFunc(func(ctx context.Context, falcopayload types.FalcoPayload) error {
+				sent = append(sent, falcopayload.Priority.String())
+				return nil
+			}))
+		}
+		for _, j := range x.order {
+			j.output.Send(x.ctx, x.falcopayload)
+		}
+	}
+	require.Equal(t, []string{"Warning", "Error", "Critical", "Alert", "Emergency"}, sent)
+	require.Equal(t, float64(4), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "pagerduty", "status": Filtered})))
+
+	// without minimum priority, the events with an unknown priority are sent too
+	require.True(t, d.NewDispatch(types.FalcoPayload{Priority: types.Default}).HasMinimumPriority("Pagerduty", ""))
+}
@@ -0,0 +1,90 @@
+package outputs
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Drain statuses
+const (
+	Running  string = "running"
+	Draining string = "draining"
+	Drained  string = "drained"
+)
+
+// Drainer tracks the events being sent to outputs, so the instance can stop accepting new events
+// and report when all the pending ones have been sent.
+type Drainer struct {
+	draining int32
+	inflight int64
+}
+
+// Go runs f in a goroutine and tracks it until it returns
+func (d *Drainer) Go(f func()) {
+	atomic.AddInt64(&d.inflight, 1)
+	go func() {
+		defer atomic.AddInt64(&d.inflight, -1)
+		f()
+	}()
+}
+
+// Drain puts the instance in draining mode, it can't be undone
+func (d *Drainer) Drain() {
+	if atomic.CompareAndSwapInt32(&d.draining, 0, 1) {
+		log.Printf("[INFO]  : Draining - new events are refused\n")
+	}
+}
+
+// Status returns Running, Draining or Drained (draining and nothing pending)
+func (d *Drainer) Status() string {
+	if atomic.LoadInt32(&d.draining) == 0 {
+		return Running
+	}
+	if atomic.LoadInt64(&d.inflight) > 0 {
+		return Draining
+	}
+	return Drained
+}
+
+// Refuse replies 503 and returns true if the instance is draining
+func (d *Drainer) Refuse(w http.ResponseWriter) bool {
+	if atomic.LoadInt32(&d.draining) == 0 {
+		return false
+	}
+	http.Error(w, "Draining, events are refused", http.StatusServiceUnavailable)
+	return true
+}
+
+// Handler returns the handler of the drain endpoint, POST starts draining and GET returns the status.
+// Requests must have the token as bearer in their Authorization header.
+func (d *Drainer) Handler(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			d.Drain()
+		case http.MethodGet:
+		default:
+			http.Error(w, "Please send with get or post http method", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		// #nosec G104 nothing to be done if the following fails
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": d.Status(), "inflight": atomic.LoadInt64(&d.inflight)})
+	}
+}
+
+// authorized returns true if the request has the token as bearer in its Authorization header
+func authorized(r *http.Request, token string) bool {
+	t := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1
+}
@@ -0,0 +1,63 @@
+package outputs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainer(t *testing.T) {
+	d := new(Drainer)
+	ingest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.Refuse(w) {
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ingest.Close()
+	drain := httptest.NewServer(d.Handler("secret"))
+	defer drain.Close()
+
+	resp, err := http.Post(ingest.URL, "application/json", nil)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	release := make(chan struct{})
+	d.Go(func() { <-release })
+
+	status := func(method, token string) (int, string) {
+		req, _ := http.NewRequest(method, drain.URL, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		defer resp.Body.Close()
+		var s struct {
+			Status string `json:"status"`
+		}
+		json.NewDecoder(resp.Body).Decode(&s)
+		return resp.StatusCode, s.Status
+	}
+
+	code, _ := status(http.MethodPost, "wrong")
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, s := status(http.MethodGet, "secret")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, Running, s)
+
+	_, s = status(http.MethodPost, "secret")
+	require.Equal(t, Draining, s)
+
+	resp, err = http.Post(ingest.URL, "application/json", nil)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		_, s := status(http.MethodGet, "secret")
+		return s == Drained
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,65 @@
+package outputs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// dryRunHeaders are the headers logged as is in dry run, the values of the other ones are replaced by RedactedValue
+var dryRunHeaders = map[string]bool{"Content-Type": true, "User-Agent": true, SchemaVersionHeader: true}
+
+// isDryRun returns true if the output is in the outputs of the dry run
+func isDryRun(config *types.Configuration, outputType string) bool {
+	for _, i := range config.DryRun.Outputs {
+		if dispatchName(i) == dispatchName(outputType) {
+			return true
+		}
+	}
+	return false
+}
+
+// dryRun logs and counts the request instead of sending it, it's answered with a 200,
+// only the scheme and the host of the URL are logged as the path may contain a secret (ex: Slack webhook)
+func (c *Client) dryRun(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.GetBody != nil {
+		b, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		body, _ = ioutil.ReadAll(b)
+		body = decompressedBody(req, body)
+	}
+	var headers []string
+	for i, j := range req.Header {
+		if !dryRunHeaders[i] {
+			j = []string{RedactedValue}
+		}
+		headers = append(headers, i+": "+strings.Join(j, ", "))
+	}
+	sort.Strings(headers)
+
+	log.Printf("[INFO]  : %v - Dry run, request not sent : %v %v://%v [%v] %s\n", c.OutputType, req.Method, req.URL.Scheme, req.URL.Host, strings.Join(headers, "; "), bytes.TrimSpace(body))
+	go c.CountMetric(Outputs, 1, []string{"output:" + strings.ToLower(c.OutputType), "status:" + DryRun})
+	if c.PromStats != nil && c.PromStats.Outputs != nil {
+		c.PromStats.Outputs.With(map[string]string{"destination": strings.ToLower(c.OutputType), "status": DryRun}).Inc()
+	}
+	return newDryRunResponse(req), nil
+}
+
+// newDryRunResponse returns the 200 answering a request not sent
+func newDryRunResponse(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(new(bytes.Buffer)),
+		Request:    req,
+	}
+}
@@ -0,0 +1,48 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestWebhookPostDryRun(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.DryRun.Outputs = []string{"webhook"}
+	config.Webhook.CustomHeaders = map[string]string{"Authorization": "secret"}
+	promStats := newTestPromStats()
+	client, err := NewClient("Webhook", ts.URL+"/falco", false, false, config, &types.Statistics{Webhook: new(expvar.Map)}, promStats, nil, nil)
+	require.Nil(t, err)
+	require.True(t, client.DryRun)
+
+	logs := new(bytes.Buffer)
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	require.Nil(t, client.WebhookPost(context.Background(), f))
+
+	require.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	require.Contains(t, logs.String(), "Webhook - Dry run, request not sent : POST "+ts.URL+" [Authorization: ***; Content-Type: application/json; charset=utf-8; User-Agent: Falcosidekick] "+`{"output":"This is a test from falcosidekick"`)
+	require.NotContains(t, logs.String(), "secret")
+	require.Equal(t, float64(1), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "webhook", "status": DryRun})))
+}
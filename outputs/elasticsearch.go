@@ -1,6 +1,8 @@
 package outputs
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/url"
 	"time"
@@ -9,41 +11,64 @@ import (
 )
 
 // ElasticsearchPost posts event to Elasticsearch
-func (c *Client) ElasticsearchPost(falcopayload types.FalcoPayload) {
+func (c *Client) ElasticsearchPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Elasticsearch.Add(Total, 1)
 
-	current := time.Now()
-	var eURL string
-	switch c.Config.Elasticsearch.Suffix {
-	case "none":
-		eURL = c.Config.Elasticsearch.HostPort + "/" + c.Config.Elasticsearch.Index + "/" + c.Config.Elasticsearch.Type
-	case "monthly":
-		eURL = c.Config.Elasticsearch.HostPort + "/" + c.Config.Elasticsearch.Index + "-" + current.Format("2006.01") + "/" + c.Config.Elasticsearch.Type
-	case "annually":
-		eURL = c.Config.Elasticsearch.HostPort + "/" + c.Config.Elasticsearch.Index + "-" + current.Format("2006") + "/" + c.Config.Elasticsearch.Type
-	default:
-		eURL = c.Config.Elasticsearch.HostPort + "/" + c.Config.Elasticsearch.Index + "-" + current.Format("2006.01.02") + "/" + c.Config.Elasticsearch.Type
+	falcopayload, f := runPipeline(falcopayload, c.config().Elasticsearch.Pipeline, c.config().Elasticsearch.FieldsMapping, c.config().Elasticsearch.RedactFields, c.Redaction, c.config().Elasticsearch.RequiredFields)
+	if f != "" {
+		if c.config().Elasticsearch.RequiredFieldsAction != Forward {
+			go c.CountMetric(Outputs, 1, []string{"output:elasticsearch", "status:dropped"})
+			c.Stats.Elasticsearch.Add(Dropped, 1)
+			c.PromStats.Outputs.With(map[string]string{"destination": "elasticsearch", "status": Dropped}).Inc()
+			log.Printf("[WARN]  : ElasticSearch - Event dropped, required field '%v' is missing\n", f)
+			if c.config().Elasticsearch.RequiredFieldsAction == DeadLetter {
+				c.deadLetter(falcopayload, fmt.Errorf("Required field '%v' is missing", f))
+			}
+			return ErrEventDropped
+		}
+		log.Printf("[WARN]  : ElasticSearch - Required field '%v' is missing\n", f)
 	}
 
-	endpointURL, err := url.Parse(eURL)
+	index := c.elasticsearchIndex(time.Now())
+	if c.elasticsearchBulk != nil {
+		return c.addElasticsearchBulk(ctx, falcopayload, index)
+	}
+
+	endpointURL, err := url.Parse(c.config().Elasticsearch.HostPort + "/" + index + "/" + c.config().Elasticsearch.Type)
 	if err != nil {
 		c.setElasticSearchErrorMetrics()
 		log.Printf("[ERROR] : %v - %v\n", c.OutputType, err.Error())
-		return
+		return err
 	}
 
 	c.EndpointURL = endpointURL
-	err = c.Post(falcopayload)
+	err = c.post(ctx, c.formatFalcoPayload(falcopayload), falcopayload)
 	if err != nil {
 		c.setElasticSearchErrorMetrics()
-		log.Printf("[ERROR] : ElasticSearch - %v\n", err)
-		return
+		logEventError("ElasticSearch", falcopayload, err)
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:elasticsearch", "status:ok"})
 	c.Stats.Elasticsearch.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "elasticsearch", "status": OK}).Inc()
+
+	return nil
+}
+
+// elasticsearchIndex returns the index of the events at t, with the suffix configured
+func (c *Client) elasticsearchIndex(t time.Time) string {
+	switch c.config().Elasticsearch.Suffix {
+	case "none":
+		return c.config().Elasticsearch.Index
+	case "monthly":
+		return c.config().Elasticsearch.Index + "-" + t.Format("2006.01")
+	case "annually":
+		return c.config().Elasticsearch.Index + "-" + t.Format("2006")
+	default:
+		return c.config().Elasticsearch.Index + "-" + t.Format("2006.01.02")
+	}
 }
 
 // setElasticSearchErrorMetrics set the error stats
@@ -0,0 +1,237 @@
+package outputs
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func newTestElasticsearchBulkClient(t *testing.T, url string) *Client {
+	config := &types.Configuration{}
+	config.Elasticsearch.HostPort = url
+	config.Elasticsearch.Index = "falco"
+	config.Elasticsearch.Type = "event"
+	config.Elasticsearch.Suffix = "none"
+	config.Elasticsearch.BatchSize = 500
+	config.Retry.MaxAttempts = 2
+	client, err := NewClient("Elasticsearch", url+"/falco/event", false, false, config, &types.Statistics{Elasticsearch: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, client.EnableElasticsearchBulk())
+	return client
+}
+
+func TestElasticsearchBulk(t *testing.T) {
+	var mu sync.Mutex
+	var bulks []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/_bulk", r.URL.Path)
+		require.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		body, _ := ioutil.ReadAll(r.Body)
+		lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+		require.Equal(t, `{"index":{"_index":"falco","_type":"event"}}`, lines[0])
+		var f types.FalcoPayload
+		require.Nil(t, json.Unmarshal([]byte(lines[1]), &f))
+		require.Equal(t, "Test rule", f.Rule)
+		mu.Lock()
+		bulks = append(bulks, len(lines)/2)
+		mu.Unlock()
+		items := strings.TrimSuffix(strings.Repeat(`{"index":{"status":201}},`, len(lines)/2), ",")
+		fmt.Fprintf(w, `{"took":3,"errors":false,"items":[%v]}`, items)
+	}))
+	defer ts.Close()
+
+	client := newTestElasticsearchBulkClient(t, ts.URL)
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	var results []<-chan error
+	for i := 0; i < 1200; i++ {
+		result, err := client.bufferElasticsearchBulk(f, "falco")
+		require.Nil(t, err)
+		results = append(results, result)
+	}
+	// the full batches are sent by the goroutine of the buffer, the last events wait for the next flush
+	for _, i := range results[:1000] {
+		require.Nil(t, <-i)
+	}
+	require.Equal(t, []int{500, 500}, bulks)
+	client.ElasticsearchFlush()
+	for _, i := range results[1000:] {
+		require.Nil(t, <-i)
+	}
+	require.Equal(t, []int{500, 500, 200}, bulks)
+	require.Equal(t, "1200", client.Stats.Elasticsearch.Get(OK).String())
+}
+
+func TestElasticsearchBulkItemErrors(t *testing.T) {
+	var requests [][]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, strings.Split(strings.TrimSuffix(string(body), "\n"), "\n"))
+		if len(requests) == 1 {
+			fmt.Fprint(w, `{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"rejected"}}},{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"errors":false,"items":[{"index":{"status":201}}]}`)
+	}))
+	defer ts.Close()
+
+	d, err := NewDeadLetterFile(&types.Configuration{DeadLetter: types.DeadLetterConfig{File: filepath.Join(t.TempDir(), "deadletter.ndjson")}})
+	require.Nil(t, err)
+	SetDeadLetterFile(d)
+	defer SetDeadLetterFile(nil)
+
+	client := newTestElasticsearchBulkClient(t, ts.URL)
+	var results []<-chan error
+	for _, i := range []string{"first", "second", "third"} {
+		result, err := client.bufferElasticsearchBulk(types.FalcoPayload{Rule: i, Priority: types.Warning}, "falco")
+		require.Nil(t, err)
+		results = append(results, result)
+	}
+	// the item rejected is sent again after a backoff, the one invalid is dead-lettered
+	client.ElasticsearchFlush()
+	require.Nil(t, <-results[0])
+	require.Nil(t, <-results[1])
+	require.EqualError(t, <-results[2], "mapper_parsing_exception : failed to parse (400)")
+	require.Len(t, requests, 2)
+	require.Len(t, requests[1], 2)
+	require.Contains(t, requests[1][1], `"rule":"second"`)
+	require.Equal(t, "2", client.Stats.Elasticsearch.Get(OK).String())
+	require.Equal(t, "1", client.Stats.Elasticsearch.Get(Error).String())
+
+	b, err := ioutil.ReadFile(d.path)
+	require.Nil(t, err)
+	require.Equal(t, 1, bytes.Count(b, []byte("\n")))
+	var l deadLetter
+	require.Nil(t, json.Unmarshal(b, &l))
+	require.Equal(t, "third", l.Event.Rule)
+	require.Equal(t, "mapper_parsing_exception : failed to parse (400)", l.Error)
+}
+
+func TestElasticsearchBulkBackpressure(t *testing.T) {
+	var bulks []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		n := strings.Count(string(body), "\n") / 2
+		bulks = append(bulks, n)
+		status := 201
+		if len(bulks) <= 3 {
+			status = 429
+		}
+		items := strings.TrimSuffix(strings.Repeat(fmt.Sprintf(`{"index":{"status":%v}},`, status), n), ",")
+		fmt.Fprintf(w, `{"errors":%v,"items":[%v]}`, status != 201, items)
+	}))
+	defer ts.Close()
+
+	client := newTestElasticsearchBulkClient(t, ts.URL)
+	client.Config.Elasticsearch.BatchSize = 8
+	client.RetryMaxAttempts = 5
+	require.Nil(t, client.EnableElasticsearchBulk())
+	var backoffs []int
+	client.elasticsearchBulk.backoff = func(attempt int) time.Duration {
+		backoffs = append(backoffs, attempt)
+		return 0
+	}
+
+	var results []<-chan error
+	for i := 0; i < 8; i++ {
+		result, err := client.bufferElasticsearchBulk(types.FalcoPayload{Rule: "Test rule"}, "falco")
+		require.Nil(t, err)
+		results = append(results, result)
+	}
+	client.ElasticsearchFlush()
+	for _, i := range results {
+		require.Nil(t, <-i)
+	}
+	// each batch throttled halves the size of the next ones and increases the backoff, each batch accepted increases
+	// the size by one event
+	require.Equal(t, []int{8, 4, 2, 1, 2, 3, 2}, bulks)
+	require.Equal(t, []int{0, 1, 2}, backoffs)
+	require.Equal(t, 5, client.elasticsearchBulk.batchSize())
+	require.Equal(t, "8", client.Stats.Elasticsearch.Get(OK).String())
+}
+
+func TestElasticsearchBulkCompactFields(t *testing.T) {
+	var lines []string
+	headerStatus := 201
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lines = strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+		// the first document is the one of the shared fields
+		items := fmt.Sprintf(`{"index":{"status":%v}}`, headerStatus) + strings.Repeat(`,{"index":{"status":201}}`, len(lines)/2-1)
+		fmt.Fprintf(w, `{"errors":false,"items":[%v]}`, items)
+	}))
+	defer ts.Close()
+
+	client := newTestElasticsearchBulkClient(t, ts.URL)
+	client.Config.Elasticsearch.CompactFields = true
+	send := func() []error {
+		var results []<-chan error
+		for _, i := range []int{1, 2} {
+			f := types.FalcoPayload{Rule: "Test rule", OutputFields: map[string]interface{}{"container.image.digest": "sha256:abcd", "proc.tty": i}}
+			result, err := client.bufferElasticsearchBulk(f, "falco")
+			require.Nil(t, err)
+			results = append(results, result)
+		}
+		client.ElasticsearchFlush()
+		return []error{<-results[0], <-results[1]}
+	}
+
+	// the field identical across the batch is indexed once, the events reference it
+	require.Equal(t, []error{nil, nil}, send())
+	require.Len(t, lines, 6)
+	var action map[string]map[string]string
+	require.Nil(t, json.Unmarshal([]byte(lines[0]), &action))
+	id := action["index"]["_id"]
+	require.Len(t, id, 64)
+	require.JSONEq(t, `{"shared_output_fields":{"container.image.digest":"sha256:abcd"}}`, lines[1])
+	for i, j := range []string{lines[3], lines[5]} {
+		var e map[string]interface{}
+		require.Nil(t, json.Unmarshal([]byte(j), &e))
+		require.Equal(t, "Test rule", e["rule"])
+		require.Equal(t, id, e["shared_output_fields_id"])
+		require.Equal(t, map[string]interface{}{"proc.tty": float64(i + 1)}, e["output_fields"])
+	}
+
+	// the events fail with the shared fields they reference
+	headerStatus = 400
+	for _, i := range send() {
+		require.EqualError(t, i, "Bulk item failed (400)")
+	}
+}
+
+func TestElasticsearchBulkInvalidResponse(t *testing.T) {
+	for _, i := range []string{`{"errors":false`, `{"errors":false,"items":[{"index":{"status":201}}]}`} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, i)
+		}))
+
+		// a response which can't be read, or without an item per event, fails the whole batch
+		client := newTestElasticsearchBulkClient(t, ts.URL)
+		var results []<-chan error
+		for _, j := range []string{"first", "second"} {
+			result, err := client.bufferElasticsearchBulk(types.FalcoPayload{Rule: j}, "falco")
+			require.Nil(t, err)
+			results = append(results, result)
+		}
+		client.ElasticsearchFlush()
+		for _, j := range results {
+			require.NotNil(t, <-j)
+		}
+		require.Equal(t, "2", client.Stats.Elasticsearch.Get(Error).String())
+		require.Nil(t, client.Stats.Elasticsearch.Get(OK))
+		ts.Close()
+	}
+}
@@ -0,0 +1,252 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// elasticsearchBulkItem is an event buffered until it's sent to the _bulk API
+type elasticsearchBulkItem struct {
+	index    string
+	document []byte
+}
+
+// elasticsearchBulkRequest is the NDJSON body of a request to the _bulk API, the response is set once it's sent
+type elasticsearchBulkRequest struct {
+	body     []byte
+	response []byte
+}
+
+type elasticsearchBulkResponse struct {
+	Errors bool                                 `json:"errors"`
+	Items  []map[string]elasticsearchBulkResult `json:"items"`
+}
+
+// elasticsearchBulkResult is the result of an item, by its action
+type elasticsearchBulkResult struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+func (r elasticsearchBulkResult) succeeded() bool {
+	return r.Status > 0 && r.Status < http.StatusMultipleChoices
+}
+
+// EnableElasticsearchBulk makes the Elasticsearch output buffer the events and send them to the _bulk API, once
+// BatchSize events are buffered or every FlushInterval
+func (c *Client) EnableElasticsearchBulk() error {
+	endpointURL, err := url.Parse(c.config().Elasticsearch.HostPort + "/_bulk")
+	if err != nil {
+		return err
+	}
+	c.EndpointURL = endpointURL
+	c.elasticsearchBulk = newBatcher(c.config().Elasticsearch.BatchSize, 0, time.Duration(c.config().Elasticsearch.FlushInterval)*time.Second, c.sendElasticsearchBulk, c.exponentialBackoff)
+	return nil
+}
+
+// addElasticsearchBulk buffers an event, it returns once its batch has been sent
+func (c *Client) addElasticsearchBulk(ctx context.Context, falcopayload types.FalcoPayload, index string) error {
+	result, err := c.bufferElasticsearchBulk(falcopayload, index)
+	if err != nil {
+		return err
+	}
+	return waitBatch(ctx, result)
+}
+
+func (c *Client) bufferElasticsearchBulk(falcopayload types.FalcoPayload, index string) (<-chan error, error) {
+	document, err := json.Marshal(c.mapKeys(c.formatFalcoPayload(falcopayload)))
+	if err != nil {
+		c.setElasticSearchErrorMetrics()
+		logEventError("ElasticSearch", falcopayload, err)
+		return nil, err
+	}
+	return c.elasticsearchBulk.add(falcopayload, elasticsearchBulkItem{index: index, document: document}, len(document)), nil
+}
+
+// ElasticsearchFlush sends all the events buffered, it's called at shutdown
+func (c *Client) ElasticsearchFlush() {
+	if c.elasticsearchBulk != nil {
+		c.elasticsearchBulk.Flush()
+	}
+}
+
+// sendElasticsearchBulk sends a batch to the _bulk API and returns the items to send again, the results of the other
+// items are set and the ones failing are dead-lettered. A response which can't be read fails the whole batch.
+func (c *Client) sendElasticsearchBulk(items []*batchItem) []*batchItem {
+	body, lines := c.elasticsearchBulkBody(items)
+	r := &elasticsearchBulkRequest{body: body}
+	err := c.Post(r)
+	var resp elasticsearchBulkResponse
+	if err == nil {
+		if err = json.Unmarshal(r.response, &resp); err != nil {
+			err = fmt.Errorf("Invalid bulk response : %v", err)
+		} else if len(resp.Items) != len(lines) {
+			err = fmt.Errorf("Bulk response of %v items for %v documents", len(resp.Items), len(lines))
+		}
+	}
+	if err != nil {
+		c.adaptElasticsearchBulkSize(errors.Is(err, ErrTooManyRequest))
+		c.setElasticsearchBulkMetrics(Error, len(items))
+		log.Printf("[ERROR] : ElasticSearch - Bulk of %v events failed : %v\n", len(items), err)
+		for _, i := range items {
+			c.deadLetter(i.falcopayload, err)
+			i.done(err)
+		}
+		return nil
+	}
+
+	// the result of an event is the one of the shared fields it references if they failed
+	results := make([]elasticsearchBulkResult, len(items))
+	set := make([]bool, len(items))
+	for n, i := range resp.Items {
+		var result elasticsearchBulkResult
+		for _, j := range i {
+			result = j
+		}
+		for _, j := range lines[n] {
+			if !set[j] || results[j].succeeded() {
+				results[j], set[j] = result, true
+			}
+		}
+	}
+
+	var ok, failed int
+	var throttled bool
+	var requeued []*batchItem
+	for n, result := range results {
+		item := items[n]
+		if result.succeeded() {
+			ok++
+			item.done(nil)
+			continue
+		}
+		item.attempts++
+		throttled = throttled || result.Status == http.StatusTooManyRequests
+		if (result.Status == http.StatusTooManyRequests || result.Status >= http.StatusInternalServerError) && item.attempts < c.RetryMaxAttempts {
+			requeued = append(requeued, item)
+			continue
+		}
+		failed++
+		err := fmt.Errorf("Bulk item failed (%v)", result.Status)
+		if result.Error != nil {
+			err = fmt.Errorf("%v : %v (%v)", result.Error.Type, result.Error.Reason, result.Status)
+		}
+		logEventError("ElasticSearch", item.falcopayload, err)
+		c.deadLetter(item.falcopayload, err)
+		item.done(err)
+	}
+	c.adaptElasticsearchBulkSize(throttled)
+	c.setElasticsearchBulkMetrics(OK, ok)
+	c.setElasticsearchBulkMetrics(Error, failed)
+	if len(requeued) != 0 {
+		c.countRetry()
+	}
+	log.Printf("[INFO]  : ElasticSearch - Bulk of %v events, %v OK, %v failed, %v to retry\n", len(items), ok, failed, len(requeued))
+	return requeued
+}
+
+// elasticsearchBulkBody returns the NDJSON body of a batch, by index, and for each document the positions in the batch of
+// the events it's the result of. With CompactFields, the output fields identical across the events of an index are
+// indexed once in a document {"shared_output_fields": {...}}, whose _id is their SHA-256, and the events reference it
+// with the field shared_output_fields_id instead.
+func (c *Client) elasticsearchBulkBody(items []*batchItem) ([]byte, [][]int) {
+	var indexes []string
+	byIndex := make(map[string][]int)
+	for n, i := range items {
+		index := i.value.(elasticsearchBulkItem).index
+		if _, ok := byIndex[index]; !ok {
+			indexes = append(indexes, index)
+		}
+		byIndex[index] = append(byIndex[index], n)
+	}
+	key := "output_fields"
+	if k, ok := c.KeysMapping[key]; ok {
+		key = k
+	}
+
+	body := new(bytes.Buffer)
+	write := func(index, id string, document []byte) {
+		action := map[string]string{"_index": index}
+		if c.config().Elasticsearch.Type != "" {
+			action["_type"] = c.config().Elasticsearch.Type
+		}
+		if id != "" {
+			action["_id"] = id
+		}
+		line, _ := json.Marshal(map[string]interface{}{"index": action})
+		body.Write(line)
+		body.WriteByte('\n')
+		body.Write(document)
+		body.WriteByte('\n')
+	}
+	var lines [][]int
+	for _, index := range indexes {
+		positions := byIndex[index]
+		documents := make([][]byte, len(positions))
+		for n, i := range positions {
+			documents[n] = items[i].value.(elasticsearchBulkItem).document
+		}
+		var events []map[string]json.RawMessage
+		var shared map[string]json.RawMessage
+		if c.config().Elasticsearch.CompactFields {
+			events, shared = compactOutputFields(documents, key)
+		}
+		if shared == nil {
+			for n, i := range positions {
+				write(index, "", documents[n])
+				lines = append(lines, []int{i})
+			}
+			continue
+		}
+		header, _ := json.Marshal(map[string]interface{}{SharedOutputFields: shared})
+		h := sha256.Sum256(header)
+		id := hex.EncodeToString(h[:])
+		write(index, id, header)
+		lines = append(lines, positions)
+		for n, i := range positions {
+			events[n][SharedOutputFields+"_id"], _ = json.Marshal(id)
+			document, _ := json.Marshal(events[n])
+			write(index, "", document)
+			lines = append(lines, []int{i})
+		}
+	}
+	return body.Bytes(), lines
+}
+
+// adaptElasticsearchBulkSize halves the size of the next batches after a batch throttled by Elasticsearch with a 429 and
+// increases it back by one event after each batch which isn't, up to BatchSize (additive increase, multiplicative
+// decrease), so the batches follow the capacity of the cluster
+func (c *Client) adaptElasticsearchBulkSize(throttled bool) {
+	size := c.elasticsearchBulk.batchSize()
+	switch {
+	case throttled:
+		if n := c.elasticsearchBulk.resize(size/2, c.config().Elasticsearch.BatchSize); n != size {
+			log.Printf("[WARN]  : ElasticSearch - Bulk throttled, batches reduced to %v events\n", n)
+		}
+	case size < c.config().Elasticsearch.BatchSize:
+		c.elasticsearchBulk.resize(size+1, c.config().Elasticsearch.BatchSize)
+	}
+}
+
+func (c *Client) setElasticsearchBulkMetrics(status string, n int) {
+	if n == 0 {
+		return
+	}
+	go c.CountMetric(Outputs, int64(n), []string{"output:elasticsearch", "status:" + status})
+	c.Stats.Elasticsearch.Add(status, int64(n))
+	c.PromStats.Outputs.With(map[string]string{"destination": "elasticsearch", "status": status}).Add(float64(n))
+}
@@ -0,0 +1,108 @@
+package outputs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// EncryptionAlgorithm is the only algorithm used for client-side encryption of payloads
+const EncryptionAlgorithm = "AES-256-GCM"
+
+// ErrDecryption is returned when an encrypted payload can't be decrypted
+var ErrDecryption = errors.New("Decryption Error")
+
+// encryptedPayload is the envelope written instead of the plain payload when client-side encryption is enabled.
+// KeyID identifies the key used, so keys can be rotated and old objects still be decrypted.
+// WrappedKey is the data key encrypted by KMS, only present when a KMS key is used.
+type encryptedPayload struct {
+	Version    int    `json:"version"`
+	Algorithm  string `json:"alg"`
+	KeyID      string `json:"kid"`
+	WrappedKey string `json:"wrapped_key,omitempty"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// decodeEncryptionKey decodes a base64 encoded 256 bits key
+func decodeEncryptionKey(key string) ([]byte, error) {
+	k, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(k) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes long")
+	}
+	return k, nil
+}
+
+// encryptPayload encrypts payload with AES-GCM and returns the JSON envelope
+func encryptPayload(payload []byte, keyID string, key []byte, wrappedKey []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	e := encryptedPayload{
+		Version:    1,
+		Algorithm:  EncryptionAlgorithm,
+		KeyID:      keyID,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, payload, []byte(keyID))),
+	}
+	if len(wrappedKey) != 0 {
+		e.WrappedKey = base64.StdEncoding.EncodeToString(wrappedKey)
+	}
+	return json.Marshal(e)
+}
+
+// decryptPayload decrypts a JSON envelope created by encryptPayload, keys are indexed by their ID
+func decryptPayload(envelope []byte, keys map[string][]byte) ([]byte, error) {
+	var e encryptedPayload
+	if err := json.Unmarshal(envelope, &e); err != nil {
+		return nil, err
+	}
+	if e.Algorithm != EncryptionAlgorithm {
+		return nil, ErrDecryption
+	}
+	key, ok := keys[e.KeyID]
+	if !ok {
+		return nil, ErrDecryption
+	}
+	nonce, err := base64.StdEncoding.DecodeString(e.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(e.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, ErrDecryption
+	}
+	payload, err := gcm.Open(nil, nonce, ciphertext, []byte(e.KeyID))
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return payload, nil
+}
@@ -0,0 +1,70 @@
+package outputs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestEncryptDecryptPayload(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	e, err := encryptPayload([]byte(falcoTestInput), "key1", key, nil)
+	require.Nil(t, err)
+	require.NotContains(t, string(e), "falcosidekick")
+
+	d, err := decryptPayload(e, map[string][]byte{"key1": key})
+	require.Nil(t, err)
+	require.Equal(t, falcoTestInput, string(d))
+
+	_, err = decryptPayload(e, map[string][]byte{"key2": key})
+	require.Equal(t, ErrDecryption, err)
+}
+
+func TestUploadS3Encrypted(t *testing.T) {
+	var object []byte
+	var objectKey string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		objectKey = r.URL.Path
+		object, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(ts.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.Nil(t, err)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	config := &types.Configuration{}
+	config.AWS.S3.Bucket = "falco"
+	config.AWS.S3.Prefix = "falco"
+	config.AWS.S3.EncryptionKey = base64.StdEncoding.EncodeToString(key)
+	config.AWS.S3.EncryptionKeyID = "key1"
+	c := &Client{OutputType: "AWS", Config: config, AWSSession: sess, Stats: &types.Statistics{}, PromStats: newTestPromStats()}
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	c.UploadS3(context.Background(), f)
+
+	require.True(t, strings.HasSuffix(objectKey, ".json.enc"))
+	d, err := decryptPayload(object, map[string][]byte{"key1": key})
+	require.Nil(t, err)
+	expected, _ := json.Marshal(f)
+	require.Equal(t, expected, d)
+}
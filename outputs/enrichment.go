@@ -0,0 +1,166 @@
+package outputs
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// EnrichmentPlaceholder is replaced in the URL of the lookup service by the value of the field
+const EnrichmentPlaceholder string = "{value}"
+
+type cachedEnrichment struct {
+	fields map[string]interface{}
+	expire time.Time
+}
+
+// Enricher adds to events the fields returned by a lookup service for the value of one of their fields
+type Enricher struct {
+	URL        string
+	Field      string
+	CacheTTL   time.Duration
+	PromStats  *types.PromStatistics
+	httpClient *http.Client
+	limiter    *rate.Limiter
+	group      singleflight.Group
+	mu         sync.Mutex
+	cache      map[string]cachedEnrichment
+}
+
+// NewEnricher returns a new Enricher for the lookup service configured
+func NewEnricher(config *types.Configuration, promStats *types.PromStatistics) (*Enricher, error) {
+	if !strings.HasPrefix(config.Enrichment.URL, "http://") && !strings.HasPrefix(config.Enrichment.URL, "https://") {
+		log.Printf("[ERROR] : Enrichment - %v\n", "Bad Endpoint")
+		return nil, ErrClientCreation
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !config.Enrichment.CheckCert {
+		// #nosec G402 This is only set as a result of explicit configuration
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	limit := rate.Inf
+	if config.Enrichment.Rate > 0 {
+		limit = rate.Limit(config.Enrichment.Rate)
+	}
+
+	return &Enricher{
+		URL:        config.Enrichment.URL,
+		Field:      config.Enrichment.Field,
+		CacheTTL:   time.Duration(config.Enrichment.CacheTTL) * time.Second,
+		PromStats:  promStats,
+		httpClient: &http.Client{Transport: transport, Timeout: time.Duration(config.Enrichment.Timeout) * time.Millisecond},
+		limiter:    rate.NewLimiter(limit, 1),
+		cache:      make(map[string]cachedEnrichment),
+	}, nil
+}
+
+// Enrich adds the fields returned by the lookup service to the event, existing fields are kept.
+// The event is left unchanged if it doesn't have the field or if the lookup fails.
+func (e *Enricher) Enrich(falcopayload *types.FalcoPayload) {
+	v, ok := falcopayload.OutputFields[e.Field]
+	if !ok || v == nil {
+		return
+	}
+	value := fmt.Sprintf("%v", v)
+
+	fields, err := e.lookup(value)
+	if err != nil {
+		e.countMetric(Error)
+		log.Printf("[ERROR] : Enrichment - %v\n", err)
+		return
+	}
+	e.countMetric(OK)
+
+	for i, j := range fields {
+		if _, ok := falcopayload.OutputFields[i]; !ok {
+			falcopayload.OutputFields[i] = j
+		}
+	}
+}
+
+func (e *Enricher) countMetric(status string) {
+	if e.PromStats != nil && e.PromStats.Outputs != nil {
+		e.PromStats.Outputs.With(map[string]string{"destination": "enrichment", "status": status}).Inc()
+	}
+}
+
+// lookup returns the fields for the value, from the cache or from the lookup service,
+// concurrent lookups of the same value share the same request
+func (e *Enricher) lookup(value string) (map[string]interface{}, error) {
+	now := time.Now()
+	e.mu.Lock()
+	c, ok := e.cache[value]
+	e.mu.Unlock()
+	if ok && now.Before(c.expire) {
+		return c.fields, nil
+	}
+
+	f, err, _ := e.group.Do(value, func() (interface{}, error) {
+		if err := e.limiter.Wait(context.Background()); err != nil {
+			return nil, err
+		}
+		fields, err := e.request(value)
+		if err != nil {
+			return nil, err
+		}
+		if e.CacheTTL > 0 {
+			e.mu.Lock()
+			for i, j := range e.cache {
+				if now.After(j.expire) {
+					delete(e.cache, i)
+				}
+			}
+			e.cache[value] = cachedEnrichment{fields: fields, expire: time.Now().Add(e.CacheTTL)}
+			e.mu.Unlock()
+		}
+		return fields, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.(map[string]interface{}), nil
+}
+
+func (e *Enricher) request(value string) (map[string]interface{}, error) {
+	u := e.URL
+	if strings.Contains(u, EnrichmentPlaceholder) {
+		u = strings.ReplaceAll(u, EnrichmentPlaceholder, url.PathEscape(value))
+	} else {
+		u += url.QueryEscape(value)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("User-Agent", "Falcosidekick")
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected Response (%v)", resp.StatusCode)
+	}
+
+	var fields map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,75 @@
+package outputs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestEnricher(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		if r.URL.Path != "/pods/nginx" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"owner":"web","team":"platform","criticality":"high","proc.name":"other"}`))
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{Enrichment: types.EnrichmentConfig{URL: ts.URL + "/pods/{value}", Field: "k8s.pod.name", CacheTTL: 60}}
+	promStats := newTestPromStats()
+	e, err := NewEnricher(config, promStats)
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.OutputFields["k8s.pod.name"] = "nginx"
+
+	// concurrent lookups for the same pod share a single request
+	var wg sync.WaitGroup
+	events := make([]types.FalcoPayload, 5)
+	for i := range events {
+		events[i] = f
+		events[i].OutputFields = map[string]interface{}{"k8s.pod.name": "nginx", "proc.name": "falcosidekick"}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			e.Enrich(&events[i])
+		}(i)
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, i := range events {
+		require.Equal(t, "platform", i.OutputFields["team"])
+		require.Equal(t, "high", i.OutputFields["criticality"])
+		require.Equal(t, "falcosidekick", i.OutputFields["proc.name"])
+	}
+
+	// from the cache
+	e.Enrich(&f)
+	require.Equal(t, "web", f.OutputFields["owner"])
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// lookup failure, the event is unchanged
+	var g types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &g))
+	g.OutputFields["k8s.pod.name"] = "unknown"
+	e.Enrich(&g)
+	require.Nil(t, g.OutputFields["team"])
+	require.Equal(t, float64(1), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "enrichment", "status": Error})))
+}
@@ -0,0 +1,93 @@
+package outputs
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// EscalatedFromField is the field of an escalated event with its original priority
+const EscalatedFromField string = "escalated_from"
+
+type occurrences struct {
+	start time.Time
+	count int
+}
+
+// Escalator raises the priority of the events repeated for the same entity, i.e. with the same rule and the same
+// values for the keys, from the Threshold-th one within a window starting at the first one
+type Escalator struct {
+	Keys        []string
+	Threshold   int
+	Window      time.Duration
+	Levels      int
+	mu          sync.Mutex
+	occurrences map[string]*occurrences
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+// NewEscalator returns an Escalator for the keys, the threshold and the window configured
+func NewEscalator(config *types.Configuration) *Escalator {
+	return &Escalator{
+		Keys:        config.Escalation.Keys,
+		Threshold:   config.Escalation.Threshold,
+		Window:      time.Duration(config.Escalation.Window) * time.Second,
+		Levels:      config.Escalation.Levels,
+		occurrences: make(map[string]*occurrences),
+		now:         time.Now,
+	}
+}
+
+// Escalate counts the event and raises its priority by Levels (up to Emergency) if it's repeated enough
+func (e *Escalator) Escalate(falcopayload *types.FalcoPayload) {
+	key := e.dedupKey(*falcopayload)
+	now := e.now()
+
+	e.mu.Lock()
+	if now.Sub(e.lastCleanup) > e.Window {
+		for i, j := range e.occurrences {
+			if now.Sub(j.start) > e.Window {
+				delete(e.occurrences, i)
+			}
+		}
+		e.lastCleanup = now
+	}
+	o, ok := e.occurrences[key]
+	if !ok || now.Sub(o.start) > e.Window {
+		o = &occurrences{start: now}
+		e.occurrences[key] = o
+	}
+	o.count++
+	count := o.count
+	e.mu.Unlock()
+
+	if count < e.Threshold || falcopayload.Priority == types.Emergency {
+		return
+	}
+	p := falcopayload.Priority + types.PriorityType(e.Levels)
+	if p > types.Emergency {
+		p = types.Emergency
+	}
+	if falcopayload.OutputFields == nil {
+		falcopayload.OutputFields = make(map[string]interface{})
+	}
+	falcopayload.OutputFields[EscalatedFromField] = falcopayload.Priority.String()
+	falcopayload.Priority = p
+}
+
+// dedupKey returns the rule and the values of the keys of an event, a missing key is an empty value
+func (e *Escalator) dedupKey(falcopayload types.FalcoPayload) string {
+	values := []string{falcopayload.Rule}
+	for _, i := range e.Keys {
+		if v, ok := falcopayload.OutputFields[i]; ok && v != nil {
+			values = append(values, fmt.Sprintf("%v", v))
+		} else {
+			values = append(values, "")
+		}
+	}
+	return strings.Join(values, "\x00")
+}
@@ -0,0 +1,49 @@
+package outputs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestEscalate(t *testing.T) {
+	config := &types.Configuration{}
+	config.Escalation.Keys = []string{"k8s.pod.name"}
+	config.Escalation.Threshold = 3
+	config.Escalation.Window = 60
+	config.Escalation.Levels = 1
+	e := NewEscalator(config)
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+
+	event := func(pod string) types.FalcoPayload {
+		f := types.FalcoPayload{Rule: "Terminal shell in container", Priority: types.Warning, OutputFields: map[string]interface{}{"k8s.pod.name": pod}}
+		e.Escalate(&f)
+		return f
+	}
+
+	for i := 0; i < 2; i++ {
+		f := event("nginx")
+		require.Equal(t, types.PriorityType(types.Warning), f.Priority)
+		require.NotContains(t, f.OutputFields, EscalatedFromField)
+	}
+	// another entity has its own count
+	require.Equal(t, types.PriorityType(types.Warning), event("redis").Priority)
+
+	f := event("nginx")
+	require.Equal(t, types.PriorityType(types.Error), f.Priority)
+	require.Equal(t, "Warning", f.OutputFields[EscalatedFromField])
+	require.Equal(t, types.PriorityType(types.Error), event("nginx").Priority)
+
+	// the count is reset once the window is over
+	now = now.Add(61 * time.Second)
+	require.Equal(t, types.PriorityType(types.Warning), event("nginx").Priority)
+
+	// the priority isn't raised above Emergency
+	e.Levels = 10
+	event("nginx")
+	require.Equal(t, types.PriorityType(types.Emergency), event("nginx").Priority)
+}
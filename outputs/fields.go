@@ -0,0 +1,101 @@
+package outputs
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Sources of the fields of an event, also used as prefix for the conflicting keys
+const (
+	CustomFieldsSource    string = "customfields"
+	TemplatedFieldsSource string = "templatedfields"
+	OutputFieldsSource    string = "outputfields"
+)
+
+// Values for Configuration.FieldsPrecedence and Configuration.FieldsConflict
+const (
+	CustomFieldsFirst string = "custom"
+	OutputFieldsFirst string = "output"
+	Overwrite         string = "overwrite"
+	Prefix            string = "prefix"
+)
+
+type fieldsSource struct {
+	name   string
+	fields map[string]interface{}
+}
+
+// FieldsMerger adds the custom and templated fields to the output fields of events
+type FieldsMerger struct {
+	Customfields    map[string]string
+	Templatedfields map[string]*template.Template
+	// Precedence is CustomFieldsFirst (custom > templated > output_fields) or OutputFieldsFirst (output_fields > templated > custom)
+	Precedence string
+	// Conflict is Overwrite (the field with the highest precedence is kept) or Prefix (the others are kept too as <source>.<key>)
+	Conflict string
+}
+
+// NewFieldsMerger returns a FieldsMerger for the custom and templated fields configured
+func NewFieldsMerger(config *types.Configuration) (*FieldsMerger, error) {
+	m := &FieldsMerger{
+		Customfields:    config.Customfields,
+		Templatedfields: make(map[string]*template.Template, len(config.Templatedfields)),
+		Precedence:      config.FieldsPrecedence,
+		Conflict:        config.FieldsConflict,
+	}
+	for i, j := range config.Templatedfields {
+		t, err := template.New(i).Parse(j)
+		if err != nil {
+			return nil, err
+		}
+		m.Templatedfields[i] = t
+	}
+	return m, nil
+}
+
+// Merge adds the custom and templated fields to the output fields of the event, templates are evaluated
+// with the event as received from Falco.
+func (m *FieldsMerger) Merge(falcopayload *types.FalcoPayload) error {
+	if len(m.Customfields) == 0 && len(m.Templatedfields) == 0 {
+		return nil
+	}
+
+	custom := make(map[string]interface{}, len(m.Customfields))
+	for i, j := range m.Customfields {
+		custom[i] = j
+	}
+	templated := make(map[string]interface{}, len(m.Templatedfields))
+	for i, j := range m.Templatedfields {
+		buf := new(bytes.Buffer)
+		if err := j.Execute(buf, falcopayload); err != nil {
+			return err
+		}
+		templated[i] = buf.String()
+	}
+
+	// from the lowest to the highest precedence
+	sources := []fieldsSource{
+		{OutputFieldsSource, falcopayload.OutputFields},
+		{TemplatedFieldsSource, templated},
+		{CustomFieldsSource, custom},
+	}
+	if m.Precedence == OutputFieldsFirst {
+		sources[0], sources[2] = sources[2], sources[0]
+	}
+
+	fields := make(map[string]interface{}, len(falcopayload.OutputFields)+len(custom)+len(templated))
+	origin := make(map[string]string)
+	for _, s := range sources {
+		for i, j := range s.fields {
+			if o, ok := origin[i]; ok && m.Conflict == Prefix {
+				fields[o+"."+i] = fields[i]
+			}
+			fields[i] = j
+			origin[i] = s.name
+		}
+	}
+	falcopayload.OutputFields = fields
+	return nil
+}
@@ -0,0 +1,65 @@
+package outputs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestFieldsMerger(t *testing.T) {
+	config := &types.Configuration{
+		Customfields:    map[string]string{"proc.name": "custom", "env": "prod"},
+		Templatedfields: map[string]string{"proc.name": "templated", "rule": "{{ .Rule }}"},
+	}
+
+	cases := []struct {
+		precedence, conflict string
+		expected             map[string]interface{}
+	}{
+		{CustomFieldsFirst, Overwrite, map[string]interface{}{
+			"proc.name": "custom",
+			"env":       "prod",
+			"rule":      "Test rule",
+		}},
+		{OutputFieldsFirst, Overwrite, map[string]interface{}{
+			"proc.name": "falcosidekick",
+			"env":       "prod",
+			"rule":      "Test rule",
+		}},
+		{CustomFieldsFirst, Prefix, map[string]interface{}{
+			"proc.name":                 "custom",
+			"outputfields.proc.name":    "falcosidekick",
+			"templatedfields.proc.name": "templated",
+			"env":                       "prod",
+			"rule":                      "Test rule",
+		}},
+		{OutputFieldsFirst, Prefix, map[string]interface{}{
+			"proc.name":                 "falcosidekick",
+			"customfields.proc.name":    "custom",
+			"templatedfields.proc.name": "templated",
+			"env":                       "prod",
+			"rule":                      "Test rule",
+		}},
+	}
+
+	for _, c := range cases {
+		config.FieldsPrecedence, config.FieldsConflict = c.precedence, c.conflict
+		m, err := NewFieldsMerger(config)
+		require.Nil(t, err)
+
+		var f types.FalcoPayload
+		require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+		require.Nil(t, m.Merge(&f))
+		for i, j := range c.expected {
+			require.Equal(t, j, f.OutputFields[i], "%v/%v %v", c.precedence, c.conflict, i)
+		}
+		require.Equal(t, float64(1234), f.OutputFields["proc.tty"])
+	}
+
+	config.Templatedfields = map[string]string{"bad": "{{ .Rule"}
+	_, err := NewFieldsMerger(config)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,128 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/DataDog/datadog-go/statsd"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Attempts to write an event to the FIFO, the next ones are made if it has no reader
+const (
+	fifoAttempts     = 3
+	fifoWriteTimeout = 10 * time.Second
+)
+
+// ErrFifoNoReader is returned for the events not written because the FIFO has no reader
+var ErrFifoNoReader = errors.New("No reader of the FIFO")
+
+// fifoWriter writes the events as NDJSON to a named pipe, it's opened once it has a reader and reopened if the reader restarts
+type fifoWriter struct {
+	path       string
+	retryDelay time.Duration
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFifoClient returns a new output.Client for writing the events to a named pipe
+func NewFifoClient(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics, statsdClient, dogstatsdClient *statsd.Client) (*Client, error) {
+	info, err := os.Stat(config.Fifo.Path)
+	if err != nil {
+		log.Printf("[ERROR] : Fifo - %v\n", err)
+		return nil, ErrClientCreation
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		log.Printf("[ERROR] : Fifo - %v isn't a named pipe\n", config.Fifo.Path)
+		return nil, ErrClientCreation
+	}
+
+	return &Client{
+		OutputType:      "Fifo",
+		Config:          config,
+		Stats:           stats,
+		PromStats:       promStats,
+		StatsdClient:    statsdClient,
+		DogstatsdClient: dogstatsdClient,
+		fifo:            &fifoWriter{path: config.Fifo.Path, retryDelay: time.Second},
+	}, nil
+}
+
+// FifoPost writes an event as a JSON line to the FIFO
+func (c *Client) FifoPost(ctx context.Context, falcopayload types.FalcoPayload) error {
+	c.Stats.Fifo.Add(Total, 1)
+
+	line, err := json.Marshal(falcopayload)
+	if err != nil {
+		c.setFifoMetrics(Error)
+		logEventError("Fifo", falcopayload, err)
+		return err
+	}
+	c.checkPayloadSize(len(line)+1, falcopayload.Rule)
+
+	if err := c.fifo.write(ctx.Done(), append(line, '\n')); err != nil {
+		c.setFifoMetrics(Error)
+		logEventError("Fifo", falcopayload, err)
+		return err
+	}
+	c.setFifoMetrics(OK)
+	log.Printf("[INFO]  : Fifo - Write OK\n")
+	return nil
+}
+
+func (c *Client) setFifoMetrics(status string) {
+	go c.CountMetric(Outputs, 1, []string{"output:fifo", "status:" + status})
+	c.Stats.Fifo.Add(status, 1)
+	c.PromStats.Outputs.With(map[string]string{"destination": "fifo", "status": status}).Inc()
+}
+
+// write writes a line, the FIFO is (re)opened if needed, the attempts are made until done is closed
+func (w *fifoWriter) write(done <-chan struct{}, line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var err error
+	for i := 0; i < fifoAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-done:
+				return ErrDeadlineExceeded
+			case <-time.After(w.retryDelay):
+			}
+		}
+		if w.file == nil {
+			if w.file, err = w.open(); err != nil {
+				continue
+			}
+		}
+		// #nosec G104 a failure is returned by the write
+		w.file.SetWriteDeadline(time.Now().Add(fifoWriteTimeout))
+		if _, err = w.file.Write(line); err == nil {
+			return nil
+		}
+		// the reader is gone (EPIPE) or stuck, the FIFO is reopened for the next attempt
+		w.file.Close()
+		w.file = nil
+		if errors.Is(err, syscall.EPIPE) {
+			err = ErrFifoNoReader
+		}
+	}
+	return err
+}
+
+// open opens the FIFO for writing without blocking, it fails with ErrFifoNoReader if there's no reader yet
+func (w *fifoWriter) open() (*os.File, error) {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if errors.Is(err, syscall.ENXIO) {
+		return nil, ErrFifoNoReader
+	}
+	return f, err
+}
@@ -0,0 +1,68 @@
+//go:build !windows
+// +build !windows
+
+package outputs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"expvar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestFifoPost(t *testing.T) {
+	dir, err := ioutil.TempDir("", "falcosidekick-fifo")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events")
+	require.Nil(t, syscall.Mkfifo(path, 0600))
+
+	config := &types.Configuration{}
+	config.Fifo.Path = path
+	client, err := NewFifoClient(config, &types.Statistics{Fifo: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.fifo.retryDelay = 10 * time.Millisecond
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	// no reader
+	require.Equal(t, ErrFifoNoReader, client.FifoPost(context.Background(), f))
+
+	read := func(reader *os.File, n int) {
+		lines := bufio.NewScanner(reader)
+		for i := 0; i < n; i++ {
+			require.True(t, lines.Scan())
+			var e types.FalcoPayload
+			require.Nil(t, json.Unmarshal(lines.Bytes(), &e))
+			require.Equal(t, "Test rule", e.Rule)
+		}
+	}
+
+	reader, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	require.Nil(t, err)
+	require.Nil(t, client.FifoPost(context.Background(), f))
+	require.Nil(t, client.FifoPost(context.Background(), f))
+	require.Nil(t, syscall.SetNonblock(int(reader.Fd()), false))
+	read(reader, 2)
+
+	// the reader restarts, the FIFO is reopened
+	reader.Close()
+	require.Equal(t, ErrFifoNoReader, client.FifoPost(context.Background(), f))
+	reader, err = os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	require.Nil(t, err)
+	defer reader.Close()
+	require.Nil(t, client.FifoPost(context.Background(), f))
+	require.Nil(t, syscall.SetNonblock(int(reader.Fd()), false))
+	read(reader, 1)
+}
@@ -0,0 +1,137 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Coercions of the values of the output fields flattened
+const (
+	CoerceNone   string = ""       // the values are unchanged
+	CoerceAuto   string = "auto"   // the numbers and the booleans, as strings or not, get their JSON type
+	CoerceString string = "string" // all the values are strings
+)
+
+// flatteningUnsafeChars matches the characters replaced by the separator in the names of the fields flattened
+var flatteningUnsafeChars = regexp.MustCompile(`[^A-Za-z0-9_\-]+`)
+
+// Flattening flattens the output fields of the events before some outputs send them: the nested objects and arrays
+// become fields named with the path of their values, joined by the separator, and the characters other than letters,
+// digits, - and _ in the names (ex: the dots of proc.name) are replaced by the separator
+type Flattening struct {
+	outputs   map[string]bool
+	separator string
+	coerce    string
+}
+
+// NewFlattening returns the Flattening of the outputs configured, all of them must be enabled
+func NewFlattening(config *types.Configuration, enabledOutputs []string) (*Flattening, error) {
+	enabled := enabledOutputSet(enabledOutputs)
+	switch config.Flattening.Coerce {
+	case CoerceNone, CoerceAuto, CoerceString:
+	default:
+		return nil, fmt.Errorf("Bad coercion '%v', must be \"\", %v or %v", config.Flattening.Coerce, CoerceAuto, CoerceString)
+	}
+	if flatteningUnsafeChars.MatchString(config.Flattening.Separator) {
+		return nil, fmt.Errorf("Bad separator '%v', must be letters, digits, - or _", config.Flattening.Separator)
+	}
+	f := &Flattening{outputs: make(map[string]bool), separator: config.Flattening.Separator, coerce: config.Flattening.Coerce}
+	for _, i := range config.Flattening.Outputs {
+		if !enabled[dispatchName(i)] {
+			return nil, fmt.Errorf("Output '%v' with flattening isn't enabled", i)
+		}
+		f.outputs[dispatchName(i)] = true
+	}
+	return f, nil
+}
+
+// Output returns an Output flattening the events before sending them with output if it's one of the outputs
+// configured, a nil Flattening returns output as is
+func (f *Flattening) Output(name string, output Output) Output {
+	if f == nil || !f.outputs[dispatchName(name)] {
+		return output
+	}
+	return OutputFunc(func(ctx context.Context, falcopayload types.FalcoPayload) error {
+		falcopayload.OutputFields = f.Flatten(falcopayload.OutputFields)
+		return output.Send(ctx, falcopayload)
+	})
+}
+
+// Flatten returns a flat copy of the output fields, with their values coerced. Two fields getting the same name are
+// merged in the order of their names.
+func (f *Flattening) Flatten(fields map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{}, len(fields))
+	for _, i := range sortedKeys(fields) {
+		f.flatten(flat, f.sanitize(i), fields[i])
+	}
+	return flat
+}
+
+func (f *Flattening) flatten(flat map[string]interface{}, name string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, i := range sortedKeys(v) {
+			f.flatten(flat, name+f.separator+f.sanitize(i), v[i])
+		}
+	case []interface{}:
+		for i, j := range v {
+			f.flatten(flat, name+f.separator+strconv.Itoa(i), j)
+		}
+	default:
+		flat[name] = f.coerceValue(value)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for i := range m {
+		keys = append(keys, i)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sanitize replaces the unsafe characters of the name of a field by the separator
+func (f *Flattening) sanitize(name string) string {
+	return strings.Trim(flatteningUnsafeChars.ReplaceAllString(name, f.separator), f.separator)
+}
+
+func (f *Flattening) coerceValue(value interface{}) interface{} {
+	switch f.coerce {
+	case CoerceString:
+		if value == nil {
+			return ""
+		}
+		return fmt.Sprintf("%v", value)
+	case CoerceAuto:
+		var s string
+		switch v := value.(type) {
+		case json.Number:
+			s = v.String()
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		case string:
+			s = strings.TrimSpace(v)
+		default:
+			return value
+		}
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		if n, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+			return n
+		}
+		if b, err := strconv.ParseBool(s); err == nil && (s == "true" || s == "false") {
+			return b
+		}
+	}
+	return value
+}
@@ -0,0 +1,72 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestFlattening(t *testing.T) {
+	var f types.FalcoPayload
+	d := json.NewDecoder(strings.NewReader(`{"output":"test","priority":"Debug","rule":"Test rule","time":"2001-01-01T01:10:00Z","output_fields":{
+		"proc.name":"falcosidekick","proc.pid":1234,"evt.time":1.5,"container.privileged":"true","fd.port":"8080",
+		"k8s.pod.labels":{"app":"nginx","app/version":"1.21"},"proc.aname":["bash","sshd"],"user.loginuid":null}}`))
+	d.UseNumber()
+	require.Nil(t, d.Decode(&f))
+
+	config := &types.Configuration{Flattening: types.FlatteningConfig{Outputs: []string{"Loki"}, Separator: "_", Coerce: CoerceAuto}}
+	flattening, err := NewFlattening(config, []string{"Loki", "Slack"})
+	require.Nil(t, err)
+	require.Equal(t, map[string]interface{}{
+		"proc_name":                  "falcosidekick",
+		"proc_pid":                   int64(1234),
+		"evt_time":                   1.5,
+		"container_privileged":       true,
+		"fd_port":                    int64(8080),
+		"k8s_pod_labels_app":         "nginx",
+		"k8s_pod_labels_app_version": 1.21,
+		"proc_aname_0":               "bash",
+		"proc_aname_1":               "sshd",
+		"user_loginuid":              nil,
+	}, flattening.Flatten(f.OutputFields))
+
+	config.Flattening.Coerce = CoerceString
+	config.Flattening.Separator = "-"
+	flattening, err = NewFlattening(config, []string{"Loki", "Slack"})
+	require.Nil(t, err)
+	flat := flattening.Flatten(f.OutputFields)
+	require.Equal(t, "1234", flat["proc-pid"])
+	require.Equal(t, "1.21", flat["k8s-pod-labels-app-version"])
+	require.Equal(t, "", flat["user-loginuid"])
+
+	// only the outputs configured get the flattened fields, the event isn't modified
+	var sent map[string]interface{}
+	send := OutputFunc(func(ctx context.Context, falcopayload types.FalcoPayload) error {
+		sent = falcopayload.OutputFields
+		return nil
+	})
+	require.Nil(t, flattening.Output("Loki", send).Send(context.Background(), f))
+	require.Equal(t, "bash", sent["proc-aname-0"])
+	require.Contains(t, f.OutputFields, "proc.aname")
+	require.Nil(t, flattening.Output("Slack", send).Send(context.Background(), f))
+	require.Contains(t, sent, "proc.aname")
+	require.Nil(t, (*Flattening)(nil).Output("Loki", send).Send(context.Background(), f))
+	require.Contains(t, sent, "proc.aname")
+
+	config.Flattening.Outputs = []string{"Teams"}
+	_, err = NewFlattening(config, []string{"Loki", "Slack"})
+	require.NotNil(t, err)
+	config.Flattening.Outputs = []string{"Loki"}
+	config.Flattening.Separator = "."
+	_, err = NewFlattening(config, []string{"Loki", "Slack"})
+	require.NotNil(t, err)
+	config.Flattening.Separator = "_"
+	config.Flattening.Coerce = "number"
+	_, err = NewFlattening(config, []string{"Loki", "Slack"})
+	require.NotNil(t, err)
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"text/template"
 	"time"
 
 	gcpfunctions "cloud.google.com/go/functions/apiv1"
@@ -56,6 +57,7 @@ func NewGCPClient(config *types.Configuration, stats *types.Statistics, promStat
 			}
 			topicClient = pubSubClient.Topic(config.GCP.PubSub.Topic)
 		}
+		topicClient.EnableMessageOrdering = config.GCP.PubSub.OrderingKey != ""
 	}
 
 	if config.GCP.Storage.Bucket != "" {
@@ -102,18 +104,21 @@ func NewGCPClient(config *types.Configuration, stats *types.Statistics, promStat
 		PromStats:               promStats,
 		StatsdClient:            statsdClient,
 		DogstatsdClient:         dogstatsdClient,
+		RetryMaxAttempts:        config.Retry.MaxAttempts,
+		RetryInitialDelay:       time.Duration(config.Retry.InitialDelay) * time.Millisecond,
+		RetryMaxDelay:           time.Duration(config.Retry.MaxDelay) * time.Millisecond,
 	}, nil
 }
 
 // GCPCallCloudFunction calls the given Cloud Function
-func (c *Client) GCPCallCloudFunction(falcopayload types.FalcoPayload) {
+func (c *Client) GCPCallCloudFunction(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.GCPCloudFunctions.Add(Total, 1)
 
 	payload, _ := json.Marshal(falcopayload)
 	data := string(payload)
 
 	result, err := c.GCPCloudFunctionsClient.CallFunction(context.Background(), &gcpfunctionspb.CallFunctionRequest{
-		Name: c.Config.GCP.CloudFunctions.Name,
+		Name: c.config().GCP.CloudFunctions.Name,
 		Data: data,
 	}, gax.WithGRPCOptions())
 
@@ -123,65 +128,133 @@ func (c *Client) GCPCallCloudFunction(falcopayload types.FalcoPayload) {
 		go c.CountMetric("outputs", 1, []string{"output:gcpcloudfunctions", "status:error"})
 		c.PromStats.Outputs.With(map[string]string{"destination": "gcpcloudfunctions", "status": Error}).Inc()
 
-		return
+		return err
 	}
 
 	log.Printf("[INFO]  : GCPCloudFunctions - Call CloudFunction OK (%v)\n", result.ExecutionId)
 	c.Stats.GCPCloudFunctions.Add(OK, 1)
 	go c.CountMetric("outputs", 1, []string{"output:gcpcloudfunctions", "status:ok"})
 
+	return nil
 }
 
-// GCPPublishTopic sends a message to a GCP PubSub Topic
-func (c *Client) GCPPublishTopic(falcopayload types.FalcoPayload) {
+// GCPPublishTopic sends a message to a GCP PubSub Topic, with the ordering key and the attributes configured. The failed
+// publishes are retried as the HTTP requests, with an ordering key the publishing of the key is resumed before.
+func (c *Client) GCPPublishTopic(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.GCPPubSub.Add(Total, 1)
 
-	payload, _ := json.Marshal(falcopayload)
-	message := &pubsub.Message{
-		Data: payload,
+	message, err := newPubSubMessage(falcopayload, c.config().GCP.PubSub.OrderingKeyTemplate, c.config().GCP.PubSub.Attributes)
+	if err != nil {
+		c.setGCPPubSubErrorMetrics()
+		logEventError("GCPPubSub", falcopayload, err)
+		return err
 	}
 
-	result := c.GCPTopicClient.Publish(context.Background(), message)
-	id, err := result.Get(context.Background())
+	var id string
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if message.OrderingKey != "" {
+				c.GCPTopicClient.ResumePublish(message.OrderingKey)
+			}
+			c.countRetry()
+			if sleepContext(ctx, c.exponentialBackoff(attempt-1)) != nil {
+				return c.deadlineExceeded()
+			}
+		}
+		id, err = c.GCPTopicClient.Publish(ctx, message).Get(ctx)
+		if err == nil || ctx.Err() != nil || attempt+1 >= c.RetryMaxAttempts {
+			break
+		}
+		log.Printf("[WARN]  : GCPPubSub - %v - %v, retrying\n", "Error while publishing message", err.Error())
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return c.deadlineExceeded()
+	}
 	if err != nil {
+		// the publishing of the key is paused by the failure, the next events of the key would fail without resuming it
+		if message.OrderingKey != "" {
+			c.GCPTopicClient.ResumePublish(message.OrderingKey)
+		}
 		log.Printf("[ERROR] : GCPPubSub - %v - %v\n", "Error while publishing message", err.Error())
-		c.Stats.GCPPubSub.Add(Error, 1)
-		go c.CountMetric("outputs", 1, []string{"output:gcppubsub", "status:error"})
-		c.PromStats.Outputs.With(map[string]string{"destination": "gcppubsub", "status": Error}).Inc()
+		c.setGCPPubSubErrorMetrics()
 
-		return
+		return err
 	}
 
 	log.Printf("[INFO]  : GCPPubSub - Send to topic OK (%v)\n", id)
 	c.Stats.GCPPubSub.Add(OK, 1)
 	go c.CountMetric("outputs", 1, []string{"output:gcppubsub", "status:ok"})
 	c.PromStats.Outputs.With(map[string]string{"destination": "gcppubsub", "status": OK}).Inc()
+
+	return nil
+}
+
+// GCPPubSubFlush publishes the messages still batched by the Pub/Sub client, at shutdown
+func (c *Client) GCPPubSubFlush() {
+	if c.GCPTopicClient != nil {
+		c.GCPTopicClient.Stop()
+	}
+}
+
+// newPubSubMessage returns the message of an event, its ordering key is the value of the template of the fields
+// orderingKey, and its attributes are the values of the fields (rule, priority, source, hostname or output fields).
+// The events with an empty ordering key aren't ordered.
+func newPubSubMessage(falcopayload types.FalcoPayload, orderingKey *template.Template, attributes []string) (*pubsub.Message, error) {
+	payload, _ := json.Marshal(falcopayload)
+	key, err := executeFieldTemplate(orderingKey, falcopayload, nil)
+	if err != nil {
+		return nil, err
+	}
+	message := &pubsub.Message{
+		Data:        payload,
+		OrderingKey: key,
+	}
+	for _, i := range attributes {
+		v, ok := FieldValue(falcopayload, i)
+		if !ok {
+			continue
+		}
+		if message.Attributes == nil {
+			message.Attributes = make(map[string]string, len(attributes))
+		}
+		message.Attributes[i] = v
+	}
+	return message, nil
+}
+
+// setGCPPubSubErrorMetrics set the error stats
+func (c *Client) setGCPPubSubErrorMetrics() {
+	c.Stats.GCPPubSub.Add(Error, 1)
+	go c.CountMetric("outputs", 1, []string{"output:gcppubsub", "status:error"})
+	c.PromStats.Outputs.With(map[string]string{"destination": "gcppubsub", "status": Error}).Inc()
 }
 
 // UploadGCS upload payload to
-func (c *Client) UploadGCS(falcopayload types.FalcoPayload) {
+func (c *Client) UploadGCS(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.GCPStorage.Add(Total, 1)
 
-	payload, _ := json.Marshal(falcopayload)
+	payload, _ := json.Marshal(withRawEvent(falcopayload, falcopayload, c.config().GCP.Storage.RawEventKey))
 
 	prefix := ""
 	t := time.Now()
-	if c.Config.GCP.Storage.Prefix != "" {
-		prefix = c.Config.GCP.Storage.Prefix
+	if c.config().GCP.Storage.Prefix != "" {
+		prefix = c.config().GCP.Storage.Prefix
 	}
 
 	key := fmt.Sprintf("%s/%s/%s.json", prefix, t.Format("2006-01-02"), t.Format(time.RFC3339Nano))
-	_, err := c.GCSStorageClient.Bucket(c.Config.GCP.Storage.Bucket).Object(key).NewWriter(context.Background()).Write(payload)
+	_, err := c.GCSStorageClient.Bucket(c.config().GCP.Storage.Bucket).Object(key).NewWriter(context.Background()).Write(payload)
 	if err != nil {
 		log.Printf("[ERROR] : GCPStorage - %v - %v\n", "Error while Uploading message", err.Error())
 		c.Stats.GCPStorage.Add(Error, 1)
 		go c.CountMetric("outputs", 1, []string{"output:gcpstorage", "status:error"})
 		c.PromStats.Outputs.With(map[string]string{"destination": "gcpstorage", "status": Error}).Inc()
-		return
+		return err
 	}
 
 	log.Printf("[INFO]  : GCPStorage - Upload to bucket OK \n")
 	c.Stats.GCPStorage.Add(OK, 1)
 	go c.CountMetric("outputs", 1, []string{"output:gcpstorage", "status:ok"})
 	c.PromStats.Outputs.With(map[string]string{"destination": "gcpstorage", "status": OK}).Inc()
+
+	return nil
 }
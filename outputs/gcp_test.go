@@ -0,0 +1,72 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+	"text/template"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestGCPPublishTopic(t *testing.T) {
+	srv := pstest.NewServer()
+	defer srv.Close()
+
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+	require.Nil(t, err)
+	defer conn.Close()
+	pubSubClient, err := pubsub.NewClient(context.Background(), "project", option.WithGRPCConn(conn))
+	require.Nil(t, err)
+	defer pubSubClient.Close()
+	topic, err := pubSubClient.CreateTopic(context.Background(), "falco")
+	require.Nil(t, err)
+
+	config := &types.Configuration{}
+	config.GCP.PubSub.OrderingKeyTemplate = template.Must(parseFieldTemplate("", `{{ .Field "k8s.ns.name" }}`))
+	config.GCP.PubSub.Attributes = []string{"k8s.pod.name", "proc.name", "container.id"}
+	topic.EnableMessageOrdering = true
+
+	c := &Client{
+		OutputType:     "GCPPubSub",
+		Config:         config,
+		GCPTopicClient: topic,
+		Stats:          &types.Statistics{GCPPubSub: new(expvar.Map)},
+		PromStats:      newTestPromStats(),
+	}
+	defer c.GCPPubSubFlush()
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.OutputFields["k8s.ns.name"] = "kube-system"
+	f.OutputFields["k8s.pod.name"] = "falco-abcde"
+	require.Nil(t, c.GCPPublishTopic(context.Background(), f))
+
+	messages := srv.Messages()
+	require.Len(t, messages, 1)
+	require.Equal(t, "kube-system", messages[0].OrderingKey)
+	require.Equal(t, map[string]string{"k8s.pod.name": "falco-abcde", "proc.name": "falcosidekick"}, messages[0].Attributes)
+
+	// the ordering key is a template of the fields, the attributes can be the rule and the priority
+	config.GCP.PubSub.OrderingKeyTemplate = template.Must(parseFieldTemplate("", `{{ .Field "k8s.ns.name" }}/{{ .Field "k8s.pod.name" }}`))
+	config.GCP.PubSub.Attributes = []string{"rule", "priority", "k8s.pod.name"}
+	require.Nil(t, c.GCPPublishTopic(context.Background(), f))
+
+	messages = srv.Messages()
+	require.Len(t, messages, 2)
+	require.Equal(t, "kube-system/falco-abcde", messages[1].OrderingKey)
+	require.Equal(t, map[string]string{"rule": "Test rule", "priority": "Debug", "k8s.pod.name": "falco-abcde"}, messages[1].Attributes)
+
+	// the events without the field of the ordering key aren't ordered
+	delete(f.OutputFields, "k8s.ns.name")
+	m, err := newPubSubMessage(f, template.Must(parseFieldTemplate("", `{{ .Field "k8s.ns.name" }}`)), nil)
+	require.Nil(t, err)
+	require.Equal(t, "", m.OrderingKey)
+}
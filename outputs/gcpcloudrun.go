@@ -1,26 +1,27 @@
 package outputs
 
 import (
-	"log"
-
+	"context"
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
 // CloudRunFunctionPost call Cloud Function
-func (c *Client) CloudRunFunctionPost(falcopayload types.FalcoPayload) {
+func (c *Client) CloudRunFunctionPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.GCPCloudRun.Add(Total, 1)
 
-	err := c.Post(falcopayload)
+	err := c.post(ctx, falcopayload, falcopayload)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:gcpcloudrun", "status:error"})
 		c.Stats.GCPCloudRun.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "gcpcloudrun", "status": Error}).Inc()
-		log.Printf("[ERROR] : GCPCloudRun - %v\n", err.Error())
-		return
+		logEventError("GCPCloudRun", falcopayload, err)
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:gcpcloudrun", "status:ok"})
 	c.Stats.GCPCloudRun.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "gcpcloudrun", "status": OK}).Inc()
+
+	return nil
 }
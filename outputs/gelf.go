@@ -0,0 +1,191 @@
+package outputs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-go/statsd"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Transports of the GELF output
+const (
+	GELFUDP string = "udp"
+	GELFTCP string = "tcp"
+)
+
+const (
+	gelfChunkHeaderSize = 12  // magic bytes, message id, sequence number and count
+	gelfMaxChunks       = 128 // above, the servers drop the message
+)
+
+// gelfFieldNameReplacer matches the characters forbidden in the names of the additional fields
+var gelfFieldNameReplacer = regexp.MustCompile(`[^\w.\-]`)
+
+// NewGELFClient returns a new output.Client for sending GELF messages to Graylog
+func NewGELFClient(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics, statsdClient, dogstatsdClient *statsd.Client) (*Client, error) {
+	if _, _, err := net.SplitHostPort(config.GELF.Address); err != nil {
+		log.Printf("[ERROR] : GELF - %v\n", err)
+		return nil, ErrClientCreation
+	}
+
+	return &Client{
+		OutputType:      "GELF",
+		Config:          config,
+		Stats:           stats,
+		PromStats:       promStats,
+		StatsdClient:    statsdClient,
+		DogstatsdClient: dogstatsdClient,
+		gelf:            &connWriter{network: config.GELF.Transport, address: config.GELF.Address},
+	}, nil
+}
+
+// gelfFieldName returns the name of the additional field of an output field, "_id" is reserved
+func gelfFieldName(name string) string {
+	name = "_" + gelfFieldNameReplacer.ReplaceAllString(name, "_")
+	if name == "_id" {
+		return "__id"
+	}
+	return name
+}
+
+// gelfFieldValue returns the value of an additional field, a string or a number, the nested values are JSON encoded
+func gelfFieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string, json.Number, float64, float32, int, int64, int32, uint, uint64, uint32:
+		return v
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// newGELFMessage returns the GELF message of an event, its level is the syslog severity of its priority, its output
+// fields are additional fields, with its rule, priority, source and tags
+func newGELFMessage(falcopayload types.FalcoPayload, hostname string) ([]byte, error) {
+	t := falcopayload.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	if falcopayload.Hostname != "" {
+		hostname = falcopayload.Hostname
+	}
+
+	fields := sortedKeys(falcopayload.OutputFields)
+	full := []string{falcopayload.Output, "", "rule: " + falcopayload.Rule, "priority: " + falcopayload.Priority.String()}
+	for _, i := range fields {
+		full = append(full, fmt.Sprintf("%v: %v", i, falcopayload.OutputFields[i]))
+	}
+
+	m := map[string]interface{}{
+		"version":       "1.1",
+		"host":          hostname,
+		"short_message": falcopayload.Output,
+		"full_message":  strings.Join(full, "\n"),
+		"timestamp":     float64(t.UnixNano()/int64(time.Microsecond)) / 1e6,
+		"level":         syslogSeverity(falcopayload.Priority),
+	}
+	for _, i := range fields {
+		if falcopayload.OutputFields[i] != nil {
+			m[gelfFieldName(i)] = gelfFieldValue(falcopayload.OutputFields[i])
+		}
+	}
+	m["_rule"] = falcopayload.Rule
+	m["_priority"] = falcopayload.Priority.String()
+	if falcopayload.Source != "" {
+		m["_source"] = falcopayload.Source
+	}
+	if len(falcopayload.Tags) != 0 {
+		m["_tags"] = strings.Join(falcopayload.Tags, ",")
+	}
+	return json.Marshal(m)
+}
+
+// gelfChunks splits a message over chunkSize bytes in chunks of chunkSize bytes at most, with the same random id
+func gelfChunks(message []byte, chunkSize int) ([][]byte, error) {
+	if len(message) <= chunkSize {
+		return [][]byte{message}, nil
+	}
+	size := chunkSize - gelfChunkHeaderSize
+	count := (len(message) + size - 1) / size
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("Message of %v bytes is over the %v chunks of %v bytes", len(message), gelfMaxChunks, chunkSize)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(message) {
+			end = len(message)
+		}
+		chunk := append([]byte{0x1e, 0x0f}, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunks = append(chunks, append(chunk, message[i*size:end]...))
+	}
+	return chunks, nil
+}
+
+// GELFPost sends the event as a GELF message, in chunks with UDP if it's over the chunk size, null-delimited with TCP
+func (c *Client) GELFPost(ctx context.Context, falcopayload types.FalcoPayload) error {
+	c.Stats.GELF.Add(Total, 1)
+
+	hostname, _ := os.Hostname()
+	message, err := newGELFMessage(falcopayload, hostname)
+	if err != nil {
+		c.setGELFMetrics(Error)
+		logEventError("GELF", falcopayload, err)
+		return err
+	}
+	c.checkPayloadSize(len(message), falcopayload.Rule)
+
+	var chunks [][]byte
+	if c.gelf.network == GELFUDP {
+		chunks, err = gelfChunks(message, c.config().GELF.ChunkSize)
+	} else {
+		chunks = [][]byte{append(message, 0)}
+	}
+	for i := 0; err == nil && i < len(chunks); i++ {
+		err = c.gelf.write(ctx, chunks[i])
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return c.deadlineExceeded()
+		}
+		c.setGELFMetrics(Error)
+		logEventError("GELF", falcopayload, err)
+		return err
+	}
+	c.setGELFMetrics(OK)
+	log.Printf("[INFO]  : GELF - Send OK\n")
+	return nil
+}
+
+func (c *Client) setGELFMetrics(status string) {
+	go c.CountMetric(Outputs, 1, []string{"output:gelf", "status:" + status})
+	c.Stats.GELF.Add(status, 1)
+	c.PromStats.Outputs.With(map[string]string{"destination": "gelf", "status": status}).Inc()
+}
+
+// GELFClose closes the connection to Graylog at shutdown
+func (c *Client) GELFClose() {
+	if c.gelf != nil {
+		c.gelf.mu.Lock()
+		c.gelf.close()
+		c.gelf.mu.Unlock()
+	}
+}
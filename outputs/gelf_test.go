@@ -0,0 +1,117 @@
+package outputs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestGELFPostUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer conn.Close()
+
+	config := &types.Configuration{}
+	config.GELF.Address = conn.LocalAddr().String()
+	config.GELF.Transport = GELFUDP
+	config.GELF.ChunkSize = 512
+	stats := &types.Statistics{GELF: new(expvar.Map)}
+	client, err := NewGELFClient(config, stats, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	defer client.GELFClose()
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.Priority = types.Critical
+	f.Hostname = "node-1"
+	f.Tags = []string{"filesystem", "mitre_persistence"}
+	f.OutputFields["proc.cmdline"] = strings.Repeat("a", 2000)
+	f.OutputFields["k8s.pod.labels"] = map[string]interface{}{"app": "nginx"}
+	f.OutputFields["id"] = "1234"
+	require.Nil(t, client.GELFPost(context.Background(), f))
+
+	// the chunks have the magic bytes, the id of the message, their sequence number and the count
+	var id []byte
+	var chunks [][]byte
+	for count := 1; len(chunks) < count; {
+		b := make([]byte, 1024)
+		require.Nil(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := conn.ReadFrom(b)
+		require.Nil(t, err)
+		require.LessOrEqual(t, n, config.GELF.ChunkSize)
+		require.Equal(t, []byte{0x1e, 0x0f}, b[:2])
+		if id == nil {
+			id, count = b[2:10], int(b[11])
+			chunks = make([][]byte, 0, count)
+		}
+		require.Equal(t, id, b[2:10])
+		require.Equal(t, len(chunks), int(b[10]))
+		chunks = append(chunks, b[12:n])
+	}
+	require.Greater(t, len(chunks), 1)
+
+	var m map[string]interface{}
+	require.Nil(t, json.Unmarshal(bytes.Join(chunks, nil), &m))
+	require.Equal(t, "1.1", m["version"])
+	require.Equal(t, "node-1", m["host"])
+	require.Equal(t, f.Output, m["short_message"])
+	require.True(t, strings.HasPrefix(m["full_message"].(string), f.Output+"\n"))
+	require.Contains(t, m["full_message"], "proc.name: falcosidekick")
+	require.Equal(t, float64(2), m["level"])
+	require.Equal(t, float64(f.Time.Unix()), m["timestamp"])
+	require.Equal(t, "Test rule", m["_rule"])
+	require.Equal(t, "Critical", m["_priority"])
+	require.Equal(t, "filesystem,mitre_persistence", m["_tags"])
+	require.Equal(t, "falcosidekick", m["_proc.name"])
+	require.Equal(t, `{"app":"nginx"}`, m["_k8s.pod.labels"])
+	require.Equal(t, "1234", m["__id"])
+	require.NotContains(t, m, "_id")
+	require.Equal(t, "1", stats.GELF.Get(OK).String())
+
+	// the messages over the maximum number of chunks fail
+	f.OutputFields["proc.cmdline"] = strings.Repeat("a", 128*512)
+	require.NotNil(t, client.GELFPost(context.Background(), f))
+}
+
+func TestGELFPostTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer l.Close()
+
+	config := &types.Configuration{}
+	config.GELF.Address = l.Addr().String()
+	config.GELF.Transport = GELFTCP
+	client, err := NewGELFClient(config, &types.Statistics{GELF: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	defer client.GELFClose()
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.Priority = types.Debug
+	require.Nil(t, client.GELFPost(context.Background(), f))
+	require.Nil(t, client.GELFPost(context.Background(), f))
+
+	conn, err := l.Accept()
+	require.Nil(t, err)
+	defer conn.Close()
+	require.Nil(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	r := bufio.NewReader(conn)
+	// the messages are null-delimited
+	for i := 0; i < 2; i++ {
+		b, err := r.ReadBytes(0)
+		require.Nil(t, err)
+		var m map[string]interface{}
+		require.Nil(t, json.Unmarshal(b[:len(b)-1], &m))
+		require.Equal(t, float64(7), m["level"])
+	}
+}
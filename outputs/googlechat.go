@@ -1,8 +1,11 @@
 package outputs
 
 import (
-	"bytes"
+	"context"
+	"html"
 	"log"
+	"net/url"
+	"unicode/utf8"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
@@ -30,27 +33,112 @@ type card struct {
 	Sections []section `json:"sections,omitempty"`
 }
 
+type googlechatThread struct {
+	ThreadKey string `json:"threadKey"`
+}
+
 type googlechatPayload struct {
-	Text  string `json:"text,omitempty"`
-	Cards []card `json:"cards,omitempty"`
+	Text   string            `json:"text,omitempty"`
+	Cards  []card            `json:"cards,omitempty"`
+	Thread *googlechatThread `json:"thread,omitempty"`
 }
 
-func newGooglechatPayload(falcopayload types.FalcoPayload, config *types.Configuration) googlechatPayload {
-	var messageText string
-	widgets := []widget{}
+// googlechatMaxValueLength is the length in runes above which the values are truncated in the cards v2
+const googlechatMaxValueLength int = 500
+
+type googlechatCardV2Header struct {
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+}
+
+type googlechatDecoratedText struct {
+	TopLabel string `json:"topLabel,omitempty"`
+	Text     string `json:"text"`
+	WrapText bool   `json:"wrapText,omitempty"`
+}
+
+type googlechatTextParagraph struct {
+	Text string `json:"text"`
+}
+
+type googlechatWidgetV2 struct {
+	DecoratedText *googlechatDecoratedText `json:"decoratedText,omitempty"`
+	TextParagraph *googlechatTextParagraph `json:"textParagraph,omitempty"`
+}
+
+type googlechatSectionV2 struct {
+	Header  string               `json:"header,omitempty"`
+	Widgets []googlechatWidgetV2 `json:"widgets"`
+}
+
+type googlechatCardV2 struct {
+	Header   googlechatCardV2Header `json:"header"`
+	Sections []googlechatSectionV2  `json:"sections"`
+}
+
+type googlechatCardV2Item struct {
+	CardID string           `json:"cardId"`
+	Card   googlechatCardV2 `json:"card"`
+}
+
+type googlechatCardsV2Payload struct {
+	Text    string                 `json:"text,omitempty"`
+	CardsV2 []googlechatCardV2Item `json:"cardsV2,omitempty"`
+	Thread  *googlechatThread      `json:"thread,omitempty"`
+}
+
+// GooglechatWebhookURL returns the URL of the webhook, with the option replying in the thread of the key of the
+// events (ThreadKey) or in the one of the threadKey of the URL, and starting it if it doesn't exist
+func GooglechatWebhookURL(config types.GooglechatConfig) string {
+	u, err := url.Parse(config.WebhookURL)
+	if err != nil {
+		return config.WebhookURL
+	}
+	q := u.Query()
+	if (config.ThreadKey == "" && q.Get("threadKey") == "") || q.Get("messageReplyOption") != "" {
+		return config.WebhookURL
+	}
+	q.Set("messageReplyOption", "REPLY_MESSAGE_FALLBACK_TO_NEW_THREAD")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
 
-	if config.Googlechat.MessageFormatTemplate != nil {
-		buf := &bytes.Buffer{}
-		if err := config.Googlechat.MessageFormatTemplate.Execute(buf, falcopayload); err != nil {
+// newGooglechatText returns the text of the message, expanded from the message format
+func newGooglechatText(falcopayload types.FalcoPayload, config *types.Configuration) string {
+	if t := getMessageFormatTemplate(falcopayload, config.Googlechat.MessageFormatField, config.Googlechat.MessageFormatTemplates, config.Googlechat.MessageFormatTemplate); t != nil {
+		if m, err := executeMessageFormat(t, falcopayload); err != nil {
 			log.Printf("[ERROR] : GoogleChat - Error expanding Google Chat message %v", err)
 		} else {
-			messageText = buf.String()
+			return m
 		}
 	}
+	return ""
+}
+
+// newGooglechatThread returns the thread of the event, nil without ThreadKey
+func newGooglechatThread(falcopayload types.FalcoPayload, config *types.Configuration) *googlechatThread {
+	if config.Googlechat.ThreadKeyTemplate == nil {
+		return nil
+	}
+	k, err := executeMessageFormat(config.Googlechat.ThreadKeyTemplate, falcopayload)
+	if err != nil {
+		log.Printf("[ERROR] : GoogleChat - Error expanding Google Chat thread key %v", err)
+		return nil
+	}
+	if k == "" {
+		return nil
+	}
+	return &googlechatThread{ThreadKey: k}
+}
+
+func newGooglechatPayload(falcopayload types.FalcoPayload, config *types.Configuration) googlechatPayload {
+	widgets := []widget{}
+	messageText := newGooglechatText(falcopayload, config)
 
 	if config.Googlechat.OutputFormat == Text {
 		return googlechatPayload{
-			Text: messageText,
+			Text:   messageText,
+			Thread: newGooglechatThread(falcopayload, config),
 		}
 	}
 
@@ -77,23 +165,104 @@ func newGooglechatPayload(falcopayload types.FalcoPayload, config *types.Configu
 				},
 			},
 		},
+		Thread: newGooglechatThread(falcopayload, config),
+	}
+}
+
+// googlechatPriorityIcon returns the icon of the priority in the header of the cards v2
+func googlechatPriorityIcon(priority types.PriorityType) string {
+	switch priority {
+	case types.Emergency, types.Alert, types.Critical:
+		return "🔴"
+	case types.Error:
+		return "🟠"
+	case types.Warning:
+		return "🟡"
+	case types.Notice:
+		return "🟢"
+	case types.Informational:
+		return "🔵"
+	default:
+		return "⚪"
+	}
+}
+
+// truncateGooglechatValue truncates the values too long to be rendered with an ellipsis
+func truncateGooglechatValue(value string) string {
+	if utf8.RuneCountInString(value) <= googlechatMaxValueLength {
+		return value
+	}
+	return string([]rune(value)[:googlechatMaxValueLength-1]) + "…"
+}
+
+// newGooglechatCardsV2Payload returns the cards v2 message of the event, the rule and the priority in the header, the
+// output and the output fields in the sections
+func newGooglechatCardsV2Payload(falcopayload types.FalcoPayload, config *types.Configuration) googlechatCardsV2Payload {
+	messageText := newGooglechatText(falcopayload, config)
+	thread := newGooglechatThread(falcopayload, config)
+
+	if config.Googlechat.OutputFormat == Text {
+		return googlechatCardsV2Payload{
+			Text:   messageText,
+			Thread: thread,
+		}
+	}
+
+	sections := []googlechatSectionV2{
+		{Widgets: []googlechatWidgetV2{{TextParagraph: &googlechatTextParagraph{Text: html.EscapeString(truncateGooglechatValue(falcopayload.Output))}}}},
+	}
+
+	widgets := []googlechatWidgetV2{}
+	for _, i := range getSortedStringKeys(falcopayload.OutputFields) {
+		v, ok := falcopayload.OutputFields[i].(string)
+		// no blank rows for the empty fields
+		if !ok || v == "" {
+			continue
+		}
+		widgets = append(widgets, googlechatWidgetV2{DecoratedText: &googlechatDecoratedText{TopLabel: i, Text: html.EscapeString(truncateGooglechatValue(v)), WrapText: true}})
+	}
+	widgets = append(widgets, googlechatWidgetV2{DecoratedText: &googlechatDecoratedText{TopLabel: "time", Text: falcopayload.Time.String()}})
+	sections = append(sections, googlechatSectionV2{Header: "Fields", Widgets: widgets})
+
+	return googlechatCardsV2Payload{
+		Text: messageText,
+		CardsV2: []googlechatCardV2Item{
+			{
+				CardID: "falco",
+				Card: googlechatCardV2{
+					Header: googlechatCardV2Header{
+						Title:    truncateGooglechatValue(falcopayload.Rule),
+						Subtitle: googlechatPriorityIcon(falcopayload.Priority) + " " + falcopayload.Priority.String(),
+					},
+					Sections: sections,
+				},
+			},
+		},
+		Thread: thread,
 	}
 }
 
 // GooglechatPost posts event to Google Chat
-func (c *Client) GooglechatPost(falcopayload types.FalcoPayload) {
+func (c *Client) GooglechatPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.GoogleChat.Add(Total, 1)
 
-	err := c.Post(newGooglechatPayload(falcopayload, c.Config))
+	var err error
+	if c.config().Googlechat.UseCardsV2 {
+		err = c.post(ctx, newGooglechatCardsV2Payload(falcopayload, c.config()), falcopayload)
+	} else {
+		err = c.post(ctx, newGooglechatPayload(falcopayload, c.config()), falcopayload)
+	}
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:googlechat", "status:error"})
 		c.Stats.GoogleChat.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "googlechat", "status": Error}).Inc()
-		log.Printf("[ERROR] : GoogleChat - %v\n", err)
-		return
+		logEventError("GoogleChat", falcopayload, err)
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:googlechat", "status:ok"})
 	c.Stats.GoogleChat.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "googlechat", "status": OK}).Inc()
+
+	return nil
 }
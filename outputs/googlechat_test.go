@@ -1,7 +1,13 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
+	"expvar"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"text/template"
 
@@ -62,3 +68,39 @@ func TestNewGoogleChatPayload(t *testing.T) {
 
 	require.Equal(t, output, expectedOutput)
 }
+
+func TestGooglechatPostCardsV2(t *testing.T) {
+	var body []byte
+	var query map[string][]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		query = r.URL.Query()
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Googlechat.WebhookURL = ts.URL + "?key=abc"
+	config.Googlechat.UseCardsV2 = true
+	config.Googlechat.ThreadKey = "{{ .Rule }}-{{ .Field \"container.name\" }}"
+	var err error
+	config.Googlechat.ThreadKeyTemplate, err = template.New("").Funcs(MessageFormatFuncs).Parse(config.Googlechat.ThreadKey)
+	require.Nil(t, err)
+	client, err := NewClient("Googlechat", GooglechatWebhookURL(config.Googlechat), false, false, config, &types.Statistics{GoogleChat: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(`{"output":"Shell spawned in a container (user=root container=nginx)","priority":"Critical","rule":"Terminal shell in container","time":"2001-01-01T01:10:00Z","output_fields":{"user.name":"root","container.name":"nginx","k8s.ns.name":"","proc.cmdline":"`+strings.Repeat("a", 600)+`"}}`), &f))
+	require.Nil(t, client.GooglechatPost(context.Background(), f))
+	require.Equal(t, []string{"abc"}, query["key"])
+	require.Equal(t, []string{"REPLY_MESSAGE_FALLBACK_TO_NEW_THREAD"}, query["messageReplyOption"])
+
+	golden, err := ioutil.ReadFile("testdata/googlechat_cardsv2.json")
+	require.Nil(t, err)
+	require.JSONEq(t, string(golden), string(body))
+}
+
+func TestGooglechatWebhookURL(t *testing.T) {
+	require.Equal(t, "https://chat.googleapis.com/v1/spaces/A/messages?key=k", GooglechatWebhookURL(types.GooglechatConfig{WebhookURL: "https://chat.googleapis.com/v1/spaces/A/messages?key=k"}))
+	// the thread key of the URL is honored
+	require.Equal(t, "https://chat.googleapis.com/v1/spaces/A/messages?key=k&messageReplyOption=REPLY_MESSAGE_FALLBACK_TO_NEW_THREAD&threadKey=t", GooglechatWebhookURL(types.GooglechatConfig{WebhookURL: "https://chat.googleapis.com/v1/spaces/A/messages?key=k&threadKey=t"}))
+}
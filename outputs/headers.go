@@ -0,0 +1,52 @@
+package outputs
+
+import (
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// headerReplacer removes the line breaks of the values of the fields, a line break would end the header
+var headerReplacer = strings.NewReplacer("\r", "", "\n", " ")
+
+// headerTemplates caches the templates of the values of the custom headers, by value, as the secret files can
+// replace them on reload
+var headerTemplates sync.Map
+
+// customHeaders returns the custom headers configured for the output, they're read at each request as the secret
+// files replace the map on reload
+func customHeaders(config *types.Configuration, outputType string) map[string]string {
+	switch outputType {
+	case "Webhook":
+		return config.Webhook.CustomHeaders
+	case "Elasticsearch":
+		return config.Elasticsearch.CustomHeaders
+	case "Loki":
+		return config.Loki.CustomHeaders
+	}
+	return nil
+}
+
+// expandCustomHeader returns the value of a custom header, a template of the fields of the event (ex: falco:{{ .Field
+// "k8s.ns.name" }}), the missing fields are empty, as for the requests of several events (ex: Elasticsearch bulk). A
+// value which isn't a valid template is sent as is.
+func expandCustomHeader(value string, falcopayload types.FalcoPayload) string {
+	if !strings.Contains(value, "{{") {
+		return value
+	}
+	t, ok := headerTemplates.Load(value)
+	if !ok {
+		p, err := parseFieldTemplate("header", value)
+		if err != nil {
+			return value
+		}
+		t, _ = headerTemplates.LoadOrStore(value, p)
+	}
+	v, err := executeFieldTemplate(t.(*template.Template), falcopayload, headerReplacer)
+	if err != nil {
+		return ""
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+package outputs
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+type hedgedResponse struct {
+	resp   *http.Response
+	err    error
+	cancel context.CancelFunc
+}
+
+// discard closes the body of a response not used and cancels its request
+func (r *hedgedResponse) discard() {
+	if r == nil {
+		return
+	}
+	if r.resp != nil {
+		r.resp.Body.Close()
+	}
+	r.cancel()
+}
+
+// cancelOnClose cancels the context of a response once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// do sends the request, if HedgeDelay is set and the output hasn't responded within it, a second request is sent,
+// the first response which isn't an error (connection error or 5xx) is used and the other request is cancelled
+func (c *Client) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	if c.HedgeDelay <= 0 || req.GetBody == nil {
+		return client.Do(req)
+	}
+
+	results := make(chan *hedgedResponse, 2)
+	var requests []*hedgedResponse
+	send := func(r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		h := &hedgedResponse{cancel: cancel}
+		requests = append(requests, h)
+		go func() {
+			h.resp, h.err = client.Do(r.WithContext(ctx))
+			results <- h
+		}()
+	}
+
+	send(req)
+	timer := time.NewTimer(c.HedgeDelay)
+	defer timer.Stop()
+	hedge := timer.C
+	var failed *hedgedResponse
+	for pending := 1; pending > 0; {
+		select {
+		case <-hedge:
+			hedge = nil
+			body, err := req.GetBody()
+			if err != nil {
+				continue
+			}
+			r := req.Clone(req.Context())
+			r.Body = body
+			if c.config().Debug {
+				log.Printf("[DEBUG] : %v - No response after %v, hedged request sent\n", c.OutputType, c.HedgeDelay)
+			}
+			send(r)
+			pending++
+		case r := <-results:
+			// if the first request fails before the delay, no hedged request is sent
+			pending--
+			if r.err != nil || r.resp.StatusCode >= http.StatusInternalServerError {
+				failed.discard()
+				failed = r
+				continue
+			}
+			for _, i := range requests {
+				if i != r {
+					i.cancel()
+				}
+			}
+			failed.discard()
+			go discardHedgedResponses(results, pending)
+			r.resp.Body = cancelOnClose{ReadCloser: r.resp.Body, cancel: r.cancel}
+			return r.resp, nil
+		}
+	}
+	if failed.err != nil {
+		failed.cancel()
+		return nil, failed.err
+	}
+	failed.resp.Body = cancelOnClose{ReadCloser: failed.resp.Body, cancel: failed.cancel}
+	return failed.resp, nil
+}
+
+func discardHedgedResponses(results chan *hedgedResponse, pending int) {
+	for ; pending > 0; pending-- {
+		(<-results).discard()
+	}
+}
@@ -1,7 +1,9 @@
 package outputs
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"strconv"
 	"strings"
 
 	"github.com/falcosecurity/falcosidekick/types"
@@ -9,6 +11,13 @@ import (
 
 type influxdbPayload string
 
+// Escaping of the line protocol
+var (
+	influxdbMeasurementReplacer = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	influxdbTagReplacer         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+	influxdbStringReplacer      = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
 func newInfluxdbPayload(falcopayload types.FalcoPayload, config *types.Configuration) influxdbPayload {
 	s := "events,rule=" + strings.Replace(falcopayload.Rule, " ", "_", -1) + ",priority=" + falcopayload.Priority.String()
 
@@ -26,21 +35,62 @@ func newInfluxdbPayload(falcopayload types.FalcoPayload, config *types.Configura
 	return influxdbPayload(s)
 }
 
+// newInfluxdbV2Payload returns the point of the event in line protocol for the API v2, its measurement is the rule, its
+// tags are the fields of tags (rule, priority, source, hostname or output fields), its fields are the output and the
+// numeric output fields, as floats to keep the type of a field across the points, and its timestamp is the time of the event in nanoseconds
+func newInfluxdbV2Payload(falcopayload types.FalcoPayload, tags []string) influxdbPayload {
+	s := influxdbMeasurementReplacer.Replace(falcopayload.Rule)
+	for _, i := range tags {
+		if v, ok := FieldValue(falcopayload, i); ok && v != "" {
+			s += "," + influxdbTagReplacer.Replace(i) + "=" + influxdbTagReplacer.Replace(v)
+		}
+	}
+
+	s += " output=\"" + influxdbStringReplacer.Replace(falcopayload.Output) + "\""
+	for _, i := range sortedKeys(falcopayload.OutputFields) {
+		var v string
+		switch n := falcopayload.OutputFields[i].(type) {
+		case json.Number:
+			v = n.String()
+		case float64:
+			v = strconv.FormatFloat(n, 'f', -1, 64)
+		case int:
+			v = strconv.Itoa(n)
+		case int64:
+			v = strconv.FormatInt(n, 10)
+		default:
+			continue
+		}
+		s += "," + influxdbTagReplacer.Replace(i) + "=" + v
+	}
+
+	if !falcopayload.Time.IsZero() {
+		s += " " + strconv.FormatInt(falcopayload.Time.UnixNano(), 10)
+	}
+	return influxdbPayload(s)
+}
+
 // InfluxdbPost posts event to InfluxDB
-func (c *Client) InfluxdbPost(falcopayload types.FalcoPayload) {
+func (c *Client) InfluxdbPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Influxdb.Add(Total, 1)
 
-	err := c.Post(newInfluxdbPayload(falcopayload, c.Config))
+	payload := newInfluxdbPayload(falcopayload, c.config())
+	if c.config().Influxdb.Token != "" {
+		payload = newInfluxdbV2Payload(falcopayload, c.config().Influxdb.Tags)
+	}
+	err := c.post(ctx, payload, falcopayload)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:influxdb", "status:error"})
 		c.Stats.Influxdb.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "influxdb", "status": Error}).Inc()
-		log.Printf("[ERROR] : InfluxDB - %v\n", err)
-		return
+		logEventError("InfluxDB", falcopayload, err)
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:influxdb", "status:ok"})
 	c.Stats.Influxdb.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "influxdb", "status": OK}).Inc()
+
+	return nil
 }
@@ -2,6 +2,7 @@ package outputs
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -20,3 +21,21 @@ func TestNewInfluxdbPayload(t *testing.T) {
 
 	require.Equal(t, string(influxdbPayload), expectedOutput)
 }
+
+func TestNewInfluxdbV2Payload(t *testing.T) {
+	var f types.FalcoPayload
+	d := json.NewDecoder(strings.NewReader(falcoTestInput))
+	d.UseNumber()
+	require.Nil(t, d.Decode(&f))
+	f.Hostname = "node 1"
+	f.OutputFields["k8s.ns.name"] = "kube-system,prod=1"
+	f.OutputFields["evt.num"] = float64(42.5)
+	f.Output = `Test "quoted" \ output`
+
+	payload := newInfluxdbV2Payload(f, []string{"priority", "hostname", "k8s.ns.name", "source"})
+
+	// the spaces, commas and equal signs are escaped, the output fields which aren't numbers aren't fields, the
+	// timestamp is the time of the event in nanoseconds
+	require.Equal(t, influxdbPayload(`Test\ rule,priority=Debug,hostname=node\ 1,k8s.ns.name=kube-system\,prod\=1 output="Test \"quoted\" \\ output",evt.num=42.5,proc.tty=1234 978311400000000000`), payload)
+	require.Equal(t, int64(978311400000000000), f.Time.UnixNano())
+}
@@ -0,0 +1,132 @@
+package outputs
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Inventory describes a running falcosidekick, for fleet management
+type Inventory struct {
+	Version   string          `json:"version"`
+	Commit    string          `json:"commit"`
+	StartTime time.Time       `json:"start_time"`
+	Outputs   []OutputSummary `json:"outputs"`
+}
+
+// OutputSummary is an enabled output with the non secret part of its configuration
+type OutputSummary struct {
+	Name   string                 `json:"name"`
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// inventoryStringFields are the only string settings of the outputs exposed, others can contain secrets (keys, tokens, URLs with credentials, etc)
+var inventoryStringFields = map[string]bool{
+	"MinimumPriority":      true,
+	"OutputFormat":         true,
+	"Mode":                 true,
+	"Suffix":               true,
+	"RequiredFieldsAction": true,
+	"RetryAfterPolicy":     true,
+	"MaxRate":              true,
+	"RateLimitMode":        true,
+	"SchemaVersion":        true,
+	"PriorityCase":         true,
+}
+
+// outputConfigs returns the configuration of an output by its name in EnabledOutputs
+var outputConfigs = map[string]func(*types.Configuration) interface{}{
+	"StatsD":            func(c *types.Configuration) interface{} { return c.Statsd },
+	"DogStatsD":         func(c *types.Configuration) interface{} { return c.Dogstatsd },
+	"Slack":             func(c *types.Configuration) interface{} { return c.Slack },
+	"Rocketchat":        func(c *types.Configuration) interface{} { return c.Rocketchat },
+	"Mattermost":        func(c *types.Configuration) interface{} { return c.Mattermost },
+	"Teams":             func(c *types.Configuration) interface{} { return c.Teams },
+	"Datadog":           func(c *types.Configuration) interface{} { return c.Datadog },
+	"Discord":           func(c *types.Configuration) interface{} { return c.Discord },
+	"AlertManager":      func(c *types.Configuration) interface{} { return c.Alertmanager },
+	"Elasticsearch":     func(c *types.Configuration) interface{} { return c.Elasticsearch },
+	"Loki":              func(c *types.Configuration) interface{} { return c.Loki },
+	"NATS":              func(c *types.Configuration) interface{} { return c.Nats },
+	"STAN":              func(c *types.Configuration) interface{} { return c.Stan },
+	"Influxdb":          func(c *types.Configuration) interface{} { return c.Influxdb },
+	"AWSLambda":         func(c *types.Configuration) interface{} { return c.AWS.Lambda },
+	"AWSSQS":            func(c *types.Configuration) interface{} { return c.AWS.SQS },
+	"AWSKinesis":        func(c *types.Configuration) interface{} { return c.AWS.Kinesis },
+	"AWSSNS":            func(c *types.Configuration) interface{} { return c.AWS.SNS },
+	"AWSCloudWatchLogs": func(c *types.Configuration) interface{} { return c.AWS.CloudWatchLogs },
+	"AWSS3":             func(c *types.Configuration) interface{} { return c.AWS.S3 },
+	"SMTP":              func(c *types.Configuration) interface{} { return c.SMTP },
+	"Opsgenie":          func(c *types.Configuration) interface{} { return c.Opsgenie },
+	"Webhook":           func(c *types.Configuration) interface{} { return c.Webhook },
+	"Tenants":           func(c *types.Configuration) interface{} { return c.Tenants },
+	"WebSocket":         func(c *types.Configuration) interface{} { return c.WebSocket },
+	"Fifo":              func(c *types.Configuration) interface{} { return c.Fifo },
+	"CloudEvents":       func(c *types.Configuration) interface{} { return c.CloudEvents },
+	"EventHub":          func(c *types.Configuration) interface{} { return c.Azure.EventHub },
+	"AzureBlob":         func(c *types.Configuration) interface{} { return c.Azure.Blob },
+	"GCPPubSub":         func(c *types.Configuration) interface{} { return c.GCP.PubSub },
+	"GCPStorage":        func(c *types.Configuration) interface{} { return c.GCP.Storage },
+	"GCPCloudFunctions": func(c *types.Configuration) interface{} { return c.GCP.CloudFunctions },
+	"GCPCloudRun":       func(c *types.Configuration) interface{} { return c.GCP.CloudRun },
+	"Google Chat":       func(c *types.Configuration) interface{} { return c.Googlechat },
+	"Kafka":             func(c *types.Configuration) interface{} { return c.Kafka },
+	"Pagerduty":         func(c *types.Configuration) interface{} { return c.Pagerduty },
+	"Kubeless":          func(c *types.Configuration) interface{} { return c.Kubeless },
+	"WebUI":             func(c *types.Configuration) interface{} { return c.WebUI },
+	"OpenFaaS":          func(c *types.Configuration) interface{} { return c.Openfaas },
+	"RabbitMQ":          func(c *types.Configuration) interface{} { return c.Rabbitmq },
+	"Redis":             func(c *types.Configuration) interface{} { return c.Redis },
+	"Syslog":            func(c *types.Configuration) interface{} { return c.Syslog },
+	"GELF":              func(c *types.Configuration) interface{} { return c.GELF },
+	"Wavefront":         func(c *types.Configuration) interface{} { return c.Wavefront },
+}
+
+// NewInventory returns the Inventory of the enabled outputs
+func NewInventory(config *types.Configuration, version, commit string, startTime time.Time) Inventory {
+	inventory := Inventory{Version: version, Commit: commit, StartTime: startTime, Outputs: []OutputSummary{}}
+	for _, i := range EnabledOutputs {
+		s := OutputSummary{Name: i}
+		if f, ok := outputConfigs[i]; ok {
+			s.Config = configSummary(f(config))
+		}
+		inventory.Outputs = append(inventory.Outputs, s)
+	}
+	return inventory
+}
+
+// configSummary returns the booleans, the numbers and the allowed strings of a configuration
+func configSummary(config interface{}) map[string]interface{} {
+	summary := make(map[string]interface{})
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Struct {
+		return summary
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		switch v.Field(i).Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+			summary[f.Name] = v.Field(i).Interface()
+		case reflect.String:
+			if inventoryStringFields[f.Name] {
+				summary[f.Name] = v.Field(i).Interface()
+			}
+		}
+	}
+	return summary
+}
+
+// InventoryHandler returns the handler of the endpoint returning the Inventory
+func InventoryHandler(config *types.Configuration, version, commit string, startTime time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		// #nosec G104 nothing to be done if the following fails
+		json.NewEncoder(w).Encode(NewInventory(config, version, commit, startTime))
+	}
+}
@@ -0,0 +1,45 @@
+package outputs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestInventoryHandler(t *testing.T) {
+	enabled := EnabledOutputs
+	EnabledOutputs = []string{"Slack", "Opsgenie"}
+	defer func() { EnabledOutputs = enabled }()
+
+	config := &types.Configuration{
+		Slack: types.SlackOutputConfig{WebhookURL: "https://hooks.slack.com/services/XXXX/YYYY/ZZZZ", MinimumPriority: "warning", CheckCert: true},
+	}
+	config.Opsgenie.APIKey = "secret-api-key"
+
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	rec := httptest.NewRecorder()
+	InventoryHandler(config, "2.23.0", "abcdef1", start)(rec, httptest.NewRequest(http.MethodGet, "/info", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body := rec.Body.String()
+	require.False(t, strings.Contains(body, "hooks.slack.com"))
+	require.False(t, strings.Contains(body, "secret-api-key"))
+
+	var inventory Inventory
+	require.Nil(t, json.Unmarshal([]byte(body), &inventory))
+	require.Equal(t, "2.23.0", inventory.Version)
+	require.Equal(t, "abcdef1", inventory.Commit)
+	require.Equal(t, start, inventory.StartTime)
+	require.Len(t, inventory.Outputs, 2)
+	require.Equal(t, "Slack", inventory.Outputs[0].Name)
+	require.Equal(t, "warning", inventory.Outputs[0].Config["MinimumPriority"])
+	require.Equal(t, true, inventory.Outputs[0].Config["CheckCert"])
+	require.Equal(t, "Opsgenie", inventory.Outputs[1].Name)
+}
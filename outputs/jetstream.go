@@ -0,0 +1,151 @@
+package outputs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-go/statsd"
+	nats "github.com/nats-io/nats.go"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// NewJetStreamClient returns a new output.Client for publishing to a NATS JetStream stream, the stream must exist
+func NewJetStreamClient(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics, statsdClient, dogstatsdClient *statsd.Client) (*Client, error) {
+	options := []nats.Option{nats.Name("falcosidekick"), nats.MaxReconnects(config.JetStream.MaxReconnects)}
+	if config.JetStream.CredsFile != "" {
+		options = append(options, nats.UserCredentials(config.JetStream.CredsFile))
+	}
+	if config.JetStream.MutualTLS || strings.HasPrefix(config.JetStream.HostPort, "tls://") {
+		tlsConfig, err := newConnTLSConfig(config, config.JetStream.MutualTLS, config.JetStream.CheckCert)
+		if err != nil {
+			log.Printf("[ERROR] : JetStream - %v\n", err)
+			return nil, err
+		}
+		options = append(options, nats.Secure(tlsConfig))
+	}
+
+	nc, err := nats.Connect(config.JetStream.HostPort, options...)
+	if err != nil {
+		log.Printf("[ERROR] : JetStream - %v\n", err)
+		return nil, err
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		log.Printf("[ERROR] : JetStream - %v\n", err)
+		return nil, err
+	}
+	if _, err := js.StreamInfo(config.JetStream.Stream); err != nil {
+		nc.Close()
+		err = fmt.Errorf("Stream '%v' can't be used : %v", config.JetStream.Stream, err)
+		log.Printf("[ERROR] : JetStream - %v\n", err)
+		return nil, err
+	}
+
+	return &Client{
+		OutputType:        "JetStream",
+		Config:            config,
+		NatsConn:          nc,
+		JetStreamContext:  js,
+		Stats:             stats,
+		PromStats:         promStats,
+		StatsdClient:      statsdClient,
+		DogstatsdClient:   dogstatsdClient,
+		RetryMaxAttempts:  config.Retry.MaxAttempts,
+		RetryInitialDelay: time.Duration(config.Retry.InitialDelay) * time.Millisecond,
+		RetryMaxDelay:     time.Duration(config.Retry.MaxDelay) * time.Millisecond,
+	}, nil
+}
+
+// newConnTLSConfig returns the TLS configuration of the connection of an output (JetStream, MQTT), with the client
+// certificate if mutualTLS is set
+func newConnTLSConfig(config *types.Configuration, mutualTLS, checkCert bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if !mutualTLS {
+		// #nosec G402 the certificate is checked unless checkCert is false
+		tlsConfig.InsecureSkipVerify = !checkCert
+		return tlsConfig, nil
+	}
+	cert, err := tls.LoadX509KeyPair(config.MutualTLSFilesPath+MutualTLSClientCertFilename, config.MutualTLSFilesPath+MutualTLSClientKeyFilename)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := ioutil.ReadFile(config.MutualTLSFilesPath + MutualTLSCacertFilename)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caCert)
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
+
+// JetStreamPublish publishes the event to the subject and waits for the ack of the stream, an ack not received within
+// AckTimeout is a failure, the failed publications are retried with an exponential backoff
+func (c *Client) JetStreamPublish(ctx context.Context, falcopayload types.FalcoPayload) error {
+	c.Stats.JetStream.Add(Total, 1)
+
+	payload, err := json.Marshal(falcopayload)
+	if err != nil {
+		c.setJetStreamErrorMetrics()
+		log.Printf("[ERROR] : JetStream - %v\n", err)
+		return err
+	}
+
+	var ack *nats.PubAck
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			c.countRetry()
+			if sleepContext(ctx, c.exponentialBackoff(attempt-1)) != nil {
+				return c.deadlineExceeded()
+			}
+		}
+		ackCtx, cancel := context.WithTimeout(ctx, time.Duration(c.config().JetStream.AckTimeout)*time.Millisecond)
+		ack, err = c.JetStreamContext.Publish(c.config().JetStream.Subject, payload, nats.Context(ackCtx), nats.ExpectStream(c.config().JetStream.Stream))
+		cancel()
+		if err == nil || ctx.Err() != nil || attempt+1 >= c.RetryMaxAttempts {
+			break
+		}
+		log.Printf("[WARN]  : JetStream - %v, retrying\n", err)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return c.deadlineExceeded()
+	}
+	if err != nil {
+		c.setJetStreamErrorMetrics()
+		log.Printf("[ERROR] : JetStream - %v\n", err)
+		return err
+	}
+
+	go c.CountMetric("outputs", 1, []string{"output:jetstream", "status:ok"})
+	c.Stats.JetStream.Add(OK, 1)
+	c.PromStats.Outputs.With(map[string]string{"destination": "jetstream", "status": OK}).Inc()
+	log.Printf("[INFO]  : JetStream - Publish OK (stream %v, sequence %v)\n", ack.Stream, ack.Sequence)
+
+	return nil
+}
+
+// JetStreamClose drains the connection at shutdown, the publications in progress get their ack
+func (c *Client) JetStreamClose() {
+	if c.NatsConn != nil {
+		if err := c.NatsConn.Drain(); err != nil {
+			log.Printf("[ERROR] : JetStream - %v\n", err)
+		}
+	}
+}
+
+// setJetStreamErrorMetrics set the error stats
+func (c *Client) setJetStreamErrorMetrics() {
+	go c.CountMetric(Outputs, 1, []string{"output:jetstream", "status:error"})
+	c.Stats.JetStream.Add(Error, 1)
+	c.PromStats.Outputs.With(map[string]string{"destination": "jetstream", "status": Error}).Inc()
+}
@@ -0,0 +1,64 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	nats "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestJetStreamPublish(t *testing.T) {
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir(), NoLog: true, NoSigs: true})
+	require.Nil(t, err)
+	go s.Start()
+	defer s.Shutdown()
+	require.True(t, s.ReadyForConnections(10*time.Second))
+
+	config := &types.Configuration{}
+	config.JetStream.HostPort = s.ClientURL()
+	config.JetStream.Subject = "falco.events"
+	config.JetStream.Stream = "FALCO"
+	config.JetStream.AckTimeout = 1000
+	config.Retry.MaxAttempts = 1
+	stats := &types.Statistics{JetStream: new(expvar.Map)}
+	promStats := newTestPromStats()
+
+	// the stream doesn't exist yet
+	_, err = NewJetStreamClient(config, stats, promStats, nil, nil)
+	require.NotNil(t, err)
+
+	nc, err := nats.Connect(s.ClientURL())
+	require.Nil(t, err)
+	defer nc.Close()
+	js, err := nc.JetStream()
+	require.Nil(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "FALCO", Subjects: []string{"falco.>"}})
+	require.Nil(t, err)
+
+	client, err := NewJetStreamClient(config, stats, promStats, nil, nil)
+	require.Nil(t, err)
+	defer client.JetStreamClose()
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	require.Nil(t, client.JetStreamPublish(context.Background(), f))
+
+	m, err := js.GetMsg("FALCO", 1)
+	require.Nil(t, err)
+	require.Equal(t, "falco.events", m.Subject)
+	var received types.FalcoPayload
+	require.Nil(t, json.Unmarshal(m.Data, &received))
+	require.Equal(t, f.Rule, received.Rule)
+	require.Equal(t, f.Output, received.Output)
+
+	// without a stream for the subject, there's no ack
+	config.JetStream.Subject = "other.events"
+	require.NotNil(t, client.JetStreamPublish(context.Background(), f))
+}
@@ -2,8 +2,13 @@ package outputs
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"log"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/DataDog/datadog-go/statsd"
 	"github.com/segmentio/kafka-go"
@@ -11,51 +16,175 @@ import (
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
+// Acknowledgements required by the Kafka output for the messages produced
+const (
+	KafkaAcksNone string = "none" // the messages aren't acknowledged, the errors of the brokers are lost
+	KafkaAcksOne  string = "one"  // the leader of the partition acknowledges the messages
+	KafkaAcksAll  string = "all"  // all the in-sync replicas acknowledge the messages
+)
+
+var kafkaCompressions = map[string]kafka.Compression{
+	"":       0,
+	"none":   0,
+	"gzip":   kafka.Gzip,
+	"snappy": kafka.Snappy,
+	"lz4":    kafka.Lz4,
+	"zstd":   kafka.Zstd,
+}
+
 // NewKafkaClient returns a new output.Client for accessing the Apache Kafka.
 func NewKafkaClient(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics, statsdClient, dogstatsdClient *statsd.Client) (*Client, error) {
+	var requiredAcks kafka.RequiredAcks
+	switch strings.ToLower(config.Kafka.RequiredAcks) {
+	case KafkaAcksNone:
+		requiredAcks = kafka.RequireNone
+	case KafkaAcksOne:
+		requiredAcks = kafka.RequireOne
+	case "", KafkaAcksAll:
+		requiredAcks = kafka.RequireAll
+	default:
+		log.Printf("[ERROR] : Kafka - Unknown RequiredAcks '%v', must be '%v', '%v' or '%v'\n", config.Kafka.RequiredAcks, KafkaAcksNone, KafkaAcksOne, KafkaAcksAll)
+		return nil, ErrClientCreation
+	}
+	compression, ok := kafkaCompressions[strings.ToLower(config.Kafka.Compression)]
+	if !ok {
+		log.Printf("[ERROR] : Kafka - Unknown Compression '%v', must be 'none', 'gzip', 'snappy', 'lz4' or 'zstd'\n", config.Kafka.Compression)
+		return nil, ErrClientCreation
+	}
 
+	transport := &kafka.Transport{}
+	if config.Kafka.TLS {
+		// #nosec G402 InsecureSkipVerify is only set as a result of explicit configuration
+		transport.TLS = &tls.Config{InsecureSkipVerify: !config.Kafka.CheckCert, MinVersion: tls.VersionTLS12}
+	}
+	mechanism, err := newSASLMechanism(config.Kafka.SASLMechanism, config.Kafka.Username, config.Kafka.Password)
+	if err != nil {
+		log.Printf("[ERROR] : Kafka - %v\n", err)
+		return nil, ErrClientCreation
+	}
+	transport.SASL = mechanism
+
+	var messageKey *template.Template
+	if config.Kafka.MessageKey != "" {
+		if messageKey, err = parseFieldTemplate("messagekey", config.Kafka.MessageKey); err != nil {
+			log.Printf("[ERROR] : Kafka - Bad MessageKey : %v\n", err)
+			return nil, ErrClientCreation
+		}
+	}
+
+	// the writer sends the messages once, the failures are retried by KafkaProduce until the deadline of the event.
+	// The events are written one by one, a batch holds the events written at the same time.
 	kafkaWriter := &kafka.Writer{
-		Addr:  kafka.TCP(config.Kafka.HostPort),
-		Topic: config.Kafka.Topic,
+		Addr:         kafka.TCP(strings.Split(config.Kafka.HostPort, ",")...),
+		Topic:        config.Kafka.Topic,
+		Balancer:     &kafka.Hash{},
+		MaxAttempts:  1,
+		BatchTimeout: 10 * time.Millisecond,
+		RequiredAcks: requiredAcks,
+		Compression:  compression,
+		Transport:    transport,
 	}
 
 	return &Client{
-		OutputType:      "Kafka",
-		Config:          config,
-		Stats:           stats,
-		PromStats:       promStats,
-		StatsdClient:    statsdClient,
-		DogstatsdClient: dogstatsdClient,
-		KafkaProducer:   kafkaWriter,
+		OutputType:        "Kafka",
+		Config:            config,
+		Stats:             stats,
+		PromStats:         promStats,
+		StatsdClient:      statsdClient,
+		DogstatsdClient:   dogstatsdClient,
+		KafkaProducer:     kafkaWriter,
+		kafkaMessageKey:   messageKey,
+		RetryMaxAttempts:  config.Retry.MaxAttempts,
+		RetryInitialDelay: time.Duration(config.Retry.InitialDelay) * time.Millisecond,
+		RetryMaxDelay:     time.Duration(config.Retry.MaxDelay) * time.Millisecond,
 	}, nil
 }
 
-// KafkaProduce sends a message to a Apach Kafka Topic
-func (c *Client) KafkaProduce(falcopayload types.FalcoPayload) {
+// KafkaProduce sends a message to a Apach Kafka Topic, with the key of the event if MessageKey is set. The event is
+// appended to the dead-letter file if it can't be sent.
+func (c *Client) KafkaProduce(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Kafka.Add(Total, 1)
 
-	falcoMsg, err := json.Marshal(falcopayload)
+	falcoMsg := falcopayload.RawPayload
+	var err error
+	if !c.config().Kafka.Passthrough || falcopayload.RawPayload == nil {
+		falcoMsg, err = json.Marshal(falcopayload)
+	}
 	if err != nil {
 		c.setKafkaErrorMetrics()
 		log.Printf("[ERROR] : Kafka - %v - %v\n", "failed to marshalling message", err.Error())
-		return
+		return err
 	}
 
 	kafkaMsg := kafka.Message{
 		Value: falcoMsg,
 	}
+	if c.kafkaMessageKey != nil {
+		key, err := executeFieldTemplate(c.kafkaMessageKey, falcopayload, nil)
+		if err != nil {
+			c.setKafkaErrorMetrics()
+			log.Printf("[ERROR] : Kafka - Bad MessageKey : %v\n", err)
+			return err
+		}
+		// without key, the messages are spread on the partitions
+		if key != "" {
+			kafkaMsg.Key = []byte(key)
+		}
+	}
 
-	err = c.KafkaProducer.WriteMessages(context.Background(), kafkaMsg)
-	if err != nil {
-		c.setKafkaErrorMetrics()
-		log.Printf("[ERROR] : Kafka - %v\n", err)
-		return
+	if err := c.writeKafkaMessage(ctx, kafkaMsg); err != nil {
+		c.deadLetter(falcopayload, err)
+		return err
 	}
 
 	go c.CountMetric("outputs", 1, []string{"output:kafka", "status:ok"})
 	c.Stats.Kafka.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "kafka", "status": OK}).Inc()
 	log.Printf("[INFO] : Kafka - Publish OK\n")
+
+	return nil
+}
+
+// writeKafkaMessage writes the message, the transient failures are retried with an exponential backoff until
+// Retry.MaxAttempts or the deadline of the event
+func (c *Client) writeKafkaMessage(ctx context.Context, kafkaMsg kafka.Message) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			c.countRetry()
+			if sleepContext(ctx, c.exponentialBackoff(attempt-1)) != nil {
+				return c.deadlineExceeded()
+			}
+		}
+		err = c.KafkaProducer.WriteMessages(ctx, kafkaMsg)
+		var werr kafka.WriteErrors
+		if errors.As(err, &werr) && len(werr) == 1 {
+			err = werr[0]
+		}
+		if err == nil || ctx.Err() != nil || !isKafkaTemporary(err) || attempt+1 >= c.RetryMaxAttempts {
+			break
+		}
+		log.Printf("[WARN]  : Kafka - %v, retrying\n", err)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return c.deadlineExceeded()
+	}
+	if err != nil {
+		c.setKafkaErrorMetrics()
+		log.Printf("[ERROR] : Kafka - %v\n", err)
+	}
+	return err
+}
+
+// isKafkaTemporary returns false for the errors of the brokers which aren't transient (message too large, topic
+// authorization failed, etc)
+func isKafkaTemporary(err error) bool {
+	var kerr kafka.Error
+	if errors.As(err, &kerr) {
+		return kerr.Temporary()
+	}
+	var terr kafka.MessageTooLargeError
+	return !errors.As(err, &terr)
 }
 
 // setKafkaErrorMetrics set the error stats
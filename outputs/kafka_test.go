@@ -0,0 +1,203 @@
+package outputs
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/json"
+	"expvar"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+	"github.com/segmentio/kafka-go/protocol/saslauthenticate"
+	"github.com/segmentio/kafka-go/protocol/saslhandshake"
+	"github.com/stretchr/testify/require"
+	"github.com/xdg/scram"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// mockKafkaBroker is the single broker of a topic with 4 partitions, authenticating the clients with SCRAM-SHA-512.
+// It records the SASL mechanisms of the handshakes and the keys of the messages produced, by partition.
+type mockKafkaBroker struct {
+	listener    net.Listener
+	credentials scram.StoredCredentials
+	mu          sync.Mutex
+	mechanisms  []string
+	users       []string
+	keys        map[int32][]string
+}
+
+var scramSHA512 = scram.HashGeneratorFcn(sha512.New)
+
+func newMockKafkaBroker(t *testing.T, username, password string) *mockKafkaBroker {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	client, err := scramSHA512.NewClient(username, password, "")
+	require.Nil(t, err)
+	b := &mockKafkaBroker{
+		listener:    l,
+		credentials: client.GetStoredCredentials(scram.KeyFactors{Salt: "salt", Iters: 4096}),
+		keys:        make(map[int32][]string),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *mockKafkaBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	server, _ := scramSHA512.NewServer(func(string) (scram.StoredCredentials, error) { return b.credentials, nil })
+	conversation := server.NewConversation()
+	host, port, _ := net.SplitHostPort(b.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	for {
+		apiVersion, correlationID, _, msg, err := protocol.ReadRequest(conn)
+		if err != nil {
+			return
+		}
+		var res protocol.Message
+		switch req := msg.(type) {
+		case *apiversions.Request:
+			res = &apiversions.Response{ApiKeys: []apiversions.ApiKeyResponse{
+				{ApiKey: int16(protocol.Produce), MaxVersion: 3},
+				{ApiKey: int16(protocol.Metadata), MaxVersion: 1},
+				{ApiKey: int16(protocol.SaslHandshake), MaxVersion: 1},
+				{ApiKey: int16(protocol.SaslAuthenticate), MaxVersion: 0},
+			}}
+		case *saslhandshake.Request:
+			b.mu.Lock()
+			b.mechanisms = append(b.mechanisms, req.Mechanism)
+			b.mu.Unlock()
+			res = &saslhandshake.Response{Mechanisms: []string{"SCRAM-SHA-512"}}
+		case *saslauthenticate.Request:
+			challenge, err := conversation.Step(string(req.AuthBytes))
+			if err != nil {
+				res = &saslauthenticate.Response{ErrorCode: 58, ErrorMessage: err.Error()}
+				break
+			}
+			if conversation.Done() {
+				b.mu.Lock()
+				b.users = append(b.users, conversation.Username())
+				b.mu.Unlock()
+			}
+			res = &saslauthenticate.Response{AuthBytes: []byte(challenge)}
+		case *metadata.Request:
+			topic := metadata.ResponseTopic{Name: "falco"}
+			for i := int32(0); i < 4; i++ {
+				topic.Partitions = append(topic.Partitions, metadata.ResponsePartition{PartitionIndex: i, LeaderID: 1, ReplicaNodes: []int32{1}, IsrNodes: []int32{1}})
+			}
+			res = &metadata.Response{Brokers: []metadata.ResponseBroker{{NodeID: 1, Host: host, Port: int32(p)}}, ControllerID: 1, Topics: []metadata.ResponseTopic{topic}}
+		case *produce.Request:
+			r := &produce.Response{}
+			for _, i := range req.Topics {
+				topic := produce.ResponseTopic{Topic: i.Topic}
+				for _, j := range i.Partitions {
+					b.mu.Lock()
+					for {
+						record, err := j.RecordSet.Records.ReadRecord()
+						if err != nil {
+							break
+						}
+						key, _ := protocol.ReadAll(record.Key)
+						b.keys[j.Partition] = append(b.keys[j.Partition], string(key))
+					}
+					b.mu.Unlock()
+					topic.Partitions = append(topic.Partitions, produce.ResponsePartition{Partition: j.Partition})
+				}
+				r.Topics = append(r.Topics, topic)
+			}
+			res = r
+		default:
+			return
+		}
+		if err := protocol.WriteResponse(conn, apiVersion, correlationID, res); err != nil {
+			return
+		}
+	}
+}
+
+func newTestKafkaClient(t *testing.T, hostport, password string) *Client {
+	config := &types.Configuration{}
+	config.Kafka.HostPort = hostport
+	config.Kafka.Topic = "falco"
+	config.Kafka.SASLMechanism = SASLScramSHA512
+	config.Kafka.Username = "falcosidekick"
+	config.Kafka.Password = password
+	config.Kafka.MessageKey = `{{index .OutputFields "k8s.ns.name"}}`
+	config.Kafka.Compression = "zstd"
+	config.Retry.MaxAttempts = 1
+	client, err := NewKafkaClient(config, &types.Statistics{Kafka: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	return client
+}
+
+func TestKafkaProduce(t *testing.T) {
+	b := newMockKafkaBroker(t, "falcosidekick", "password")
+	defer b.listener.Close()
+
+	client := newTestKafkaClient(t, b.listener.Addr().String(), "password")
+	defer client.KafkaProducer.Close()
+	for _, i := range []string{"falco", "default", "falco", ""} {
+		require.Nil(t, client.KafkaProduce(context.Background(), types.FalcoPayload{Rule: "Test rule", Priority: types.Warning, OutputFields: map[string]interface{}{"k8s.ns.name": i}}))
+	}
+	require.Equal(t, "4", client.Stats.Kafka.Get(OK).String())
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	require.NotEmpty(t, b.mechanisms)
+	for _, i := range b.mechanisms {
+		require.Equal(t, "SCRAM-SHA-512", i)
+	}
+	require.Contains(t, b.users, "falcosidekick")
+	// the events of a namespace are in the same partition
+	var keys []string
+	partitions := make(map[int32]bool)
+	for i, j := range b.keys {
+		for _, k := range j {
+			if k == "falco" {
+				partitions[i] = true
+			}
+		}
+		keys = append(keys, j...)
+	}
+	require.Len(t, partitions, 1)
+	require.ElementsMatch(t, []string{"falco", "default", "falco", ""}, keys)
+}
+
+func TestKafkaProduceError(t *testing.T) {
+	b := newMockKafkaBroker(t, "falcosidekick", "password")
+	defer b.listener.Close()
+
+	d, err := NewDeadLetterFile(&types.Configuration{DeadLetter: types.DeadLetterConfig{File: filepath.Join(t.TempDir(), "deadletter.ndjson")}})
+	require.Nil(t, err)
+	SetDeadLetterFile(d)
+	defer SetDeadLetterFile(nil)
+
+	// the authentication fails, the error of the producer is returned and the event is dead-lettered
+	client := newTestKafkaClient(t, b.listener.Addr().String(), "wrong")
+	defer client.KafkaProducer.Close()
+	require.NotNil(t, client.KafkaProduce(context.Background(), types.FalcoPayload{Rule: "Test rule", Priority: types.Warning}))
+	require.Equal(t, "1", client.Stats.Kafka.Get(Error).String())
+
+	f, err := ioutil.ReadFile(d.path)
+	require.Nil(t, err)
+	var l deadLetter
+	require.Nil(t, json.Unmarshal(f, &l))
+	require.Equal(t, "Kafka", l.Output)
+	require.Equal(t, "Test rule", l.Event.Rule)
+}
@@ -0,0 +1,111 @@
+package outputs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// SASL mechanisms of the Kafka input
+const (
+	SASLPlain       string = "plain"
+	SASLScramSHA256 string = "scram-sha-256"
+	SASLScramSHA512 string = "scram-sha-512"
+)
+
+// kafkaMessageReader is the part of kafka.Reader used by the KafkaConsumer
+type kafkaMessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaConsumer reads the events from a Kafka topic, the offset of a message is committed only once handle succeeded,
+// i.e. once its event has been sent to all the outputs (at-least-once), a message is handled again after retryDelay
+// until it succeeds
+type KafkaConsumer struct {
+	reader     kafkaMessageReader
+	handle     func([]byte) error
+	retryDelay time.Duration
+}
+
+// NewKafkaConsumer returns a new KafkaConsumer for the topic and the consumer group configured
+func NewKafkaConsumer(config *types.Configuration, handle func([]byte) error) (*KafkaConsumer, error) {
+	dialer := &kafka.Dialer{Timeout: 10 * time.Second, DualStack: true}
+	if config.KafkaInput.TLS {
+		// #nosec G402 InsecureSkipVerify is only set as a result of explicit configuration
+		dialer.TLS = &tls.Config{InsecureSkipVerify: !config.KafkaInput.CheckCert, MinVersion: tls.VersionTLS12}
+	}
+	mechanism, err := newSASLMechanism(config.KafkaInput.SASLMechanism, config.KafkaInput.Username, config.KafkaInput.Password)
+	if err != nil {
+		log.Printf("[ERROR] : Kafka input - %v\n", err)
+		return nil, ErrClientCreation
+	}
+	dialer.SASLMechanism = mechanism
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: strings.Split(config.KafkaInput.HostPort, ","),
+		GroupID: config.KafkaInput.GroupID,
+		Topic:   config.KafkaInput.Topic,
+		Dialer:  dialer,
+	})
+	return &KafkaConsumer{reader: reader, handle: handle, retryDelay: time.Second}, nil
+}
+
+func newSASLMechanism(mechanism, username, password string) (sasl.Mechanism, error) {
+	switch strings.ToLower(mechanism) {
+	case "":
+		return nil, nil
+	case SASLPlain:
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case SASLScramSHA256:
+		return scram.Mechanism(scram.SHA256, username, password)
+	case SASLScramSHA512:
+		return scram.Mechanism(scram.SHA512, username, password)
+	default:
+		return nil, fmt.Errorf("Unknown SASL mechanism '%v', must be '%v', '%v' or '%v'", mechanism, SASLPlain, SASLScramSHA256, SASLScramSHA512)
+	}
+}
+
+// Run consumes the messages until the context is done, the message being handled isn't committed when it's done
+func (k *KafkaConsumer) Run(ctx context.Context) {
+	defer k.reader.Close()
+	for {
+		m, err := k.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[ERROR] : Kafka input - %v\n", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for {
+			err := k.handle(m.Value)
+			if err == nil {
+				break
+			}
+			log.Printf("[ERROR] : Kafka input - Offset %v of partition %v not committed, retry in %v : %v\n", m.Offset, m.Partition, k.retryDelay, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(k.retryDelay):
+			}
+		}
+
+		if err := k.reader.CommitMessages(ctx, m); err != nil {
+			log.Printf("[ERROR] : Kafka input - Commit of offset %v of partition %v failed : %v\n", m.Offset, m.Partition, err)
+		}
+	}
+}
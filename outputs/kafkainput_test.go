@@ -0,0 +1,126 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// mockKafkaReader is a broker returning its messages then blocking until the context is done
+type mockKafkaReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed []int64
+	done      chan struct{}
+}
+
+func (r *mockKafkaReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) != 0 {
+		m := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return m, nil
+	}
+	r.mu.Unlock()
+	close(r.done)
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *mockKafkaReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, i := range msgs {
+		r.committed = append(r.committed, i.Offset)
+	}
+	return nil
+}
+
+func (r *mockKafkaReader) Close() error {
+	return nil
+}
+
+func TestKafkaConsumerRun(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var f types.FalcoPayload
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&f))
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, f.Rule)
+		// the first attempt of the first event fails
+		if len(received) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient("Webhook", ts.URL, false, false, &types.Configuration{}, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	d, err := NewDispatcher(nil, []string{"Webhook"}, new(Drainer), client.PromStats)
+	require.Nil(t, err)
+
+	reader := &mockKafkaReader{done: make(chan struct{})}
+	for i, j := range []string{"rule 1", "rule 2"} {
+		f := bytes.Replace([]byte(falcoTestInput), []byte("Test rule"), []byte(j), 1)
+		reader.messages = append(reader.messages, kafka.Message{Offset: int64(i), Value: f})
+	}
+
+	var committedBeforeSend []int
+	consumer := &KafkaConsumer{reader: reader, retryDelay: 10 * time.Millisecond, handle: func(message []byte) error {
+		reader.mu.Lock()
+		committedBeforeSend = append(committedBeforeSend, len(reader.committed))
+		reader.mu.Unlock()
+		var f types.FalcoPayload
+		require.Nil(t, json.Unmarshal(message, &f))
+		x := d.NewDispatch(f)
+		x.Add("Webhook", OutputFunc(client.WebhookPost))
+		x.Run()
+		x.Wait()
+		return x.Err()
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		consumer.Run(ctx)
+		close(stopped)
+	}()
+	<-reader.done
+	cancel()
+	<-stopped
+
+	require.Equal(t, []string{"rule 1", "rule 1", "rule 2"}, received)
+	require.Equal(t, []int64{0, 1}, reader.committed)
+	// the offset of a message is committed only once it has been sent, a failed message is handled again
+	require.Equal(t, []int{0, 0, 1}, committedBeforeSend)
+}
+
+func TestNewSASLMechanism(t *testing.T) {
+	m, err := newSASLMechanism("", "", "")
+	require.Nil(t, err)
+	require.Nil(t, m)
+
+	for _, i := range []string{"PLAIN", "scram-sha-256", "scram-sha-512"} {
+		m, err = newSASLMechanism(i, "user", "password")
+		require.Nil(t, err)
+		require.NotNil(t, m)
+	}
+
+	_, err = newSASLMechanism("gssapi", "user", "password")
+	require.NotNil(t, err)
+}
@@ -0,0 +1,59 @@
+package outputs
+
+import (
+	"encoding/json"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// keysMappedPayload is a payload whose top-level keys are renamed or dropped when it's encoded
+type keysMappedPayload struct {
+	payload interface{}
+	mapping map[string]string // key: new name, "" drops it
+}
+
+// MarshalJSON encodes the payload then renames its keys, the ones not mapped are kept, a key renamed overrides the
+// one it's renamed to. A payload which isn't a JSON object is returned as is.
+func (k keysMappedPayload) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(k.payload)
+	if err != nil {
+		return nil, err
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return b, nil
+	}
+	mapped := make(map[string]json.RawMessage, len(keys))
+	for i, j := range keys {
+		if _, ok := k.mapping[i]; !ok {
+			mapped[i] = j
+		}
+	}
+	for i, j := range keys {
+		if n, ok := k.mapping[i]; ok && n != "" {
+			mapped[n] = j
+		}
+	}
+	return json.Marshal(mapped)
+}
+
+// keysMapping returns the KeysMapping configured for the output, only Webhook and Elasticsearch post the event itself,
+// the other outputs post payloads of their own formats
+func keysMapping(config *types.Configuration, outputType string) map[string]string {
+	switch outputType {
+	case "Webhook":
+		return config.Webhook.KeysMapping
+	case "Elasticsearch":
+		return config.Elasticsearch.KeysMapping
+	}
+	return nil
+}
+
+// mapKeys returns the payload with its top-level keys mapped with the KeysMapping of the output when it's encoded,
+// the payload is unchanged, it's returned as is without KeysMapping
+func (c *Client) mapKeys(payload interface{}) interface{} {
+	if len(c.KeysMapping) == 0 {
+		return payload
+	}
+	return keysMappedPayload{payload: payload, mapping: c.KeysMapping}
+}
@@ -49,17 +49,17 @@ func NewKubelessClient(config *types.Configuration, stats *types.Statistics, pro
 }
 
 // KubelessCall .
-func (c *Client) KubelessCall(falcopayload types.FalcoPayload) {
+func (c *Client) KubelessCall(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Kubeless.Add(Total, 1)
 
-	if c.Config.Kubeless.Kubeconfig != "" {
+	if c.config().Kubeless.Kubeconfig != "" {
 		str, _ := json.Marshal(falcopayload)
-		req := c.KubernetesClient.CoreV1().RESTClient().Post().AbsPath("/api/v1/namespaces/" + c.Config.Kubeless.Namespace + "/services/" + c.Config.Kubeless.Function + ":" + strconv.Itoa(c.Config.Kubeless.Port) + "/proxy/").Body(str)
+		req := c.KubernetesClient.CoreV1().RESTClient().Post().AbsPath("/api/v1/namespaces/" + c.config().Kubeless.Namespace + "/services/" + c.config().Kubeless.Function + ":" + strconv.Itoa(c.config().Kubeless.Port) + "/proxy/").Body(str)
 		req.SetHeader("event-id", uuid.New().String())
 		req.SetHeader("Content-Type", "application/json")
 		req.SetHeader("User-Agent", "Falcosidekick")
 		req.SetHeader("event-type", "falco")
-		req.SetHeader("event-namespace", c.Config.Kubeless.Namespace)
+		req.SetHeader("event-namespace", c.config().Kubeless.Namespace)
 
 		res := req.Do(context.TODO())
 		rawbody, err := res.Raw()
@@ -68,21 +68,23 @@ func (c *Client) KubelessCall(falcopayload types.FalcoPayload) {
 			c.Stats.Kubeless.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "kubeless", "status": Error}).Inc()
 			log.Printf("[ERROR] : Kubeless - %v\n", err)
-			return
+			return err
 		}
 		log.Printf("[INFO]  : Kubeless - Function Response : %v\n", string(rawbody))
 	} else {
-		err := c.Post(falcopayload)
+		err := c.post(ctx, falcopayload, falcopayload)
 		if err != nil {
 			go c.CountMetric(Outputs, 1, []string{"output:kubeless", "status:error"})
 			c.Stats.Kubeless.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "kubeless", "status": Error}).Inc()
 			log.Printf("[ERROR] : Kubeless - %v\n", err)
-			return
+			return err
 		}
 	}
-	log.Printf("[INFO]  : Kubeless - Call Function \"%v\" OK\n", c.Config.Kubeless.Function)
+	log.Printf("[INFO]  : Kubeless - Call Function \"%v\" OK\n", c.config().Kubeless.Function)
 	go c.CountMetric(Outputs, 1, []string{"output:kubeless", "status:ok"})
 	c.Stats.Kubeless.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "kubeless", "status": OK}).Inc()
+
+	return nil
 }
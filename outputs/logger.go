@@ -0,0 +1,189 @@
+package outputs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Log formats
+const (
+	TextLog string = "text"
+	JSONLog string = "json"
+)
+
+var logLevels = map[string]int{"debug": 0, "info": 1, "warn": 2, "error": 3}
+
+// logLineRegexp matches the logs of falcosidekick: "[LEVEL] : Output - message (event_id: ID)", output and ID are optional
+var logLineRegexp = regexp.MustCompile(`^\[([A-Z]+)\]\s*: (?:([A-Za-z][\w .]*?) - )?(.*?)(?: \(event_id: ([^)]+)\))?$`)
+
+type logRecord struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Output  string `json:"output,omitempty"`
+	EventID string `json:"event_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Msg     string `json:"msg,omitempty"`
+}
+
+// LogWriter rewrites the logs of falcosidekick in the configured format and drops the ones below the configured level.
+// It's meant to be used with log.SetOutput, see SetupLogs.
+type LogWriter struct {
+	Out    io.Writer
+	Format string
+	Level  string
+	// ErrorWindow enables the coalescing of the errors of an output: once an error is logged, the next ones within the window
+	// are summarized in a single line at its end, until the output logs a line with the info level (recovery)
+	ErrorWindow time.Duration
+	// ErrorRate logs 1 of every ErrorRate errors coalesced, 0 logs none
+	ErrorRate int
+	mu        sync.Mutex
+	failures  map[string]*outputFailure
+}
+
+// outputFailure is the state of an output whose errors are coalesced
+type outputFailure struct {
+	count int // errors not logged since the start of the window
+	seen  int // errors since the first one logged
+	timer *time.Timer
+}
+
+// SetupLogs sets the format and the level of the logs of falcosidekick
+func SetupLogs(config *types.Configuration) {
+	w := &LogWriter{
+		Out:         log.Writer(),
+		Format:      strings.ToLower(config.Log.Format),
+		Level:       strings.ToLower(config.Log.Level),
+		ErrorWindow: time.Duration(config.Log.ErrorSampling.Window) * time.Second,
+		ErrorRate:   config.Log.ErrorSampling.Rate,
+	}
+	if w.Format == JSONLog {
+		log.SetFlags(0)
+	}
+	log.SetOutput(w)
+}
+
+// Write writes a log line, lines not following the format of falcosidekick logs are written as messages with the info level
+func (w *LogWriter) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\n")
+	timestamp := ""
+	if w.Format != JSONLog && len(line) > 20 && line[19] == ' ' {
+		// with the default flags of the log package, lines start with the date and time
+		timestamp, line = line[:20], line[20:]
+	}
+
+	r := logRecord{Level: "info", Msg: line}
+	if m := logLineRegexp.FindStringSubmatch(line); m != nil {
+		r.Level, r.Output, r.Msg, r.EventID = strings.ToLower(m[1]), m[2], m[3], m[4]
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ErrorWindow > 0 && r.Output != "" && !w.sampleError(r) {
+		return len(p), nil
+	}
+	if err := w.write(timestamp, line, r); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// write writes a log line in the configured format if its level is enabled, w.mu must be held
+func (w *LogWriter) write(timestamp, line string, r logRecord) error {
+	if l, ok := logLevels[w.Level]; ok && logLevels[r.Level] < l {
+		return nil
+	}
+
+	if w.Format != JSONLog {
+		_, err := io.WriteString(w.Out, timestamp+line+"\n")
+		return err
+	}
+
+	r.Time = time.Now().UTC().Format(time.RFC3339)
+	if r.Level == "error" {
+		r.Error, r.Msg = r.Msg, ""
+	}
+	b := new(bytes.Buffer)
+	if err := json.NewEncoder(b).Encode(r); err != nil {
+		return err
+	}
+	_, err := w.Out.Write(b.Bytes())
+	return err
+}
+
+// sampleError returns if a line of an output must be logged, the first error of an output is logged,
+// the next ones are counted and summarized at the end of the window, a line with the info level ends the coalescing. w.mu must be held.
+func (w *LogWriter) sampleError(r logRecord) bool {
+	f := w.failures[r.Output]
+	switch {
+	case r.Level == "info" && f != nil:
+		f.timer.Stop()
+		w.summarizeErrors(r.Output, f)
+		delete(w.failures, r.Output)
+		return true
+	case r.Level != "error":
+		return true
+	case f == nil:
+		if w.failures == nil {
+			w.failures = make(map[string]*outputFailure)
+		}
+		f = &outputFailure{}
+		f.timer = time.AfterFunc(w.ErrorWindow, func() { w.endErrorWindow(r.Output, f) })
+		w.failures[r.Output] = f
+		return true
+	}
+	f.seen++
+	if w.ErrorRate > 0 && f.seen%w.ErrorRate == 0 {
+		return true
+	}
+	f.count++
+	return false
+}
+
+// endErrorWindow logs the summary of the errors of an output at the end of a window, the coalescing goes on
+// for a new window if there were errors
+func (w *LogWriter) endErrorWindow(output string, f *outputFailure) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failures[output] != f {
+		return
+	}
+	if f.count == 0 {
+		delete(w.failures, output)
+		return
+	}
+	w.summarizeErrors(output, f)
+	f.timer.Reset(w.ErrorWindow)
+}
+
+// summarizeErrors logs the number of errors of an output not logged since the start of the window, w.mu must be held
+func (w *LogWriter) summarizeErrors(output string, f *outputFailure) {
+	if f.count == 0 {
+		return
+	}
+	msg := fmt.Sprintf("%v more errors in last %v", f.count, w.ErrorWindow)
+	f.count = 0
+	timestamp := ""
+	if log.Flags()&(log.Ldate|log.Ltime) != 0 {
+		timestamp = time.Now().Format("2006/01/02 15:04:05 ")
+	}
+	// #nosec G104 nothing to be done if the following fails
+	w.write(timestamp, fmt.Sprintf("[ERROR] : %v - %v", output, msg), logRecord{Level: "error", Output: output, Msg: msg})
+}
+
+// logEventError logs the error of an output for an event, with the ID of the event if it has one
+func logEventError(output string, falcopayload types.FalcoPayload, err error) {
+	if falcopayload.UUID == "" {
+		log.Printf("[ERROR] : %v - %v\n", output, err)
+		return
+	}
+	log.Printf("[ERROR] : %v - %v (event_id: %v)\n", output, err, falcopayload.UUID)
+}
@@ -0,0 +1,73 @@
+package outputs
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) lines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var lines []string
+	for _, i := range strings.Split(strings.TrimSpace(s.b.String()), "\n") {
+		// strip the date and time
+		lines = append(lines, i[20:])
+	}
+	return lines
+}
+
+func TestLogWriterErrorSampling(t *testing.T) {
+	out := new(syncBuffer)
+	logger := log.New(&LogWriter{Out: out, Format: TextLog, ErrorWindow: 100 * time.Millisecond}, "", log.LstdFlags)
+
+	for i := 0; i < 10; i++ {
+		logger.Printf("[ERROR] : Webhook - Post failed (event_id: %v)\n", i)
+	}
+	logger.Printf("[ERROR] : Slack - Post failed\n")
+	require.Equal(t, []string{"[ERROR] : Webhook - Post failed (event_id: 0)", "[ERROR] : Slack - Post failed"}, out.lines())
+
+	require.Eventually(t, func() bool { return len(out.lines()) == 3 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, "[ERROR] : Webhook - 9 more errors in last 100ms", out.lines()[2])
+
+	// the output still fails, its errors are coalesced in a new window until it recovers
+	logger.Printf("[ERROR] : Webhook - Post failed\n")
+	logger.Printf("[ERROR] : Webhook - Post failed\n")
+	logger.Printf("[INFO]  : Webhook - Post OK (200)\n")
+	logger.Printf("[ERROR] : Webhook - Post failed\n")
+	require.Equal(t, []string{
+		"[ERROR] : Webhook - 2 more errors in last 100ms",
+		"[INFO]  : Webhook - Post OK (200)",
+		"[ERROR] : Webhook - Post failed",
+	}, out.lines()[3:])
+}
+
+func TestLogWriterErrorSamplingRate(t *testing.T) {
+	out := new(syncBuffer)
+	logger := log.New(&LogWriter{Out: out, Format: TextLog, ErrorWindow: time.Minute, ErrorRate: 4}, "", log.LstdFlags)
+
+	for i := 0; i < 10; i++ {
+		logger.Printf("[ERROR] : Webhook - Post failed (event_id: %v)\n", i)
+	}
+	require.Equal(t, []string{
+		"[ERROR] : Webhook - Post failed (event_id: 0)",
+		"[ERROR] : Webhook - Post failed (event_id: 4)",
+		"[ERROR] : Webhook - Post failed (event_id: 8)",
+	}, out.lines())
+}
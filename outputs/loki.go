@@ -1,8 +1,13 @@
 package outputs
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/falcosecurity/falcosidekick/types"
@@ -22,10 +27,97 @@ type lokiEntry struct {
 	Line string `json:"line"`
 }
 
-func newLokiPayload(falcopayload types.FalcoPayload, config *types.Configuration) lokiPayload {
-	le := lokiEntry{Ts: falcopayload.Time.Format(time.RFC3339), Line: falcopayload.Output}
+// lokiLabelValues caps the number of values of the extra labels, Loki doesn't cope with streams of high cardinality
+type lokiLabelValues struct {
+	max    int // 0 disables the cap
+	mu     sync.Mutex
+	values map[string]map[string]bool
+}
+
+var lokiLabelNameRegexp = regexp.MustCompile("[^a-zA-Z0-9_]")
+
+// EnableLokiExtraLabels makes the Loki output cap the values of the extra labels to MaxLabelValues per label
+func (c *Client) EnableLokiExtraLabels() {
+	c.lokiLabels = &lokiLabelValues{max: c.config().Loki.MaxLabelValues, values: make(map[string]map[string]bool)}
+}
+
+// allow returns true if the value is one of the values of the label already used or if the label has less than max
+// values, a warning is logged once the label reaches max values
+func (l *lokiLabelValues) allow(label, value string) bool {
+	if l == nil || l.max <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	v := l.values[label]
+	if v == nil {
+		v = make(map[string]bool)
+		l.values[label] = v
+	}
+	if v[value] {
+		return true
+	}
+	if len(v) >= l.max {
+		return false
+	}
+	v[value] = true
+	if len(v) == l.max {
+		log.Printf("[WARN]  : Loki - Label '%v' has reached %v values, it's not set for the new ones\n", label, l.max)
+	}
+	return true
+}
+
+// lokiLabelName returns the key with the characters not allowed in a Loki label name replaced by _, "k8s.ns.name" is
+// "k8s_ns_name"
+func lokiLabelName(key string) string {
+	name := lokiLabelNameRegexp.ReplaceAllString(key, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// newLokiExtraLabels returns the labels of the stream for the keys of ExtraLabels, rule, priority, source or output
+// fields, the stream has at least the priority label
+func newLokiExtraLabels(falcopayload types.FalcoPayload, keys []string, values *lokiLabelValues) string {
+	var labels []string
+	for _, i := range keys {
+		var value string
+		switch i {
+		case "rule":
+			value = falcopayload.Rule
+		case "priority":
+			value = falcopayload.Priority.String()
+		case "source":
+			value = falcopayload.Source
+		default:
+			if j, ok := falcopayload.OutputFields[i]; ok && j != nil {
+				value = fmt.Sprintf("%v", j)
+			}
+		}
+		name := lokiLabelName(i)
+		if value == "" || !values.allow(name, value) {
+			continue
+		}
+		labels = append(labels, name+"="+strconv.Quote(value))
+	}
+	if len(labels) == 0 {
+		labels = append(labels, "priority="+strconv.Quote(falcopayload.Priority.String()))
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// newLokiPayload returns the push request of the event, timestamped with the time of the event, its labels are the
+// ExtraLabels if they're set, all the output fields with a string value, the rule and the priority otherwise
+func newLokiPayload(falcopayload types.FalcoPayload, config *types.Configuration, values *lokiLabelValues) lokiPayload {
+	le := lokiEntry{Ts: falcopayload.Time.Format(time.RFC3339Nano), Line: falcopayload.Output}
 	ls := lokiStream{Entries: []lokiEntry{le}}
 
+	if len(config.Loki.ExtraLabels) != 0 {
+		ls.Labels = newLokiExtraLabels(falcopayload, config.Loki.ExtraLabels, values)
+		return lokiPayload{Streams: []lokiStream{ls}}
+	}
+
 	var s string
 	for i, j := range falcopayload.OutputFields {
 		switch v := j.(type) {
@@ -45,19 +137,21 @@ func newLokiPayload(falcopayload types.FalcoPayload, config *types.Configuration
 }
 
 // LokiPost posts event to Loki
-func (c *Client) LokiPost(falcopayload types.FalcoPayload) {
+func (c *Client) LokiPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Loki.Add(Total, 1)
 
-	err := c.Post(newLokiPayload(falcopayload, c.Config))
+	err := c.post(ctx, newLokiPayload(falcopayload, c.config(), c.lokiLabels), falcopayload)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:loki", "status:error"})
 		c.Stats.Loki.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "loki", "status": Error}).Inc()
-		log.Printf("[ERROR] : Loki - %v\n", err)
-		return
+		logEventError("Loki", falcopayload, err)
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:loki", "status:ok"})
 	c.Stats.Loki.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "loki", "status": OK}).Inc()
+
+	return nil
 }
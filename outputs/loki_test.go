@@ -1,8 +1,13 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -26,7 +31,52 @@ func TestNewLokiPayload(t *testing.T) {
 
 	var f types.FalcoPayload
 	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
-	output := newLokiPayload(f, &types.Configuration{})
+	output := newLokiPayload(f, &types.Configuration{}, nil)
 
 	require.Equal(t, output, expectedOutput)
 }
+
+func TestLokiPostExtraLabels(t *testing.T) {
+	var pushed []lokiPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p lokiPayload
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		pushed = append(pushed, p)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Loki.ExtraLabels = []string{"rule", "priority", "source", "k8s.ns.name", "proc.tty", "container.id"}
+	config.Loki.MaxLabelValues = 2
+	client, err := NewClient("Loki", ts.URL+"/api/prom/push", false, false, config, &types.Statistics{Loki: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.EnableLokiExtraLabels()
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.Time = time.Date(2001, 1, 1, 1, 10, 0, 123456789, time.UTC)
+	f.Source = "syscall"
+	f.OutputFields["k8s.ns.name"] = `my"ns`
+	require.Nil(t, client.LokiPost(context.Background(), f))
+
+	require.Len(t, pushed, 1)
+	require.Equal(t, `{rule="Test rule",priority="Debug",source="syscall",k8s_ns_name="my\"ns",proc_tty="1234"}`, pushed[0].Streams[0].Labels)
+	require.Equal(t, "2001-01-01T01:10:00.123456789Z", pushed[0].Streams[0].Entries[0].Ts)
+	require.Equal(t, f.Output, pushed[0].Streams[0].Entries[0].Line)
+
+	// the third value of a label isn't set, the ones already used still are
+	for _, i := range []string{"b", "c", "my\"ns"} {
+		f.OutputFields["k8s.ns.name"] = i
+		require.Nil(t, client.LokiPost(context.Background(), f))
+	}
+	require.Contains(t, pushed[1].Streams[0].Labels, `k8s_ns_name="b"`)
+	require.NotContains(t, pushed[2].Streams[0].Labels, "k8s_ns_name")
+	require.Contains(t, pushed[3].Streams[0].Labels, `k8s_ns_name="my\"ns"`)
+}
+
+func TestLokiLabelName(t *testing.T) {
+	require.Equal(t, "k8s_ns_name", lokiLabelName("k8s.ns.name"))
+	require.Equal(t, "proc_aname_2_", lokiLabelName("proc.aname[2]"))
+	require.Equal(t, "_1field", lokiLabelName("1field"))
+}
@@ -0,0 +1,49 @@
+package outputs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// MalformedInputs counts the events received with a malformed JSON body in falcosidekick_malformed_inputs by source,
+// their raw body is appended to the dead-letter file if it's set
+type MalformedInputs struct {
+	PromStats  *types.PromStatistics
+	DeadLetter *DeadLetterFile
+}
+
+// CheckJSON returns an error locating the problem if the body isn't valid JSON (ex: a truncated event)
+func CheckJSON(body []byte) error {
+	var v json.RawMessage
+	err := json.Unmarshal(body, &v)
+	var syntaxError *json.SyntaxError
+	if errors.As(err, &syntaxError) {
+		return fmt.Errorf("%v at offset %v", err, syntaxError.Offset)
+	}
+	return err
+}
+
+// Add counts a malformed event and writes it to the dead-letter file
+func (m *MalformedInputs) Add(source string, body []byte, err error) {
+	if m.PromStats != nil && m.PromStats.MalformedInputs != nil {
+		m.PromStats.MalformedInputs.With(map[string]string{"source": source}).Inc()
+	}
+	if m.DeadLetter == nil {
+		return
+	}
+	if err := m.DeadLetter.write(deadLetter{Time: time.Now().UTC(), Source: source, Error: err.Error(), Body: string(body)}); err != nil {
+		log.Printf("[ERROR] : Dead letter - %v\n", err)
+	}
+}
+
+// Reject replies 400 with the error and counts the malformed event
+func (m *MalformedInputs) Reject(w http.ResponseWriter, source string, body []byte, err error) {
+	http.Error(w, "Malformed JSON event : "+err.Error(), http.StatusBadRequest)
+	m.Add(source, body, err)
+}
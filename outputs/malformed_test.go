@@ -0,0 +1,70 @@
+package outputs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestMalformedInputs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "deadletter.ndjson")
+	promStats := newTestPromStats()
+	promStats.MalformedInputs = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "falcosidekick_malformed_inputs"}, []string{"source"})
+	f, err := NewDeadLetterFile(&types.Configuration{DeadLetter: types.DeadLetterConfig{File: file}})
+	require.Nil(t, err)
+	m := &MalformedInputs{PromStats: promStats, DeadLetter: f}
+
+	var accepted int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := CheckJSON(body); err != nil {
+			m.Reject(w, "requests", body, err)
+			return
+		}
+		accepted++
+	}))
+	defer ts.Close()
+
+	truncated := `{"output":"This is a test from falcosidekick","priority":"Debug","rule":"Test ru`
+	resp, err := http.Post(ts.URL, "application/json", strings.NewReader(truncated))
+	require.Nil(t, err)
+	message, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Equal(t, "Malformed JSON event : unexpected end of JSON input at offset 80\n", string(message))
+
+	resp, err = http.Post(ts.URL, "application/json", strings.NewReader(`{"output":"test",}`))
+	require.Nil(t, err)
+	message, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Contains(t, string(message), "at offset 18")
+
+	resp, err = http.Post(ts.URL, "application/json", strings.NewReader(falcoTestInput))
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 1, accepted)
+
+	require.Equal(t, float64(2), testutil.ToFloat64(promStats.MalformedInputs.With(map[string]string{"source": "requests"})))
+
+	content, err := ioutil.ReadFile(file)
+	require.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 2)
+	var d deadLetter
+	require.Nil(t, json.Unmarshal([]byte(lines[0]), &d))
+	require.Equal(t, "requests", d.Source)
+	require.Equal(t, truncated, d.Body)
+	require.Equal(t, "unexpected end of JSON input at offset 80", d.Error)
+}
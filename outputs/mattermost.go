@@ -1,7 +1,7 @@
 package outputs
 
 import (
-	"bytes"
+	"context"
 	"log"
 
 	"github.com/falcosecurity/falcosidekick/types"
@@ -54,12 +54,11 @@ func newMattermostPayload(falcopayload types.FalcoPayload, config *types.Configu
 		attachment.Text = falcopayload.Output
 	}
 
-	if config.Mattermost.MessageFormatTemplate != nil {
-		buf := &bytes.Buffer{}
-		if err := config.Mattermost.MessageFormatTemplate.Execute(buf, falcopayload); err != nil {
+	if t := getMessageFormatTemplate(falcopayload, config.Mattermost.MessageFormatField, config.Mattermost.MessageFormatTemplates, config.Mattermost.MessageFormatTemplate); t != nil {
+		if m, err := executeMessageFormat(t, falcopayload); err != nil {
 			log.Printf("[ERROR] : Mattermost - Error expanding Mattermost message %v", err)
 		} else {
-			messageText = buf.String()
+			messageText = m
 		}
 	}
 
@@ -102,20 +101,22 @@ func newMattermostPayload(falcopayload types.FalcoPayload, config *types.Configu
 }
 
 // MattermostPost posts event to Mattermost
-func (c *Client) MattermostPost(falcopayload types.FalcoPayload) {
+func (c *Client) MattermostPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Mattermost.Add(Total, 1)
 
-	err := c.Post(newMattermostPayload(falcopayload, c.Config))
+	err := c.post(ctx, newMattermostPayload(falcopayload, c.config()), falcopayload)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:mattermost", "status:error"})
 		c.Stats.Mattermost.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "mattermost", "status": Error}).Inc()
-		log.Printf("[ERROR] : Mattermost - %v\n", err)
-		return
+		logEventError("Mattermost", falcopayload, err)
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:mattermost", "status:ok"})
 	c.Stats.Mattermost.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "mattermost", "status": OK}).Inc()
+
+	return nil
 }
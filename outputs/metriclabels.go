@@ -0,0 +1,71 @@
+package outputs
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// OverflowLabelValue is the value of a label for the events beyond its cardinality limit
+const OverflowLabelValue string = "__overflow__"
+
+// reservedLabels are the labels falco_events always has
+var reservedLabels = map[string]bool{"rule": true, "priority": true, "k8s_ns_name": true, "k8s_pod_name": true}
+
+var labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// MetricLabels gives the extra labels of falco_events from the fields of the events, each label has at most
+// Limit distinct values, the next ones are counted with OverflowLabelValue
+type MetricLabels struct {
+	Names  []string // sorted
+	Limit  int
+	fields map[string]string // label: field
+	mu     sync.Mutex
+	values map[string]map[string]bool // label: values seen
+}
+
+// NewMetricLabels returns the MetricLabels configured (label: field)
+func NewMetricLabels(config *types.Configuration) (*MetricLabels, error) {
+	m := &MetricLabels{
+		Limit:  config.Metrics.CardinalityLimit,
+		fields: make(map[string]string),
+		values: make(map[string]map[string]bool),
+	}
+	for label, field := range config.Metrics.Labels {
+		if !labelNameRegexp.MatchString(label) || reservedLabels[label] {
+			return nil, fmt.Errorf("Bad label name '%v'", label)
+		}
+		m.Names = append(m.Names, label)
+		m.fields[label] = field
+		m.values[label] = make(map[string]bool)
+	}
+	sort.Strings(m.Names)
+	return m, nil
+}
+
+// Values returns the values of the labels for an event, "" for a missing field
+func (m *MetricLabels) Values(falcopayload types.FalcoPayload) map[string]string {
+	labels := make(map[string]string, len(m.Names))
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, i := range m.Names {
+		v, ok := falcopayload.OutputFields[m.fields[i]]
+		if !ok || v == nil {
+			labels[i] = ""
+			continue
+		}
+		value := fmt.Sprintf("%v", v)
+		if !m.values[i][value] {
+			if m.Limit > 0 && len(m.values[i]) >= m.Limit {
+				value = OverflowLabelValue
+			} else {
+				m.values[i][value] = true
+			}
+		}
+		labels[i] = value
+	}
+	return labels
+}
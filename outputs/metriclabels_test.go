@@ -0,0 +1,35 @@
+package outputs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestMetricLabels(t *testing.T) {
+	config := &types.Configuration{Metrics: types.MetricsConfig{
+		Labels:           map[string]string{"namespace": "k8s.ns.name", "user": "user.name"},
+		CardinalityLimit: 2,
+	}}
+	m, err := NewMetricLabels(config)
+	require.Nil(t, err)
+	require.Equal(t, []string{"namespace", "user"}, m.Names)
+
+	values := func(ns string) string {
+		return m.Values(types.FalcoPayload{OutputFields: map[string]interface{}{"k8s.ns.name": ns}})["namespace"]
+	}
+	require.Equal(t, "default", values("default"))
+	require.Equal(t, "kube-system", values("kube-system"))
+	// beyond the limit, new values collapse to the overflow bucket, the ones already seen are kept
+	require.Equal(t, OverflowLabelValue, values("team-a"))
+	require.Equal(t, OverflowLabelValue, values("team-b"))
+	require.Equal(t, "default", values("default"))
+	require.Equal(t, "", m.Values(types.FalcoPayload{})["user"])
+
+	for _, i := range []string{"rule", "bad-label"} {
+		_, err = NewMetricLabels(&types.Configuration{Metrics: types.MetricsConfig{Labels: map[string]string{i: "k8s.ns.name"}}})
+		require.NotNil(t, err)
+	}
+}
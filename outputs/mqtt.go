@@ -0,0 +1,120 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-go/statsd"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// ErrMQTTAckTimeout is returned for the publications not confirmed by the broker within AckTimeout
+var ErrMQTTAckTimeout = errors.New("Publication not acked by the broker")
+
+// mqttTopicReplacer replaces the characters of the values of the fields which can't be in the name of a topic
+var mqttTopicReplacer = strings.NewReplacer("+", "_", "#", "_", "\x00", "")
+
+// NewMQTTClient returns a new output.Client for publishing to a MQTT broker, the connection is established at startup
+// and reestablished if it's lost
+func NewMQTTClient(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics, statsdClient, dogstatsdClient *statsd.Client) (*Client, error) {
+	options := mqtt.NewClientOptions().
+		AddBroker(config.MQTT.Broker).
+		SetClientID(config.MQTT.ClientID).
+		SetUsername(config.MQTT.User).
+		SetPassword(config.MQTT.Password).
+		SetConnectTimeout(time.Duration(config.MQTT.AckTimeout) * time.Millisecond).
+		SetAutoReconnect(true)
+	if config.MQTT.MutualTLS || strings.HasPrefix(config.MQTT.Broker, "ssl://") || strings.HasPrefix(config.MQTT.Broker, "tls://") || strings.HasPrefix(config.MQTT.Broker, "wss://") {
+		tlsConfig, err := newConnTLSConfig(config, config.MQTT.MutualTLS, config.MQTT.CheckCert)
+		if err != nil {
+			log.Printf("[ERROR] : MQTT - %v\n", err)
+			return nil, err
+		}
+		options.SetTLSConfig(tlsConfig)
+	}
+
+	client := mqtt.NewClient(options)
+	t := client.Connect()
+	if !t.WaitTimeout(time.Duration(config.MQTT.AckTimeout) * time.Millisecond) {
+		client.Disconnect(0)
+		log.Printf("[ERROR] : MQTT - Connection to %v timed out\n", config.MQTT.Broker)
+		return nil, ErrClientCreation
+	}
+	if err := t.Error(); err != nil {
+		log.Printf("[ERROR] : MQTT - %v\n", err)
+		return nil, err
+	}
+
+	return &Client{
+		OutputType:      "MQTT",
+		Config:          config,
+		MQTTClient:      client,
+		Stats:           stats,
+		PromStats:       promStats,
+		StatsdClient:    statsdClient,
+		DogstatsdClient: dogstatsdClient,
+	}, nil
+}
+
+// MQTTPublish publishes the event to its topic, it returns once the event is sent with QoS 0, and once the broker
+// confirmed its delivery with QoS 1 and 2, within AckTimeout
+func (c *Client) MQTTPublish(ctx context.Context, falcopayload types.FalcoPayload) error {
+	c.Stats.MQTT.Add(Total, 1)
+
+	payload, err := json.Marshal(falcopayload)
+	if err != nil {
+		c.setMQTTErrorMetrics()
+		log.Printf("[ERROR] : MQTT - %v\n", err)
+		return err
+	}
+
+	topic, err := executeFieldTemplate(c.config().MQTT.TopicTemplate, falcopayload, mqttTopicReplacer)
+	if err != nil {
+		c.setMQTTErrorMetrics()
+		logEventError("MQTT", falcopayload, err)
+		return err
+	}
+	t := c.MQTTClient.Publish(topic, byte(c.config().MQTT.QOS), c.config().MQTT.Retained, payload)
+	timer := time.NewTimer(time.Duration(c.config().MQTT.AckTimeout) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-t.Done():
+		err = t.Error()
+	case <-timer.C:
+		err = ErrMQTTAckTimeout
+	case <-ctx.Done():
+		return c.deadlineExceeded()
+	}
+	if err != nil {
+		c.setMQTTErrorMetrics()
+		logEventError("MQTT", falcopayload, err)
+		return err
+	}
+
+	go c.CountMetric(Outputs, 1, []string{"output:mqtt", "status:ok"})
+	c.Stats.MQTT.Add(OK, 1)
+	c.PromStats.Outputs.With(map[string]string{"destination": "mqtt", "status": OK}).Inc()
+	log.Printf("[INFO]  : MQTT - Publish OK (topic %v)\n", topic)
+
+	return nil
+}
+
+// MQTTClose disconnects from the broker at shutdown, the publications in progress get their ack
+func (c *Client) MQTTClose() {
+	if c.MQTTClient != nil {
+		c.MQTTClient.Disconnect(uint(c.config().MQTT.AckTimeout))
+	}
+}
+
+// setMQTTErrorMetrics set the error stats
+func (c *Client) setMQTTErrorMetrics() {
+	go c.CountMetric(Outputs, 1, []string{"output:mqtt", "status:error"})
+	c.Stats.MQTT.Add(Error, 1)
+	c.PromStats.Outputs.With(map[string]string{"destination": "mqtt", "status": Error}).Inc()
+}
@@ -0,0 +1,186 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// mockMQTTBroker is a MQTT 3.1.1 broker forwarding the publications to the subscribers of their topic (exact names
+// or prefix/#) with QoS 0, the publications aren't acked with noAck
+type mockMQTTBroker struct {
+	listener net.Listener
+	user     string
+	password string
+	noAck    bool
+
+	mu          sync.Mutex
+	subscribers map[net.Conn][]string
+	retained    []bool
+}
+
+func newMockMQTTBroker(t *testing.T, user, password string) *mockMQTTBroker {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	b := &mockMQTTBroker{listener: l, user: user, password: password, subscribers: make(map[net.Conn][]string)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return b
+}
+
+func (b *mockMQTTBroker) url() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+func (b *mockMQTTBroker) serve(conn net.Conn) {
+	defer func() {
+		b.mu.Lock()
+		delete(b.subscribers, conn)
+		b.mu.Unlock()
+		conn.Close()
+	}()
+	for {
+		p, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		switch p := p.(type) {
+		case *packets.ConnectPacket:
+			ack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+			if p.Username != b.user || string(p.Password) != b.password {
+				ack.ReturnCode = packets.ErrRefusedNotAuthorised
+			}
+			err = ack.Write(conn)
+		case *packets.SubscribePacket:
+			b.subscribers[conn] = append(b.subscribers[conn], p.Topics...)
+			ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			ack.MessageID = p.MessageID
+			ack.ReturnCodes = make([]byte, len(p.Topics))
+			err = ack.Write(conn)
+		case *packets.PublishPacket:
+			b.retained = append(b.retained, p.Retain)
+			for i, j := range b.subscribers {
+				for _, k := range j {
+					if k == p.TopicName || (strings.HasSuffix(k, "/#") && strings.HasPrefix(p.TopicName, strings.TrimSuffix(k, "#"))) {
+						m := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+						m.TopicName = p.TopicName
+						m.Payload = p.Payload
+						// #nosec G104 the subscriber may be gone
+						m.Write(i)
+						break
+					}
+				}
+			}
+			switch {
+			case b.noAck:
+			case p.Qos == 1:
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				err = ack.Write(conn)
+			case p.Qos == 2:
+				ack := packets.NewControlPacket(packets.Pubrec).(*packets.PubrecPacket)
+				ack.MessageID = p.MessageID
+				err = ack.Write(conn)
+			}
+		case *packets.PubrelPacket:
+			ack := packets.NewControlPacket(packets.Pubcomp).(*packets.PubcompPacket)
+			ack.MessageID = p.MessageID
+			err = ack.Write(conn)
+		case *packets.PingreqPacket:
+			err = packets.NewControlPacket(packets.Pingresp).Write(conn)
+		case *packets.DisconnectPacket:
+			b.mu.Unlock()
+			return
+		}
+		b.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func TestMQTTPublish(t *testing.T) {
+	b := newMockMQTTBroker(t, "falco", "secret")
+
+	config := &types.Configuration{}
+	config.MQTT.Broker = b.url()
+	config.MQTT.TopicTemplate = template.Must(parseFieldTemplate("", `falco/{{ .Field "k8s.ns.name" }}/alerts`))
+	config.MQTT.ClientID = "falcosidekick-test"
+	config.MQTT.User = "falco"
+	config.MQTT.Password = "wrong"
+	config.MQTT.AckTimeout = 1000
+	stats := &types.Statistics{MQTT: new(expvar.Map)}
+
+	// the credentials are refused
+	_, err := NewMQTTClient(config, stats, newTestPromStats(), nil, nil)
+	require.NotNil(t, err)
+
+	config.MQTT.Password = "secret"
+	client, err := NewMQTTClient(config, stats, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	defer client.MQTTClose()
+
+	received := make(chan mqtt.Message, 3)
+	subscriber := mqtt.NewClient(mqtt.NewClientOptions().AddBroker(b.url()).SetUsername("falco").SetPassword("secret"))
+	require.True(t, subscriber.Connect().WaitTimeout(time.Second))
+	defer subscriber.Disconnect(0)
+	s := subscriber.Subscribe("falco/#", 0, func(_ mqtt.Client, m mqtt.Message) { received <- m })
+	require.True(t, s.WaitTimeout(time.Second))
+	require.Nil(t, s.Error())
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.OutputFields["k8s.ns.name"] = "kube+system"
+	for _, qos := range []int{0, 1, 2} {
+		config.MQTT.QOS = qos
+		config.MQTT.Retained = qos == 2
+		require.Nil(t, client.MQTTPublish(context.Background(), f))
+
+		select {
+		case m := <-received:
+			// the wildcards can't be in the name of the topic
+			require.Equal(t, "falco/kube_system/alerts", m.Topic())
+			var published types.FalcoPayload
+			require.Nil(t, json.Unmarshal(m.Payload(), &published))
+			require.Equal(t, f.Rule, published.Rule)
+			require.Equal(t, f.Output, published.Output)
+			require.Equal(t, "kube+system", published.OutputFields["k8s.ns.name"])
+		case <-time.After(time.Second):
+			t.Fatalf("No message received with QoS %v", qos)
+		}
+	}
+	b.mu.Lock()
+	require.Equal(t, []bool{false, false, true}, b.retained)
+	b.mu.Unlock()
+	require.Equal(t, "3", stats.MQTT.Get(OK).String())
+
+	// a publication not acked is a failure
+	b.mu.Lock()
+	b.noAck = true
+	b.mu.Unlock()
+	config.MQTT.QOS = 1
+	config.MQTT.AckTimeout = 100
+	require.Equal(t, ErrMQTTAckTimeout, client.MQTTPublish(context.Background(), f))
+	require.Equal(t, "1", stats.MQTT.Get(Error).String())
+}
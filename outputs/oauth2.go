@@ -0,0 +1,103 @@
+package outputs
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// OAuth2TokenSource returns the tokens of the client credentials grant, a token is cached, for all the requests, and
+// fetched again from the token endpoint 10s before it expires
+type OAuth2TokenSource struct {
+	config *clientcredentials.Config
+	mu     sync.Mutex // a single fetch at a time, the concurrent requests wait for its token
+	token  *oauth2.Token
+}
+
+// tokenError is the failure to get a token, the request is retried like after a transient failure
+type tokenError struct {
+	err error
+}
+
+func (e *tokenError) Error() string {
+	return "Can't get an OAuth2 token : " + e.err.Error()
+}
+
+func (e *tokenError) Unwrap() error {
+	return e.err
+}
+
+// NewOAuth2TokenSource returns the OAuth2TokenSource of the client credentials grant
+func NewOAuth2TokenSource(tokenURL, clientID, clientSecret string, scopes []string) *OAuth2TokenSource {
+	return &OAuth2TokenSource{config: &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     tokenURL,
+		Scopes:       scopes,
+	}}
+}
+
+// Token returns the token cached if it's still valid, or fetches a new one with the HTTP client (timeout, TLS and proxy)
+// and the context (deadline) of the request to authenticate
+func (s *OAuth2TokenSource) Token(ctx context.Context, client *http.Client) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token.Valid() {
+		return s.token, nil
+	}
+	token, err := s.config.Token(context.WithValue(ctx, oauth2.HTTPClient, client))
+	if err != nil {
+		return nil, &tokenError{err: err}
+	}
+	s.token = token
+	return token, nil
+}
+
+// setClientSecret sets the secret of the client reloaded, the token cached is dropped if it's a new one
+func (s *OAuth2TokenSource) setClientSecret(clientSecret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config.ClientSecret != clientSecret {
+		s.config.ClientSecret = clientSecret
+		s.token = nil
+	}
+}
+
+// invalidate drops the token cached if it's still token, refused by the output, so the next request fetches a new one
+func (s *OAuth2TokenSource) invalidate(token *oauth2.Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = nil
+	}
+}
+
+// sendWithToken sends a request authenticated with a token of the TokenSource, fetched for each attempt, a request
+// refused with a 401 is sent again once with a new token
+func (c *Client) sendWithToken(client *http.Client, req *http.Request) (*http.Response, error) {
+	if c.TokenSource == nil {
+		return c.sendAuthenticated(client, req)
+	}
+	if secret := c.config().Webhook.OAuth2ClientSecret; c.OutputType == "Webhook" && secret != "" {
+		c.TokenSource.setClientSecret(secret)
+	}
+	for attempt := 0; ; attempt++ {
+		token, err := c.TokenSource.Token(req.Context(), client)
+		if err != nil {
+			return nil, err
+		}
+		token.SetAuthHeader(req)
+		resp, err := c.sendAuthenticated(client, req)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || attempt > 0 || req.GetBody == nil {
+			return resp, err
+		}
+		resp.Body.Close()
+		c.TokenSource.invalidate(token)
+		if req.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+}
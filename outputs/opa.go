@@ -0,0 +1,198 @@
+package outputs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// OutputSelection is a set of output names (as in EnabledOutputs, case and spaces ignored), nil means all outputs
+type OutputSelection map[string]bool
+
+// NewOutputSelection returns an OutputSelection with the given outputs
+func NewOutputSelection(names ...string) OutputSelection {
+	s := make(OutputSelection, len(names))
+	for _, i := range names {
+		s[dispatchName(i)] = true
+	}
+	return s
+}
+
+// Has returns true if the output is part of the selection
+func (s OutputSelection) Has(name string) bool {
+	return s == nil || s[dispatchName(name)]
+}
+
+// Intersect returns the outputs part of both selections, nil if both are all the outputs
+func (s OutputSelection) Intersect(o OutputSelection) OutputSelection {
+	if s == nil {
+		return o
+	}
+	if o == nil {
+		return s
+	}
+	i := make(OutputSelection, len(s))
+	for j := range s {
+		if o[j] {
+			i[j] = true
+		}
+	}
+	return i
+}
+
+// PolicyDecision is the result returned by OPA for an event
+type PolicyDecision struct {
+	Allow    *bool    `json:"allow"`
+	Outputs  []string `json:"outputs"`
+	Priority string   `json:"priority"`
+}
+
+type policyInput struct {
+	Input types.FalcoPayload `json:"input"`
+}
+
+type policyResult struct {
+	Result *PolicyDecision `json:"result"`
+}
+
+type cachedPolicyDecision struct {
+	decision PolicyDecision
+	expire   time.Time
+}
+
+// PolicyClient asks an OPA server which outputs an event must be sent to
+type PolicyClient struct {
+	URL        string
+	FailOpen   bool
+	CacheTTL   time.Duration
+	PromStats  *types.PromStatistics
+	httpClient *http.Client
+	mu         sync.Mutex
+	cache      map[string]cachedPolicyDecision
+}
+
+// NewPolicyClient returns a new PolicyClient for the OPA decision endpoint configured
+func NewPolicyClient(config *types.Configuration, promStats *types.PromStatistics) (*PolicyClient, error) {
+	if !strings.HasPrefix(config.OPA.URL, "http://") && !strings.HasPrefix(config.OPA.URL, "https://") {
+		log.Printf("[ERROR] : OPA - %v\n", "Bad Endpoint")
+		return nil, ErrClientCreation
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !config.OPA.CheckCert {
+		// #nosec G402 This is only set as a result of explicit configuration
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &PolicyClient{
+		URL:        config.OPA.URL,
+		FailOpen:   config.OPA.FailOpen,
+		CacheTTL:   time.Duration(config.OPA.CacheTTL) * time.Second,
+		PromStats:  promStats,
+		httpClient: &http.Client{Transport: transport, Timeout: 5 * time.Second},
+		cache:      make(map[string]cachedPolicyDecision),
+	}, nil
+}
+
+// Apply asks OPA for a decision about the event, overrides its priority if required and returns
+// the outputs to send it to, false means the event must be dropped.
+func (p *PolicyClient) Apply(falcopayload *types.FalcoPayload) (OutputSelection, bool) {
+	decision, err := p.decide(*falcopayload)
+	if err != nil {
+		p.countMetric(Error)
+		if p.FailOpen {
+			log.Printf("[ERROR] : OPA - %v, event is sent to all outputs\n", err)
+			return nil, true
+		}
+		log.Printf("[ERROR] : OPA - %v, event is dropped\n", err)
+		return nil, false
+	}
+
+	if decision.Allow != nil && !*decision.Allow {
+		p.countMetric(Rejected)
+		return nil, false
+	}
+	p.countMetric(Accepted)
+
+	if decision.Priority != "" {
+		falcopayload.Priority = types.Priority(decision.Priority)
+	}
+	if decision.Outputs != nil {
+		return NewOutputSelection(decision.Outputs...), true
+	}
+	return nil, true
+}
+
+func (p *PolicyClient) countMetric(status string) {
+	if p.PromStats != nil && p.PromStats.Outputs != nil {
+		p.PromStats.Outputs.With(map[string]string{"destination": "opa", "status": status}).Inc()
+	}
+}
+
+func (p *PolicyClient) decide(falcopayload types.FalcoPayload) (PolicyDecision, error) {
+	key := policyCacheKey(falcopayload)
+	now := time.Now()
+	if p.CacheTTL > 0 {
+		p.mu.Lock()
+		d, ok := p.cache[key]
+		p.mu.Unlock()
+		if ok && now.Before(d.expire) {
+			return d.decision, nil
+		}
+	}
+
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(policyInput{Input: falcopayload}); err != nil {
+		return PolicyDecision{}, err
+	}
+	resp, err := p.httpClient.Post(p.URL, "application/json", body)
+	if err != nil {
+		return PolicyDecision{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return PolicyDecision{}, fmt.Errorf("Unexpected Response (%v)", resp.StatusCode)
+	}
+
+	var r policyResult
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return PolicyDecision{}, err
+	}
+	if r.Result == nil {
+		return PolicyDecision{}, errors.New("undefined decision")
+	}
+
+	if p.CacheTTL > 0 {
+		p.mu.Lock()
+		for i, j := range p.cache {
+			if now.After(j.expire) {
+				delete(p.cache, i)
+			}
+		}
+		p.cache[key] = cachedPolicyDecision{decision: *r.Result, expire: now.Add(p.CacheTTL)}
+		p.mu.Unlock()
+	}
+	return *r.Result, nil
+}
+
+// policyCacheKey identifies events with the same rule, priority and output fields, whatever their time
+func policyCacheKey(falcopayload types.FalcoPayload) string {
+	b, _ := json.Marshal(struct {
+		Rule         string                 `json:"rule"`
+		Priority     string                 `json:"priority"`
+		OutputFields map[string]interface{} `json:"output_fields"`
+	}{falcopayload.Rule, falcopayload.Priority.String(), falcopayload.OutputFields})
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,85 @@
+package outputs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestPolicyClientApply(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var input struct {
+			Input types.FalcoPayload `json:"input"`
+		}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&input))
+		switch input.Input.Rule {
+		case "Test rule":
+			w.Write([]byte(`{"result":{"allow":true,"outputs":["Slack","webhook"],"priority":"Critical"}}`))
+		case "Denied rule":
+			w.Write([]byte(`{"result":{"allow":false}}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{OPA: types.OPAConfig{URL: ts.URL, CacheTTL: 60}}
+	p, err := NewPolicyClient(config, newTestPromStats())
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	targets, allowed := p.Apply(&f)
+	require.True(t, allowed)
+	require.True(t, targets.Has("Slack"))
+	require.True(t, targets.Has("Webhook"))
+	require.False(t, targets.Has("Teams"))
+	require.Equal(t, types.PriorityType(types.Critical), f.Priority)
+
+	// same rule, priority and fields, the decision comes from the cache
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	_, allowed = p.Apply(&f)
+	require.True(t, allowed)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	f.Rule = "Denied rule"
+	_, allowed = p.Apply(&f)
+	require.False(t, allowed)
+
+	// undefined decision
+	f.Rule = "Unknown rule"
+	p.FailOpen = false
+	_, allowed = p.Apply(&f)
+	require.False(t, allowed)
+	p.FailOpen = true
+	f.Rule = "Other rule"
+	targets, allowed = p.Apply(&f)
+	require.True(t, allowed)
+	require.True(t, targets.Has("Teams"))
+}
+
+func TestOutputSelectionIntersect(t *testing.T) {
+	routed := NewOutputSelection("Slack", "Pagerduty")
+	scheduled := NewOutputSelection("pagerduty", "Webhook")
+
+	require.Equal(t, NewOutputSelection("Pagerduty"), routed.Intersect(scheduled))
+	// nil is all the outputs
+	require.Equal(t, routed, routed.Intersect(nil))
+	require.Equal(t, scheduled, OutputSelection(nil).Intersect(scheduled))
+	require.Nil(t, OutputSelection(nil).Intersect(nil))
+	// no output in common, the event isn't sent
+	require.Equal(t, OutputSelection{}, routed.Intersect(NewOutputSelection("Webhook")))
+
+	// the names are compared without case and spaces, as the other settings of the outputs
+	require.True(t, NewOutputSelection("Google Chat").Has("googlechat"))
+	require.True(t, NewOutputSelection("googlechat").Has("Google Chat"))
+}
@@ -49,12 +49,12 @@ func NewOpenfaasClient(config *types.Configuration, stats *types.Statistics, pro
 }
 
 // OpenfaasCall .
-func (c *Client) OpenfaasCall(falcopayload types.FalcoPayload) {
+func (c *Client) OpenfaasCall(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Openfaas.Add(Total, 1)
 
-	if c.Config.Openfaas.Kubeconfig != "" {
+	if c.config().Openfaas.Kubeconfig != "" {
 		str, _ := json.Marshal(falcopayload)
-		req := c.KubernetesClient.CoreV1().RESTClient().Post().AbsPath("/api/v1/namespaces/" + c.Config.Openfaas.GatewayNamespace + "/services/" + c.Config.Openfaas.GatewayService + ":" + strconv.Itoa(c.Config.Openfaas.GatewayPort) + "/proxy" + "/function/" + c.Config.Openfaas.FunctionName + "." + c.Config.Openfaas.FunctionNamespace).Body(str)
+		req := c.KubernetesClient.CoreV1().RESTClient().Post().AbsPath("/api/v1/namespaces/" + c.config().Openfaas.GatewayNamespace + "/services/" + c.config().Openfaas.GatewayService + ":" + strconv.Itoa(c.config().Openfaas.GatewayPort) + "/proxy" + "/function/" + c.config().Openfaas.FunctionName + "." + c.config().Openfaas.FunctionNamespace).Body(str)
 		req.SetHeader("event-id", uuid.New().String())
 		req.SetHeader("Content-Type", "application/json")
 		req.SetHeader("User-Agent", "Falcosidekick")
@@ -66,21 +66,23 @@ func (c *Client) OpenfaasCall(falcopayload types.FalcoPayload) {
 			c.Stats.Openfaas.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "openfaas", "status": Error}).Inc()
 			log.Printf("[ERROR] : %v - %v\n", Openfaas, err)
-			return
+			return err
 		}
 		log.Printf("[INFO]  : %v - Function Response : %v\n", Openfaas, string(rawbody))
 	} else {
-		err := c.Post(falcopayload)
+		err := c.post(ctx, falcopayload, falcopayload)
 		if err != nil {
 			go c.CountMetric(Outputs, 1, []string{"output:openfaas", "status:error"})
 			c.Stats.Openfaas.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "openfaas", "status": Error}).Inc()
 			log.Printf("[ERROR] : %v - %v\n", Openfaas, err)
-			return
+			return err
 		}
 	}
-	log.Printf("[INFO]  : %v - Call Function \"%v\" OK\n", Openfaas, c.Config.Openfaas.FunctionName+"."+c.Config.Openfaas.FunctionNamespace)
+	log.Printf("[INFO]  : %v - Call Function \"%v\" OK\n", Openfaas, c.config().Openfaas.FunctionName+"."+c.config().Openfaas.FunctionNamespace)
 	go c.CountMetric(Outputs, 1, []string{"output:openfaas", "status:ok"})
 	c.Stats.Openfaas.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "openfaas", "status": OK}).Inc()
+
+	return nil
 }
@@ -1,19 +1,47 @@
 package outputs
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"log"
+	"net/url"
+	"sort"
+	"strings"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
+// OpsgenieMaxAliasLength is the maximum length of the alias of an alert, the longer ones are replaced by their hash
+const OpsgenieMaxAliasLength int = 512
+
 type opsgeniePayload struct {
 	Message     string            `json:"message"`
+	Alias       string            `json:"alias,omitempty"`
 	Entity      string            `json:"entity,omitempty"`
 	Description string            `json:"description,omitempty"`
 	Details     map[string]string `json:"details,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
 	Priority    string            `json:"priority,omitempty"`
 }
 
+// opsgenieCloseRequest closes the alert with the alias
+type opsgenieCloseRequest struct {
+	alias   string
+	payload opsgenieClosePayload
+}
+
+type opsgenieClosePayload struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+// url returns the URL of the close request of the alert, from the one of the alerts
+func (r *opsgenieCloseRequest) url(alerts string) string {
+	return strings.TrimSuffix(alerts, "/") + "/" + url.PathEscape(r.alias) + "/close?identifierType=alias"
+}
+
 func newOpsgeniePayload(falcopayload types.FalcoPayload, config *types.Configuration) opsgeniePayload {
 	details := make(map[string]string, len(falcopayload.OutputFields))
 	for i, j := range falcopayload.OutputFields {
@@ -25,44 +53,109 @@ func newOpsgeniePayload(falcopayload types.FalcoPayload, config *types.Configura
 		}
 	}
 
-	var prio string
-	switch falcopayload.Priority {
-	case types.Emergency, types.Alert:
-		prio = "P1"
-	case types.Critical:
-		prio = "P2"
-	case types.Error:
-		prio = "P3"
-	case types.Warning:
-		prio = "P4"
-	default:
-		prio = "P5"
+	var tags []string
+	for _, i := range config.Opsgenie.Tags {
+		if v, ok := falcopayload.OutputFields[i]; ok && v != nil {
+			tags = append(tags, fmt.Sprintf("%v:%v", i, v))
+		}
+	}
+	sort.Strings(tags)
+
+	alias, err := opsgenieAlias(falcopayload, config)
+	if err != nil {
+		log.Printf("[ERROR] : OpsGenie - Alias : %v\n", err)
 	}
 
 	return opsgeniePayload{
 		Message:     falcopayload.Output,
+		Alias:       alias,
 		Entity:      "Falcosidekick",
 		Description: falcopayload.Rule,
 		Details:     details,
-		Priority:    prio,
+		Tags:        tags,
+		Priority:    opsgeniePriority(falcopayload.Priority, config),
 	}
 }
 
-// OpsgeniePost posts event to OpsGenie
-func (c *Client) OpsgeniePost(falcopayload types.FalcoPayload) {
+// opsgeniePriority returns the priority of the alert of an event, P1 to P5, from the Priorities configured or by default
+func opsgeniePriority(priority types.PriorityType, config *types.Configuration) string {
+	if p, ok := config.Opsgenie.Priorities[strings.ToLower(priority.String())]; ok {
+		return p
+	}
+	switch priority {
+	case types.Emergency, types.Alert:
+		return "P1"
+	case types.Critical:
+		return "P2"
+	case types.Error:
+		return "P3"
+	case types.Warning:
+		return "P4"
+	default:
+		return "P5"
+	}
+}
+
+// opsgenieAlias returns the alias of the alert of an event, from the Alias template, "" without it. The events with
+// the same values for the fields of the template get the same alias.
+func opsgenieAlias(falcopayload types.FalcoPayload, config *types.Configuration) (string, error) {
+	if config.Opsgenie.AliasTemplate == nil {
+		return "", nil
+	}
+	alias, err := executeMessageFormat(config.Opsgenie.AliasTemplate, falcopayload)
+	if err != nil {
+		return "", err
+	}
+	if len(alias) > OpsgenieMaxAliasLength {
+		h := sha256.Sum256([]byte(alias))
+		alias = hex.EncodeToString(h[:])
+	}
+	return alias, nil
+}
+
+// newOpsgenieCloseRequest returns the close request of the alert resolved by the event, nil if its rule isn't one of
+// the CloseRules. The alias of the alert is the one of the event with the rule of the alert.
+func newOpsgenieCloseRequest(falcopayload types.FalcoPayload, config *types.Configuration) (*opsgenieCloseRequest, error) {
+	rule, ok := config.Opsgenie.CloseRules[strings.ToLower(falcopayload.Rule)]
+	if !ok {
+		return nil, nil
+	}
+	resolved := falcopayload
+	resolved.Rule = rule
+	alias, err := opsgenieAlias(resolved, config)
+	if err != nil || alias == "" {
+		return nil, err
+	}
+	return &opsgenieCloseRequest{alias: alias, payload: opsgenieClosePayload{Source: "Falcosidekick", Note: falcopayload.Output}}, nil
+}
+
+// OpsgeniePost posts event to OpsGenie, the events of the CloseRules close the alert they resolve
+func (c *Client) OpsgeniePost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Opsgenie.Add(Total, 1)
 
-	err := c.Post(newOpsgeniePayload(falcopayload, c.Config))
+	r, err := newOpsgenieCloseRequest(falcopayload, c.config())
+	switch {
+	case err != nil:
+	case r != nil:
+		err = c.postURL(ctx, r.url(c.EndpointURL.String()), r.payload, falcopayload)
+	default:
+		err = c.post(ctx, newOpsgeniePayload(falcopayload, c.config()), falcopayload)
+	}
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:opsgenie", "status:error"})
 		c.Stats.Opsgenie.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "opsgenie", "status": Error}).Inc()
-		log.Printf("[ERROR] : OpsGenie - %v\n", err)
-		return
+		logEventError("OpsGenie", falcopayload, err)
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:opsgenie", "status:ok"})
 	c.Stats.Opsgenie.Add("ok", 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "opsgenie", "status": OK}).Inc()
+	if r != nil {
+		log.Printf("[INFO]  : OpsGenie - Close of alert '%v' OK\n", r.alias)
+	}
+
+	return nil
 }
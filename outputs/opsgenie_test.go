@@ -1,8 +1,14 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"text/template"
 
 	"github.com/stretchr/testify/require"
 
@@ -26,3 +32,56 @@ func TestNewOpsgeniePayload(t *testing.T) {
 
 	require.Equal(t, output, expectedOutput)
 }
+
+func TestOpsgenieAlias(t *testing.T) {
+	config := &types.Configuration{}
+	config.Opsgenie.AliasTemplate = template.Must(template.New("").Funcs(MessageFormatFuncs).Parse(`{{ .Rule }}/{{ .Field "k8s.pod.name" }}`))
+	config.Opsgenie.Priorities = map[string]string{"debug": "P3"}
+	config.Opsgenie.Tags = []string{"proc.name", "k8s.pod.name", "k8s.ns.name"}
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.OutputFields["k8s.pod.name"] = "nginx"
+	first := newOpsgeniePayload(f, config)
+	require.Equal(t, "Test rule/nginx", first.Alias)
+	require.Equal(t, "P3", first.Priority)
+	require.Equal(t, []string{"k8s.pod.name:nginx", "proc.name:falcosidekick"}, first.Tags)
+
+	// the alias is the same for the same entity, whatever the other fields and the output
+	f.Output = "An other test"
+	f.OutputFields["proc.name"] = "bash"
+	require.Equal(t, first.Alias, newOpsgeniePayload(f, config).Alias)
+
+	f.OutputFields["k8s.pod.name"] = "redis"
+	require.NotEqual(t, first.Alias, newOpsgeniePayload(f, config).Alias)
+
+	f.OutputFields["k8s.pod.name"] = strings.Repeat("a", OpsgenieMaxAliasLength)
+	alias := newOpsgeniePayload(f, config).Alias
+	require.Len(t, alias, 64)
+	require.Equal(t, alias, newOpsgeniePayload(f, config).Alias)
+}
+
+func TestOpsgeniePostClose(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GenieKey key", r.Header.Get("Authorization"))
+		paths = append(paths, r.URL.RequestURI())
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Opsgenie.APIKey = "key"
+	config.Opsgenie.AliasTemplate = template.Must(template.New("").Funcs(MessageFormatFuncs).Parse(`{{ .Rule }}/{{ .Field "k8s.pod.name" }}`))
+	config.Opsgenie.CloseRules = map[string]string{"pod restored": "Test rule"}
+	client, err := NewClient("Opsgenie", ts.URL+"/v2/alerts", false, false, config, &types.Statistics{Opsgenie: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.OutputFields["k8s.pod.name"] = "nginx"
+	require.Nil(t, client.OpsgeniePost(context.Background(), f))
+	f.Rule = "Pod restored"
+	require.Nil(t, client.OpsgeniePost(context.Background(), f))
+	require.Equal(t, []string{"/v2/alerts", "/v2/alerts/Test%20rule%2Fnginx/close?identifierType=alias"}, paths)
+}
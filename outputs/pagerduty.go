@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"log"
 	"time"
 
@@ -10,23 +11,31 @@ import (
 )
 
 // PagerdutyPost posts alert event to Pagerduty
-func (c *Client) PagerdutyPost(falcopayload types.FalcoPayload) {
+func (c *Client) PagerdutyPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Pagerduty.Add(Total, 1)
 
-	event := createPagerdutyEvent(falcopayload, c.Config.Pagerduty)
+	if c.RateLimiter != nil {
+		if err := c.rateLimit(ctx); err != nil {
+			return err
+		}
+	}
+
+	event := createPagerdutyEvent(falcopayload, c.config().Pagerduty)
 
 	if _, err := pagerduty.ManageEvent(event); err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:pagerduty", "status:error"})
 		c.Stats.Pagerduty.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "pagerduty", "status": Error}).Inc()
-		log.Printf("[ERROR] : PagerDuty - %v\n", err)
-		return
+		logEventError("PagerDuty", falcopayload, err)
+		return err
 	}
 
 	go c.CountMetric(Outputs, 1, []string{"output:pagerduty", "status:ok"})
 	c.Stats.Pagerduty.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "pagerduty", "status": OK}).Inc()
 	log.Printf("[INFO]  : Pagerduty - Create Incident OK\n")
+
+	return nil
 }
 
 func createPagerdutyEvent(falcopayload types.FalcoPayload, config types.PagerdutyConfig) pagerduty.V2Event {
@@ -41,5 +50,8 @@ func createPagerdutyEvent(falcopayload types.FalcoPayload, config types.Pagerdut
 			Details:   falcopayload.OutputFields,
 		},
 	}
+	if u := runbookURL(falcopayload); u != "" {
+		event.Links = []interface{}{map[string]string{"href": u, "text": "Runbook"}}
+	}
 	return event
 }
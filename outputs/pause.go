@@ -0,0 +1,85 @@
+package outputs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Values for RetryAfterPolicy, what to do with events while an output is paused after a Retry-After
+const (
+	Queue string = "queue"
+	Drop  string = "drop"
+)
+
+// ErrOutputPaused is returned for events dropped while an output is paused
+var ErrOutputPaused = errors.New("Output paused by Retry-After")
+
+// parseRetryAfter returns the duration of a Retry-After header, in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(value); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// pauseFor pauses the whole output for d, a longer pause already in progress is kept
+func (c *Client) pauseFor(d time.Duration, reason string) {
+	until := time.Now().Add(d).UnixNano()
+	for {
+		current := atomic.LoadInt64(&c.pausedUntil)
+		if current >= until {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&c.pausedUntil, current, until) {
+			log.Printf("[WARN]  : %v - Paused for %v (%v)\n", c.OutputType, d, reason)
+			return
+		}
+	}
+}
+
+// nextBackpressure returns the pause of the output after a 429, BackpressureDelay for the first one then doubled up to
+// BackpressureMaxDelay, the 429s of the requests sent before the end of the current pause don't increase it
+func (c *Client) nextBackpressure() time.Duration {
+	d := time.Duration(atomic.LoadInt64(&c.backpressure))
+	if d > 0 && time.Now().UnixNano() < atomic.LoadInt64(&c.pausedUntil) {
+		return d
+	}
+	if d *= 2; d < c.BackpressureDelay {
+		d = c.BackpressureDelay
+	}
+	if c.BackpressureMaxDelay > 0 && d > c.BackpressureMaxDelay {
+		d = c.BackpressureMaxDelay
+	}
+	atomic.StoreInt64(&c.backpressure, int64(d))
+	return d
+}
+
+// waitPause waits for the end of the pause of the output with the Queue policy or returns ErrOutputPaused with the Drop one
+func (c *Client) waitPause(ctx context.Context) error {
+	d := time.Until(time.Unix(0, atomic.LoadInt64(&c.pausedUntil)))
+	if d <= 0 {
+		return nil
+	}
+	if c.RetryAfterPolicy == Drop {
+		log.Printf("[ERROR] : %v - %v, event dropped\n", c.OutputType, ErrOutputPaused)
+		return ErrOutputPaused
+	}
+	return sleepContext(ctx, d)
+}
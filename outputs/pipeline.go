@@ -0,0 +1,66 @@
+package outputs
+
+import (
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Steps of the processing pipeline of an output, run in the configured order on a copy of the event before it's sent
+const (
+	TransformStep string = "transform" // renames the output fields with FieldsMapping
+	RedactStep    string = "redact"    // removes the RedactFields, and redacts the secrets with the Redaction of the output
+	ValidateStep  string = "validate"  // checks the RequiredFields are present
+)
+
+// DefaultPipeline is the order of the steps of an output without pipeline configured
+var DefaultPipeline = []string{ValidateStep, TransformStep, RedactStep}
+
+// runPipeline runs the steps on a copy of the event, it returns the copy and the first required field missing
+// when it's validated, if any, a required field removed or redacted by a previous step is missing.
+// All the steps are run, the caller handles the missing field with its RequiredFieldsAction.
+func runPipeline(falcopayload types.FalcoPayload, steps []string, fieldsMapping map[string]string, redactFields []string, redaction *Redaction, requiredFields []string) (types.FalcoPayload, string) {
+	if len(steps) == 0 {
+		steps = DefaultPipeline
+	}
+	falcopayload.OutputFields = copyOutputFields(falcopayload.OutputFields)
+
+	missing := ""
+	redacted := make(map[string]bool)
+	for _, step := range steps {
+		switch step {
+		case TransformStep:
+			if len(fieldsMapping) == 0 {
+				continue
+			}
+			// the names are mapped from the fields before the step, a field isn't renamed twice
+			mapped := make(map[string]interface{}, len(falcopayload.OutputFields))
+			for i, j := range falcopayload.OutputFields {
+				if n, ok := fieldsMapping[i]; ok && n != "" {
+					i = n
+				}
+				mapped[i] = j
+			}
+			falcopayload.OutputFields = mapped
+		case RedactStep:
+			for _, i := range redactFields {
+				delete(falcopayload.OutputFields, i)
+			}
+			// the event as received still has the fields removed
+			if len(redactFields) != 0 {
+				falcopayload.RawPayload = nil
+			}
+			if redaction == nil {
+				continue
+			}
+			for _, i := range redaction.redact(&falcopayload) {
+				redacted[i] = true
+			}
+		case ValidateStep:
+			for _, i := range requiredFields {
+				if v, ok := falcopayload.OutputFields[i]; missing == "" && (!ok || v == nil || redacted[i]) {
+					missing = i
+				}
+			}
+		}
+	}
+	return falcopayload, missing
+}
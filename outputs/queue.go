@@ -0,0 +1,133 @@
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Overflow policies of the queue of an output, when it's full
+const (
+	QueueBlock      string = "block"       // the dispatch of the event waits for room in the queue
+	QueueDropOldest string = "drop-oldest" // the oldest event of the queue is dropped for the new one
+	QueueDropNewest string = "drop-newest" // the new event is dropped
+)
+
+// OutputQueue is the bounded queue of the events of an output, drained by its workers, so a slow output doesn't
+// accumulate goroutines. The events dropped when it's full are counted with the status dropped in
+// falcosidekick_outputs.
+type OutputQueue struct {
+	output    string
+	policy    string
+	jobs      chan queuedEvent
+	drainer   *Drainer
+	promStats *types.PromStatistics
+}
+
+type queuedEvent struct {
+	ctx          context.Context
+	o            *dispatchedOutput
+	falcopayload types.FalcoPayload
+}
+
+// NewOutputQueues returns the queues configured by output ("workers/size/policy", ex: 4/1000/drop-oldest, the policy
+// is block by default) with their workers started, all the outputs must be enabled
+func NewOutputQueues(queues map[string]string, enabledOutputs []string, drainer *Drainer, promStats *types.PromStatistics) (map[string]*OutputQueue, error) {
+	enabled := enabledOutputSet(enabledOutputs)
+	q := make(map[string]*OutputQueue, len(queues))
+	for i, j := range queues {
+		if !enabled[dispatchName(i)] {
+			return nil, fmt.Errorf("Output '%v' with a queue isn't enabled", i)
+		}
+		s := strings.Split(strings.TrimSpace(j), "/")
+		if len(s) == 2 {
+			s = append(s, QueueBlock)
+		}
+		if len(s) != 3 {
+			return nil, fmt.Errorf("Bad queue '%v' of output '%v', must be workers/size/policy (ex: 4/1000/drop-oldest)", j, i)
+		}
+		workers, err := strconv.Atoi(s[0])
+		if err != nil || workers <= 0 {
+			return nil, fmt.Errorf("Bad number of workers '%v' of output '%v'", s[0], i)
+		}
+		size, err := strconv.Atoi(s[1])
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("Bad size '%v' of the queue of output '%v'", s[1], i)
+		}
+		switch s[2] {
+		case QueueBlock, QueueDropOldest, QueueDropNewest:
+		default:
+			return nil, fmt.Errorf("Bad overflow policy '%v' of output '%v', must be %v, %v or %v", s[2], i, QueueBlock, QueueDropOldest, QueueDropNewest)
+		}
+		q[dispatchName(i)] = newOutputQueue(i, workers, size, s[2], drainer, promStats)
+	}
+	return q, nil
+}
+
+func newOutputQueue(output string, workers, size int, policy string, drainer *Drainer, promStats *types.PromStatistics) *OutputQueue {
+	q := &OutputQueue{
+		output:    output,
+		policy:    policy,
+		jobs:      make(chan queuedEvent, size),
+		drainer:   drainer,
+		promStats: promStats,
+	}
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// work sends the events of the queue to the output
+func (q *OutputQueue) work() {
+	for j := range q.jobs {
+		j.o.err = j.o.output.Send(j.ctx, j.falcopayload)
+		close(j.o.done)
+		atomic.AddInt64(&q.drainer.inflight, -1)
+	}
+}
+
+// push queues the event for the output, a full queue is handled with its policy
+func (q *OutputQueue) push(ctx context.Context, o *dispatchedOutput, falcopayload types.FalcoPayload) {
+	atomic.AddInt64(&q.drainer.inflight, 1)
+	j := queuedEvent{ctx: ctx, o: o, falcopayload: falcopayload}
+	switch q.policy {
+	case QueueBlock:
+		q.jobs <- j
+	case QueueDropNewest:
+		select {
+		case q.jobs <- j:
+		default:
+			q.drop(j)
+		}
+	case QueueDropOldest:
+		for {
+			select {
+			case q.jobs <- j:
+				return
+			default:
+			}
+			select {
+			case old := <-q.jobs:
+				q.drop(old)
+			default:
+			}
+		}
+	}
+}
+
+// drop ends the sending of an event not sent because the queue is full
+func (q *OutputQueue) drop(j queuedEvent) {
+	j.o.err = ErrEventDropped
+	close(j.o.done)
+	atomic.AddInt64(&q.drainer.inflight, -1)
+	if q.promStats != nil && q.promStats.Outputs != nil {
+		q.promStats.Outputs.With(map[string]string{"destination": dispatchName(q.output), "status": Dropped}).Inc()
+	}
+	log.Printf("[WARN]  : %v - Event dropped, queue full (%v)\n", q.output, q.policy)
+}
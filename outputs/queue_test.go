@@ -0,0 +1,114 @@
+package outputs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestOutputQueues(t *testing.T) {
+	for policy, expected := range map[string][]string{
+		QueueDropNewest: {"0", "1", "2"},
+		QueueDropOldest: {"0", "3", "4"},
+	} {
+		promStats := newTestPromStats()
+		d, err := NewDispatcher(nil, []string{"Slack", "Webhook"}, new(Drainer), promStats)
+		require.Nil(t, err)
+		d.Queues, err = NewOutputQueues(map[string]string{"webhook": "1/2/" + policy}, []string{"Slack", "Webhook"}, d.Drainer, promStats)
+		require.Nil(t, err)
+
+		// the webhook is stuck until release is closed, its single worker blocks on the first event
+		release := make(chan struct{})
+		started := make(chan struct{}, 5)
+		var mu sync.Mutex
+		var sent []string
+		webhook := OutputFunc(func(ctx context.Context, falcopayload types.FalcoPayload) error {
+			started <- struct{}{}
+			<-release
+			mu.Lock()
+			sent = append(sent, falcopayload.Rule)
+			mu.Unlock()
+			return nil
+		})
+		var slack int32
+		for i, rule := range []string{"0", "1", "2", "3", "4"} {
+			x := d.NewDispatch(types.FalcoPayload{Rule: rule})
+			x.Add("Webhook", webhook)
+			x.Add("Slack", OutputFunc(func(context.Context, types.FalcoPayload) error { atomic.AddInt32(&slack, 1); return nil }))
+			x.Run()
+			if i == 0 {
+				<-started
+			}
+		}
+
+		// the other outputs aren't blocked by the webhook
+		require.Eventually(t, func() bool { return atomic.LoadInt32(&slack) == 5 }, time.Second, 10*time.Millisecond, policy)
+		require.Equal(t, float64(2), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "webhook", "status": Dropped})), policy)
+		require.Equal(t, float64(0), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "slack", "status": Dropped})), policy)
+
+		close(release)
+		require.Eventually(t, func() bool { return atomic.LoadInt64(&d.Drainer.inflight) == 0 }, time.Second, 10*time.Millisecond, policy)
+		require.Equal(t, expected, sent, policy)
+	}
+}
+
+func TestOutputQueueBlock(t *testing.T) {
+	promStats := newTestPromStats()
+	d, err := NewDispatcher(nil, []string{"Slack", "Webhook"}, new(Drainer), promStats)
+	require.Nil(t, err)
+	d.Queues, err = NewOutputQueues(map[string]string{"webhook": "1/1", "slack": "1/1"}, []string{"Slack", "Webhook"}, d.Drainer, promStats)
+	require.Nil(t, err)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	var slack int32
+	dispatch := func() *Dispatch {
+		x := d.NewDispatch(types.FalcoPayload{})
+		x.Add("Webhook", OutputFunc(func(context.Context, types.FalcoPayload) error { started <- struct{}{}; <-release; return nil }))
+		x.Add("Slack", OutputFunc(func(context.Context, types.FalcoPayload) error { atomic.AddInt32(&slack, 1); return nil }))
+		x.Run()
+		return x
+	}
+	dispatch()
+	<-started
+	dispatch()
+
+	// the queue of the Webhook is full, its event waits for room without delaying the queue of Slack
+	x := dispatch()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&slack) == 3 }, time.Second, 10*time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		x.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("The event should wait for room in the queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-done
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&d.Drainer.inflight) == 0 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, float64(0), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "webhook", "status": Dropped})))
+}
+
+func TestNewOutputQueuesErrors(t *testing.T) {
+	for _, i := range []map[string]string{
+		{"slack": "1/10"},
+		{"webhook": "0/10"},
+		{"webhook": "1/0"},
+		{"webhook": "1/10/drop"},
+		{"webhook": "10"},
+	} {
+		_, err := NewOutputQueues(i, []string{"Webhook"}, new(Drainer), nil)
+		require.NotNil(t, err, i)
+	}
+}
@@ -0,0 +1,154 @@
+package outputs
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// the timezones are embedded for the images without tzdata
+	_ "time/tzdata"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// DigestRule is the rule of the events summarizing the events deferred during quiet hours
+const DigestRule string = "Falcosidekick quiet hours digest"
+
+// quietWindow is a daily period of quiet hours, in minutes since midnight, end is before start for a window over midnight
+type quietWindow struct {
+	start, end int
+}
+
+func (w quietWindow) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.start <= w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// QuietHours defers the events below a priority sent to some outputs during their quiet hours,
+// they're sent in a single digest event to each output once its quiet hours are over
+type QuietHours struct {
+	ExemptPriority types.PriorityType
+	Location       *time.Location
+	windows        map[string]quietWindow // output: quiet hours, names are lowercased without spaces
+	names          map[string]string      // output lowercased without spaces: name in EnabledOutputs
+	send           func(output string, digest types.FalcoPayload)
+	mu             sync.Mutex
+	deferred       map[string][]types.FalcoPayload
+	now            func() time.Time
+}
+
+// NewQuietHours returns QuietHours for the windows configured (output: "HH:MM-HH:MM"), all the outputs must be enabled,
+// the digests are sent with send
+func NewQuietHours(config *types.Configuration, enabledOutputs []string, send func(output string, digest types.FalcoPayload)) (*QuietHours, error) {
+	location, err := time.LoadLocation(config.QuietHours.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	enabled := make(map[string]string, len(enabledOutputs))
+	for _, i := range enabledOutputs {
+		enabled[dispatchName(i)] = i
+	}
+	q := &QuietHours{
+		ExemptPriority: types.Priority(config.QuietHours.ExemptPriority),
+		Location:       location,
+		windows:        make(map[string]quietWindow),
+		names:          enabled,
+		send:           send,
+		deferred:       make(map[string][]types.FalcoPayload),
+		now:            time.Now,
+	}
+	for output, window := range config.QuietHours.Windows {
+		output = dispatchName(output)
+		if _, ok := enabled[output]; !ok {
+			return nil, fmt.Errorf("Output '%v' with quiet hours isn't enabled", output)
+		}
+		w, err := parseQuietWindow(window)
+		if err != nil {
+			return nil, fmt.Errorf("Bad quiet hours '%v' for output '%v', must be 'HH:MM-HH:MM'", window, output)
+		}
+		q.windows[output] = w
+	}
+	return q, nil
+}
+
+func parseQuietWindow(window string) (quietWindow, error) {
+	bounds := strings.Split(window, "-")
+	if len(bounds) != 2 {
+		return quietWindow{}, fmt.Errorf("bad window")
+	}
+	var minutes [2]int
+	for i, j := range bounds {
+		t, err := time.Parse("15:04", strings.TrimSpace(j))
+		if err != nil {
+			return quietWindow{}, err
+		}
+		minutes[i] = t.Hour()*60 + t.Minute()
+	}
+	return quietWindow{start: minutes[0], end: minutes[1]}, nil
+}
+
+// Defer returns true if the event mustn't be sent to the output now, it's then kept for the digest
+func (q *QuietHours) Defer(output string, falcopayload types.FalcoPayload) bool {
+	if q == nil || falcopayload.Rule == DigestRule || (q.ExemptPriority != types.Default && falcopayload.Priority >= q.ExemptPriority) {
+		return false
+	}
+	output = dispatchName(output)
+	w, ok := q.windows[output]
+	if !ok || !w.contains(q.now().In(q.Location)) {
+		return false
+	}
+	q.mu.Lock()
+	q.deferred[output] = append(q.deferred[output], falcopayload)
+	q.mu.Unlock()
+	return true
+}
+
+// Run sends the digests of the outputs once their quiet hours are over, it checks them every interval
+func (q *QuietHours) Run(interval time.Duration) {
+	for range time.Tick(interval) {
+		q.flush()
+	}
+}
+
+// flush sends the digests of the outputs not in their quiet hours anymore
+func (q *QuietHours) flush() {
+	now := q.now().In(q.Location)
+	q.mu.Lock()
+	digests := make(map[string][]types.FalcoPayload)
+	for output, events := range q.deferred {
+		if len(events) != 0 && !q.windows[output].contains(now) {
+			digests[output] = events
+			delete(q.deferred, output)
+		}
+	}
+	q.mu.Unlock()
+
+	for output, events := range digests {
+		q.send(q.names[output], newDigest(events, now))
+	}
+}
+
+// newDigest returns an event summarizing the events deferred, with the highest priority of them
+func newDigest(events []types.FalcoPayload, now time.Time) types.FalcoPayload {
+	d := types.FalcoPayload{
+		Rule:         DigestRule,
+		Time:         now,
+		OutputFields: map[string]interface{}{"digest.count": len(events)},
+	}
+	var lines []string
+	rules := make(map[string]int)
+	for _, i := range events {
+		if i.Priority > d.Priority {
+			d.Priority = i.Priority
+		}
+		rules[i.Rule]++
+		lines = append(lines, fmt.Sprintf("%v: %v", i.Time.In(now.Location()).Format(time.RFC3339), i.Output))
+	}
+	d.OutputFields["digest.rules"] = rules
+	d.Output = fmt.Sprintf("%v events during quiet hours\n%v", len(events), strings.Join(lines, "\n"))
+	return d
+}
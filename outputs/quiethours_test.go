@@ -0,0 +1,84 @@
+package outputs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestQuietHours(t *testing.T) {
+	config := &types.Configuration{QuietHours: types.QuietHoursConfig{
+		Windows:        map[string]string{"slack": "22:00-07:00"},
+		Timezone:       "Europe/Paris",
+		ExemptPriority: "critical",
+	}}
+	digests := make(map[string]types.FalcoPayload)
+	q, err := NewQuietHours(config, []string{"Slack", "Webhook"}, func(output string, digest types.FalcoPayload) { digests[output] = digest })
+	require.Nil(t, err)
+
+	promStats := newTestPromStats()
+	d, err := NewDispatcher(nil, []string{"Slack", "Webhook"}, new(Drainer), promStats)
+	require.Nil(t, err)
+	d.QuietHours = q
+
+	paris, _ := time.LoadLocation("Europe/Paris")
+	now := time.Date(2021, 6, 1, 23, 30, 0, 0, paris)
+	q.now = func() time.Time { return now }
+
+	var mu sync.Mutex
+	var sent []string
+	dispatch := func(falcopayload types.FalcoPayload) {
+		x := d.NewDispatch(falcopayload)
+		for _, i := range []string{"Slack", "Webhook"} {
+			i := i
+			x.Add(i, OutputFunc(func(context.Context, types.FalcoPayload) error {
+				mu.Lock()
+				sent = append(sent, i+":"+falcopayload.Priority.String())
+				mu.Unlock()
+				return nil
+			}))
+		}
+		x.Run()
+		x.Wait()
+	}
+
+	// during the quiet hours, only the critical events are sent to Slack
+	dispatch(types.FalcoPayload{Rule: "Test rule", Output: "Notice event", Priority: types.Notice, Time: now})
+	dispatch(types.FalcoPayload{Rule: "Test rule", Output: "Critical event", Priority: types.Critical, Time: now})
+	require.ElementsMatch(t, []string{"Webhook:Notice", "Slack:Critical", "Webhook:Critical"}, sent)
+	require.Equal(t, float64(1), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "slack", "status": Deferred})))
+
+	q.flush()
+	require.Len(t, digests, 0)
+
+	// the deferred events are flushed once the quiet hours are over
+	now = time.Date(2021, 6, 2, 7, 0, 0, 0, paris)
+	q.flush()
+	require.Len(t, digests, 1)
+	digest := digests["Slack"]
+	require.Equal(t, DigestRule, digest.Rule)
+	require.Equal(t, types.PriorityType(types.Notice), digest.Priority)
+	require.Equal(t, 1, digest.OutputFields["digest.count"])
+	require.Contains(t, digest.Output, "2021-06-01T23:30:00+02:00: Notice event")
+	require.False(t, q.Defer("Slack", digest))
+
+	q.flush()
+	require.Len(t, digests, 1)
+}
+
+func TestNewQuietHoursErrors(t *testing.T) {
+	for _, i := range []types.QuietHoursConfig{
+		{Windows: map[string]string{"slack": "22:00-07:00"}, Timezone: "Mars/Olympus"},
+		{Windows: map[string]string{"pagerduty": "22:00-07:00"}},
+		{Windows: map[string]string{"slack": "22h-7h"}},
+	} {
+		_, err := NewQuietHours(&types.Configuration{QuietHours: i}, []string{"Slack"}, nil)
+		require.NotNil(t, err)
+	}
+}
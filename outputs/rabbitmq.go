@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
@@ -40,12 +41,12 @@ func NewRabbitmqClient(config *types.Configuration, stats *types.Statistics, pro
 }
 
 // Publish sends a message to a Rabbitmq
-func (c *Client) Publish(falcopayload types.FalcoPayload) {
+func (c *Client) Publish(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Rabbitmq.Add(Total, 1)
 
 	payload, _ := json.Marshal(falcopayload)
 
-	err := c.RabbitmqClient.Publish("", c.Config.Rabbitmq.Queue, false, false, amqp.Publishing{
+	err := c.RabbitmqClient.Publish("", c.config().Rabbitmq.Queue, false, false, amqp.Publishing{
 		ContentType: "text/plain",
 		Body:        payload,
 	})
@@ -56,11 +57,13 @@ func (c *Client) Publish(falcopayload types.FalcoPayload) {
 		go c.CountMetric("outputs", 1, []string{"output:rabbitmq", "status:error"})
 		c.PromStats.Outputs.With(map[string]string{"destination": "rabbitmq", "status": Error}).Inc()
 
-		return
+		return err
 	}
 
 	log.Printf("[INFO]  : RabbitMQ - Send to message OK \n")
 	c.Stats.Rabbitmq.Add(OK, 1)
 	go c.CountMetric("outputs", 1, []string{"output:rabbitmq", "status:ok"})
 	c.PromStats.Outputs.With(map[string]string{"destination": "rabbitmq", "status": OK}).Inc()
+
+	return nil
 }
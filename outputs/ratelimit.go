@@ -0,0 +1,69 @@
+package outputs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+// Values for RateLimitMode, what to do with events exceeding the MaxRate of an output, Block or Drop
+const (
+	Block string = "block"
+)
+
+// ErrRateLimited is returned for events dropped by the rate limiter of an output
+var ErrRateLimited = errors.New("Rate limit of the output exceeded")
+
+// NewRateLimiter returns a token bucket for a MaxRate "N/s", "N/m" or "N/h", nil if it's empty.
+// The bucket holds the events of one second (at least 1), it's safe for concurrent use.
+func NewRateLimiter(maxRate string) (*rate.Limiter, error) {
+	if maxRate == "" {
+		return nil, nil
+	}
+	s := strings.SplitN(strings.TrimSpace(maxRate), "/", 2)
+	if len(s) != 2 {
+		return nil, fmt.Errorf("Bad MaxRate '%v', must be N/s, N/m or N/h", maxRate)
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s[0]), 64)
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("Bad MaxRate '%v', must be N/s, N/m or N/h", maxRate)
+	}
+	var limit rate.Limit
+	switch strings.TrimSpace(s[1]) {
+	case "s":
+		limit = rate.Limit(n)
+	case "m":
+		limit = rate.Limit(n / 60)
+	case "h":
+		limit = rate.Limit(n / 3600)
+	default:
+		return nil, fmt.Errorf("Bad MaxRate '%v', must be N/s, N/m or N/h", maxRate)
+	}
+	return rate.NewLimiter(limit, int(math.Max(1, math.Floor(float64(limit))))), nil
+}
+
+// rateLimit waits for the RateLimiter of the output in block mode, until the deadline of the event,
+// in drop mode the event is dropped and counted as ratelimited if the output is over its rate
+func (c *Client) rateLimit(ctx context.Context) error {
+	if c.RateLimitMode == Drop {
+		if c.RateLimiter.Allow() {
+			return nil
+		}
+		log.Printf("[WARN]  : %v - %v, event dropped\n", c.OutputType, ErrRateLimited)
+		go c.CountMetric("outputs.ratelimited", 1, []string{"output:" + strings.ToLower(c.OutputType)})
+		if c.PromStats != nil && c.PromStats.RateLimited != nil {
+			c.PromStats.RateLimited.With(map[string]string{"destination": strings.ToLower(c.OutputType)}).Inc()
+		}
+		return ErrRateLimited
+	}
+	if err := c.RateLimiter.Wait(ctx); err != nil {
+		return c.deadlineExceeded()
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrNotConnected is returned by the health checks of the outputs which lost their connection
+var ErrNotConnected = errors.New("Not connected")
+
+// HealthChecker is implemented by the outputs able to check their connectivity, for /readyz
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// Readiness aggregates the health checks of the outputs, falcosidekick isn't ready if one of the critical outputs is
+// down. The results are cached for TTL, so the probes don't hammer the outputs.
+type Readiness struct {
+	checkers map[string]HealthChecker
+	critical map[string]bool
+	ttl      time.Duration
+	timeout  time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	results   map[string]readinessResult
+}
+
+type readinessResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Critical bool   `json:"critical"`
+}
+
+// NewReadiness returns the readiness of the outputs with a health check, the critical outputs must be among them
+func NewReadiness(critical []string, checkers map[string]HealthChecker, ttl, timeout time.Duration) (*Readiness, error) {
+	names := make(map[string]bool, len(checkers))
+	for i := range checkers {
+		names[dispatchName(i)] = true
+	}
+	r := &Readiness{checkers: checkers, critical: make(map[string]bool, len(critical)), ttl: ttl, timeout: timeout, now: time.Now}
+	for _, i := range critical {
+		if !names[dispatchName(i)] {
+			return nil, fmt.Errorf("Critical output '%v' isn't enabled or has no health check", i)
+		}
+		r.critical[dispatchName(i)] = true
+	}
+	return r, nil
+}
+
+// status returns the results of the health checks by output, and false if a critical output is down
+func (r *Readiness) status() (map[string]readinessResult, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.results == nil || r.now().Sub(r.checkedAt) >= r.ttl {
+		r.results = r.run()
+		r.checkedAt = r.now()
+	}
+	ready := true
+	for _, i := range r.results {
+		if i.Critical && i.Status != OK {
+			ready = false
+		}
+	}
+	return r.results, ready
+}
+
+// run runs the health checks concurrently, each one within timeout
+func (r *Readiness) run() map[string]readinessResult {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]readinessResult, len(r.checkers))
+	for i, j := range r.checkers {
+		wg.Add(1)
+		go func(name string, checker HealthChecker) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+			defer cancel()
+			result := readinessResult{Status: OK, Critical: r.critical[dispatchName(name)]}
+			if err := checker.HealthCheck(ctx); err != nil {
+				result.Status, result.Error = Error, err.Error()
+			}
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(i, j)
+	}
+	wg.Wait()
+	return results
+}
+
+// Handler returns the handler of /readyz, it responds 503 with the outputs down if a critical one is among them
+func (r *Readiness) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		results, ready := r.status()
+		status, down := OK, []string{}
+		for i, j := range results {
+			if j.Status != OK {
+				down = append(down, i)
+			}
+		}
+		sort.Strings(down)
+
+		w.Header().Add("Content-Type", "application/json")
+		if !ready {
+			status = "unavailable"
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		// #nosec G104 nothing to be done if the following fails
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": status, "down": down, "outputs": results})
+	}
+}
+
+// HealthCheck checks the connectivity of the output: a metadata fetch of the topic for Kafka, the state of the
+// connection for NATS, JetStream and MQTT, a ping for Redis, a request to the cluster for Elasticsearch and a HEAD request to
+// the endpoint for the other outputs, the outputs in dry run are always up
+func (c *Client) HealthCheck(ctx context.Context) error {
+	switch {
+	case c.DryRun:
+		return nil
+	case c.KafkaProducer != nil:
+		client := &kafka.Client{Addr: c.KafkaProducer.Addr, Transport: c.KafkaProducer.Transport}
+		resp, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{c.KafkaProducer.Topic}})
+		if err != nil {
+			return err
+		}
+		for _, i := range resp.Topics {
+			if i.Error != nil {
+				return i.Error
+			}
+		}
+		return nil
+	case c.JetStreamContext != nil:
+		_, err := c.JetStreamContext.AccountInfo(nats.Context(ctx))
+		return err
+	case c.NatsConn != nil:
+		if !c.NatsConn.IsConnected() {
+			return ErrNotConnected
+		}
+		return c.NatsConn.FlushWithContext(ctx)
+	case c.MQTTClient != nil:
+		if !c.MQTTClient.IsConnectionOpen() {
+			return ErrNotConnected
+		}
+		return nil
+	case c.RedisClient != nil:
+		return c.RedisClient.Ping(ctx).Err()
+	case c.syslog != nil:
+		return c.syslog.healthCheck(ctx)
+	case c.gelf != nil:
+		return c.gelf.healthCheck(ctx)
+	case c.EndpointURL == nil:
+		return nil
+	case c.OutputType == "Elasticsearch":
+		return c.elasticsearchHealthCheck(ctx)
+	}
+
+	return c.httpHealthCheck(ctx)
+}
+
+// httpHealthCheck sends a HEAD request to the endpoint, through the proxy of the output if any, any response means the
+// endpoint is up
+func (c *Client) httpHealthCheck(ctx context.Context) error {
+	client, err := c.httpClient()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "HEAD", c.EndpointURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	if proxyAuthorization := c.proxyAuthorization(); proxyAuthorization != "" && sendsProxyAuthorization(client, req) {
+		req.Header.Set("Proxy-Authorization", proxyAuthorization)
+	}
+	req.Header.Add("User-Agent", "Falcosidekick")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// elasticsearchHealthCheck requests the health of the cluster, it's down if its status is red
+func (c *Client) elasticsearchHealthCheck(ctx context.Context) error {
+	client, err := c.httpClient()
+	if err != nil {
+		return err
+	}
+	u := *c.EndpointURL
+	u.Path, u.RawQuery = "/_cluster/health", ""
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if authorization := c.authorization(); authorization != "" {
+		req.Header.Add("Authorization", authorization)
+	} else if bearerToken := c.bearerToken(); bearerToken != "" {
+		req.Header.Add("Authorization", "Bearer "+bearerToken)
+	}
+	req.Header.Add("User-Agent", "Falcosidekick")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return c.responseError(resp, fmt.Errorf("Unexpected response %v", resp.StatusCode))
+	}
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return err
+	}
+	if health.Status == "red" {
+		return errors.New("Cluster status is red")
+	}
+	return nil
+}
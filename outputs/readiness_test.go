@@ -0,0 +1,121 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+type fakeHealthChecker struct {
+	err    error
+	checks int32
+}
+
+func (f *fakeHealthChecker) HealthCheck(ctx context.Context) error {
+	atomic.AddInt32(&f.checks, 1)
+	return f.err
+}
+
+func TestReadinessHandler(t *testing.T) {
+	kafka := &fakeHealthChecker{err: errors.New("connection refused")}
+	loki := &fakeHealthChecker{}
+	r, err := NewReadiness([]string{"kafka"}, map[string]HealthChecker{"Kafka": kafka, "Loki": loki}, 5*time.Second, time.Second)
+	require.Nil(t, err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	w := httptest.NewRecorder()
+	r.Handler()(w, httptest.NewRequest("GET", "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Contains(t, w.Body.String(), "Kafka")
+	require.Contains(t, w.Body.String(), "connection refused")
+	var body struct {
+		Status  string
+		Down    []string
+		Outputs map[string]readinessResult
+	}
+	require.Nil(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Equal(t, "unavailable", body.Status)
+	require.Equal(t, []string{"Kafka"}, body.Down)
+	require.True(t, body.Outputs["Kafka"].Critical)
+	require.Equal(t, OK, body.Outputs["Loki"].Status)
+
+	// the results are cached
+	kafka.err = nil
+	w = httptest.NewRecorder()
+	r.Handler()(w, httptest.NewRequest("GET", "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Equal(t, int32(1), atomic.LoadInt32(&kafka.checks))
+
+	now = now.Add(5 * time.Second)
+	w = httptest.NewRecorder()
+	r.Handler()(w, httptest.NewRequest("GET", "/readyz", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, int32(2), atomic.LoadInt32(&kafka.checks))
+
+	// a non critical output down doesn't make falcosidekick unready
+	loki.err = errors.New("timeout")
+	now = now.Add(5 * time.Second)
+	w = httptest.NewRecorder()
+	r.Handler()(w, httptest.NewRequest("GET", "/readyz", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"down":["Loki"]`)
+
+	_, err = NewReadiness([]string{"elasticsearch"}, map[string]HealthChecker{"Kafka": kafka}, time.Second, time.Second)
+	require.NotNil(t, err)
+}
+
+func TestClientHealthCheck(t *testing.T) {
+	status := "green"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_cluster/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// #nosec G104 test server
+		w.Write([]byte(`{"status": "` + status + `"}`))
+	}))
+
+	config := &types.Configuration{}
+	es, err := NewClient("Elasticsearch", ts.URL+"/falco/_doc", false, false, config, &types.Statistics{Elasticsearch: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	webhook, err := NewClient("Webhook", ts.URL+"/events", false, false, config, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, es.HealthCheck(context.Background()))
+	require.Nil(t, webhook.HealthCheck(context.Background()))
+
+	status = "red"
+	require.NotNil(t, es.HealthCheck(context.Background()))
+
+	ts.Close()
+	err = webhook.HealthCheck(context.Background())
+	require.NotNil(t, err)
+	require.True(t, strings.Contains(err.Error(), "refused"), err)
+
+	// behind a proxy, the endpoint is requested through it
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.Method + " " + r.URL.String()
+	}))
+	defer proxy.Close()
+	transport := http.DefaultTransport.(*http.Transport)
+	proxyFunc := transport.Proxy
+	defer func() { transport.Proxy = proxyFunc }()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.Nil(t, err)
+	transport.Proxy = http.ProxyURL(proxyURL)
+	require.Nil(t, webhook.HealthCheck(context.Background()))
+	require.Equal(t, "HEAD "+ts.URL+"/events", requested)
+}
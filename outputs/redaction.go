@@ -0,0 +1,111 @@
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Redaction replaces by RedactedValue the values of some output fields of the events, and the secrets matching some
+// patterns in their output and in the string values of their fields, before an output sends them
+type Redaction struct {
+	Fields   []string // names of the output fields, with * globs (ex: evt.arg.*)
+	Patterns []*regexp.Regexp
+}
+
+// NewRedaction returns the Redaction of an output for the fields and the patterns configured, nil if there are none
+func NewRedaction(fields, patterns []string) (*Redaction, error) {
+	if len(fields) == 0 && len(patterns) == 0 {
+		return nil, nil
+	}
+	r := &Redaction{}
+	for _, i := range fields {
+		if _, err := path.Match(i, ""); err != nil {
+			return nil, fmt.Errorf("Bad MaskFields '%v' : %v", i, err)
+		}
+		r.Fields = append(r.Fields, i)
+	}
+	for _, i := range patterns {
+		p, err := regexp.Compile(i)
+		if err != nil {
+			return nil, fmt.Errorf("Bad RedactPatterns '%v' : %v", i, err)
+		}
+		r.Patterns = append(r.Patterns, p)
+	}
+	return r, nil
+}
+
+// Redact returns a copy of the event with its secrets redacted, the event is returned as is by a nil Redaction
+func (r *Redaction) Redact(falcopayload types.FalcoPayload) types.FalcoPayload {
+	if r == nil {
+		return falcopayload
+	}
+	falcopayload.OutputFields = copyOutputFields(falcopayload.OutputFields)
+	r.redact(&falcopayload)
+	return falcopayload
+}
+
+// Output returns an Output redacting the events before sending them with send, a nil Redaction sends them as is
+func (r *Redaction) Output(send OutputFunc) Output {
+	if r == nil {
+		return send
+	}
+	return OutputFunc(func(ctx context.Context, falcopayload types.FalcoPayload) error {
+		return send(ctx, r.Redact(falcopayload))
+	})
+}
+
+// redact redacts the event in place, its output fields must be a copy, it returns the names of the fields redacted.
+// The string values of the fields redacted are also redacted from the output of the event, which usually contains them.
+// The event as received is dropped, it has the secrets.
+func (r *Redaction) redact(falcopayload *types.FalcoPayload) []string {
+	falcopayload.RawPayload = nil
+	var redacted []string
+	for i, j := range falcopayload.OutputFields {
+		if !r.matchField(i) {
+			if s, ok := j.(string); ok {
+				falcopayload.OutputFields[i] = r.redactPatterns(s)
+			}
+			continue
+		}
+		if s, ok := j.(string); ok && s != "" {
+			falcopayload.Output = strings.ReplaceAll(falcopayload.Output, s, RedactedValue)
+		}
+		falcopayload.OutputFields[i] = RedactedValue
+		redacted = append(redacted, i)
+	}
+	falcopayload.Output = r.redactPatterns(falcopayload.Output)
+	return redacted
+}
+
+func (r *Redaction) matchField(name string) bool {
+	for _, i := range r.Fields {
+		if ok, _ := path.Match(i, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redaction) redactPatterns(s string) string {
+	for _, i := range r.Patterns {
+		s = i.ReplaceAllString(s, RedactedValue)
+	}
+	return s
+}
+
+// copyOutputFields returns a shallow copy of the output fields of an event, to modify them for an output only
+func copyOutputFields(fields map[string]interface{}) map[string]interface{} {
+	if fields == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(fields))
+	for i, j := range fields {
+		c[i] = j
+	}
+	return c
+}
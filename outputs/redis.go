@@ -0,0 +1,126 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-go/statsd"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Modes of the Redis output
+const (
+	RedisList    string = "list"    // the events are pushed (LPUSH) onto the list of the key
+	RedisPublish string = "publish" // the events are published to the channel of the key
+)
+
+// Backoff between the attempts of a command failing with a network error, the connections to Redis are
+// reestablished by the next attempts
+const (
+	RedisMaxRetries      int           = 5
+	RedisMinRetryBackoff time.Duration = 100 * time.Millisecond
+	RedisMaxRetryBackoff time.Duration = 3 * time.Second
+)
+
+// NewRedisClient returns a new output.Client for pushing or publishing to Redis, the connection is checked at startup
+func NewRedisClient(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics, statsdClient, dogstatsdClient *statsd.Client) (*Client, error) {
+	options := &redis.Options{
+		Addr:            config.Redis.Address,
+		Password:        config.Redis.Password,
+		DB:              config.Redis.Database,
+		MaxRetries:      RedisMaxRetries,
+		MinRetryBackoff: RedisMinRetryBackoff,
+		MaxRetryBackoff: RedisMaxRetryBackoff,
+	}
+	if config.Redis.TLS || config.Redis.MutualTLS {
+		tlsConfig, err := newConnTLSConfig(config, config.Redis.MutualTLS, config.Redis.CheckCert)
+		if err != nil {
+			log.Printf("[ERROR] : Redis - %v\n", err)
+			return nil, err
+		}
+		options.TLSConfig = tlsConfig
+	}
+
+	client := redis.NewClient(options)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		log.Printf("[ERROR] : Redis - %v\n", err)
+		return nil, err
+	}
+
+	return &Client{
+		OutputType:      "Redis",
+		Config:          config,
+		RedisClient:     client,
+		Stats:           stats,
+		PromStats:       promStats,
+		StatsdClient:    statsdClient,
+		DogstatsdClient: dogstatsdClient,
+	}, nil
+}
+
+// RedisPost pushes the event onto the list of its key, trimmed to MaxLength, or publishes it to the channel of its key
+func (c *Client) RedisPost(ctx context.Context, falcopayload types.FalcoPayload) error {
+	c.Stats.Redis.Add(Total, 1)
+
+	payload, err := json.Marshal(falcopayload)
+	if err != nil {
+		c.setRedisErrorMetrics()
+		log.Printf("[ERROR] : Redis - %v\n", err)
+		return err
+	}
+
+	key, err := executeFieldTemplate(c.config().Redis.KeyTemplate, falcopayload, nil)
+	if err != nil {
+		c.setRedisErrorMetrics()
+		logEventError("Redis", falcopayload, err)
+		return err
+	}
+	if c.config().Redis.Mode == RedisPublish {
+		err = c.RedisClient.Publish(ctx, key, payload).Err()
+	} else {
+		_, err = c.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.LPush(ctx, key, payload)
+			if c.config().Redis.MaxLength > 0 {
+				pipe.LTrim(ctx, key, 0, int64(c.config().Redis.MaxLength-1))
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return c.deadlineExceeded()
+		}
+		c.setRedisErrorMetrics()
+		logEventError("Redis", falcopayload, err)
+		return err
+	}
+
+	go c.CountMetric(Outputs, 1, []string{"output:redis", "status:ok"})
+	c.Stats.Redis.Add(OK, 1)
+	c.PromStats.Outputs.With(map[string]string{"destination": "redis", "status": OK}).Inc()
+	log.Printf("[INFO]  : Redis - %v OK (key %v)\n", strings.Title(c.config().Redis.Mode), key)
+
+	return nil
+}
+
+// RedisClose closes the connections to Redis at shutdown
+func (c *Client) RedisClose() {
+	if c.RedisClient != nil {
+		if err := c.RedisClient.Close(); err != nil {
+			log.Printf("[ERROR] : Redis - %v\n", err)
+		}
+	}
+}
+
+// setRedisErrorMetrics set the error stats
+func (c *Client) setRedisErrorMetrics() {
+	go c.CountMetric(Outputs, 1, []string{"output:redis", "status:error"})
+	c.Stats.Redis.Add(Error, 1)
+	c.PromStats.Outputs.With(map[string]string{"destination": "redis", "status": Error}).Inc()
+}
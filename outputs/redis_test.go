@@ -0,0 +1,78 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestRedisPost(t *testing.T) {
+	s, err := miniredis.Run()
+	require.Nil(t, err)
+	defer s.Close()
+	s.RequireAuth("secret")
+
+	config := &types.Configuration{}
+	config.Redis.Address = s.Addr()
+	config.Redis.Password = "wrong"
+	config.Redis.Mode = RedisList
+	config.Redis.KeyTemplate = template.Must(parseFieldTemplate("", `falco:{{ .Field "k8s.ns.name" }}`))
+	config.Redis.MaxLength = 2
+	stats := &types.Statistics{Redis: new(expvar.Map)}
+
+	// the password is refused
+	_, err = NewRedisClient(config, stats, newTestPromStats(), nil, nil)
+	require.NotNil(t, err)
+
+	config.Redis.Password = "secret"
+	client, err := NewRedisClient(config, stats, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	defer client.RedisClose()
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.OutputFields["k8s.ns.name"] = "default"
+	for _, i := range []string{"1", "2", "3"} {
+		f.Rule = i
+		require.Nil(t, client.RedisPost(context.Background(), f))
+	}
+
+	// the list is trimmed to the newest events
+	list, err := s.List("falco:default")
+	require.Nil(t, err)
+	require.Len(t, list, 2)
+	var pushed types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(list[0]), &pushed))
+	require.Equal(t, "3", pushed.Rule)
+	require.Equal(t, f.Output, pushed.Output)
+	require.Nil(t, json.Unmarshal([]byte(list[1]), &pushed))
+	require.Equal(t, "2", pushed.Rule)
+
+	config.Redis.Mode = RedisPublish
+	subscriber := redis.NewClient(&redis.Options{Addr: s.Addr(), Password: "secret"}).Subscribe(context.Background(), "falco:default")
+	_, err = subscriber.Receive(context.Background())
+	require.Nil(t, err)
+	require.Nil(t, client.RedisPost(context.Background(), f))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	m, err := subscriber.ReceiveMessage(ctx)
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal([]byte(m.Payload), &pushed))
+	require.Equal(t, f.Rule, pushed.Rule)
+	subscriber.Close()
+
+	// the connection is reestablished once Redis is back
+	s.Close()
+	require.Nil(t, s.Restart())
+	require.Nil(t, client.RedisPost(context.Background(), f))
+	require.Equal(t, "5", stats.Redis.Get(OK).String())
+}
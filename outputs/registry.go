@@ -0,0 +1,100 @@
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Output sends the events to a destination, the built-in outputs and the ones registered are dispatched the same way,
+// ctx expires with the deadline of the dispatch of the event. Send takes a ctx, unlike the Send(types.FalcoPayload)
+// error first asked for the registered outputs, a plain function of the event is an Output with PayloadFunc.
+type Output interface {
+	Send(ctx context.Context, falcopayload types.FalcoPayload) error
+}
+
+// OutputFunc is a function used as an Output, ex: the post method of a Client
+type OutputFunc func(ctx context.Context, falcopayload types.FalcoPayload) error
+
+// Send calls f
+func (f OutputFunc) Send(ctx context.Context, falcopayload types.FalcoPayload) error {
+	return f(ctx, falcopayload)
+}
+
+// PayloadFunc is a function of the event only used as an Output, it isn't stopped by the deadline of the dispatch
+type PayloadFunc func(falcopayload types.FalcoPayload) error
+
+// Send calls f, ctx is ignored
+func (f PayloadFunc) Send(ctx context.Context, falcopayload types.FalcoPayload) error {
+	return f(falcopayload)
+}
+
+// OutputFactory returns the Output of a registered output for the configuration, or nil if it isn't enabled
+type OutputFactory func(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics) (Output, error)
+
+// builtinOutputs are the names of the built-in outputs, the registered outputs can't have them, even if the built-in
+// ones are disabled, as they're the names of their settings
+var builtinOutputs = []string{
+	"AlertManager", "AWSCloudWatchLogs", "AWSKinesis", "AWSLambda", "AWSS3", "AWSSNS", "AWSSQS", "AzureBlob",
+	"CloudEvents", "Datadog", "DatadogLogs", "Discord", "DogStatsD", "Elasticsearch", "EventHub", "Fifo",
+	"GCPCloudFunctions", "GCPCloudRun", "GCPPubSub", "GCPStorage", "GELF", "Google Chat", "Influxdb", "JetStream",
+	"Kafka", "Kubeless", "Loki", "Mattermost", "MQTT", "NATS", "OpenFaaS", "Opsgenie", "Pagerduty", "RabbitMQ",
+	"Redis", "Rocketchat", "Slack", "SMTP", "STAN", "StatsD", "Syslog", "Teams", "Tenants", "Wavefront",
+	"Webhook", "WebSocket", "WebUI",
+}
+
+// registry holds the outputs registered, names are lowercased without spaces
+var registry = struct {
+	mu        sync.RWMutex
+	factories map[string]OutputFactory
+	names     map[string]string // name as registered
+}{factories: make(map[string]OutputFactory), names: make(map[string]string)}
+
+// Register adds an output to the ones falcosidekick sends the events to, it must be called before the outputs are
+// initialized, ex: in the init function of a package imported by the main package. The name is the one used in the
+// logs, the metrics and the settings of the dispatch (ex: dependencies), it can't be the one of another output,
+// built-in or registered, lowercased and without spaces.
+func Register(name string, factory OutputFactory) error {
+	n := dispatchName(name)
+	if n == "" || factory == nil {
+		return fmt.Errorf("Output '%v' must have a name and a factory", name)
+	}
+	if enabledOutputSet(builtinOutputs)[n] {
+		return fmt.Errorf("Output '%v' is already a built-in output", name)
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, ok := registry.factories[n]; ok {
+		return fmt.Errorf("Output '%v' is already registered", name)
+	}
+	registry.factories[n] = factory
+	registry.names[n] = name
+	return nil
+}
+
+// RegisteredOutput is an output registered and enabled
+type RegisteredOutput struct {
+	Name   string
+	Output Output
+}
+
+// NewRegisteredOutputs calls the factories of the outputs registered and returns the enabled ones, sorted by name
+func NewRegisteredOutputs(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics) ([]RegisteredOutput, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	var r []RegisteredOutput
+	for i, j := range registry.factories {
+		o, err := j(config, stats, promStats)
+		if err != nil {
+			return nil, fmt.Errorf("Output '%v' : %v", registry.names[i], err)
+		}
+		if o != nil {
+			r = append(r, RegisteredOutput{Name: registry.names[i], Output: o})
+		}
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	return r, nil
+}
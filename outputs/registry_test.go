@@ -0,0 +1,113 @@
+package outputs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+type fakeOutput struct {
+	mu       sync.Mutex
+	received []types.FalcoPayload
+}
+
+func (f *fakeOutput) Send(ctx context.Context, falcopayload types.FalcoPayload) error {
+	f.mu.Lock()
+	f.received = append(f.received, falcopayload)
+	f.mu.Unlock()
+	return nil
+}
+
+func unregister(names ...string) {
+	registry.mu.Lock()
+	for _, i := range names {
+		delete(registry.factories, i)
+		delete(registry.names, i)
+	}
+	registry.mu.Unlock()
+}
+
+func TestRegister(t *testing.T) {
+	defer unregister("siem", "disabled", "concurrent")
+
+	fake := new(fakeOutput)
+	require.Nil(t, Register("SIEM", func(*types.Configuration, *types.Statistics, *types.PromStatistics) (Output, error) {
+		return fake, nil
+	}))
+	require.NotNil(t, Register("siem", func(*types.Configuration, *types.Statistics, *types.PromStatistics) (Output, error) {
+		return fake, nil
+	}))
+	// the names of the built-in outputs can't be used, even if they're disabled
+	for _, i := range []string{"Slack", "google chat", "AWSKinesis"} {
+		require.NotNil(t, Register(i, func(*types.Configuration, *types.Statistics, *types.PromStatistics) (Output, error) {
+			return fake, nil
+		}))
+	}
+	require.Nil(t, Register("Disabled", func(*types.Configuration, *types.Statistics, *types.PromStatistics) (Output, error) {
+		return nil, nil
+	}))
+
+	// concurrent registrations of the same name, only one succeeds
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- Register("Concurrent", func(*types.Configuration, *types.Statistics, *types.PromStatistics) (Output, error) { return nil, nil })
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	var registered int
+	for err := range errs {
+		if err == nil {
+			registered++
+		}
+	}
+	require.Equal(t, 1, registered)
+	unregister("concurrent")
+
+	o, err := NewRegisteredOutputs(&types.Configuration{}, &types.Statistics{}, nil)
+	require.Nil(t, err)
+	require.Equal(t, []RegisteredOutput{{Name: "SIEM", Output: fake}}, o)
+
+	d, err := NewDispatcher(map[string]string{"siem": "Slack"}, []string{"Slack", "SIEM"}, new(Drainer), newTestPromStats())
+	require.Nil(t, err)
+	f := types.FalcoPayload{Rule: "Test rule", Output: "This is a test from falcosidekick"}
+	x := d.NewDispatch(f)
+	x.Add("Slack", OutputFunc(func(context.Context, types.FalcoPayload) error { return nil }))
+	x.Add(o[0].Name, o[0].Output)
+	x.Run()
+	x.Wait()
+	require.Len(t, fake.received, 1)
+	require.Equal(t, "Test rule", fake.received[0].Rule)
+
+	// it isn't called if the output it depends on fails
+	x = d.NewDispatch(f)
+	x.Add("Slack", OutputFunc(func(context.Context, types.FalcoPayload) error { return errors.New("post failed") }))
+	x.Add(o[0].Name, o[0].Output)
+	x.Run()
+	x.Wait()
+	require.Len(t, fake.received, 1)
+}
+
+func TestPayloadFunc(t *testing.T) {
+	var received []types.FalcoPayload
+	var o Output = PayloadFunc(func(falcopayload types.FalcoPayload) error {
+		received = append(received, falcopayload)
+		if falcopayload.Rule == "refused" {
+			return errors.New("push failed")
+		}
+		return nil
+	})
+	require.Nil(t, o.Send(context.Background(), types.FalcoPayload{Rule: "Test rule"}))
+	require.NotNil(t, o.Send(context.Background(), types.FalcoPayload{Rule: "refused"}))
+	require.Len(t, received, 2)
+	require.Equal(t, "Test rule", received[0].Rule)
+}
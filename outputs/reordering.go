@@ -0,0 +1,121 @@
+package outputs
+
+import (
+	"container/heap"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Reorderer holds the events for a grace period and emits them sorted by their time, so the ones arriving late within
+// the window are in order for the time-series outputs. Events older than the window are emitted immediately.
+type Reorderer struct {
+	Window time.Duration
+	emit   func(types.FalcoPayload)
+	emitMu sync.Mutex // the events released are emitted one batch at a time, to keep them in order
+	mu     sync.Mutex
+	events reorderedEvents
+	timer  *time.Timer
+}
+
+type reorderedEvent struct {
+	falcopayload types.FalcoPayload
+	deadline     time.Time // time of the event (or of its reception if it's in the future) + Window
+}
+
+// reorderedEvents is a heap of the events by time
+type reorderedEvents []reorderedEvent
+
+func (e reorderedEvents) Len() int { return len(e) }
+func (e reorderedEvents) Less(i, j int) bool {
+	return e[i].falcopayload.Time.Before(e[j].falcopayload.Time)
+}
+func (e reorderedEvents) Swap(i, j int)       { e[i], e[j] = e[j], e[i] }
+func (e *reorderedEvents) Push(x interface{}) { *e = append(*e, x.(reorderedEvent)) }
+func (e *reorderedEvents) Pop() interface{} {
+	old := *e
+	x := old[len(old)-1]
+	*e = old[:len(old)-1]
+	return x
+}
+
+// NewReorderer returns a Reorderer for the window configured, events are emitted with emit, one at a time, emit must
+// return once the event is sent for the outputs to receive them in order
+func NewReorderer(config *types.Configuration, emit func(types.FalcoPayload)) *Reorderer {
+	return &Reorderer{
+		Window: time.Duration(config.Reordering.Window) * time.Millisecond,
+		emit:   emit,
+	}
+}
+
+// Add receives an event, it's emitted once the window after its time is over
+func (r *Reorderer) Add(falcopayload types.FalcoPayload) {
+	now := time.Now()
+	if now.Sub(falcopayload.Time) > r.Window {
+		log.Printf("[WARN]  : Reordering - Event for rule '%v' older than the window (%v), sent out of order\n", falcopayload.Rule, falcopayload.Time)
+		r.emit(falcopayload)
+		return
+	}
+
+	deadline := falcopayload.Time
+	if deadline.After(now) {
+		deadline = now
+	}
+	r.mu.Lock()
+	heap.Push(&r.events, reorderedEvent{falcopayload: falcopayload, deadline: deadline.Add(r.Window)})
+	r.schedule(now)
+	r.mu.Unlock()
+}
+
+// Flush emits all the events held, in order, it's called at shutdown
+func (r *Reorderer) Flush() {
+	r.emitMu.Lock()
+	defer r.emitMu.Unlock()
+	r.mu.Lock()
+	events := make([]types.FalcoPayload, 0, len(r.events))
+	for len(r.events) > 0 {
+		events = append(events, heap.Pop(&r.events).(reorderedEvent).falcopayload)
+	}
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	r.mu.Unlock()
+
+	for _, i := range events {
+		r.emit(i)
+	}
+}
+
+// release emits the events whose window is over, in order
+func (r *Reorderer) release() {
+	r.emitMu.Lock()
+	defer r.emitMu.Unlock()
+	now := time.Now()
+	r.mu.Lock()
+	var events []types.FalcoPayload
+	for len(r.events) > 0 && !r.events[0].deadline.After(now) {
+		events = append(events, heap.Pop(&r.events).(reorderedEvent).falcopayload)
+	}
+	r.schedule(now)
+	r.mu.Unlock()
+
+	for _, i := range events {
+		r.emit(i)
+	}
+}
+
+// schedule sets the timer to the deadline of the oldest event, r.mu must be held
+func (r *Reorderer) schedule(now time.Time) {
+	if len(r.events) == 0 {
+		return
+	}
+	d := r.events[0].deadline.Sub(now)
+	if r.timer == nil {
+		r.timer = time.AfterFunc(d, r.release)
+		return
+	}
+	r.timer.Stop()
+	r.timer.Reset(d)
+}
@@ -0,0 +1,77 @@
+package outputs
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestReorderer(t *testing.T) {
+	emitted := make(chan types.FalcoPayload, 10)
+	r := NewReorderer(&types.Configuration{Reordering: types.ReorderingConfig{Window: 100}}, func(falcopayload types.FalcoPayload) { emitted <- falcopayload })
+
+	now := time.Now()
+	r.Add(types.FalcoPayload{Rule: "2", Time: now.Add(-20 * time.Millisecond)})
+	r.Add(types.FalcoPayload{Rule: "3", Time: now})
+	r.Add(types.FalcoPayload{Rule: "1", Time: now.Add(-50 * time.Millisecond)})
+	select {
+	case <-emitted:
+		t.Fatal("event emitted before the end of the window")
+	case <-time.After(20 * time.Millisecond):
+	}
+	for _, i := range []string{"1", "2", "3"} {
+		select {
+		case e := <-emitted:
+			require.Equal(t, i, e.Rule)
+		case <-time.After(time.Second):
+			t.Fatalf("event %v not emitted", i)
+		}
+	}
+
+	// an event older than the window is emitted immediately
+	r.Add(types.FalcoPayload{Rule: "late", Time: now.Add(-time.Second)})
+	require.Equal(t, "late", (<-emitted).Rule)
+
+	// the events held are emitted at the flush
+	r.Add(types.FalcoPayload{Rule: "5", Time: time.Now()})
+	r.Add(types.FalcoPayload{Rule: "4", Time: time.Now().Add(-10 * time.Millisecond)})
+	r.Flush()
+	require.Equal(t, "4", (<-emitted).Rule)
+	require.Equal(t, "5", (<-emitted).Rule)
+}
+
+func TestReordererDispatch(t *testing.T) {
+	d, err := NewDispatcher(nil, []string{"Influxdb"}, new(Drainer), newTestPromStats())
+	require.Nil(t, err)
+	var mu sync.Mutex
+	var received []string
+	influxdb := OutputFunc(func(ctx context.Context, falcopayload types.FalcoPayload) error {
+		// the first events are the slowest to send
+		n, _ := strconv.Atoi(falcopayload.Rule)
+		time.Sleep(time.Duration(10-n) * time.Millisecond)
+		mu.Lock()
+		received = append(received, falcopayload.Rule)
+		mu.Unlock()
+		return nil
+	})
+	r := NewReorderer(&types.Configuration{Reordering: types.ReorderingConfig{Window: 10}}, func(falcopayload types.FalcoPayload) {
+		x := d.NewDispatch(falcopayload)
+		x.Add("Influxdb", influxdb)
+		x.Run()
+		x.Wait()
+	})
+
+	// the output receives the events in order, the sends of the events released together don't overlap
+	now := time.Now()
+	for _, i := range []int{3, 1, 4, 2, 5} {
+		r.Add(types.FalcoPayload{Rule: strconv.Itoa(i), Time: now.Add(time.Duration(i) * time.Millisecond)})
+	}
+	r.Flush()
+	require.Equal(t, []string{"1", "2", "3", "4", "5"}, received)
+}
@@ -0,0 +1,170 @@
+package outputs
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// ReplayOptions represents parameters for replaying a file of events
+type ReplayOptions struct {
+	Parallelism      int     // number of events sent concurrently
+	Rate             float64 // maximum number of events per second, 0 means no limit
+	Offset           int     // number of lines to skip, to resume an interrupted replay
+	ProgressInterval time.Duration
+}
+
+// ReplayStats represents the result of a replay
+type ReplayStats struct {
+	Total     int64
+	Processed int64
+	Failed    int64
+	// Offset is the line from which a replay can be resumed, all lines before it have been processed
+	Offset int64
+}
+
+// ReplayFile replays a NDJSON file of Falco events with send, see Replay
+func ReplayFile(path string, opts ReplayOptions, send func(types.FalcoPayload) error) (ReplayStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ReplayStats{}, err
+	}
+	var total int64
+	s := newReplayScanner(f)
+	for s.Scan() {
+		total++
+	}
+	if err := s.Err(); err != nil {
+		f.Close()
+		return ReplayStats{}, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return ReplayStats{}, err
+	}
+	defer f.Close()
+	return replay(f, total, opts, send)
+}
+
+// Replay reads Falco events from r, one JSON object per line, and sends them with send
+// with a bounded parallelism and rate. Lines which can't be decoded or sent are counted as failed.
+func Replay(r io.Reader, opts ReplayOptions, send func(types.FalcoPayload) error) (ReplayStats, error) {
+	return replay(r, 0, opts, send)
+}
+
+func replay(r io.Reader, total int64, opts ReplayOptions, send func(types.FalcoPayload) error) (ReplayStats, error) {
+	if opts.Parallelism < 1 {
+		opts.Parallelism = 1
+	}
+	if opts.ProgressInterval == 0 {
+		opts.ProgressInterval = 10 * time.Second
+	}
+
+	stats := ReplayStats{Total: total, Offset: int64(opts.Offset)}
+	w := newReplayWatermark(opts.Offset)
+
+	var ticker *time.Ticker
+	if opts.Rate > 0 {
+		ticker = time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer ticker.Stop()
+	}
+	progress := time.NewTicker(opts.ProgressInterval)
+	defer progress.Stop()
+
+	sem := make(chan struct{}, opts.Parallelism)
+	var wg sync.WaitGroup
+
+	s := newReplayScanner(r)
+	line := -1
+	for s.Scan() {
+		line++
+		if line < opts.Offset {
+			continue
+		}
+		if total == 0 {
+			stats.Total++
+		}
+
+		var falcopayload types.FalcoPayload
+		if err := json.Unmarshal(s.Bytes(), &falcopayload); err != nil {
+			log.Printf("[ERROR] : Replay - Line %v - %v\n", line, err)
+			atomic.AddInt64(&stats.Failed, 1)
+			atomic.AddInt64(&stats.Processed, 1)
+			w.done(line)
+			continue
+		}
+
+		if ticker != nil {
+			<-ticker.C
+		}
+		select {
+		case <-progress.C:
+			logReplayProgress(&stats, w)
+		default:
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(line int, falcopayload types.FalcoPayload) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := send(falcopayload); err != nil {
+				log.Printf("[ERROR] : Replay - Line %v - %v\n", line, err)
+				atomic.AddInt64(&stats.Failed, 1)
+			}
+			atomic.AddInt64(&stats.Processed, 1)
+			w.done(line)
+		}(line, falcopayload)
+	}
+	wg.Wait()
+
+	stats.Offset = w.offset()
+	logReplayProgress(&stats, w)
+	return stats, s.Err()
+}
+
+func logReplayProgress(stats *ReplayStats, w *replayWatermark) {
+	log.Printf("[INFO]  : Replay - processed %v/%v, failed %v, resume offset %v\n", atomic.LoadInt64(&stats.Processed), stats.Total, atomic.LoadInt64(&stats.Failed), w.offset())
+}
+
+func newReplayScanner(r io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	return s
+}
+
+// replayWatermark tracks the first line not processed yet, lines complete out of order with parallelism
+type replayWatermark struct {
+	sync.Mutex
+	next    int
+	pending map[int]bool
+}
+
+func newReplayWatermark(offset int) *replayWatermark {
+	return &replayWatermark{next: offset, pending: make(map[int]bool)}
+}
+
+func (w *replayWatermark) done(line int) {
+	w.Lock()
+	defer w.Unlock()
+	w.pending[line] = true
+	for w.pending[w.next] {
+		delete(w.pending, w.next)
+		w.next++
+	}
+}
+
+func (w *replayWatermark) offset() int64 {
+	w.Lock()
+	defer w.Unlock()
+	return int64(w.next)
+}
@@ -0,0 +1,51 @@
+package outputs
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestReplayFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "falcosidekick-replay")
+	require.Nil(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(strings.Repeat(falcoTestInput+"\n", 20) + "{malformed\n")
+	require.Nil(t, err)
+	f.Close()
+
+	var running, maxRunning, received int64
+	var mu sync.Mutex
+	send := func(falcopayload types.FalcoPayload) error {
+		r := atomic.AddInt64(&running, 1)
+		mu.Lock()
+		if r > maxRunning {
+			maxRunning = r
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt64(&running, -1)
+		atomic.AddInt64(&received, 1)
+		return nil
+	}
+
+	stats, err := ReplayFile(f.Name(), ReplayOptions{Parallelism: 3}, send)
+	require.Nil(t, err)
+	require.Equal(t, int64(20), received)
+	require.LessOrEqual(t, maxRunning, int64(3))
+	require.Equal(t, ReplayStats{Total: 21, Processed: 21, Failed: 1, Offset: 21}, stats)
+
+	received = 0
+	stats, err = ReplayFile(f.Name(), ReplayOptions{Parallelism: 3, Offset: 15}, send)
+	require.Nil(t, err)
+	require.Equal(t, int64(5), received)
+	require.Equal(t, int64(21), stats.Offset)
+}
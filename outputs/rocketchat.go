@@ -1,7 +1,7 @@
 package outputs
 
 import (
-	"bytes"
+	"context"
 	"log"
 
 	"github.com/falcosecurity/falcosidekick/types"
@@ -49,12 +49,11 @@ func newRocketchatPayload(falcopayload types.FalcoPayload, config *types.Configu
 		attachment.Text = falcopayload.Output
 	}
 
-	if config.Rocketchat.MessageFormatTemplate != nil {
-		buf := &bytes.Buffer{}
-		if err := config.Rocketchat.MessageFormatTemplate.Execute(buf, falcopayload); err != nil {
+	if t := getMessageFormatTemplate(falcopayload, config.Rocketchat.MessageFormatField, config.Rocketchat.MessageFormatTemplates, config.Rocketchat.MessageFormatTemplate); t != nil {
+		if m, err := executeMessageFormat(t, falcopayload); err != nil {
 			log.Printf("[ERROR] : RocketChat - Error expanding RocketChat message %v", err)
 		} else {
-			messageText = buf.String()
+			messageText = m
 		}
 	}
 
@@ -98,20 +97,22 @@ func newRocketchatPayload(falcopayload types.FalcoPayload, config *types.Configu
 }
 
 // RocketchatPost posts event to Rocketchat
-func (c *Client) RocketchatPost(falcopayload types.FalcoPayload) {
+func (c *Client) RocketchatPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Rocketchat.Add(Total, 1)
 
-	err := c.Post(newRocketchatPayload(falcopayload, c.Config))
+	err := c.post(ctx, newRocketchatPayload(falcopayload, c.config()), falcopayload)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:rocketchat", "status:error"})
 		c.Stats.Rocketchat.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "rocketchat", "status": Error}).Inc()
-		log.Printf("[ERROR] : RocketChat - %v\n", err.Error())
-		return
+		logEventError("RocketChat", falcopayload, err)
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:rocketchat", "status:ok"})
 	c.Stats.Rocketchat.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "rocketchat", "status": OK}).Inc()
+
+	return nil
 }
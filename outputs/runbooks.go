@@ -0,0 +1,115 @@
+package outputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// RunbookField is the field of the events with the URL of the runbook of their rule
+const RunbookField string = "runbook.url"
+
+type runbook struct {
+	pattern *regexp.Regexp
+	url     string
+}
+
+// Runbooks adds to the events the URL of the runbook of their rule, from a JSON file of rules (globs) and URLs, the first
+// rule matching an event gives its runbook
+type Runbooks struct {
+	File     string
+	mu       sync.RWMutex
+	runbooks []runbook
+}
+
+// NewRunbooks returns the Runbooks of a file
+func NewRunbooks(file string) (*Runbooks, error) {
+	r := &Runbooks{File: file}
+	if err := r.Load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Load reads the file, the runbooks are unchanged if it's invalid
+func (r *Runbooks) Load() error {
+	b, err := ioutil.ReadFile(filepath.Clean(r.File))
+	if err != nil {
+		return err
+	}
+	var entries []struct {
+		Rule string `json:"rule"`
+		URL  string `json:"url"`
+	}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return fmt.Errorf("Bad runbooks file '%v' : %v", r.File, err)
+	}
+	runbooks := make([]runbook, 0, len(entries))
+	for _, i := range entries {
+		if i.Rule == "" || i.URL == "" {
+			return fmt.Errorf("Bad runbooks file '%v' : rule and url are required", r.File)
+		}
+		runbooks = append(runbooks, runbook{pattern: globRegexp(i.Rule), url: i.URL})
+	}
+
+	r.mu.Lock()
+	r.runbooks = runbooks
+	r.mu.Unlock()
+	return nil
+}
+
+// globRegexp returns the regular expression of a glob, * matches any characters and ? a single one
+func globRegexp(glob string) *regexp.Regexp {
+	var p strings.Builder
+	p.WriteString("^")
+	for _, i := range glob {
+		switch i {
+		case '*':
+			p.WriteString(".*")
+		case '?':
+			p.WriteString(".")
+		default:
+			p.WriteString(regexp.QuoteMeta(string(i)))
+		}
+	}
+	p.WriteString("$")
+	return regexp.MustCompile(p.String())
+}
+
+// URL returns the URL of the runbook of a rule, "" if it has none
+func (r *Runbooks) URL(rule string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, i := range r.runbooks {
+		if i.pattern.MatchString(rule) {
+			return i.url
+		}
+	}
+	return ""
+}
+
+// Attach adds the RunbookField to the event if its rule has a runbook, an existing field is kept
+func (r *Runbooks) Attach(falcopayload *types.FalcoPayload) {
+	u := r.URL(falcopayload.Rule)
+	if u == "" {
+		return
+	}
+	if falcopayload.OutputFields == nil {
+		falcopayload.OutputFields = make(map[string]interface{})
+	}
+	if _, ok := falcopayload.OutputFields[RunbookField]; !ok {
+		falcopayload.OutputFields[RunbookField] = u
+	}
+}
+
+// runbookURL returns the URL of the runbook of an event, "" if it has none
+func runbookURL(falcopayload types.FalcoPayload) string {
+	u, _ := falcopayload.OutputFields[RunbookField].(string)
+	return u
+}
@@ -0,0 +1,54 @@
+package outputs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestRunbooks(t *testing.T) {
+	f, err := ioutil.TempFile("", "falcosidekick-runbooks")
+	require.Nil(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`[{"rule": "Test r?le*", "url": "https://runbooks/test"}, {"rule": "*", "url": "https://runbooks/default"}]`)
+	require.Nil(t, err)
+	f.Close()
+
+	r, err := NewRunbooks(f.Name())
+	require.Nil(t, err)
+	require.Equal(t, "https://runbooks/test", r.URL("Test rule (k8s)"))
+	require.Equal(t, "https://runbooks/default", r.URL("Terminal shell in container"))
+
+	var falcopayload types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &falcopayload))
+	delete(falcopayload.OutputFields, "proc.tty")
+	r.Attach(&falcopayload)
+	require.Equal(t, "https://runbooks/test", falcopayload.OutputFields[RunbookField])
+
+	s := newSlackPayload(falcopayload, &types.Configuration{})
+	require.Equal(t, []slackAction{{Type: "button", Text: "Runbook", URL: "https://runbooks/test"}}, s.Attachments[0].Actions)
+	require.Contains(t, s.Attachments[0].Fields, slackAttachmentField{Title: RunbookField, Value: "https://runbooks/test", Short: true})
+
+	e := createPagerdutyEvent(falcopayload, types.PagerdutyConfig{})
+	require.Equal(t, []interface{}{map[string]string{"href": "https://runbooks/test", "text": "Runbook"}}, e.Links)
+
+	// a rule without runbook adds nothing
+	require.Nil(t, ioutil.WriteFile(f.Name(), []byte(`[{"rule": "Other rule", "url": "https://runbooks/other"}]`), 0600))
+	require.Nil(t, r.Load())
+	falcopayload = types.FalcoPayload{}
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &falcopayload))
+	delete(falcopayload.OutputFields, "proc.tty")
+	r.Attach(&falcopayload)
+	require.NotContains(t, falcopayload.OutputFields, RunbookField)
+	require.Empty(t, newSlackPayload(falcopayload, &types.Configuration{}).Attachments[0].Actions)
+
+	// an invalid file keeps the previous runbooks
+	require.Nil(t, ioutil.WriteFile(f.Name(), []byte(`{malformed`), 0600))
+	require.NotNil(t, r.Load())
+	require.Equal(t, "https://runbooks/other", r.URL("Other rule"))
+}
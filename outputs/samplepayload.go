@@ -0,0 +1,167 @@
+package outputs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// samplePayloadBuilder builds the body an output posts for an event without its client, with the redaction of the
+// output
+type samplePayloadBuilder struct {
+	redaction func(config *types.Configuration) (fields, patterns []string)
+	payload   func(falcopayload types.FalcoPayload, config *types.Configuration, redaction *Redaction) interface{}
+}
+
+// samplePayloads are the builders of the outputs with a sample payload, names are lowercased without spaces
+var samplePayloads = map[string]samplePayloadBuilder{
+	"alertmanager": {
+		payload: func(f types.FalcoPayload, c *types.Configuration, r *Redaction) interface{} {
+			return newAlertmanagerPayload(f)
+		},
+	},
+	"datadog": {
+		redaction: func(c *types.Configuration) ([]string, []string) {
+			return c.Datadog.MaskFields, c.Datadog.RedactPatterns
+		},
+		payload: func(f types.FalcoPayload, c *types.Configuration, r *Redaction) interface{} {
+			return newDatadogPayload(r.Redact(f))
+		},
+	},
+	"discord": {
+		redaction: func(c *types.Configuration) ([]string, []string) {
+			return c.Discord.MaskFields, c.Discord.RedactPatterns
+		},
+		payload: func(f types.FalcoPayload, c *types.Configuration, r *Redaction) interface{} {
+			return newDiscordPayload(r.Redact(f), c)
+		},
+	},
+	"googlechat": {
+		redaction: func(c *types.Configuration) ([]string, []string) {
+			return c.Googlechat.MaskFields, c.Googlechat.RedactPatterns
+		},
+		payload: func(f types.FalcoPayload, c *types.Configuration, r *Redaction) interface{} {
+			return newGooglechatPayload(r.Redact(f), c)
+		},
+	},
+	"mattermost": {
+		redaction: func(c *types.Configuration) ([]string, []string) {
+			return c.Mattermost.MaskFields, c.Mattermost.RedactPatterns
+		},
+		payload: func(f types.FalcoPayload, c *types.Configuration, r *Redaction) interface{} {
+			return newMattermostPayload(r.Redact(f), c)
+		},
+	},
+	"opsgenie": {
+		redaction: func(c *types.Configuration) ([]string, []string) {
+			return c.Opsgenie.MaskFields, c.Opsgenie.RedactPatterns
+		},
+		payload: func(f types.FalcoPayload, c *types.Configuration, r *Redaction) interface{} {
+			return newOpsgeniePayload(r.Redact(f), c)
+		},
+	},
+	"rocketchat": {
+		redaction: func(c *types.Configuration) ([]string, []string) {
+			return c.Rocketchat.MaskFields, c.Rocketchat.RedactPatterns
+		},
+		payload: func(f types.FalcoPayload, c *types.Configuration, r *Redaction) interface{} {
+			return newRocketchatPayload(r.Redact(f), c)
+		},
+	},
+	"slack": {
+		redaction: func(c *types.Configuration) ([]string, []string) { return c.Slack.MaskFields, c.Slack.RedactPatterns },
+		payload: func(f types.FalcoPayload, c *types.Configuration, r *Redaction) interface{} {
+			return newSlackPayload(r.Redact(f), c)
+		},
+	},
+	"teams": {
+		redaction: func(c *types.Configuration) ([]string, []string) { return c.Teams.MaskFields, c.Teams.RedactPatterns },
+		payload: func(f types.FalcoPayload, c *types.Configuration, r *Redaction) interface{} {
+			if c.Teams.Mode == TeamsWorkflows || c.Teams.UseAdaptiveCard {
+				return newTeamsWorkflowsPayload(r.Redact(f), c)
+			}
+			return newTeamsPayload(r.Redact(f), c)
+		},
+	},
+	"webhook": {
+		redaction: func(c *types.Configuration) ([]string, []string) {
+			return c.Webhook.MaskFields, c.Webhook.RedactPatterns
+		},
+		payload: newWebhookSamplePayload,
+	},
+	"webui": {
+		payload: func(f types.FalcoPayload, c *types.Configuration, r *Redaction) interface{} {
+			return newWebUIPayload(f, c)
+		},
+	},
+}
+
+// newWebhookSamplePayload returns the body posted by the Webhook output, after its pipeline, message format, keys
+// mapping and formatting
+func newWebhookSamplePayload(falcopayload types.FalcoPayload, config *types.Configuration, redaction *Redaction) interface{} {
+	if config.Webhook.Passthrough && falcopayload.RawPayload != nil {
+		return rawPayload(falcopayload.RawPayload)
+	}
+	falcopayload, _ = runPipeline(falcopayload, config.Webhook.Pipeline, config.Webhook.FieldsMapping, config.Webhook.RedactFields, redaction, config.Webhook.RequiredFields)
+	if t := config.Webhook.MessageFormatTemplate; t != nil {
+		if m, err := executeMessageFormat(t, falcopayload); err == nil {
+			falcopayload.Output = m
+		}
+	}
+	var payload interface{} = formatFalcoPayload(falcopayload, config.Webhook.PriorityCase, config.Webhook.SchemaVersion, config.Webhook.SchemaVersionInPayload)
+	if len(config.Webhook.KeysMapping) != 0 {
+		payload = keysMappedPayload{payload: payload, mapping: config.Webhook.KeysMapping}
+	}
+	return withRawEvent(payload, falcopayload, config.Webhook.RawEventKey)
+}
+
+// SamplePayloadOutputs returns the names of the outputs with a sample payload, sorted
+func SamplePayloadOutputs() []string {
+	names := make([]string, 0, len(samplePayloads))
+	for i := range samplePayloads {
+		names = append(names, i)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SamplePayload returns the headers and the body, indented, an output would post for an event, after its redaction,
+// without creating its client, so without connecting to anything
+func SamplePayload(output string, falcopayload types.FalcoPayload, config *types.Configuration) ([]byte, error) {
+	b, ok := samplePayloads[dispatchName(output)]
+	if !ok {
+		return nil, fmt.Errorf("Output '%v' has no sample payload, must be one of %v", output, strings.Join(SamplePayloadOutputs(), ", "))
+	}
+	var redaction *Redaction
+	if b.redaction != nil {
+		var err error
+		if redaction, err = NewRedaction(b.redaction(config)); err != nil {
+			return nil, err
+		}
+	}
+	body, err := json.MarshalIndent(b.payload(falcopayload, config, redaction), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	sample := new(bytes.Buffer)
+	fmt.Fprintf(sample, "Content-Type: %v\n", DefaultContentType)
+	if dispatchName(output) == "webhook" {
+		headers := make([]string, 0, len(config.Webhook.CustomHeaders))
+		for i := range config.Webhook.CustomHeaders {
+			headers = append(headers, i)
+		}
+		sort.Strings(headers)
+		for _, i := range headers {
+			fmt.Fprintf(sample, "%v: %v\n", i, expandCustomHeader(config.Webhook.CustomHeaders[i], falcopayload))
+		}
+	}
+	sample.WriteByte('\n')
+	sample.Write(body)
+	sample.WriteByte('\n')
+	return sample.Bytes(), nil
+}
@@ -0,0 +1,48 @@
+package outputs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestSamplePayload(t *testing.T) {
+	config := &types.Configuration{}
+	config.Webhook.CustomHeaders = map[string]string{"X-Team": "falco", "X-Rule": "{{ .Rule }}"}
+	config.Webhook.MaskFields = []string{"proc.name"}
+	config.Webhook.KeysMapping = map[string]string{"rule": "name"}
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	sample, err := SamplePayload("Webhook", f, config)
+	require.Nil(t, err)
+	require.Equal(t, `Content-Type: application/json; charset=utf-8
+X-Rule: Test rule
+X-Team: falco
+
+{
+  "name": "Test rule",
+  "output": "This is a test from ***",
+  "output_fields": {
+    "proc.name": "***",
+    "proc.tty": 1234
+  },
+  "priority": "Debug",
+  "time": "2001-01-01T01:10:00Z"
+}
+`, string(sample))
+
+	config.Slack.MaskFields = []string{"proc.name"}
+	sample, err = SamplePayload("slack", f, config)
+	require.Nil(t, err)
+	require.Contains(t, string(sample), `"text": "This is a test from ***"`)
+	require.Contains(t, string(sample), `"value": "Test rule"`)
+	require.NotContains(t, string(sample), `"falcosidekick"`)
+
+	_, err = SamplePayload("kafka", f, config)
+	require.EqualError(t, err, "Output 'kafka' has no sample payload, must be one of alertmanager, datadog, discord, googlechat, mattermost, opsgenie, rocketchat, slack, teams, webhook, webui")
+}
@@ -0,0 +1,150 @@
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Sampled is the status of the outputs not called for an event sampled out by their sampling
+const Sampled string = "sampled"
+
+// SamplingRateField is the output field of the rate of the sampling of the events sent, if AddRate is set
+const SamplingRateField string = "sampling.rate"
+
+type samplingRule struct {
+	pattern *regexp.Regexp
+	rate    float64
+	outputs map[string]bool // names are lowercased without spaces, empty for all the outputs
+}
+
+// Sampler keeps only a sample of the events of some rules, for all the outputs or some of them, events of other rules
+// are all kept. The events are sampled by a hash of their content, so the replicas keep the same events and the
+// events kept with a rate are kept with the greater ones.
+type Sampler struct {
+	ExemptPriority types.PriorityType
+	AddRate        bool
+	PromStats      *types.PromStatistics
+	rules          []samplingRule
+	outputs        map[string]float64 // output: rate of the events not matching a rule of the output
+	sample         func(types.FalcoPayload) float64
+}
+
+// NewSampler returns a Sampler for the rules and the outputs configured, the first rule matching an event gives its
+// rate, all the outputs must be enabled
+func NewSampler(config *types.Configuration, enabledOutputs []string, promStats *types.PromStatistics) (*Sampler, error) {
+	enabled := enabledOutputSet(enabledOutputs)
+	s := &Sampler{
+		ExemptPriority: types.Priority(config.Sampling.ExemptPriority),
+		AddRate:        config.Sampling.AddRate,
+		PromStats:      promStats,
+		outputs:        make(map[string]float64),
+		sample:         samplingHash,
+	}
+	for _, i := range config.Sampling.Rules {
+		p, err := regexp.Compile(i.Rule)
+		if err != nil {
+			return nil, err
+		}
+		r := samplingRule{pattern: p, rate: i.Rate, outputs: make(map[string]bool)}
+		for _, j := range i.Outputs {
+			if !enabled[dispatchName(j)] {
+				return nil, fmt.Errorf("Output '%v' of sampling rule '%v' isn't enabled", j, i.Rule)
+			}
+			r.outputs[dispatchName(j)] = true
+		}
+		s.rules = append(s.rules, r)
+	}
+	for i, j := range config.Sampling.Outputs {
+		if !enabled[dispatchName(i)] {
+			return nil, fmt.Errorf("Output '%v' with sampling isn't enabled", i)
+		}
+		rate, err := strconv.ParseFloat(j, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("Bad sampling rate '%v' for output '%v', must be between 0 and 1", j, i)
+		}
+		s.outputs[dispatchName(i)] = rate
+	}
+	return s, nil
+}
+
+// samplingHash returns a value in [0, 1) uniformly distributed from the content of the event
+func samplingHash(falcopayload types.FalcoPayload) float64 {
+	h := fnv.New64a()
+	for _, i := range []string{falcopayload.Rule, falcopayload.Hostname, falcopayload.Time.Format(time.RFC3339Nano), falcopayload.Output} {
+		h.Write([]byte(i))
+		h.Write([]byte{0})
+	}
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
+
+// rate returns the rate of the event, from the rules of the output, or of all the outputs if output is "", then the
+// rate of the output, 1 if the event isn't sampled
+func (s *Sampler) rate(output string, falcopayload types.FalcoPayload) float64 {
+	if s.ExemptPriority != types.Default && falcopayload.Priority >= s.ExemptPriority {
+		return 1
+	}
+	for _, i := range s.rules {
+		if (output == "") != (len(i.outputs) == 0) || (output != "" && !i.outputs[output]) {
+			continue
+		}
+		if i.pattern.MatchString(falcopayload.Rule) {
+			return i.rate
+		}
+	}
+	if r, ok := s.outputs[output]; ok && output != "" {
+		return r
+	}
+	return 1
+}
+
+// Keep returns false if the event is sampled out by the rules of all the outputs
+func (s *Sampler) Keep(falcopayload types.FalcoPayload) bool {
+	r := s.rate("", falcopayload)
+	if r >= 1 || s.sample(falcopayload) < r {
+		return true
+	}
+	if s.PromStats != nil && s.PromStats.Sampled != nil {
+		s.PromStats.Sampled.With(map[string]string{"rule": falcopayload.Rule}).Inc()
+	}
+	return false
+}
+
+// KeepFor returns false if the event is sampled out for the output by its rules or its rate, a nil Sampler keeps all
+// the events
+func (s *Sampler) KeepFor(output string, falcopayload types.FalcoPayload) bool {
+	if s == nil {
+		return true
+	}
+	r := s.rate(dispatchName(output), falcopayload)
+	return r >= 1 || s.sample(falcopayload) < r
+}
+
+// Output returns an Output adding the rate of the sampling of the event in its SamplingRateField before sending it
+// with output, if AddRate is set, the events not sampled are sent as is
+func (s *Sampler) Output(name string, output Output) Output {
+	if s == nil || !s.AddRate {
+		return output
+	}
+	return OutputFunc(func(ctx context.Context, falcopayload types.FalcoPayload) error {
+		// the events kept for the output are the ones with a hash below both rates
+		r := s.rate("", falcopayload)
+		if o := s.rate(dispatchName(name), falcopayload); o < r {
+			r = o
+		}
+		if r < 1 {
+			fields := make(map[string]interface{}, len(falcopayload.OutputFields)+1)
+			for i, j := range falcopayload.OutputFields {
+				fields[i] = j
+			}
+			fields[SamplingRateField] = r
+			falcopayload.OutputFields = fields
+		}
+		return output.Send(ctx, falcopayload)
+	})
+}
@@ -0,0 +1,103 @@
+package outputs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestSamplerKeep(t *testing.T) {
+	config := &types.Configuration{Sampling: types.SamplingConfig{
+		Rules:          []types.SamplingRuleConfig{{Rule: "^Unexpected outbound connection", Rate: 0.1}},
+		ExemptPriority: "critical",
+	}}
+	promStats := &types.PromStatistics{Sampled: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "falcosidekick_sampled_out"}, []string{"rule"})}
+	s, err := NewSampler(config, nil, promStats)
+	require.Nil(t, err)
+	var n int
+	s.sample = func(types.FalcoPayload) float64 {
+		n++
+		return float64(n%10) / 10
+	}
+
+	noisy := types.FalcoPayload{Rule: "Unexpected outbound connection destination", Priority: types.Notice}
+	other := types.FalcoPayload{Rule: "Terminal shell in container", Priority: types.Notice}
+	var kept, keptOther int
+	for i := 0; i < 100; i++ {
+		if s.Keep(noisy) {
+			kept++
+		}
+		if s.Keep(other) {
+			keptOther++
+		}
+	}
+	require.Equal(t, 10, kept)
+	require.Equal(t, 100, keptOther)
+	require.Equal(t, float64(90), testutil.ToFloat64(promStats.Sampled.With(map[string]string{"rule": noisy.Rule})))
+
+	noisy.Priority = types.Critical
+	for i := 0; i < 10; i++ {
+		require.True(t, s.Keep(noisy))
+	}
+
+	config.Sampling.Rules[0].Rule = "("
+	_, err = NewSampler(config, nil, promStats)
+	require.NotNil(t, err)
+}
+
+func TestSamplerKeepFor(t *testing.T) {
+	config := &types.Configuration{Sampling: types.SamplingConfig{
+		Rules:          []types.SamplingRuleConfig{{Rule: "^Terminal shell", Rate: 0.5, Outputs: []string{"Datadog"}}},
+		Outputs:        map[string]string{"Datadog": "0.1"},
+		ExemptPriority: "critical",
+		AddRate:        true,
+	}}
+	s, err := NewSampler(config, []string{"Datadog", "Slack"}, newTestPromStats())
+	require.Nil(t, err)
+
+	// the hashes of the events are uniformly distributed, about the rate of their events is kept
+	kept := map[string]int{}
+	start := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 10000; i++ {
+		for _, j := range []string{"Terminal shell in container", "Write below etc"} {
+			f := types.FalcoPayload{Rule: j, Priority: types.Notice, Hostname: "node-1", Time: start.Add(time.Duration(i) * time.Millisecond)}
+			require.Equal(t, s.KeepFor("Datadog", f), s.KeepFor("Datadog", f))
+			require.True(t, s.Keep(f))
+			require.True(t, s.KeepFor("Slack", f))
+			if s.KeepFor("Datadog", f) {
+				kept[j]++
+			}
+		}
+	}
+	require.InDelta(t, 5000, kept["Terminal shell in container"], 200)
+	require.InDelta(t, 1000, kept["Write below etc"], 100)
+
+	f := types.FalcoPayload{Rule: "Write below etc", Priority: types.Emergency, Time: start}
+	for i := 0; i < 100; i++ {
+		f.Time = f.Time.Add(time.Millisecond)
+		require.True(t, s.KeepFor("Datadog", f))
+	}
+
+	// the rate is added to the events sent, not to the ones of the outputs without sampling
+	var sent types.FalcoPayload
+	send := OutputFunc(func(ctx context.Context, falcopayload types.FalcoPayload) error { sent = falcopayload; return nil })
+	f = types.FalcoPayload{Rule: "Write below etc", Priority: types.Notice, OutputFields: map[string]interface{}{"proc.name": "vi"}}
+	require.Nil(t, s.Output("Datadog", send).Send(context.Background(), f))
+	require.Equal(t, 0.1, sent.OutputFields[SamplingRateField])
+	require.NotContains(t, f.OutputFields, SamplingRateField)
+	require.Nil(t, s.Output("Slack", send).Send(context.Background(), f))
+	require.NotContains(t, sent.OutputFields, SamplingRateField)
+
+	config.Sampling.Outputs = map[string]string{"Teams": "0.1"}
+	_, err = NewSampler(config, []string{"Datadog", "Slack"}, newTestPromStats())
+	require.NotNil(t, err)
+	config.Sampling.Outputs = map[string]string{"Datadog": "10%"}
+	_, err = NewSampler(config, []string{"Datadog", "Slack"}, newTestPromStats())
+	require.NotNil(t, err)
+}
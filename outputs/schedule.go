@@ -0,0 +1,63 @@
+package outputs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+type scheduleRoute struct {
+	window  quietWindow
+	outputs []string // names are lowercased without spaces
+}
+
+// Schedule routes the events to the outputs of the first daily window containing their time, instead of all the
+// outputs, the events out of all the windows are sent to all the outputs
+type Schedule struct {
+	Location *time.Location
+	routes   []scheduleRoute
+}
+
+// NewSchedule returns a Schedule for the routes configured ("HH:MM-HH:MM": outputs), all the outputs must be enabled
+func NewSchedule(config *types.Configuration, enabledOutputs []string) (*Schedule, error) {
+	location, err := time.LoadLocation(config.Schedule.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	enabled := enabledOutputSet(enabledOutputs)
+	s := &Schedule{Location: location}
+	for _, i := range config.Schedule.Routes {
+		w, err := parseQuietWindow(i.Window)
+		if err != nil {
+			return nil, fmt.Errorf("Bad window '%v', must be 'HH:MM-HH:MM'", i.Window)
+		}
+		if len(i.Outputs) == 0 {
+			return nil, fmt.Errorf("No output for the window '%v'", i.Window)
+		}
+		r := scheduleRoute{window: w}
+		for _, j := range i.Outputs {
+			if !enabled[dispatchName(j)] {
+				return nil, fmt.Errorf("Output '%v' of the window '%v' isn't enabled", j, i.Window)
+			}
+			r.outputs = append(r.outputs, dispatchName(j))
+		}
+		s.routes = append(s.routes, r)
+	}
+	return s, nil
+}
+
+// Route returns the outputs of the window containing the time of the event (now if it has none), nil for all the outputs
+func (s *Schedule) Route(falcopayload types.FalcoPayload) OutputSelection {
+	t := falcopayload.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	t = t.In(s.Location)
+	for _, i := range s.routes {
+		if i.window.contains(t) {
+			return NewOutputSelection(i.outputs...)
+		}
+	}
+	return nil
+}
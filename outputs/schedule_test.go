@@ -0,0 +1,53 @@
+package outputs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestSchedule(t *testing.T) {
+	config := &types.Configuration{Schedule: types.ScheduleConfig{
+		Timezone: "Europe/Paris",
+		Routes: []types.ScheduleRouteConfig{
+			{Window: "08:00-18:00", Outputs: []string{"slack"}},
+			{Window: "22:00-08:00", Outputs: []string{"pagerduty", "AWS Lambda"}},
+		},
+	}}
+	s, err := NewSchedule(config, []string{"Slack", "PagerDuty", "AWSLambda", "Webhook"})
+	require.Nil(t, err)
+
+	paris, _ := time.LoadLocation("Europe/Paris")
+	night := s.Route(types.FalcoPayload{Time: time.Date(2021, 6, 1, 3, 0, 0, 0, paris)})
+	require.True(t, night.Has("PagerDuty"))
+	require.True(t, night.Has("AWSLambda"))
+	require.False(t, night.Has("Slack"))
+	require.False(t, night.Has("Webhook"))
+
+	day := s.Route(types.FalcoPayload{Time: time.Date(2021, 6, 1, 10, 0, 0, 0, paris)})
+	require.True(t, day.Has("Slack"))
+	require.False(t, day.Has("PagerDuty"))
+
+	// the time is compared in the timezone of the schedule
+	require.True(t, s.Route(types.FalcoPayload{Time: time.Date(2021, 6, 1, 1, 0, 0, 0, time.UTC)}).Has("PagerDuty"))
+
+	// out of the windows, the events are sent to all the outputs
+	evening := s.Route(types.FalcoPayload{Time: time.Date(2021, 6, 1, 20, 0, 0, 0, paris)})
+	require.Nil(t, evening)
+	require.True(t, evening.Has("Webhook"))
+}
+
+func TestNewScheduleErrors(t *testing.T) {
+	for _, i := range []types.ScheduleConfig{
+		{Timezone: "Mars/Olympus", Routes: []types.ScheduleRouteConfig{{Window: "08:00-18:00", Outputs: []string{"slack"}}}},
+		{Routes: []types.ScheduleRouteConfig{{Window: "8h-18h", Outputs: []string{"slack"}}}},
+		{Routes: []types.ScheduleRouteConfig{{Window: "08:00-18:00"}}},
+		{Routes: []types.ScheduleRouteConfig{{Window: "08:00-18:00", Outputs: []string{"pagerduty"}}}},
+	} {
+		_, err := NewSchedule(&types.Configuration{Schedule: i}, []string{"Slack"})
+		require.NotNil(t, err)
+	}
+}
@@ -0,0 +1,154 @@
+package outputs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// SecretFilePrefix prefixes the settings whose value is the content of a file (ex: file:///secrets/slack_token)
+const SecretFilePrefix string = "file://"
+
+// SecretFiles sets the settings referencing a file to its content, for secrets mounted as files. Any string setting can
+// reference a file, values of maps and lists included. On reload the outputs reading these settings at each request
+// (ex: tokens, API keys, headers) use the new contents, the ones read once at startup (ex: endpoints) are kept.
+// The contents reloaded are set in a copy of the configuration, the clients read it with Client.config, so the
+// configuration being read by the outputs is never modified.
+type SecretFiles struct {
+	config  *types.Configuration
+	refs    []secretFileRef
+	current atomic.Value // *types.Configuration with the contents of the last load
+}
+
+// secretFileRef is a setting referencing a file, a string or a value of a map of strings
+type secretFileRef struct {
+	name string
+	file string
+	path []int         // indexes of the fields and elements of the lists from the configuration, to the string or the map
+	key  reflect.Value // the key of the value of the map, if it's one
+}
+
+// secretFiles is the SecretFiles whose reloaded configuration is used by the clients, if it's set
+var secretFiles *SecretFiles
+
+// SetSecretFiles makes the clients of the configuration of s use its contents once reloaded, nil disables it
+func SetSecretFiles(s *SecretFiles) {
+	secretFiles = s
+}
+
+// NewSecretFiles returns the SecretFiles of the configuration and sets the settings referencing a file, it must be
+// called before the configuration is used
+func NewSecretFiles(config *types.Configuration) (*SecretFiles, error) {
+	s := &SecretFiles{config: config}
+	s.findRefs(reflect.ValueOf(config).Elem(), "", nil)
+	next, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	*config = *next
+	s.current.Store(config)
+	return s, nil
+}
+
+// Files returns the number of settings referencing a file
+func (s *SecretFiles) Files() int {
+	return len(s.refs)
+}
+
+// Config returns the configuration with the contents of the files of the last load
+func (s *SecretFiles) Config() *types.Configuration {
+	return s.current.Load().(*types.Configuration)
+}
+
+func (s *SecretFiles) findRefs(v reflect.Value, name string, path []int) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Type().Field(i); f.PkgPath == "" {
+				s.findRefs(v.Field(i), strings.TrimPrefix(name+"."+f.Name, "."), appendPath(path, i))
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			s.findRefs(v.Index(i), fmt.Sprintf("%v[%v]", name, i), appendPath(path, i))
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String || !v.CanSet() {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if f := iter.Value().String(); strings.HasPrefix(f, SecretFilePrefix) {
+				s.refs = append(s.refs, secretFileRef{name: fmt.Sprintf("%v.%v", name, iter.Key()), file: strings.TrimPrefix(f, SecretFilePrefix), path: path, key: iter.Key()})
+			}
+		}
+	case reflect.String:
+		if f := v.String(); strings.HasPrefix(f, SecretFilePrefix) && v.CanSet() {
+			s.refs = append(s.refs, secretFileRef{name: name, file: strings.TrimPrefix(f, SecretFilePrefix), path: path})
+		}
+	}
+}
+
+func appendPath(path []int, i int) []int {
+	return append(append(make([]int, 0, len(path)+1), path...), i)
+}
+
+// Load reads the files and publishes a copy of the configuration with the settings set to their contents, without the
+// final newline, the settings are unchanged if one of the files can't be read
+func (s *SecretFiles) Load() error {
+	next, err := s.load()
+	if err != nil {
+		return err
+	}
+	s.current.Store(next)
+	return nil
+}
+
+// load returns a copy of the configuration with the contents of the files, the lists and the maps with a setting
+// referencing a file are copied too
+func (s *SecretFiles) load() (*types.Configuration, error) {
+	contents := make([]string, len(s.refs))
+	for i, j := range s.refs {
+		b, err := ioutil.ReadFile(filepath.Clean(j.file))
+		if err != nil {
+			return nil, fmt.Errorf("Can't read the file of setting '%v' : %v", j.name, err)
+		}
+		contents[i] = strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r")
+	}
+
+	next := new(types.Configuration)
+	*next = *s.config
+	if v := s.current.Load(); v != nil {
+		*next = *v.(*types.Configuration)
+	}
+	for i, j := range s.refs {
+		v := reflect.ValueOf(next).Elem()
+		for _, k := range j.path {
+			if v.Kind() == reflect.Slice {
+				c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				reflect.Copy(c, v)
+				v.Set(c)
+				v = v.Index(k)
+				continue
+			}
+			v = v.Field(k)
+		}
+		if !j.key.IsValid() {
+			v.SetString(contents[i])
+			continue
+		}
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), iter.Value())
+		}
+		m.SetMapIndex(j.key, reflect.ValueOf(contents[i]).Convert(v.Type().Elem()))
+		v.Set(m)
+	}
+	return next, nil
+}
@@ -0,0 +1,91 @@
+package outputs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestSecretFiles(t *testing.T) {
+	var authorization, header string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization, header = r.Header.Get("Authorization"), r.Header.Get("X-Token")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "secrets")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	apiKey := filepath.Join(dir, "opsgenie_apikey")
+	token := filepath.Join(dir, "webhook_token")
+	slackToken := filepath.Join(dir, "slack_token")
+	bearerToken := filepath.Join(dir, "webhook_authorization")
+	require.Nil(t, ioutil.WriteFile(apiKey, []byte("key-1\n"), 0600))
+	require.Nil(t, ioutil.WriteFile(token, []byte("token-1"), 0600))
+	require.Nil(t, ioutil.WriteFile(slackToken, []byte("xoxb-1\n"), 0600))
+	require.Nil(t, ioutil.WriteFile(bearerToken, []byte("Bearer webhook-1\n"), 0600))
+
+	config := &types.Configuration{}
+	config.Opsgenie.APIKey = SecretFilePrefix + apiKey
+	config.Opsgenie.Region = "eu"
+	config.Webhook.CustomHeaders = map[string]string{"X-Token": SecretFilePrefix + token, "X-Static": "value"}
+	config.Webhook.Authorization = SecretFilePrefix + bearerToken
+	config.Slack.Token = SecretFilePrefix + slackToken
+	s, err := NewSecretFiles(config)
+	require.Nil(t, err)
+	SetSecretFiles(s)
+	defer SetSecretFiles(nil)
+	require.Equal(t, 4, s.Files())
+	require.Equal(t, "key-1", config.Opsgenie.APIKey)
+	require.Equal(t, "eu", config.Opsgenie.Region)
+	require.Equal(t, map[string]string{"X-Token": "token-1", "X-Static": "value"}, config.Webhook.CustomHeaders)
+
+	opsgenie, err := NewClient("Opsgenie", ts.URL, false, false, config, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	webhook, err := NewClient("Webhook", ts.URL, false, false, config, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	slack, err := NewClient("Slack", ts.URL, false, false, config, &types.Statistics{}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, opsgenie.Post(""))
+	require.Equal(t, "GenieKey key-1", authorization)
+	require.Nil(t, webhook.Post(""))
+	require.Equal(t, "token-1", header)
+	require.Equal(t, "Bearer webhook-1", authorization)
+	require.Nil(t, slack.Post(""))
+	require.Equal(t, "Bearer xoxb-1", authorization)
+
+	// once reloaded, the new contents of the files are used
+	require.Nil(t, ioutil.WriteFile(apiKey, []byte("key-2\n"), 0600))
+	require.Nil(t, ioutil.WriteFile(token, []byte("token-2"), 0600))
+	require.Nil(t, ioutil.WriteFile(slackToken, []byte("xoxb-2\n"), 0600))
+	require.Nil(t, ioutil.WriteFile(bearerToken, []byte("Bearer webhook-2\n"), 0600))
+	require.Nil(t, s.Load())
+	require.Nil(t, opsgenie.Post(""))
+	require.Equal(t, "GenieKey key-2", authorization)
+	require.Nil(t, webhook.Post(""))
+	require.Equal(t, "token-2", header)
+	require.Equal(t, "Bearer webhook-2", authorization)
+	require.Nil(t, slack.Post(""))
+	require.Equal(t, "Bearer xoxb-2", authorization)
+	// the configuration loaded at startup isn't modified, the new contents are in a copy
+	require.Equal(t, "key-1", config.Opsgenie.APIKey)
+	require.Equal(t, "token-1", config.Webhook.CustomHeaders["X-Token"])
+	require.Equal(t, "key-2", s.Config().Opsgenie.APIKey)
+
+	// the values are kept if a file can't be read
+	require.Nil(t, os.Remove(token))
+	require.NotNil(t, s.Load())
+	require.Equal(t, "key-2", s.Config().Opsgenie.APIKey)
+	require.Equal(t, "token-2", s.Config().Webhook.CustomHeaders["X-Token"])
+
+	_, err = NewSecretFiles(&types.Configuration{Slack: types.SlackOutputConfig{WebhookURL: SecretFilePrefix + token}})
+	require.NotNil(t, err)
+}
@@ -0,0 +1,103 @@
+package outputs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Flusher is implemented by the outputs buffering events, Flush sends the events buffered and returns once they're sent
+type Flusher interface {
+	Flush()
+}
+
+// Flush sends the events buffered by the client (Azure Blob, Datadog Logs, AWS Kinesis, Elasticsearch bulk, GCP Pub/Sub, Kafka), drains its
+// NATS JetStream connection and disconnects it from the MQTT broker and from Redis, the client can't be used after
+func (c *Client) Flush() {
+	if c.azureBlob != nil {
+		c.AzureBlobFlush()
+	}
+	c.DatadogLogsFlush()
+	c.AWSKinesisFlush()
+	c.ElasticsearchFlush()
+	c.GCPPubSubFlush()
+	if c.KafkaProducer != nil {
+		if err := c.KafkaProducer.Close(); err != nil {
+			log.Printf("[ERROR] : Kafka - %v\n", err)
+		}
+	}
+	c.JetStreamClose()
+	c.MQTTClose()
+	c.RedisClose()
+	c.SyslogClose()
+	c.GELFClose()
+}
+
+// batchDrainer is implemented by the outputs sending the events by batches, the sends of the events returning once
+// their batch has been sent
+type batchDrainer interface {
+	drainBatches()
+}
+
+// drainBatches makes the client send its batches as soon as their events are buffered, so the events being sent at
+// shutdown don't wait for the next flush
+func (c *Client) drainBatches() {
+	for _, i := range []*batcher{c.datadogLogs, c.elasticsearchBulk} {
+		if i != nil {
+			i.drain()
+		}
+	}
+	if c.awsKinesis != nil {
+		c.awsKinesis.drain()
+	}
+}
+
+// Wait returns once the events being sent are, or with the error of ctx if it expires before
+func (d *Drainer) Wait(ctx context.Context) error {
+	t := time.NewTicker(10 * time.Millisecond)
+	defer t.Stop()
+	for atomic.LoadInt64(&d.inflight) > 0 {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Shutdown refuses the new events, waits for the events being sent to the outputs and then flushes the Flushers
+// concurrently, it returns the error of ctx if it expires before all of this is done
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.Drainer.Drain()
+	for _, i := range d.Flushers {
+		if b, ok := i.(batchDrainer); ok {
+			b.drainBatches()
+		}
+	}
+	if err := d.Drainer.Wait(ctx); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for _, i := range d.Flushers {
+			wg.Add(1)
+			go func(f Flusher) {
+				defer wg.Done()
+				f.Flush()
+			}(i)
+		}
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,64 @@
+package outputs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// bufferingOutput buffers the events it receives, they're sent once flushed
+type bufferingOutput struct {
+	mu       sync.Mutex
+	buffered []string
+	sent     []string
+}
+
+func (o *bufferingOutput) Send(ctx context.Context, falcopayload types.FalcoPayload) error {
+	time.Sleep(20 * time.Millisecond)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buffered = append(o.buffered, falcopayload.Rule)
+	return nil
+}
+
+func (o *bufferingOutput) Flush() {
+	time.Sleep(20 * time.Millisecond)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = append(o.sent, o.buffered...)
+	o.buffered = nil
+}
+
+func TestDispatcherShutdown(t *testing.T) {
+	o := new(bufferingOutput)
+	d, err := NewDispatcher(nil, []string{"Buffering"}, new(Drainer), newTestPromStats())
+	require.Nil(t, err)
+	d.Flushers = []Flusher{o}
+
+	for _, i := range []string{"a", "b", "c"} {
+		x := d.NewDispatch(types.FalcoPayload{Rule: i})
+		x.Add("Buffering", o)
+		x.Run()
+	}
+	require.Nil(t, d.Shutdown(context.Background()))
+	require.ElementsMatch(t, []string{"a", "b", "c"}, o.sent)
+	require.Empty(t, o.buffered)
+	require.Equal(t, Drained, d.Drainer.Status())
+
+	// the deadline is returned if the flushes take longer
+	d.Flushers = []Flusher{flusherFunc(func() { time.Sleep(time.Second) })}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, d.Shutdown(ctx))
+}
+
+type flusherFunc func()
+
+func (f flusherFunc) Flush() {
+	f()
+}
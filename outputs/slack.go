@@ -1,7 +1,7 @@
 package outputs
 
 import (
-	"bytes"
+	"context"
 	"log"
 
 	"github.com/falcosecurity/falcosidekick/types"
@@ -22,10 +22,20 @@ type slackAttachment struct {
 	Fields     []slackAttachmentField `json:"fields"`
 	Footer     string                 `json:"footer,omitempty"`
 	FooterIcon string                 `json:"footer_icon,omitempty"`
+	Actions    []slackAction          `json:"actions,omitempty"`
+}
+
+// Action, a link button
+type slackAction struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	URL  string `json:"url"`
 }
 
 // Payload
 type slackPayload struct {
+	Channel     string            `json:"channel,omitempty"`
+	ThreadTS    string            `json:"thread_ts,omitempty"`
 	Text        string            `json:"text,omitempty"`
 	Username    string            `json:"username,omitempty"`
 	IconURL     string            `json:"icon_url,omitempty"`
@@ -78,12 +88,11 @@ func newSlackPayload(falcopayload types.FalcoPayload, config *types.Configuratio
 		attachment.Text = falcopayload.Output
 	}
 
-	if config.Slack.MessageFormatTemplate != nil {
-		buf := &bytes.Buffer{}
-		if err := config.Slack.MessageFormatTemplate.Execute(buf, falcopayload); err != nil {
+	if t := getMessageFormatTemplate(falcopayload, config.Slack.MessageFormatField, config.Slack.MessageFormatTemplates, config.Slack.MessageFormatTemplate); t != nil {
+		if m, err := executeMessageFormat(t, falcopayload); err != nil {
 			log.Printf("[ERROR] : Slack - Error expanding Slack message %v", err)
 		} else {
-			messageText = buf.String()
+			messageText = m
 		}
 	}
 
@@ -108,9 +117,14 @@ func newSlackPayload(falcopayload types.FalcoPayload, config *types.Configuratio
 	}
 	attachment.Color = color
 
+	if u := runbookURL(falcopayload); u != "" {
+		attachment.Actions = []slackAction{{Type: "button", Text: "Runbook", URL: u}}
+	}
+
 	attachments = append(attachments, attachment)
 
 	s := slackPayload{
+		Channel:     config.Slack.Channel,
 		Text:        messageText,
 		Username:    config.Slack.Username,
 		IconURL:     config.Slack.Icon,
@@ -119,21 +133,49 @@ func newSlackPayload(falcopayload types.FalcoPayload, config *types.Configuratio
 	return s
 }
 
-// SlackPost posts event to Slack
-func (c *Client) SlackPost(falcopayload types.FalcoPayload) {
+// SlackPost posts event to Slack, with a token the related events are posted as replies to the first one if the
+// threads are enabled
+func (c *Client) SlackPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Slack.Add(Total, 1)
 
-	err := c.Post(newSlackPayload(falcopayload, c.Config))
+	var err error
+	if c.config().Slack.Token == "" {
+		err = c.post(ctx, newSlackPayload(falcopayload, c.config()), falcopayload)
+	} else {
+		err = c.postSlackMessage(ctx, falcopayload)
+	}
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:slack", "status:error"})
 		c.Stats.Slack.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "slack", "status": Error}).Inc()
-		log.Printf("[ERROR] : Slack - %v\n", err)
-		return
+		logEventError("Slack", falcopayload, err)
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:slack", "status:ok"})
 	c.Stats.Slack.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "slack", "status": OK}).Inc()
+
+	return nil
+}
+
+// postSlackMessage posts the event with the Slack API, as a reply in its thread if there's one, otherwise the message
+// starts the thread
+func (c *Client) postSlackMessage(ctx context.Context, falcopayload types.FalcoPayload) error {
+	r := &slackRequest{payload: newSlackPayload(falcopayload, c.config())}
+	var key string
+	if c.slackThreads != nil {
+		key = c.slackThreads.key(falcopayload)
+		ts, err := c.slackThreads.get(ctx, key)
+		if err != nil {
+			return c.deadlineExceeded()
+		}
+		r.payload.ThreadTS = ts
+	}
+	err := c.post(ctx, r, falcopayload)
+	if c.slackThreads != nil && r.payload.ThreadTS == "" {
+		c.slackThreads.set(key, r.ts)
+	}
+	return err
 }
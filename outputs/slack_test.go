@@ -1,9 +1,16 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"text/template"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -63,3 +70,126 @@ func TestNewSlackPayload(t *testing.T) {
 	output := newSlackPayload(f, config)
 	require.Equal(t, output, expectedOutput)
 }
+
+func TestNewSlackPayloadMessageFormats(t *testing.T) {
+	config := &types.Configuration{
+		Slack: types.SlackOutputConfig{
+			MessageFormatField: "region",
+			MessageFormatTemplates: map[string]*template.Template{
+				"eu": template.Must(template.New("eu").Parse("Règle : {{ .Rule }}")),
+			},
+			MessageFormatTemplate: template.Must(template.New("").Parse("Rule: {{ .Rule }}")),
+		},
+	}
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	f.OutputFields["region"] = "EU"
+	require.Equal(t, "Règle : Test rule", newSlackPayload(f, config).Text)
+
+	f.OutputFields["region"] = "apac"
+	require.Equal(t, "Rule: Test rule", newSlackPayload(f, config).Text)
+}
+
+func TestSlackPostThreads(t *testing.T) {
+	var messages []slackPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer xoxb-token", r.Header.Get("Authorization"))
+		var p slackPayload
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		messages = append(messages, p)
+		if p.Text == "refused" {
+			fmt.Fprint(w, `{"ok":false,"error":"channel_not_found"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"ok":true,"channel":"C123","ts":"1000.%v"}`, len(messages))
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Slack.Token = "xoxb-token"
+	config.Slack.Channel = "C123"
+	config.Slack.ThreadWindow = 600
+	config.Slack.ThreadField = "k8s.pod.name"
+	client, err := NewClient("Slack", ts.URL, false, false, config, &types.Statistics{Slack: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.EnableSlackThreads()
+	now := time.Now()
+	client.slackThreads.now = func() time.Time { return now }
+
+	event := func(rule, pod string) types.FalcoPayload {
+		return types.FalcoPayload{Rule: rule, Priority: types.Warning, Output: rule, OutputFields: map[string]interface{}{"k8s.pod.name": pod}}
+	}
+	require.Nil(t, client.SlackPost(context.Background(), event("Shell", "a")))
+	require.Nil(t, client.SlackPost(context.Background(), event("Shell", "a")))
+	require.Nil(t, client.SlackPost(context.Background(), event("Shell", "b")))
+	require.Nil(t, client.SlackPost(context.Background(), event("Other", "a")))
+	require.Equal(t, "C123", messages[0].Channel)
+	require.Equal(t, "", messages[0].ThreadTS)
+	require.Equal(t, "1000.1", messages[1].ThreadTS)
+	require.Equal(t, "", messages[2].ThreadTS)
+	require.Equal(t, "", messages[3].ThreadTS)
+
+	// the events after the window start a new thread
+	now = now.Add(10 * time.Minute)
+	require.Nil(t, client.SlackPost(context.Background(), event("Shell", "a")))
+	require.Nil(t, client.SlackPost(context.Background(), event("Shell", "a")))
+	require.Equal(t, "", messages[4].ThreadTS)
+	require.Equal(t, "1000.5", messages[5].ThreadTS)
+
+	// without a first message, there's no thread
+	config.Slack.MessageFormatTemplate = template.Must(template.New("").Parse("refused"))
+	require.NotNil(t, client.SlackPost(context.Background(), event("Refused", "a")))
+	config.Slack.MessageFormatTemplate = nil
+	require.Nil(t, client.SlackPost(context.Background(), event("Refused", "a")))
+	require.Equal(t, "", messages[7].ThreadTS)
+	require.Equal(t, "1", client.Stats.Slack.Get(Error).String())
+}
+
+func TestSlackPostThreadsConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	var messages []slackPayload
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p slackPayload
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		if p.ThreadTS == "" {
+			<-release
+		}
+		mu.Lock()
+		messages = append(messages, p)
+		fmt.Fprintf(w, `{"ok":true,"channel":"C123","ts":"1000.%v"}`, len(messages))
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Slack.Token = "xoxb-token"
+	config.Slack.ThreadWindow = 600
+	client, err := NewClient("Slack", ts.URL, false, false, config, &types.Statistics{Slack: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.EnableSlackThreads()
+
+	// the events of a new thread wait for its first message, they're replies to it
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Nil(t, client.SlackPost(context.Background(), types.FalcoPayload{Rule: "Shell", Priority: types.Warning}))
+		}()
+	}
+	close(release)
+	wg.Wait()
+	require.Len(t, messages, 3)
+	require.Equal(t, "", messages[0].ThreadTS)
+	require.Equal(t, "1000.1", messages[1].ThreadTS)
+	require.Equal(t, "1000.1", messages[2].ThreadTS)
+
+	// a waiting event gives up with its deadline
+	client.slackThreads.posting["Other"] = make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Equal(t, ErrDeadlineExceeded, client.SlackPost(ctx, types.FalcoPayload{Rule: "Other", Priority: types.Warning}))
+}
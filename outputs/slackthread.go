@@ -0,0 +1,129 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// SlackPostMessageURL is the endpoint of the Slack API the messages are posted to with a token
+const SlackPostMessageURL string = "https://slack.com/api/chat.postMessage"
+
+// slackRequest is a message posted with the Slack API, the ts of the message is set once it's posted
+type slackRequest struct {
+	payload slackPayload
+	ts      string
+}
+
+type slackResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	TS    string `json:"ts"`
+}
+
+// setResponse reads the response of the Slack API, which answers 200 to the messages refused too
+func (r *slackRequest) setResponse(body []byte) error {
+	var resp slackResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("Bad response : %v", err)
+	}
+	if !resp.OK {
+		return errors.New(resp.Error)
+	}
+	r.ts = resp.TS
+	return nil
+}
+
+// slackThreads keeps the ts of the first message of each thread, the events with the same rule and the same value of
+// ThreadField are posted as replies to it until the end of the ThreadWindow started by this message
+type slackThreads struct {
+	window  time.Duration
+	field   string
+	mu      sync.Mutex
+	threads map[string]slackThread
+	posting map[string]chan struct{} // keys whose first message is being posted, closed once it's done
+	purge   int                      // size of threads purging the expired ones
+	now     func() time.Time
+}
+
+type slackThread struct {
+	ts      string
+	started time.Time
+}
+
+// EnableSlackThreads makes the Slack output post the related events as replies to the first one, within ThreadWindow
+func (c *Client) EnableSlackThreads() {
+	c.slackThreads = &slackThreads{
+		window:  time.Duration(c.config().Slack.ThreadWindow) * time.Second,
+		field:   c.config().Slack.ThreadField,
+		threads: make(map[string]slackThread),
+		posting: make(map[string]chan struct{}),
+		purge:   1024,
+		now:     time.Now,
+	}
+}
+
+// key returns the key of the thread of an event, its rule and the value of the field
+func (t *slackThreads) key(falcopayload types.FalcoPayload) string {
+	key := falcopayload.Rule
+	if t.field != "" {
+		key += fmt.Sprintf("\x00%v", falcopayload.OutputFields[t.field])
+	}
+	return key
+}
+
+// get returns the ts of the thread of the key, empty if there's none or if its window is over, the event then posts
+// the first message of the thread and ends it with set. The events of the key wait for the first message being
+// posted, or for ctx to be done.
+func (t *slackThreads) get(ctx context.Context, key string) (string, error) {
+	for {
+		t.mu.Lock()
+		if i, ok := t.threads[key]; ok && t.now().Sub(i.started) < t.window {
+			t.mu.Unlock()
+			return i.ts, nil
+		}
+		posting, ok := t.posting[key]
+		if !ok {
+			t.posting[key] = make(chan struct{})
+			t.mu.Unlock()
+			return "", nil
+		}
+		t.mu.Unlock()
+		select {
+		case <-posting:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// set starts the thread of the key with the message ts, empty if the first message failed, the next event of the key
+// posts it then
+func (t *slackThreads) set(key, ts string) {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if posting, ok := t.posting[key]; ok {
+		close(posting)
+		delete(t.posting, key)
+	}
+	if ts == "" {
+		return
+	}
+	t.threads[key] = slackThread{ts: ts, started: now}
+	if len(t.threads) >= t.purge {
+		for i, j := range t.threads {
+			if now.Sub(j.started) >= t.window {
+				delete(t.threads, i)
+			}
+		}
+		if t.purge < 2*len(t.threads) {
+			t.purge = 2 * len(t.threads)
+		}
+	}
+}
@@ -2,11 +2,23 @@ package outputs
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/tls"
+	"fmt"
 	htmlTemplate "html/template"
+	"io"
 	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/textproto"
 	"regexp"
 	"strings"
 	textTemplate "text/template"
+	"time"
 
 	"github.com/DataDog/datadog-go/statsd"
 	sasl "github.com/emersion/go-sasl"
@@ -15,11 +27,54 @@ import (
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
+// TLS modes of the SMTP output, without one STARTTLS is used if the server supports it
+const (
+	SMTPTLSNone     string = "none"     // plaintext
+	SMTPTLSStartTLS string = "starttls" // the connection is upgraded with STARTTLS, the servers not supporting it fail
+	SMTPTLSImplicit string = "tls"      // the connection is encrypted from the start (ex: port 465)
+)
+
+// Authentication mechanisms of the SMTP output
+const (
+	SMTPAuthPlain   string = "plain"
+	SMTPAuthLogin   string = "login"
+	SMTPAuthCRAMMD5 string = "cram-md5"
+)
+
+var (
+	smtpTextTemplate = textTemplate.Must(textTemplate.New(Text).Parse(plaintextTmpl))
+	smtpHTMLTemplate = htmlTemplate.Must(htmlTemplate.New("html").Parse(htmlTmpl))
+)
+
 // SMTPPayload is payload for SMTP Output
 type SMTPPayload struct {
-	To      string
-	Subject string
-	Body    string
+	From        string
+	To          string
+	Cc          string
+	Subject     string
+	ContentType string
+	Body        string
+}
+
+// message returns the email with its headers, the Bcc recipients aren't in them
+func (s SMTPPayload) message(date time.Time) string {
+	headers := []string{
+		"From: " + s.From,
+		"To: " + s.To,
+	}
+	if s.Cc != "" {
+		headers = append(headers, "Cc: "+s.Cc)
+	}
+	headers = append(headers,
+		"Subject: "+s.Subject,
+		"Date: "+date.Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: "+s.ContentType,
+	)
+	if !strings.HasPrefix(s.ContentType, "multipart/") {
+		headers = append(headers, "Content-Transfer-Encoding: quoted-printable")
+	}
+	return strings.Join(headers, "\r\n") + "\r\n\r\n" + s.Body
 }
 
 // NewSMTPClient returns a new output.Client for accessing a SMTP server.
@@ -29,6 +84,18 @@ func NewSMTPClient(config *types.Configuration, stats *types.Statistics, promSta
 		log.Printf("[ERROR] : SMTP - Bad Host:Port\n")
 		return nil, ErrClientCreation
 	}
+	switch config.SMTP.TLS {
+	case "", SMTPTLSNone, SMTPTLSStartTLS, SMTPTLSImplicit:
+	default:
+		log.Printf("[ERROR] : SMTP - Bad TLS mode '%v', must be %v, %v or %v\n", config.SMTP.TLS, SMTPTLSNone, SMTPTLSStartTLS, SMTPTLSImplicit)
+		return nil, ErrClientCreation
+	}
+	switch config.SMTP.AuthMechanism {
+	case "", SMTPAuthPlain, SMTPAuthLogin, SMTPAuthCRAMMD5:
+	default:
+		log.Printf("[ERROR] : SMTP - Bad auth mechanism '%v', must be %v, %v or %v\n", config.SMTP.AuthMechanism, SMTPAuthPlain, SMTPAuthLogin, SMTPAuthCRAMMD5)
+		return nil, ErrClientCreation
+	}
 
 	return &Client{
 		OutputType:      "SMTP",
@@ -40,71 +107,222 @@ func NewSMTPClient(config *types.Configuration, stats *types.Statistics, promSta
 	}, nil
 }
 
-func newSMTPPayload(falcopayload types.FalcoPayload, config *types.Configuration) SMTPPayload {
+// smtpAddresses returns the addresses of a comma separated list
+func smtpAddresses(list string) []string {
+	var addresses []string
+	for _, i := range strings.Split(list, ",") {
+		if i = strings.TrimSpace(i); i != "" {
+			addresses = append(addresses, i)
+		}
+	}
+	return addresses
+}
+
+func newSMTPPayload(falcopayload types.FalcoPayload, config *types.Configuration) (SMTPPayload, error) {
 	s := SMTPPayload{
-		To:      "To: " + config.SMTP.To,
-		Subject: "Subject: [" + falcopayload.Priority.String() + "] " + falcopayload.Output,
+		From:    config.SMTP.From,
+		To:      strings.Join(smtpAddresses(config.SMTP.To), ", "),
+		Cc:      strings.Join(smtpAddresses(config.SMTP.Cc), ", "),
+		Subject: mime.QEncoding.Encode("UTF-8", "["+falcopayload.Priority.String()+"] "+strings.Join(strings.Fields(falcopayload.Output), " ")),
 	}
 
-	s.Body = "MIME-version: 1.0;\n"
+	var text bytes.Buffer
+	if err := smtpTextTemplate.Execute(&text, falcopayload); err != nil {
+		return s, err
+	}
+	var body bytes.Buffer
+	if config.SMTP.OutputFormat == Text {
+		s.ContentType = `text/plain; charset="UTF-8"`
+		if err := writeQuotedPrintable(&body, text.Bytes()); err != nil {
+			return s, err
+		}
+		s.Body = body.String()
+		return s, nil
+	}
 
-	if config.SMTP.OutputFormat != Text {
-		s.Body += "Content-Type: multipart/alternative; boundary=4t74weu9byeSdJTM\n\n\n--4t74weu9byeSdJTM\n"
+	// the plain text part is the fallback of the clients not displaying the html one
+	var html bytes.Buffer
+	if err := smtpHTMLTemplate.Execute(&html, falcopayload); err != nil {
+		return s, err
+	}
+	w := multipart.NewWriter(&body)
+	for _, i := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{`text/plain; charset="UTF-8"`, text.Bytes()},
+		{`text/html; charset="UTF-8"`, html.Bytes()},
+	} {
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {i.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return s, err
+		}
+		if err := writeQuotedPrintable(part, i.content); err != nil {
+			return s, err
+		}
 	}
+	if err := w.Close(); err != nil {
+		return s, err
+	}
+	s.ContentType = "multipart/alternative; boundary=" + w.Boundary()
+	s.Body = body.String()
 
-	s.Body += "Content-Type: text/plain; charset=\"UTF-8\";\n\n"
+	return s, nil
+}
 
-	ttmpl := textTemplate.New(Text)
-	ttmpl, _ = ttmpl.Parse(plaintextTmpl)
-	var outtext bytes.Buffer
-	err := ttmpl.Execute(&outtext, falcopayload)
-	if err != nil {
-		log.Printf("[ERROR] : SMTP - %v\n", err)
-		return s
+func writeQuotedPrintable(w io.Writer, content []byte) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write(content); err != nil {
+		return err
 	}
-	s.Body += outtext.String()
+	return qp.Close()
+}
 
-	if config.SMTP.OutputFormat == Text {
-		return s
+// smtpLoginClient is the LOGIN mechanism, answering to the prompts of the server for the username and the password
+type smtpLoginClient struct {
+	username, password string
+}
+
+func (a *smtpLoginClient) Start() (string, []byte, error) {
+	return sasl.Login, nil, nil
+}
+
+func (a *smtpLoginClient) Next(challenge []byte) ([]byte, error) {
+	if strings.Contains(strings.ToLower(string(challenge)), "user") {
+		return []byte(a.username), nil
 	}
+	return []byte(a.password), nil
+}
 
-	s.Body += "--4t74weu9byeSdJTM\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
+// smtpCRAMMD5Client is the CRAM-MD5 mechanism (RFC 2195)
+type smtpCRAMMD5Client struct {
+	username, secret string
+}
+
+func (a *smtpCRAMMD5Client) Start() (string, []byte, error) {
+	return "CRAM-MD5", nil, nil
+}
+
+func (a *smtpCRAMMD5Client) Next(challenge []byte) ([]byte, error) {
+	d := hmac.New(md5.New, []byte(a.secret))
+	d.Write(challenge)
+	return []byte(fmt.Sprintf("%s %x", a.username, d.Sum(nil))), nil
+}
+
+func smtpAuth(config *types.Configuration) sasl.Client {
+	switch config.SMTP.AuthMechanism {
+	case SMTPAuthLogin:
+		return &smtpLoginClient{config.SMTP.User, config.SMTP.Password}
+	case SMTPAuthCRAMMD5:
+		return &smtpCRAMMD5Client{config.SMTP.User, config.SMTP.Password}
+	default:
+		return sasl.NewPlainClient("", config.SMTP.User, config.SMTP.Password)
+	}
+}
 
-	htmpl := htmlTemplate.New("html")
-	htmpl, _ = htmpl.Parse(htmlTmpl)
-	var outhtml bytes.Buffer
-	err = htmpl.Execute(&outhtml, falcopayload)
+// sendSMTP connects to the server with the TLS mode configured, authenticates if a user is set and sends the email
+// to all the recipients
+func (c *Client) sendSMTP(ctx context.Context, recipients []string, message string) error {
+	host, _, err := net.SplitHostPort(c.config().SMTP.HostPort)
 	if err != nil {
-		log.Printf("[ERROR] : SMTP - %v\n", err)
-		return s
+		return err
+	}
+	tlsConfig, err := newConnTLSConfig(c.config(), false, c.config().SMTP.CheckCert)
+	if err != nil {
+		return err
+	}
+	tlsConfig.ServerName = host
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", c.config().SMTP.HostPort)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if c.config().SMTP.TLS == SMTPTLSImplicit {
+		conn = tls.Client(conn, tlsConfig)
 	}
-	s.Body += outhtml.String()
 
-	return s
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	switch c.config().SMTP.TLS {
+	case SMTPTLSStartTLS:
+		err = client.StartTLS(tlsConfig)
+	case "":
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			err = client.StartTLS(tlsConfig)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if c.config().SMTP.User != "" {
+		if err := client.Auth(smtpAuth(c.config())); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(c.config().SMTP.From, nil); err != nil {
+		return err
+	}
+	for _, i := range recipients {
+		if err := client.Rcpt(i); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(message)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
 }
 
-// SendMail sends email to SMTP server
-func (c *Client) SendMail(falcopayload types.FalcoPayload) {
-	sp := newSMTPPayload(falcopayload, c.Config)
+// SendMail sends email to SMTP server, to the To, Cc and Bcc recipients
+func (c *Client) SendMail(ctx context.Context, falcopayload types.FalcoPayload) error {
+	c.Stats.SMTP.Add(Total, 1)
 
-	to := strings.Split(strings.Replace(c.Config.SMTP.To, " ", "", -1), ",")
-	auth := sasl.NewPlainClient("", c.Config.SMTP.User, c.Config.SMTP.Password)
-	body := sp.To + "\n" + sp.Subject + "\n" + sp.Body
+	sp, err := newSMTPPayload(falcopayload, c.config())
+	if err == nil {
+		var recipients []string
+		for _, i := range []string{c.config().SMTP.To, c.config().SMTP.Cc, c.config().SMTP.Bcc} {
+			recipients = append(recipients, smtpAddresses(i)...)
+		}
 
-	if c.Config.Debug == true {
-		log.Printf("[DEBUG] : SMTP payload : \nServer: %v\nFrom: %v\nTo: %v\nSubject: %v\n", c.Config.SMTP.HostPort, c.Config.SMTP.From, sp.To, sp.Subject)
-	}
+		if c.config().Debug == true {
+			log.Printf("[DEBUG] : SMTP payload : \nServer: %v\nFrom: %v\nTo: %v\nCc: %v\nSubject: %v\n", c.config().SMTP.HostPort, sp.From, sp.To, sp.Cc, sp.Subject)
+		}
 
-	c.Stats.SMTP.Add("total", 1)
-	err := smtp.SendMail(c.Config.SMTP.HostPort, auth, c.Config.SMTP.From, to, strings.NewReader(body))
+		err = c.sendSMTP(ctx, recipients, sp.message(time.Now()))
+	}
 	if err != nil {
-		go c.CountMetric("outputs", 1, []string{"output:smtp", "status:error"})
+		if ctx.Err() != nil {
+			return c.deadlineExceeded()
+		}
+		go c.CountMetric(Outputs, 1, []string{"output:smtp", "status:error"})
 		c.Stats.SMTP.Add(Error, 1)
-		log.Printf("[ERROR] : SMTP - %v\n", err)
-		return
+		c.PromStats.Outputs.With(map[string]string{"destination": "smtp", "status": Error}).Inc()
+		logEventError("SMTP", falcopayload, err)
+		return err
 	}
 
 	log.Printf("[INFO]  : SMTP - Sent OK\n")
-	go c.CountMetric("outputs", 1, []string{"output:smtp", "status:ok"})
+	go c.CountMetric(Outputs, 1, []string{"output:smtp", "status:ok"})
 	c.Stats.SMTP.Add(OK, 1)
+	c.PromStats.Outputs.With(map[string]string{"destination": "smtp", "status": OK}).Inc()
+
+	return nil
 }
@@ -0,0 +1,176 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+
+	sasl "github.com/emersion/go-sasl"
+	smtp "github.com/emersion/go-smtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+type testSMTPMail struct {
+	tls        bool
+	from       string
+	recipients []string
+	data       string
+}
+
+type testSMTPBackend struct {
+	mails chan testSMTPMail
+}
+
+func (b *testSMTPBackend) Login(state *smtp.ConnectionState, username, password string) (smtp.Session, error) {
+	if username != "falco" || password != "secret" {
+		return nil, errors.New("Invalid credentials")
+	}
+	return &testSMTPSession{backend: b, mail: testSMTPMail{tls: state.TLS.HandshakeComplete}}, nil
+}
+
+func (b *testSMTPBackend) AnonymousLogin(*smtp.ConnectionState) (smtp.Session, error) {
+	return nil, smtp.ErrAuthRequired
+}
+
+type testSMTPSession struct {
+	backend *testSMTPBackend
+	mail    testSMTPMail
+}
+
+func (s *testSMTPSession) Reset()        {}
+func (s *testSMTPSession) Logout() error { return nil }
+func (s *testSMTPSession) Mail(from string, opts smtp.MailOptions) error {
+	s.mail.from = from
+	return nil
+}
+func (s *testSMTPSession) Rcpt(to string) error {
+	s.mail.recipients = append(s.mail.recipients, to)
+	return nil
+}
+func (s *testSMTPSession) Data(r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	s.mail.data = string(b)
+	s.backend.mails <- s.mail
+	return err
+}
+
+func TestSMTPSendMail(t *testing.T) {
+	// the certificate of the server is the one of an httptest server
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	backend := &testSMTPBackend{mails: make(chan testSMTPMail, 1)}
+	s := smtp.NewServer(backend)
+	s.Domain = "localhost"
+	s.TLSConfig = ts.TLS
+	s.EnableAuth(sasl.Login, func(conn *smtp.Conn) sasl.Server {
+		return sasl.NewLoginServer(func(username, password string) error {
+			state := conn.State()
+			session, err := backend.Login(&state, username, password)
+			if err == nil {
+				conn.SetSession(session)
+			}
+			return err
+		})
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	go s.Serve(l)
+	defer s.Close()
+
+	config := &types.Configuration{}
+	config.SMTP.HostPort = l.Addr().String()
+	config.SMTP.TLS = SMTPTLSStartTLS
+	config.SMTP.AuthMechanism = SMTPAuthLogin
+	config.SMTP.User = "falco"
+	config.SMTP.Password = "secret"
+	config.SMTP.From = "falcosidekick@example.com"
+	config.SMTP.To = "soc@example.com, oncall@example.com"
+	config.SMTP.Cc = "security@example.com"
+	config.SMTP.Bcc = "audit@example.com"
+	config.SMTP.OutputFormat = "html"
+	stats := &types.Statistics{SMTP: new(expvar.Map)}
+	client, err := NewSMTPClient(config, stats, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	require.Nil(t, client.SendMail(context.Background(), f))
+
+	m := <-backend.mails
+	require.True(t, m.tls)
+	require.Equal(t, config.SMTP.From, m.from)
+	require.Equal(t, []string{"soc@example.com", "oncall@example.com", "security@example.com", "audit@example.com"}, m.recipients)
+
+	msg, err := mail.ReadMessage(strings.NewReader(m.data))
+	require.Nil(t, err)
+	require.Equal(t, "soc@example.com, oncall@example.com", msg.Header.Get("To"))
+	require.Equal(t, "security@example.com", msg.Header.Get("Cc"))
+	require.Empty(t, msg.Header.Get("Bcc"))
+	require.Equal(t, "[Debug] "+f.Output, msg.Header.Get("Subject"))
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.Nil(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	var parts []string
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		// the quoted-printable parts are decoded by the reader
+		b, err := ioutil.ReadAll(p)
+		require.Nil(t, err)
+		parts = append(parts, p.Header.Get("Content-Type"))
+		switch p.Header.Get("Content-Type") {
+		case `text/plain; charset="UTF-8"`:
+			require.Contains(t, string(b), "Rule: Test rule")
+			require.Contains(t, string(b), "proc.name: falcosidekick")
+		case `text/html; charset="UTF-8"`:
+			// the header colored by the priority and the table of the fields
+			require.Contains(t, string(b), "background-color:#ccfff2")
+			require.Contains(t, string(b), "<strong>proc.name</strong>")
+		}
+	}
+	require.Equal(t, []string{`text/plain; charset="UTF-8"`, `text/html; charset="UTF-8"`}, parts)
+
+	// the credentials refused and a server without STARTTLS fail the event
+	config.SMTP.Password = "wrong"
+	require.NotNil(t, client.SendMail(context.Background(), f))
+	config.SMTP.Password = "secret"
+	s.TLSConfig = nil
+	require.NotNil(t, client.SendMail(context.Background(), f))
+	require.Equal(t, "1", stats.SMTP.Get(OK).String())
+	require.Equal(t, "2", stats.SMTP.Get(Error).String())
+
+	config.SMTP.TLS = "ssl"
+	_, err = NewSMTPClient(config, stats, newTestPromStats(), nil, nil)
+	require.NotNil(t, err)
+}
+
+func TestSMTPCRAMMD5(t *testing.T) {
+	// example of RFC 2195
+	a := &smtpCRAMMD5Client{"tim", "tanstaaftanstaaf"}
+	mech, ir, err := a.Start()
+	require.Nil(t, err)
+	require.Equal(t, "CRAM-MD5", mech)
+	require.Nil(t, ir)
+	resp, err := a.Next([]byte("<1896.697170952@postoffice.reston.mci.net>"))
+	require.Nil(t, err)
+	require.Equal(t, "tim b913a602c7eda7a495b4e6e7334d3890", string(resp))
+}
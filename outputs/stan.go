@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"strings"
@@ -11,14 +12,14 @@ import (
 )
 
 // StanPublish publishes event to NATS Streaming
-func (c *Client) StanPublish(falcopayload types.FalcoPayload) {
+func (c *Client) StanPublish(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Stan.Add(Total, 1)
 
-	nc, err := stan.Connect(c.Config.Stan.ClusterID, c.Config.Stan.ClientID, stan.NatsURL(c.EndpointURL.String()))
+	nc, err := stan.Connect(c.config().Stan.ClusterID, c.config().Stan.ClientID, stan.NatsURL(c.EndpointURL.String()))
 	if err != nil {
 		c.setStanErrorMetrics()
 		log.Printf("[ERROR] : STAN - %v\n", err.Error())
-		return
+		return err
 	}
 	defer nc.Close()
 
@@ -27,14 +28,14 @@ func (c *Client) StanPublish(falcopayload types.FalcoPayload) {
 	if err != nil {
 		c.setStanErrorMetrics()
 		log.Printf("[ERROR] : STAN - %v\n", err.Error())
-		return
+		return err
 	}
 
 	err = nc.Publish("falco."+strings.ToLower(falcopayload.Priority.String())+"."+r, j)
 	if err != nil {
 		c.setStanErrorMetrics()
 		log.Printf("[ERROR] : STAN - %v\n", err)
-		return
+		return err
 	}
 
 	// Setting the success status
@@ -42,6 +43,8 @@ func (c *Client) StanPublish(falcopayload types.FalcoPayload) {
 	c.Stats.Stan.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "stan", "status": OK}).Inc()
 	log.Printf("[INFO]  : STAN - Publish OK\n")
+
+	return nil
 }
 
 // setStanErrorMetrics set the error stats
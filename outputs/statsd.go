@@ -45,14 +45,14 @@ func (c *Client) CountMetric(metric string, value int64, tags []string) {
 		if err := c.StatsdClient.Count(metric+t, value, []string{}, 1); err != nil {
 			c.Stats.Statsd.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "statsd", "status": Error}).Inc()
-			log.Printf("[ERROR] : StatsD - Unable to send metric (%v%v%v) : %v\n", c.Config.Statsd.Namespace, metric, t, err)
+			log.Printf("[ERROR] : StatsD - Unable to send metric (%v%v%v) : %v\n", c.config().Statsd.Namespace, metric, t, err)
 
 			return
 		}
 
 		c.Stats.Statsd.Add(OK, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "statsd", "status": OK}).Inc()
-		log.Printf("[INFO]  : StatsD - Send Metric OK (%v%v%v)\n", c.Config.Statsd.Namespace, metric, t)
+		log.Printf("[INFO]  : StatsD - Send Metric OK (%v%v%v)\n", c.config().Statsd.Namespace, metric, t)
 	}
 
 	if c.DogstatsdClient != nil {
@@ -60,13 +60,13 @@ func (c *Client) CountMetric(metric string, value int64, tags []string) {
 		if err := c.DogstatsdClient.Count(metric, value, tags, 1); err != nil {
 			c.Stats.Dogstatsd.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "dogstatsd", "status": Error}).Inc()
-			log.Printf("[ERROR] : DogStatsD - Send Metric Error (%v%v%v) : %v\n", c.Config.Statsd.Namespace, metric, tags, err)
+			log.Printf("[ERROR] : DogStatsD - Send Metric Error (%v%v%v) : %v\n", c.config().Statsd.Namespace, metric, tags, err)
 
 			return
 		}
 
 		c.Stats.Dogstatsd.Add(OK, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "dogstatsd", "status": OK}).Inc()
-		log.Printf("[INFO]  : DogStatsD - Send Metric OK (%v%v %v)\n", c.Config.Statsd.Namespace, metric, tags)
+		log.Printf("[INFO]  : DogStatsD - Send Metric OK (%v%v %v)\n", c.config().Statsd.Namespace, metric, tags)
 	}
 }
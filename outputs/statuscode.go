@@ -0,0 +1,124 @@
+package outputs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Actions of a StatusCodePolicy
+const (
+	RetryPolicy   string = "retry"
+	FailPolicy    string = "fail"
+	SuccessPolicy string = "success"
+)
+
+// DefaultStatusCodeBackoff is the backoff of a retry policy without duration
+const DefaultStatusCodeBackoff = time.Second
+
+// StatusCodePolicy overrides the default handling of a status code by an output
+type StatusCodePolicy struct {
+	Action  string
+	Backoff time.Duration
+}
+
+// ParseStatusCodePolicies parses policies by status code, they're "retry" or "retry:<backoff>" (ex: retry:5s), "fail" or "success"
+func ParseStatusCodePolicies(policies map[string]string) (map[int]StatusCodePolicy, error) {
+	p := make(map[int]StatusCodePolicy, len(policies))
+	for i, j := range policies {
+		code, err := strconv.Atoi(i)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("bad status code '%v'", i)
+		}
+		s := strings.SplitN(strings.ToLower(strings.TrimSpace(j)), ":", 2)
+		switch s[0] {
+		case RetryPolicy:
+			backoff := DefaultStatusCodeBackoff
+			if len(s) == 2 {
+				if backoff, err = time.ParseDuration(s[1]); err != nil {
+					return nil, fmt.Errorf("bad backoff for status code %v: %v", code, err)
+				}
+			}
+			p[code] = StatusCodePolicy{Action: RetryPolicy, Backoff: backoff}
+		case FailPolicy, SuccessPolicy:
+			p[code] = StatusCodePolicy{Action: s[0]}
+		default:
+			return nil, fmt.Errorf("bad policy '%v' for status code %v", j, code)
+		}
+	}
+	return p, nil
+}
+
+// retryBackoff returns the backoff before the next attempt of a request, and false if it mustn't be retried. With a
+// MaxRetryDuration, the connection errors are retried too and the number of attempts isn't limited.
+// Without policy for the status code, the transient failures are retried with an exponential backoff if RetryMaxAttempts
+// is above 1.
+func (c *Client) retryBackoff(resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err == nil {
+		if p, ok := c.StatusCodePolicies[resp.StatusCode]; ok {
+			return p.Backoff, p.Action == RetryPolicy && (c.MaxRetryDuration > 0 || attempt < c.StatusCodeRetries)
+		}
+	}
+	if c.RetryMaxAttempts > 1 && isTransientFailure(resp, err) && (c.MaxRetryDuration > 0 || attempt+1 < c.RetryMaxAttempts) {
+		return c.exponentialBackoff(attempt), true
+	}
+	return DefaultStatusCodeBackoff, err != nil && c.MaxRetryDuration > 0
+}
+
+// isTransientFailure returns true for the 429, 502, 503 and 504 responses, the connections reset and the failures to get
+// an OAuth2 token, the other failures (ex: 400, 401, 403, 404) are permanent
+func isTransientFailure(resp *http.Response, err error) bool {
+	var t *tokenError
+	if errors.As(err, &t) {
+		return true
+	}
+	if err != nil {
+		return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// exponentialBackoff returns the backoff before the retry following an attempt, RetryInitialDelay doubled at each attempt
+// up to RetryMaxDelay, with a random jitter of up to half of it
+func (c *Client) exponentialBackoff(attempt int) time.Duration {
+	d := c.RetryInitialDelay
+	for i := 0; i < attempt && (c.RetryMaxDelay <= 0 || d < c.RetryMaxDelay); i++ {
+		d *= 2
+	}
+	if c.RetryMaxDelay > 0 && d > c.RetryMaxDelay {
+		d = c.RetryMaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	// #nosec G404 no need of a cryptographic source for a jitter
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// countRetry counts a retry of a request in falcosidekick_outputs_retries
+func (c *Client) countRetry() {
+	go c.CountMetric("outputs.retries", 1, []string{"output:" + strings.ToLower(c.OutputType)})
+	if c.PromStats != nil && c.PromStats.Retries != nil {
+		c.PromStats.Retries.With(map[string]string{"destination": strings.ToLower(c.OutputType)}).Inc()
+	}
+}
+
+// finalFailure counts an event given up once its MaxRetryDuration is exhausted, with the status FinalFailure
+func (c *Client) finalFailure(elapsed time.Duration) {
+	log.Printf("[ERROR] : %v - Final failure, retries given up after %v\n", c.OutputType, elapsed.Round(time.Millisecond))
+	go c.CountMetric(Outputs, 1, []string{"output:" + strings.ToLower(c.OutputType), "status:" + FinalFailure})
+	if c.PromStats != nil && c.PromStats.Outputs != nil {
+		c.PromStats.Outputs.With(map[string]string{"destination": strings.ToLower(c.OutputType), "status": FinalFailure}).Inc()
+	}
+}
@@ -0,0 +1,148 @@
+package outputs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// SummaryRule is the rule of the events summarizing the events received during an interval
+const SummaryRule string = "Falcosidekick summary"
+
+// Summarizer counts the events by priority, rule and namespace, and sends every interval a summary event
+// with these counts to some outputs, the counts are then reset
+type Summarizer struct {
+	Interval   time.Duration
+	Priority   types.PriorityType
+	outputs    []string // names in EnabledOutputs
+	send       func(outputs []string, summary types.FalcoPayload)
+	mu         sync.Mutex
+	start      time.Time
+	count      int
+	priorities map[string]int
+	rules      map[string]int
+	namespaces map[string]int
+	now        func() time.Time
+}
+
+// NewSummarizer returns a Summarizer for the interval and the outputs configured, all the outputs must be enabled,
+// the summaries are sent with send
+func NewSummarizer(config *types.Configuration, enabledOutputs []string, send func(outputs []string, summary types.FalcoPayload)) (*Summarizer, error) {
+	enabled := make(map[string]string, len(enabledOutputs))
+	for _, i := range enabledOutputs {
+		enabled[dispatchName(i)] = i
+	}
+	s := &Summarizer{
+		Interval: time.Duration(config.Summary.Interval) * time.Second,
+		Priority: types.Priority(config.Summary.Priority),
+		send:     send,
+		now:      time.Now,
+	}
+	if len(config.Summary.Outputs) == 0 {
+		return nil, fmt.Errorf("No output for the summaries")
+	}
+	for _, i := range config.Summary.Outputs {
+		name, ok := enabled[dispatchName(i)]
+		if !ok {
+			return nil, fmt.Errorf("Output '%v' of the summaries isn't enabled", i)
+		}
+		s.outputs = append(s.outputs, name)
+	}
+	s.reset(s.now())
+	return s, nil
+}
+
+// reset starts a new interval, s.mu must be held
+func (s *Summarizer) reset(now time.Time) {
+	s.start = now
+	s.count = 0
+	s.priorities = make(map[string]int)
+	s.rules = make(map[string]int)
+	s.namespaces = make(map[string]int)
+}
+
+// Add counts an event, the summaries and the digests aren't counted
+func (s *Summarizer) Add(falcopayload types.FalcoPayload) {
+	if s == nil || falcopayload.Rule == SummaryRule || falcopayload.Rule == DigestRule {
+		return
+	}
+	namespace := ""
+	if v, ok := falcopayload.OutputFields["k8s.ns.name"]; ok && v != nil {
+		namespace = fmt.Sprintf("%v", v)
+	}
+	s.mu.Lock()
+	s.count++
+	s.priorities[strings.ToLower(falcopayload.Priority.String())]++
+	s.rules[falcopayload.Rule]++
+	if namespace != "" {
+		s.namespaces[namespace]++
+	}
+	s.mu.Unlock()
+}
+
+// Run sends a summary every interval
+func (s *Summarizer) Run() {
+	for range time.Tick(s.Interval) {
+		s.flush()
+	}
+}
+
+// flush sends the summary of the current interval and resets the counts
+func (s *Summarizer) flush() {
+	now := s.now()
+	s.mu.Lock()
+	summary := s.newSummary(now)
+	s.reset(now)
+	s.mu.Unlock()
+
+	s.send(s.outputs, summary)
+}
+
+// newSummary returns the event summarizing the current interval, s.mu must be held
+func (s *Summarizer) newSummary(now time.Time) types.FalcoPayload {
+	d := types.FalcoPayload{
+		Rule:     SummaryRule,
+		Priority: s.Priority,
+		Time:     now,
+		OutputFields: map[string]interface{}{
+			"summary.start":      s.start.UTC().Format(time.RFC3339),
+			"summary.end":        now.UTC().Format(time.RFC3339),
+			"summary.count":      s.count,
+			"summary.priorities": s.priorities,
+			"summary.rules":      s.rules,
+			"summary.namespaces": s.namespaces,
+		},
+	}
+	lines := []string{fmt.Sprintf("%v events from %v to %v", s.count, s.start.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))}
+	lines = append(lines, summaryLines("Priorities", s.priorities)...)
+	lines = append(lines, summaryLines("Rules", s.rules)...)
+	lines = append(lines, summaryLines("Namespaces", s.namespaces)...)
+	d.Output = strings.Join(lines, "\n")
+	return d
+}
+
+// summaryLines returns the lines of the counts, by decreasing count
+func summaryLines(title string, counts map[string]int) []string {
+	if len(counts) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(counts))
+	for i := range counts {
+		keys = append(keys, i)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	lines := []string{title + ":"}
+	for _, i := range keys {
+		lines = append(lines, fmt.Sprintf("  %v: %v", i, counts[i]))
+	}
+	return lines
+}
@@ -0,0 +1,65 @@
+package outputs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestSummarizer(t *testing.T) {
+	config := &types.Configuration{Summary: types.SummaryConfig{Interval: 3600, Outputs: []string{"slack"}, Priority: "notice"}}
+	var sent []types.FalcoPayload
+	var sentTo []string
+	s, err := NewSummarizer(config, []string{"Slack", "Webhook"}, func(outputs []string, summary types.FalcoPayload) {
+		sentTo = outputs
+		sent = append(sent, summary)
+	})
+	require.Nil(t, err)
+
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	s.reset(now)
+
+	s.Add(types.FalcoPayload{Rule: "Terminal shell in container", Priority: types.Notice, OutputFields: map[string]interface{}{"k8s.ns.name": "default"}})
+	s.Add(types.FalcoPayload{Rule: "Terminal shell in container", Priority: types.Notice, OutputFields: map[string]interface{}{"k8s.ns.name": "kube-system"}})
+	s.Add(types.FalcoPayload{Rule: "Write below etc", Priority: types.Critical, OutputFields: map[string]interface{}{"k8s.ns.name": "default"}})
+	s.Add(types.FalcoPayload{Rule: "Write below etc", Priority: types.Critical})
+	s.Add(types.FalcoPayload{Rule: DigestRule, Priority: types.Critical})
+
+	now = now.Add(time.Hour)
+	s.flush()
+	require.Len(t, sent, 1)
+	require.Equal(t, []string{"Slack"}, sentTo)
+	summary := sent[0]
+	require.Equal(t, SummaryRule, summary.Rule)
+	require.Equal(t, types.PriorityType(types.Notice), summary.Priority)
+	require.Equal(t, 4, summary.OutputFields["summary.count"])
+	require.Equal(t, map[string]int{"notice": 2, "critical": 2}, summary.OutputFields["summary.priorities"])
+	require.Equal(t, map[string]int{"Terminal shell in container": 2, "Write below etc": 2}, summary.OutputFields["summary.rules"])
+	require.Equal(t, map[string]int{"default": 2, "kube-system": 1}, summary.OutputFields["summary.namespaces"])
+	require.Equal(t, "2021-06-01T10:00:00Z", summary.OutputFields["summary.start"])
+	require.Equal(t, "2021-06-01T11:00:00Z", summary.OutputFields["summary.end"])
+	require.Contains(t, summary.Output, "4 events from 2021-06-01T10:00:00Z to 2021-06-01T11:00:00Z")
+	require.Contains(t, summary.Output, "Namespaces:\n  default: 2\n  kube-system: 1")
+
+	// the summary isn't counted and the counts are reset
+	s.Add(summary)
+	now = now.Add(time.Hour)
+	s.flush()
+	require.Len(t, sent, 2)
+	require.Equal(t, 0, sent[1].OutputFields["summary.count"])
+	require.Equal(t, "2021-06-01T11:00:00Z", sent[1].OutputFields["summary.start"])
+}
+
+func TestNewSummarizerErrors(t *testing.T) {
+	for _, i := range []types.SummaryConfig{
+		{Interval: 60},
+		{Interval: 60, Outputs: []string{"pagerduty"}},
+	} {
+		_, err := NewSummarizer(&types.Configuration{Summary: i}, []string{"Slack"}, nil)
+		require.NotNil(t, err)
+	}
+}
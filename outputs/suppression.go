@@ -0,0 +1,69 @@
+package outputs
+
+import (
+	"fmt"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Suppressed is the status of the outputs not called for an event matching one of their suppression rules
+const Suppressed string = "suppressed"
+
+type suppressionRule struct {
+	reason  string
+	field   string
+	values  map[string]bool
+	outputs map[string]bool // names are lowercased without spaces, empty for all the outputs
+}
+
+// Suppressor drops the events whose field has one of the values of a suppression rule (ex: evt.res "whitelisted")
+// before they're sent to the outputs of the rule, they're counted in falcosidekick_suppressed by output and reason
+type Suppressor struct {
+	PromStats *types.PromStatistics
+	rules     []suppressionRule
+}
+
+// NewSuppressor returns a Suppressor for the rules configured, all their outputs must be enabled
+func NewSuppressor(config *types.Configuration, enabledOutputs []string, promStats *types.PromStatistics) (*Suppressor, error) {
+	enabled := enabledOutputSet(enabledOutputs)
+	s := &Suppressor{PromStats: promStats}
+	for _, i := range config.Suppression.Rules {
+		if i.Reason == "" || i.Field == "" || len(i.Values) == 0 {
+			return nil, fmt.Errorf("Suppression rules must have a reason, a field and values")
+		}
+		r := suppressionRule{reason: i.Reason, field: i.Field, values: make(map[string]bool), outputs: make(map[string]bool)}
+		for _, j := range i.Values {
+			r.values[j] = true
+		}
+		for _, j := range i.Outputs {
+			if !enabled[dispatchName(j)] {
+				return nil, fmt.Errorf("Output '%v' of suppression rule '%v' isn't enabled", j, i.Reason)
+			}
+			r.outputs[dispatchName(j)] = true
+		}
+		s.rules = append(s.rules, r)
+	}
+	return s, nil
+}
+
+// Suppress returns true if the event mustn't be sent to the output, it's then counted with the reason of the first rule matching it
+func (s *Suppressor) Suppress(output string, falcopayload types.FalcoPayload) bool {
+	if s == nil {
+		return false
+	}
+	output = dispatchName(output)
+	for _, i := range s.rules {
+		if len(i.outputs) != 0 && !i.outputs[output] {
+			continue
+		}
+		v, ok := falcopayload.OutputFields[i.field]
+		if !ok || v == nil || !i.values[fmt.Sprintf("%v", v)] {
+			continue
+		}
+		if s.PromStats != nil && s.PromStats.Suppressed != nil {
+			s.PromStats.Suppressed.With(map[string]string{"destination": output, "reason": i.reason}).Inc()
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package outputs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestSuppressor(t *testing.T) {
+	config := &types.Configuration{}
+	config.Suppression.Rules = []types.SuppressionRuleConfig{
+		{Reason: "whitelisted", Field: "evt.res", Values: []string{"whitelisted", "allowed"}, Outputs: []string{"Slack"}},
+	}
+	promStats := newTestPromStats()
+	promStats.Suppressed = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "falcosidekick_suppressed"}, []string{"destination", "reason"})
+	s, err := NewSuppressor(config, []string{"Slack", "Webhook"}, promStats)
+	require.Nil(t, err)
+
+	d, err := NewDispatcher(nil, []string{"Slack", "Webhook"}, nil, promStats)
+	require.Nil(t, err)
+	d.Suppressor = s
+
+	dispatched := func(res string) []string {
+		var called []string
+		f := types.FalcoPayload{Rule: "Terminal shell in container", OutputFields: map[string]interface{}{"evt.res": res}}
+		x := d.NewDispatch(f)
+		x.Add("Slack", OutputFunc(func(context.Context, types.FalcoPayload) error { called = append(called, "Slack"); return nil }))
+		x.Add("Webhook", OutputFunc(func(context.Context, types.FalcoPayload) error { called = append(called, "Webhook"); return nil }))
+		for _, i := range x.order {
+			i.output.Send(x.ctx, x.falcopayload)
+		}
+		return called
+	}
+
+	require.Equal(t, []string{"Slack", "Webhook"}, dispatched("SUCCESS"))
+	require.Equal(t, []string{"Webhook"}, dispatched("whitelisted"))
+	require.Equal(t, []string{"Webhook"}, dispatched("allowed"))
+	require.Equal(t, float64(2), testutil.ToFloat64(promStats.Suppressed.With(map[string]string{"destination": "slack", "reason": "whitelisted"})))
+	require.Equal(t, float64(2), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "slack", "status": Suppressed})))
+
+	config.Suppression.Rules[0].Outputs = []string{"Teams"}
+	_, err = NewSuppressor(config, []string{"Slack", "Webhook"}, promStats)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,201 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-go/statsd"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Transports of the Syslog output
+const (
+	SyslogUDP string = "udp"
+	SyslogTCP string = "tcp"
+	SyslogTLS string = "tls"
+)
+
+// SyslogSDID is the SD-ID of the STRUCTURED-DATA element of the events, with the enterprise number reserved for
+// the documentation (RFC 5612)
+const SyslogSDID string = "falco@32473"
+
+// SyslogFacilities are the codes of the facilities (RFC 5424)
+var SyslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11, "ntp": 12, "security": 13, "console": 14, "solaris-cron": 15,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogParamNameReplacer removes the characters forbidden in the names of the SD-PARAMs
+var syslogParamNameReplacer = strings.NewReplacer("=", "_", " ", "_", "]", "_", `"`, "_")
+
+// syslogParamValueReplacer escapes the characters of the values of the SD-PARAMs
+var syslogParamValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "]", `\]`)
+
+// NewSyslogClient returns a new output.Client for sending RFC 5424 messages to a Syslog server
+func NewSyslogClient(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics, statsdClient, dogstatsdClient *statsd.Client) (*Client, error) {
+	if _, _, err := net.SplitHostPort(config.Syslog.Address); err != nil {
+		log.Printf("[ERROR] : Syslog - %v\n", err)
+		return nil, ErrClientCreation
+	}
+	w := &connWriter{network: "udp", address: config.Syslog.Address}
+	if config.Syslog.Transport != SyslogUDP {
+		w.network = "tcp"
+	}
+	if config.Syslog.Transport == SyslogTLS {
+		tlsConfig, err := newConnTLSConfig(config, config.Syslog.MutualTLS, config.Syslog.CheckCert)
+		if err != nil {
+			log.Printf("[ERROR] : Syslog - %v\n", err)
+			return nil, ErrClientCreation
+		}
+		w.tlsConfig = tlsConfig
+	}
+
+	return &Client{
+		OutputType:      "Syslog",
+		Config:          config,
+		Stats:           stats,
+		PromStats:       promStats,
+		StatsdClient:    statsdClient,
+		DogstatsdClient: dogstatsdClient,
+		syslog:          w,
+	}, nil
+}
+
+// syslogSeverity returns the severity of the priority of an event
+func syslogSeverity(priority types.PriorityType) int {
+	switch priority {
+	case types.Emergency:
+		return 0
+	case types.Alert:
+		return 1
+	case types.Critical:
+		return 2
+	case types.Error:
+		return 3
+	case types.Warning:
+		return 4
+	case types.Notice:
+		return 5
+	case types.Debug:
+		return 7
+	default:
+		return 6
+	}
+}
+
+// syslogHeaderField returns the value of a field of the header, printable ASCII truncated to max, "-" if it's empty
+func syslogHeaderField(value string, max int) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+	if len(value) > max {
+		value = value[:max]
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// syslogParamName returns the name of an SD-PARAM for an output field, without the forbidden characters
+func syslogParamName(name string) string {
+	return syslogHeaderField(syslogParamNameReplacer.Replace(name), 32)
+}
+
+func syslogParamValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		s = string(b)
+	case nil:
+	default:
+		s = fmt.Sprintf("%v", v)
+	}
+	return syslogParamValueReplacer.Replace(s)
+}
+
+// newSyslogMessage returns the RFC 5424 message of an event, its output fields are the SD-PARAMs of the SyslogSDID
+// element, with its rule, priority, source and tags
+func newSyslogMessage(falcopayload types.FalcoPayload, config *types.Configuration, hostname string) string {
+	pri := SyslogFacilities[config.Syslog.Facility]*8 + syslogSeverity(falcopayload.Priority)
+	timestamp := "-"
+	if !falcopayload.Time.IsZero() {
+		timestamp = falcopayload.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+	if falcopayload.Hostname != "" {
+		hostname = falcopayload.Hostname
+	}
+
+	var sd strings.Builder
+	sd.WriteString("[" + SyslogSDID)
+	params := []string{"rule", falcopayload.Rule, "priority", falcopayload.Priority.String()}
+	if falcopayload.Source != "" {
+		params = append(params, "source", falcopayload.Source)
+	}
+	if len(falcopayload.Tags) != 0 {
+		params = append(params, "tags", strings.Join(falcopayload.Tags, ","))
+	}
+	for i := 0; i < len(params); i += 2 {
+		sd.WriteString(" " + params[i] + `="` + syslogParamValueReplacer.Replace(params[i+1]) + `"`)
+	}
+	for _, i := range sortedKeys(falcopayload.OutputFields) {
+		sd.WriteString(" " + syslogParamName(i) + `="` + syslogParamValue(falcopayload.OutputFields[i]) + `"`)
+	}
+	sd.WriteString("]")
+
+	return fmt.Sprintf("<%d>1 %s %s %s - - %s %s", pri, timestamp, syslogHeaderField(hostname, 255),
+		syslogHeaderField(config.Syslog.AppName, 48), sd.String(), falcopayload.Output)
+}
+
+// SyslogPost sends the event as an RFC 5424 message, octet-counted (RFC 6587) with TCP and TLS
+func (c *Client) SyslogPost(ctx context.Context, falcopayload types.FalcoPayload) error {
+	c.Stats.Syslog.Add(Total, 1)
+
+	hostname, _ := os.Hostname()
+	message := newSyslogMessage(falcopayload, c.config(), hostname)
+	if c.syslog.network != "udp" {
+		message = strconv.Itoa(len(message)) + " " + message
+	}
+	c.checkPayloadSize(len(message), falcopayload.Rule)
+
+	if err := c.syslog.write(ctx, []byte(message)); err != nil {
+		if ctx.Err() != nil {
+			return c.deadlineExceeded()
+		}
+		c.setSyslogMetrics(Error)
+		logEventError("Syslog", falcopayload, err)
+		return err
+	}
+	c.setSyslogMetrics(OK)
+	log.Printf("[INFO]  : Syslog - Send OK\n")
+	return nil
+}
+
+func (c *Client) setSyslogMetrics(status string) {
+	go c.CountMetric(Outputs, 1, []string{"output:syslog", "status:" + status})
+	c.Stats.Syslog.Add(status, 1)
+	c.PromStats.Outputs.With(map[string]string{"destination": "syslog", "status": status}).Inc()
+}
+
+// SyslogClose closes the connection to the Syslog server at shutdown
+func (c *Client) SyslogClose() {
+	if c.syslog != nil {
+		c.syslog.mu.Lock()
+		c.syslog.close()
+		c.syslog.mu.Unlock()
+	}
+}
@@ -0,0 +1,122 @@
+package outputs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"expvar"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// syslogFrame matches the RFC 5424 messages of the events: PRI, TIMESTAMP, HOSTNAME, APP-NAME, STRUCTURED-DATA, MSG
+var syslogFrame = regexp.MustCompile(`^<(\d+)>1 (\S+) (\S+) (\S+) - - (\[.*\]) (.*)$`)
+
+func TestNewSyslogMessage(t *testing.T) {
+	config := &types.Configuration{}
+	config.Syslog.Facility = "local0"
+	config.Syslog.AppName = "falcosidekick"
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.Hostname = "node-1"
+	f.OutputFields["k8s.pod.labels"] = map[string]interface{}{"app": "nginx"}
+	f.OutputFields["fd.name"] = `/etc/"shadow"]`
+
+	for priority, severity := range map[types.PriorityType]int{types.Emergency: 0, types.Alert: 1, types.Critical: 2, types.Error: 3, types.Warning: 4, types.Notice: 5, types.Informational: 6, types.Debug: 7} {
+		f.Priority = priority
+		m := syslogFrame.FindStringSubmatch(newSyslogMessage(f, config, "localhost"))
+		require.NotNil(t, m)
+		require.Equal(t, strconv.Itoa(16*8+severity), m[1])
+	}
+
+	f.Priority = types.Debug
+	m := syslogFrame.FindStringSubmatch(newSyslogMessage(f, config, "localhost"))
+	require.Equal(t, "2001-01-01T01:10:00.000000Z", m[2])
+	require.Equal(t, "node-1", m[3])
+	require.Equal(t, "falcosidekick", m[4])
+	require.Equal(t, `[falco@32473 rule="Test rule" priority="Debug" fd.name="/etc/\"shadow\"\]" k8s.pod.labels="{\"app\":\"nginx\"}" proc.name="falcosidekick" proc.tty="1234"]`, m[5])
+	require.Equal(t, f.Output, m[6])
+}
+
+func TestSyslogPost(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer l.Close()
+	conns := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	config := &types.Configuration{}
+	config.Syslog.Address = l.Addr().String()
+	config.Syslog.Transport = SyslogTCP
+	config.Syslog.Facility = "auth"
+	config.Syslog.AppName = "falco"
+	stats := &types.Statistics{Syslog: new(expvar.Map)}
+	client, err := NewSyslogClient(config, stats, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	defer client.SyslogClose()
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.Priority = types.Critical
+
+	// the messages are octet-counted
+	read := func(conn net.Conn) string {
+		r := bufio.NewReader(conn)
+		require.Nil(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		length, err := r.ReadString(' ')
+		require.Nil(t, err)
+		n, err := strconv.Atoi(strings.TrimSpace(length))
+		require.Nil(t, err)
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		require.Nil(t, err)
+		return string(b)
+	}
+	require.Nil(t, client.SyslogPost(context.Background(), f))
+	conn := <-conns
+	m := syslogFrame.FindStringSubmatch(read(conn))
+	require.NotNil(t, m)
+	require.Equal(t, strconv.Itoa(4*8+2), m[1])
+	require.Contains(t, m[5], `proc.name="falcosidekick"`)
+
+	// the connection closed by the server is reestablished
+	conn.Close()
+	require.Eventually(t, func() bool {
+		client.syslog.mu.Lock()
+		defer client.syslog.mu.Unlock()
+		return client.syslog.conn == nil
+	}, time.Second, 10*time.Millisecond)
+	require.Nil(t, client.SyslogPost(context.Background(), f))
+	conn = <-conns
+	defer conn.Close()
+	require.NotNil(t, syslogFrame.FindStringSubmatch(read(conn)))
+	require.Equal(t, "2", stats.Syslog.Get(OK).String())
+
+	// the errors of the connections are returned
+	l.Close()
+	conn.Close()
+	require.Eventually(t, func() bool {
+		client.syslog.mu.Lock()
+		defer client.syslog.mu.Unlock()
+		return client.syslog.conn == nil
+	}, time.Second, 10*time.Millisecond)
+	require.NotNil(t, client.SyslogPost(context.Background(), f))
+}
@@ -1,7 +1,11 @@
 package outputs
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"strings"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
@@ -27,6 +31,44 @@ type teamsPayload struct {
 	Sections   []teamsSection `json:"sections"`
 }
 
+// Adaptive Card element, used for TextBlock, Image, Container and FactSet
+type teamsAdaptiveCardElement struct {
+	Type     string                     `json:"type"`
+	Text     string                     `json:"text,omitempty"`
+	URL      string                     `json:"url,omitempty"`
+	Size     string                     `json:"size,omitempty"`
+	Weight   string                     `json:"weight,omitempty"`
+	Style    string                     `json:"style,omitempty"`
+	Wrap     bool                       `json:"wrap,omitempty"`
+	IsSubtle bool                       `json:"isSubtle,omitempty"`
+	Bleed    bool                       `json:"bleed,omitempty"`
+	Items    []teamsAdaptiveCardElement `json:"items,omitempty"`
+	Facts    []teamsFact                `json:"facts,omitempty"`
+}
+
+type teamsAdaptiveCardMSTeams struct {
+	Width string `json:"width"`
+}
+
+type teamsAdaptiveCard struct {
+	Schema  string                     `json:"$schema"`
+	Type    string                     `json:"type"`
+	Version string                     `json:"version"`
+	Body    []teamsAdaptiveCardElement `json:"body"`
+	MSTeams teamsAdaptiveCardMSTeams   `json:"msteams"`
+}
+
+type teamsAttachment struct {
+	ContentType string            `json:"contentType"`
+	Content     teamsAdaptiveCard `json:"content"`
+}
+
+// Payload for Workflows (Power Automate) webhooks
+type teamsWorkflowsPayload struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
 func newTeamsPayload(falcopayload types.FalcoPayload, config *types.Configuration) teamsPayload {
 	var (
 		sections []teamsSection
@@ -101,21 +143,125 @@ func newTeamsPayload(falcopayload types.FalcoPayload, config *types.Configuratio
 	return t
 }
 
+// newTeamsWorkflowsPayload returns the Adaptive Card of the event, used by the Workflows webhooks and by the legacy
+// connectors with UseAdaptiveCard
+func newTeamsWorkflowsPayload(falcopayload types.FalcoPayload, config *types.Configuration) teamsWorkflowsPayload {
+	var (
+		items []teamsAdaptiveCardElement
+		facts []teamsFact
+		fact  teamsFact
+	)
+
+	if config.Teams.ActivityImage != "" {
+		items = append(items, teamsAdaptiveCardElement{Type: "Image", URL: config.Teams.ActivityImage, Size: "Small"})
+	}
+	items = append(items,
+		teamsAdaptiveCardElement{Type: "TextBlock", Text: falcopayload.Rule, Size: "Large", Weight: "Bolder", Wrap: true},
+		teamsAdaptiveCardElement{Type: "TextBlock", Text: falcopayload.Priority.String() + " - " + falcopayload.Time.String(), IsSubtle: true, Wrap: true},
+	)
+
+	body := []teamsAdaptiveCardElement{
+		{Type: "Container", Style: teamsAccentStyle(falcopayload.Priority), Bleed: true, Items: items},
+	}
+
+	var text string
+	if config.Teams.OutputFormat == All || config.Teams.OutputFormat == Text || config.Teams.OutputFormat == "" {
+		text = falcopayload.Output
+	}
+	if t := getMessageFormatTemplate(falcopayload, config.Teams.MessageFormatField, config.Teams.MessageFormatTemplates, config.Teams.MessageFormatTemplate); t != nil {
+		if m, err := executeMessageFormat(t, falcopayload); err != nil {
+			log.Printf("[ERROR] : Teams - Error expanding Teams message %v", err)
+		} else {
+			text = m
+		}
+	}
+	if text != "" {
+		body = append(body, teamsAdaptiveCardElement{Type: "TextBlock", Text: text, Wrap: true})
+	}
+
+	if config.Teams.OutputFormat == All || config.Teams.OutputFormat == "facts" || config.Teams.OutputFormat == "" {
+		for _, i := range getSortedStringKeys(falcopayload.OutputFields) {
+			// no blank rows for the empty fields
+			if falcopayload.OutputFields[i].(string) == "" {
+				continue
+			}
+			fact.Name = i
+			fact.Value = falcopayload.OutputFields[i].(string)
+			facts = append(facts, fact)
+		}
+		if len(facts) != 0 {
+			body = append(body, teamsAdaptiveCardElement{Type: "FactSet", Facts: facts})
+		}
+	}
+
+	return teamsWorkflowsPayload{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content: teamsAdaptiveCard{
+					Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+					Type:    "AdaptiveCard",
+					Version: "1.4",
+					Body:    body,
+					MSTeams: teamsAdaptiveCardMSTeams{Width: "Full"},
+				},
+			},
+		},
+	}
+}
+
+// checkTeamsResponse checks the body of a 2xx response. Legacy connectors answer 200 with "1" on success
+// but may also answer 200 with an error message in the body, Workflows answers 202 with an empty body.
+func checkTeamsResponse(mode string, statusCode int, body []byte) error {
+	if mode == TeamsWorkflows {
+		return nil
+	}
+	b := strings.TrimSpace(string(body))
+	if statusCode == http.StatusOK && b != "" && b != "1" {
+		return errors.New(b)
+	}
+	return nil
+}
+
+// teamsAccentStyle returns the Adaptive Card container style matching the priority
+func teamsAccentStyle(priority types.PriorityType) string {
+	switch priority {
+	case types.Emergency, types.Alert, types.Critical, types.Error:
+		return "attention"
+	case types.Warning:
+		return "warning"
+	case types.Notice:
+		return "good"
+	case types.Informational:
+		return "accent"
+	default:
+		return "default"
+	}
+}
+
 // TeamsPost posts event to Teams
-func (c *Client) TeamsPost(falcopayload types.FalcoPayload) {
+func (c *Client) TeamsPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Teams.Add(Total, 1)
 
-	err := c.Post(newTeamsPayload(falcopayload, c.Config))
+	var err error
+	if c.config().Teams.Mode == TeamsWorkflows || c.config().Teams.UseAdaptiveCard {
+		err = c.post(ctx, newTeamsWorkflowsPayload(falcopayload, c.config()), falcopayload)
+	} else {
+		err = c.post(ctx, newTeamsPayload(falcopayload, c.config()), falcopayload)
+	}
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:teams", "status:error"})
 		c.Stats.Teams.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "teams", "status": Error}).Inc()
-		log.Printf("[ERROR] : Teams - %v\n", err)
-		return
+		logEventError("Teams", falcopayload, err)
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:teams", "status:ok"})
 	c.Stats.Teams.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "teams", "status": OK}).Inc()
+
+	return nil
 }
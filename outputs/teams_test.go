@@ -1,7 +1,12 @@
 package outputs
 
 import (
+	"context"
 	"encoding/json"
+	"expvar"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -44,3 +49,45 @@ func TestNewTeamsPayload(t *testing.T) {
 	output := newTeamsPayload(f, &types.Configuration{})
 	require.Equal(t, output, expectedOutput)
 }
+
+func TestNewTeamsWorkflowsPayload(t *testing.T) {
+	expectedOutput := `{"type":"message","attachments":[{"contentType":"application/vnd.microsoft.card.adaptive","content":{"$schema":"http://adaptivecards.io/schemas/adaptive-card.json","type":"AdaptiveCard","version":"1.4","body":[{"type":"Container","style":"default","bleed":true,"items":[{"type":"TextBlock","text":"Test rule","size":"Large","weight":"Bolder","wrap":true},{"type":"TextBlock","text":"Debug - 2001-01-01 01:10:00 +0000 UTC","wrap":true,"isSubtle":true}]},{"type":"TextBlock","text":"This is a test from falcosidekick","wrap":true},{"type":"FactSet","facts":[{"name":"proc.name","value":"falcosidekick"}]}],"msteams":{"width":"Full"}}}]}`
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	output, err := json.Marshal(newTeamsWorkflowsPayload(f, &types.Configuration{}))
+	require.Nil(t, err)
+	require.JSONEq(t, expectedOutput, string(output))
+
+	f.Priority = types.Critical
+	require.Equal(t, "attention", newTeamsWorkflowsPayload(f, &types.Configuration{}).Attachments[0].Content.Body[0].Style)
+}
+
+func TestCheckTeamsResponse(t *testing.T) {
+	require.Nil(t, checkTeamsResponse(TeamsLegacy, 200, []byte("1")))
+	require.NotNil(t, checkTeamsResponse(TeamsLegacy, 200, []byte("Webhook message delivery failed with error: Microsoft Teams endpoint returned HTTP error 429")))
+	require.Nil(t, checkTeamsResponse(TeamsWorkflows, 202, []byte("")))
+}
+
+func TestTeamsPostAdaptiveCard(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte("1"))
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Teams.UseAdaptiveCard = true
+	client, err := NewClient("Teams", ts.URL, false, false, config, &types.Statistics{Teams: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(`{"output":"Shell spawned in a container (user=root container=nginx)","priority":"Warning","rule":"Terminal shell in container","time":"2001-01-01T01:10:00Z","output_fields":{"user.name":"root","container.name":"nginx","k8s.ns.name":"","k8s.pod.name":null,"proc.pid":1234}}`), &f))
+	require.Nil(t, client.TeamsPost(context.Background(), f))
+
+	golden, err := ioutil.ReadFile("testdata/teams_adaptive_card.json")
+	require.Nil(t, err)
+	require.JSONEq(t, string(golden), string(body))
+}
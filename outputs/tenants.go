@@ -0,0 +1,103 @@
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-go/statsd"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// DefaultTenant is the tenant of the events with an unknown value for the tenant field
+const DefaultTenant string = "default"
+
+// TenantRouter posts the events to the destination of their tenant, given by the value of a field.
+// A Client is created for each tenant the first time it receives an event.
+type TenantRouter struct {
+	*Client
+	Field   string
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+// NewTenantRouter returns a new TenantRouter for the tenants configured
+func NewTenantRouter(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics, statsdClient, dogstatsdClient *statsd.Client) *TenantRouter {
+	return &TenantRouter{
+		Client: &Client{
+			OutputType:      "Tenants",
+			Config:          config,
+			Stats:           stats,
+			PromStats:       promStats,
+			StatsdClient:    statsdClient,
+			DogstatsdClient: dogstatsdClient,
+		},
+		Field:   config.Tenants.Field,
+		clients: make(map[string]*Client),
+	}
+}
+
+// TenantsPost posts event to the destination of its tenant, events of unknown tenants go to the default destination or are dropped
+func (r *TenantRouter) TenantsPost(ctx context.Context, falcopayload types.FalcoPayload) error {
+	r.Stats.Tenants.Add(Total, 1)
+
+	var tenant string
+	if v, ok := falcopayload.OutputFields[r.Field]; ok && v != nil {
+		tenant = strings.ToLower(fmt.Sprintf("%v", v))
+	}
+	c, err := r.client(tenant)
+	if err != nil {
+		r.countMetric(Error)
+		logEventError("Tenants", falcopayload, err)
+		return err
+	}
+	if c == nil {
+		r.countMetric(Dropped)
+		if r.config().Debug {
+			log.Printf("[DEBUG] : Tenants - Event dropped, no destination for tenant '%v'\n", tenant)
+		}
+		return ErrEventDropped
+	}
+
+	if err := c.post(ctx, falcopayload, falcopayload); err != nil {
+		r.countMetric(Error)
+		logEventError("Tenants", falcopayload, err)
+		return err
+	}
+	r.countMetric(OK)
+	return nil
+}
+
+// client returns the Client of a tenant, nil if it has no destination and there's no default one
+func (r *TenantRouter) client(tenant string) (*Client, error) {
+	address, token := r.config().Tenants.Destinations[tenant], r.config().Tenants.Tokens[tenant]
+	if address == "" {
+		if r.config().Tenants.DefaultURL == "" {
+			return nil, nil
+		}
+		tenant, address, token = DefaultTenant, r.config().Tenants.DefaultURL, r.config().Tenants.DefaultToken
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.clients[tenant]; ok {
+		return c, nil
+	}
+	c, err := NewClient("Tenants", address, r.config().Tenants.MutualTLS, r.config().Tenants.CheckCert, r.config(), r.Stats, r.PromStats, r.StatsdClient, r.DogstatsdClient)
+	if err != nil {
+		return nil, err
+	}
+	c.CheckCert = r.config().Tenants.CheckCert
+	c.BearerToken = token
+	r.clients[tenant] = c
+	return c, nil
+}
+
+func (r *TenantRouter) countMetric(status string) {
+	go r.CountMetric(Outputs, 1, []string{"output:tenants", "status:" + status})
+	r.Stats.Tenants.Add(status, 1)
+	r.PromStats.Outputs.With(map[string]string{"destination": "tenants", "status": status}).Inc()
+}
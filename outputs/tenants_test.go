@@ -0,0 +1,58 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestTenantsPost(t *testing.T) {
+	received := make(map[string][]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var f types.FalcoPayload
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&f))
+		received[r.URL.Path] = append(received[r.URL.Path], r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{
+		Tenants: types.TenantsOutputConfig{
+			Field:        "k8s.ns.name",
+			Destinations: map[string]string{"team-a": ts.URL + "/a", "team-b": ts.URL + "/b"},
+			Tokens:       map[string]string{"team-a": "token-a"},
+		},
+	}
+	router := NewTenantRouter(config, &types.Statistics{Tenants: new(expvar.Map)}, newTestPromStats(), nil, nil)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	for _, i := range []string{"team-a", "team-b", "Team-A", "team-c"} {
+		f.OutputFields["k8s.ns.name"] = i
+		err := router.TenantsPost(context.Background(), f)
+		if i == "team-c" {
+			require.Equal(t, ErrEventDropped, err)
+		} else {
+			require.Nil(t, err)
+		}
+	}
+
+	require.Equal(t, []string{"Bearer token-a", "Bearer token-a"}, received["/a"])
+	require.Equal(t, []string{""}, received["/b"])
+	require.Len(t, router.clients, 2)
+	require.Equal(t, "1", router.Stats.Tenants.Get(Dropped).String())
+
+	// the unknown tenants go to the default destination
+	config.Tenants.DefaultURL = ts.URL + "/default"
+	require.Nil(t, router.TenantsPost(context.Background(), f))
+	delete(f.OutputFields, "k8s.ns.name")
+	require.Nil(t, router.TenantsPost(context.Background(), f))
+	require.Len(t, received["/default"], 2)
+	require.Len(t, router.clients, 3)
+}
@@ -0,0 +1,61 @@
+package outputs
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// TestEventResult is the result of the sending of the test event to an output
+type TestEventResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// TestEventHandler returns the handler of the test endpoint, a POST sends the test event with send, at the priority
+// of the priority query parameter if set (Default otherwise), and responds with the result of each output it's been
+// sent to, in JSON format, its status is Dropped if a filter of the pipeline dropped it. With a token, requests must
+// have it as bearer in their Authorization header.
+func TestEventHandler(token string, drainer *Drainer, send func(priority types.PriorityType) *Dispatch) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && !authorized(r, token) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Please send with post http method", http.StatusBadRequest)
+			return
+		}
+		if drainer.Refuse(w) {
+			return
+		}
+
+		var priority types.PriorityType = types.Default
+		if p := r.URL.Query().Get("priority"); p != "" {
+			if priority = types.Priority(p); priority == types.Default {
+				http.Error(w, "Unknown priority '"+p+"'", http.StatusBadRequest)
+				return
+			}
+		}
+
+		x := send(priority)
+		x.Wait()
+		status, results := OK, make(map[string]TestEventResult)
+		if x == nil {
+			status = Dropped
+		}
+		for name, err := range x.Results() {
+			if err != nil {
+				status = Error
+				results[name] = TestEventResult{Status: Error, Error: err.Error()}
+				continue
+			}
+			results[name] = TestEventResult{Status: OK}
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		// #nosec G104 nothing to be done if the following fails
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": status, "outputs": results})
+	}
+}
@@ -0,0 +1,65 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestTestEventHandler(t *testing.T) {
+	d, err := NewDispatcher(nil, []string{"Slack", "Webhook"}, new(Drainer), newTestPromStats())
+	require.Nil(t, err)
+
+	var priorities []types.PriorityType
+	ts := httptest.NewServer(TestEventHandler("secret", d.Drainer, func(priority types.PriorityType) *Dispatch {
+		priorities = append(priorities, priority)
+		x := d.NewDispatch(types.FalcoPayload{Priority: priority})
+		x.Add("Slack", OutputFunc(func(context.Context, types.FalcoPayload) error { return nil }))
+		x.Add("Webhook", OutputFunc(func(context.Context, types.FalcoPayload) error { return errors.New("Unexpected Response") }))
+		x.Run()
+		return x
+	}))
+	defer ts.Close()
+
+	post := func(query, token string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		return resp
+	}
+
+	resp := post("?priority=critical", "secret")
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var summary struct {
+		Status  string                     `json:"status"`
+		Outputs map[string]TestEventResult `json:"outputs"`
+	}
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&summary))
+	require.Equal(t, Error, summary.Status)
+	require.Equal(t, map[string]TestEventResult{
+		"Slack":   {Status: OK},
+		"Webhook": {Status: Error, Error: "Unexpected Response"},
+	}, summary.Outputs)
+	require.Equal(t, []types.PriorityType{types.Critical}, priorities)
+
+	// the requests without the token or with an unknown priority don't send the event
+	for query, code := range map[string]int{"": http.StatusUnauthorized, "?priority=urgent": http.StatusBadRequest} {
+		token := "secret"
+		if code == http.StatusUnauthorized {
+			token = "wrong"
+		}
+		resp := post(query, token)
+		resp.Body.Close()
+		require.Equal(t, code, resp.StatusCode)
+	}
+	require.Len(t, priorities, 1)
+}
@@ -0,0 +1,64 @@
+package outputs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"strings"
+)
+
+// mutualTLSConfig returns the TLS configuration with the CA bundle and the client certificate of the output, those it
+// doesn't have are the ones of MutualTLSFilesPath if MutualTLSEnabled is set. Without MutualTLSEnabled, its CA bundle
+// verifies the server unless CheckCert is false.
+func (c *Client) mutualTLSConfig() (*tls.Config, error) {
+	caCert, clientCert, clientKey := c.MutualTLSCACert, c.MutualTLSClientCert, c.MutualTLSClientKey
+	if c.MutualTLSEnabled {
+		if caCert == "" {
+			caCert = c.config().MutualTLSFilesPath + MutualTLSCacertFilename
+		}
+		if clientCert == "" {
+			clientCert, clientKey = c.config().MutualTLSFilesPath+MutualTLSClientCertFilename, c.config().MutualTLSFilesPath+MutualTLSClientKeyFilename
+		}
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if clientCert != "" {
+		certPEM, err := readPEM(clientCert)
+		if err != nil {
+			return nil, err
+		}
+		keyPEM, err := readPEM(clientKey)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if caCert != "" {
+		caPEM, err := readPEM(caCert)
+		if err != nil {
+			return nil, err
+		}
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("No certificate in the CA bundle")
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+	// With MutualTLS enabled, the check cert flag is ignored
+	// #nosec G402 This is only set as a result of explicit configuration
+	tlsConfig.InsecureSkipVerify = !c.MutualTLSEnabled && !c.CheckCert
+	return tlsConfig, nil
+}
+
+// readPEM returns the PEM of a setting, it's either inline (ex: from an environment variable) or the path of a file
+func readPEM(v string) ([]byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(v), "-----BEGIN") {
+		return []byte(v), nil
+	}
+	return ioutil.ReadFile(v)
+}
@@ -1,6 +1,16 @@
 package outputs
 
-import "sort"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
 
 func getSortedStringKeys(m map[string]interface{}) []string {
 	var keys []string
@@ -15,3 +25,231 @@ func getSortedStringKeys(m map[string]interface{}) []string {
 	sort.Strings(keys)
 	return keys
 }
+
+// getMessageFormatTemplate returns the template of templates (with lowercase keys) for the value of the output field
+// of the event, or the default one if there's none
+func getMessageFormatTemplate(falcopayload types.FalcoPayload, field string, templates map[string]*template.Template, def *template.Template) *template.Template {
+	if field == "" {
+		return def
+	}
+	if v, ok := falcopayload.OutputFields[field]; ok && v != nil {
+		if t, ok := templates[strings.ToLower(fmt.Sprintf("%v", v))]; ok && t != nil {
+			return t
+		}
+	}
+	return def
+}
+
+// MessageFormatFuncs are the functions of the message templates
+var MessageFormatFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// messageFormatData is the event given to the message templates, with the Field method for its output fields
+type messageFormatData struct {
+	types.FalcoPayload
+}
+
+// Field returns the value of an output field of the event, or "" if it doesn't have it (ex: {{ .Field "k8s.ns.name" | upper }})
+func (d messageFormatData) Field(name string) string {
+	if v, ok := d.OutputFields[name]; ok && v != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
+
+// executeMessageFormat returns the message of the template for the event, the template is shared by the events
+func executeMessageFormat(t *template.Template, falcopayload types.FalcoPayload) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, messageFormatData{falcopayload}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// parseFieldTemplate returns the template of a value computed from the fields of the events (keys, IDs, topics,
+// headers), with the syntax and functions of the message templates
+func parseFieldTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(MessageFormatFuncs).Parse(text)
+}
+
+// executeFieldTemplate returns the value of a template of the fields for the event, empty for a nil template, the
+// missing fields are empty and the values of the fields are escaped with replacer if it's not nil
+func executeFieldTemplate(t *template.Template, falcopayload types.FalcoPayload, replacer *strings.Replacer) (string, error) {
+	if t == nil {
+		return "", nil
+	}
+	if replacer != nil {
+		falcopayload = escapeFields(falcopayload, replacer)
+	}
+	s, err := executeMessageFormat(t, falcopayload)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(s, "<no value>", ""), nil
+}
+
+// escapeFields returns a copy of the event with the values of its fields escaped with replacer
+func escapeFields(falcopayload types.FalcoPayload, replacer *strings.Replacer) types.FalcoPayload {
+	falcopayload.Rule = replacer.Replace(falcopayload.Rule)
+	falcopayload.Source = replacer.Replace(falcopayload.Source)
+	falcopayload.Hostname = replacer.Replace(falcopayload.Hostname)
+	fields := make(map[string]interface{}, len(falcopayload.OutputFields))
+	for i, j := range falcopayload.OutputFields {
+		if j != nil {
+			j = replacer.Replace(fmt.Sprintf("%v", j))
+		}
+		fields[i] = j
+	}
+	falcopayload.OutputFields = fields
+	return falcopayload
+}
+
+// FieldValue returns the value of a field of the event, the output fields take precedence over the properties of
+// the event (rule, priority, source and hostname)
+func FieldValue(falcopayload types.FalcoPayload, field string) (string, bool) {
+	if v, ok := falcopayload.OutputFields[field]; ok && v != nil {
+		return fmt.Sprintf("%v", v), true
+	}
+	switch field {
+	case "rule":
+		return falcopayload.Rule, true
+	case "priority":
+		return falcopayload.Priority.String(), true
+	case "source":
+		return falcopayload.Source, falcopayload.Source != ""
+	case "hostname":
+		return falcopayload.Hostname, falcopayload.Hostname != ""
+	}
+	return "", false
+}
+
+// AddIngestLatency adds to the event the delay in milliseconds between its time and now as ingest_latency_ms.
+// An event with a time in the future (clock skew) gets a latency of 0 and the skew is added as ingest_clock_skew_ms.
+// It returns the latency, negative in case of clock skew.
+func AddIngestLatency(falcopayload *types.FalcoPayload, now time.Time) time.Duration {
+	latency := now.Sub(falcopayload.Time)
+	if falcopayload.OutputFields == nil {
+		falcopayload.OutputFields = make(map[string]interface{})
+	}
+	if latency < 0 {
+		falcopayload.OutputFields[IngestLatencyField] = int64(0)
+		falcopayload.OutputFields[IngestClockSkewField] = (-latency).Milliseconds()
+		return latency
+	}
+	falcopayload.OutputFields[IngestLatencyField] = latency.Milliseconds()
+	return latency
+}
+
+// AddNodeField sets the field to the node of the event, the value of the first of the sources (output fields) present
+// and not empty, the hostname sent by Falco if none is, or the default value. The field is left as is if it's already set, and unset if there's no
+// value. It returns true if the event has the field.
+func AddNodeField(falcopayload *types.FalcoPayload, field string, sources []string, defaultValue string) bool {
+	if v, ok := falcopayload.OutputFields[field]; ok && v != nil && v != "" {
+		return true
+	}
+	node := falcopayload.Hostname
+	for _, i := range sources {
+		if v, ok := falcopayload.OutputFields[i]; ok && v != nil && v != "" {
+			node = fmt.Sprintf("%v", v)
+			break
+		}
+	}
+	if node == "" {
+		node = defaultValue
+	}
+	if node == "" {
+		return false
+	}
+	if falcopayload.OutputFields == nil {
+		falcopayload.OutputFields = make(map[string]interface{})
+	}
+	falcopayload.OutputFields[field] = node
+	return true
+}
+
+// Casings of the priority in the payloads
+const (
+	PriorityCaseAsIs  string = "asis"
+	PriorityCaseLower string = "lower"
+	PriorityCaseUpper string = "upper"
+)
+
+// priorityString returns the priority with the casing of the output
+func (c *Client) priorityString(p types.PriorityType) string {
+	return casedPriority(p, c.PriorityCase)
+}
+
+// casedPriority returns the priority with a casing, asis, lower or upper
+func casedPriority(p types.PriorityType, priorityCase string) string {
+	switch priorityCase {
+	case PriorityCaseLower:
+		return strings.ToLower(p.String())
+	case PriorityCaseUpper:
+		return strings.ToUpper(p.String())
+	default:
+		return p.String()
+	}
+}
+
+// formattedFalcoPayload is a FalcoPayload with the priority cased for the output and the version of its schema embedded
+type formattedFalcoPayload struct {
+	types.FalcoPayload
+	Priority      string `json:"priority"`
+	SchemaVersion string `json:"schema_version,omitempty"`
+}
+
+// rawEventPayload is a payload with the event as received, as a string under a key
+type rawEventPayload struct {
+	payload interface{}
+	key     string
+	raw     []byte
+}
+
+func (r rawEventPayload) MarshalJSON() ([]byte, error) {
+	p, err := json.Marshal(r.payload)
+	if err != nil {
+		return nil, err
+	}
+	k, _ := json.Marshal(r.key)
+	v, _ := json.Marshal(string(r.raw))
+	if len(p) < 2 || p[len(p)-1] != '}' {
+		return nil, fmt.Errorf("payload isn't a JSON object")
+	}
+	p = p[:len(p)-1]
+	if len(p) > 1 {
+		p = append(p, ',')
+	}
+	return append(append(append(append(p, k...), ':'), v...), '}'), nil
+}
+
+// rawPayload is the event as received, it's sent as is
+type rawPayload []byte
+
+// withRawEvent returns the payload with the event as received, verbatim, as a string under key,
+// the payload is unchanged if key is empty, or the event wasn't received as JSON or it was redacted
+func withRawEvent(payload interface{}, falcopayload types.FalcoPayload, key string) interface{} {
+	if key == "" || falcopayload.RawPayload == nil {
+		return payload
+	}
+	return rawEventPayload{payload: payload, key: key, raw: falcopayload.RawPayload}
+}
+
+// formatFalcoPayload returns the event with the priority casing of the output and its schema version embedded if it's configured so
+func (c *Client) formatFalcoPayload(falcopayload types.FalcoPayload) interface{} {
+	return formatFalcoPayload(falcopayload, c.PriorityCase, c.SchemaVersion, c.SchemaVersionInPayload)
+}
+
+// formatFalcoPayload returns the event with a priority casing and the schema version embedded if inPayload is true
+func formatFalcoPayload(falcopayload types.FalcoPayload, priorityCase, schemaVersion string, inPayload bool) interface{} {
+	withVersion := inPayload && schemaVersion != ""
+	if !withVersion && (priorityCase == "" || priorityCase == PriorityCaseAsIs) {
+		return falcopayload
+	}
+	f := formattedFalcoPayload{FalcoPayload: falcopayload, Priority: casedPriority(falcopayload.Priority, priorityCase)}
+	if withVersion {
+		f.SchemaVersion = schemaVersion
+	}
+	return f
+}
@@ -0,0 +1,74 @@
+package outputs
+
+import (
+	"encoding/json"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestAddIngestLatency(t *testing.T) {
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	now := f.Time.Add(1500 * time.Millisecond)
+	require.Equal(t, 1500*time.Millisecond, AddIngestLatency(&f, now))
+	require.Equal(t, int64(1500), f.OutputFields[IngestLatencyField])
+	require.Nil(t, f.OutputFields[IngestClockSkewField])
+
+	var g types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &g))
+	now = g.Time.Add(-2 * time.Second)
+	require.Equal(t, -2*time.Second, AddIngestLatency(&g, now))
+	require.Equal(t, int64(0), g.OutputFields[IngestLatencyField])
+	require.Equal(t, int64(2000), g.OutputFields[IngestClockSkewField])
+}
+
+func TestAddNodeField(t *testing.T) {
+	sources := []string{"hostname", "k8s.node.name"}
+
+	f := types.FalcoPayload{OutputFields: map[string]interface{}{"k8s.node.name": "node-1", "proc.name": "falcosidekick"}}
+	require.True(t, AddNodeField(&f, "hostname", sources, ""))
+	require.Equal(t, "node-1", f.OutputFields["hostname"])
+
+	g := types.FalcoPayload{OutputFields: map[string]interface{}{"hostname": "host-1", "k8s.node.name": "node-1"}}
+	require.True(t, AddNodeField(&g, "node", sources, ""))
+	require.Equal(t, "host-1", g.OutputFields["node"])
+
+	h := types.FalcoPayload{OutputFields: map[string]interface{}{"hostname": ""}}
+	require.True(t, AddNodeField(&h, "node", sources, "default-node"))
+	require.Equal(t, "default-node", h.OutputFields["node"])
+
+	// without the sources, the hostname sent by Falco takes precedence over the default
+	j := types.FalcoPayload{Hostname: "falco-1", OutputFields: map[string]interface{}{"proc.name": "falcosidekick"}}
+	require.True(t, AddNodeField(&j, "node", sources, "default-node"))
+	require.Equal(t, "falco-1", j.OutputFields["node"])
+	j = types.FalcoPayload{Hostname: "falco-1", OutputFields: map[string]interface{}{"k8s.node.name": "node-1"}}
+	require.True(t, AddNodeField(&j, "node", sources, ""))
+	require.Equal(t, "node-1", j.OutputFields["node"])
+
+	var i types.FalcoPayload
+	require.False(t, AddNodeField(&i, "node", sources, ""))
+	require.Nil(t, i.OutputFields)
+}
+
+func TestExecuteMessageFormat(t *testing.T) {
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(`{"output":"This is a test from falcosidekick","priority":"Debug","rule":"Test rule","hostname":"node-1","time":"2001-01-01T01:10:00Z","output_fields":{"proc.name":"falcosidekick"}}`), &f))
+
+	tmpl, err := template.New("test").Funcs(MessageFormatFuncs).Parse(`{{ upper .Rule }} on {{ .Hostname }} ({{ .Priority }}) : {{ .Field "proc.name" | lower }}/[{{ .Field "k8s.ns.name" }}]`)
+	require.Nil(t, err)
+	m, err := executeMessageFormat(tmpl, f)
+	require.Nil(t, err)
+	require.Equal(t, "TEST RULE on node-1 (Debug) : falcosidekick/[]", m)
+
+	// the missing fields render empty, even the events without any field
+	f.OutputFields = nil
+	m, err = executeMessageFormat(tmpl, f)
+	require.Nil(t, err)
+	require.Equal(t, "TEST RULE on node-1 (Debug) : /[]", m)
+}
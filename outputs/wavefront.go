@@ -1,6 +1,7 @@
 package outputs
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -63,10 +64,10 @@ func NewWavefrontClient(config *types.Configuration, stats *types.Statistics, pr
 }
 
 // WavefrontPost sends metrics to WaveFront.
-func (c *Client) WavefrontPost(falcopayload types.FalcoPayload) {
+func (c *Client) WavefrontPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 
 	tags := make(map[string]string)
-	tags["severity"] = falcopayload.Priority.String()
+	tags["severity"] = c.priorityString(falcopayload.Priority)
 	tags["rule"] = falcopayload.Rule
 
 	for tag, value := range falcopayload.OutputFields {
@@ -83,20 +84,22 @@ func (c *Client) WavefrontPost(falcopayload types.FalcoPayload) {
 	if c.WavefrontSender != nil {
 		sender := *c.WavefrontSender
 		// TODO: configurable metric name
-		if err := sender.SendMetric(c.Config.Wavefront.MetricName, 1, falcopayload.Time.UnixNano(), "falco-exporter", tags); err != nil {
+		if err := sender.SendMetric(c.config().Wavefront.MetricName, 1, falcopayload.Time.UnixNano(), "falco-exporter", tags); err != nil {
 			c.Stats.Wavefront.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "wavefront", "status": Error}).Inc()
 			log.Printf("[ERROR] : Wavefront - Unable to send event %s: %s\n", falcopayload.Rule, err)
-			return
+			return err
 		}
 		if err := sender.Flush(); err != nil {
 			c.Stats.Wavefront.Add(Error, 1)
 			c.PromStats.Outputs.With(map[string]string{"destination": "wavefront", "status": Error}).Inc()
 			log.Printf("[ERROR] : Wavefront - Unable to flush event %s: %s\n", falcopayload.Rule, err)
-			return
+			return err
 		}
 		c.Stats.Wavefront.Add(OK, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "wavefront", "status": OK}).Inc()
 		log.Printf("[INFO]  : Wavefront - Send Event OK %s\n", falcopayload.Rule)
 	}
+
+	return nil
 }
@@ -1,26 +1,60 @@
 package outputs
 
 import (
+	"context"
+	"fmt"
 	"log"
 
 	"github.com/falcosecurity/falcosidekick/types"
 )
 
 // WebhookPost posts event to Slack
-func (c *Client) WebhookPost(falcopayload types.FalcoPayload) {
+func (c *Client) WebhookPost(ctx context.Context, falcopayload types.FalcoPayload) error {
 	c.Stats.Webhook.Add(Total, 1)
 
-	err := c.Post(falcopayload)
+	// the event received is posted untouched, without the pipeline, the message format and the keys mapping
+	var payload interface{} = rawPayload(falcopayload.RawPayload)
+	if !c.config().Webhook.Passthrough || falcopayload.RawPayload == nil {
+		var f string
+		falcopayload, f = runPipeline(falcopayload, c.config().Webhook.Pipeline, c.config().Webhook.FieldsMapping, c.config().Webhook.RedactFields, c.Redaction, c.config().Webhook.RequiredFields)
+		if f != "" {
+			if c.config().Webhook.RequiredFieldsAction != Forward {
+				go c.CountMetric(Outputs, 1, []string{"output:webhook", "status:dropped"})
+				c.Stats.Webhook.Add(Dropped, 1)
+				c.PromStats.Outputs.With(map[string]string{"destination": "webhook", "status": Dropped}).Inc()
+				log.Printf("[WARN]  : WebHook - Event dropped, required field '%v' is missing\n", f)
+				if c.config().Webhook.RequiredFieldsAction == DeadLetter {
+					c.deadLetter(falcopayload, fmt.Errorf("Required field '%v' is missing", f))
+				}
+				return ErrEventDropped
+			}
+			log.Printf("[WARN]  : WebHook - Required field '%v' is missing\n", f)
+		}
+
+		if t := c.config().Webhook.MessageFormatTemplate; t != nil {
+			if m, err := executeMessageFormat(t, falcopayload); err != nil {
+				log.Printf("[ERROR] : WebHook - Error expanding WebHook message %v", err)
+			} else {
+				falcopayload.Output = m
+			}
+		}
+
+		payload = withRawEvent(c.formatFalcoPayload(falcopayload), falcopayload, c.config().Webhook.RawEventKey)
+	}
+
+	err := c.post(ctx, payload, falcopayload)
 	if err != nil {
 		go c.CountMetric(Outputs, 1, []string{"output:webhook", "status:error"})
 		c.Stats.Webhook.Add(Error, 1)
 		c.PromStats.Outputs.With(map[string]string{"destination": "webhook", "status": Error}).Inc()
-		log.Printf("[ERROR] : WebHook - %v\n", err.Error())
-		return
+		logEventError("WebHook", falcopayload, err)
+		return err
 	}
 
 	// Setting the success status
 	go c.CountMetric(Outputs, 1, []string{"output:webhook", "status:ok"})
 	c.Stats.Webhook.Add(OK, 1)
 	c.PromStats.Outputs.With(map[string]string{"destination": "webhook", "status": OK}).Inc()
+
+	return nil
 }
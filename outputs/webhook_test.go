@@ -0,0 +1,695 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func newTestPromStats() *types.PromStatistics {
+	return &types.PromStatistics{
+		Outputs: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "falcosidekick_outputs"}, []string{"destination", "status"}),
+	}
+}
+
+func TestWebhookPostRequiredFields(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Webhook.RequiredFields = []string{"proc.name", "k8s.ns.name"}
+	stats := &types.Statistics{Webhook: new(expvar.Map)}
+	promStats := newTestPromStats()
+
+	nc, err := NewClient("Webhook", ts.URL, false, true, config, stats, promStats, nil, nil)
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	nc.WebhookPost(context.Background(), f)
+	require.Equal(t, 0, requests)
+	require.Equal(t, "1", stats.Webhook.Get(Dropped).String())
+	require.Equal(t, float64(1), testutil.ToFloat64(promStats.Outputs.With(map[string]string{"destination": "webhook", "status": Dropped})))
+
+	// with the deadletter action, the event dropped is written to the dead letter file
+	d, err := NewDeadLetterFile(&types.Configuration{DeadLetter: types.DeadLetterConfig{File: filepath.Join(t.TempDir(), "deadletter.ndjson")}})
+	require.Nil(t, err)
+	SetDeadLetterFile(d)
+	defer SetDeadLetterFile(nil)
+	config.Webhook.RequiredFieldsAction = DeadLetter
+	require.Equal(t, ErrEventDropped, nc.WebhookPost(context.Background(), f))
+	require.Equal(t, 0, requests)
+	require.Equal(t, "2", stats.Webhook.Get(Dropped).String())
+	b, err := ioutil.ReadFile(d.path)
+	require.Nil(t, err)
+	var l deadLetter
+	require.Nil(t, json.Unmarshal(b, &l))
+	require.Equal(t, "Webhook", l.Output)
+	require.Equal(t, "Required field 'k8s.ns.name' is missing", l.Error)
+	require.Equal(t, "Test rule", l.Event.Rule)
+
+	f.OutputFields["k8s.ns.name"] = "falco"
+	nc.WebhookPost(context.Background(), f)
+	require.Equal(t, 1, requests)
+	require.Equal(t, "1", stats.Webhook.Get(OK).String())
+}
+
+func TestWebhookPostJSONLogs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	buf := new(bytes.Buffer)
+	flags, out := log.Flags(), log.Writer()
+	log.SetFlags(0)
+	log.SetOutput(&LogWriter{Out: buf, Format: JSONLog, Level: "warn"})
+	defer func() {
+		log.SetFlags(flags)
+		log.SetOutput(out)
+	}()
+
+	config := &types.Configuration{}
+	client, err := NewClient("Webhook", ts.URL, false, false, config, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.UUID = "5e1d6bd8-f7e8-4d7a-a025-2f4a8e086a2e"
+	client.WebhookPost(context.Background(), f)
+
+	var records []map[string]string
+	d := json.NewDecoder(buf)
+	for d.More() {
+		var r map[string]string
+		require.Nil(t, d.Decode(&r))
+		records = append(records, r)
+	}
+	require.Len(t, records, 2)
+	require.Equal(t, "Webhook", records[0]["output"])
+	require.Equal(t, "Unexpected Response  (500)", records[0]["error"])
+
+	r := records[1]
+	require.Equal(t, "error", r["level"])
+	require.Equal(t, "WebHook", r["output"])
+	require.Equal(t, f.UUID, r["event_id"])
+	require.Equal(t, "500 Internal Server Error", r["error"])
+	require.NotEmpty(t, r["time"])
+}
+
+func TestWebhookPostSchemaVersion(t *testing.T) {
+	var header string
+	var payload map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(SchemaVersionHeader)
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer ts.Close()
+
+	client, err := NewClient("Webhook", ts.URL, false, false, &types.Configuration{}, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.SchemaVersion = "1.2"
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	client.WebhookPost(context.Background(), f)
+	require.Equal(t, "1.2", header)
+	require.Nil(t, payload["schema_version"])
+
+	client.SchemaVersionInPayload = true
+	client.WebhookPost(context.Background(), f)
+	require.Equal(t, "1.2", payload["schema_version"])
+	require.Equal(t, "Test rule", payload["rule"])
+}
+
+func TestWebhookPostCustomHeaders(t *testing.T) {
+	var headers http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Webhook.CustomHeaders = map[string]string{"X-Tenant": "acme", "X-Route": `falco:{{ .Field "proc.name" }}/{{ .Priority }}`, "X-Namespace": `{{ .Field "k8s.ns.name" }}`}
+	client, err := NewClient("Webhook", ts.URL, false, false, config, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	require.Nil(t, client.WebhookPost(context.Background(), f))
+	require.Equal(t, "acme", headers.Get("X-Tenant"))
+	require.Equal(t, "falco:falcosidekick/Debug", headers.Get("X-Route"))
+	// the fields missing are empty
+	require.Contains(t, headers, "X-Namespace")
+	require.Equal(t, "", headers.Get("X-Namespace"))
+
+	f.OutputFields["k8s.ns.name"] = "kube\r\nsystem"
+	require.Nil(t, client.WebhookPost(context.Background(), f))
+	require.Equal(t, "kube system", headers.Get("X-Namespace"))
+}
+
+func TestWebhookPostPriorityCase(t *testing.T) {
+	var payload map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer ts.Close()
+
+	client, err := NewClient("Webhook", ts.URL, false, false, &types.Configuration{}, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.Priority = types.Critical
+	client.WebhookPost(context.Background(), f)
+	require.Equal(t, "Critical", payload["priority"])
+
+	client.PriorityCase = PriorityCaseLower
+	client.WebhookPost(context.Background(), f)
+	require.Equal(t, "critical", payload["priority"])
+	require.Equal(t, "Test rule", payload["rule"])
+	require.Nil(t, payload["schema_version"])
+
+	client.PriorityCase = PriorityCaseUpper
+	client.SchemaVersion = "1.2"
+	client.SchemaVersionInPayload = true
+	client.WebhookPost(context.Background(), f)
+	require.Equal(t, "CRITICAL", payload["priority"])
+	require.Equal(t, "1.2", payload["schema_version"])
+}
+
+func TestWebhookPostKeysMapping(t *testing.T) {
+	var payload map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	config.Webhook.KeysMapping = map[string]string{"priority": "severity", "output": "message", "time": ""}
+	client, err := NewClient("Webhook", ts.URL, false, false, config, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.PriorityCase = PriorityCaseLower
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	f.Priority = types.Critical
+	require.Nil(t, client.WebhookPost(context.Background(), f))
+	require.Equal(t, "critical", payload["severity"])
+	require.Equal(t, f.Output, payload["message"])
+	require.Equal(t, "Test rule", payload["rule"])
+	require.Equal(t, "falcosidekick", payload["output_fields"].(map[string]interface{})["proc.name"])
+	for _, i := range []string{"priority", "output", "time"} {
+		require.NotContains(t, payload, i)
+	}
+	// the event itself isn't changed
+	require.Equal(t, types.PriorityType(types.Critical), f.Priority)
+	require.NotEmpty(t, f.Output)
+}
+
+func TestWebhookPostPayloadSizeThreshold(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	buf := new(bytes.Buffer)
+	out := log.Writer()
+	log.SetOutput(buf)
+	defer log.SetOutput(out)
+
+	config := &types.Configuration{}
+	config.PayloadSize.Threshold = 1024
+	promStats := newTestPromStats()
+	promStats.PayloadSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "falcosidekick_payload_size_bytes"}, []string{"destination"})
+	client, err := NewClient("Webhook", ts.URL, false, false, config, &types.Statistics{Webhook: new(expvar.Map)}, promStats, nil, nil)
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	client.WebhookPost(context.Background(), f)
+	require.NotContains(t, buf.String(), "[WARN]")
+
+	f.Rule = "Huge rule"
+	f.OutputFields["proc.cmdline"] = string(bytes.Repeat([]byte("x"), 2048))
+	client.WebhookPost(context.Background(), f)
+	require.Contains(t, buf.String(), "[WARN]  : Webhook - Payload of")
+	require.Contains(t, buf.String(), "above the threshold of 1024 bytes (rule: Huge rule)")
+
+	require.Equal(t, 1, testutil.CollectAndCount(promStats.PayloadSize))
+}
+
+func TestWebhookPostHedging(t *testing.T) {
+	var requests int32
+	cancelled := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the body is read for the server to detect the cancellation
+		ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// the first request is slow and fails
+			select {
+			case <-r.Context().Done():
+				close(cancelled)
+			case <-time.After(2 * time.Second):
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient("Webhook", ts.URL, false, false, &types.Configuration{}, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.HedgeDelay = 50 * time.Millisecond
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	start := time.Now()
+	require.Nil(t, client.WebhookPost(context.Background(), f))
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("the slow request isn't cancelled")
+	}
+}
+
+func TestWebhookPostProxyAuthorization(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	redirects := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/gateway" {
+			http.Redirect(w, r, "/falco", http.StatusTemporaryRedirect)
+			return true
+		}
+		if r.URL.Path == "/away" {
+			// another host for the same server
+			http.Redirect(w, r, strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)+"/falco", http.StatusTemporaryRedirect)
+			return true
+		}
+		return false
+	}
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !redirects(w, r) {
+			headers <- r.Header
+		}
+	}))
+	defer origin.Close()
+	// the proxy answers the requests itself
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !redirects(w, r) {
+			headers <- r.Header
+		}
+	}))
+	defer proxy.Close()
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	post := func(path string) http.Header {
+		client, err := NewClient("Webhook", origin.URL+path, false, false, &types.Configuration{}, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+		require.Nil(t, err)
+		client.Authorization = "Bearer origin"
+		client.ProxyAuthorization = "Basic cHJveHk6c2VjcmV0"
+		require.Nil(t, client.WebhookPost(context.Background(), f))
+		return <-headers
+	}
+
+	// without proxy, the proxy credentials are never sent to the origin
+	h := post("/falco")
+	require.Equal(t, "Bearer origin", h.Get("Authorization"))
+	require.Empty(t, h.Get("Proxy-Authorization"))
+
+	// the origin credentials are kept on a redirect to the same host
+	h = post("/gateway")
+	require.Equal(t, "Bearer origin", h.Get("Authorization"))
+	require.Empty(t, h.Get("Proxy-Authorization"))
+
+	// none of the credentials is sent to another host
+	h = post("/away")
+	require.Empty(t, h.Get("Authorization"))
+	require.Empty(t, h.Get("Proxy-Authorization"))
+
+	transport := http.DefaultTransport.(*http.Transport)
+	proxyFunc := transport.Proxy
+	defer func() { transport.Proxy = proxyFunc }()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.Nil(t, err)
+	transport.Proxy = http.ProxyURL(proxyURL)
+
+	// through an HTTP proxy, the proxy credentials are sent to it for the first request and the redirects
+	h = post("/falco")
+	require.Equal(t, "Basic cHJveHk6c2VjcmV0", h.Get("Proxy-Authorization"))
+
+	h = post("/away")
+	require.Empty(t, h.Get("Authorization"))
+	require.Equal(t, "Basic cHJveHk6c2VjcmV0", h.Get("Proxy-Authorization"))
+}
+
+func TestWebhookPostAuthMethods(t *testing.T) {
+	var keys, tokens []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("X-Api-Key"))
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient("Webhook", ts.URL, false, false, &types.Configuration{}, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.AuthMethods, err = ParseAuthMethods([]string{"X-API-Key: old", "Authorization: Bearer new"})
+	require.Nil(t, err)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	// the 401 with the first method is retried with the second one, which is kept for the next events
+	require.Nil(t, client.WebhookPost(context.Background(), f))
+	require.Nil(t, client.WebhookPost(context.Background(), f))
+	require.Equal(t, []string{"old", "", ""}, keys)
+	require.Equal(t, []string{"", "Bearer new", "Bearer new"}, tokens)
+
+	_, err = ParseAuthMethods([]string{"Bearer new"})
+	require.NotNil(t, err)
+}
+
+func TestWebhookPostOAuth2(t *testing.T) {
+	var issued int32
+	var failing int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Nil(t, r.ParseForm())
+		require.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		require.Equal(t, "falco.write", r.Form.Get("scope"))
+		if id, secret, _ := r.BasicAuth(); id != "falcosidekick" || secret != "secret" || atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		// valid for 1s, the tokens are refreshed 10s before they expire
+		fmt.Fprintf(w, `{"access_token":"token-%v","token_type":"Bearer","expires_in":11}`, n)
+	}))
+	defer tokenServer.Close()
+
+	var mu sync.Mutex
+	tokens := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tokens[r.Header.Get("Authorization")]++
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	client, err := NewClient("Webhook", ts.URL, false, false, &types.Configuration{}, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.TokenSource = NewOAuth2TokenSource(tokenServer.URL, "falcosidekick", "secret", []string{"falco.write"})
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	// the concurrent requests share the token
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Nil(t, client.WebhookPost(context.Background(), f))
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&issued))
+	require.Equal(t, map[string]int{"Bearer token-1": 10}, tokens)
+
+	// once it expires, a new token is fetched
+	time.Sleep(1100 * time.Millisecond)
+	require.Nil(t, client.WebhookPost(context.Background(), f))
+	require.Equal(t, int32(2), atomic.LoadInt32(&issued))
+	require.Equal(t, 1, tokens["Bearer token-2"])
+
+	// the events can't be sent without a token
+	time.Sleep(1100 * time.Millisecond)
+	atomic.StoreInt32(&failing, 1)
+	require.NotNil(t, client.WebhookPost(context.Background(), f))
+	require.Equal(t, 11, tokens["Bearer token-1"]+tokens["Bearer token-2"])
+}
+
+func TestWebhookPostOAuth2Retries(t *testing.T) {
+	var fetches int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&fetches, 1) {
+		case 1:
+			// the identity provider is down
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		case 4:
+			// slower than the timeout of the output
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%v","token_type":"Bearer","expires_in":3600}`, atomic.LoadInt32(&fetches))
+	}))
+	defer tokenServer.Close()
+
+	var tokens []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		// the first token issued is revoked
+		if r.Header.Get("Authorization") == "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{Retry: types.RetryConfig{MaxAttempts: 2, InitialDelay: 10}}
+	config.Timeout.Default = "100ms"
+	client, err := NewClient("Webhook", ts.URL, false, false, config, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	client.TokenSource = NewOAuth2TokenSource(tokenServer.URL, "falcosidekick", "secret", nil)
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+
+	// the failure to get a token is retried, a token refused is fetched again once
+	require.Nil(t, client.WebhookPost(context.Background(), f))
+	require.Equal(t, []string{"Bearer token-2", "Bearer token-3"}, tokens)
+
+	// the token endpoint is called with the timeout of the output
+	client.TokenSource.invalidate(client.TokenSource.token)
+	client.RetryMaxAttempts = 1
+	start := time.Now()
+	require.NotNil(t, client.WebhookPost(context.Background(), f))
+	require.Less(t, int64(time.Since(start)), int64(200*time.Millisecond))
+}
+
+func TestWebhookPostRawEvent(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{}
+	stats := &types.Statistics{Webhook: new(expvar.Map)}
+	nc, err := NewClient("Webhook", ts.URL, false, true, config, stats, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	// the raw event has fields which aren't modeled, and its own formatting
+	raw := []byte(`{"output":"This is a test from falcosidekick","priority":"Debug","rule":"Test rule", "time":"2001-01-01T01:10:00Z","output_fields": {"proc.name":"falcosidekick"}, "hostname": "falco-1"}`)
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal(raw, &f))
+	f.RawPayload = raw
+
+	require.Nil(t, nc.WebhookPost(context.Background(), f))
+	var received map[string]interface{}
+	require.Nil(t, json.Unmarshal(body, &received))
+	require.NotContains(t, received, "_raw")
+
+	config.Webhook.RawEventKey = "_raw"
+	require.Nil(t, nc.WebhookPost(context.Background(), f))
+	received = nil
+	require.Nil(t, json.Unmarshal(body, &received))
+	require.Equal(t, string(raw), received["_raw"])
+	require.Equal(t, "Test rule", received["rule"])
+	require.Equal(t, map[string]interface{}{"proc.name": "falcosidekick"}, received["output_fields"])
+
+	// the schema version is kept with the raw event
+	nc.SchemaVersion, nc.SchemaVersionInPayload = "1.0", true
+	require.Nil(t, nc.WebhookPost(context.Background(), f))
+	received = nil
+	require.Nil(t, json.Unmarshal(body, &received))
+	require.Equal(t, "1.0", received["schema_version"])
+	require.Equal(t, string(raw), received["_raw"])
+}
+
+func TestWebhookPostPassthrough(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{Webhook: types.WebhookOutputConfig{
+		Passthrough:   true,
+		FieldsMapping: map[string]string{"proc.name": "process"},
+		KeysMapping:   map[string]string{"rule": "name"},
+	}}
+	stats := &types.Statistics{Webhook: new(expvar.Map)}
+	nc, err := NewClient("Webhook", ts.URL, false, true, config, stats, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	// the fields unknown to falcosidekick and the formatting of the event are forwarded untouched
+	raw := []byte(`{"output":"This is a test from falcosidekick","priority":"Debug","rule":"Test rule", "time":"2001-01-01T01:10:00Z","output_fields": {"proc.name":"falcosidekick"}, "newfield": {"a": 1}}`)
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal(raw, &f))
+	f.RawPayload = raw
+	// the changes made by falcosidekick once the event is received (escalation, custom fields, enrichment, node
+	// fields, runbooks) are bypassed as well
+	f.Priority = types.Critical
+	f.OutputFields["customfield"] = "custom"
+
+	require.Nil(t, nc.WebhookPost(context.Background(), f))
+	require.Equal(t, string(raw), string(body))
+	var received map[string]interface{}
+	require.Nil(t, json.Unmarshal(body, &received))
+	require.Equal(t, map[string]interface{}{"a": float64(1)}, received["newfield"])
+	require.Equal(t, "1", stats.Webhook.Get(OK).String())
+
+	// an event not received as JSON is encoded
+	f.RawPayload = nil
+	require.Nil(t, nc.WebhookPost(context.Background(), f))
+	received = nil
+	require.Nil(t, json.Unmarshal(body, &received))
+	require.Equal(t, "Test rule", received["name"])
+	require.Equal(t, "Critical", received["priority"])
+	require.NotContains(t, received, "newfield")
+}
+
+func TestWebhookPostPipeline(t *testing.T) {
+	var calls int32
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{Webhook: types.WebhookOutputConfig{
+		RequiredFields: []string{"user.name"},
+		RedactFields:   []string{"user.name"},
+		FieldsMapping:  map[string]string{"proc.name": "process"},
+	}}
+	stats := &types.Statistics{Webhook: new(expvar.Map)}
+	nc, err := NewClient("Webhook", ts.URL, false, true, config, stats, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	f := types.FalcoPayload{Rule: "Test rule", Priority: types.Debug, OutputFields: map[string]interface{}{"proc.name": "falcosidekick", "user.name": "root"}}
+
+	// validated before being redacted, the event is sent without the field
+	config.Webhook.Pipeline = []string{ValidateStep, TransformStep, RedactStep}
+	require.Nil(t, nc.WebhookPost(context.Background(), f))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	var received types.FalcoPayload
+	require.Nil(t, json.Unmarshal(body, &received))
+	require.Equal(t, map[string]interface{}{"process": "falcosidekick"}, received.OutputFields)
+
+	// redacted before being validated, the required field is missing and the event is dropped
+	config.Webhook.Pipeline = []string{TransformStep, RedactStep, ValidateStep}
+	require.Equal(t, ErrEventDropped, nc.WebhookPost(context.Background(), f))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// the steps run on a copy of the event
+	require.Equal(t, map[string]interface{}{"proc.name": "falcosidekick", "user.name": "root"}, f.OutputFields)
+}
+
+func TestWebhookPostPipelineMaskFields(t *testing.T) {
+	var calls int32
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{Webhook: types.WebhookOutputConfig{
+		RequiredFields: []string{"user.name"},
+		RedactFields:   []string{"proc.cmdline"},
+	}}
+	stats := &types.Statistics{Webhook: new(expvar.Map)}
+	nc, err := NewClient("Webhook", ts.URL, false, true, config, stats, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	nc.Redaction, err = NewRedaction([]string{"user.name"}, nil)
+	require.Nil(t, err)
+
+	f := types.FalcoPayload{Rule: "Test rule", Priority: types.Debug, OutputFields: map[string]interface{}{"proc.cmdline": "cat token", "user.name": "root"}}
+
+	// the redact step removes the RedactFields and replaces the values of the MaskFields
+	require.Nil(t, nc.WebhookPost(context.Background(), f))
+	var received types.FalcoPayload
+	require.Nil(t, json.Unmarshal(body, &received))
+	require.Equal(t, map[string]interface{}{"user.name": RedactedValue}, received.OutputFields)
+
+	// masked before being validated, the required field is missing and the event is dropped
+	config.Webhook.Pipeline = []string{RedactStep, ValidateStep}
+	require.Equal(t, ErrEventDropped, nc.WebhookPost(context.Background(), f))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestWebhookPostRedactFieldsRawEvent(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{Webhook: types.WebhookOutputConfig{
+		RedactFields: []string{"proc.cmdline"},
+		RawEventKey:  "_raw",
+	}}
+	nc, err := NewClient("Webhook", ts.URL, false, true, config, &types.Statistics{Webhook: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+
+	raw := []byte(`{"output":"A shell was spawned","priority":"Debug","rule":"Test rule","output_fields":{"proc.cmdline":"cat secret-token"}}`)
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal(raw, &f))
+	f.RawPayload = raw
+
+	// the event as received isn't added once redacted, it has the fields removed
+	require.Nil(t, nc.WebhookPost(context.Background(), f))
+	require.NotContains(t, string(body), "secret-token")
+	require.NotContains(t, string(body), "_raw")
+
+	// nor with the secrets of the Redaction
+	config.Webhook.RedactFields = nil
+	nc.Redaction, err = NewRedaction(nil, []string{"secret-[a-z]+"})
+	require.Nil(t, err)
+	require.Nil(t, nc.WebhookPost(context.Background(), f))
+	require.NotContains(t, string(body), "secret-token")
+}
@@ -0,0 +1,239 @@
+package outputs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/statsd"
+	"github.com/gorilla/websocket"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+// Backoffs between the reconnections to the WebSocket endpoint
+const (
+	webSocketMinBackoff = time.Second
+	webSocketMaxBackoff = 30 * time.Second
+)
+
+const webSocketWriteTimeout = 10 * time.Second
+
+// ErrWebSocketBufferFull is returned for the events dropped because the buffer used during the reconnections is full
+var ErrWebSocketBufferFull = errors.New("Buffer full during reconnection")
+
+// webSocketConn is a persistent connection to a WebSocket endpoint, it's reconnected with a backoff if it fails
+// and the events are buffered until it's back
+type webSocketConn struct {
+	url          string
+	header       http.Header
+	dialer       *websocket.Dialer
+	bufferSize   int
+	pingInterval time.Duration
+
+	mu           sync.Mutex
+	conn         *websocket.Conn
+	buffer       [][]byte
+	reconnecting bool
+}
+
+// NewWebSocketClient returns a new output.Client for sending the events to a WebSocket endpoint,
+// the connection is established in the background
+func NewWebSocketClient(config *types.Configuration, stats *types.Statistics, promStats *types.PromStatistics, statsdClient, dogstatsdClient *statsd.Client) (*Client, error) {
+	dialer := &websocket.Dialer{Proxy: http.ProxyFromEnvironment, HandshakeTimeout: 10 * time.Second}
+	if config.WebSocket.MutualTLS {
+		cert, err := tls.LoadX509KeyPair(config.MutualTLSFilesPath+MutualTLSClientCertFilename, config.MutualTLSFilesPath+MutualTLSClientKeyFilename)
+		if err != nil {
+			log.Printf("[ERROR] : WebSocket - %v\n", err)
+			return nil, ErrClientCreation
+		}
+		caCert, err := ioutil.ReadFile(config.MutualTLSFilesPath + MutualTLSCacertFilename)
+		if err != nil {
+			log.Printf("[ERROR] : WebSocket - %v\n", err)
+			return nil, ErrClientCreation
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		dialer.TLSClientConfig = &tls.Config{Certificates: []tls.Certificate{cert}, RootCAs: caCertPool, MinVersion: tls.VersionTLS12}
+	} else if !config.WebSocket.CheckCert {
+		// #nosec G402 This is only set as a result of explicit configuration
+		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	header := make(http.Header)
+	header.Set("User-Agent", "Falcosidekick")
+	for i, j := range config.WebSocket.CustomHeaders {
+		header.Add(i, j)
+	}
+
+	ws := &webSocketConn{
+		url:          config.WebSocket.Address,
+		header:       header,
+		dialer:       dialer,
+		bufferSize:   config.WebSocket.BufferSize,
+		pingInterval: time.Duration(config.WebSocket.PingInterval) * time.Second,
+		reconnecting: true,
+	}
+	go ws.connect()
+
+	return &Client{
+		OutputType:      "WebSocket",
+		Config:          config,
+		Stats:           stats,
+		PromStats:       promStats,
+		StatsdClient:    statsdClient,
+		DogstatsdClient: dogstatsdClient,
+		webSocket:       ws,
+	}, nil
+}
+
+// WebSocketPost writes an event as a JSON text frame, it's buffered if the connection is being reestablished
+func (c *Client) WebSocketPost(ctx context.Context, falcopayload types.FalcoPayload) error {
+	c.Stats.WebSocket.Add(Total, 1)
+
+	message, err := json.Marshal(falcopayload)
+	if err != nil {
+		c.setWebSocketMetrics(Error)
+		logEventError("WebSocket", falcopayload, err)
+		return err
+	}
+	c.checkPayloadSize(len(message), falcopayload.Rule)
+
+	if err := c.webSocket.send(message); err != nil {
+		c.setWebSocketMetrics(Error)
+		logEventError("WebSocket", falcopayload, err)
+		return err
+	}
+	c.setWebSocketMetrics(OK)
+	log.Printf("[INFO]  : WebSocket - Send OK\n")
+	return nil
+}
+
+func (c *Client) setWebSocketMetrics(status string) {
+	go c.CountMetric(Outputs, 1, []string{"output:websocket", "status:" + status})
+	c.Stats.WebSocket.Add(status, 1)
+	c.PromStats.Outputs.With(map[string]string{"destination": "websocket", "status": status}).Inc()
+}
+
+// send writes a message, or buffers it if there's no connection
+func (w *webSocketConn) send(message []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		if err := w.write(w.conn, message); err == nil {
+			return nil
+		}
+		w.fail(w.conn)
+	}
+	if len(w.buffer) >= w.bufferSize {
+		return ErrWebSocketBufferFull
+	}
+	w.buffer = append(w.buffer, message)
+	return nil
+}
+
+func (w *webSocketConn) write(conn *websocket.Conn, message []byte) error {
+	// #nosec G104 a failure is returned by the write
+	conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+	return conn.WriteMessage(websocket.TextMessage, message)
+}
+
+// fail closes a broken connection and reconnects in the background, w.mu must be held
+func (w *webSocketConn) fail(conn *websocket.Conn) {
+	if w.conn != conn {
+		return
+	}
+	w.conn = nil
+	conn.Close()
+	if !w.reconnecting {
+		w.reconnecting = true
+		go w.connect()
+	}
+}
+
+// connect dials the endpoint until it succeeds, with an exponential backoff, then sends the messages buffered
+func (w *webSocketConn) connect() {
+	backoff := webSocketMinBackoff
+	for {
+		conn, resp, err := w.dialer.Dial(w.url, w.header)
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+		if err == nil {
+			w.mu.Lock()
+			err = w.flush(conn)
+			if err == nil {
+				w.conn = conn
+				w.reconnecting = false
+				w.mu.Unlock()
+				log.Printf("[INFO]  : WebSocket - Connected to %v\n", w.url)
+				go w.keepAlive(conn)
+				return
+			}
+			w.mu.Unlock()
+			conn.Close()
+		}
+		log.Printf("[ERROR] : WebSocket - %v, reconnecting in %v\n", err, backoff)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > webSocketMaxBackoff {
+			backoff = webSocketMaxBackoff
+		}
+	}
+}
+
+// flush sends the messages buffered on a new connection, the ones not sent stay in the buffer, w.mu must be held
+func (w *webSocketConn) flush(conn *websocket.Conn) error {
+	for len(w.buffer) != 0 {
+		if err := w.write(conn, w.buffer[0]); err != nil {
+			return err
+		}
+		w.buffer = w.buffer[1:]
+	}
+	w.buffer = nil
+	return nil
+}
+
+// keepAlive pings the endpoint and reads the frames it sends, the connection is reestablished if it doesn't answer
+func (w *webSocketConn) keepAlive(conn *websocket.Conn) {
+	timeout := 2 * w.pingInterval
+	if w.pingInterval > 0 {
+		// #nosec G104 a failure is returned by the next read
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		conn.SetPongHandler(func(string) error { return conn.SetReadDeadline(time.Now().Add(timeout)) })
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			ticker := time.NewTicker(w.pingInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(webSocketWriteTimeout)); err != nil {
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	// the frames sent by the endpoint are ignored, reading them handles the control frames
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			log.Printf("[ERROR] : WebSocket - Connection lost : %v\n", err)
+			w.mu.Lock()
+			w.fail(conn)
+			w.mu.Unlock()
+			return
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package outputs
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestWebSocketPost(t *testing.T) {
+	var down int32
+	frames := make(chan types.FalcoPayload, 10)
+	conns := make(chan *websocket.Conn, 10)
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.Nil(t, err)
+		conns <- conn
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			require.Equal(t, websocket.TextMessage, messageType)
+			var f types.FalcoPayload
+			require.Nil(t, json.Unmarshal(message, &f))
+			frames <- f
+		}
+	}))
+	defer ts.Close()
+
+	config := &types.Configuration{WebSocket: types.WebSocketOutputConfig{
+		Address:       "ws" + strings.TrimPrefix(ts.URL, "http"),
+		CustomHeaders: map[string]string{"Authorization": "Bearer token"},
+		BufferSize:    10,
+		PingInterval:  1,
+	}}
+	client, err := NewWebSocketClient(config, &types.Statistics{WebSocket: new(expvar.Map)}, newTestPromStats(), nil, nil)
+	require.Nil(t, err)
+	conn := <-conns
+
+	var f types.FalcoPayload
+	require.Nil(t, json.Unmarshal([]byte(falcoTestInput), &f))
+	require.Nil(t, client.WebSocketPost(context.Background(), f))
+	require.Equal(t, f.Rule, (<-frames).Rule)
+
+	// the events are buffered while the endpoint is down and sent once reconnected
+	atomic.StoreInt32(&down, 1)
+	conn.Close()
+	require.Eventually(t, func() bool {
+		client.webSocket.mu.Lock()
+		defer client.webSocket.mu.Unlock()
+		return client.webSocket.conn == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	f.Rule = "Buffered rule"
+	require.Nil(t, client.WebSocketPost(context.Background(), f))
+	atomic.StoreInt32(&down, 0)
+
+	select {
+	case r := <-frames:
+		require.Equal(t, "Buffered rule", r.Rule)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the buffered event isn't sent after the reconnection")
+	}
+	require.Equal(t, "2", client.Stats.WebSocket.Get(OK).String())
+}
+
+func TestWebSocketBufferFull(t *testing.T) {
+	w := &webSocketConn{bufferSize: 1}
+	require.Nil(t, w.send([]byte("{}")))
+	require.Equal(t, ErrWebSocketBufferFull, w.send([]byte("{}")))
+}
@@ -0,0 +1,104 @@
+// Package routing routes the events to some of the outputs, by conditions on their fields
+package routing
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+type route struct {
+	field    string
+	equals   string
+	contains string
+	regex    *regexp.Regexp
+	outputs  []string
+	final    bool
+}
+
+// Router routes the events to the outputs of the routes whose condition on a field they match, instead of all the
+// outputs, the routes are evaluated in order and an event can match several of them, the events matching none are
+// sent to the default outputs
+type Router struct {
+	routes   []route
+	fallback []string // all the outputs if empty
+}
+
+// NewRouter returns a Router for the routes configured, all the outputs must be enabled
+func NewRouter(config *types.Configuration, enabledOutputs []string) (*Router, error) {
+	enabled := outputs.NewOutputSelection(enabledOutputs...)
+	r := new(Router)
+	for n, i := range config.Routing.Routes {
+		if i.Field == "" || len(i.Outputs) == 0 {
+			return nil, fmt.Errorf("Route %v must have a field and outputs", n+1)
+		}
+		var conditions int
+		for _, j := range []string{i.Equals, i.Contains, i.Regex} {
+			if j != "" {
+				conditions++
+			}
+		}
+		if conditions != 1 {
+			return nil, fmt.Errorf("Route %v must have one of equals, contains or regex", n+1)
+		}
+		t := route{field: i.Field, equals: i.Equals, contains: i.Contains, final: i.Final}
+		if i.Regex != "" {
+			reg, err := regexp.Compile(i.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("Bad regex of route %v : %v", n+1, err)
+			}
+			t.regex = reg
+		}
+		for _, j := range i.Outputs {
+			if !enabled.Has(j) {
+				return nil, fmt.Errorf("Output '%v' of route %v isn't enabled", j, n+1)
+			}
+			t.outputs = append(t.outputs, j)
+		}
+		r.routes = append(r.routes, t)
+	}
+	for _, i := range config.Routing.Default {
+		if !enabled.Has(i) {
+			return nil, fmt.Errorf("Default output '%v' isn't enabled", i)
+		}
+		r.fallback = append(r.fallback, i)
+	}
+	return r, nil
+}
+
+// Route returns the outputs of all the routes matching the event, up to the first final one, or the default outputs
+// if it matches none, nil for all the outputs
+func (r *Router) Route(falcopayload types.FalcoPayload) outputs.OutputSelection {
+	var selected []string
+	for _, i := range r.routes {
+		v, ok := outputs.FieldValue(falcopayload, i.field)
+		if !ok || !i.match(v) {
+			continue
+		}
+		selected = append(selected, i.outputs...)
+		if i.final {
+			break
+		}
+	}
+	if len(selected) == 0 {
+		selected = r.fallback
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+	return outputs.NewOutputSelection(selected...)
+}
+
+func (r route) match(v string) bool {
+	switch {
+	case r.regex != nil:
+		return r.regex.MatchString(v)
+	case r.contains != "":
+		return strings.Contains(v, r.contains)
+	default:
+		return v == r.equals
+	}
+}
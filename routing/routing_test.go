@@ -0,0 +1,58 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/falcosecurity/falcosidekick/outputs"
+	"github.com/falcosecurity/falcosidekick/types"
+)
+
+func TestRouter(t *testing.T) {
+	config := &types.Configuration{}
+	config.Routing.Routes = []types.RoutingRouteConfig{
+		{Field: "k8s.ns.name", Equals: "production", Outputs: []string{"Pagerduty"}},
+		{Field: "k8s.ns.name", Equals: "staging", Outputs: []string{"Slack"}},
+		{Field: "rule", Regex: "^Terminal shell", Outputs: []string{"Slack"}},
+		{Field: "k8s.pod.name", Contains: "debug", Outputs: []string{"Webhook"}, Final: true},
+		{Field: "priority", Equals: "Critical", Outputs: []string{"Pagerduty"}},
+	}
+	config.Routing.Default = []string{"Webhook"}
+	enabled := []string{"Slack", "Pagerduty", "Webhook"}
+	r, err := NewRouter(config, enabled)
+	require.Nil(t, err)
+
+	// equals
+	f := types.FalcoPayload{Rule: "Write below etc", Priority: types.Warning, OutputFields: map[string]interface{}{"k8s.ns.name": "production"}}
+	require.Equal(t, outputs.NewOutputSelection("Pagerduty"), r.Route(f))
+
+	// regex
+	f = types.FalcoPayload{Rule: "Terminal shell in container", Priority: types.Notice}
+	require.Equal(t, outputs.NewOutputSelection("Slack"), r.Route(f))
+
+	// no match, the default outputs
+	f = types.FalcoPayload{Rule: "Write below etc", Priority: types.Warning, OutputFields: map[string]interface{}{"k8s.ns.name": "dev"}}
+	require.Equal(t, outputs.NewOutputSelection("Webhook"), r.Route(f))
+
+	// two routes
+	f = types.FalcoPayload{Rule: "Terminal shell in container", Priority: types.Notice, OutputFields: map[string]interface{}{"k8s.ns.name": "production"}}
+	require.Equal(t, outputs.NewOutputSelection("Pagerduty", "Slack"), r.Route(f))
+
+	// the routes after a final one aren't evaluated
+	f = types.FalcoPayload{Rule: "Write below etc", Priority: types.Critical, OutputFields: map[string]interface{}{"k8s.pod.name": "debug-shell"}}
+	require.Equal(t, outputs.NewOutputSelection("Webhook"), r.Route(f))
+
+	// without default outputs, the events matching no route are sent to all the outputs
+	config.Routing.Default = nil
+	r, err = NewRouter(config, enabled)
+	require.Nil(t, err)
+	require.Nil(t, r.Route(types.FalcoPayload{Rule: "Write below etc", Priority: types.Warning}))
+
+	config.Routing.Routes = []types.RoutingRouteConfig{{Field: "k8s.ns.name", Equals: "production", Regex: "prod", Outputs: []string{"Slack"}}}
+	_, err = NewRouter(config, enabled)
+	require.NotNil(t, err)
+	config.Routing.Routes = []types.RoutingRouteConfig{{Field: "k8s.ns.name", Equals: "production", Outputs: []string{"Loki"}}}
+	_, err = NewRouter(config, enabled)
+	require.NotNil(t, err)
+}
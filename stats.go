@@ -21,21 +21,29 @@ func getInitStats() *types.Statistics {
 		Requests:          getInputNewMap("requests"),
 		FIFO:              getInputNewMap("fifo"),
 		GRPC:              getInputNewMap("grpc"),
+		KafkaInput:        getInputNewMap("kafka"),
 		Falco:             expvar.NewMap("falco.priority"),
 		Slack:             getOutputNewMap("slack"),
 		Rocketchat:        getOutputNewMap("rocketchat"),
 		Mattermost:        getOutputNewMap("mattermost"),
 		Teams:             getOutputNewMap("teams"),
 		Datadog:           getOutputNewMap("datadog"),
+		DatadogLogs:       getOutputNewMap("datadoglogs"),
 		Discord:           getOutputNewMap("discord"),
 		Alertmanager:      getOutputNewMap("alertmanager"),
 		Elasticsearch:     getOutputNewMap("elasticsearch"),
 		Loki:              getOutputNewMap("loki"),
 		Nats:              getOutputNewMap("nats"),
+		JetStream:         getOutputNewMap("jetstream"),
+		MQTT:              getOutputNewMap("mqtt"),
+		Redis:             getOutputNewMap("redis"),
+		Syslog:            getOutputNewMap("syslog"),
+		GELF:              getOutputNewMap("gelf"),
 		Stan:              getOutputNewMap("stan"),
 		Influxdb:          getOutputNewMap("influxdb"),
 		AWSLambda:         getOutputNewMap("awslambda"),
 		AWSSQS:            getOutputNewMap("awssqs"),
+		AWSKinesis:        getOutputNewMap("awskinesis"),
 		AWSSNS:            getOutputNewMap("awssns"),
 		AWSCloudWatchLogs: getOutputNewMap("awscloudwatchlogs"),
 		AWSS3:             getOutputNewMap("awss3"),
@@ -44,8 +52,12 @@ func getInitStats() *types.Statistics {
 		Statsd:            getOutputNewMap("statsd"),
 		Dogstatsd:         getOutputNewMap("dogstatsd"),
 		Webhook:           getOutputNewMap("webhook"),
+		Tenants:           getOutputNewMap("tenants"),
+		WebSocket:         getOutputNewMap("websocket"),
+		Fifo:              getOutputNewMap("fifo"),
 		CloudEvents:       getOutputNewMap("cloudevents"),
 		AzureEventHub:     getOutputNewMap("azureeventhub"),
+		AzureBlob:         getOutputNewMap("azureblob"),
 		GCPPubSub:         getOutputNewMap("gcppubsub"),
 		GCPStorage:        getOutputNewMap("gcpstorage"),
 		GCPCloudFunctions: getOutputNewMap("gcpcloudfunctions"),
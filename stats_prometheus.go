@@ -9,13 +9,67 @@ import (
 
 func getInitPromStats() *types.PromStatistics {
 	promStats = &types.PromStatistics{
-		Falco:   getFalcoNewCounterVec(),
-		Inputs:  getInputNewCounterVec(),
-		Outputs: getOutputNewCounterVec(),
+		Falco:           getFalcoNewCounterVec(),
+		Inputs:          getInputNewCounterVec(),
+		Outputs:         getOutputNewCounterVec(),
+		Sampled:         getSampledNewCounterVec(),
+		Suppressed:      getSuppressedNewCounterVec(),
+		Deduplicated:    getDeduplicatedNewCounterVec(),
+		Retries:         getRetriesNewCounterVec(),
+		RateLimited:     getRateLimitedNewCounterVec(),
+		MalformedInputs: getMalformedInputsNewCounterVec(),
+		PayloadSize:     getPayloadSizeNewHistogramVec(),
+		RequestDuration: getRequestDurationNewHistogramVec(),
+		CircuitBreakers: getCircuitBreakersNewGaugeVec(),
+	}
+	if config.IngestLatency.Metric {
+		promStats.IngestLatency = getIngestLatencyNewHistogram()
 	}
 	return promStats
 }
 
+func getIngestLatencyNewHistogram() prometheus.Histogram {
+	return promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "falcosidekick_ingest_latency_seconds",
+			Help:    "Delay between the time of the event and its reception by falcosidekick",
+			Buckets: []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+		},
+	)
+}
+
+func getPayloadSizeNewHistogramVec() *prometheus.HistogramVec {
+	return promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "falcosidekick_payload_size_bytes",
+			Help:    "Size of the payloads sent to the outputs",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 8),
+		},
+		[]string{"destination"},
+	)
+}
+
+func getRequestDurationNewHistogramVec() *prometheus.HistogramVec {
+	return promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "falcosidekick_output_request_duration_seconds",
+			Help:    "Duration of the requests to the outputs, by class of status code (error for the connection errors)",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"destination", "status_class"},
+	)
+}
+
+func getCircuitBreakersNewGaugeVec() *prometheus.GaugeVec {
+	return promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "falcosidekick_outputs_circuit_breaker",
+			Help: "State of the circuit breakers of the outputs, 0 closed, 1 open, 2 half-open",
+		},
+		[]string{"destination"},
+	)
+}
+
 func getInputNewCounterVec() *prometheus.CounterVec {
 	return promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -25,6 +79,36 @@ func getInputNewCounterVec() *prometheus.CounterVec {
 	)
 }
 
+func getRetriesNewCounterVec() *prometheus.CounterVec {
+	return promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "falcosidekick_outputs_retries",
+			Help: "Retries of the requests to the outputs",
+		},
+		[]string{"destination"},
+	)
+}
+
+func getRateLimitedNewCounterVec() *prometheus.CounterVec {
+	return promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "falcosidekick_outputs_ratelimited",
+			Help: "Events dropped by the rate limiters of the outputs",
+		},
+		[]string{"destination"},
+	)
+}
+
+func getMalformedInputsNewCounterVec() *prometheus.CounterVec {
+	return promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "falcosidekick_malformed_inputs",
+			Help: "Events received with a malformed JSON body",
+		},
+		[]string{"source"},
+	)
+}
+
 func getOutputNewCounterVec() *prometheus.CounterVec {
 	return promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -34,16 +118,46 @@ func getOutputNewCounterVec() *prometheus.CounterVec {
 	)
 }
 
+func getSampledNewCounterVec() *prometheus.CounterVec {
+	return promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "falcosidekick_sampled_out",
+			Help: "Events dropped by the sampling of their rule",
+		},
+		[]string{"rule"},
+	)
+}
+
+func getDeduplicatedNewCounterVec() *prometheus.CounterVec {
+	return promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "falcosidekick_outputs_deduplicated",
+			Help: "Events not sent to the outputs as duplicates of an event sent within the deduplication window",
+		},
+		[]string{"rule"},
+	)
+}
+
+func getSuppressedNewCounterVec() *prometheus.CounterVec {
+	return promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "falcosidekick_suppressed",
+			Help: "Events not sent to the outputs by a suppression rule",
+		},
+		[]string{"destination", "reason"},
+	)
+}
+
 func getFalcoNewCounterVec() *prometheus.CounterVec {
 	return promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "falco_events",
 		},
-		[]string{
+		append([]string{
 			"rule",
 			"priority",
 			"k8s_ns_name",
 			"k8s_pod_name",
-		},
+		}, metricLabels.Names...),
 	)
 }
@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 )
 
@@ -67,31 +68,49 @@ func Priority(p string) PriorityType {
 	}
 }
 
+// priorityAliases are the other names of the priorities in the events, lowercased, see SetPriorityAliases
+var priorityAliases = map[string]PriorityType{
+	"emerg": Emergency,
+	"crit":  Critical,
+	"err":   Error,
+	"warn":  Warning,
+	"info":  Informational,
+}
+
+// unknownPriority is the priority of the events with an unknown priority
+var unknownPriority PriorityType = Default
+
+// SetPriorityAliases adds aliases (alias: priority) to the ones of the priorities in the events
+// and sets the priority of the events with an unknown priority
+func SetPriorityAliases(aliases map[string]string, unknown string) error {
+	for alias, priority := range aliases {
+		p := Priority(priority)
+		if p == Default {
+			return fmt.Errorf("Unknown priority '%v' for alias '%v'", priority, alias)
+		}
+		priorityAliases[strings.ToLower(alias)] = p
+	}
+	unknownPriority = Priority(unknown)
+	return nil
+}
+
+// NormalizePriority returns the priority of an event, with any casing or alias
+func NormalizePriority(p string) PriorityType {
+	if n := Priority(p); n != Default || p == "" {
+		return n
+	}
+	if n, ok := priorityAliases[strings.ToLower(p)]; ok {
+		return n
+	}
+	return unknownPriority
+}
+
 func (p *PriorityType) UnmarshalJSON(b []byte) error {
 	var s string
 	if err := json.Unmarshal(b, &s); err != nil {
 		return err
 	}
-	switch strings.ToLower(s) {
-	case "emergency":
-		*p = Emergency
-	case "alert":
-		*p = Alert
-	case "critical":
-		*p = Critical
-	case "error":
-		*p = Error
-	case "warning":
-		*p = Warning
-	case "notice":
-		*p = Notice
-	case "informational":
-		*p = Informational
-	case "debug":
-		*p = Debug
-	default:
-		*p = Default
-	}
+	*p = NormalizePriority(s)
 
 	return nil
 }
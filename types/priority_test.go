@@ -215,3 +215,41 @@ func TestPriority(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizePriority(t *testing.T) {
+	for _, i := range []string{"warn", "WARNING", "Warning", "WARN"} {
+		var p PriorityType
+		if err := p.UnmarshalJSON([]byte(`"` + i + `"`)); err != nil {
+			t.Fatal(err)
+		}
+		if p != Warning {
+			t.Errorf("UnmarshalJSON(%v) = %v, want %v", i, p, PriorityType(Warning))
+		}
+	}
+
+	defer func() {
+		delete(priorityAliases, "high")
+		unknownPriority = Default
+	}()
+	if err := SetPriorityAliases(map[string]string{"High": "critical"}, "notice"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		p    string
+		want PriorityType
+	}{
+		{p: "high", want: Critical},
+		{p: "crit", want: Critical},
+		{p: "idk", want: Notice},
+		{p: "", want: Default},
+	}
+	for _, tt := range tests {
+		if got := NormalizePriority(tt.p); got != tt.want {
+			t.Errorf("NormalizePriority(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+
+	if err := SetPriorityAliases(map[string]string{"low": "lowest"}, ""); err == nil {
+		t.Error("SetPriorityAliases() with an unknown priority should fail")
+	}
+}
@@ -16,6 +16,12 @@ type FalcoPayload struct {
 	Rule         string                 `json:"rule"`
 	Time         time.Time              `json:"time"`
 	OutputFields map[string]interface{} `json:"output_fields"`
+	Source       string                 `json:"source,omitempty"`
+	Tags         []string               `json:"tags,omitempty"`
+	Hostname     string                 `json:"hostname,omitempty"`
+	// RawPayload is the event as received by the HTTP handler, the Kafka input or the sample payload, verbatim, it's nil
+	// for the events replayed from a file, the summaries built by falcosidekick and the events once redacted
+	RawPayload []byte `json:"-"`
 }
 
 // Configuration is a struct to store configuration
@@ -25,17 +31,59 @@ type Configuration struct {
 	ListenAddress      string
 	ListenPort         int
 	Customfields       map[string]string
+	Templatedfields    map[string]string
+	FieldsPrecedence   string
+	FieldsConflict     string
+	IngestLatency      IngestLatencyConfig
+	Node               NodeConfig
+	Replay             ReplayConfig
+	OPA                OPAConfig
+	Drain              DrainConfig
+	Test               TestConfig
+	Readiness          ReadinessConfig
+	DeadLetter         DeadLetterConfig
+	Log                LogConfig
+	Consul             ConsulConfig
+	Metrics            MetricsConfig
+	Priorities         PrioritiesConfig
+	Correlation        CorrelationConfig
+	Reordering         ReorderingConfig
+	Escalation         EscalationConfig
+	CEL                CELConfig
+	Enrichment         EnrichmentConfig
+	Runbooks           RunbooksConfig
+	Sampling           SamplingConfig
+	Suppression        SuppressionConfig
+	Cooldown           CooldownConfig
+	Flattening         FlatteningConfig
+	Deduplication      DeduplicationConfig
+	Dispatch           DispatchConfig
+	QuietHours         QuietHoursConfig
+	Schedule           ScheduleConfig
+	Routing            RoutingConfig
+	Summary            SummaryConfig
+	DryRun             DryRunConfig
+	Retry              RetryConfig
+	Timeout            TimeoutConfig
+	PayloadSize        PayloadSizeConfig
+	KafkaInput         KafkaInputConfig
 	Slack              SlackOutputConfig
 	Mattermost         MattermostOutputConfig
 	Rocketchat         RocketchatOutputConfig
 	Teams              teamsOutputConfig
 	Datadog            datadogOutputConfig
+	DatadogLogs        datadogLogsOutputConfig
 	Discord            DiscordOutputConfig
 	Alertmanager       alertmanagerOutputConfig
 	Elasticsearch      elasticsearchOutputConfig
 	Influxdb           influxdbOutputConfig
 	Loki               lokiOutputConfig
 	Nats               natsOutputConfig
+	JetStream          jetstreamOutputConfig
+	MQTT               mqttOutputConfig
+	Redis              redisOutputConfig
+	Syslog             syslogOutputConfig
+	GELF               gelfOutputConfig
 	Stan               stanOutputConfig
 	AWS                awsOutputConfig
 	SMTP               smtpOutputConfig
@@ -43,6 +91,9 @@ type Configuration struct {
 	Statsd             statsdOutputConfig
 	Dogstatsd          statsdOutputConfig
 	Webhook            WebhookOutputConfig
+	Tenants            TenantsOutputConfig
+	WebSocket          WebSocketOutputConfig
+	Fifo               FifoOutputConfig
 	CloudEvents        CloudEventsOutputConfig
 	Azure              azureConfig
 	GCP                gcpOutputConfig
@@ -56,46 +107,334 @@ type Configuration struct {
 	Wavefront          WavefrontOutputConfig
 }
 
+// IngestLatencyConfig represents parameters for tagging events with their ingest latency
+type IngestLatencyConfig struct {
+	Enabled bool
+	Metric  bool
+}
+
+// NodeConfig represents parameters for adding to events the node they happened on, under a single field
+type NodeConfig struct {
+	Enabled bool
+	Field   string
+	Sources []string
+	Default string
+}
+
+// ReplayConfig represents parameters for replaying a file of events at startup
+type ReplayConfig struct {
+	File        string
+	Parallelism int
+	Rate        float64
+	Offset      int
+}
+
+// OPAConfig represents parameters for routing events with the decisions of an OPA server
+type OPAConfig struct {
+	URL       string
+	CacheTTL  int
+	FailOpen  bool
+	CheckCert bool
+}
+
+// DrainConfig represents parameters for the drain endpoint
+type DrainConfig struct {
+	Token           string
+	ShutdownTimeout int // seconds
+}
+
+// TestConfig represents parameters for the test endpoint
+type TestConfig struct {
+	Token string
+}
+
+// ReadinessConfig represents parameters for the readiness endpoint
+type ReadinessConfig struct {
+	Outputs  []string // outputs which must be up for falcosidekick to be ready
+	CacheTTL int      // seconds
+	Timeout  int      // in ms
+}
+
+// DeadLetterConfig represents parameters for keeping the events received with a malformed JSON body
+type DeadLetterConfig struct {
+	File     string
+	MaxSize  int64 // bytes
+	MaxFiles int
+}
+
+// LogConfig represents parameters for the logs of falcosidekick itself
+type LogConfig struct {
+	Format        string
+	Level         string
+	ErrorSampling ErrorSamplingConfig
+}
+
+// ErrorSamplingConfig represents parameters for coalescing the errors logged by an output
+type ErrorSamplingConfig struct {
+	Window int
+	Rate   int
+}
+
+// ConsulConfig represents parameters for the Consul agent used to resolve the endpoints of outputs
+type ConsulConfig struct {
+	Address         string
+	Token           string
+	RefreshInterval int
+}
+
+// MetricsConfig represents parameters for the extra labels of the falco_events metric
+type MetricsConfig struct {
+	Labels           map[string]string // label: field
+	CardinalityLimit int
+}
+
+// PrioritiesConfig represents parameters for the normalization of the priorities of the events
+type PrioritiesConfig struct {
+	Aliases map[string]string // alias: priority
+	Unknown string
+}
+
+// EscalationConfig represents parameters for raising the priority of the events repeated for the same entity
+type EscalationConfig struct {
+	Keys      []string // fields identifying the entity, with the rule
+	Threshold int      // occurrences from which the priority is raised, 0 disables it
+	Window    int      // s
+	Levels    int
+}
+
+// CELConfig represents parameters for filtering the events, remapping their priority and computing fields
+// with CEL expressions
+type CELConfig struct {
+	Filter   string
+	Priority string
+	Fields   map[string]string // field: expression
+}
+
+// CorrelationConfig represents parameters for merging syscall and k8s_audit events describing the same action
+type CorrelationConfig struct {
+	Enabled     bool
+	Window      int // ms
+	SyscallKeys []string
+	AuditKeys   []string
+}
+
+// ReorderingConfig represents parameters for emitting the events sorted by their time
+type ReorderingConfig struct {
+	Window int // ms, 0 disables it
+}
+
+// EnrichmentConfig represents parameters for enriching events with the fields returned by a lookup service
+type EnrichmentConfig struct {
+	URL       string
+	Field     string
+	CacheTTL  int     // s
+	Rate      float64 // lookups per second
+	Timeout   int     // ms
+	CheckCert bool
+}
+
+// RunbooksConfig represents parameters for adding to the events the URL of the runbook of their rule
+type RunbooksConfig struct {
+	File string // JSON array of {"rule": glob, "url": url}
+}
+
+// SuppressionConfig represents parameters for dropping the benign variants of events before they're sent to outputs
+type SuppressionConfig struct {
+	Rules []SuppressionRuleConfig
+}
+
+// SuppressionRuleConfig represents the values of a field of the events suppressed for some outputs
+type SuppressionRuleConfig struct {
+	Reason  string
+	Field   string
+	Values  []string
+	Outputs []string // all the outputs if empty
+}
+
+// SamplingConfig represents parameters for keeping only a sample of the events of noisy rules
+type SamplingConfig struct {
+	Rules          []SamplingRuleConfig
+	Outputs        map[string]string // output: rate of the events not matching a rule of the output
+	ExemptPriority string
+	AddRate        bool
+}
+
+// SamplingRuleConfig represents the rate of events kept for the rules matching a regular expression
+type SamplingRuleConfig struct {
+	Rule    string
+	Rate    float64
+	Outputs []string // all the outputs if empty
+}
+
+// DispatchConfig represents parameters for the order of the calls of the outputs
+type DispatchConfig struct {
+	Dependencies map[string]string // output: comma separated outputs which must succeed before it's called
+	Deadline     int               // in ms, time budget of an event for all the outputs, retries included, 0 disables it
+	Queues       map[string]string // output: "workers/size/policy" of the bounded queue its events are sent from
+}
+
+// DryRunConfig represents parameters for the outputs logging their requests instead of sending them
+type DryRunConfig struct {
+	Outputs []string
+}
+
+// CooldownConfig represents parameters for suppressing the events identical to one sent to an output within a window
+type CooldownConfig struct {
+	Windows map[string]string // output: duration (ex: 10m)
+	Key     string            // template of the key of the identical events
+}
+
+// FlatteningConfig represents parameters for flattening the nested output fields of the events sent to some outputs
+type FlatteningConfig struct {
+	Outputs   []string // names are the ones of the enabled outputs in lowercase
+	Separator string
+	Coerce    string // "", auto or string
+}
+
+// DeduplicationConfig represents parameters for suppressing the duplicates of an event within a window
+type DeduplicationConfig struct {
+	Window int      // s, 0 disables it
+	MaxAge int      // s, 0 is 10 windows
+	Fields []string // output fields of the fingerprint of the events, with their rule and hostname
+}
+
+// QuietHoursConfig represents parameters for deferring the events of low priority sent to outputs during their quiet hours
+type QuietHoursConfig struct {
+	Windows        map[string]string // output: "HH:MM-HH:MM"
+	Timezone       string
+	ExemptPriority string
+}
+
+// ScheduleConfig represents parameters for routing the events to different outputs depending on the time of day
+type ScheduleConfig struct {
+	Timezone string
+	Routes   []ScheduleRouteConfig
+}
+
+// ScheduleRouteConfig represents the outputs of the events during a daily window
+type ScheduleRouteConfig struct {
+	Window  string // "HH:MM-HH:MM"
+	Outputs []string
+}
+
+// RoutingConfig represents parameters for routing the events to different outputs depending on their fields
+type RoutingConfig struct {
+	Routes  []RoutingRouteConfig
+	Default []string // outputs of the events matching no route, all the outputs if empty
+}
+
+// RoutingRouteConfig represents the outputs of the events whose field matches a condition, one of Equals, Contains or Regex
+type RoutingRouteConfig struct {
+	Field    string // rule, priority, source, hostname or one of the output fields
+	Equals   string
+	Contains string
+	Regex    string
+	Outputs  []string
+	Final    bool // if true, the next routes aren't evaluated for the events matching this one
+}
+
+// SummaryConfig represents parameters for the periodic summary events with the counts of the events received
+type SummaryConfig struct {
+	Interval int // s, 0 disables it
+	Outputs  []string
+	Priority string
+}
+
+// RetryConfig represents parameters for retrying the requests of the HTTP outputs failing with a transient error
+type RetryConfig struct {
+	MaxAttempts  int // 1 disables the retries
+	InitialDelay int // ms
+	MaxDelay     int // ms
+}
+
+// TimeoutConfig represents the timeouts of the requests of the HTTP outputs
+type TimeoutConfig struct {
+	Default string            // duration (ex: 10s), "" or 0 disables it
+	Outputs map[string]string // output: duration (ex: 3s), overrides Default, 0 uses Default
+}
+
+// PayloadSizeConfig represents parameters for the monitoring of the size of the payloads sent to the outputs
+type PayloadSizeConfig struct {
+	Threshold int // bytes
+}
+
+// KafkaInputConfig represents parameters for consuming the events from a Kafka topic
+type KafkaInputConfig struct {
+	HostPort      string // comma separated brokers
+	Topic         string
+	GroupID       string
+	TLS           bool
+	CheckCert     bool
+	SASLMechanism string // plain, scram-sha-256 or scram-sha-512
+	Username      string
+	Password      string
+}
+
 // SlackOutputConfig represents parameters for Slack
 type SlackOutputConfig struct {
-	WebhookURL            string
-	Footer                string
-	Icon                  string
-	Username              string
-	OutputFormat          string
-	MinimumPriority       string
-	MessageFormat         string
-	MessageFormatTemplate *template.Template
-	CheckCert             bool
-	MutualTLS             bool
+	WebhookURL             string
+	Token                  string // if set, the messages are posted to Channel with the Slack API instead of WebhookURL
+	Channel                string
+	ThreadWindow           int    // s, the related events are replies to the first one for this window, 0 disables it
+	ThreadField            string // output field whose value is the thread of the events of a rule, with the rule
+	Footer                 string
+	Icon                   string
+	Username               string
+	OutputFormat           string
+	MinimumPriority        string
+	RedactFields           []string // same as MaskFields
+	MaskFields             []string // output fields whose values are replaced by ***, with * globs
+	RedactPatterns         []string // regexps of the secrets replaced by *** in the output and the string values of the fields
+	MessageFormat          string
+	MessageFormatTemplate  *template.Template
+	MessageFormatField     string
+	MessageFormats         map[string]string
+	MessageFormatTemplates map[string]*template.Template
+	RetryAfterPolicy       string // "" (disabled), queue or drop
+	MaxRate                string // "" (disabled), N/s, N/m or N/h
+	RateLimitMode          string // block or drop
+	CheckCert              bool
+	MutualTLS              bool
 }
 
 // RocketchatOutputConfig .
 type RocketchatOutputConfig struct {
-	WebhookURL            string
-	Footer                string
-	Icon                  string
-	Username              string
-	OutputFormat          string
-	MinimumPriority       string
-	MessageFormat         string
-	MessageFormatTemplate *template.Template
-	CheckCert             bool
-	MutualTLS             bool
+	WebhookURL             string
+	Footer                 string
+	Icon                   string
+	Username               string
+	OutputFormat           string
+	MinimumPriority        string
+	RedactFields           []string // same as MaskFields
+	MaskFields             []string
+	RedactPatterns         []string
+	MessageFormat          string
+	MessageFormatTemplate  *template.Template
+	MessageFormatField     string
+	MessageFormats         map[string]string
+	MessageFormatTemplates map[string]*template.Template
+	CheckCert              bool
+	MutualTLS              bool
 }
 
 // MattermostOutputConfig represents parameters for Mattermost
 type MattermostOutputConfig struct {
-	WebhookURL            string
-	Footer                string
-	Icon                  string
-	Username              string
-	OutputFormat          string
-	MinimumPriority       string
-	MessageFormat         string
-	MessageFormatTemplate *template.Template
-	CheckCert             bool
-	MutualTLS             bool
+	WebhookURL             string
+	Footer                 string
+	Icon                   string
+	Username               string
+	OutputFormat           string
+	MinimumPriority        string
+	RedactFields           []string // same as MaskFields
+	MaskFields             []string
+	RedactPatterns         []string
+	MessageFormat          string
+	MessageFormatTemplate  *template.Template
+	MessageFormatField     string
+	MessageFormats         map[string]string
+	MessageFormatTemplates map[string]*template.Template
+	CheckCert              bool
+	MutualTLS              bool
 }
 
 type WavefrontOutputConfig struct {
@@ -107,75 +446,224 @@ type WavefrontOutputConfig struct {
 	FlushIntervalSeconds int    // Time between flushes.
 	BatchSize            int    // BatchSize to send. Only for direct mode
 	MinimumPriority      string
+	PriorityCase         string // asis, lower or upper
 }
 
 type teamsOutputConfig struct {
-	WebhookURL      string
-	ActivityImage   string
-	OutputFormat    string
-	MinimumPriority string
-	CheckCert       bool
-	MutualTLS       bool
+	WebhookURL             string
+	ActivityImage          string
+	OutputFormat           string
+	Mode                   string // legacy or workflows
+	UseAdaptiveCard        bool   // Adaptive Card instead of MessageCard in legacy mode
+	MinimumPriority        string
+	RedactFields           []string // same as MaskFields
+	MaskFields             []string
+	RedactPatterns         []string
+	MessageFormat          string
+	MessageFormatTemplate  *template.Template
+	MessageFormatField     string
+	MessageFormats         map[string]string
+	MessageFormatTemplates map[string]*template.Template
+	RetryAfterPolicy       string
+	MaxRate                string
+	RateLimitMode          string
+	CheckCert              bool
+	MutualTLS              bool
 }
 
 type datadogOutputConfig struct {
 	APIKey          string
 	Host            string
 	MinimumPriority string
+	RedactFields    []string // same as MaskFields
+	MaskFields      []string
+	RedactPatterns  []string
 	CheckCert       bool
 	MutualTLS       bool
 }
 
-// DiscordOutputConfig .
-type DiscordOutputConfig struct {
-	WebhookURL      string
+type datadogLogsOutputConfig struct {
+	APIKey          string
+	Site            string // datadoghq.com (US1), datadoghq.eu (EU)... or the URL of a custom intake
+	Service         string
+	Tags            []string // ddtags of the logs, besides the priority
+	Fields          []string // output fields promoted to attributes of the logs
+	BatchSize       int      // events sent in a single request, 1 sends each event in its own request
+	FlushInterval   int      // s
 	MinimumPriority string
-	Icon            string
 	CheckCert       bool
 	MutualTLS       bool
 }
 
+// DiscordOutputConfig .
+type DiscordOutputConfig struct {
+	WebhookURL       string
+	MinimumPriority  string
+	RedactFields     []string // same as MaskFields
+	MaskFields       []string
+	RedactPatterns   []string
+	Icon             string
+	RetryAfterPolicy string
+	MaxRate          string
+	RateLimitMode    string
+	CheckCert        bool
+	MutualTLS        bool
+}
+
 type alertmanagerOutputConfig struct {
+	HostPort               string
+	MinimumPriority        string
+	ServerName             string
+	DisableSessionTickets  bool
+	SessionCacheSize       int // TLS sessions kept for their resumption, 0 disables it
+	SchemaVersion          string
+	SchemaVersionInPayload bool
+	CheckCert              bool
+	MutualTLS              bool
+}
+
+type elasticsearchOutputConfig struct {
+	HostPort                string
+	Index                   string
+	Type                    string
+	CustomHeaders           map[string]string // values are templates of the fields of the event
+	MinimumPriority         string
+	ServerName              string
+	DisableSessionTickets   bool
+	SessionCacheSize        int // TLS sessions kept for their resumption, 0 disables it
+	Suffix                  string
+	EnableCompression       bool
+	CompressionThreshold    int // bytes, the smaller bodies aren't compressed
+	RequiredFields          []string
+	RequiredFieldsAction    string            // drop, deadletter or forward
+	Pipeline                []string          // order of the transform, redact and validate steps
+	FieldsMapping           map[string]string // output field: new name, for the transform step
+	KeysMapping             map[string]string // top-level key of the payload: new name, "" drops it
+	RedactFields            []string          // output fields removed by the redact step
+	MaskFields              []string          // output fields whose values are replaced by *** by the redact step, with * globs
+	RedactPatterns          []string          // regexps of the secrets replaced by *** by the redact step
+	SchemaVersion           string
+	SchemaVersionInPayload  bool
+	PriorityCase            string            // asis, lower or upper
+	StatusCodePolicies      map[string]string // status code: retry[:backoff], fail or success
+	StatusCodeRetries       int
+	MaxRetryDuration        int // s, 0 disables it
+	CircuitBreakerThreshold int // consecutive failures opening the circuit breaker, 0 disables it
+	CircuitBreakerCooldown  int // s
+	BackpressureDelay       int // ms
+	BackpressureMaxDelay    int // ms
+	BatchSize               int // events sent in a single request to the _bulk API, 0 disables it
+	FlushInterval           int // s, the events buffered are sent at least at this interval
+	CompactFields           bool
+	CheckCert               bool
+	MutualTLS               bool
+	MutualTLSCACert         string // file or inline PEM, instead of the CA bundle of MutualTLSFilesPath
+	MutualTLSClientCert     string // file or inline PEM, instead of the client certificate of MutualTLSFilesPath
+	MutualTLSClientKey      string // file or inline PEM
+}
+
+type influxdbOutputConfig struct {
+	HostPort              string
+	Database              string
+	User                  string
+	Password              string
+	Token                 string // if set, the API v2 is used, with Organization and Bucket
+	Organization          string
+	Bucket                string
+	Tags                  []string // fields set as tags of the points of the API v2
+	MinimumPriority       string
+	ServerName            string
+	DisableSessionTickets bool
+	SessionCacheSize      int // TLS sessions kept for their resumption, 0 disables it
+	CheckCert             bool
+	MutualTLS             bool
+}
+
+type lokiOutputConfig struct {
+	HostPort                string
+	CustomHeaders           map[string]string // values are templates of the fields of the event
+	MinimumPriority         string
+	ExtraLabels             []string // rule, priority, source or output fields, the labels of the streams instead of all the output fields
+	MaxLabelValues          int      // values of an extra label at most, 0 disables the cap
+	ServerName              string
+	DisableSessionTickets   bool
+	SessionCacheSize        int // TLS sessions kept for their resumption, 0 disables it
+	HedgeDelay              int // in ms, 0 disables the hedged requests
+	CircuitBreakerThreshold int // consecutive failures opening the circuit breaker, 0 disables it
+	CircuitBreakerCooldown  int // s
+	EnableCompression       bool
+	CompressionThreshold    int // bytes, the smaller bodies aren't compressed
+	ConsulService           string
+	ConsulTags              []string
+	CheckCert               bool
+	MutualTLS               bool
+}
+
+type natsOutputConfig struct {
 	HostPort        string
 	MinimumPriority string
 	CheckCert       bool
 	MutualTLS       bool
 }
 
-type elasticsearchOutputConfig struct {
+type jetstreamOutputConfig struct {
 	HostPort        string
-	Index           string
-	Type            string
+	Subject         string
+	Stream          string // must exist, it's checked at startup
+	CredsFile       string
+	MaxReconnects   int // -1 for no limit
+	AckTimeout      int // ms
 	MinimumPriority string
-	Suffix          string
 	CheckCert       bool
 	MutualTLS       bool
 }
 
-type influxdbOutputConfig struct {
-	HostPort        string
-	Database        string
+type mqttOutputConfig struct {
+	Broker          string // tcp://, ssl:// (TLS), ws:// or wss:// host:port
+	Topic           string // template of the fields of the event
+	QOS             int    // 0, 1 or 2
+	Retained        bool
+	ClientID        string
 	User            string
 	Password        string
+	AckTimeout      int // ms
 	MinimumPriority string
 	CheckCert       bool
 	MutualTLS       bool
+	TopicTemplate   *template.Template
 }
 
-type lokiOutputConfig struct {
-	HostPort        string
+type redisOutputConfig struct {
+	Address         string // host:port
+	Password        string
+	Database        int
+	Mode            string // list or publish
+	Key             string // list or channel, template of the fields of the event
+	MaxLength       int    // 0 (no maximum) or maximum length of the list, the oldest events are trimmed
 	MinimumPriority string
+	TLS             bool
 	CheckCert       bool
 	MutualTLS       bool
+	KeyTemplate     *template.Template
 }
 
-type natsOutputConfig struct {
-	HostPort        string
+type syslogOutputConfig struct {
+	Address         string // host:port
+	Transport       string // udp, tcp or tls
+	Facility        string
+	AppName         string
 	MinimumPriority string
 	CheckCert       bool
 	MutualTLS       bool
 }
 
+type gelfOutputConfig struct {
+	Address         string // host:port
+	Transport       string // udp or tcp
+	ChunkSize       int    // maximum size in bytes of the UDP datagrams
+	MinimumPriority string
+}
+
 type stanOutputConfig struct {
 	HostPort        string
 	ClusterID       string
@@ -194,6 +682,7 @@ type awsOutputConfig struct {
 	SNS             awsSNSConfig
 	S3              awsS3Config
 	CloudWatchLogs  awsCloudWatchLogs
+	Kinesis         awsKinesisConfig
 }
 
 type awsLambdaConfig struct {
@@ -205,6 +694,10 @@ type awsLambdaConfig struct {
 
 type awsSQSConfig struct {
 	URL             string
+	MessageGroupID  string // template on the event, required for a FIFO queue
+	Deduplication   string // contentbased or id, required for a FIFO queue
+	DeduplicationID string // template on the event, the SHA-256 of the message if empty
+	BatchSize       int
 	MinimumPriority string
 }
 
@@ -220,18 +713,38 @@ type awsCloudWatchLogs struct {
 	MinimumPriority string
 }
 
+type awsKinesisConfig struct {
+	StreamName           string
+	PartitionKey         string // template of the fields of the event
+	PartitionKeyTemplate *template.Template
+	BatchSize            int
+	FlushInterval        int // seconds
+	MaxAttempts          int // attempts of the records failing, 1 disables the retries
+	MinimumPriority      string
+}
+
 type awsS3Config struct {
 	Prefix          string
 	Bucket          string
+	EncryptionKey   string // base64 encoded 256 bits key for client-side encryption
+	EncryptionKeyID string
+	KMSKeyID        string // KMS key used to wrap a data key per object for client-side encryption
+	RawEventKey     string // if set, key of the event as received, verbatim
+	Passthrough     bool   // if true, the event is uploaded as received, verbatim
 	MinimumPriority string
 }
 
 type smtpOutputConfig struct {
 	HostPort        string
+	TLS             string // none, starttls or tls, STARTTLS if the server supports it without it
+	CheckCert       bool
+	AuthMechanism   string // plain, login or cram-md5
 	User            string
 	Password        string
 	From            string
 	To              string
+	Cc              string
+	Bcc             string
 	OutputFormat    string
 	MinimumPriority string
 }
@@ -240,22 +753,105 @@ type opsgenieOutputConfig struct {
 	Region          string
 	APIKey          string
 	MinimumPriority string
+	Alias           string // Go template of the alias of the alert, the events with the same alias update it (ex: {{ .Rule }}/{{ .Field "k8s.pod.name" }})
+	AliasTemplate   *template.Template
+	CloseRules      map[string]string // rule: rule of the alert closed by its events, with the same alias
+	Priorities      map[string]string // Falco priority: P1 to P5
+	Tags            []string          // output fields added as tags (field:value)
+	RedactFields    []string          // same as MaskFields
+	MaskFields      []string
+	RedactPatterns  []string
+	MaxRate         string
+	RateLimitMode   string
 	CheckCert       bool
 	MutualTLS       bool
 }
 
 // WebhookOutputConfig represents parameters for Webhook
 type WebhookOutputConfig struct {
+	Address                 string
+	CustomHeaders           map[string]string // values are templates of the fields of the event
+	MinimumPriority         string
+	ServerName              string
+	DisableSessionTickets   bool
+	SessionCacheSize        int // TLS sessions kept for their resumption, 0 disables it
+	RequiredFields          []string
+	RequiredFieldsAction    string            // drop, deadletter or forward
+	Pipeline                []string          // order of the transform, redact and validate steps
+	FieldsMapping           map[string]string // output field: new name, for the transform step
+	KeysMapping             map[string]string // top-level key of the payload: new name, "" drops it
+	RedactFields            []string          // output fields removed by the redact step
+	MaskFields              []string          // output fields whose values are replaced by *** by the redact step, with * globs
+	RedactPatterns          []string          // regexps of the secrets replaced by *** by the redact step
+	RawEventKey             string            // if set, key of the event as received, verbatim
+	Passthrough             bool              // if true, the event is posted as received, verbatim
+	EnableCompression       bool
+	CompressionThreshold    int // bytes, the smaller bodies aren't compressed
+	RetryAfterPolicy        string
+	MaxRate                 string
+	RateLimitMode           string
+	SchemaVersion           string
+	SchemaVersionInPayload  bool
+	PriorityCase            string            // asis, lower or upper
+	StatusCodePolicies      map[string]string // status code: retry[:backoff], fail or success
+	StatusCodeRetries       int
+	MaxRetryDuration        int // s, 0 disables it
+	CircuitBreakerThreshold int // consecutive failures opening the circuit breaker, 0 disables it
+	CircuitBreakerCooldown  int // s
+	HedgeDelay              int // in ms, 0 disables the hedged requests
+	ConsulService           string
+	ConsulTags              []string
+	Authorization           string   // value of the Authorization header, for the origin
+	ProxyAuthorization      string   // value of the Proxy-Authorization header, for the HTTP proxy
+	AuthMethods             []string // "Header: value", in the order to try them
+	OAuth2TokenURL          string   // if set, the requests are authenticated with a token of the client credentials grant
+	OAuth2ClientID          string
+	OAuth2ClientSecret      string
+	OAuth2Scopes            []string
+	CheckCert               bool
+	MutualTLS               bool
+	MutualTLSCACert         string // file or inline PEM, instead of the CA bundle of MutualTLSFilesPath
+	MutualTLSClientCert     string // file or inline PEM, instead of the client certificate of MutualTLSFilesPath
+	MutualTLSClientKey      string // file or inline PEM
+	MessageFormat           string // Go template replacing the output of the events
+	MessageFormatTemplate   *template.Template
+}
+
+// TenantsOutputConfig represents parameters for the routing of the events to the destination of their tenant
+type TenantsOutputConfig struct {
+	Field           string
+	Destinations    map[string]string // tenant: URL
+	Tokens          map[string]string // tenant: bearer token
+	DefaultURL      string
+	DefaultToken    string
+	MinimumPriority string
+	CheckCert       bool
+	MutualTLS       bool
+}
+
+// WebSocketOutputConfig represents parameters for WebSocket
+type WebSocketOutputConfig struct {
 	Address         string
 	CustomHeaders   map[string]string
+	BufferSize      int
+	PingInterval    int
 	MinimumPriority string
 	CheckCert       bool
 	MutualTLS       bool
 }
 
+// FifoOutputConfig represents parameters for Fifo
+type FifoOutputConfig struct {
+	Path            string
+	MinimumPriority string
+}
+
 // CloudEventsOutputConfig represents parameters for CloudEvents
 type CloudEventsOutputConfig struct {
 	Address         string
+	Mode            string // binary or structured
+	Type            string
+	Source          string
 	Extensions      map[string]string
 	MinimumPriority string
 	CheckCert       bool
@@ -270,6 +866,7 @@ type statsdOutputConfig struct {
 
 type azureConfig struct {
 	EventHub eventHub
+	Blob     azureBlob
 }
 
 type eventHub struct {
@@ -278,6 +875,22 @@ type eventHub struct {
 	MinimumPriority string
 }
 
+type azureBlob struct {
+	AccountName        string
+	Container          string
+	Endpoint           string
+	ConnectionString   string
+	SASToken           string
+	UseManagedIdentity bool
+	KeyFormat          string
+	BatchSize          int
+	FlushInterval      int
+	AppendMode         bool
+	CompactFields      bool
+	RawEventKey        string // if set, key of the event as received, verbatim
+	MinimumPriority    string
+}
+
 type gcpCloudRun struct {
 	Endpoint        string
 	JWT             string
@@ -299,37 +912,64 @@ type gcpCloudFunctions struct {
 }
 
 type gcpPubSub struct {
-	ProjectID       string
-	Topic           string
-	MinimumPriority string
+	ProjectID           string
+	Topic               string
+	OrderingKey         string // template of the fields of the event, the ordering key of the messages, enables the message ordering
+	OrderingKeyTemplate *template.Template
+	Attributes          []string // fields set as attributes of the messages
+	MinimumPriority     string
 }
 
 type gcpStorage struct {
 	Bucket          string
 	Prefix          string
+	RawEventKey     string // if set, key of the event as received, verbatim
 	MinimumPriority string
 }
 
 // GooglechatConfig represents parameters for Google chat
 type GooglechatConfig struct {
-	WebhookURL            string
-	OutputFormat          string
-	MinimumPriority       string
-	MessageFormat         string
-	MessageFormatTemplate *template.Template
-	CheckCert             bool
-	MutualTLS             bool
+	WebhookURL             string
+	OutputFormat           string
+	MinimumPriority        string
+	RedactFields           []string // same as MaskFields
+	MaskFields             []string
+	RedactPatterns         []string
+	MessageFormat          string
+	MessageFormatTemplate  *template.Template
+	MessageFormatField     string
+	MessageFormats         map[string]string
+	MessageFormatTemplates map[string]*template.Template
+	UseCardsV2             bool
+	ThreadKey              string // Go template of the key of the thread of the event (ex: {{ .Rule }})
+	ThreadKeyTemplate      *template.Template
+	CheckCert              bool
+	MutualTLS              bool
 }
 
 type kafkaConfig struct {
-	HostPort        string
+	HostPort        string // comma separated brokers
 	Topic           string
+	TLS             bool
+	CheckCert       bool
+	SASLMechanism   string // plain, scram-sha-256 or scram-sha-512
+	Username        string
+	Password        string
+	MessageKey      string // template of the key of the messages
+	RequiredAcks    string // none, one or all
+	Compression     string // none, gzip, snappy, lz4 or zstd
+	Passthrough     bool   // if true, the event is produced as received, verbatim
 	MinimumPriority string
 }
 
 type PagerdutyConfig struct {
 	RoutingKey      string
 	MinimumPriority string
+	RedactFields    []string // same as MaskFields
+	MaskFields      []string
+	RedactPatterns  []string
+	MaxRate         string
+	RateLimitMode   string
 	CheckCert       bool
 	MutualTLS       bool
 }
@@ -375,21 +1015,29 @@ type Statistics struct {
 	Requests          *expvar.Map
 	FIFO              *expvar.Map
 	GRPC              *expvar.Map
+	KafkaInput        *expvar.Map
 	Falco             *expvar.Map
 	Slack             *expvar.Map
 	Mattermost        *expvar.Map
 	Rocketchat        *expvar.Map
 	Teams             *expvar.Map
 	Datadog           *expvar.Map
+	DatadogLogs       *expvar.Map
 	Discord           *expvar.Map
 	Alertmanager      *expvar.Map
 	Elasticsearch     *expvar.Map
 	Loki              *expvar.Map
 	Nats              *expvar.Map
+	JetStream         *expvar.Map
+	MQTT              *expvar.Map
+	Redis             *expvar.Map
+	Syslog            *expvar.Map
+	GELF              *expvar.Map
 	Stan              *expvar.Map
 	Influxdb          *expvar.Map
 	AWSLambda         *expvar.Map
 	AWSSQS            *expvar.Map
+	AWSKinesis        *expvar.Map
 	AWSSNS            *expvar.Map
 	AWSCloudWatchLogs *expvar.Map
 	AWSS3             *expvar.Map
@@ -398,7 +1046,11 @@ type Statistics struct {
 	Statsd            *expvar.Map
 	Dogstatsd         *expvar.Map
 	Webhook           *expvar.Map
+	Tenants           *expvar.Map
+	WebSocket         *expvar.Map
+	Fifo              *expvar.Map
 	AzureEventHub     *expvar.Map
+	AzureBlob         *expvar.Map
 	GCPPubSub         *expvar.Map
 	GCPStorage        *expvar.Map
 	GCPCloudFunctions *expvar.Map
@@ -416,7 +1068,17 @@ type Statistics struct {
 
 // PromStatistics is a struct to store prometheus metrics
 type PromStatistics struct {
-	Falco   *prometheus.CounterVec
-	Inputs  *prometheus.CounterVec
-	Outputs *prometheus.CounterVec
+	Falco           *prometheus.CounterVec
+	Inputs          *prometheus.CounterVec
+	Outputs         *prometheus.CounterVec
+	IngestLatency   prometheus.Histogram
+	Sampled         *prometheus.CounterVec
+	Suppressed      *prometheus.CounterVec
+	Deduplicated    *prometheus.CounterVec
+	Retries         *prometheus.CounterVec
+	RateLimited     *prometheus.CounterVec
+	MalformedInputs *prometheus.CounterVec
+	PayloadSize     *prometheus.HistogramVec
+	RequestDuration *prometheus.HistogramVec
+	CircuitBreakers *prometheus.GaugeVec
 }